@@ -8,32 +8,42 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AdminController struct {
-	userService *UserService
+	store              store.Store
+	userService        *UserService
+	orderService       *OrderService
+	productController  *ProductController
+	categoryController *CategoryController
+	orderController    *OrderController
 }
 
 // NewAdminController creates a new admin controller instance
-func NewAdminController() *AdminController {
+func NewAdminController(st store.Store) *AdminController {
 	return &AdminController{
-		userService: NewUserService(),
+		store:              st,
+		userService:        NewUserService(st),
+		orderService:       NewOrderService(st),
+		productController:  NewProductController(st),
+		categoryController: NewCategoryController(st),
+		orderController:    NewOrderController(st),
 	}
 }
 
 // DashboardStats struct for admin dashboard statistics
 type DashboardStats struct {
-	TotalUsers      int64   `json:"totalUsers"`
-	TotalProducts   int64   `json:"totalProducts"`
-	TotalOrders     int64   `json:"totalOrders"`
-	TotalCategories int64   `json:"totalCategories"`
-	TotalRevenue    float64 `json:"totalRevenue"`
-	OrdersToday     int64   `json:"ordersToday"`
-	RevenueToday    float64 `json:"revenueToday"`
+	TotalUsers      int64        `json:"totalUsers"`
+	TotalProducts   int64        `json:"totalProducts"`
+	TotalOrders     int64        `json:"totalOrders"`
+	TotalCategories int64        `json:"totalCategories"`
+	TotalRevenue    models.Money `json:"totalRevenue"` // Đã trừ số tiền hoàn
+	TotalRefunded   models.Money `json:"totalRefunded"`
+	OrdersToday     int64        `json:"ordersToday"`
+	RevenueToday    models.Money `json:"revenueToday"`
 }
 
 // GetDashboardStats lấy thống kê cho dashboard admin
@@ -41,96 +51,47 @@ func (ac *AdminController) GetDashboardStats(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-
 	// Count total users
-	userCollection := db.Collection("Users")
-	totalUsers, err := userCollection.CountDocuments(ctx, bson.M{})
+	totalUsers, err := ac.store.Users().Count(ctx, store.UserFilter{})
 	if err != nil {
 		totalUsers = 0
 	}
 
 	// Count total products
-	productCollection := db.Collection("Products")
-	totalProducts, err := productCollection.CountDocuments(ctx, bson.M{})
+	totalProducts, err := ac.store.Products().Count(ctx, store.ProductFilter{})
 	if err != nil {
 		totalProducts = 0
 	}
 
 	// Count total orders
-	orderCollection := db.Collection("orders")
-	totalOrders, err := orderCollection.CountDocuments(ctx, bson.M{})
+	totalOrders, err := ac.store.Orders().Count(ctx, store.OrderFilter{})
 	if err != nil {
 		totalOrders = 0
 	}
 
 	// Count total categories
-	categoryCollection := db.Collection("categories")
-	totalCategories, err := categoryCollection.CountDocuments(ctx, bson.M{})
+	totalCategories, err := ac.store.Categories().Count(ctx)
 	if err != nil {
 		totalCategories = 0
 	}
 
-	// Calculate total revenue
-	pipeline := []bson.M{
-		{
-			"$group": bson.M{
-				"_id":          nil,
-				"totalRevenue": bson.M{"$sum": "$totalAmount"},
-			},
-		},
-	}
-
-	cursor, err := orderCollection.Aggregate(ctx, pipeline)
-	var totalRevenue float64 = 0
-	if err == nil {
-		var result []bson.M
-		if err := cursor.All(ctx, &result); err == nil && len(result) > 0 {
-			if revenue, ok := result[0]["totalRevenue"].(float64); ok {
-				totalRevenue = revenue
-			}
-		}
-		cursor.Close(ctx)
+	// Calculate total revenue (cùng cách tính với thống kê đơn hàng)
+	var totalRevenue, totalRefunded models.Money
+	if statistics, err := ac.orderService.GetOrderStatistics(nil, nil); err == nil {
+		totalRevenue = statistics.TotalRevenue
+		totalRefunded = statistics.TotalRefunded
 	}
 
 	// Calculate today's stats
 	today := time.Now()
 	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
-	todayFilter := bson.M{
-		"createdAt": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
-	}
+	endOfDay := startOfDay.Add(24*time.Hour - time.Nanosecond)
 
-	ordersToday, err := orderCollection.CountDocuments(ctx, todayFilter)
-	if err != nil {
-		ordersToday = 0
-	}
-
-	// Calculate today's revenue
-	pipelineToday := []bson.M{
-		{"$match": todayFilter},
-		{
-			"$group": bson.M{
-				"_id":          nil,
-				"revenueToday": bson.M{"$sum": "$totalAmount"},
-			},
-		},
-	}
-
-	cursor, err = orderCollection.Aggregate(ctx, pipelineToday)
-	var revenueToday float64 = 0
-	if err == nil {
-		var result []bson.M
-		if err := cursor.All(ctx, &result); err == nil && len(result) > 0 {
-			if revenue, ok := result[0]["revenueToday"].(float64); ok {
-				revenueToday = revenue
-			}
-		}
-		cursor.Close(ctx)
+	var ordersToday int64 = 0
+	var revenueToday models.Money
+	if statistics, err := ac.orderService.GetOrderStatistics(&startOfDay, &endOfDay); err == nil {
+		ordersToday = statistics.TotalOrders
+		revenueToday = statistics.TotalRevenue
 	}
 
 	stats := DashboardStats{
@@ -139,6 +100,7 @@ func (ac *AdminController) GetDashboardStats(c *gin.Context) {
 		TotalOrders:     totalOrders,
 		TotalCategories: totalCategories,
 		TotalRevenue:    totalRevenue,
+		TotalRefunded:   totalRefunded,
 		OrdersToday:     ordersToday,
 		RevenueToday:    revenueToday,
 	}
@@ -281,24 +243,13 @@ func (ac *AdminController) DeleteUser(c *gin.Context) {
 
 // GetRecentOrders lấy danh sách đơn hàng gần đây (Admin only)
 func (ac *AdminController) GetRecentOrders(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	limitStr := c.DefaultQuery("limit", "10")
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit < 1 {
 		limit = 10
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("orders")
-
-	// Build sort options
-	sortOptions := options.Find()
-	sortOptions.SetSort(bson.D{{Key: "createdAt", Value: -1}})
-	sortOptions.SetLimit(int64(limit))
-
-	cursor, err := collection.Find(ctx, bson.M{}, sortOptions)
+	orders, err := ac.orderService.GetRecentOrders(limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -306,16 +257,6 @@ func (ac *AdminController) GetRecentOrders(c *gin.Context) {
 		})
 		return
 	}
-	defer cursor.Close(ctx)
-
-	var orders []models.Order
-	if err = cursor.All(ctx, &orders); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Lỗi khi decode đơn hàng",
-		})
-		return
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -325,53 +266,43 @@ func (ac *AdminController) GetRecentOrders(c *gin.Context) {
 
 // Admin Product Management
 func (ac *AdminController) GetAllProducts(c *gin.Context) {
-	productController := NewProductController()
-	productController.GetProducts(c)
+	ac.productController.GetProducts(c)
 }
 
 func (ac *AdminController) CreateProduct(c *gin.Context) {
-	productController := NewProductController()
-	productController.CreateProduct(c)
+	ac.productController.CreateProduct(c)
 }
 
 func (ac *AdminController) UpdateProduct(c *gin.Context) {
-	productController := NewProductController()
-	productController.UpdateProduct(c)
+	ac.productController.UpdateProduct(c)
 }
 
 func (ac *AdminController) DeleteProduct(c *gin.Context) {
-	productController := NewProductController()
-	productController.DeleteProduct(c)
+	ac.productController.DeleteProduct(c)
 }
 
 // Admin Category Management
 func (ac *AdminController) GetAllCategories(c *gin.Context) {
-	categoryController := &CategoryController{}
-	categoryController.GetAllCategories(c)
+	ac.categoryController.GetAllCategories(c)
 }
 
 func (ac *AdminController) CreateCategory(c *gin.Context) {
-	categoryController := &CategoryController{}
-	categoryController.CreateCategory(c)
+	ac.categoryController.CreateCategory(c)
 }
 
 func (ac *AdminController) UpdateCategory(c *gin.Context) {
-	categoryController := &CategoryController{}
-	categoryController.UpdateCategory(c)
+	ac.categoryController.UpdateCategory(c)
 }
 
 func (ac *AdminController) DeleteCategory(c *gin.Context) {
-	categoryController := &CategoryController{}
-	categoryController.DeleteCategory(c)
+	ac.categoryController.DeleteCategory(c)
 }
 
 // Admin Order Management
 func (ac *AdminController) GetAllOrders(c *gin.Context) {
-	orderController := &OrderController{}
-	orderController.GetAllOrders(c)
+	ac.orderController.GetAllOrders(c)
 }
 
 func (ac *AdminController) UpdateOrderStatus(c *gin.Context) {
-	orderController := &OrderController{}
-	orderController.UpdateOrderStatus(c)
+	ac.orderController.UpdateOrderStatus(c)
 }
@@ -1,21 +1,26 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/middleware"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 type AuthController struct {
 	userService *UserService
+	guestMerge  *GuestMergeService
 }
 
 // NewAuthController creates a new auth controller instance
-func NewAuthController() *AuthController {
+func NewAuthController(st store.Store) *AuthController {
 	return &AuthController{
-		userService: NewUserService(),
+		userService: NewUserService(st),
+		guestMerge:  NewGuestMergeService(st),
 	}
 }
 
@@ -26,12 +31,15 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 	FullName string `json:"full_name"`
 	Phone    string `json:"phone"`
+	// GuestToken là guest token của giỏ hàng cần gộp (có thể gửi qua header X-Guest-Token)
+	GuestToken string `json:"guest_token"`
 }
 
 // LoginRequest struct for login data
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	GuestToken string `json:"guest_token"`
 }
 
 // AuthResponse struct for authentication response
@@ -40,6 +48,8 @@ type AuthResponse struct {
 	Message string      `json:"message"`
 	Token   string      `json:"token,omitempty"`
 	User    interface{} `json:"user,omitempty"`
+	// GuestMerge là kết quả gộp giỏ hàng, wishlist, so sánh của khách (chỉ có khi gửi guest token)
+	GuestMerge *GuestMergeResult `json:"guest_merge,omitempty"`
 }
 
 // UserResponse struct for user data in response
@@ -105,10 +115,11 @@ func (ac *AuthController) Register(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, AuthResponse{
-		Success: true,
-		Message: "Đăng ký thành công",
-		Token:   loginResult.Token,
-		User:    userResponse,
+		Success:    true,
+		Message:    "Đăng ký thành công",
+		Token:      loginResult.Token,
+		User:       userResponse,
+		GuestMerge: ac.mergeGuestLists(c, req.GuestToken, createdUser),
 	})
 }
 
@@ -157,13 +168,37 @@ func (ac *AuthController) Login(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, AuthResponse{
-		Success: true,
-		Message: "Đăng nhập thành công",
-		Token:   loginResult.Token,
-		User:    userResponse,
+		Success:    true,
+		Message:    "Đăng nhập thành công",
+		Token:      loginResult.Token,
+		User:       userResponse,
+		GuestMerge: ac.mergeGuestLists(c, req.GuestToken, &loginResult.User),
 	})
 }
 
+// mergeGuestLists gộp giỏ hàng, wishlist, so sánh của guest token (trong body hoặc header X-Guest-Token)
+// vào tài khoản vừa đăng nhập. Lỗi gộp không làm đăng nhập thất bại mà được trả về trong kết quả.
+func (ac *AuthController) mergeGuestLists(c *gin.Context, guestToken string, user *models.User) *GuestMergeResult {
+	if guestToken == "" {
+		guestToken = c.GetHeader(middleware.GuestTokenHeader)
+	}
+	// Admin không có giỏ hàng nên dữ liệu của khách được giữ nguyên
+	if guestToken == "" || user == nil || user.Role == "admin" {
+		return nil
+	}
+
+	guestID, err := middleware.ParseGuestToken(guestToken)
+	if err != nil {
+		return &GuestMergeResult{Skipped: []MergeSkippedItem{}, Error: err.Error()}
+	}
+	result, err := ac.guestMerge.Merge(guestID, user.ID)
+	if err != nil {
+		log.Printf("Merge guest %s into user %s failed: %v", guestID.Hex(), user.ID.Hex(), err)
+		return &GuestMergeResult{Skipped: []MergeSkippedItem{}, Error: "không thể gộp giỏ hàng của khách: " + err.Error()}
+	}
+	return result
+}
+
 // GetProfile lấy thông tin profile
 func (ac *AuthController) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("userID")
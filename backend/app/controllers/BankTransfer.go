@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// BankTransferMethod là phương thức thanh toán chuyển khoản ngân hàng (payment.method)
+const BankTransferMethod = "bank_transfer"
+
+// Cờ đối soát của thanh toán chuyển khoản (payment.flag)
+const (
+	PaymentFlagPartial  = "partial"  // Khách chuyển thiếu
+	PaymentFlagOverpaid = "overpaid" // Khách chuyển thừa, cần hoàn lại phần thừa
+)
+
+// Kết quả đối soát của một dòng sao kê
+const (
+	StatementLinePaid      = "paid"
+	StatementLinePartial   = "partial"
+	StatementLineOverpaid  = "overpaid"
+	StatementLineDuplicate = "duplicate"
+	StatementLineUnmatched = "unmatched"
+	StatementLineSkipped   = "skipped"
+	StatementLineError     = "error"
+)
+
+// MaxBankStatementSize là dung lượng tối đa của file sao kê được tải lên
+const MaxBankStatementSize = 5 << 20
+
+// ErrBankTransferNotConfigured được trả về khi chưa cấu hình tài khoản nhận chuyển khoản
+var ErrBankTransferNotConfigured = errors.New("chưa cấu hình tài khoản nhận chuyển khoản")
+
+// BankStatementError được trả về khi file sao kê không đọc được
+type BankStatementError struct {
+	Reason string
+}
+
+func (e *BankStatementError) Error() string {
+	return "file sao kê không hợp lệ: " + e.Reason
+}
+
+// BankTransferAccount là tài khoản ngân hàng nhận tiền của shop
+type BankTransferAccount struct {
+	BankBIN       string `json:"bank_bin"`
+	BankName      string `json:"bank_name,omitempty"`
+	AccountNumber string `json:"account_number"`
+	AccountName   string `json:"account_name"`
+}
+
+// bankTransferAccountFromEnv đọc BANK_TRANSFER_BIN, BANK_TRANSFER_BANK_NAME,
+// BANK_TRANSFER_ACCOUNT_NUMBER và BANK_TRANSFER_ACCOUNT_NAME
+func bankTransferAccountFromEnv() BankTransferAccount {
+	return BankTransferAccount{
+		BankBIN:       os.Getenv("BANK_TRANSFER_BIN"),
+		BankName:      os.Getenv("BANK_TRANSFER_BANK_NAME"),
+		AccountNumber: os.Getenv("BANK_TRANSFER_ACCOUNT_NUMBER"),
+		AccountName:   os.Getenv("BANK_TRANSFER_ACCOUNT_NAME"),
+	}
+}
+
+// BankTransferInstructions là thông tin chuyển khoản trả về cho khách khi checkout
+type BankTransferInstructions struct {
+	BankTransferAccount
+	Amount    models.Money `json:"amount"`
+	Memo      string       `json:"memo"`       // Nội dung chuyển khoản, chính là mã đơn hàng
+	QRPayload string       `json:"qr_payload"` // Chuỗi VietQR (EMVCo) để render mã QR
+}
+
+// Instructions tạo thông tin chuyển khoản cho số tiền còn phải trả của đơn hàng
+func (a BankTransferAccount) Instructions(order *models.Order) (*BankTransferInstructions, error) {
+	if a.BankBIN == "" || a.AccountNumber == "" {
+		return nil, ErrBankTransferNotConfigured
+	}
+
+	amount := order.TotalAmount.Sub(order.Payment.ReceivedAmount)
+	if amount.CurrencyCode() != "VND" {
+		return nil, fmt.Errorf("VietQR chỉ hỗ trợ VND, đơn hàng dùng %s", amount.CurrencyCode())
+	}
+
+	payload, err := VietQR{
+		BankBIN:       a.BankBIN,
+		AccountNumber: a.AccountNumber,
+		Amount:        amount.Amount,
+		Memo:          order.OrderNumber,
+	}.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	return &BankTransferInstructions{
+		BankTransferAccount: a,
+		Amount:              amount,
+		Memo:                order.OrderNumber,
+		QRPayload:           payload,
+	}, nil
+}
+
+// BankStatementLine là một giao dịch đọc từ sao kê ngân hàng
+type BankStatementLine struct {
+	Line        int          `json:"line"` // Số dòng trong file (dòng tiêu đề là 1)
+	Reference   string       `json:"reference"`
+	Amount      models.Money `json:"amount"`
+	Description string       `json:"description"`
+	PostedAt    time.Time    `json:"posted_at,omitempty"`
+}
+
+// StatementLineResult là kết quả đối soát một giao dịch
+type StatementLineResult struct {
+	BankStatementLine
+	Result         string        `json:"result"` // "paid", "partial", "overpaid", "duplicate", "unmatched", "skipped", "error"
+	OrderNumber    string        `json:"order_number,omitempty"`
+	ReceivedAmount *models.Money `json:"received_amount,omitempty"` // Tổng đã nhận của đơn sau giao dịch này
+	Message        string        `json:"message,omitempty"`
+}
+
+// StatementReconciliation là kết quả đối soát cả file sao kê
+type StatementReconciliation struct {
+	Lines   []StatementLineResult `json:"lines"`
+	Summary map[string]int        `json:"summary"` // Số dòng theo từng kết quả
+}
+
+// statementColumns: tên cột được chấp nhận (đã viết thường) cho từng trường của sao kê
+var statementColumns = map[string][]string{
+	"reference":   {"reference", "ref", "reference number", "transaction id", "số tham chiếu", "so tham chieu", "mã giao dịch", "ma giao dich", "số bút toán", "so but toan"},
+	"amount":      {"amount", "credit", "credit amount", "số tiền", "so tien", "ghi có", "ghi co", "số tiền ghi có", "so tien ghi co"},
+	"description": {"description", "memo", "content", "details", "nội dung", "noi dung", "diễn giải", "dien giai", "mô tả", "mo ta"},
+	"date":        {"date", "transaction date", "posted date", "ngày", "ngay", "ngày giao dịch", "ngay giao dich"},
+}
+
+// statementDateLayouts là các định dạng ngày thường gặp trong sao kê
+var statementDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+}
+
+// ParseBankStatement đọc sao kê CSV (dấu phân cách "," hoặc ";") có dòng tiêu đề. Bắt buộc có cột số tiền
+// ghi có và nội dung; thiếu cột mã giao dịch thì mã được sinh từ nội dung dòng để tải lại file không bị ghi trùng.
+func ParseBankStatement(r io.Reader) ([]BankStatementLine, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxBankStatementSize+1))
+	if err != nil {
+		return nil, &BankStatementError{Reason: "không đọc được file"}
+	}
+	if len(data) > MaxBankStatementSize {
+		return nil, &BankStatementError{Reason: "file quá lớn"}
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &BankStatementError{Reason: err.Error()}
+	}
+	if len(records) == 0 {
+		return nil, &BankStatementError{Reason: "file rỗng"}
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for field, aliases := range statementColumns {
+			for _, alias := range aliases {
+				if _, seen := columns[field]; !seen && name == alias {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil, &BankStatementError{Reason: "thiếu cột số tiền ghi có"}
+	}
+	if _, ok := columns["description"]; !ok {
+		return nil, &BankStatementError{Reason: "thiếu cột nội dung"}
+	}
+
+	cell := func(record []string, field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	lines := make([]BankStatementLine, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		line := BankStatementLine{
+			Line:        i + 2,
+			Reference:   cell(record, "reference"),
+			Description: cell(record, "description"),
+		}
+		amount, err := parseStatementAmount(cell(record, "amount"))
+		if err != nil {
+			return nil, &BankStatementError{Reason: fmt.Sprintf("dòng %d: %v", line.Line, err)}
+		}
+		line.Amount = amount
+
+		date := cell(record, "date")
+		for _, layout := range statementDateLayouts {
+			if t, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+				line.PostedAt = t
+				break
+			}
+		}
+
+		if line.Reference == "" {
+			sum := sha256.Sum256([]byte(strings.Join([]string{date, amount.String(), line.Description}, "|")))
+			line.Reference = "row-" + hex.EncodeToString(sum[:8])
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// parseStatementAmount đọc số tiền VND dạng "1,500,000", "1.500.000", "1500000.00" hoặc "-200,000"
+func parseStatementAmount(value string) (models.Money, error) {
+	s := strings.NewReplacer(" ", "", "VND", "", "vnd", "", "₫", "", "đ", "").Replace(value)
+	if s == "" {
+		return models.Money{}, nil
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, s[1:len(s)-1]
+	}
+	if strings.HasPrefix(s, "-") {
+		negative, s = true, s[1:]
+	}
+	s = strings.TrimPrefix(s, "+")
+
+	// Dấu phân cách cuối cùng là dấu thập phân nếu file dùng cả hai loại dấu
+	// hoặc sau nó không phải đúng 3 chữ số; ngược lại là dấu phân cách hàng nghìn
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 {
+		integer, fraction := s[:i], s[i+1:]
+		other := "."
+		if s[i] == '.' {
+			other = ","
+		}
+		separators := strings.NewReplacer(".", "", ",", "")
+		if strings.Contains(integer, other) || len(fraction) != 3 {
+			s = separators.Replace(integer) + "." + fraction
+		} else {
+			s = separators.Replace(s)
+		}
+	}
+
+	major, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return models.Money{}, fmt.Errorf("số tiền không hợp lệ %q", value)
+	}
+	if negative {
+		major = -major
+	}
+	return models.MoneyFromMajor(major, models.DefaultCurrency), nil
+}
+
+// ReconcileBankStatement đối chiếu các khoản ghi có trong sao kê với đơn hàng chuyển khoản theo mã đơn
+// trong nội dung chuyển khoản. Đủ tiền thì đơn được đánh dấu đã thanh toán; thiếu hoặc thừa thì gắn cờ
+// "partial"/"overpaid" trên payment. Giao dịch đã ghi nhận (cùng mã giao dịch) được bỏ qua nên có thể tải lại file.
+func (ps *PaymentService) ReconcileBankStatement(r io.Reader, actorID string) (*StatementReconciliation, error) {
+	lines, err := ParseBankStatement(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StatementReconciliation{
+		Lines:   make([]StatementLineResult, 0, len(lines)),
+		Summary: map[string]int{},
+	}
+	for _, line := range lines {
+		lineResult := ps.reconcileLine(line, actorID)
+		result.Lines = append(result.Lines, lineResult)
+		result.Summary[lineResult.Result]++
+	}
+	return result, nil
+}
+
+// reconcileLine ghi nhận một khoản ghi có vào đơn hàng tương ứng
+func (ps *PaymentService) reconcileLine(line BankStatementLine, actorID string) StatementLineResult {
+	result := StatementLineResult{BankStatementLine: line}
+	if line.Amount.Amount <= 0 {
+		result.Result, result.Message = StatementLineSkipped, "không phải giao dịch ghi có"
+		return result
+	}
+
+	orderNumber, ok := ps.orderNumbers.Find(line.Description)
+	if !ok {
+		result.Result, result.Message = StatementLineUnmatched, "không tìm thấy mã đơn hàng trong nội dung chuyển khoản"
+		return result
+	}
+	result.OrderNumber = orderNumber
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, err := ps.store.Orders().FindByNumber(ctx, orderNumber)
+	if err != nil {
+		if err == store.ErrNotFound {
+			result.Result, result.Message = StatementLineUnmatched, "đơn hàng không tồn tại"
+		} else {
+			result.Result, result.Message = StatementLineError, "lỗi khi tìm đơn hàng"
+		}
+		return result
+	}
+	if order.Payment.Method != BankTransferMethod {
+		result.Result, result.Message = StatementLineUnmatched, "đơn hàng không thanh toán bằng chuyển khoản"
+		return result
+	}
+	if line.Amount.CurrencyCode() != order.TotalAmount.CurrencyCode() {
+		result.Result, result.Message = StatementLineUnmatched, "loại tiền không khớp với đơn hàng"
+		return result
+	}
+
+	received := order.Payment.ReceivedAmount.Add(line.Amount)
+	flag := ""
+	switch received.Cmp(order.TotalAmount) {
+	case -1:
+		flag = PaymentFlagPartial
+	case 1:
+		flag = PaymentFlagOverpaid
+	}
+
+	credit := models.PaymentCredit{
+		Reference:   line.Reference,
+		Amount:      line.Amount,
+		Description: line.Description,
+		PostedAt:    line.PostedAt,
+		RecordedBy:  actorID,
+		RecordedAt:  time.Now(),
+	}
+	err = ps.store.Orders().AddPaymentCredit(ctx, order.ID, order.Payment.Status, credit, bson.M{
+		"payment.received_amount": received,
+		"payment.flag":            flag,
+	})
+	switch err {
+	case nil:
+	case store.ErrDuplicateCredit:
+		result.Result, result.Message = StatementLineDuplicate, "giao dịch đã được ghi nhận trước đó"
+		return result
+	case store.ErrStatusConflict:
+		result.Result, result.Message = StatementLineError, ErrPaymentConflict.Error()
+		return result
+	default:
+		log.Printf("Error recording bank credit %s for order %s: %v", line.Reference, orderNumber, err)
+		result.Result, result.Message = StatementLineError, "lỗi khi cập nhật thanh toán"
+		return result
+	}
+	result.ReceivedAmount = &received
+
+	payable := order.Payment.Status == PaymentStatusPending || order.Payment.Status == PaymentStatusFailed
+	if payable && flag != PaymentFlagPartial {
+		event := &PaymentEvent{TransactionID: line.Reference, Amount: received}
+		if err := ps.markPaid(ctx, order, BankTransferMethod, event); err != nil {
+			result.Result, result.Message = StatementLineError, "đã ghi nhận tiền nhưng không cập nhật được đơn hàng: "+err.Error()
+			return result
+		}
+	}
+
+	switch flag {
+	case PaymentFlagPartial:
+		result.Result = StatementLinePartial
+		result.Message = fmt.Sprintf("còn thiếu %s", order.TotalAmount.Sub(received))
+	case PaymentFlagOverpaid:
+		result.Result = StatementLineOverpaid
+		result.Message = fmt.Sprintf("thừa %s", received.Sub(order.TotalAmount))
+	default:
+		result.Result = StatementLinePaid
+	}
+	if status := normalizeOrderStatus(order.Status); status == OrderStatusCancelled || status == OrderStatusRefunded {
+		result.Message = strings.TrimPrefix(result.Message+"; đơn hàng đã hủy, cần hoàn tiền cho khách", "; ")
+	}
+	return result
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testBankAccount = BankTransferAccount{
+	BankBIN:       "970436",
+	BankName:      "Vietcombank",
+	AccountNumber: "0011001234567",
+	AccountName:   "CONG TY GP",
+}
+
+func newTestBankTransferService(st store.Store) *PaymentService {
+	ps, _ := newTestPaymentService(st)
+	ps.bankAccount = testBankAccount
+	return ps
+}
+
+// createBankTransferOrder tạo đơn chuyển khoản với mã đơn hợp lệ (200000 VND)
+func createBankTransferOrder(t *testing.T, st store.Store, userID primitive.ObjectID) *models.Order {
+	t.Helper()
+
+	number, err := NewOrderNumberGenerator(st.Counters(), "").Next(context.Background())
+	mustNoError(t, err)
+	return createTestOrder(t, st, userID, func(o *models.Order) {
+		o.OrderNumber = number
+		o.Payment.Method = BankTransferMethod
+	})
+}
+
+func reconcile(t *testing.T, ps *PaymentService, csv string) *StatementReconciliation {
+	t.Helper()
+	result, err := ps.ReconcileBankStatement(strings.NewReader(csv), "admin-1")
+	mustNoError(t, err)
+	return result
+}
+
+func TestParseBankStatement(t *testing.T) {
+	csv := "\xef\xbb\xbfNgày giao dịch;Số tham chiếu;Số tiền ghi có;Nội dung\n" +
+		"15/01/2025;FT001;1.500.000;CK GP2025010100019\n" +
+		"15/01/2025;FT002;\"1,250,000.00\";thanh toan\n" +
+		";;;\n" +
+		"16/01/2025;;-200.000;phi dich vu\n"
+
+	lines, err := ParseBankStatement(strings.NewReader(csv))
+	mustNoError(t, err)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	if lines[0].Reference != "FT001" || lines[0].Amount != models.VND(1500000) || lines[0].Line != 2 || lines[0].PostedAt.Day() != 15 {
+		t.Fatalf("unexpected first line %+v", lines[0])
+	}
+	if lines[1].Amount != models.VND(1250000) {
+		t.Fatalf("expected decimal amount parsed, got %v", lines[1].Amount)
+	}
+	if lines[2].Amount != models.VND(-200000) || !strings.HasPrefix(lines[2].Reference, "row-") {
+		t.Fatalf("expected debit line with generated reference, got %+v", lines[2])
+	}
+
+	// Mã sinh ra ổn định giữa các lần tải lên
+	again, err := ParseBankStatement(strings.NewReader(csv))
+	mustNoError(t, err)
+	if again[2].Reference != lines[2].Reference {
+		t.Fatalf("expected stable generated reference")
+	}
+
+	_, err = ParseBankStatement(strings.NewReader("date,reference,description\n2025-01-15,FT1,GP1\n"))
+	expectError(t, err, "thiếu cột số tiền ghi có")
+	_, err = ParseBankStatement(strings.NewReader("amount,description\nabc,GP1\n"))
+	expectError(t, err, "số tiền không hợp lệ")
+}
+
+func TestPaymentService_BankTransferIntent(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createBankTransferOrder(t, st, user.ID)
+	ps := newTestBankTransferService(st)
+
+	intent, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	mustNoError(t, err)
+	if intent.Provider != BankTransferMethod || intent.BankTransfer == nil {
+		t.Fatalf("expected bank transfer intent, got %+v", intent)
+	}
+	if intent.BankTransfer.Memo != order.OrderNumber || intent.BankTransfer.Amount != order.TotalAmount {
+		t.Fatalf("unexpected instructions %+v", intent.BankTransfer)
+	}
+	fields := parseEMV(t, intent.BankTransfer.QRPayload)
+	if fields["54"] != "200000" || parseEMV(t, fields["62"])["08"] != order.OrderNumber {
+		t.Fatalf("expected amount and order number in QR payload, got %v", fields)
+	}
+
+	ps.bankAccount = BankTransferAccount{}
+	if _, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex()); err != ErrBankTransferNotConfigured {
+		t.Fatalf("expected ErrBankTransferNotConfigured, got %v", err)
+	}
+}
+
+func TestPaymentService_ReconcileExactPayment(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createBankTransferOrder(t, st, user.ID)
+	ps := newTestBankTransferService(st)
+
+	csv := "reference,amount,description\n" +
+		"FT100,200000,MBVCB.123." + strings.ToLower(order.OrderNumber) + ".CT tu NGUYEN VAN A\n" +
+		"FT101,50000,chuyen tien an trua\n"
+	result := reconcile(t, ps, csv)
+
+	if result.Lines[0].Result != StatementLinePaid || result.Lines[0].OrderNumber != order.OrderNumber {
+		t.Fatalf("expected first line paid, got %+v", result.Lines[0])
+	}
+	if result.Lines[1].Result != StatementLineUnmatched {
+		t.Fatalf("expected second line unmatched, got %+v", result.Lines[1])
+	}
+	if result.Summary[StatementLinePaid] != 1 || result.Summary[StatementLineUnmatched] != 1 {
+		t.Fatalf("unexpected summary %v", result.Summary)
+	}
+
+	paid, err := st.Orders().FindByID(context.Background(), order.ID)
+	mustNoError(t, err)
+	if paid.Payment.Status != PaymentStatusPaid || paid.Payment.TransactionID != "FT100" || paid.Status != OrderStatusConfirmed {
+		t.Fatalf("expected paid and confirmed order, got %s %+v", paid.Status, paid.Payment)
+	}
+	if len(paid.Payment.Credits) != 1 || paid.Payment.Credits[0].RecordedBy != "admin-1" || paid.Payment.Flag != "" {
+		t.Fatalf("expected one recorded credit, got %+v", paid.Payment)
+	}
+
+	// Tải lại cùng file không ghi nhận lần nữa
+	again := reconcile(t, ps, csv)
+	if again.Lines[0].Result != StatementLineDuplicate {
+		t.Fatalf("expected duplicate on re-upload, got %+v", again.Lines[0])
+	}
+}
+
+func TestPaymentService_ReconcilePartialThenOverpaid(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createBankTransferOrder(t, st, user.ID)
+	ps := newTestBankTransferService(st)
+
+	result := reconcile(t, ps, "reference,amount,description\nFT200,150000,"+order.OrderNumber+"\n")
+	if result.Lines[0].Result != StatementLinePartial || *result.Lines[0].ReceivedAmount != models.VND(150000) {
+		t.Fatalf("expected partial payment, got %+v", result.Lines[0])
+	}
+
+	partial, err := st.Orders().FindByID(context.Background(), order.ID)
+	mustNoError(t, err)
+	if partial.Payment.Status != PaymentStatusPending || partial.Payment.Flag != PaymentFlagPartial || partial.Status != OrderStatusPending {
+		t.Fatalf("expected pending partial payment, got %s %+v", partial.Status, partial.Payment)
+	}
+
+	// QR cho phần còn thiếu
+	intent, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	mustNoError(t, err)
+	if intent.Amount != models.VND(50000) {
+		t.Fatalf("expected remaining 50000, got %v", intent.Amount)
+	}
+
+	result = reconcile(t, ps, "reference,amount,description\nFT201,80000,"+order.OrderNumber+"\n")
+	if result.Lines[0].Result != StatementLineOverpaid {
+		t.Fatalf("expected overpaid, got %+v", result.Lines[0])
+	}
+
+	overpaid, err := st.Orders().FindByID(context.Background(), order.ID)
+	mustNoError(t, err)
+	if overpaid.Payment.Status != PaymentStatusPaid || overpaid.Payment.Flag != PaymentFlagOverpaid || overpaid.Payment.ReceivedAmount != models.VND(230000) {
+		t.Fatalf("expected paid overpaid payment, got %+v", overpaid.Payment)
+	}
+	if overpaid.Status != OrderStatusConfirmed || len(overpaid.Payment.Credits) != 2 {
+		t.Fatalf("expected confirmed order with two credits, got %s %+v", overpaid.Status, overpaid.Payment)
+	}
+}
+
+func TestPaymentService_ReconcileIgnoresOtherOrders(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	cod := createBankTransferOrder(t, st, user.ID)
+	mustNoError(t, st.Orders().UpdatePayment(context.Background(), cod.ID, PaymentStatusPending, bson.M{
+		"payment.method": "cash_on_delivery",
+	}))
+	ps := newTestBankTransferService(st)
+
+	result := reconcile(t, ps, "reference,amount,description\n"+
+		"FT300,200000,"+cod.OrderNumber+"\n"+
+		"FT301,200000,GP2025010100018\n"+ // sai chữ số kiểm tra
+		"FT302,-200000,"+cod.OrderNumber+"\n")
+
+	want := []string{StatementLineUnmatched, StatementLineUnmatched, StatementLineSkipped}
+	for i, line := range result.Lines {
+		if line.Result != want[i] {
+			t.Fatalf("line %d: expected %s, got %+v", line.Line, want[i], line)
+		}
+	}
+}
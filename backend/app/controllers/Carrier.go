@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+// Trạng thái vận đơn đã chuẩn hóa giữa các carrier (models.Shipment.Status)
+const (
+	ShipmentStatusCreated   = "created"   // Đã tạo, chờ lấy hàng
+	ShipmentStatusPickedUp  = "picked_up" // Carrier đã lấy hàng
+	ShipmentStatusInTransit = "in_transit"
+	ShipmentStatusDelivered = "delivered"
+	ShipmentStatusFailed    = "failed"   // Giao không thành công, carrier sẽ giao lại hoặc hoàn
+	ShipmentStatusReturned  = "returned" // Đã hoàn hàng về shop
+	ShipmentStatusCancelled = "cancelled"
+)
+
+// ErrCarrierRequest được bọc trong lỗi trả về khi không gọi được API của carrier hoặc carrier từ chối yêu cầu
+var ErrCarrierRequest = errors.New("đơn vị vận chuyển từ chối yêu cầu")
+
+// Carrier là một đơn vị vận chuyển có API (GHN, GHTK, Viettel Post...). Code() trùng với
+// code của TrackingCarrier để vận đơn tạo qua API có link tra cứu cho khách.
+type Carrier interface {
+	// Code là mã carrier, được lưu vào shipment.carrier và tracking.carrier của đơn hàng
+	Code() string
+	// QuoteRate báo phí vận chuyển cho kiện hàng
+	QuoteRate(ctx context.Context, req ShipmentRequest) (*CarrierRate, error)
+	// CreateShipment tạo vận đơn, carrier sẽ tới lấy hàng
+	CreateShipment(ctx context.Context, req ShipmentRequest) (*CarrierShipment, error)
+	// PrintLabel trả về tem vận đơn dạng PDF
+	PrintLabel(ctx context.Context, trackingNumber string) ([]byte, error)
+	// FetchStatus lấy trạng thái hiện tại và hành trình của vận đơn
+	FetchStatus(ctx context.Context, trackingNumber string) (*CarrierStatus, error)
+}
+
+// ShipmentRequest là kiện hàng gửi cho carrier
+type ShipmentRequest struct {
+	OrderNumber   string                 `json:"order_number"`
+	Recipient     models.ShippingAddress `json:"recipient"`
+	Items         []ShipmentItem         `json:"items"`
+	WeightGrams   int                    `json:"weight_grams"`
+	CODAmount     models.Money           `json:"cod_amount"`     // Tiền thu hộ, 0 nếu khách đã thanh toán
+	DeclaredValue models.Money           `json:"declared_value"` // Giá trị khai báo để bảo hiểm
+	Note          string                 `json:"note,omitempty"`
+}
+
+// ShipmentItem là một dòng hàng trong kiện
+type ShipmentItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+// CarrierRate là báo giá của carrier
+type CarrierRate struct {
+	Carrier       string       `json:"carrier"`
+	Service       string       `json:"service"`
+	Fee           models.Money `json:"fee"`
+	EstimatedDays int          `json:"estimated_days,omitempty"`
+}
+
+// CarrierShipment là vận đơn carrier vừa tạo
+type CarrierShipment struct {
+	TrackingNumber string       `json:"tracking_number"`
+	Service        string       `json:"service"`
+	Fee            models.Money `json:"fee"`
+}
+
+// CarrierStatus là trạng thái vận đơn, Events theo thứ tự thời gian
+type CarrierStatus struct {
+	TrackingNumber string                 `json:"tracking_number"`
+	Status         string                 `json:"status"`
+	Events         []models.ShipmentEvent `json:"events"`
+}
+
+// CarrierConfig là cấu hình kết nối tới API của một carrier
+type CarrierConfig struct {
+	Code    string `json:"code"`
+	BaseURL string `json:"base_url"`
+	Token   string `json:"token"`
+}
+
+// carriersFromEnv đọc CARRIERS (JSON, vd [{"code":"ghn","base_url":"https://carrier-gateway.local","token":"..."}]).
+// Không cấu hình thì không có carrier nào, admin vẫn nhập vận đơn thủ công được.
+func carriersFromEnv() []Carrier {
+	value := os.Getenv("CARRIERS")
+	if value == "" {
+		return nil
+	}
+
+	var configs []CarrierConfig
+	if err := json.Unmarshal([]byte(value), &configs); err != nil {
+		log.Printf("Invalid CARRIERS: %v", err)
+		return nil
+	}
+	var carriers []Carrier
+	for _, config := range configs {
+		if config.Code == "" || config.BaseURL == "" {
+			log.Printf("Invalid CARRIERS entry %q: code and base_url are required", config.Code)
+			continue
+		}
+		carriers = append(carriers, NewHTTPCarrier(config))
+	}
+	return carriers
+}
+
+// HTTPCarrier gọi API vận chuyển theo hợp đồng JSON chung (MockCarrierServer cài đặt cùng hợp đồng):
+//
+//	POST /v1/rates                      ShipmentRequest → CarrierRate
+//	POST /v1/shipments                  ShipmentRequest → CarrierShipment
+//	GET  /v1/shipments/:tracking/label  → application/pdf
+//	GET  /v1/shipments/:tracking        → CarrierStatus
+//
+// Xác thực bằng header "Token". Carrier có API khác (GHN, GHTK...) được nối qua gateway
+// chuyển đổi sang hợp đồng này, hoặc cài đặt Carrier riêng.
+type HTTPCarrier struct {
+	code    string
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewHTTPCarrier creates a carrier client for config
+func NewHTTPCarrier(config CarrierConfig) *HTTPCarrier {
+	return &HTTPCarrier{
+		code:    strings.ToLower(config.Code),
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		token:   config.Token,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (c *HTTPCarrier) Code() string {
+	return c.code
+}
+
+func (c *HTTPCarrier) QuoteRate(ctx context.Context, req ShipmentRequest) (*CarrierRate, error) {
+	var rate CarrierRate
+	if err := c.doJSON(ctx, http.MethodPost, "/v1/rates", req, &rate); err != nil {
+		return nil, err
+	}
+	rate.Carrier = c.code
+	return &rate, nil
+}
+
+func (c *HTTPCarrier) CreateShipment(ctx context.Context, req ShipmentRequest) (*CarrierShipment, error) {
+	var shipment CarrierShipment
+	if err := c.doJSON(ctx, http.MethodPost, "/v1/shipments", req, &shipment); err != nil {
+		return nil, err
+	}
+	if shipment.TrackingNumber == "" {
+		return nil, fmt.Errorf("%w: thiếu mã vận đơn", ErrCarrierRequest)
+	}
+	return &shipment, nil
+}
+
+func (c *HTTPCarrier) PrintLabel(ctx context.Context, trackingNumber string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/shipments/"+url.PathEscape(trackingNumber)+"/label", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	label, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(label, []byte("%PDF")) {
+		return nil, fmt.Errorf("%w: tem vận đơn không phải PDF", ErrCarrierRequest)
+	}
+	return label, nil
+}
+
+func (c *HTTPCarrier) FetchStatus(ctx context.Context, trackingNumber string) (*CarrierStatus, error) {
+	var status CarrierStatus
+	if err := c.doJSON(ctx, http.MethodGet, "/v1/shipments/"+url.PathEscape(trackingNumber), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *HTTPCarrier) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	resp, err := c.do(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do gửi request và trả về response 2xx; lỗi HTTP được bọc ErrCarrierRequest kèm message của carrier
+func (c *HTTPCarrier) do(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Token", c.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v (%s)", ErrCarrierRequest, err, c.code)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var failure struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&failure)
+		if failure.Message == "" {
+			failure.Message = resp.Status
+		}
+		return nil, fmt.Errorf("%w: %s (%s)", ErrCarrierRequest, failure.Message, c.code)
+	}
+	return resp, nil
+}
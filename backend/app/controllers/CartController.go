@@ -1,13 +1,25 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
-type CartController struct{}
+type CartController struct {
+	cartService *CartService
+}
+
+// NewCartController creates a new cart controller instance
+func NewCartController(st store.Store) *CartController {
+	return &CartController{
+		cartService: NewCartService(st),
+	}
+}
 
 // AddToCartRequest struct for adding items to cart
 type AddToCartRequest struct {
@@ -25,6 +37,33 @@ type RemoveFromCartRequest struct {
 	ProductID string `json:"product_id" binding:"required"`
 }
 
+// ApplyCouponRequest struct for applying a discount code to the cart
+type ApplyCouponRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CreateGuestToken cấp guest token cho khách chưa đăng nhập. Client gửi token trong header
+// X-Guest-Token với các API giỏ hàng và đặt hàng; giỏ hàng của khách gắn với guest id trong token.
+func (cc *CartController) CreateGuestToken(c *gin.Context) {
+	token, _, expiresAt, err := middleware.NewGuestToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data": gin.H{
+			"guest_token": token,
+			"header":      middleware.GuestTokenHeader,
+			"expires_at":  expiresAt,
+		},
+	})
+}
+
 // GetCart lấy giỏ hàng của user
 func (cc *CartController) GetCart(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -42,7 +81,7 @@ func (cc *CartController) GetCart(c *gin.Context) {
 		userRoleStr = userRole.(string)
 	}
 
-	cartService := NewCartService()
+	cartService := cc.cartService
 	result, err := cartService.GetUserCart(userID.(string), userRoleStr)
 	if err != nil {
 		if err.Error() == "admin không có quyền thao tác với giỏ hàng" {
@@ -53,6 +92,10 @@ func (cc *CartController) GetCart(c *gin.Context) {
 			return
 		}
 
+		if respondCartError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Internal server error",
@@ -66,11 +109,14 @@ func (cc *CartController) GetCart(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"data":         result.Items,
-		"count":        result.TotalItems,
-		"total_amount": result.TotalAmount,
-		"message":      message,
+		"success":         true,
+		"data":            result.Items,
+		"count":           result.TotalItems,
+		"total_amount":    result.TotalAmount,
+		"discount_amount": result.DiscountAmount,
+		"promotions":      result.Promotions,
+		"coupon":          result.Coupon,
+		"message":         message,
 	})
 }
 
@@ -100,7 +146,7 @@ func (cc *CartController) AddToCart(c *gin.Context) {
 		userRoleStr = userRole.(string)
 	}
 
-	cartService := NewCartService()
+	cartService := cc.cartService
 	result, err := cartService.AddToCart(userID.(string), userRoleStr, req.ProductID, req.Quantity)
 	if err != nil {
 		// Handle specific error types
@@ -124,6 +170,10 @@ func (cc *CartController) AddToCart(c *gin.Context) {
 			return
 		}
 
+		if respondCartError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Internal server error",
@@ -132,11 +182,13 @@ func (cc *CartController) AddToCart(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"message":      "Đã thêm vào giỏ hàng",
-		"data":         result.Items,
-		"count":        result.TotalItems,
-		"total_amount": result.TotalAmount,
+		"success":         true,
+		"message":         "Đã thêm vào giỏ hàng",
+		"data":            result.Items,
+		"count":           result.TotalItems,
+		"total_amount":    result.TotalAmount,
+		"discount_amount": result.DiscountAmount,
+		"promotions":      result.Promotions,
 	})
 }
 
@@ -167,7 +219,7 @@ func (cc *CartController) UpdateCartItem(c *gin.Context) {
 		userRoleStr = userRole.(string)
 	}
 
-	cartService := NewCartService()
+	cartService := cc.cartService
 	result, err := cartService.UpdateCartItem(userID.(string), userRoleStr, productID, req.Quantity)
 	if err != nil {
 		// Handle specific error types
@@ -191,6 +243,10 @@ func (cc *CartController) UpdateCartItem(c *gin.Context) {
 			return
 		}
 
+		if respondCartError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Internal server error",
@@ -199,11 +255,13 @@ func (cc *CartController) UpdateCartItem(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"message":      "Đã cập nhật số lượng",
-		"data":         result.Items,
-		"count":        result.TotalItems,
-		"total_amount": result.TotalAmount,
+		"success":         true,
+		"message":         "Đã cập nhật số lượng",
+		"data":            result.Items,
+		"count":           result.TotalItems,
+		"total_amount":    result.TotalAmount,
+		"discount_amount": result.DiscountAmount,
+		"promotions":      result.Promotions,
 	})
 }
 
@@ -241,7 +299,7 @@ func (cc *CartController) RemoveFromCart(c *gin.Context) {
 		userRoleStr = userRole.(string)
 	}
 
-	cartService := NewCartService()
+	cartService := cc.cartService
 	result, err := cartService.RemoveFromCart(userID.(string), userRoleStr, productID)
 	if err != nil {
 		// Handle specific error types
@@ -263,6 +321,10 @@ func (cc *CartController) RemoveFromCart(c *gin.Context) {
 			return
 		}
 
+		if respondCartError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Internal server error",
@@ -271,11 +333,13 @@ func (cc *CartController) RemoveFromCart(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"message":      "Đã xóa sản phẩm khỏi giỏ hàng",
-		"data":         result.Items,
-		"count":        result.TotalItems,
-		"total_amount": result.TotalAmount,
+		"success":         true,
+		"message":         "Đã xóa sản phẩm khỏi giỏ hàng",
+		"data":            result.Items,
+		"count":           result.TotalItems,
+		"total_amount":    result.TotalAmount,
+		"discount_amount": result.DiscountAmount,
+		"promotions":      result.Promotions,
 	})
 }
 
@@ -296,7 +360,7 @@ func (cc *CartController) ClearCart(c *gin.Context) {
 		userRoleStr = userRole.(string)
 	}
 
-	cartService := NewCartService()
+	cartService := cc.cartService
 	result, err := cartService.ClearCart(userID.(string), userRoleStr)
 	if err != nil {
 		// Handle specific error types
@@ -323,3 +387,137 @@ func (cc *CartController) ClearCart(c *gin.Context) {
 		"total_amount": result.TotalAmount,
 	})
 }
+
+// ApplyCoupon áp dụng mã giảm giá cho giỏ hàng
+func (cc *CartController) ApplyCoupon(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	var req ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ: " + err.Error(),
+		})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	userRoleStr := ""
+	if userRole != nil {
+		userRoleStr = userRole.(string)
+	}
+
+	result, err := cc.cartService.ApplyCoupon(userID.(string), userRoleStr, req.Code)
+	if err != nil {
+		if err.Error() == "admin không có quyền thao tác với giỏ hàng" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		// Client errors (400)
+		var couponErr *CouponError
+		if errors.As(err, &couponErr) ||
+			err.Error() == "mã giảm giá là bắt buộc" ||
+			err.Error() == "giỏ hàng trống" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if respondCartError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"message":         "Đã áp dụng mã giảm giá",
+		"data":            result.Items,
+		"count":           result.TotalItems,
+		"total_amount":    result.TotalAmount,
+		"discount_amount": result.DiscountAmount,
+		"promotions":      result.Promotions,
+		"coupon":          result.Coupon,
+	})
+}
+
+// RemoveCoupon bỏ mã giảm giá khỏi giỏ hàng
+func (cc *CartController) RemoveCoupon(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	userRoleStr := ""
+	if userRole != nil {
+		userRoleStr = userRole.(string)
+	}
+
+	result, err := cc.cartService.RemoveCoupon(userID.(string), userRoleStr)
+	if err != nil {
+		if err.Error() == "admin không có quyền thao tác với giỏ hàng" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if respondCartError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"message":         "Đã bỏ mã giảm giá",
+		"data":            result.Items,
+		"count":           result.TotalItems,
+		"total_amount":    result.TotalAmount,
+		"discount_amount": result.DiscountAmount,
+		"promotions":      result.Promotions,
+	})
+}
+
+// respondCartError trả về 400 cho lỗi giỏ hàng chung của các handler (sản phẩm không định giá bằng VND).
+// Trả về false nếu err không thuộc loại này để handler tự xử lý.
+func respondCartError(c *gin.Context, err error) bool {
+	var currencyErr *CurrencyError
+	if !errors.As(err, &currencyErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+	return true
+}
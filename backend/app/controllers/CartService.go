@@ -6,19 +6,25 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-type CartService struct{}
+type CartService struct {
+	store      store.Store
+	coupons    *CouponService
+	promotions *PromotionService
+	pricing    *PricingService
+}
 
 type CartResult struct {
-	Items       []models.CartItem `json:"items"`
-	TotalAmount float64           `json:"total_amount"`
-	TotalItems  int               `json:"total_items"`
+	Items          []models.CartItem         `json:"items"`
+	TotalAmount    models.Money              `json:"total_amount"`
+	TotalItems     int                       `json:"total_items"`
+	DiscountAmount models.Money              `json:"discount_amount"`
+	Promotions     []models.AppliedPromotion `json:"promotions,omitempty"`
+	Coupon         *CouponDiscount           `json:"coupon,omitempty"`
 }
 
 type StockValidationItem struct {
@@ -59,29 +65,46 @@ func (cs *CartService) ValidateProduct(productID string, requestedQuantity int)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
-	var product models.Product
-	var filter bson.M
+	var product *models.Product
+	var err error
 
 	// Try to find by ObjectID first, then by string ID
-	if objectID, err := primitive.ObjectIDFromHex(productID); err == nil {
-		filter = bson.M{"_id": objectID}
+	if objectID, parseErr := primitive.ObjectIDFromHex(productID); parseErr == nil {
+		product, err = cs.store.Products().FindByObjectID(ctx, objectID)
 	} else {
-		filter = bson.M{"id": productID}
+		product, err = cs.store.Products().FindByProductID(ctx, productID)
 	}
-
-	err := collection.FindOne(ctx, filter).Decode(&product)
 	if err != nil {
 		return nil, errors.New("sản phẩm không tồn tại")
 	}
 
+	if err := checkPriceCurrency(product.Name, product.Price); err != nil {
+		return nil, err
+	}
 	if product.Amount < requestedQuantity {
 		return nil, fmt.Errorf("sản phẩm chỉ còn %d trong kho", product.Amount)
 	}
 
-	return &product, nil
+	return product, nil
+}
+
+// CurrencyError được trả về khi giỏ hàng có sản phẩm không định giá bằng DefaultCurrency
+type CurrencyError struct {
+	ProductName string
+	Currency    string
+}
+
+func (e *CurrencyError) Error() string {
+	return fmt.Sprintf("sản phẩm %s được định giá bằng %s, cửa hàng chỉ bán bằng %s", e.ProductName, e.Currency, models.DefaultCurrency)
+}
+
+// checkPriceCurrency: phí giao hàng, mã giảm giá và thuế đều tính bằng DefaultCurrency nên chỉ bán được
+// sản phẩm định giá bằng DefaultCurrency (giá sản phẩm đã được kiểm tra khi tạo/sửa, còn lại dữ liệu cũ)
+func checkPriceCurrency(productName string, price models.Money) error {
+	if price.CurrencyCode() != models.DefaultCurrency {
+		return &CurrencyError{ProductName: productName, Currency: price.CurrencyCode()}
+	}
+	return nil
 }
 
 // FindOrCreateCart tìm hoặc tạo giỏ hàng mới
@@ -94,23 +117,15 @@ func (cs *CartService) FindOrCreateCart(userID string) (*models.Cart, error) {
 		return nil, errors.New("invalid user ID")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("Carts")
-
-	var cart models.Cart
-	err = collection.FindOne(ctx, bson.M{
-		"user_id":   userObjectID,
-		"cart_type": "cart",
-	}).Decode(&cart)
-
-	if err == mongo.ErrNoDocuments {
+	cart, err := cs.store.Carts().FindByUser(ctx, userObjectID, "cart")
+	if err == store.ErrNotFound {
 		// Create new cart
-		cart = models.Cart{
+		cart = &models.Cart{
 			UserID:      userObjectID,
 			CartType:    "cart",
 			Items:       []models.CartItem{},
 			TotalItems:  0,
-			TotalAmount: 0,
+			TotalAmount: models.Money{},
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		}
@@ -118,21 +133,89 @@ func (cs *CartService) FindOrCreateCart(userID string) (*models.Cart, error) {
 		return nil, err
 	}
 
-	return &cart, nil
+	return cart, nil
 }
 
-// CalculateCartTotals tính toán lại tổng tiền và số lượng
-func (cs *CartService) CalculateCartTotals(cart *models.Cart) {
+// CalculateCartTotals tính toán lại tổng tiền, số lượng và khuyến mãi tự động.
+// TotalAmount là tổng tiền hàng đã trừ khuyến mãi (chưa trừ mã giảm giá).
+// Trả về lỗi nếu giỏ hàng có sản phẩm không định giá bằng DefaultCurrency.
+func (cs *CartService) CalculateCartTotals(cart *models.Cart) error {
+	for _, item := range cart.Items {
+		if err := checkPriceCurrency(item.ProductName, item.Price); err != nil {
+			return err
+		}
+		if err := checkPriceCurrency(item.ProductName, item.ListPrice); err != nil {
+			return err
+		}
+	}
+
 	// Calculate total for each item first
+	lines := make([]PromotionLine, len(cart.Items))
 	for i := range cart.Items {
-		cart.Items[i].Total = cart.Items[i].Price * float64(cart.Items[i].Quantity)
+		item := &cart.Items[i]
+		item.Total = item.Price.Mul(item.Quantity)
+		lines[i] = PromotionLine{ProductID: item.ProductID, Price: item.Price, Quantity: item.Quantity}
+	}
+
+	// Chỉ tra danh mục sản phẩm khi có khuyến mãi theo danh mục
+	if cs.promotions.NeedsCategories() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for i := range lines {
+			if product, err := cs.store.Products().FindByProductID(ctx, lines[i].ProductID); err == nil {
+				lines[i].Category = product.Category
+			}
+		}
 	}
 
-	cart.TotalAmount = 0
+	promotions := cs.promotions.Apply(lines, time.Now())
+
+	cart.TotalAmount = models.Money{}
 	cart.TotalItems = 0
-	for _, item := range cart.Items {
-		cart.TotalAmount += item.Total
-		cart.TotalItems += item.Quantity
+	for i := range cart.Items {
+		cart.Items[i].Discount = promotions.LineDiscounts[i]
+		cart.Items[i].Promotions = promotions.LinePromotions[i]
+		cart.TotalAmount = cart.TotalAmount.Add(cart.Items[i].Total)
+		cart.TotalItems += cart.Items[i].Quantity
+	}
+	cart.DiscountAmount = promotions.Discount
+	cart.Promotions = promotions.Applied
+	cart.TotalAmount = cart.TotalAmount.Sub(promotions.Discount)
+	return nil
+}
+
+// cartResult tạo CartResult từ giỏ hàng đã tính tổng
+func cartResult(cart *models.Cart) *CartResult {
+	return &CartResult{
+		Items:          cart.Items,
+		TotalAmount:    cart.TotalAmount,
+		TotalItems:     cart.TotalItems,
+		DiscountAmount: cart.DiscountAmount,
+		Promotions:     cart.Promotions,
+	}
+}
+
+// refreshPrices cập nhật giá hiệu lực (sale_price, flash sale theo số lượng và lượt mua của user)
+// cho từng sản phẩm trong giỏ tại thời điểm hiện tại. Sản phẩm không còn tồn tại giữ nguyên giá cũ.
+func (cs *CartService) refreshPrices(cart *models.Cart, userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		product, err := cs.store.Products().FindByProductID(ctx, item.ProductID)
+		if err != nil {
+			continue
+		}
+
+		price, sale := cs.pricing.Resolve(ctx, product, userID, item.Quantity, now)
+		item.Price = price
+		item.ListPrice = product.Price
+		item.FlashSaleID = ""
+		if sale != nil {
+			item.FlashSaleID = sale.FlashSaleID
+		}
 	}
 }
 
@@ -151,25 +234,9 @@ func (cs *CartService) SaveCart(cart *models.Cart) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Carts")
-
 	cart.UpdatedAt = time.Now()
 
-	if cart.ID.IsZero() {
-		result, err := collection.InsertOne(ctx, cart)
-		if err != nil {
-			return err
-		}
-		cart.ID = result.InsertedID.(primitive.ObjectID)
-	} else {
-		_, err := collection.ReplaceOne(ctx, bson.M{"_id": cart.ID}, cart)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return cs.store.Carts().Save(ctx, cart)
 }
 
 // GetUserCart lấy giỏ hàng của user
@@ -184,11 +251,122 @@ func (cs *CartService) GetUserCart(userID, userRole string) (*CartResult, error)
 		return nil, err
 	}
 
-	return &CartResult{
-		Items:       cart.Items,
-		TotalAmount: cart.TotalAmount,
-		TotalItems:  cart.TotalItems,
-	}, nil
+	// Tính lại vì giá khuyến mãi, flash sale có thể đã bắt đầu hoặc hết hạn kể từ lần lưu trước
+	cs.refreshPrices(cart, userID)
+	if err := cs.CalculateCartTotals(cart); err != nil {
+		return nil, err
+	}
+
+	result := cartResult(cart)
+	result.Coupon = cs.cartCoupon(cart, userID)
+	return result, nil
+}
+
+// ApplyCoupon kiểm tra mã giảm giá với giỏ hàng hiện tại và lưu mã vào giỏ hàng.
+// Lượt dùng chỉ bị trừ khi đặt hàng thành công.
+func (cs *CartService) ApplyCoupon(userID, userRole, code string) (*CartResult, error) {
+	// Kiểm tra quyền admin
+	if err := cs.ValidateUserRole(userRole); err != nil {
+		return nil, err
+	}
+
+	if normalizeCouponCode(code) == "" {
+		return nil, errors.New("mã giảm giá là bắt buộc")
+	}
+
+	cart, err := cs.FindOrCreateCart(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cart.Items) == 0 {
+		return nil, errors.New("giỏ hàng trống")
+	}
+	cs.refreshPrices(cart, userID)
+	if err := cs.CalculateCartTotals(cart); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	discount, err := cs.coupons.Evaluate(ctx, code, userID, cs.couponLines(ctx, cart), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	cart.CouponCode = discount.Code
+	if err := cs.SaveCart(cart); err != nil {
+		return nil, err
+	}
+
+	result := cartResult(cart)
+	result.Coupon = discount
+	return result, nil
+}
+
+// RemoveCoupon bỏ mã giảm giá khỏi giỏ hàng
+func (cs *CartService) RemoveCoupon(userID, userRole string) (*CartResult, error) {
+	// Kiểm tra quyền admin
+	if err := cs.ValidateUserRole(userRole); err != nil {
+		return nil, err
+	}
+
+	cart, err := cs.FindOrCreateCart(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cart.CouponCode != "" {
+		cart.CouponCode = ""
+		if err := cs.SaveCart(cart); err != nil {
+			return nil, err
+		}
+	}
+	cs.refreshPrices(cart, userID)
+	if err := cs.CalculateCartTotals(cart); err != nil {
+		return nil, err
+	}
+
+	return cartResult(cart), nil
+}
+
+// cartCoupon tính lại mã giảm giá đang lưu trong giỏ hàng. Mã không còn áp dụng được
+// (hết hạn, chưa đủ giá trị tối thiểu...) vẫn được trả về kèm lý do để client hiển thị.
+func (cs *CartService) cartCoupon(cart *models.Cart, userID string) *CouponDiscount {
+	if cart.CouponCode == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	discount, err := cs.coupons.Evaluate(ctx, cart.CouponCode, userID, cs.couponLines(ctx, cart), time.Now())
+	if err != nil {
+		return &CouponDiscount{
+			Code:    cart.CouponCode,
+			Amount:  models.Money{Currency: cart.TotalAmount.Currency},
+			Message: err.Error(),
+		}
+	}
+	return discount
+}
+
+// couponLines chuyển cart items sang CouponLine (tiền hàng sau khuyến mãi tự động), lấy category từ sản phẩm
+func (cs *CartService) couponLines(ctx context.Context, cart *models.Cart) []CouponLine {
+	lines := make([]CouponLine, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		category := ""
+		if product, err := cs.store.Products().FindByProductID(ctx, item.ProductID); err == nil {
+			category = product.Category
+		}
+		lines = append(lines, CouponLine{
+			ProductID: item.ProductID,
+			Category:  category,
+			Total:     item.Price.Mul(item.Quantity).Sub(item.Discount),
+		})
+	}
+	return lines
 }
 
 // AddToCart thêm sản phẩm vào giỏ hàng
@@ -226,7 +404,7 @@ func (cs *CartService) AddToCart(userID, userRole, productID string, quantity in
 		}
 
 		existingItem.Quantity = newQuantity
-		existingItem.Total = existingItem.Price * float64(newQuantity)
+		existingItem.Total = existingItem.Price.Mul(newQuantity)
 	} else {
 		// Thêm sản phẩm mới
 		newItem := models.CartItem{
@@ -235,26 +413,26 @@ func (cs *CartService) AddToCart(userID, userRole, productID string, quantity in
 			ProductImage: product.Image,
 			ProductSlug:  product.Slug,
 			Price:        product.Price,
+			ListPrice:    product.Price,
 			Quantity:     quantity,
-			Total:        product.Price * float64(quantity),
+			Total:        product.Price.Mul(quantity),
 		}
 
 		cart.Items = append(cart.Items, newItem)
 	}
 
-	// Tính toán lại tổng
-	cs.CalculateCartTotals(cart)
+	// Cập nhật giá hiệu lực và tính toán lại tổng
+	cs.refreshPrices(cart, userID)
+	if err := cs.CalculateCartTotals(cart); err != nil {
+		return nil, err
+	}
 
 	// Lưu cart
 	if err := cs.SaveCart(cart); err != nil {
 		return nil, err
 	}
 
-	return &CartResult{
-		Items:       cart.Items,
-		TotalAmount: cart.TotalAmount,
-		TotalItems:  cart.TotalItems,
-	}, nil
+	return cartResult(cart), nil
 }
 
 // UpdateCartItem cập nhật số lượng sản phẩm trong giỏ hàng
@@ -290,21 +468,20 @@ func (cs *CartService) UpdateCartItem(userID, userRole, productID string, quanti
 	}
 
 	existingItem.Quantity = quantity
-	existingItem.Total = existingItem.Price * float64(quantity)
+	existingItem.Total = existingItem.Price.Mul(quantity)
 
-	// Tính toán lại tổng
-	cs.CalculateCartTotals(cart)
+	// Cập nhật giá hiệu lực và tính toán lại tổng
+	cs.refreshPrices(cart, userID)
+	if err := cs.CalculateCartTotals(cart); err != nil {
+		return nil, err
+	}
 
 	// Lưu cart
 	if err := cs.SaveCart(cart); err != nil {
 		return nil, err
 	}
 
-	return &CartResult{
-		Items:       cart.Items,
-		TotalAmount: cart.TotalAmount,
-		TotalItems:  cart.TotalItems,
-	}, nil
+	return cartResult(cart), nil
 }
 
 // RemoveFromCart xóa sản phẩm khỏi giỏ hàng
@@ -336,19 +513,18 @@ func (cs *CartService) RemoveFromCart(userID, userRole, productID string) (*Cart
 		return nil, errors.New("sản phẩm không có trong giỏ hàng")
 	}
 
-	// Tính toán lại tổng
-	cs.CalculateCartTotals(cart)
+	// Cập nhật giá hiệu lực và tính toán lại tổng
+	cs.refreshPrices(cart, userID)
+	if err := cs.CalculateCartTotals(cart); err != nil {
+		return nil, err
+	}
 
 	// Lưu cart
 	if err := cs.SaveCart(cart); err != nil {
 		return nil, err
 	}
 
-	return &CartResult{
-		Items:       cart.Items,
-		TotalAmount: cart.TotalAmount,
-		TotalItems:  cart.TotalItems,
-	}, nil
+	return cartResult(cart), nil
 }
 
 // ClearCart xóa toàn bộ giỏ hàng
@@ -366,32 +542,13 @@ func (cs *CartService) ClearCart(userID, userRole string) (*CartResult, error) {
 		return nil, errors.New("invalid user ID")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("Carts")
-
-	err = collection.FindOneAndUpdate(
-		ctx,
-		bson.M{
-			"user_id":   userObjectID,
-			"cart_type": "cart",
-		},
-		bson.M{
-			"$set": bson.M{
-				"items":        []models.CartItem{},
-				"total_amount": 0,
-				"total_items":  0,
-				"updatedAt":    time.Now(),
-			},
-		},
-	).Decode(&models.Cart{})
-
-	if err != nil && err != mongo.ErrNoDocuments {
+	if err := cs.store.Carts().Clear(ctx, userObjectID, "cart"); err != nil {
 		return nil, err
 	}
 
 	return &CartResult{
 		Items:       []models.CartItem{},
-		TotalAmount: 0,
+		TotalAmount: models.Money{},
 		TotalItems:  0,
 	}, nil
 }
@@ -450,6 +607,11 @@ func (cs *CartService) ValidateCartStock(userID string) (*StockValidationResult,
 }
 
 // NewCartService creates a new instance of CartService
-func NewCartService() *CartService {
-	return &CartService{}
+func NewCartService(st store.Store) *CartService {
+	return &CartService{
+		store:      st,
+		coupons:    NewCouponService(st),
+		promotions: NewPromotionService(promotionsFromEnv()),
+		pricing:    NewPricingService(st),
+	}
 }
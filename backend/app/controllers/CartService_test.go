@@ -0,0 +1,593 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCartService_ValidateUserRole(t *testing.T) {
+	cs := NewCartService(newTestStore())
+
+	expectError(t, cs.ValidateUserRole("admin"), "admin không có quyền thao tác với giỏ hàng")
+	mustNoError(t, cs.ValidateUserRole("user"))
+}
+
+func TestCartService_GetUserCart(t *testing.T) {
+	t.Run("trả về giỏ hàng rỗng khi user chưa có giỏ hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+
+		result, err := cs.GetUserCart(user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if len(result.Items) != 0 || result.TotalItems != 0 || result.TotalAmount.Amount != 0 {
+			t.Fatalf("expected empty cart, got %+v", result)
+		}
+	})
+
+	t.Run("trả về giỏ hàng có sẵn", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st, func(p *models.Product) { p.Price = models.VND(50000) })
+		p2 := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(p1, 1), cartItemFor(p2, 2)})
+
+		result, err := cs.GetUserCart(user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if len(result.Items) != 2 || result.TotalItems != 3 || result.TotalAmount.Amount != 250000 {
+			t.Fatalf("unexpected cart: %+v", result)
+		}
+	})
+
+	t.Run("admin không được xem giỏ hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		admin := createTestAdmin(t, st)
+
+		_, err := cs.GetUserCart(admin.ID.Hex(), "admin")
+		expectError(t, err, "admin không có quyền thao tác với giỏ hàng")
+	})
+}
+
+func TestCartService_CalculateCartTotals(t *testing.T) {
+	cs := NewCartService(newTestStore())
+	cart := &models.Cart{Items: []models.CartItem{
+		{ProductID: "P1", Price: models.VND(33333), ListPrice: models.VND(33333), Quantity: 3},
+		{ProductID: "P2", Price: models.VND(20000), ListPrice: models.VND(20000), Quantity: 1},
+	}}
+
+	mustNoError(t, cs.CalculateCartTotals(cart))
+
+	if cart.Items[0].Total != models.VND(99999) {
+		t.Fatalf("expected item total 99999 VND, got %v", cart.Items[0].Total)
+	}
+	if cart.TotalAmount != models.VND(119999) || cart.TotalItems != 4 {
+		t.Fatalf("expected 119999 VND/4, got %v/%d", cart.TotalAmount, cart.TotalItems)
+	}
+}
+
+func TestCartService_MixedCurrencyCart(t *testing.T) {
+	st := newTestStore()
+	cs := NewCartService(st)
+	os := NewOrderService(st)
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	// Dữ liệu cũ: sản phẩm định giá bằng USD được ghi thẳng vào database
+	imported := createTestProduct(t, st, func(p *models.Product) {
+		p.Name = "Bánh nhập khẩu"
+		p.Price = models.NewMoney(1250, "USD")
+	})
+
+	_, err := cs.AddToCart(user.ID.Hex(), "customer", product.ProductID, 1)
+	mustNoError(t, err)
+	_, err = cs.AddToCart(user.ID.Hex(), "customer", imported.ProductID, 1)
+	expectError(t, err, "sản phẩm Bánh nhập khẩu được định giá bằng USD, cửa hàng chỉ bán bằng VND")
+
+	// Giỏ hàng đã lẫn sản phẩm USD: báo lỗi thay vì panic khi tính tổng, mã giảm giá, thuế
+	cart, err := st.Carts().FindByUser(context.Background(), user.ID, "cart")
+	mustNoError(t, err)
+	cart.Items = append(cart.Items, cartItemFor(imported, 1))
+	cart.CouponCode = "SALE10"
+	mustNoError(t, st.Carts().Save(context.Background(), cart))
+	createTestCoupon(t, st)
+
+	_, err = cs.GetUserCart(user.ID.Hex(), "customer")
+	expectError(t, err, "được định giá bằng USD")
+	_, err = cs.ApplyCoupon(user.ID.Hex(), "customer", "SALE10")
+	expectError(t, err, "được định giá bằng USD")
+	_, err = os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+	expectError(t, err, "được định giá bằng USD")
+}
+
+func TestCartService_CalculateCartTotals_Promotions(t *testing.T) {
+	t.Setenv("PROMOTIONS", `[{"id":"b2g1","name":"Mua 2 tặng 1","type":"buy_x_get_y","buy_quantity":2,"get_quantity":1}]`)
+	st := newTestStore()
+	cs := NewCartService(st)
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+
+	result, err := cs.AddToCart(user.ID.Hex(), "customer", product.ProductID, 3)
+	mustNoError(t, err)
+
+	// Tổng 300000, tặng 1 sản phẩm
+	item := result.Items[0]
+	if item.Total.Amount != 300000 || item.Discount.Amount != 100000 || len(item.Promotions) != 1 || item.Promotions[0].ID != "b2g1" {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+	if result.TotalAmount.Amount != 200000 || result.DiscountAmount.Amount != 100000 || len(result.Promotions) != 1 {
+		t.Fatalf("unexpected totals: %v %v %+v", result.TotalAmount, result.DiscountAmount, result.Promotions)
+	}
+
+	// Giảm số lượng thì không còn khuyến mãi
+	result, err = cs.UpdateCartItem(user.ID.Hex(), "customer", product.ProductID, 2)
+	mustNoError(t, err)
+	if result.TotalAmount.Amount != 200000 || !result.DiscountAmount.IsZero() || len(result.Promotions) != 0 || len(result.Items[0].Promotions) != 0 {
+		t.Fatalf("expected promotion to be removed, got %+v", result)
+	}
+}
+
+func TestCartService_AddToCart(t *testing.T) {
+	t.Run("thêm sản phẩm vào giỏ hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		result, err := cs.AddToCart(user.ID.Hex(), "user", product.ProductID, 1)
+		mustNoError(t, err)
+		if len(result.Items) != 1 || result.TotalItems != 1 {
+			t.Fatalf("unexpected cart: %+v", result)
+		}
+		if result.Items[0].ProductID != product.ProductID || result.Items[0].ProductName != product.Name {
+			t.Fatalf("unexpected item: %+v", result.Items[0])
+		}
+	})
+
+	t.Run("thêm nhiều sản phẩm khác nhau", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st)
+
+		_, err := cs.AddToCart(user.ID.Hex(), "user", p1.ProductID, 1)
+		mustNoError(t, err)
+		result, err := cs.AddToCart(user.ID.Hex(), "user", p2.ProductID, 1)
+		mustNoError(t, err)
+		if len(result.Items) != 2 || result.TotalItems != 2 {
+			t.Fatalf("unexpected cart: %+v", result)
+		}
+	})
+
+	t.Run("tăng số lượng khi sản phẩm đã có trong giỏ", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		_, err := cs.AddToCart(user.ID.Hex(), "user", product.ProductID, 1)
+		mustNoError(t, err)
+		result, err := cs.AddToCart(user.ID.Hex(), "user", product.ProductID, 2)
+		mustNoError(t, err)
+		if len(result.Items) != 1 || result.TotalItems != 3 || result.Items[0].Quantity != 3 {
+			t.Fatalf("unexpected cart: %+v", result)
+		}
+	})
+
+	t.Run("thêm bằng ObjectID của sản phẩm", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		result, err := cs.AddToCart(user.ID.Hex(), "user", product.ID.Hex(), 1)
+		mustNoError(t, err)
+		if result.Items[0].ProductID != product.ProductID {
+			t.Fatalf("expected product id %s, got %s", product.ProductID, result.Items[0].ProductID)
+		}
+	})
+
+	t.Run("lỗi khi thiếu product_id", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+
+		_, err := cs.AddToCart(user.ID.Hex(), "user", "", 1)
+		expectError(t, err, "product_id là bắt buộc")
+	})
+
+	t.Run("lỗi khi sản phẩm không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+
+		_, err := cs.AddToCart(user.ID.Hex(), "user", primitive.NewObjectID().Hex(), 1)
+		expectError(t, err, "sản phẩm không tồn tại")
+	})
+
+	t.Run("lỗi khi số lượng không hợp lệ", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		_, err := cs.AddToCart(user.ID.Hex(), "user", product.ProductID, 0)
+		expectError(t, err, "số lượng phải lớn hơn 0")
+		_, err = cs.AddToCart(user.ID.Hex(), "user", product.ProductID, -1)
+		expectError(t, err, "số lượng phải lớn hơn 0")
+	})
+
+	t.Run("lỗi khi vượt quá tồn kho", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Amount = 2 })
+
+		_, err := cs.AddToCart(user.ID.Hex(), "user", product.ProductID, 3)
+		expectError(t, err, "sản phẩm chỉ còn 2 trong kho")
+	})
+
+	t.Run("lỗi khi tổng số lượng vượt quá tồn kho", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Amount = 3 })
+
+		_, err := cs.AddToCart(user.ID.Hex(), "user", product.ProductID, 2)
+		mustNoError(t, err)
+		_, err = cs.AddToCart(user.ID.Hex(), "user", product.ProductID, 2)
+		expectError(t, err, "sản phẩm chỉ còn 3 trong kho")
+	})
+}
+
+func TestCartService_AddToCart_FlashSale(t *testing.T) {
+	st := newTestStore()
+	cs := NewCartService(st)
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	sale := createTestFlashSale(t, st, product)
+
+	result, err := cs.AddToCart(user.ID.Hex(), "customer", product.ProductID, 2)
+	mustNoError(t, err)
+	item := result.Items[0]
+	if item.Price.Amount != 60000 || item.ListPrice.Amount != 100000 || item.FlashSaleID != sale.ID.Hex() || result.TotalAmount.Amount != 120000 {
+		t.Fatalf("expected flash sale price, got %+v", item)
+	}
+
+	// Vượt giới hạn mỗi khách: cả dòng hàng quay về giá thường
+	result, err = cs.AddToCart(user.ID.Hex(), "customer", product.ProductID, 1)
+	mustNoError(t, err)
+	item = result.Items[0]
+	if item.Price.Amount != 100000 || item.FlashSaleID != "" || result.TotalAmount.Amount != 300000 {
+		t.Fatalf("expected regular price over the limit, got %+v", item)
+	}
+}
+
+func TestCartService_UpdateCartItem(t *testing.T) {
+	t.Run("cập nhật số lượng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+		result, err := cs.UpdateCartItem(user.ID.Hex(), "user", product.ProductID, 3)
+		mustNoError(t, err)
+		if result.Items[0].Quantity != 3 || result.TotalItems != 3 {
+			t.Fatalf("unexpected cart: %+v", result)
+		}
+	})
+
+	t.Run("lỗi khi giỏ hàng không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		_, err := cs.UpdateCartItem(user.ID.Hex(), "user", product.ProductID, 1)
+		expectError(t, err, "giỏ hàng không tồn tại")
+	})
+
+	t.Run("lỗi khi sản phẩm không có trong giỏ hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(p1, 1)})
+
+		_, err := cs.UpdateCartItem(user.ID.Hex(), "user", p2.ProductID, 1)
+		expectError(t, err, "sản phẩm không có trong giỏ hàng")
+	})
+
+	t.Run("lỗi khi số lượng bằng 0", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+		_, err := cs.UpdateCartItem(user.ID.Hex(), "user", product.ProductID, 0)
+		expectError(t, err, "số lượng phải lớn hơn 0")
+	})
+
+	t.Run("lỗi khi vượt quá tồn kho", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Amount = 2 })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+		_, err := cs.UpdateCartItem(user.ID.Hex(), "user", product.ProductID, 5)
+		expectError(t, err, "sản phẩm chỉ còn 2 trong kho")
+	})
+
+	t.Run("tính lại tổng tiền", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st, func(p *models.Product) { p.Price = models.VND(200000) })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(p1, 1), cartItemFor(p2, 1)})
+
+		result, err := cs.UpdateCartItem(user.ID.Hex(), "user", p1.ProductID, 3)
+		mustNoError(t, err)
+		if result.TotalAmount.Amount != 500000 || result.TotalItems != 4 {
+			t.Fatalf("expected 500000/4, got %v/%d", result.TotalAmount, result.TotalItems)
+		}
+	})
+}
+
+func TestCartService_RemoveFromCart(t *testing.T) {
+	t.Run("xóa sản phẩm duy nhất", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+
+		result, err := cs.RemoveFromCart(user.ID.Hex(), "user", product.ProductID)
+		mustNoError(t, err)
+		if len(result.Items) != 0 || result.TotalItems != 0 || result.TotalAmount.Amount != 0 {
+			t.Fatalf("expected empty cart, got %+v", result)
+		}
+	})
+
+	t.Run("giữ lại các sản phẩm khác", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st, func(p *models.Product) { p.Price = models.VND(200000) })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(p1, 1), cartItemFor(p2, 1)})
+
+		result, err := cs.RemoveFromCart(user.ID.Hex(), "user", p1.ProductID)
+		mustNoError(t, err)
+		if len(result.Items) != 1 || result.Items[0].ProductID != p2.ProductID {
+			t.Fatalf("unexpected items: %+v", result.Items)
+		}
+		if result.TotalAmount.Amount != 200000 || result.TotalItems != 1 {
+			t.Fatalf("expected 200000/1, got %v/%d", result.TotalAmount, result.TotalItems)
+		}
+	})
+
+	t.Run("lỗi khi giỏ hàng không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+
+		_, err := cs.RemoveFromCart(user.ID.Hex(), "user", "prod_missing")
+		expectError(t, err, "giỏ hàng không tồn tại")
+	})
+
+	t.Run("lỗi khi sản phẩm không có trong giỏ hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+		_, err := cs.RemoveFromCart(user.ID.Hex(), "user", "prod_missing")
+		expectError(t, err, "sản phẩm không có trong giỏ hàng")
+	})
+}
+
+func TestCartService_ClearCart(t *testing.T) {
+	t.Run("xóa toàn bộ giỏ hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(p1, 1), cartItemFor(p2, 2)})
+
+		result, err := cs.ClearCart(user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if len(result.Items) != 0 || result.TotalItems != 0 || result.TotalAmount.Amount != 0 {
+			t.Fatalf("expected empty cart, got %+v", result)
+		}
+
+		cart, err := st.Carts().FindByUser(context.Background(), user.ID, "cart")
+		mustNoError(t, err)
+		if len(cart.Items) != 0 || cart.TotalItems != 0 {
+			t.Fatalf("expected stored cart to be cleared, got %+v", cart)
+		}
+	})
+
+	t.Run("không lỗi khi giỏ hàng chưa tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+
+		result, err := cs.ClearCart(user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if result.TotalItems != 0 || result.TotalAmount.Amount != 0 {
+			t.Fatalf("expected empty cart, got %+v", result)
+		}
+	})
+}
+
+func TestCartService_ApplyCoupon(t *testing.T) {
+	t.Run("áp dụng và bỏ mã giảm giá", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+		createTestCoupon(t, st)
+
+		result, err := cs.ApplyCoupon(user.ID.Hex(), "user", "sale10")
+		mustNoError(t, err)
+		if result.Coupon == nil || result.Coupon.Code != "SALE10" || result.Coupon.Amount.Amount != 20000 {
+			t.Fatalf("unexpected coupon: %+v", result.Coupon)
+		}
+
+		cart, err := cs.GetUserCart(user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if cart.Coupon == nil || cart.Coupon.Amount.Amount != 20000 {
+			t.Fatalf("expected coupon on cart, got %+v", cart.Coupon)
+		}
+
+		result, err = cs.RemoveCoupon(user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if result.Coupon != nil {
+			t.Fatalf("expected coupon to be removed, got %+v", result.Coupon)
+		}
+		stored, _ := st.Carts().FindByUser(context.Background(), user.ID, "cart")
+		if stored.CouponCode != "" {
+			t.Fatalf("expected stored coupon code to be cleared, got %q", stored.CouponCode)
+		}
+	})
+
+	t.Run("mã không còn áp dụng được khi giỏ hàng thay đổi", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+		createTestCoupon(t, st, func(c *models.Coupon) { c.MinOrderAmount = models.VND(150000) })
+
+		_, err := cs.ApplyCoupon(user.ID.Hex(), "user", "SALE10")
+		mustNoError(t, err)
+		_, err = cs.UpdateCartItem(user.ID.Hex(), "user", product.ProductID, 1)
+		mustNoError(t, err)
+
+		cart, err := cs.GetUserCart(user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if cart.Coupon == nil || !cart.Coupon.Amount.IsZero() || !strings.Contains(cart.Coupon.Message, "đơn hàng tối thiểu") {
+			t.Fatalf("expected inapplicable coupon with reason, got %+v", cart.Coupon)
+		}
+	})
+
+	t.Run("lỗi khi mã không hợp lệ", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+
+		_, err := cs.ApplyCoupon(user.ID.Hex(), "user", "SALE10")
+		expectError(t, err, "giỏ hàng trống")
+
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+		_, err = cs.ApplyCoupon(user.ID.Hex(), "user", "NOPE")
+		expectError(t, err, "mã giảm giá không tồn tại")
+
+		_, err = cs.ApplyCoupon(user.ID.Hex(), "admin", "SALE10")
+		expectError(t, err, "admin không có quyền")
+	})
+}
+
+func TestCartService_GetCartCount(t *testing.T) {
+	st := newTestStore()
+	cs := NewCartService(st)
+	user := createTestUser(t, st)
+
+	count, err := cs.GetCartCount(user.ID.Hex())
+	mustNoError(t, err)
+	if count != 0 {
+		t.Fatalf("expected 0, got %d", count)
+	}
+
+	product := createTestProduct(t, st)
+	createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+
+	count, err = cs.GetCartCount(user.ID.Hex())
+	mustNoError(t, err)
+	if count != 2 {
+		t.Fatalf("expected 2, got %d", count)
+	}
+}
+
+func TestCartService_ValidateCartStock(t *testing.T) {
+	t.Run("hợp lệ khi đủ hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+
+		result, err := cs.ValidateCartStock(user.ID.Hex())
+		mustNoError(t, err)
+		if !result.Valid || len(result.Items) != 1 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		item := result.Items[0]
+		if item.ProductID != product.ProductID || item.Quantity != 2 || item.AvailableStock != product.Amount || !item.Valid {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+	})
+
+	t.Run("không hợp lệ khi thiếu hàng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Amount = 1 })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 5)})
+
+		result, err := cs.ValidateCartStock(user.ID.Hex())
+		mustNoError(t, err)
+		if result.Valid || len(result.Items) != 1 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		item := result.Items[0]
+		if item.Quantity != 5 || item.AvailableStock != 1 || item.Valid {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+	})
+
+	t.Run("hợp lệ khi giỏ hàng rỗng", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+
+		result, err := cs.ValidateCartStock(user.ID.Hex())
+		mustNoError(t, err)
+		if !result.Valid || len(result.Items) != 0 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("không hợp lệ khi sản phẩm đã bị xóa", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCartService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+		mustNoError(t, st.Products().Delete(context.Background(), product.ID))
+
+		result, err := cs.ValidateCartStock(user.ID.Hex())
+		mustNoError(t, err)
+		if result.Valid || len(result.Items) != 1 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		item := result.Items[0]
+		if item.ProductID != product.ProductID || item.Quantity != 1 || item.AvailableStock != 0 || item.Valid {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+	})
+}
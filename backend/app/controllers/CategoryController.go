@@ -6,10 +6,20 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 )
 
 // CategoryController handles category-related HTTP requests
-type CategoryController struct{}
+type CategoryController struct {
+	categoryService *CategoryService
+}
+
+// NewCategoryController creates a new category controller instance
+func NewCategoryController(st store.Store) *CategoryController {
+	return &CategoryController{
+		categoryService: NewCategoryService(st),
+	}
+}
 
 // CreateCategoryRequest struct for creating categories
 type CreateCategoryRequest struct {
@@ -25,7 +35,7 @@ type UpdateCategoryRequest struct {
 
 // GetAllCategories lấy tất cả categories
 func (cc *CategoryController) GetAllCategories(c *gin.Context) {
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	categories, err := categoryService.GetAllCategories()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -44,7 +54,7 @@ func (cc *CategoryController) GetAllCategories(c *gin.Context) {
 
 // GetCategoriesWithStats lấy categories với thống kê sản phẩm
 func (cc *CategoryController) GetCategoriesWithStats(c *gin.Context) {
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	categories, err := categoryService.GetCategoriesWithStats()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -65,7 +75,7 @@ func (cc *CategoryController) GetCategoriesWithStats(c *gin.Context) {
 func (cc *CategoryController) GetCategoryByID(c *gin.Context) {
 	id := c.Param("id")
 
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	category, err := categoryService.GetCategoryByID(id)
 	if err != nil {
 		if err.Error() == "category không tồn tại" {
@@ -93,7 +103,7 @@ func (cc *CategoryController) GetCategoryByID(c *gin.Context) {
 func (cc *CategoryController) GetCategoryBySlug(c *gin.Context) {
 	slug := c.Param("slug")
 
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	category, err := categoryService.GetCategoryBySlug(slug)
 	if err != nil {
 		if err.Error() == "category không tồn tại" {
@@ -133,7 +143,7 @@ func (cc *CategoryController) CreateCategory(c *gin.Context) {
 		CategoryID: req.CategoryID,
 	}
 
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	category, err := categoryService.CreateCategory(categoryData)
 	if err != nil {
 		// Handle specific error types
@@ -180,7 +190,7 @@ func (cc *CategoryController) UpdateCategory(c *gin.Context) {
 		CategoryID: req.CategoryID,
 	}
 
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	category, err := categoryService.UpdateCategory(id, updateData)
 	if err != nil {
 		// Handle specific error types
@@ -221,7 +231,7 @@ func (cc *CategoryController) UpdateCategory(c *gin.Context) {
 func (cc *CategoryController) DeleteCategory(c *gin.Context) {
 	id := c.Param("id")
 
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	err := categoryService.DeleteCategory(id)
 	if err != nil {
 		// Handle specific error types
@@ -281,7 +291,7 @@ func (cc *CategoryController) GetProductsByCategory(c *gin.Context) {
 		limit = 12
 	}
 
-	categoryService := NewCategoryService()
+	categoryService := cc.categoryService
 	result, err := categoryService.GetProductsByCategory(categoryID, page, limit)
 	if err != nil {
 		if err.Error() == "category không tồn tại" {
@@ -303,7 +313,7 @@ func (cc *CategoryController) GetProductsByCategory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    result.Categories,
+		"data":    result.Products,
 		"pagination": gin.H{
 			"current_page":   page,
 			"total_pages":    totalPages,
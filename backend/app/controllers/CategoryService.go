@@ -4,20 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type CategoryService struct{}
+type CategoryService struct {
+	store store.Store
+}
 
 type CategoryResult struct {
 	Categories []models.Category `json:"categories"`
+	Products   []models.Product  `json:"products"`
 	Total      int               `json:"total"`
 }
 
@@ -42,19 +44,10 @@ func (cs *CategoryService) GetAllCategories() ([]models.Category, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
-	cursor, err := collection.Find(ctx, bson.M{})
+	categories, err := cs.store.Categories().List(ctx)
 	if err != nil {
 		return nil, errors.New("failed to fetch categories")
 	}
-	defer cursor.Close(ctx)
-
-	var categories []models.Category
-	if err = cursor.All(ctx, &categories); err != nil {
-		return nil, errors.New("failed to decode categories")
-	}
 
 	return categories, nil
 }
@@ -64,34 +57,28 @@ func (cs *CategoryService) GetCategoriesWithStats() ([]CategoryWithStats, error)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
-	pipeline := mongo.Pipeline{
-		{{Key: "$lookup", Value: bson.D{
-			{Key: "from", Value: "products"},
-			{Key: "localField", Value: "id"},
-			{Key: "foreignField", Value: "category_id"},
-			{Key: "as", Value: "products"},
-		}}},
-		{{Key: "$addFields", Value: bson.D{
-			{Key: "product_count", Value: bson.D{{Key: "$size", Value: "$products"}}},
-		}}},
-		{{Key: "$project", Value: bson.D{
-			{Key: "products", Value: 0},
-		}}},
-		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
-	}
-
-	cursor, err := collection.Aggregate(ctx, pipeline)
+	categories, err := cs.store.Categories().List(ctx)
 	if err != nil {
-		return nil, errors.New("failed to aggregate categories")
+		return nil, errors.New("failed to fetch categories")
 	}
-	defer cursor.Close(ctx)
 
-	var categoriesWithStats []CategoryWithStats
-	if err = cursor.All(ctx, &categoriesWithStats); err != nil {
-		return nil, errors.New("failed to decode categories with stats")
+	// Sắp xếp category mới nhất lên đầu
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].CreatedAt.After(categories[j].CreatedAt)
+	})
+
+	categoriesWithStats := make([]CategoryWithStats, 0, len(categories))
+	for _, category := range categories {
+		// Product.category lưu Category.id
+		count, err := cs.store.Products().Count(ctx, store.ProductFilter{Category: category.CategoryID})
+		if err != nil {
+			return nil, errors.New("failed to count products")
+		}
+
+		categoriesWithStats = append(categoriesWithStats, CategoryWithStats{
+			Category:     category,
+			ProductCount: int(count),
+		})
 	}
 
 	return categoriesWithStats, nil
@@ -107,39 +94,31 @@ func (cs *CategoryService) GetCategoryByID(categoryID string) (*models.Category,
 		return nil, errors.New("invalid category ID")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
-	var category models.Category
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&category)
+	category, err := cs.store.Categories().FindByObjectID(ctx, objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, errors.New("category không tồn tại")
 		}
 		return nil, errors.New("failed to fetch category")
 	}
 
-	return &category, nil
+	return category, nil
 }
 
-// GetCategoryBySlug lấy category theo slug
+// GetCategoryBySlug lấy category theo slug (category dùng field id làm slug)
 func (cs *CategoryService) GetCategoryBySlug(slug string) (*models.Category, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
-	var category models.Category
-	err := collection.FindOne(ctx, bson.M{"slug": slug}).Decode(&category)
+	category, err := cs.store.Categories().FindByCategoryID(ctx, slug)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, errors.New("category không tồn tại")
 		}
 		return nil, errors.New("failed to fetch category")
 	}
 
-	return &category, nil
+	return category, nil
 }
 
 // CreateCategory tạo category mới
@@ -157,19 +136,14 @@ func (cs *CategoryService) CreateCategory(categoryData models.Category) (*models
 		return nil, err
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
 	// Set timestamps
 	categoryData.CreatedAt = time.Now()
 	categoryData.UpdatedAt = time.Now()
 
-	result, err := collection.InsertOne(ctx, categoryData)
-	if err != nil {
+	if err := cs.store.Categories().Insert(ctx, &categoryData); err != nil {
 		return nil, errors.New("failed to create category")
 	}
 
-	categoryData.ID = result.InsertedID.(primitive.ObjectID)
 	return &categoryData, nil
 }
 
@@ -201,9 +175,6 @@ func (cs *CategoryService) UpdateCategory(categoryID string, updateData models.C
 		}
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
 	// Prepare update document
 	updateDoc := bson.M{
 		"updatedAt": time.Now(),
@@ -216,7 +187,7 @@ func (cs *CategoryService) UpdateCategory(categoryID string, updateData models.C
 		updateDoc["id"] = updateData.CategoryID
 	}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": updateDoc})
+	err = cs.store.Categories().Update(ctx, objectID, updateDoc)
 	if err != nil {
 		return nil, errors.New("failed to update category")
 	}
@@ -236,20 +207,17 @@ func (cs *CategoryService) DeleteCategory(categoryID string) error {
 	}
 
 	// Kiểm tra category có tồn tại
-	_, err = cs.GetCategoryByID(categoryID)
+	category, err := cs.GetCategoryByID(categoryID)
 	if err != nil {
 		return err
 	}
 
 	// Kiểm tra có sản phẩm nào đang sử dụng category này không
-	if err := cs.checkProductsInCategory(categoryID); err != nil {
+	if err := cs.checkProductsInCategory(category.CategoryID); err != nil {
 		return err
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
-	_, err = collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	err = cs.store.Categories().Delete(ctx, objectID)
 	if err != nil {
 		return errors.New("failed to delete category")
 	}
@@ -260,7 +228,7 @@ func (cs *CategoryService) DeleteCategory(categoryID string) error {
 // GetProductsByCategory lấy sản phẩm theo category
 func (cs *CategoryService) GetProductsByCategory(categoryID string, page, limit int) (*CategoryResult, error) {
 	// Kiểm tra category có tồn tại
-	_, err := cs.GetCategoryByID(categoryID)
+	category, err := cs.GetCategoryByID(categoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -268,37 +236,27 @@ func (cs *CategoryService) GetProductsByCategory(categoryID string, page, limit
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("products")
+	// Product.category lưu Category.id
+	filter := store.ProductFilter{Category: category.CategoryID}
 
-	skip := int64((page - 1) * limit)
-	limitInt64 := int64(limit)
-
-	findOptions := options.Find()
-	findOptions.SetSkip(skip)
-	findOptions.SetLimit(limitInt64)
-	findOptions.SetSort(bson.M{"created_at": -1})
-
-	cursor, err := collection.Find(ctx, bson.M{"category_id": categoryID}, findOptions)
+	products, err := cs.store.Products().List(ctx, filter, store.ListOptions{
+		Skip:  int64((page - 1) * limit),
+		Limit: int64(limit),
+		Sort:  "-created_at",
+	})
 	if err != nil {
 		return nil, errors.New("failed to fetch products")
 	}
-	defer cursor.Close(ctx)
-
-	var products []models.Category // This should be []models.Product in real implementation
-	if err = cursor.All(ctx, &products); err != nil {
-		return nil, errors.New("failed to decode products")
-	}
 
 	// Count total products
-	total, err := collection.CountDocuments(ctx, bson.M{"category_id": categoryID})
+	total, err := cs.store.Products().Count(ctx, filter)
 	if err != nil {
 		return nil, errors.New("failed to count products")
 	}
 
 	return &CategoryResult{
-		Categories: products,
-		Total:      int(total),
+		Products: products,
+		Total:    int(total),
 	}, nil
 }
 
@@ -321,26 +279,19 @@ func (cs *CategoryService) checkDuplicateName(name, excludeID string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
-	filter := bson.M{
-		"name": bson.M{"$regex": primitive.Regex{Pattern: "^" + name + "$", Options: "i"}},
-	}
-
+	var excludeOID primitive.ObjectID
 	if excludeID != "" {
-		objectID, err := primitive.ObjectIDFromHex(excludeID)
-		if err == nil {
-			filter["_id"] = bson.M{"$ne": objectID}
+		if objectID, err := primitive.ObjectIDFromHex(excludeID); err == nil {
+			excludeOID = objectID
 		}
 	}
 
-	count, err := collection.CountDocuments(ctx, filter)
+	exists, err := cs.store.Categories().ExistsByName(ctx, name, excludeOID)
 	if err != nil {
 		return errors.New("failed to check duplicate name")
 	}
 
-	if count > 0 {
+	if exists {
 		return errors.New("tên category đã tồn tại")
 	}
 
@@ -351,10 +302,7 @@ func (cs *CategoryService) checkProductsInCategory(categoryID string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("products")
-
-	count, err := collection.CountDocuments(ctx, bson.M{"category_id": categoryID})
+	count, err := cs.store.Products().Count(ctx, store.ProductFilter{Category: categoryID})
 	if err != nil {
 		return errors.New("failed to check products in category")
 	}
@@ -367,6 +315,6 @@ func (cs *CategoryService) checkProductsInCategory(categoryID string) error {
 }
 
 // NewCategoryService creates a new instance of CategoryService
-func NewCategoryService() *CategoryService {
-	return &CategoryService{}
+func NewCategoryService(st store.Store) *CategoryService {
+	return &CategoryService{store: st}
 }
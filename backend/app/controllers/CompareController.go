@@ -4,13 +4,18 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/store"
 )
 
-type CompareController struct{}
+type CompareController struct {
+	compareService *CompareService
+}
 
 // NewCompareController tạo instance mới của CompareController
-func NewCompareController() *CompareController {
-	return &CompareController{}
+func NewCompareController(st store.Store) *CompareController {
+	return &CompareController{
+		compareService: NewCompareService(st),
+	}
 }
 
 // AddToCompareRequest struct for adding products to compare
@@ -41,7 +46,7 @@ func (cc *CompareController) GetCompare(c *gin.Context) {
 		userRole = "user" // default role
 	}
 
-	compareService := NewCompareService()
+	compareService := cc.compareService
 
 	// Validate user role
 	if err := compareService.ValidateUserRole(userRole.(string)); err != nil {
@@ -101,7 +106,7 @@ func (cc *CompareController) AddToCompare(c *gin.Context) {
 		userRole = "user" // default role
 	}
 
-	compareService := NewCompareService()
+	compareService := cc.compareService
 
 	// Validate user role
 	if err := compareService.ValidateUserRole(userRole.(string)); err != nil {
@@ -183,7 +188,7 @@ func (cc *CompareController) RemoveFromCompare(c *gin.Context) {
 		userRole = "user" // default role
 	}
 
-	compareService := NewCompareService()
+	compareService := cc.compareService
 
 	// Validate user role
 	if err := compareService.ValidateUserRole(userRole.(string)); err != nil {
@@ -239,7 +244,7 @@ func (cc *CompareController) ClearCompare(c *gin.Context) {
 		userRole = "user" // default role
 	}
 
-	compareService := NewCompareService()
+	compareService := cc.compareService
 
 	// Validate user role
 	if err := compareService.ValidateUserRole(userRole.(string)); err != nil {
@@ -6,19 +6,22 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// MaxCompareItems là số sản phẩm tối đa trong danh sách so sánh
+const MaxCompareItems = 4
+
 // CompareService handles business logic for compare operations
-type CompareService struct{}
+type CompareService struct {
+	store store.Store
+}
 
 // NewCompareService creates a new instance of CompareService
-func NewCompareService() *CompareService {
-	return &CompareService{}
+func NewCompareService(st store.Store) *CompareService {
+	return &CompareService{store: st}
 }
 
 // CompareResult represents the result structure for compare operations
@@ -38,11 +41,7 @@ func (cs *CompareService) GetUserCompareList(userID string) (*models.Compare, er
 		return nil, errors.New("user ID không hợp lệ")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("Compares")
-
-	var compare models.Compare
-	err = collection.FindOne(ctx, bson.M{"user_id": userOID}).Decode(&compare)
+	compare, err := cs.store.Compares().FindByUser(ctx, userOID)
 	if err != nil {
 		// Return empty compare if not found
 		return &models.Compare{
@@ -51,7 +50,7 @@ func (cs *CompareService) GetUserCompareList(userID string) (*models.Compare, er
 		}, nil
 	}
 
-	return &compare, nil
+	return compare, nil
 }
 
 // ValidateUserRole kiểm tra quyền admin
@@ -75,20 +74,16 @@ func (cs *CompareService) FindProductByID(productID string) (*models.Product, er
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
-	var product models.Product
 	// Search by the 'id' field (not '_id') which contains product IDs like "P001", "P002", etc.
-	err := collection.FindOne(ctx, bson.M{"id": productID}).Decode(&product)
+	product, err := cs.store.Products().FindByProductID(ctx, productID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, errors.New("sản phẩm không tồn tại")
 		}
 		return nil, fmt.Errorf("lỗi tìm kiếm sản phẩm: %v", err)
 	}
 
-	return &product, nil
+	return product, nil
 }
 
 // FindOrCreateCompare tìm hoặc tạo compare mới
@@ -102,14 +97,10 @@ func (cs *CompareService) FindOrCreateCompare(userID string) (*models.Compare, e
 		return nil, errors.New("user ID không hợp lệ")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("Compares")
-
-	var compare models.Compare
-	err = collection.FindOne(ctx, bson.M{"user_id": userOID}).Decode(&compare)
+	compare, err := cs.store.Compares().FindByUser(ctx, userOID)
 	if err != nil {
 		// Create new compare if not found
-		compare = models.Compare{
+		compare = &models.Compare{
 			UserID:    userOID,
 			Items:     []models.CompareItem{},
 			CreatedAt: time.Now(),
@@ -117,7 +108,7 @@ func (cs *CompareService) FindOrCreateCompare(userID string) (*models.Compare, e
 		}
 	}
 
-	return &compare, nil
+	return compare, nil
 }
 
 // CheckProductInCompare kiểm tra sản phẩm có trong compare không
@@ -149,9 +140,9 @@ func (cs *CompareService) AddProductToCompare(userID, productID string) (*models
 		return nil, err
 	}
 
-	// Kiểm tra giới hạn tối đa 4 sản phẩm
-	if len(compare.Items) >= 4 {
-		return nil, errors.New("chỉ có thể so sánh tối đa 4 sản phẩm")
+	// Kiểm tra giới hạn tối đa MaxCompareItems sản phẩm
+	if len(compare.Items) >= MaxCompareItems {
+		return nil, fmt.Errorf("chỉ có thể so sánh tối đa %d sản phẩm", MaxCompareItems)
 	}
 
 	// Kiểm tra sản phẩm đã có trong compare chưa
@@ -172,31 +163,16 @@ func (cs *CompareService) AddProductToCompare(userID, productID string) (*models
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Compares")
+	isNew := compare.ID.IsZero()
+	compare.Items = append(compare.Items, newItem)
+	compare.TotalItems = len(compare.Items)
+	compare.UpdatedAt = time.Now()
 
-	if compare.ID.IsZero() {
-		// Insert new compare
-		compare.Items = append(compare.Items, newItem)
-		result, err := collection.InsertOne(ctx, compare)
-		if err != nil {
+	if err := cs.store.Compares().Save(ctx, compare); err != nil {
+		if isNew {
 			return nil, errors.New("lỗi khi tạo danh sách so sánh")
 		}
-		compare.ID = result.InsertedID.(primitive.ObjectID)
-	} else {
-		// Update existing compare
-		update := bson.M{
-			"$push": bson.M{"items": newItem},
-			"$set":  bson.M{"updatedAt": time.Now()},
-		}
-
-		_, err = collection.UpdateOne(ctx, bson.M{"_id": compare.ID}, update)
-		if err != nil {
-			return nil, errors.New("lỗi khi thêm sản phẩm vào danh sách so sánh")
-		}
-
-		compare.Items = append(compare.Items, newItem)
-		compare.UpdatedAt = time.Now()
+		return nil, errors.New("lỗi khi thêm sản phẩm vào danh sách so sánh")
 	}
 
 	return compare, nil
@@ -232,29 +208,18 @@ func (cs *CompareService) RemoveProductFromCompare(userID, productID string) (*m
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Compares")
-
-	// Convert userID to ObjectID
-	userOID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		return nil, errors.New("user ID không hợp lệ")
-	}
-
 	// Update database
-	update := bson.M{
-		"$pull": bson.M{"items": bson.M{"product_id": productID}},
-		"$set":  bson.M{"updatedAt": time.Now()},
+	if newItems == nil {
+		newItems = []models.CompareItem{}
 	}
+	compare.Items = newItems
+	compare.TotalItems = len(newItems)
+	compare.UpdatedAt = time.Now()
 
-	_, err = collection.UpdateOne(ctx, bson.M{"user_id": userOID}, update)
-	if err != nil {
+	if err := cs.store.Compares().Save(ctx, compare); err != nil {
 		return nil, errors.New("lỗi khi xóa sản phẩm khỏi danh sách so sánh")
 	}
 
-	compare.Items = newItems
-	compare.UpdatedAt = time.Now()
-
 	return compare, nil
 }
 
@@ -269,18 +234,8 @@ func (cs *CompareService) ClearUserCompare(userID string) (*models.Compare, erro
 		return nil, errors.New("user ID không hợp lệ")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("Compares")
-
 	// Clear all items
-	update := bson.M{
-		"$set": bson.M{
-			"items":     []models.CompareItem{},
-			"updatedAt": time.Now(),
-		},
-	}
-
-	_, err = collection.UpdateOne(ctx, bson.M{"user_id": userOID}, update)
+	err = cs.store.Compares().Clear(ctx, userOID)
 	if err != nil {
 		return nil, errors.New("lỗi khi xóa danh sách so sánh")
 	}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"testing"
+)
+
+func TestCompareService_GetUserCompareList(t *testing.T) {
+	st := newTestStore()
+	cs := NewCompareService(st)
+	user := createTestUser(t, st)
+
+	compare, err := cs.GetUserCompareList(user.ID.Hex())
+	mustNoError(t, err)
+	if len(compare.Items) != 0 {
+		t.Fatalf("expected empty compare list, got %d items", len(compare.Items))
+	}
+}
+
+func TestCompareService_AddProductToCompare(t *testing.T) {
+	t.Run("thêm sản phẩm vào danh sách so sánh", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		compare, err := cs.AddProductToCompare(user.ID.Hex(), product.ProductID)
+		mustNoError(t, err)
+		if len(compare.Items) != 1 || compare.TotalItems != 1 || compare.Items[0].ProductID != product.ProductID {
+			t.Fatalf("unexpected compare list: %+v", compare)
+		}
+
+		count, err := cs.GetCompareCount(user.ID.Hex())
+		mustNoError(t, err)
+		if count != 1 {
+			t.Fatalf("expected stored count 1, got %d", count)
+		}
+	})
+
+	t.Run("lỗi khi vượt quá 4 sản phẩm", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+		for i := 0; i < 4; i++ {
+			product := createTestProduct(t, st)
+			_, err := cs.AddProductToCompare(user.ID.Hex(), product.ProductID)
+			mustNoError(t, err)
+		}
+
+		extra := createTestProduct(t, st)
+		_, err := cs.AddProductToCompare(user.ID.Hex(), extra.ProductID)
+		expectError(t, err, "chỉ có thể so sánh tối đa 4 sản phẩm")
+	})
+
+	t.Run("lỗi khi sản phẩm đã có trong danh sách", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		_, err := cs.AddProductToCompare(user.ID.Hex(), product.ProductID)
+		mustNoError(t, err)
+		_, err = cs.AddProductToCompare(user.ID.Hex(), product.ProductID)
+		expectError(t, err, "sản phẩm đã có trong compare list")
+	})
+
+	t.Run("lỗi khi sản phẩm không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+
+		_, err := cs.AddProductToCompare(user.ID.Hex(), "prod_missing")
+		expectError(t, err, "sản phẩm không tồn tại")
+	})
+
+	t.Run("lỗi khi thiếu product_id", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+
+		_, err := cs.AddProductToCompare(user.ID.Hex(), "")
+		expectError(t, err, "product_id là bắt buộc")
+	})
+}
+
+func TestCompareService_RemoveProductFromCompare(t *testing.T) {
+	t.Run("xóa sản phẩm khỏi danh sách so sánh", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st)
+		_, err := cs.AddProductToCompare(user.ID.Hex(), p1.ProductID)
+		mustNoError(t, err)
+		_, err = cs.AddProductToCompare(user.ID.Hex(), p2.ProductID)
+		mustNoError(t, err)
+
+		compare, err := cs.RemoveProductFromCompare(user.ID.Hex(), p1.ProductID)
+		mustNoError(t, err)
+		if len(compare.Items) != 1 || compare.Items[0].ProductID != p2.ProductID {
+			t.Fatalf("unexpected compare list: %+v", compare.Items)
+		}
+	})
+
+	t.Run("lỗi khi danh sách so sánh không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+
+		_, err := cs.RemoveProductFromCompare(user.ID.Hex(), "prod_missing")
+		expectError(t, err, "compare không tồn tại")
+	})
+
+	t.Run("lỗi khi sản phẩm không có trong danh sách", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCompareService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		_, err := cs.AddProductToCompare(user.ID.Hex(), product.ProductID)
+		mustNoError(t, err)
+
+		_, err = cs.RemoveProductFromCompare(user.ID.Hex(), "prod_missing")
+		expectError(t, err, "sản phẩm không có trong compare list")
+	})
+}
+
+func TestCompareService_ClearUserCompare(t *testing.T) {
+	st := newTestStore()
+	cs := NewCompareService(st)
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	_, err := cs.AddProductToCompare(user.ID.Hex(), product.ProductID)
+	mustNoError(t, err)
+
+	compare, err := cs.ClearUserCompare(user.ID.Hex())
+	mustNoError(t, err)
+	if len(compare.Items) != 0 {
+		t.Fatalf("expected empty result, got %+v", compare.Items)
+	}
+
+	count, err := cs.GetCompareCount(user.ID.Hex())
+	mustNoError(t, err)
+	if count != 0 {
+		t.Fatalf("expected stored compare list to be empty, got %d", count)
+	}
+}
+
+func TestCompareService_IsProductInCompare(t *testing.T) {
+	st := newTestStore()
+	cs := NewCompareService(st)
+	user := createTestUser(t, st)
+	p1 := createTestProduct(t, st)
+	p2 := createTestProduct(t, st)
+	_, err := cs.AddProductToCompare(user.ID.Hex(), p1.ProductID)
+	mustNoError(t, err)
+
+	inCompare, err := cs.IsProductInCompare(user.ID.Hex(), p1.ProductID)
+	mustNoError(t, err)
+	if !inCompare {
+		t.Fatalf("expected product to be in compare list")
+	}
+
+	inCompare, err = cs.IsProductInCompare(user.ID.Hex(), p2.ProductID)
+	mustNoError(t, err)
+	if inCompare {
+		t.Fatalf("expected product not to be in compare list")
+	}
+}
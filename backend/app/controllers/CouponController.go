@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/store"
+)
+
+// CouponController handles coupon management HTTP requests (admin)
+type CouponController struct {
+	couponService *CouponService
+}
+
+// NewCouponController creates a new coupon controller instance
+func NewCouponController(st store.Store) *CouponController {
+	return &CouponController{
+		couponService: NewCouponService(st),
+	}
+}
+
+// respondCouponError trả về status code phù hợp cho lỗi của CouponService
+func respondCouponError(c *gin.Context, err error) {
+	if err == ErrCouponNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var couponErr *CouponError
+	if errors.As(err, &couponErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": "Internal server error",
+	})
+}
+
+// GetCoupons lấy danh sách mã giảm giá
+func (cc *CouponController) GetCoupons(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	var isActive *bool
+	if value, err := strconv.ParseBool(c.Query("is_active")); err == nil {
+		isActive = &value
+	}
+
+	result, err := cc.couponService.GetCoupons(page, limit, c.Query("search"), isActive)
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       result.Coupons,
+		"pagination": result.Pagination,
+	})
+}
+
+// GetCouponByID lấy chi tiết mã giảm giá
+func (cc *CouponController) GetCouponByID(c *gin.Context) {
+	coupon, err := cc.couponService.GetCouponByID(c.Param("id"))
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    coupon,
+	})
+}
+
+// CreateCoupon tạo mã giảm giá mới
+func (cc *CouponController) CreateCoupon(c *gin.Context) {
+	var req CouponInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ: " + err.Error(),
+		})
+		return
+	}
+
+	coupon, err := cc.couponService.CreateCoupon(req)
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Tạo mã giảm giá thành công",
+		"data":    coupon,
+	})
+}
+
+// UpdateCoupon cập nhật mã giảm giá
+func (cc *CouponController) UpdateCoupon(c *gin.Context) {
+	var req CouponInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ: " + err.Error(),
+		})
+		return
+	}
+
+	coupon, err := cc.couponService.UpdateCoupon(c.Param("id"), req)
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cập nhật mã giảm giá thành công",
+		"data":    coupon,
+	})
+}
+
+// DeleteCoupon xóa mã giảm giá
+func (cc *CouponController) DeleteCoupon(c *gin.Context) {
+	if err := cc.couponService.DeleteCoupon(c.Param("id")); err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Xóa mã giảm giá thành công",
+	})
+}
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CouponService xử lý mã giảm giá: CRUD cho admin, kiểm tra điều kiện và tính tiền giảm cho giỏ hàng
+type CouponService struct {
+	store store.Store
+}
+
+// NewCouponService creates a new instance of CouponService
+func NewCouponService(st store.Store) *CouponService {
+	return &CouponService{store: st}
+}
+
+// ErrCouponNotFound được trả về bởi các API admin khi không tìm thấy mã giảm giá
+var ErrCouponNotFound = errors.New("mã giảm giá không tồn tại")
+
+// CouponError là lỗi nghiệp vụ khi tạo hoặc áp dụng mã giảm giá (lỗi phía client)
+type CouponError struct {
+	Reason string
+}
+
+func (e *CouponError) Error() string {
+	return e.Reason
+}
+
+func couponErrorf(format string, args ...interface{}) *CouponError {
+	return &CouponError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// CouponInput là dữ liệu tạo/cập nhật mã giảm giá
+type CouponInput struct {
+	Code           string       `json:"code"`
+	Description    string       `json:"description"`
+	Type           string       `json:"type"`
+	Percent        float64      `json:"percent"`
+	Amount         models.Money `json:"amount"`
+	MaxDiscount    models.Money `json:"max_discount"`
+	MinOrderAmount models.Money `json:"min_order_amount"`
+	UsageLimit     int          `json:"usage_limit"`
+	PerUserLimit   int          `json:"per_user_limit"`
+	StartsAt       *time.Time   `json:"starts_at"`
+	ExpiresAt      *time.Time   `json:"expires_at"`
+	Categories     []string     `json:"categories"`
+	Products       []string     `json:"products"`
+	IsActive       *bool        `json:"is_active"` // mặc định true
+}
+
+// CouponLine là một dòng hàng dùng để kiểm tra phạm vi và tính tiền giảm
+type CouponLine struct {
+	ProductID string
+	Category  string
+	Total     models.Money
+}
+
+// CouponDiscount là kết quả áp dụng mã giảm giá cho giỏ hàng
+type CouponDiscount struct {
+	Code        string         `json:"code"`
+	Description string         `json:"description,omitempty"`
+	Amount      models.Money   `json:"amount"`
+	Message     string         `json:"message,omitempty"` // lý do mã không còn áp dụng được (chỉ dùng khi hiển thị giỏ hàng)
+	Lines       []models.Money `json:"-"`                 // tiền giảm phân bổ cho từng dòng, cùng thứ tự với lines
+}
+
+// CouponListResult là kết quả danh sách mã giảm giá cho admin
+type CouponListResult struct {
+	Coupons    []models.Coupon        `json:"coupons"`
+	Pagination map[string]interface{} `json:"pagination"`
+}
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// normalizeCouponCode viết hoa và bỏ khoảng trắng thừa
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// GetCoupons lấy danh sách mã giảm giá với pagination (cho admin)
+func (cs *CouponService) GetCoupons(page, limit int, search string, isActive *bool) (*CouponListResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := store.CouponFilter{Search: search, IsActive: isActive}
+	coupons, err := cs.store.Coupons().List(ctx, filter, store.ListOptions{
+		Skip:  int64((page - 1) * limit),
+		Limit: int64(limit),
+		Sort:  "-createdAt",
+	})
+	if err != nil {
+		return nil, errors.New("lỗi khi lấy danh sách mã giảm giá")
+	}
+
+	total, err := cs.store.Coupons().Count(ctx, filter)
+	if err != nil {
+		return nil, errors.New("lỗi khi đếm mã giảm giá")
+	}
+
+	return &CouponListResult{
+		Coupons: coupons,
+		Pagination: map[string]interface{}{
+			"current_page":   page,
+			"total_pages":    (int(total) + limit - 1) / limit,
+			"total_items":    total,
+			"items_per_page": limit,
+		},
+	}, nil
+}
+
+// GetCouponByID lấy chi tiết mã giảm giá
+func (cs *CouponService) GetCouponByID(id string) (*models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrCouponNotFound
+	}
+
+	coupon, err := cs.store.Coupons().FindByID(ctx, objectID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrCouponNotFound
+		}
+		return nil, errors.New("lỗi khi tìm mã giảm giá")
+	}
+	return coupon, nil
+}
+
+// CreateCoupon tạo mã giảm giá mới
+func (cs *CouponService) CreateCoupon(input CouponInput) (*models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	coupon, err := cs.couponFromInput(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := cs.store.Coupons().ExistsByCode(ctx, coupon.Code, primitive.NilObjectID)
+	if err != nil {
+		return nil, errors.New("lỗi khi kiểm tra mã giảm giá")
+	}
+	if exists {
+		return nil, &CouponError{Reason: "mã giảm giá đã tồn tại"}
+	}
+
+	now := time.Now()
+	coupon.CreatedAt = now
+	coupon.UpdatedAt = now
+	if err := cs.store.Coupons().Insert(ctx, coupon); err != nil {
+		return nil, errors.New("lỗi khi tạo mã giảm giá")
+	}
+	return coupon, nil
+}
+
+// UpdateCoupon cập nhật toàn bộ cấu hình của mã giảm giá (giữ nguyên số lượt đã dùng)
+func (cs *CouponService) UpdateCoupon(id string, input CouponInput) (*models.Coupon, error) {
+	existing, err := cs.GetCouponByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	coupon, err := cs.couponFromInput(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := cs.store.Coupons().ExistsByCode(ctx, coupon.Code, existing.ID)
+	if err != nil {
+		return nil, errors.New("lỗi khi kiểm tra mã giảm giá")
+	}
+	if exists {
+		return nil, &CouponError{Reason: "mã giảm giá đã tồn tại"}
+	}
+
+	updated, err := cs.store.Coupons().Update(ctx, existing.ID, bson.M{
+		"code":             coupon.Code,
+		"description":      coupon.Description,
+		"type":             coupon.Type,
+		"percent":          coupon.Percent,
+		"amount":           coupon.Amount,
+		"max_discount":     coupon.MaxDiscount,
+		"min_order_amount": coupon.MinOrderAmount,
+		"usage_limit":      coupon.UsageLimit,
+		"per_user_limit":   coupon.PerUserLimit,
+		"starts_at":        coupon.StartsAt,
+		"expires_at":       coupon.ExpiresAt,
+		"categories":       coupon.Categories,
+		"products":         coupon.Products,
+		"is_active":        coupon.IsActive,
+		"updatedAt":        time.Now(),
+	})
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrCouponNotFound
+		}
+		return nil, errors.New("lỗi khi cập nhật mã giảm giá")
+	}
+	return updated, nil
+}
+
+// DeleteCoupon xóa mã giảm giá. Đơn hàng đã dùng mã vẫn giữ coupon_code và số tiền giảm.
+func (cs *CouponService) DeleteCoupon(id string) error {
+	existing, err := cs.GetCouponByID(id)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := cs.store.Coupons().Delete(ctx, existing.ID); err != nil {
+		if err == store.ErrNotFound {
+			return ErrCouponNotFound
+		}
+		return errors.New("lỗi khi xóa mã giảm giá")
+	}
+	return nil
+}
+
+// couponFromInput validate input và chuyển sang models.Coupon
+func (cs *CouponService) couponFromInput(ctx context.Context, input CouponInput) (*models.Coupon, error) {
+	coupon := &models.Coupon{
+		Code:           normalizeCouponCode(input.Code),
+		Description:    strings.TrimSpace(input.Description),
+		Type:           input.Type,
+		MinOrderAmount: input.MinOrderAmount,
+		UsageLimit:     input.UsageLimit,
+		PerUserLimit:   input.PerUserLimit,
+		StartsAt:       input.StartsAt,
+		ExpiresAt:      input.ExpiresAt,
+		IsActive:       input.IsActive == nil || *input.IsActive,
+	}
+
+	if !couponCodePattern.MatchString(coupon.Code) {
+		return nil, &CouponError{Reason: "mã giảm giá phải gồm 3-32 ký tự chữ, số, '-' hoặc '_'"}
+	}
+
+	switch input.Type {
+	case models.CouponPercentage:
+		if input.Percent <= 0 || input.Percent > 100 {
+			return nil, &CouponError{Reason: "phần trăm giảm phải lớn hơn 0 và không vượt quá 100"}
+		}
+		if input.MaxDiscount.Amount < 0 {
+			return nil, &CouponError{Reason: "mức giảm tối đa không được âm"}
+		}
+		coupon.Percent = input.Percent
+		coupon.MaxDiscount = input.MaxDiscount
+	case models.CouponFixed:
+		if input.Amount.Amount <= 0 {
+			return nil, &CouponError{Reason: "số tiền giảm phải lớn hơn 0"}
+		}
+		coupon.Amount = input.Amount
+	default:
+		return nil, &CouponError{Reason: "loại mã giảm giá không hợp lệ"}
+	}
+
+	if input.MinOrderAmount.Amount < 0 || input.UsageLimit < 0 || input.PerUserLimit < 0 {
+		return nil, &CouponError{Reason: "giá trị đơn tối thiểu và giới hạn lượt dùng không được âm"}
+	}
+	if input.StartsAt != nil && input.ExpiresAt != nil && !input.ExpiresAt.After(*input.StartsAt) {
+		return nil, &CouponError{Reason: "thời gian kết thúc phải sau thời gian bắt đầu"}
+	}
+
+	for _, categoryID := range input.Categories {
+		categoryID = strings.TrimSpace(categoryID)
+		if categoryID == "" {
+			continue
+		}
+		if _, err := cs.store.Categories().FindByCategoryID(ctx, categoryID); err != nil {
+			return nil, couponErrorf("category %s không tồn tại", categoryID)
+		}
+		coupon.Categories = append(coupon.Categories, categoryID)
+	}
+	for _, productID := range input.Products {
+		productID = strings.TrimSpace(productID)
+		if productID == "" {
+			continue
+		}
+		if _, err := cs.store.Products().FindByProductID(ctx, productID); err != nil {
+			return nil, couponErrorf("sản phẩm %s không tồn tại", productID)
+		}
+		coupon.Products = append(coupon.Products, productID)
+	}
+
+	return coupon, nil
+}
+
+// Evaluate tìm mã giảm giá theo code và tính tiền giảm cho các dòng hàng của userID
+func (cs *CouponService) Evaluate(ctx context.Context, code, userID string, lines []CouponLine, now time.Time) (*CouponDiscount, error) {
+	coupon, err := cs.store.Coupons().FindByCode(ctx, normalizeCouponCode(code))
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, &CouponError{Reason: "mã giảm giá không tồn tại"}
+		}
+		return nil, errors.New("lỗi khi tìm mã giảm giá")
+	}
+	return applyCoupon(coupon, userID, lines, now)
+}
+
+// applyCoupon kiểm tra trạng thái, thời gian hiệu lực, giới hạn lượt dùng, giá trị đơn tối thiểu
+// và phạm vi áp dụng, rồi tính tiền giảm và phân bổ cho các dòng hàng thuộc phạm vi
+func applyCoupon(coupon *models.Coupon, userID string, lines []CouponLine, now time.Time) (*CouponDiscount, error) {
+	if !coupon.IsActive {
+		return nil, &CouponError{Reason: "mã giảm giá không còn hiệu lực"}
+	}
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		return nil, &CouponError{Reason: "mã giảm giá chưa đến thời gian áp dụng"}
+	}
+	if coupon.ExpiresAt != nil && !now.Before(*coupon.ExpiresAt) {
+		return nil, &CouponError{Reason: "mã giảm giá đã hết hạn"}
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return nil, &CouponError{Reason: "mã giảm giá đã hết lượt sử dụng"}
+	}
+	if coupon.PerUserLimit > 0 && coupon.UserUsage[userID] >= coupon.PerUserLimit {
+		return nil, &CouponError{Reason: "bạn đã dùng hết lượt cho mã giảm giá này"}
+	}
+
+	var subtotal, eligible models.Money
+	inScope := make([]bool, len(lines))
+	for i, line := range lines {
+		subtotal = subtotal.Add(line.Total)
+		if couponCovers(coupon, line) {
+			inScope[i] = true
+			eligible = eligible.Add(line.Total)
+		}
+	}
+
+	if !coupon.MinOrderAmount.IsZero() && subtotal.Cmp(coupon.MinOrderAmount) < 0 {
+		return nil, couponErrorf("đơn hàng tối thiểu %s để dùng mã giảm giá này", coupon.MinOrderAmount)
+	}
+	if eligible.IsZero() {
+		return nil, &CouponError{Reason: "mã giảm giá không áp dụng cho sản phẩm trong giỏ hàng"}
+	}
+
+	var amount int64
+	switch coupon.Type {
+	case models.CouponPercentage:
+		bp := int64(math.Round(coupon.Percent * 100))
+		amount = divRound(eligible.Amount*bp, 10000)
+		if coupon.MaxDiscount.Amount > 0 && amount > coupon.MaxDiscount.Amount {
+			amount = coupon.MaxDiscount.Amount
+		}
+	default:
+		amount = coupon.Amount.Amount
+	}
+	if amount > eligible.Amount {
+		amount = eligible.Amount
+	}
+
+	return &CouponDiscount{
+		Code:        coupon.Code,
+		Description: coupon.Description,
+		Amount:      models.Money{Amount: amount, Currency: eligible.Currency},
+		Lines:       allocateDiscount(amount, lines, inScope),
+	}, nil
+}
+
+// couponCovers kiểm tra dòng hàng có thuộc phạm vi của mã không (không giới hạn phạm vi = tất cả)
+func couponCovers(coupon *models.Coupon, line CouponLine) bool {
+	if len(coupon.Categories) == 0 && len(coupon.Products) == 0 {
+		return true
+	}
+	for _, productID := range coupon.Products {
+		if productID == line.ProductID {
+			return true
+		}
+	}
+	for _, categoryID := range coupon.Categories {
+		if categoryID == line.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// allocateDiscount chia tiền giảm cho các dòng thuộc phạm vi theo tỷ lệ thành tiền,
+// phần dư do làm tròn dồn vào dòng thuộc phạm vi cuối cùng
+func allocateDiscount(amount int64, lines []CouponLine, inScope []bool) []models.Money {
+	var eligible int64
+	last := -1
+	for i, line := range lines {
+		if inScope[i] {
+			eligible += line.Total.Amount
+			last = i
+		}
+	}
+
+	shares := make([]models.Money, len(lines))
+	remaining := amount
+	for i, line := range lines {
+		shares[i] = models.Money{Currency: line.Total.Currency}
+		if !inScope[i] || eligible == 0 {
+			continue
+		}
+		share := amount * line.Total.Amount / eligible
+		if i == last {
+			share = remaining
+		}
+		shares[i].Amount = share
+		remaining -= share
+	}
+	return shares
+}
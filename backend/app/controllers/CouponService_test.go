@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// createTestCoupon tạo mã giảm giá 10% không giới hạn
+func createTestCoupon(t *testing.T, st store.Store, modify ...func(c *models.Coupon)) *models.Coupon {
+	t.Helper()
+
+	coupon := &models.Coupon{
+		Code:      "SALE10",
+		Type:      models.CouponPercentage,
+		Percent:   10,
+		IsActive:  true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	for _, fn := range modify {
+		fn(coupon)
+	}
+
+	if err := st.Coupons().Insert(context.Background(), coupon); err != nil {
+		t.Fatalf("insert coupon: %v", err)
+	}
+	return coupon
+}
+
+func TestCouponService_CreateCoupon(t *testing.T) {
+	t.Run("tạo mã giảm giá", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCouponService(st)
+		category := createTestCategory(t, st)
+
+		coupon, err := cs.CreateCoupon(CouponInput{
+			Code:       " welcome ",
+			Type:       models.CouponFixed,
+			Amount:     models.VND(50000),
+			Categories: []string{category.CategoryID},
+		})
+		mustNoError(t, err)
+		if coupon.Code != "WELCOME" || !coupon.IsActive || coupon.Amount.Amount != 50000 {
+			t.Fatalf("unexpected coupon: %+v", coupon)
+		}
+
+		_, err = cs.CreateCoupon(CouponInput{Code: "Welcome", Type: models.CouponPercentage, Percent: 5})
+		expectError(t, err, "mã giảm giá đã tồn tại")
+	})
+
+	t.Run("validate dữ liệu", func(t *testing.T) {
+		st := newTestStore()
+		cs := NewCouponService(st)
+		start := time.Now()
+		end := start.Add(-time.Hour)
+
+		cases := []struct {
+			input CouponInput
+			want  string
+		}{
+			{CouponInput{Code: "A", Type: models.CouponFixed, Amount: models.VND(1)}, "3-32 ký tự"},
+			{CouponInput{Code: "FREE", Type: "gift"}, "loại mã giảm giá không hợp lệ"},
+			{CouponInput{Code: "HALF", Type: models.CouponPercentage, Percent: 150}, "phần trăm giảm"},
+			{CouponInput{Code: "ZERO", Type: models.CouponFixed}, "số tiền giảm phải lớn hơn 0"},
+			{CouponInput{Code: "NEG", Type: models.CouponFixed, Amount: models.VND(1), UsageLimit: -1}, "không được âm"},
+			{CouponInput{Code: "WINDOW", Type: models.CouponFixed, Amount: models.VND(1), StartsAt: &start, ExpiresAt: &end}, "thời gian kết thúc"},
+			{CouponInput{Code: "SCOPE", Type: models.CouponFixed, Amount: models.VND(1), Products: []string{"missing"}}, "sản phẩm missing không tồn tại"},
+		}
+		for _, tc := range cases {
+			_, err := cs.CreateCoupon(tc.input)
+			expectError(t, err, tc.want)
+		}
+	})
+}
+
+func TestCouponService_UpdateDeleteCoupon(t *testing.T) {
+	st := newTestStore()
+	cs := NewCouponService(st)
+	coupon := createTestCoupon(t, st, func(c *models.Coupon) { c.UsedCount = 3 })
+	createTestCoupon(t, st, func(c *models.Coupon) { c.Code = "OTHER" })
+
+	_, err := cs.UpdateCoupon(coupon.ID.Hex(), CouponInput{Code: "other", Type: models.CouponFixed, Amount: models.VND(1000)})
+	expectError(t, err, "mã giảm giá đã tồn tại")
+
+	inactive := false
+	updated, err := cs.UpdateCoupon(coupon.ID.Hex(), CouponInput{Code: "SALE20", Type: models.CouponPercentage, Percent: 20, IsActive: &inactive})
+	mustNoError(t, err)
+	if updated.Code != "SALE20" || updated.Percent != 20 || updated.IsActive || updated.UsedCount != 3 {
+		t.Fatalf("unexpected updated coupon: %+v", updated)
+	}
+
+	mustNoError(t, cs.DeleteCoupon(coupon.ID.Hex()))
+	if _, err := cs.GetCouponByID(coupon.ID.Hex()); err != ErrCouponNotFound {
+		t.Fatalf("expected ErrCouponNotFound, got %v", err)
+	}
+}
+
+func TestApplyCoupon(t *testing.T) {
+	now := time.Now()
+	lines := []CouponLine{
+		{ProductID: "p1", Category: "books", Total: models.VND(100000)},
+		{ProductID: "p2", Category: "toys", Total: models.VND(200000)},
+	}
+
+	t.Run("phần trăm có giới hạn tối đa", func(t *testing.T) {
+		coupon := &models.Coupon{Code: "SALE", Type: models.CouponPercentage, Percent: 10, IsActive: true, MaxDiscount: models.VND(25000)}
+		discount, err := applyCoupon(coupon, "u1", lines, now)
+		mustNoError(t, err)
+		if discount.Amount.Amount != 25000 {
+			t.Fatalf("expected capped discount 25000, got %v", discount.Amount)
+		}
+		// 25000 chia theo tỷ lệ 1:2
+		if discount.Lines[0].Amount != 8333 || discount.Lines[1].Amount != 16667 {
+			t.Fatalf("unexpected allocation: %v", discount.Lines)
+		}
+	})
+
+	t.Run("chỉ áp dụng cho danh mục", func(t *testing.T) {
+		coupon := &models.Coupon{Code: "BOOKS", Type: models.CouponFixed, Amount: models.VND(150000), IsActive: true, Categories: []string{"books"}}
+		discount, err := applyCoupon(coupon, "u1", lines, now)
+		mustNoError(t, err)
+		// Không giảm quá giá trị sản phẩm thuộc phạm vi
+		if discount.Amount.Amount != 100000 || discount.Lines[0].Amount != 100000 || !discount.Lines[1].IsZero() {
+			t.Fatalf("unexpected discount: %+v", discount)
+		}
+
+		coupon.Categories = []string{"food"}
+		_, err = applyCoupon(coupon, "u1", lines, now)
+		expectError(t, err, "không áp dụng cho sản phẩm trong giỏ hàng")
+	})
+
+	t.Run("điều kiện áp dụng", func(t *testing.T) {
+		past, future := now.Add(-time.Hour), now.Add(time.Hour)
+		cases := []struct {
+			modify func(c *models.Coupon)
+			want   string
+		}{
+			{func(c *models.Coupon) { c.IsActive = false }, "không còn hiệu lực"},
+			{func(c *models.Coupon) { c.StartsAt = &future }, "chưa đến thời gian áp dụng"},
+			{func(c *models.Coupon) { c.ExpiresAt = &past }, "đã hết hạn"},
+			{func(c *models.Coupon) { c.UsageLimit, c.UsedCount = 5, 5 }, "đã hết lượt sử dụng"},
+			{func(c *models.Coupon) { c.PerUserLimit, c.UserUsage = 1, map[string]int{"u1": 1} }, "bạn đã dùng hết lượt"},
+			{func(c *models.Coupon) { c.MinOrderAmount = models.VND(500000) }, "đơn hàng tối thiểu 500000 VND"},
+		}
+		for _, tc := range cases {
+			coupon := &models.Coupon{Code: "SALE", Type: models.CouponPercentage, Percent: 10, IsActive: true}
+			tc.modify(coupon)
+			_, err := applyCoupon(coupon, "u1", lines, now)
+			expectError(t, err, tc.want)
+		}
+	})
+}
+
+func TestCouponStore_Redeem(t *testing.T) {
+	st := newTestStore()
+	ctx := context.Background()
+	createTestCoupon(t, st, func(c *models.Coupon) {
+		c.UsageLimit = 3
+		c.PerUserLimit = 2
+	})
+
+	mustNoError(t, st.Coupons().Redeem(ctx, "sale10", "u1"))
+	mustNoError(t, st.Coupons().Redeem(ctx, "SALE10", "u1"))
+	if err := st.Coupons().Redeem(ctx, "SALE10", "u1"); err != store.ErrCouponLimitReached {
+		t.Fatalf("expected per-user limit, got %v", err)
+	}
+	mustNoError(t, st.Coupons().Redeem(ctx, "SALE10", "u2"))
+	if err := st.Coupons().Redeem(ctx, "SALE10", "u3"); err != store.ErrCouponLimitReached {
+		t.Fatalf("expected global limit, got %v", err)
+	}
+
+	mustNoError(t, st.Coupons().Release(ctx, "SALE10", "u1"))
+	coupon, _ := st.Coupons().FindByCode(ctx, "SALE10")
+	if coupon.UsedCount != 2 || coupon.UserUsage["u1"] != 1 {
+		t.Fatalf("unexpected usage after release: %d %v", coupon.UsedCount, coupon.UserUsage)
+	}
+}
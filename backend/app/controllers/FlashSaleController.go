@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/store"
+)
+
+// FlashSaleController handles flash sale management HTTP requests (admin)
+type FlashSaleController struct {
+	flashSaleService *FlashSaleService
+}
+
+// NewFlashSaleController creates a new flash sale controller instance
+func NewFlashSaleController(st store.Store) *FlashSaleController {
+	return &FlashSaleController{
+		flashSaleService: NewFlashSaleService(st),
+	}
+}
+
+// respondFlashSaleError trả về status code phù hợp cho lỗi của FlashSaleService
+func respondFlashSaleError(c *gin.Context, err error) {
+	if err == ErrFlashSaleNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var saleErr *FlashSaleError
+	if errors.As(err, &saleErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": "Internal server error",
+	})
+}
+
+// GetFlashSales lấy danh sách flash sale
+func (fc *FlashSaleController) GetFlashSales(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	var isActive *bool
+	if value, err := strconv.ParseBool(c.Query("is_active")); err == nil {
+		isActive = &value
+	}
+
+	result, err := fc.flashSaleService.GetFlashSales(page, limit, c.Query("product_id"), isActive)
+	if err != nil {
+		respondFlashSaleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       result.FlashSales,
+		"pagination": result.Pagination,
+	})
+}
+
+// GetFlashSaleByID lấy chi tiết flash sale
+func (fc *FlashSaleController) GetFlashSaleByID(c *gin.Context) {
+	sale, err := fc.flashSaleService.GetFlashSaleByID(c.Param("id"))
+	if err != nil {
+		respondFlashSaleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sale,
+	})
+}
+
+// CreateFlashSale tạo flash sale mới
+func (fc *FlashSaleController) CreateFlashSale(c *gin.Context) {
+	var req FlashSaleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ: " + err.Error(),
+		})
+		return
+	}
+
+	sale, err := fc.flashSaleService.CreateFlashSale(req)
+	if err != nil {
+		respondFlashSaleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Tạo flash sale thành công",
+		"data":    sale,
+	})
+}
+
+// UpdateFlashSale cập nhật flash sale
+func (fc *FlashSaleController) UpdateFlashSale(c *gin.Context) {
+	var req FlashSaleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ: " + err.Error(),
+		})
+		return
+	}
+
+	sale, err := fc.flashSaleService.UpdateFlashSale(c.Param("id"), req)
+	if err != nil {
+		respondFlashSaleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cập nhật flash sale thành công",
+		"data":    sale,
+	})
+}
+
+// DeleteFlashSale xóa flash sale
+func (fc *FlashSaleController) DeleteFlashSale(c *gin.Context) {
+	if err := fc.flashSaleService.DeleteFlashSale(c.Param("id")); err != nil {
+		respondFlashSaleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Xóa flash sale thành công",
+	})
+}
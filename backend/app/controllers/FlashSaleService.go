@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FlashSaleService xử lý CRUD flash sale cho admin. Giá flash sale được áp dụng qua PricingService.
+type FlashSaleService struct {
+	store store.Store
+}
+
+// NewFlashSaleService creates a new instance of FlashSaleService
+func NewFlashSaleService(st store.Store) *FlashSaleService {
+	return &FlashSaleService{store: st}
+}
+
+// ErrFlashSaleNotFound được trả về bởi các API admin khi không tìm thấy flash sale
+var ErrFlashSaleNotFound = errors.New("flash sale không tồn tại")
+
+// ErrFlashSaleSoldOut được trả về khi đặt hàng mà flash sale không còn đủ số lượng cho đơn
+var ErrFlashSaleSoldOut = errors.New("flash sale không còn đủ số lượng, vui lòng tải lại giỏ hàng để cập nhật giá")
+
+// FlashSaleError là lỗi validate dữ liệu flash sale (lỗi phía client)
+type FlashSaleError struct {
+	Reason string
+}
+
+func (e *FlashSaleError) Error() string {
+	return e.Reason
+}
+
+// FlashSaleInput là dữ liệu tạo/cập nhật flash sale
+type FlashSaleInput struct {
+	Name             string       `json:"name"`
+	ProductID        string       `json:"product_id"`
+	Price            models.Money `json:"price"`
+	Quantity         int          `json:"quantity"`
+	PerCustomerLimit int          `json:"per_customer_limit"`
+	StartsAt         time.Time    `json:"starts_at"`
+	EndsAt           time.Time    `json:"ends_at"`
+	IsActive         *bool        `json:"is_active"` // mặc định true
+}
+
+// FlashSaleListResult là kết quả danh sách flash sale cho admin
+type FlashSaleListResult struct {
+	FlashSales []models.FlashSale     `json:"flash_sales"`
+	Pagination map[string]interface{} `json:"pagination"`
+}
+
+// GetFlashSales lấy danh sách flash sale với pagination (cho admin)
+func (fs *FlashSaleService) GetFlashSales(page, limit int, productID string, isActive *bool) (*FlashSaleListResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := store.FlashSaleFilter{ProductID: productID, IsActive: isActive}
+	sales, err := fs.store.FlashSales().List(ctx, filter, store.ListOptions{
+		Skip:  int64((page - 1) * limit),
+		Limit: int64(limit),
+		Sort:  "-starts_at",
+	})
+	if err != nil {
+		return nil, errors.New("lỗi khi lấy danh sách flash sale")
+	}
+
+	total, err := fs.store.FlashSales().Count(ctx, filter)
+	if err != nil {
+		return nil, errors.New("lỗi khi đếm flash sale")
+	}
+
+	return &FlashSaleListResult{
+		FlashSales: sales,
+		Pagination: map[string]interface{}{
+			"current_page":   page,
+			"total_pages":    (int(total) + limit - 1) / limit,
+			"total_items":    total,
+			"items_per_page": limit,
+		},
+	}, nil
+}
+
+// GetFlashSaleByID lấy chi tiết flash sale
+func (fs *FlashSaleService) GetFlashSaleByID(id string) (*models.FlashSale, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrFlashSaleNotFound
+	}
+
+	sale, err := fs.store.FlashSales().FindByID(ctx, objectID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrFlashSaleNotFound
+		}
+		return nil, errors.New("lỗi khi tìm flash sale")
+	}
+	return sale, nil
+}
+
+// CreateFlashSale tạo flash sale mới
+func (fs *FlashSaleService) CreateFlashSale(input FlashSaleInput) (*models.FlashSale, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sale, err := fs.flashSaleFromInput(ctx, input, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sale.CreatedAt = now
+	sale.UpdatedAt = now
+	if err := fs.store.FlashSales().Insert(ctx, sale); err != nil {
+		return nil, errors.New("lỗi khi tạo flash sale")
+	}
+	return sale, nil
+}
+
+// UpdateFlashSale cập nhật cấu hình flash sale (giữ nguyên số lượng đã bán)
+func (fs *FlashSaleService) UpdateFlashSale(id string, input FlashSaleInput) (*models.FlashSale, error) {
+	existing, err := fs.GetFlashSaleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sale, err := fs.flashSaleFromInput(ctx, input, existing.SoldCount)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := fs.store.FlashSales().Update(ctx, existing.ID, bson.M{
+		"name":               sale.Name,
+		"product_id":         sale.ProductID,
+		"price":              sale.Price,
+		"quantity":           sale.Quantity,
+		"per_customer_limit": sale.PerCustomerLimit,
+		"starts_at":          sale.StartsAt,
+		"ends_at":            sale.EndsAt,
+		"is_active":          sale.IsActive,
+		"updatedAt":          time.Now(),
+	})
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrFlashSaleNotFound
+		}
+		return nil, errors.New("lỗi khi cập nhật flash sale")
+	}
+	return updated, nil
+}
+
+// DeleteFlashSale xóa flash sale. Đơn hàng đã mua giá flash sale vẫn giữ giá đã chốt.
+func (fs *FlashSaleService) DeleteFlashSale(id string) error {
+	existing, err := fs.GetFlashSaleByID(id)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := fs.store.FlashSales().Delete(ctx, existing.ID); err != nil {
+		if err == store.ErrNotFound {
+			return ErrFlashSaleNotFound
+		}
+		return errors.New("lỗi khi xóa flash sale")
+	}
+	return nil
+}
+
+// flashSaleFromInput validate input và chuyển sang models.FlashSale
+func (fs *FlashSaleService) flashSaleFromInput(ctx context.Context, input FlashSaleInput, soldCount int) (*models.FlashSale, error) {
+	sale := &models.FlashSale{
+		Name:             strings.TrimSpace(input.Name),
+		ProductID:        strings.TrimSpace(input.ProductID),
+		Price:            input.Price,
+		Quantity:         input.Quantity,
+		PerCustomerLimit: input.PerCustomerLimit,
+		StartsAt:         input.StartsAt,
+		EndsAt:           input.EndsAt,
+		IsActive:         input.IsActive == nil || *input.IsActive,
+	}
+
+	if sale.Name == "" {
+		return nil, &FlashSaleError{Reason: "tên flash sale là bắt buộc"}
+	}
+	if sale.StartsAt.IsZero() || !sale.EndsAt.After(sale.StartsAt) {
+		return nil, &FlashSaleError{Reason: "thời gian kết thúc phải sau thời gian bắt đầu"}
+	}
+	if sale.Quantity <= 0 || sale.Quantity < soldCount {
+		return nil, &FlashSaleError{Reason: "số lượng flash sale phải lớn hơn 0 và không nhỏ hơn số lượng đã bán"}
+	}
+	if sale.PerCustomerLimit < 0 {
+		return nil, &FlashSaleError{Reason: "giới hạn mỗi khách không được âm"}
+	}
+
+	product, err := fs.store.Products().FindByProductID(ctx, sale.ProductID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, &FlashSaleError{Reason: "sản phẩm " + sale.ProductID + " không tồn tại"}
+		}
+		return nil, errors.New("lỗi khi kiểm tra sản phẩm")
+	}
+	if sale.Price.CurrencyCode() != product.Price.CurrencyCode() || sale.Price.Amount <= 0 || sale.Price.Cmp(product.Price) >= 0 {
+		return nil, &FlashSaleError{Reason: "giá flash sale phải lớn hơn 0 và thấp hơn giá sản phẩm"}
+	}
+
+	return sale, nil
+}
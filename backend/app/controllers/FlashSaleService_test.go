@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// createTestFlashSale tạo flash sale đang diễn ra: giá 60000, 5 suất, mỗi khách 2
+func createTestFlashSale(t *testing.T, st store.Store, product *models.Product, modify ...func(f *models.FlashSale)) *models.FlashSale {
+	t.Helper()
+
+	now := time.Now()
+	sale := &models.FlashSale{
+		Name:             "Flash sale",
+		ProductID:        product.ProductID,
+		Price:            models.VND(60000),
+		Quantity:         5,
+		PerCustomerLimit: 2,
+		StartsAt:         now.Add(-time.Hour),
+		EndsAt:           now.Add(time.Hour),
+		IsActive:         true,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	for _, fn := range modify {
+		fn(sale)
+	}
+
+	if err := st.FlashSales().Insert(context.Background(), sale); err != nil {
+		t.Fatalf("insert flash sale: %v", err)
+	}
+	return sale
+}
+
+func TestFlashSaleService_CreateUpdateFlashSale(t *testing.T) {
+	st := newTestStore()
+	fs := NewFlashSaleService(st)
+	product := createTestProduct(t, st)
+	start := time.Now()
+
+	sale, err := fs.CreateFlashSale(FlashSaleInput{
+		Name:      " Giờ vàng ",
+		ProductID: product.ProductID,
+		Price:     models.VND(50000),
+		Quantity:  10,
+		StartsAt:  start,
+		EndsAt:    start.Add(2 * time.Hour),
+	})
+	mustNoError(t, err)
+	if sale.Name != "Giờ vàng" || !sale.IsActive || sale.Price.Amount != 50000 {
+		t.Fatalf("unexpected flash sale: %+v", sale)
+	}
+
+	cases := []struct {
+		input FlashSaleInput
+		want  string
+	}{
+		{FlashSaleInput{ProductID: product.ProductID}, "tên flash sale là bắt buộc"},
+		{FlashSaleInput{Name: "A", ProductID: product.ProductID, StartsAt: start, EndsAt: start}, "thời gian kết thúc"},
+		{FlashSaleInput{Name: "A", ProductID: product.ProductID, StartsAt: start, EndsAt: start.Add(time.Hour)}, "số lượng flash sale"},
+		{FlashSaleInput{Name: "A", ProductID: "missing", Quantity: 1, StartsAt: start, EndsAt: start.Add(time.Hour)}, "sản phẩm missing không tồn tại"},
+		{FlashSaleInput{Name: "A", ProductID: product.ProductID, Price: models.VND(100000), Quantity: 1, StartsAt: start, EndsAt: start.Add(time.Hour)}, "thấp hơn giá sản phẩm"},
+	}
+	for _, tc := range cases {
+		_, err := fs.CreateFlashSale(tc.input)
+		expectError(t, err, tc.want)
+	}
+
+	// Không được giảm số lượng xuống dưới số đã bán
+	mustNoError(t, st.FlashSales().Reserve(context.Background(), sale.ID, "u1", 4))
+	input := FlashSaleInput{Name: "Giờ vàng", ProductID: product.ProductID, Price: models.VND(40000), Quantity: 3, StartsAt: start, EndsAt: start.Add(time.Hour)}
+	_, err = fs.UpdateFlashSale(sale.ID.Hex(), input)
+	expectError(t, err, "không nhỏ hơn số lượng đã bán")
+
+	input.Quantity = 4
+	updated, err := fs.UpdateFlashSale(sale.ID.Hex(), input)
+	mustNoError(t, err)
+	if updated.Price.Amount != 40000 || updated.SoldCount != 4 {
+		t.Fatalf("unexpected updated flash sale: %+v", updated)
+	}
+
+	mustNoError(t, fs.DeleteFlashSale(sale.ID.Hex()))
+	if _, err := fs.GetFlashSaleByID(sale.ID.Hex()); err != ErrFlashSaleNotFound {
+		t.Fatalf("expected ErrFlashSaleNotFound, got %v", err)
+	}
+}
+
+func TestFlashSaleStore_Reserve(t *testing.T) {
+	st := newTestStore()
+	ctx := context.Background()
+	sale := createTestFlashSale(t, st, createTestProduct(t, st), func(f *models.FlashSale) { f.Quantity = 3 })
+
+	mustNoError(t, st.FlashSales().Reserve(ctx, sale.ID, "u1", 2))
+	if err := st.FlashSales().Reserve(ctx, sale.ID, "u1", 1); err != store.ErrFlashSaleLimitReached {
+		t.Fatalf("expected per-customer limit, got %v", err)
+	}
+	if err := st.FlashSales().Reserve(ctx, sale.ID, "u2", 2); err != store.ErrFlashSaleLimitReached {
+		t.Fatalf("expected quantity limit, got %v", err)
+	}
+	mustNoError(t, st.FlashSales().Reserve(ctx, sale.ID, "u2", 1))
+
+	mustNoError(t, st.FlashSales().Release(ctx, sale.ID, "u1", 2))
+	stored, _ := st.FlashSales().FindByID(ctx, sale.ID)
+	if stored.SoldCount != 1 || stored.CustomerUsage["u1"] != 0 || stored.Remaining("u1") != 2 {
+		t.Fatalf("unexpected usage after release: %d %v", stored.SoldCount, stored.CustomerUsage)
+	}
+}
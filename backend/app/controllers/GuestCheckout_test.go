@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOrderService_GuestCheckout(t *testing.T) {
+	st := newTestStore()
+	os := NewOrderService(st)
+	cs := NewCartService(st)
+	guestID := primitive.NewObjectID()
+	product := createTestProduct(t, st)
+
+	// Giỏ hàng của khách dùng guest id như user id
+	_, err := cs.AddToCart(guestID.Hex(), "guest", product.ProductID, 2)
+	mustNoError(t, err)
+
+	data := validOrderData()
+	data.Guest = true
+	data.PaymentMethod = "cash_on_delivery"
+	data.CustomerEmail = ""
+	expectError(t, os.ValidateOrderData(data), "Email không hợp lệ")
+	data.CustomerEmail = "khach@example.com"
+	mustNoError(t, os.ValidateOrderData(data))
+
+	order, err := os.CreateOrderFromCart(guestID.Hex(), data)
+	mustNoError(t, err)
+	if order.UserID != nil || order.GuestID == nil || *order.GuestID != guestID {
+		t.Fatalf("expected guest order without user, got user=%v guest=%v", order.UserID, order.GuestID)
+	}
+	if order.ShippingAddress.Email != "khach@example.com" || order.ShippingAddress.Phone != data.Phone {
+		t.Fatalf("expected guest contact on order, got %+v", order.ShippingAddress)
+	}
+
+	// Chỉ guest token đã đặt mới xem được đơn
+	found, err := os.GetOrderByID(order.OrderNumber, guestID.Hex())
+	mustNoError(t, err)
+	if found.ID != order.ID {
+		t.Fatalf("expected order %s, got %s", order.ID.Hex(), found.ID.Hex())
+	}
+	_, err = os.GetOrderByID(order.ID.Hex(), primitive.NewObjectID().Hex())
+	expectError(t, err, "đơn hàng không tồn tại")
+
+	result, err := os.GetGuestOrders(guestID.Hex(), 1, 10)
+	mustNoError(t, err)
+	if len(result.Orders) != 1 {
+		t.Fatalf("expected 1 guest order, got %d", len(result.Orders))
+	}
+	user := createTestUser(t, st)
+	createTestOrder(t, st, user.ID)
+	result, err = os.GetUserOrders(guestID.Hex(), 1, 10)
+	mustNoError(t, err)
+	if len(result.Orders) != 0 {
+		t.Fatalf("guest orders must not be listed as user orders")
+	}
+
+	// Khách hủy được đơn của mình
+	cancelled, err := os.CancelOrder(order.ID.Hex(), guestID.Hex(), "đổi ý")
+	mustNoError(t, err)
+	if cancelled.Status != OrderStatusCancelled {
+		t.Fatalf("expected cancelled, got %s", cancelled.Status)
+	}
+	stored, _ := st.Products().FindByProductID(context.Background(), product.ProductID)
+	if stored.Amount != product.Amount {
+		t.Fatalf("expected stock restored to %d, got %d", product.Amount, stored.Amount)
+	}
+}
+
+func TestOrderService_GuestCouponLimitByPhone(t *testing.T) {
+	st := newTestStore()
+	os := NewOrderService(st)
+	product := createTestProduct(t, st)
+	createTestCoupon(t, st, func(c *models.Coupon) { c.PerUserLimit = 1 })
+
+	// Mỗi lần đặt dùng guest id mới nhưng cùng số điện thoại (viết khác nhau)
+	checkout := func(phone string) error {
+		guestID := primitive.NewObjectID()
+		cart := createTestCart(t, st, guestID, []models.CartItem{cartItemFor(product, 1)})
+		cart.CouponCode = "SALE10"
+		mustNoError(t, st.Carts().Save(context.Background(), cart))
+
+		data := validOrderData()
+		data.Guest = true
+		data.PaymentMethod = "cash_on_delivery"
+		data.Phone = phone
+		_, err := os.CreateOrderFromCart(guestID.Hex(), data)
+		return err
+	}
+
+	mustNoError(t, checkout("090 123 4567"))
+	expectError(t, checkout("+84 901 234 567"), "bạn đã dùng hết lượt cho mã giảm giá này")
+	mustNoError(t, checkout("0907654321"))
+	expectError(t, checkout("không có số"), "số điện thoại không hợp lệ")
+
+	coupon, _ := st.Coupons().FindByCode(context.Background(), "SALE10")
+	if coupon.UsedCount != 2 || coupon.UserUsage["guest_phone_0901234567"] != 1 {
+		t.Fatalf("expected usage keyed by phone, got %d %v", coupon.UsedCount, coupon.UserUsage)
+	}
+}
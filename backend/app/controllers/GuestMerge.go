@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Danh sách được gộp khi khách đăng nhập
+const (
+	MergeListCart     = "cart"
+	MergeListWishlist = "wishlist"
+	MergeListCompare  = "compare"
+)
+
+// MergeSkippedItem là sản phẩm của khách không gộp được (hoặc chỉ gộp được một phần) vào tài khoản
+type MergeSkippedItem struct {
+	List        string `json:"list"`
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name,omitempty"`
+	Requested   int    `json:"requested,omitempty"` // Số lượng trong giỏ của khách
+	Merged      int    `json:"merged"`              // Số lượng đã cộng vào giỏ của tài khoản
+	Reason      string `json:"reason"`
+}
+
+// GuestMergeResult báo cáo kết quả gộp giỏ hàng, wishlist và danh sách so sánh của khách vào tài khoản.
+// Error khác rỗng khi không gộp được; khi đó dữ liệu của khách được giữ nguyên.
+type GuestMergeResult struct {
+	CartItems     int                `json:"cart_items"`
+	WishlistItems int                `json:"wishlist_items"`
+	CompareItems  int                `json:"compare_items"`
+	Skipped       []MergeSkippedItem `json:"skipped"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// GuestMergeService gộp dữ liệu gắn với guest token vào tài khoản khi khách đăng nhập hoặc đăng ký
+type GuestMergeService struct {
+	store store.Store
+	carts *CartService
+}
+
+// NewGuestMergeService tạo instance mới của GuestMergeService
+func NewGuestMergeService(st store.Store) *GuestMergeService {
+	return &GuestMergeService{store: st, carts: NewCartService(st)}
+}
+
+// guestMergePlan giữ danh sách của khách, danh sách của tài khoản sau khi gộp (chưa lưu)
+// và bản sao danh sách cũ của tài khoản (nil nếu chưa có) để hoàn tác. Danh sách nil nghĩa là không cần gộp.
+type guestMergePlan struct {
+	guestID, userID                         primitive.ObjectID
+	guestCart, cart, cartBefore             *models.Cart
+	guestWishlist, wishlist, wishlistBefore *models.Wishlist
+	guestCompare, compare, compareBefore    *models.Compare
+}
+
+// Merge gộp giỏ hàng, wishlist và danh sách so sánh của guestID vào userID rồi xóa dữ liệu của khách.
+// Số lượng trong giỏ được cộng dồn nhưng không vượt quá tồn kho, sản phẩm trùng chỉ giữ một,
+// danh sách so sánh không vượt quá MaxCompareItems. Sản phẩm không gộp được nằm trong Skipped.
+func (gs *GuestMergeService) Merge(guestID, userID primitive.ObjectID) (*GuestMergeResult, error) {
+	if guestID == userID {
+		return nil, errors.New("guest id trùng với tài khoản")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := &GuestMergeResult{Skipped: []MergeSkippedItem{}}
+	plan := &guestMergePlan{guestID: guestID, userID: userID}
+	if err := gs.mergeCart(ctx, plan, result); err != nil {
+		return nil, err
+	}
+	if err := gs.mergeWishlist(ctx, plan, result); err != nil {
+		return nil, err
+	}
+	if err := gs.mergeCompare(ctx, plan, result); err != nil {
+		return nil, err
+	}
+
+	err := runAtomic(ctx, gs.store, func(ctx context.Context, rb *rollback) error {
+		if plan.cart != nil {
+			if err := gs.store.Carts().Save(ctx, plan.cart); err != nil {
+				return err
+			}
+			rb.onRollback(func(ctx context.Context) error {
+				if plan.cartBefore == nil {
+					return gs.store.Carts().Clear(ctx, userID, "cart")
+				}
+				return gs.store.Carts().Save(ctx, plan.cartBefore)
+			})
+			if err := gs.store.Carts().Clear(ctx, guestID, "cart"); err != nil {
+				return err
+			}
+			rb.onRollback(func(ctx context.Context) error { return gs.store.Carts().Save(ctx, plan.guestCart) })
+		}
+
+		if plan.wishlist != nil {
+			if err := gs.store.Wishlists().Save(ctx, plan.wishlist); err != nil {
+				return err
+			}
+			rb.onRollback(func(ctx context.Context) error {
+				if plan.wishlistBefore == nil {
+					return gs.store.Wishlists().Clear(ctx, userID)
+				}
+				return gs.store.Wishlists().Save(ctx, plan.wishlistBefore)
+			})
+			if err := gs.store.Wishlists().Clear(ctx, guestID); err != nil {
+				return err
+			}
+			rb.onRollback(func(ctx context.Context) error { return gs.store.Wishlists().Save(ctx, plan.guestWishlist) })
+		}
+
+		if plan.compare != nil {
+			if err := gs.store.Compares().Save(ctx, plan.compare); err != nil {
+				return err
+			}
+			rb.onRollback(func(ctx context.Context) error {
+				if plan.compareBefore == nil {
+					return gs.store.Compares().Clear(ctx, userID)
+				}
+				return gs.store.Compares().Save(ctx, plan.compareBefore)
+			})
+			if err := gs.store.Compares().Clear(ctx, guestID); err != nil {
+				return err
+			}
+			rb.onRollback(func(ctx context.Context) error { return gs.store.Compares().Save(ctx, plan.guestCompare) })
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeCart tính giỏ hàng sau khi gộp, cộng dồn số lượng trong giới hạn tồn kho
+func (gs *GuestMergeService) mergeCart(ctx context.Context, plan *guestMergePlan, result *GuestMergeResult) error {
+	guestCart, err := gs.store.Carts().FindByUser(ctx, plan.guestID, "cart")
+	if err == store.ErrNotFound || (err == nil && len(guestCart.Items) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cart, err := gs.carts.FindOrCreateCart(plan.userID.Hex())
+	if err != nil {
+		return err
+	}
+	if !cart.ID.IsZero() {
+		before := *cart
+		before.Items = append([]models.CartItem(nil), cart.Items...)
+		plan.cartBefore = &before
+	}
+
+	for _, guestItem := range guestCart.Items {
+		skipped := MergeSkippedItem{
+			List:        MergeListCart,
+			ProductID:   guestItem.ProductID,
+			ProductName: guestItem.ProductName,
+			Requested:   guestItem.Quantity,
+		}
+		product, err := gs.store.Products().FindByProductID(ctx, guestItem.ProductID)
+		if err == store.ErrNotFound {
+			skipped.Reason = "sản phẩm không tồn tại"
+			result.Skipped = append(result.Skipped, skipped)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		existing := gs.carts.FindCartItem(cart, product.ProductID)
+		current := 0
+		if existing != nil {
+			current = existing.Quantity
+		}
+		merged := guestItem.Quantity
+		if current+merged > product.Amount {
+			merged = product.Amount - current
+			if merged < 0 {
+				merged = 0
+			}
+			skipped.Merged = merged
+			skipped.Reason = fmt.Sprintf("sản phẩm chỉ còn %d trong kho", product.Amount)
+			if product.Amount <= 0 {
+				skipped.Reason = "sản phẩm đã hết hàng"
+			}
+			result.Skipped = append(result.Skipped, skipped)
+		}
+		if merged == 0 {
+			continue
+		}
+
+		if existing != nil {
+			existing.Quantity += merged
+		} else {
+			cart.Items = append(cart.Items, models.CartItem{
+				ProductID:    product.ProductID,
+				ProductName:  product.Name,
+				ProductImage: product.Image,
+				ProductSlug:  product.Slug,
+				Price:        product.Price,
+				ListPrice:    product.Price,
+				Quantity:     merged,
+			})
+		}
+		result.CartItems++
+	}
+
+	// Mã giảm giá của tài khoản được ưu tiên; mã của khách sẽ được kiểm tra lại khi xem giỏ hàng
+	if cart.CouponCode == "" {
+		cart.CouponCode = guestCart.CouponCode
+	}
+	gs.carts.refreshPrices(cart, plan.userID.Hex())
+	if err := gs.carts.CalculateCartTotals(cart); err != nil {
+		return err
+	}
+	cart.UpdatedAt = time.Now()
+	plan.guestCart, plan.cart = guestCart, cart
+	return nil
+}
+
+// mergeWishlist tính wishlist sau khi gộp (chưa lưu), bỏ qua sản phẩm đã có hoặc không còn tồn tại
+func (gs *GuestMergeService) mergeWishlist(ctx context.Context, plan *guestMergePlan, result *GuestMergeResult) error {
+	guestWishlist, err := gs.store.Wishlists().FindByUser(ctx, plan.guestID)
+	if err == store.ErrNotFound || (err == nil && len(guestWishlist.Items) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	wishlist, err := gs.store.Wishlists().FindByUser(ctx, plan.userID)
+	if err == store.ErrNotFound {
+		wishlist = &models.Wishlist{UserID: plan.userID, Items: []models.WishlistItem{}, CreatedAt: time.Now()}
+	} else if err != nil {
+		return err
+	} else {
+		before := *wishlist
+		before.Items = append([]models.WishlistItem(nil), wishlist.Items...)
+		plan.wishlistBefore = &before
+	}
+
+	seen := make(map[string]bool, len(wishlist.Items))
+	for _, item := range wishlist.Items {
+		seen[item.ProductID] = true
+	}
+	for _, item := range guestWishlist.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		if _, err := gs.store.Products().FindByProductID(ctx, item.ProductID); err == store.ErrNotFound {
+			result.Skipped = append(result.Skipped, MergeSkippedItem{
+				List: MergeListWishlist, ProductID: item.ProductID, ProductName: item.ProductName,
+				Reason: "sản phẩm không tồn tại",
+			})
+			continue
+		} else if err != nil {
+			return err
+		}
+		seen[item.ProductID] = true
+		wishlist.Items = append(wishlist.Items, item)
+		result.WishlistItems++
+	}
+
+	wishlist.TotalItems = len(wishlist.Items)
+	wishlist.UpdatedAt = time.Now()
+	plan.guestWishlist, plan.wishlist = guestWishlist, wishlist
+	return nil
+}
+
+// mergeCompare tính danh sách so sánh sau khi gộp (chưa lưu), giữ tối đa MaxCompareItems sản phẩm
+func (gs *GuestMergeService) mergeCompare(ctx context.Context, plan *guestMergePlan, result *GuestMergeResult) error {
+	guestCompare, err := gs.store.Compares().FindByUser(ctx, plan.guestID)
+	if err == store.ErrNotFound || (err == nil && len(guestCompare.Items) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	compare, err := gs.store.Compares().FindByUser(ctx, plan.userID)
+	if err == store.ErrNotFound {
+		compare = &models.Compare{UserID: plan.userID, Items: []models.CompareItem{}, CreatedAt: time.Now()}
+	} else if err != nil {
+		return err
+	} else {
+		before := *compare
+		before.Items = append([]models.CompareItem(nil), compare.Items...)
+		plan.compareBefore = &before
+	}
+
+	seen := make(map[string]bool, len(compare.Items))
+	for _, item := range compare.Items {
+		seen[item.ProductID] = true
+	}
+	for _, item := range guestCompare.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		skipped := MergeSkippedItem{List: MergeListCompare, ProductID: item.ProductID, ProductName: item.ProductName}
+		if _, err := gs.store.Products().FindByProductID(ctx, item.ProductID); err == store.ErrNotFound {
+			skipped.Reason = "sản phẩm không tồn tại"
+			result.Skipped = append(result.Skipped, skipped)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if len(compare.Items) >= MaxCompareItems {
+			skipped.Reason = fmt.Sprintf("chỉ có thể so sánh tối đa %d sản phẩm", MaxCompareItems)
+			result.Skipped = append(result.Skipped, skipped)
+			continue
+		}
+		seen[item.ProductID] = true
+		compare.Items = append(compare.Items, item)
+		result.CompareItems++
+	}
+
+	compare.TotalItems = len(compare.Items)
+	compare.UpdatedAt = time.Now()
+	plan.guestCompare, plan.compare = guestCompare, compare
+	return nil
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGuestMergeService_Merge(t *testing.T) {
+	st := newTestStore()
+	ctx := context.Background()
+	user := createTestUser(t, st)
+	guestID := primitive.NewObjectID()
+	limited := createTestProduct(t, st)
+	fresh := createTestProduct(t, st)
+	soldOut := createTestProduct(t, st, func(p *models.Product) { p.Amount = 0 })
+
+	createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(limited, 7)})
+	createTestCart(t, st, guestID, []models.CartItem{
+		cartItemFor(limited, 5),
+		cartItemFor(fresh, 2),
+		cartItemFor(soldOut, 1),
+		{ProductID: "khong-ton-tai", ProductName: "Đã xóa", Quantity: 1, Price: models.VND(1000)},
+	})
+
+	wishlistItem := func(p *models.Product) models.WishlistItem {
+		return models.WishlistItem{ProductID: p.ProductID, ProductName: p.Name, Price: p.Price}
+	}
+	mustNoError(t, st.Wishlists().Save(ctx, &models.Wishlist{UserID: user.ID, Items: []models.WishlistItem{wishlistItem(limited)}}))
+	mustNoError(t, st.Wishlists().Save(ctx, &models.Wishlist{UserID: guestID, Items: []models.WishlistItem{wishlistItem(limited), wishlistItem(fresh)}}))
+
+	compareItems := make([]models.CompareItem, 0, MaxCompareItems)
+	for i := 0; i < MaxCompareItems-1; i++ {
+		p := createTestProduct(t, st)
+		compareItems = append(compareItems, models.CompareItem{ProductID: p.ProductID})
+	}
+	mustNoError(t, st.Compares().Save(ctx, &models.Compare{UserID: user.ID, Items: compareItems}))
+	mustNoError(t, st.Compares().Save(ctx, &models.Compare{UserID: guestID, Items: []models.CompareItem{
+		compareItems[0], {ProductID: fresh.ProductID}, {ProductID: limited.ProductID},
+	}}))
+
+	result, err := NewGuestMergeService(st).Merge(guestID, user.ID)
+	mustNoError(t, err)
+	if result.CartItems != 2 || result.WishlistItems != 1 || result.CompareItems != 1 {
+		t.Fatalf("unexpected merge counts %+v", result)
+	}
+
+	// Cộng dồn nhưng không vượt tồn kho; sản phẩm hết hàng hoặc đã xóa được báo lại
+	skipped := map[string]MergeSkippedItem{}
+	for _, item := range result.Skipped {
+		skipped[item.List+"/"+item.ProductID] = item
+	}
+	if item := skipped["cart/"+limited.ProductID]; item.Requested != 5 || item.Merged != 3 || item.Reason != "sản phẩm chỉ còn 10 trong kho" {
+		t.Fatalf("expected limited item clamped to stock, got %+v", item)
+	}
+	if skipped["cart/"+soldOut.ProductID].Reason != "sản phẩm đã hết hàng" || skipped["cart/khong-ton-tai"].Reason != "sản phẩm không tồn tại" {
+		t.Fatalf("unexpected skipped items %+v", result.Skipped)
+	}
+	if _, ok := skipped["compare/"+limited.ProductID]; !ok || len(result.Skipped) != 4 {
+		t.Fatalf("expected compare overflow to be reported, got %+v", result.Skipped)
+	}
+
+	cart, err := st.Carts().FindByUser(ctx, user.ID, "cart")
+	mustNoError(t, err)
+	if len(cart.Items) != 2 || cart.Items[0].Quantity != 10 || cart.Items[1].ProductID != fresh.ProductID || cart.Items[1].Quantity != 2 {
+		t.Fatalf("unexpected merged cart %+v", cart.Items)
+	}
+	if cart.TotalAmount.Cmp(models.VND(1200000)) != 0 {
+		t.Fatalf("expected totals recalculated, got %v", cart.TotalAmount)
+	}
+	wishlist, _ := st.Wishlists().FindByUser(ctx, user.ID)
+	compare, _ := st.Compares().FindByUser(ctx, user.ID)
+	if len(wishlist.Items) != 2 || len(compare.Items) != MaxCompareItems || compare.Items[MaxCompareItems-1].ProductID != fresh.ProductID {
+		t.Fatalf("unexpected merged lists wishlist=%+v compare=%+v", wishlist.Items, compare.Items)
+	}
+
+	// Dữ liệu của khách được xóa nên gộp lại lần nữa không cộng thêm
+	guestCart, _ := st.Carts().FindByUser(ctx, guestID, "cart")
+	guestWishlist, _ := st.Wishlists().FindByUser(ctx, guestID)
+	guestCompare, _ := st.Compares().FindByUser(ctx, guestID)
+	if len(guestCart.Items) != 0 || len(guestWishlist.Items) != 0 || len(guestCompare.Items) != 0 {
+		t.Fatalf("expected guest lists to be cleared")
+	}
+	result, err = NewGuestMergeService(st).Merge(guestID, user.ID)
+	mustNoError(t, err)
+	if result.CartItems != 0 || len(result.Skipped) != 0 {
+		t.Fatalf("expected second merge to be a no-op, got %+v", result)
+	}
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// InvoiceController handles invoice HTTP requests
+type InvoiceController struct {
+	invoiceService *InvoiceService
+}
+
+// NewInvoiceController creates a new invoice controller instance
+func NewInvoiceController(st store.Store) *InvoiceController {
+	return &InvoiceController{
+		invoiceService: NewInvoiceService(st, invoiceSellerFromEnv(), invoiceNumberPrefix()),
+	}
+}
+
+// GetInvoicePDF trả về hóa đơn PDF của đơn hàng. Đơn hàng đã được ValidateOrderAccess kiểm tra quyền và đặt vào context.
+func (ic *InvoiceController) GetInvoicePDF(c *gin.Context) {
+	value, exists := c.Get("order")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Order information missing",
+		})
+		return
+	}
+	order := value.(models.Order)
+
+	issued, pdf, err := ic.invoiceService.InvoicePDF(&order)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, issued.Invoice.Number))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// DownloadInvoices tải ZIP hóa đơn của các đơn tạo trong khoảng date_from..date_to (admin).
+// Ngày dạng YYYY-MM-DD được tính trọn ngày.
+func (ic *InvoiceController) DownloadInvoices(c *gin.Context) {
+	from, okFrom := parseFilterDate(c.Query("date_from"))
+	to, okTo := parseFilterDateTo(c.Query("date_to"))
+	if !okFrom || !okTo {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "date_from và date_to là bắt buộc (YYYY-MM-DD hoặc RFC3339)",
+		})
+		return
+	}
+
+	orders, err := ic.invoiceService.InvoicesInRange(from, to)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	if len(orders) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Không có hóa đơn nào trong khoảng thời gian này",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("invoices_%s_%s.zip", from.Format("20060102"), to.Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := ic.invoiceService.WriteZIP(c.Writer, orders); err != nil {
+		// Header đã được gửi, chỉ có thể log lỗi
+		log.Printf("Error writing invoice ZIP %s: %v", filename, err)
+	}
+}
+
+func respondInvoiceError(c *gin.Context, err error) {
+	var invoiceErr *InvoiceError
+	switch {
+	case errors.As(err, &invoiceErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case err.Error() == "đơn hàng không tồn tại":
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+	}
+}
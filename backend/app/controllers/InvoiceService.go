@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// defaultInvoiceNumberPrefix là ký hiệu hóa đơn khi không cấu hình INVOICE_NUMBER_PREFIX
+const defaultInvoiceNumberPrefix = "HD"
+
+// MaxInvoiceExportRange giới hạn khoảng thời gian của một lần tải ZIP hóa đơn
+const MaxInvoiceExportRange = 366 * 24 * time.Hour
+
+// InvoiceError được trả về khi đơn hàng không thể xuất hóa đơn
+type InvoiceError struct {
+	Reason string
+}
+
+func (e *InvoiceError) Error() string {
+	return e.Reason
+}
+
+// InvoiceSeller là thông tin đơn vị bán hàng in trên hóa đơn
+type InvoiceSeller struct {
+	Name    string
+	TaxCode string
+	Address string
+	Phone   string
+	Email   string
+}
+
+// invoiceSellerFromEnv đọc thông tin người bán từ INVOICE_SELLER_*
+func invoiceSellerFromEnv() InvoiceSeller {
+	return InvoiceSeller{
+		Name:    os.Getenv("INVOICE_SELLER_NAME"),
+		TaxCode: os.Getenv("INVOICE_SELLER_TAX_CODE"),
+		Address: os.Getenv("INVOICE_SELLER_ADDRESS"),
+		Phone:   os.Getenv("INVOICE_SELLER_PHONE"),
+		Email:   os.Getenv("INVOICE_SELLER_EMAIL"),
+	}
+}
+
+// InvoiceService cấp số hóa đơn và xuất hóa đơn GTGT dạng PDF từ đơn hàng.
+// Số hóa đơn có dạng <prefix><năm>-<sequence>, ví dụ HD2025-000017, tăng dần theo năm và
+// chỉ được cấp khi hóa đơn được xuất lần đầu.
+type InvoiceService struct {
+	store  store.Store
+	seller InvoiceSeller
+	prefix string
+	now    func() time.Time
+}
+
+// NewInvoiceService creates a new invoice service; prefix rỗng = "HD"
+func NewInvoiceService(st store.Store, seller InvoiceSeller, prefix string) *InvoiceService {
+	if prefix == "" {
+		prefix = defaultInvoiceNumberPrefix
+	}
+	return &InvoiceService{
+		store:  st,
+		seller: seller,
+		prefix: prefix,
+		now:    time.Now,
+	}
+}
+
+// invoiceNumberPrefix đọc ký hiệu hóa đơn từ INVOICE_NUMBER_PREFIX
+func invoiceNumberPrefix() string {
+	return os.Getenv("INVOICE_NUMBER_PREFIX")
+}
+
+// invoiceable: đơn đã xác nhận mới được xuất hóa đơn; đơn đã có hóa đơn thì luôn in lại được
+func invoiceable(order *models.Order) bool {
+	if order.Invoice != nil {
+		return true
+	}
+	switch normalizeOrderStatus(order.Status) {
+	case OrderStatusPending, OrderStatusCancelled:
+		return false
+	}
+	return true
+}
+
+// Issue cấp số hóa đơn cho đơn hàng nếu chưa có và trả về đơn đã gắn hóa đơn
+func (is *InvoiceService) Issue(ctx context.Context, order *models.Order) (*models.Order, error) {
+	if order.Invoice != nil {
+		return order, nil
+	}
+	if !invoiceable(order) {
+		return nil, &InvoiceError{Reason: "chỉ có thể xuất hóa đơn cho đơn hàng đã xác nhận"}
+	}
+
+	now := is.now()
+	year := now.Format("2006")
+	seq, err := is.store.Counters().Next(ctx, "invoice_number:"+year)
+	if err != nil {
+		return nil, errors.New("lỗi khi cấp số hóa đơn")
+	}
+	invoice := models.Invoice{Number: fmt.Sprintf("%s%s-%06d", is.prefix, year, seq), IssuedAt: now}
+
+	if err := is.store.Orders().AssignInvoice(ctx, order.ID, invoice); err != nil {
+		if err != store.ErrStatusConflict {
+			return nil, errors.New("lỗi khi lưu hóa đơn")
+		}
+		// Request khác vừa cấp hóa đơn cho đơn này: dùng hóa đơn đó, số vừa lấy bị bỏ trống
+		log.Printf("Invoice number %s skipped: order %s was invoiced concurrently", invoice.Number, order.OrderNumber)
+		updated, err := is.store.Orders().FindByID(ctx, order.ID)
+		if err != nil {
+			return nil, errors.New("đơn hàng không tồn tại")
+		}
+		return updated, nil
+	}
+
+	issued := *order
+	issued.Invoice = &invoice
+	return &issued, nil
+}
+
+// InvoicePDF cấp số (nếu cần) và trả về hóa đơn PDF của đơn hàng
+func (is *InvoiceService) InvoicePDF(order *models.Order) (*models.Order, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	issued, err := is.Issue(ctx, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	return issued, is.Render(issued), nil
+}
+
+// InvoicesInRange cấp số hóa đơn cho các đơn tạo trong [from, to] theo thứ tự thời gian và trả về
+// các đơn có hóa đơn; đơn chưa xác nhận hoặc đã hủy khi chưa xuất hóa đơn được bỏ qua
+func (is *InvoiceService) InvoicesInRange(from, to time.Time) ([]models.Order, error) {
+	if to.Before(from) {
+		return nil, &InvoiceError{Reason: "khoảng thời gian không hợp lệ"}
+	}
+	if to.Sub(from) > MaxInvoiceExportRange {
+		return nil, &InvoiceError{Reason: "chỉ có thể tải hóa đơn tối đa 1 năm mỗi lần"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	orders, err := is.store.Orders().List(ctx, store.OrderFilter{DateFrom: &from, DateTo: &to}, store.ListOptions{})
+	if err != nil {
+		return nil, errors.New("lỗi khi lấy danh sách đơn hàng")
+	}
+
+	// List trả về đơn mới nhất trước; cấp số theo thứ tự đặt hàng
+	var invoiced []models.Order
+	for i := len(orders) - 1; i >= 0; i-- {
+		if !invoiceable(&orders[i]) {
+			continue
+		}
+		issued, err := is.Issue(ctx, &orders[i])
+		if err != nil {
+			return nil, err
+		}
+		invoiced = append(invoiced, *issued)
+	}
+	return invoiced, nil
+}
+
+// WriteZIP ghi hóa đơn PDF của các đơn vào w dưới dạng file ZIP
+func (is *InvoiceService) WriteZIP(w io.Writer, orders []models.Order) error {
+	archive := zip.NewWriter(w)
+	for i := range orders {
+		order := &orders[i]
+		if order.Invoice == nil {
+			continue
+		}
+		header := &zip.FileHeader{
+			Name:     fmt.Sprintf("%s_%s.pdf", order.Invoice.Number, order.OrderNumber),
+			Method:   zip.Deflate,
+			Modified: order.Invoice.IssuedAt,
+		}
+		file, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(is.Render(order)); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// invoicePaymentMethods là tên hình thức thanh toán in trên hóa đơn
+var invoicePaymentMethods = map[string]string{
+	"cash_on_delivery": "Tiền mặt khi nhận hàng (COD)",
+	BankTransferMethod: "Chuyển khoản",
+	"credit_card":      "Thẻ tín dụng",
+	"e_wallet":         "Ví điện tử",
+}
+
+// invoiceBuyer trả về thông tin người mua: địa chỉ thanh toán nếu có, không thì địa chỉ giao hàng
+func invoiceBuyer(order *models.Order) models.BillingAddress {
+	if order.BillingAddress != nil {
+		return *order.BillingAddress
+	}
+	a := order.ShippingAddress
+	return models.BillingAddress{
+		FullName:   a.FullName,
+		Phone:      a.Phone,
+		Email:      a.Email,
+		Street:     a.Street,
+		City:       a.City,
+		State:      a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
+
+// Render vẽ hóa đơn GTGT khổ A4 cho đơn đã có số hóa đơn
+func (is *InvoiceService) Render(order *models.Order) []byte {
+	const left, right = 40.0, PDFA4Width - 40
+	doc := NewPDFDocument(PDFA4Width, PDFA4Height)
+
+	y := 800.0
+	doc.Text(left, y, 16, true, "HÓA ĐƠN GIÁ TRỊ GIA TĂNG")
+	doc.Text(left, y-14, 9, false, "(VAT INVOICE)")
+	if order.Invoice != nil {
+		doc.TextRight(right, y, 10, true, "Số: "+order.Invoice.Number)
+		doc.TextRight(right, y-14, 9, false, "Ngày: "+order.Invoice.IssuedAt.Format("02/01/2006"))
+	}
+	doc.TextRight(right, y-28, 9, false, "Đơn hàng: "+order.OrderNumber)
+	if normalizeOrderStatus(order.Status) == OrderStatusCancelled {
+		doc.Text(left, y-28, 10, true, "ĐƠN HÀNG ĐÃ HỦY")
+	}
+	y -= 40
+	doc.Line(left, y, right, y)
+
+	// Người bán
+	y -= 18
+	doc.Text(left, y, 10, true, "Đơn vị bán hàng: "+is.seller.Name)
+	for _, line := range []string{
+		"Mã số thuế: " + is.seller.TaxCode,
+		"Địa chỉ: " + is.seller.Address,
+		strings.TrimSpace("Điện thoại: " + is.seller.Phone + "   " + is.seller.Email),
+	} {
+		y -= 14
+		doc.Text(left, y, 9, false, line)
+	}
+	y -= 10
+	doc.Line(left, y, right, y)
+
+	// Người mua
+	buyer := invoiceBuyer(order)
+	address := []string{}
+	for _, part := range []string{buyer.Street, buyer.State, buyer.City, buyer.PostalCode, buyer.Country} {
+		if part != "" {
+			address = append(address, part)
+		}
+	}
+	method := invoicePaymentMethods[order.Payment.Method]
+	if method == "" {
+		method = order.Payment.Method
+	}
+	y -= 18
+	doc.Text(left, y, 10, true, "Người mua hàng: "+buyer.FullName)
+	for _, line := range []string{
+		"Địa chỉ: " + strings.Join(address, ", "),
+		"Điện thoại: " + buyer.Phone + "   Email: " + buyer.Email,
+		"Hình thức thanh toán: " + method,
+	} {
+		y -= 14
+		doc.Text(left, y, 9, false, line)
+	}
+	y -= 10
+	doc.Line(left, y, right, y)
+
+	// Bảng hàng hóa
+	header := func() {
+		y -= 16
+		doc.Text(left, y, 9, true, "STT")
+		doc.Text(left+30, y, 9, true, "Tên hàng hóa, dịch vụ")
+		doc.TextRight(330, y, 9, true, "SL")
+		doc.TextRight(420, y, 9, true, "Đơn giá")
+		doc.TextRight(465, y, 9, true, "VAT")
+		doc.TextRight(right, y, 9, true, "Thành tiền")
+		y -= 6
+		doc.Line(left, y, right, y)
+	}
+	header()
+	for i, item := range order.Items {
+		if y < 80 {
+			doc.AddPage()
+			y = 800
+			header()
+		}
+		y -= 14
+		name := []rune(item.ProductName)
+		if len(name) > 48 {
+			name = append(name[:45], '.', '.', '.')
+		}
+		rate := "-"
+		if item.Tax != nil {
+			rate = fmt.Sprintf("%g%%", item.Tax.Rate)
+		}
+		doc.Text(left, y, 9, false, fmt.Sprintf("%d", i+1))
+		doc.Text(left+30, y, 9, false, string(name))
+		doc.TextRight(330, y, 9, false, fmt.Sprintf("%d", item.Quantity))
+		doc.TextRight(420, y, 9, false, invoiceMoney(item.Price))
+		doc.TextRight(465, y, 9, false, rate)
+		doc.TextRight(right, y, 9, false, invoiceMoney(item.Total))
+	}
+	y -= 8
+	doc.Line(left, y, right, y)
+
+	// Tổng tiền
+	type totalLine struct {
+		label  string
+		amount string
+		bold   bool
+	}
+	tax := "Thuế GTGT"
+	if order.TaxInclusive {
+		tax += " (đã gồm trong giá)"
+	}
+	lines := []totalLine{{label: "Cộng tiền hàng", amount: invoiceMoney(order.Subtotal)}}
+	if !order.DiscountAmount.IsZero() {
+		label := "Giảm giá"
+		if order.CouponCode != "" {
+			label += " (" + order.CouponCode + ")"
+		}
+		lines = append(lines, totalLine{label: label, amount: "-" + invoiceMoney(order.DiscountAmount)})
+	}
+	lines = append(lines,
+		totalLine{label: "Phí vận chuyển", amount: invoiceMoney(order.ShippingAmount)},
+		totalLine{label: tax, amount: invoiceMoney(order.TaxAmount)},
+		totalLine{label: "Tổng cộng tiền thanh toán", amount: invoiceMoney(order.TotalAmount), bold: true},
+	)
+	if !order.RefundedAmount.IsZero() {
+		lines = append(lines, totalLine{label: "Đã hoàn tiền", amount: invoiceMoney(order.RefundedAmount)})
+	}
+	if y < 40+float64(len(lines))*16 {
+		doc.AddPage()
+		y = 800
+	}
+	for _, line := range lines {
+		y -= 16
+		doc.TextRight(430, y, 10, line.bold, line.label+":")
+		doc.TextRight(right, y, 10, line.bold, line.amount)
+	}
+
+	doc.Text(left, 30, 8, false, "Hóa đơn được tạo tự động từ hệ thống bán hàng.")
+	return doc.Bytes()
+}
+
+// invoiceMoney format số tiền kiểu Việt Nam: 1.250.000 VND, 12,50 USD
+func invoiceMoney(m models.Money) string {
+	amount, currency, _ := strings.Cut(m.String(), " ")
+	sign := ""
+	if strings.HasPrefix(amount, "-") {
+		sign, amount = "-", amount[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(amount, ".")
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		b.WriteString("," + frac)
+	}
+	return sign + b.String() + " " + currency
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+func newTestInvoiceService(t *testing.T) (*InvoiceService, func(*models.Order)) {
+	t.Helper()
+	st := newTestStore()
+	is := NewInvoiceService(st, InvoiceSeller{Name: "Cửa hàng Test", TaxCode: "0312345678"}, "")
+	is.now = func() time.Time { return time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local) }
+	return is, func(o *models.Order) { o.Status = OrderStatusConfirmed }
+}
+
+func TestInvoiceService_Issue(t *testing.T) {
+	is, confirmed := newTestInvoiceService(t)
+	user := createTestUser(t, is.store)
+	ctx := context.Background()
+
+	pending := createTestOrder(t, is.store, user.ID)
+	_, err := is.Issue(ctx, pending)
+	expectError(t, err, "đơn hàng đã xác nhận")
+
+	first := createTestOrder(t, is.store, user.ID, confirmed, func(o *models.Order) {
+		o.BillingAddress = &models.BillingAddress{FullName: "Công ty TNHH Minh Phát", Street: "1 Lê Lợi", City: "Hà Nội"}
+		o.DiscountAmount = models.VND(20000)
+		o.CouponCode = "SALE10"
+		o.TaxAmount = models.VND(18182)
+		o.TaxInclusive = true
+	})
+	second := createTestOrder(t, is.store, user.ID, confirmed)
+
+	issued, pdf, err := is.InvoicePDF(first)
+	mustNoError(t, err)
+	if issued.Invoice == nil || issued.Invoice.Number != "HD2025-000001" {
+		t.Fatalf("unexpected invoice %+v", issued.Invoice)
+	}
+	for _, want := range []string{"%PDF-", "HD2025-000001", "Cong ty TNHH Minh Phat", "Giam gia \\(SALE10\\)", "-20.000 VND", "200.000 VND", "0312345678"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Fatalf("expected invoice PDF to contain %q", want)
+		}
+	}
+
+	next, err := is.Issue(ctx, second)
+	mustNoError(t, err)
+	if next.Invoice.Number != "HD2025-000002" {
+		t.Fatalf("expected sequential invoice number, got %s", next.Invoice.Number)
+	}
+
+	// In lại dùng số đã cấp, kể cả khi đơn đã bị hủy sau đó
+	stored, _ := is.store.Orders().FindByID(ctx, first.ID)
+	stored.Status = OrderStatusCancelled
+	again, err := is.Issue(ctx, stored)
+	mustNoError(t, err)
+	if again.Invoice.Number != "HD2025-000001" {
+		t.Fatalf("expected invoice number to be kept, got %s", again.Invoice.Number)
+	}
+}
+
+func TestInvoiceService_InvoicesInRangeZIP(t *testing.T) {
+	is, confirmed := newTestInvoiceService(t)
+	user := createTestUser(t, is.store)
+	day := time.Date(2025, 2, 10, 0, 0, 0, 0, time.Local)
+	at := func(offset time.Duration) func(*models.Order) {
+		return func(o *models.Order) { o.CreatedAt = day.Add(offset) }
+	}
+
+	later := createTestOrder(t, is.store, user.ID, confirmed, at(20*time.Hour))
+	earlier := createTestOrder(t, is.store, user.ID, confirmed, at(8*time.Hour))
+	createTestOrder(t, is.store, user.ID, at(9*time.Hour)) // pending: bỏ qua
+	createTestOrder(t, is.store, user.ID, confirmed, at(30*time.Hour))
+
+	orders, err := is.InvoicesInRange(day, day.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	mustNoError(t, err)
+	if len(orders) != 2 || orders[0].ID != earlier.ID || orders[1].ID != later.ID {
+		t.Fatalf("expected 2 invoices in order, got %d", len(orders))
+	}
+	if orders[0].Invoice.Number != "HD2025-000001" || orders[1].Invoice.Number != "HD2025-000002" {
+		t.Fatalf("expected invoice numbers in order date order, got %s %s", orders[0].Invoice.Number, orders[1].Invoice.Number)
+	}
+
+	var buf bytes.Buffer
+	mustNoError(t, is.WriteZIP(&buf, orders))
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	mustNoError(t, err)
+	if len(archive.File) != 2 || archive.File[0].Name != "HD2025-000001_"+earlier.OrderNumber+".pdf" {
+		t.Fatalf("unexpected ZIP entries %+v", archive.File)
+	}
+
+	_, err = is.InvoicesInRange(day, day.AddDate(-2, 0, 0))
+	expectError(t, err, "khoảng thời gian không hợp lệ")
+}
+
+func TestInvoiceMoney(t *testing.T) {
+	cases := map[string]models.Money{
+		"1.250.000 VND": models.VND(1250000),
+		"-500 VND":      models.VND(-500),
+		"1.234,50 USD":  models.NewMoney(123450, "USD"),
+	}
+	for want, money := range cases {
+		if got := invoiceMoney(money); got != want {
+			t.Fatalf("invoiceMoney(%v) = %q, want %q", money, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MailMessage là email dạng văn bản gửi cho khách
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer gửi email cho khách
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
+
+// ErrMailerNotConfigured được trả về khi gửi email mà chưa cấu hình SMTP
+var ErrMailerNotConfigured = errors.New("chưa cấu hình máy chủ gửi email (SMTP_HOST)")
+
+// LogMailer chỉ ghi email ra log thay vì gửi, kể cả liên kết/token trong email.
+// Chỉ dùng ở môi trường dev (MAIL_LOG_ENABLED=true).
+type LogMailer struct{}
+
+// Send ghi email ra log
+func (LogMailer) Send(ctx context.Context, msg MailMessage) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPConfig là cấu hình máy chủ SMTP
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer gửi email qua máy chủ SMTP (STARTTLS nếu máy chủ hỗ trợ)
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer creates a new SMTP mailer; Port rỗng = 587
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	return &SMTPMailer{config: config}
+}
+
+// Send gửi email dạng text/plain UTF-8
+func (m *SMTPMailer) Send(ctx context.Context, msg MailMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Chặn header injection qua địa chỉ hoặc tiêu đề
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("địa chỉ hoặc tiêu đề email không hợp lệ")
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	return smtp.SendMail(addr, auth, m.config.From, []string{msg.To}, []byte(body.String()))
+}
+
+// disabledMailer từ chối gửi email khi chưa cấu hình SMTP
+type disabledMailer struct{}
+
+func (disabledMailer) Send(ctx context.Context, msg MailMessage) error {
+	return ErrMailerNotConfigured
+}
+
+// mailerFromEnv đọc cấu hình SMTP_*. Không đặt SMTP_HOST thì không gửi được email, trừ khi bật
+// MAIL_LOG_ENABLED=true (môi trường dev) để ghi email ra log.
+func mailerFromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		if enabled, _ := strconv.ParseBool(os.Getenv("MAIL_LOG_ENABLED")); enabled {
+			log.Printf("WARNING: SMTP_HOST is not set and MAIL_LOG_ENABLED is on, emails (including order lookup links) are written to the log instead of being sent; do not use in production")
+			return LogMailer{}
+		}
+		log.Printf("WARNING: SMTP_HOST is not set, emails such as order lookup links cannot be sent")
+		return disabledMailer{}
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USERNAME")
+	}
+	return NewSMTPMailer(SMTPConfig{
+		Host:     host,
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	})
+}
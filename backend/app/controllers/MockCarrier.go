@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+// MockCarrierServer là carrier giả cài đặt hợp đồng của HTTPCarrier, dùng trong test
+// (httptest.NewServer) và khi chạy local (cmd/mockcarrier). Trạng thái vận đơn chỉ thay đổi khi gọi Advance.
+type MockCarrierServer struct {
+	token string
+	mux   *http.ServeMux
+
+	mu        sync.Mutex
+	sequence  int
+	shipments map[string]*mockShipment
+}
+
+type mockShipment struct {
+	request ShipmentRequest
+	fee     models.Money
+	status  CarrierStatus
+}
+
+// NewMockCarrierServer tạo carrier giả chỉ chấp nhận request có header Token bằng token
+func NewMockCarrierServer(token string) *MockCarrierServer {
+	m := &MockCarrierServer{token: token, mux: http.NewServeMux(), shipments: make(map[string]*mockShipment)}
+	m.mux.HandleFunc("POST /v1/rates", m.handleRate)
+	m.mux.HandleFunc("POST /v1/shipments", m.handleCreate)
+	m.mux.HandleFunc("GET /v1/shipments/{tracking}/label", m.handleLabel)
+	m.mux.HandleFunc("GET /v1/shipments/{tracking}", m.handleStatus)
+	return m
+}
+
+func (m *MockCarrierServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Token") != m.token {
+		mockCarrierError(w, http.StatusUnauthorized, "token không hợp lệ")
+		return
+	}
+	m.mux.ServeHTTP(w, r)
+}
+
+// Advance thêm một mốc hành trình cho vận đơn, giống carrier cập nhật khi lấy/giao hàng
+func (m *MockCarrierServer) Advance(trackingNumber, status, description string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shipment, ok := m.shipments[trackingNumber]
+	if !ok {
+		return fmt.Errorf("không tìm thấy vận đơn %s", trackingNumber)
+	}
+	shipment.status.Status = status
+	shipment.status.Events = append(shipment.status.Events, models.ShipmentEvent{
+		Status:      status,
+		Description: description,
+		Time:        time.Now(),
+	})
+	return nil
+}
+
+// mockCarrierFee tính phí 22.000đ cho 500g đầu, thêm 5.000đ mỗi 500g tiếp theo
+func mockCarrierFee(weightGrams int) models.Money {
+	extra := 0
+	if weightGrams > 500 {
+		extra = (weightGrams - 500 + 499) / 500
+	}
+	return models.VND(22000 + 5000*int64(extra))
+}
+
+func (m *MockCarrierServer) handleRate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMockShipmentRequest(w, r)
+	if !ok {
+		return
+	}
+	mockCarrierJSON(w, http.StatusOK, CarrierRate{Service: "standard", Fee: mockCarrierFee(req.WeightGrams), EstimatedDays: 3})
+}
+
+func (m *MockCarrierServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMockShipmentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	m.sequence++
+	trackingNumber := fmt.Sprintf("MOCK%08d", m.sequence)
+	shipment := &mockShipment{
+		request: req,
+		fee:     mockCarrierFee(req.WeightGrams),
+		status: CarrierStatus{
+			TrackingNumber: trackingNumber,
+			Status:         ShipmentStatusCreated,
+			Events:         []models.ShipmentEvent{{Status: ShipmentStatusCreated, Description: "Đã tạo vận đơn", Time: time.Now()}},
+		},
+	}
+	m.shipments[trackingNumber] = shipment
+	m.mu.Unlock()
+
+	mockCarrierJSON(w, http.StatusCreated, CarrierShipment{TrackingNumber: trackingNumber, Service: "standard", Fee: shipment.fee})
+}
+
+func (m *MockCarrierServer) handleLabel(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	shipment, ok := m.shipments[r.PathValue("tracking")]
+	m.mu.Unlock()
+	if !ok {
+		mockCarrierError(w, http.StatusNotFound, "không tìm thấy vận đơn")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Write(mockCarrierLabel(shipment.status.TrackingNumber, shipment.request, shipment.fee))
+}
+
+func (m *MockCarrierServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shipment, ok := m.shipments[r.PathValue("tracking")]
+	if !ok {
+		mockCarrierError(w, http.StatusNotFound, "không tìm thấy vận đơn")
+		return
+	}
+	mockCarrierJSON(w, http.StatusOK, shipment.status)
+}
+
+// mockCarrierLabel vẽ tem A6: mã vận đơn, người nhận, tiền thu hộ và danh sách hàng
+func mockCarrierLabel(trackingNumber string, req ShipmentRequest, fee models.Money) []byte {
+	doc := NewPDFDocument(PDFLabelWidth, PDFLabelHeight)
+	const left, right = 16.0, PDFLabelWidth - 16
+
+	doc.Rect(8, 8, PDFLabelWidth-16, PDFLabelHeight-16)
+	doc.Text(left, 388, 10, true, "MOCK CARRIER")
+	doc.TextRight(right, 388, 9, false, req.OrderNumber)
+	doc.Text(left, 356, 22, true, trackingNumber)
+	doc.Line(8, 344, PDFLabelWidth-8, 344)
+
+	doc.Text(left, 326, 9, true, "Người nhận")
+	doc.Text(left, 310, 11, true, req.Recipient.FullName)
+	doc.Text(left, 296, 9, false, req.Recipient.Phone)
+	doc.Text(left, 282, 9, false, req.Recipient.Street)
+	doc.Text(left, 268, 9, false, strings.TrimSpace(req.Recipient.State+" "+req.Recipient.City))
+	doc.Line(8, 256, PDFLabelWidth-8, 256)
+
+	doc.Text(left, 238, 9, true, "Thu hộ (COD)")
+	doc.TextRight(right, 238, 12, true, req.CODAmount.String())
+	doc.Text(left, 222, 9, false, fmt.Sprintf("Khối lượng: %dg", req.WeightGrams))
+	doc.TextRight(right, 222, 9, false, "Phí: "+fee.String())
+	doc.Line(8, 210, PDFLabelWidth-8, 210)
+
+	y := 192.0
+	for _, item := range req.Items {
+		if y < 24 {
+			break
+		}
+		doc.Text(left, y, 8, false, fmt.Sprintf("%d x %s", item.Quantity, item.Name))
+		y -= 12
+	}
+	return doc.Bytes()
+}
+
+func decodeMockShipmentRequest(w http.ResponseWriter, r *http.Request) (ShipmentRequest, bool) {
+	var req ShipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		mockCarrierError(w, http.StatusBadRequest, "dữ liệu không hợp lệ")
+		return req, false
+	}
+	if req.Recipient.FullName == "" || req.Recipient.Phone == "" || req.Recipient.Street == "" {
+		mockCarrierError(w, http.StatusBadRequest, "thiếu thông tin người nhận")
+		return req, false
+	}
+	if req.WeightGrams <= 0 {
+		mockCarrierError(w, http.StatusBadRequest, "khối lượng không hợp lệ")
+		return req, false
+	}
+	return req, true
+}
+
+func mockCarrierJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func mockCarrierError(w http.ResponseWriter, status int, message string) {
+	mockCarrierJSON(w, status, map[string]string{"message": message})
+}
@@ -1,15 +1,34 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/middleware"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 )
 
-type OrderController struct{}
+type OrderController struct {
+	orderService *OrderService
+	bankAccount  BankTransferAccount
+	returnWindow time.Duration
+}
+
+// NewOrderController creates a new order controller instance
+func NewOrderController(st store.Store) *OrderController {
+	return &OrderController{
+		orderService: NewOrderService(st),
+		bankAccount:  bankTransferAccountFromEnv(),
+		returnWindow: returnWindowFromEnv(),
+	}
+}
 
 // CreateOrderRequest struct for creating orders
 type CreateOrderRequest struct {
@@ -18,6 +37,8 @@ type CreateOrderRequest struct {
 	CustomerPhone   string `json:"customer_phone"`
 	CustomerName    string `json:"customer_name"`
 	CustomerEmail   string `json:"customer_email"`
+	City            string `json:"city"`
+	ShippingMethod  string `json:"shipping_method"`
 	PaymentMethod   string `json:"payment_method"`
 	Notes           string `json:"notes"`
 }
@@ -25,6 +46,38 @@ type CreateOrderRequest struct {
 // UpdateOrderStatusRequest struct for updating order status
 type UpdateOrderStatusRequest struct {
 	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// UpdateTrackingRequest struct for attaching a shipment tracking number
+type UpdateTrackingRequest struct {
+	Carrier        string `json:"carrier" binding:"required"`
+	TrackingNumber string `json:"tracking_number" binding:"required"`
+	TrackingURL    string `json:"tracking_url"`
+	MarkShipped    bool   `json:"mark_shipped"`
+}
+
+// CancelOrderRequest struct for cancelling an order (body is optional)
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// orderActorFromContext lấy người thực hiện từ thông tin token
+func orderActorFromContext(c *gin.Context) OrderActor {
+	actor := OrderActor{Role: ActorCustomer}
+	if userID, exists := c.Get("userID"); exists {
+		actor.ID, _ = userID.(string)
+	}
+	if role, _ := c.Get("role"); role == "admin" {
+		actor.Role = ActorAdmin
+	}
+	return actor
+}
+
+// isGuest kiểm tra request dùng guest token thay vì tài khoản (xem middleware.OptionalAuthMiddleware)
+func isGuest(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	return role == middleware.RoleGuest
 }
 
 // OrderResponse struct for pagination
@@ -55,7 +108,7 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	orderService := NewOrderService()
+	orderService := oc.orderService
 
 	// Validate order data
 	orderData := CreateOrderData{
@@ -64,8 +117,11 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 		CustomerPhone:   req.CustomerPhone,
 		CustomerName:    req.CustomerName,
 		CustomerEmail:   req.CustomerEmail,
+		City:            req.City,
+		ShippingMethod:  req.ShippingMethod,
 		PaymentMethod:   req.PaymentMethod,
 		Notes:           req.Notes,
+		Guest:           isGuest(c),
 	}
 
 	if err := orderService.ValidateOrderData(orderData); err != nil {
@@ -88,7 +144,28 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 			return
 		}
 
-		if err.Error() == "địa chỉ giao hàng và số điện thoại là bắt buộc" {
+		if err.Error() == "địa chỉ giao hàng và số điện thoại là bắt buộc" || err.Error() == "số điện thoại không hợp lệ" ||
+			err == ErrInvalidShippingMethod || err == ErrOnlinePaymentUnavailable {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		// Flash sale vừa hết suất: giá trong giỏ không còn đúng
+		if err == ErrFlashSaleSoldOut {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		// Mã giảm giá không hợp lệ hoặc đã hết lượt, sản phẩm không định giá bằng VND
+		var couponErr *CouponError
+		var currencyErr *CurrencyError
+		if errors.As(err, &couponErr) || errors.As(err, &currencyErr) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -96,6 +173,17 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 			return
 		}
 
+		// Không đủ hàng: trả về danh sách lỗi theo từng sản phẩm
+		var stockErr *StockError
+		if errors.As(err, &stockErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "Một số sản phẩm không đủ hàng",
+				"errors":  stockErr.Items,
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Internal server error",
@@ -103,10 +191,73 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"success": true,
 		"message": "Tạo đơn hàng thành công",
 		"data":    order,
+	}
+
+	// Chuyển khoản: trả kèm tài khoản nhận và mã VietQR với nội dung là mã đơn hàng
+	if order.Payment.Method == BankTransferMethod {
+		if instructions, err := oc.bankAccount.Instructions(order); err == nil {
+			response["bank_transfer"] = instructions
+		} else {
+			log.Printf("Cannot create bank transfer instructions for order %s: %v", order.OrderNumber, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// QuoteOrderRequest struct for shipping quote
+type QuoteOrderRequest struct {
+	City string `json:"city"`
+}
+
+// QuoteOrder trả về các phương thức giao hàng và phí cho giỏ hàng hiện tại
+func (oc *OrderController) QuoteOrder(c *gin.Context) {
+	var req QuoteOrderRequest
+	// Body là tùy chọn (không gửi city thì dùng tỉnh mặc định)
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ: " + err.Error(),
+		})
+		return
+	}
+
+	// Get user ID from token
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Không thể xác thực người dùng",
+		})
+		return
+	}
+
+	quote, err := oc.orderService.QuoteCart(userID.(string), req.City)
+	if err != nil {
+		var couponErr *CouponError
+		var currencyErr *CurrencyError
+		if err.Error() == "giỏ hàng trống" || errors.As(err, &couponErr) || errors.As(err, &currencyErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    quote,
 	})
 }
 
@@ -138,8 +289,13 @@ func (oc *OrderController) GetOrders(c *gin.Context) {
 		limit = 10
 	}
 
-	orderService := NewOrderService()
-	result, err := orderService.GetUserOrders(userID.(string), page, limit)
+	orderService := oc.orderService
+	var result *OrderResult
+	if isGuest(c) {
+		result, err = orderService.GetGuestOrders(userID.(string), page, limit)
+	} else {
+		result, err = orderService.GetUserOrders(userID.(string), page, limit)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -230,7 +386,7 @@ func (oc *OrderController) GetOrderByID(c *gin.Context) {
 		return
 	}
 
-	orderService := NewOrderService()
+	orderService := oc.orderService
 	order, err := orderService.GetOrderByID(id, userID.(string))
 	if err != nil {
 		if err.Error() == "đơn hàng không tồn tại" {
@@ -249,8 +405,12 @@ func (oc *OrderController) GetOrderByID(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    order,
+		"success":         true,
+		"data":            order,
+		"timeline":        OrderTimeline(order),
+		"next_statuses":   orderService.NextOrderStatuses(order, orderActorFromContext(c).Role),
+		"return_deadline": ReturnDeadline(order, oc.returnWindow),
+		"tracking":        orderService.TrackingInfo(order),
 	})
 }
 
@@ -267,8 +427,8 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	orderService := NewOrderService()
-	order, err := orderService.UpdateOrderStatus(id, req.Status, "")
+	orderService := oc.orderService
+	order, err := orderService.UpdateOrderStatus(id, req.Status, orderActorFromContext(c), req.Reason)
 	if err != nil {
 		// Handle specific error types
 		if err.Error() == "đơn hàng không tồn tại" {
@@ -279,8 +439,8 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 			return
 		}
 
-		if err.Error() == "trạng thái không hợp lệ" ||
-			err.Error() == "không thể thay đổi trạng thái đơn hàng đã hoàn thành hoặc đã hủy" {
+		var transitionErr *TransitionError
+		if err == ErrInvalidOrderStatus || errors.As(err, &transitionErr) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -288,6 +448,14 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 			return
 		}
 
+		if err.Error() == "trạng thái đơn hàng vừa được cập nhật, vui lòng thử lại" {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Internal server error",
@@ -302,12 +470,74 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 	})
 }
 
+// UpdateTracking gắn đơn vị vận chuyển và mã vận đơn cho đơn hàng (Admin only)
+func (oc *OrderController) UpdateTracking(c *gin.Context) {
+	id := c.Param("id")
+
+	var req UpdateTrackingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	order, err := oc.orderService.UpdateTracking(id, TrackingUpdate{
+		Carrier:        req.Carrier,
+		TrackingNumber: req.TrackingNumber,
+		TrackingURL:    req.TrackingURL,
+		MarkShipped:    req.MarkShipped,
+	}, orderActorFromContext(c))
+	if err != nil {
+		switch {
+		case err.Error() == "đơn hàng không tồn tại":
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case err.Error() == "trạng thái đơn hàng vừa được cập nhật, vui lòng thử lại":
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case err.Error() == "lỗi khi cập nhật vận đơn" || err.Error() == "lỗi khi lấy đơn hàng đã cập nhật":
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Internal server error",
+			})
+		default:
+			// Dữ liệu vận đơn không hợp lệ hoặc trạng thái đơn không cho phép (kể cả TransitionError)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Cập nhật vận đơn thành công",
+		"data":     order,
+		"tracking": oc.orderService.TrackingInfo(order),
+	})
+}
+
+// GetCarriers trả về danh sách đơn vị vận chuyển có thể gắn vào đơn hàng (Admin only)
+func (oc *OrderController) GetCarriers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    oc.orderService.Carriers(),
+	})
+}
+
 // GetAllOrders lấy tất cả đơn hàng (Admin only)
 func (oc *OrderController) GetAllOrders(c *gin.Context) {
 	// Parse query parameters
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
-	status := c.Query("status")
 
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
@@ -319,14 +549,8 @@ func (oc *OrderController) GetAllOrders(c *gin.Context) {
 		limit = 10
 	}
 
-	// Build filters
-	filters := make(map[string]interface{})
-	if status != "" {
-		filters["status"] = status
-	}
-
-	orderService := NewOrderService()
-	result, err := orderService.GetAllOrders(page, limit, filters)
+	orderService := oc.orderService
+	result, err := orderService.GetAllOrders(page, limit, orderFiltersFromQuery(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -348,6 +572,52 @@ func (oc *OrderController) GetAllOrders(c *gin.Context) {
 	})
 }
 
+// orderFiltersFromQuery đọc filter danh sách đơn hàng của admin: status, return_status, user_id, date_from, date_to
+func orderFiltersFromQuery(c *gin.Context) map[string]interface{} {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"status", "return_status", "user_id", "date_from"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if dateTo, ok := parseFilterDateTo(c.Query("date_to")); ok {
+		filters["date_to"] = dateTo
+	}
+	return filters
+}
+
+// ExportOrders xuất đơn hàng ra CSV/XLSX (admin), cùng filter với GetAllOrders.
+// format=csv|xlsx, layout=orders|items. Dữ liệu được ghi dần vào response.
+func (oc *OrderController) ExportOrders(c *gin.Context) {
+	format := c.DefaultQuery("format", ExportFormatCSV)
+	layout := c.DefaultQuery("layout", ExportLayoutOrders)
+	if (format != ExportFormatCSV && format != ExportFormatXLSX) || !ValidExportLayout(layout) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "format phải là csv hoặc xlsx, layout phải là orders hoặc items",
+		})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == ExportFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	filename := fmt.Sprintf("orders_%s_%s.%s", layout, time.Now().Format("20060102_150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	writer, err := NewOrderRowWriter(format, c.Writer)
+	if err == nil {
+		_, err = oc.orderService.ExportOrders(c.Request.Context(), orderFiltersFromQuery(c), layout, writer)
+	}
+	if err != nil {
+		// Header đã được gửi, file tải về sẽ bị thiếu dữ liệu
+		log.Printf("Error exporting orders to %s: %v", filename, err)
+	}
+}
+
 // CancelOrder hủy đơn hàng
 func (oc *OrderController) CancelOrder(c *gin.Context) {
 	id := c.Param("id")
@@ -362,8 +632,12 @@ func (oc *OrderController) CancelOrder(c *gin.Context) {
 		return
 	}
 
-	orderService := NewOrderService()
-	order, err := orderService.CancelOrder(id, userID.(string))
+	// Lý do hủy là tùy chọn, body có thể rỗng
+	var req CancelOrderRequest
+	_ = c.ShouldBindJSON(&req)
+
+	orderService := oc.orderService
+	order, err := orderService.CancelOrder(id, userID.(string), req.Reason)
 	if err != nil {
 		// Handle specific error types
 		if err.Error() == "đơn hàng không tồn tại" {
@@ -374,7 +648,8 @@ func (oc *OrderController) CancelOrder(c *gin.Context) {
 			return
 		}
 
-		if err.Error() == "chỉ có thể hủy đơn hàng đang chờ xử lý" {
+		var transitionErr *TransitionError
+		if err.Error() == "chỉ có thể hủy đơn hàng đang chờ xử lý" || errors.As(err, &transitionErr) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -395,58 +670,3 @@ func (oc *OrderController) CancelOrder(c *gin.Context) {
 		"data":    order,
 	})
 }
-
-// GetOrderByNumber lấy đơn hàng theo order number
-func (oc *OrderController) GetOrderByNumber(c *gin.Context) {
-	orderNumber := c.Param("orderNumber")
-
-	orderService := NewOrderService()
-	order, err := orderService.GetOrderByNumber(orderNumber)
-	if err != nil {
-		if err.Error() == "đơn hàng không tồn tại" {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Internal server error",
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    order,
-	})
-}
-
-// GetOrdersByEmail lấy đơn hàng theo email
-func (oc *OrderController) GetOrdersByEmail(c *gin.Context) {
-	email := c.Query("email")
-	if email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Email là bắt buộc",
-		})
-		return
-	}
-
-	orderService := NewOrderService()
-	orders, err := orderService.GetOrdersByEmail(email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Internal server error",
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    orders,
-	})
-}
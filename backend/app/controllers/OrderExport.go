@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+// Định dạng và bố cục file xuất đơn hàng
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+
+	ExportLayoutOrders = "orders" // Mỗi đơn một dòng
+	ExportLayoutItems  = "items"  // Mỗi dòng hàng một dòng
+)
+
+// exportTimeLayout là định dạng thời gian trong file xuất (giờ server)
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// OrderRowWriter ghi các dòng của file xuất
+type OrderRowWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// NewOrderRowWriter tạo writer cho format csv hoặc xlsx
+func NewOrderRowWriter(format string, w io.Writer) (OrderRowWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		// BOM để Excel nhận đúng UTF-8 (tên tiếng Việt)
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return nil, err
+		}
+		return &csvRowWriter{w: csv.NewWriter(w)}, nil
+	case ExportFormatXLSX:
+		return NewXLSXWriter(w, "Orders")
+	}
+	return nil, fmt.Errorf("định dạng %q không được hỗ trợ", format)
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			record[i] = csvEscapeText(v)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvEscapeText thêm dấu ' trước chuỗi bắt đầu bằng ký tự công thức để Excel không thực thi
+// (CSV injection). Chuỗi bắt đầu bằng ' cũng được thêm để csvUnescapeText trả lại đúng giá trị gốc.
+func csvEscapeText(v string) string {
+	if v != "" && strings.ContainsRune("=+-@'", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// csvUnescapeText bỏ dấu ' do csvEscapeText thêm vào khi đọc lại file đã xuất
+func csvUnescapeText(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.ContainsRune("=+-@'", rune(v[1])) {
+		return v[1:]
+	}
+	return v
+}
+
+var orderExportColumns = []string{
+	"order_number", "created_at", "status", "customer_name", "email", "phone", "city",
+	"payment_method", "payment_status", "items", "subtotal", "discount", "shipping", "tax", "total",
+	"refunded", "currency", "coupon_code", "shipping_method", "carrier", "tracking_number", "invoice_number",
+}
+
+var itemExportColumns = []string{
+	"order_number", "created_at", "status", "customer_name", "email",
+	"product_sku", "product_name", "category", "quantity", "unit_price", "list_price",
+	"discount", "line_total", "tax_rate", "tax_amount", "restocked", "currency",
+}
+
+// ValidExportLayout kiểm tra bố cục file xuất
+func ValidExportLayout(layout string) bool {
+	return layout == ExportLayoutOrders || layout == ExportLayoutItems
+}
+
+// ExportOrders ghi các đơn khớp filters (như GetAllOrders) vào w theo thứ tự tạo, đọc dần từ store
+// để không phải giữ cả danh sách trong bộ nhớ. Trả về số đơn đã ghi.
+func (os *OrderService) ExportOrders(ctx context.Context, filters map[string]interface{}, layout string, w OrderRowWriter) (int, error) {
+	if !ValidExportLayout(layout) {
+		return 0, fmt.Errorf("bố cục %q không được hỗ trợ", layout)
+	}
+
+	header := orderExportColumns
+	if layout == ExportLayoutItems {
+		header = itemExportColumns
+	}
+	values := make([]interface{}, len(header))
+	for i, column := range header {
+		values[i] = column
+	}
+	if err := w.WriteRow(values); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := os.store.Orders().Stream(ctx, buildOrderFilter(filters), func(order *models.Order) error {
+		count++
+		if layout == ExportLayoutItems {
+			for _, item := range order.Items {
+				if err := w.WriteRow(itemExportRow(order, item)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return w.WriteRow(orderExportRow(order))
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, w.Close()
+}
+
+func orderExportRow(order *models.Order) []interface{} {
+	quantity := 0
+	for _, item := range order.Items {
+		quantity += item.Quantity
+	}
+	carrier, trackingNumber, invoiceNumber := "", "", ""
+	if order.Tracking != nil {
+		carrier, trackingNumber = order.Tracking.Carrier, order.Tracking.TrackingNumber
+	}
+	if order.Invoice != nil {
+		invoiceNumber = order.Invoice.Number
+	}
+	a := order.ShippingAddress
+	return []interface{}{
+		order.OrderNumber, exportTime(order.CreatedAt), normalizeOrderStatus(order.Status), a.FullName, a.Email, a.Phone, a.City,
+		order.Payment.Method, order.Payment.Status, quantity,
+		order.Subtotal.Major(), order.DiscountAmount.Major(), order.ShippingAmount.Major(), order.TaxAmount.Major(), order.TotalAmount.Major(),
+		order.RefundedAmount.Major(), order.TotalAmount.CurrencyCode(), order.CouponCode, order.ShippingMethod, carrier, trackingNumber, invoiceNumber,
+	}
+}
+
+func itemExportRow(order *models.Order, item models.OrderItem) []interface{} {
+	var taxRate interface{} = ""
+	taxAmount := 0.0
+	if item.Tax != nil {
+		taxRate, taxAmount = item.Tax.Rate, item.Tax.Amount.Major()
+	}
+	a := order.ShippingAddress
+	return []interface{}{
+		order.OrderNumber, exportTime(order.CreatedAt), normalizeOrderStatus(order.Status), a.FullName, a.Email,
+		item.ProductSKU, item.ProductName, item.Category, item.Quantity, item.Price.Major(), item.ListPrice.Major(),
+		item.Discount.Major(), item.Total.Major(), taxRate, taxAmount, item.RestockedQuantity, item.Total.CurrencyCode(),
+	}
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(exportTimeLayout)
+}
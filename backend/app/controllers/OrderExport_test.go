@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+func TestOrderService_ExportOrdersCSV(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	os := NewOrderService(st)
+	day := time.Date(2025, 5, 1, 10, 0, 0, 0, time.Local)
+
+	first := createTestOrder(t, st, user.ID, func(o *models.Order) {
+		o.Status = OrderStatusDelivered
+		o.CreatedAt = day
+		o.ShippingAddress.FullName = "=HYPERLINK(\"x\")"
+		o.Invoice = &models.Invoice{Number: "HD2025-000001"}
+	})
+	second := createTestOrder(t, st, user.ID, func(o *models.Order) {
+		o.Status = OrderStatusDelivered
+		o.CreatedAt = day.Add(2 * time.Hour)
+		o.Items = append(o.Items, models.OrderItem{ProductSKU: "TEST-002", ProductName: "Áo thun", Quantity: 1,
+			Price: models.VND(50000), Total: models.VND(50000),
+			Tax: &models.OrderItemTax{Rate: 8, Amount: models.VND(3704)}})
+	})
+	createTestOrder(t, st, user.ID, func(o *models.Order) { o.CreatedAt = day.Add(time.Hour) })                                    // khác trạng thái
+	createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = OrderStatusDelivered; o.CreatedAt = day.AddDate(0, 0, 1) }) // ngoài khoảng ngày
+
+	filters := map[string]interface{}{"status": OrderStatusDelivered, "date_from": "2025-05-01"}
+	if to, ok := parseFilterDateTo("2025-05-01"); ok {
+		filters["date_to"] = to
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewOrderRowWriter(ExportFormatCSV, &buf)
+	mustNoError(t, err)
+	count, err := os.ExportOrders(context.Background(), filters, ExportLayoutOrders, writer)
+	mustNoError(t, err)
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\uFEFF"))).ReadAll()
+	mustNoError(t, err)
+	if count != 2 || len(rows) != 3 {
+		t.Fatalf("expected 2 orders, got %d rows %v", count, rows)
+	}
+	if rows[0][0] != "order_number" || rows[1][0] != first.OrderNumber || rows[2][0] != second.OrderNumber {
+		t.Fatalf("expected orders oldest first, got %v", rows)
+	}
+	if rows[1][3] != "'=HYPERLINK(\"x\")" || rows[1][14] != "200000" || rows[1][21] != "HD2025-000001" {
+		t.Fatalf("unexpected order row %v", rows[1])
+	}
+
+	buf.Reset()
+	writer, err = NewOrderRowWriter(ExportFormatCSV, &buf)
+	mustNoError(t, err)
+	_, err = os.ExportOrders(context.Background(), filters, ExportLayoutItems, writer)
+	mustNoError(t, err)
+	rows, err = csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\uFEFF"))).ReadAll()
+	mustNoError(t, err)
+	if len(rows) != 4 || rows[3][5] != "TEST-002" || rows[3][6] != "Áo thun" || rows[3][13] != "8" || rows[3][14] != "3704" {
+		t.Fatalf("unexpected item rows %v", rows)
+	}
+}
+
+func TestOrderService_ExportOrdersXLSX(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, func(o *models.Order) { o.ShippingAddress.FullName = "Trần <Bình> & Co" })
+
+	var buf bytes.Buffer
+	writer, err := NewOrderRowWriter(ExportFormatXLSX, &buf)
+	mustNoError(t, err)
+	_, err = NewOrderService(st).ExportOrders(context.Background(), nil, ExportLayoutOrders, writer)
+	mustNoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	mustNoError(t, err)
+	var sheet string
+	for _, f := range archive.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			r, err := f.Open()
+			mustNoError(t, err)
+			data, _ := io.ReadAll(r)
+			sheet = string(data)
+		}
+	}
+	for _, want := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">` + order.OrderNumber + `</t></is></c>`,
+		`Trần &lt;Bình&gt; &amp; Co`,
+		`<c r="O2"><v>200000</v></c>`,
+		`</sheetData></worksheet>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Fatalf("expected sheet to contain %q, got %s", want, sheet)
+		}
+	}
+
+	if xlsxColumn(0) != "A" || xlsxColumn(25) != "Z" || xlsxColumn(26) != "AA" || xlsxColumn(701) != "ZZ" {
+		t.Fatalf("unexpected column names")
+	}
+}
@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrderLookupLinkTTL là thời hạn của liên kết tra cứu đơn hàng gửi qua email
+const OrderLookupLinkTTL = 30 * time.Minute
+
+const (
+	orderLookupPurpose    = "order_lookup"
+	defaultOrderLookupURL = "http://localhost:5173/order-tracking"
+)
+
+// ErrOrderLookupFailed được trả về khi mã đơn không tồn tại hoặc email/số điện thoại không khớp.
+// Hai trường hợp dùng chung một lỗi để không lộ mã đơn nào có thật.
+var ErrOrderLookupFailed = errors.New("không tìm thấy đơn hàng khớp với thông tin đã nhập")
+
+// ErrOrderLookupLinkInvalid được trả về khi liên kết tra cứu sai, hết hạn hoặc đã được dùng
+var ErrOrderLookupLinkInvalid = errors.New("liên kết tra cứu không hợp lệ, đã hết hạn hoặc đã được sử dụng")
+
+// OrderLookupView là thông tin đơn hàng trả cho khách tra cứu không đăng nhập.
+// Chỉ gồm một đơn, thông tin liên hệ đã được che bớt và không có địa chỉ chi tiết.
+type OrderLookupView struct {
+	OrderNumber     string             `json:"order_number"`
+	Status          string             `json:"status"`
+	CreatedAt       time.Time          `json:"createdAt"`
+	Items           []OrderLookupItem  `json:"items"`
+	Subtotal        models.Money       `json:"subtotal"`
+	DiscountAmount  models.Money       `json:"discount_amount"`
+	ShippingAmount  models.Money       `json:"shipping_amount"`
+	TaxAmount       models.Money       `json:"tax_amount"`
+	TotalAmount     models.Money       `json:"total_amount"`
+	PaymentMethod   string             `json:"payment_method"`
+	PaymentStatus   string             `json:"payment_status"`
+	ShippingMethod  string             `json:"shipping_method,omitempty"`
+	ShippingAddress OrderLookupAddress `json:"shipping_address"`
+	Tracking        *OrderTrackingInfo `json:"tracking,omitempty"`
+}
+
+// OrderLookupItem là dòng hàng trong OrderLookupView
+type OrderLookupItem struct {
+	ProductName string       `json:"product_name"`
+	Quantity    int          `json:"quantity"`
+	Price       models.Money `json:"price"`
+	Total       models.Money `json:"total"`
+}
+
+// OrderLookupAddress là địa chỉ giao hàng đã che bớt
+type OrderLookupAddress struct {
+	FullName string `json:"full_name"`
+	Phone    string `json:"phone"`
+	Email    string `json:"email"`
+	City     string `json:"city"`
+	State    string `json:"state,omitempty"`
+	Country  string `json:"country,omitempty"`
+}
+
+// OrderLookupService cho khách tra cứu đúng một đơn hàng bằng mã đơn kèm email/số điện thoại trên đơn,
+// hoặc bằng liên kết dùng một lần gửi tới email trên đơn
+type OrderLookupService struct {
+	store   store.Store
+	orders  *OrderService
+	mailer  Mailer
+	linkURL string
+	now     func() time.Time
+}
+
+// NewOrderLookupService creates a new order lookup service; linkURL rỗng = trang tra cứu mặc định
+func NewOrderLookupService(st store.Store, mailer Mailer, linkURL string) *OrderLookupService {
+	if linkURL == "" {
+		linkURL = defaultOrderLookupURL
+	}
+	return &OrderLookupService{
+		store:   st,
+		orders:  NewOrderService(st),
+		mailer:  mailer,
+		linkURL: linkURL,
+		now:     time.Now,
+	}
+}
+
+// orderLookupSecret là khóa ký liên kết tra cứu, tách khỏi khóa của token đăng nhập
+func orderLookupSecret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "fallback_jwt_secret_g47_project_2024"
+	}
+	return []byte(secret + ":" + orderLookupPurpose)
+}
+
+// Lookup trả về đơn hàng có mã orderNumber nếu contact khớp email hoặc số điện thoại giao hàng của đơn
+func (ls *OrderLookupService) Lookup(orderNumber, contact string) (*OrderLookupView, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	orderNumber = strings.TrimSpace(orderNumber)
+	if orderNumber == "" || strings.TrimSpace(contact) == "" {
+		return nil, ErrOrderLookupFailed
+	}
+	order, err := ls.store.Orders().FindByNumber(ctx, orderNumber)
+	if err == store.ErrNotFound {
+		return nil, ErrOrderLookupFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !orderContactMatches(order, contact) {
+		return nil, ErrOrderLookupFailed
+	}
+	return ls.view(order), nil
+}
+
+// SendLookupLink gửi liên kết tra cứu dùng một lần tới email trên đơn. Mã đơn không tồn tại hoặc
+// đơn không có email vẫn trả về nil để người gọi không phân biệt được.
+func (ls *OrderLookupService) SendLookupLink(orderNumber string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, err := ls.store.Orders().FindByNumber(ctx, strings.TrimSpace(orderNumber))
+	if err == store.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	email := strings.TrimSpace(order.ShippingAddress.Email)
+	if email == "" {
+		return nil
+	}
+
+	token, err := ls.newLinkToken(order.ID)
+	if err != nil {
+		return err
+	}
+	link := ls.linkURL + "?token=" + url.QueryEscape(token)
+	return ls.mailer.Send(ctx, MailMessage{
+		To:      email,
+		Subject: "Tra cứu đơn hàng " + order.OrderNumber,
+		Body: fmt.Sprintf("Xin chào,\n\nMở liên kết sau để xem đơn hàng %s:\n%s\n\n"+
+			"Liên kết chỉ dùng được một lần và hết hạn sau %d phút. Nếu bạn không yêu cầu, hãy bỏ qua email này.\n",
+			order.OrderNumber, link, int(OrderLookupLinkTTL/time.Minute)),
+	})
+}
+
+// RedeemLookupLink kiểm tra liên kết tra cứu, đánh dấu đã dùng và trả về đơn hàng của liên kết
+func (ls *OrderLookupService) RedeemLookupLink(token string) (*OrderLookupView, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	orderID, linkID, expiresAt, err := ls.parseLinkToken(token)
+	if err != nil {
+		return nil, ErrOrderLookupLinkInvalid
+	}
+
+	// Đánh dấu liên kết đã dùng qua idempotency store: chỉ lần claim đầu tiên thành công
+	existing, err := ls.store.Idempotency().Claim(ctx, &models.IdempotencyRecord{
+		Key:       orderLookupPurpose + "|" + linkID,
+		Status:    models.IdempotencyCompleted,
+		CreatedAt: ls.now(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrOrderLookupLinkInvalid
+	}
+
+	order, err := ls.store.Orders().FindByID(ctx, orderID)
+	if err == store.ErrNotFound {
+		return nil, ErrOrderLookupLinkInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ls.view(order), nil
+}
+
+func (ls *OrderLookupService) newLinkToken(orderID primitive.ObjectID) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"orderID": orderID.Hex(),
+		"purpose": orderLookupPurpose,
+		"jti":     randomHex(16),
+		"exp":     ls.now().Add(OrderLookupLinkTTL).Unix(),
+	})
+	return token.SignedString(orderLookupSecret())
+}
+
+func (ls *OrderLookupService) parseLinkToken(tokenString string) (primitive.ObjectID, string, time.Time, error) {
+	invalid := func() (primitive.ObjectID, string, time.Time, error) {
+		return primitive.NilObjectID, "", time.Time{}, ErrOrderLookupLinkInvalid
+	}
+	token, err := jwt.Parse(strings.TrimSpace(tokenString), func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("invalid signing method")
+		}
+		return orderLookupSecret(), nil
+	}, jwt.WithTimeFunc(ls.now))
+	if err != nil || !token.Valid {
+		return invalid()
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != orderLookupPurpose {
+		return invalid()
+	}
+	orderHex, _ := claims["orderID"].(string)
+	linkID, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	orderID, idErr := primitive.ObjectIDFromHex(orderHex)
+	if err != nil || exp == nil || idErr != nil || linkID == "" {
+		return invalid()
+	}
+	return orderID, linkID, exp.Time, nil
+}
+
+// view tạo OrderLookupView từ đơn hàng, che thông tin liên hệ
+func (ls *OrderLookupService) view(order *models.Order) *OrderLookupView {
+	items := make([]OrderLookupItem, len(order.Items))
+	for i, item := range order.Items {
+		items[i] = OrderLookupItem{ProductName: item.ProductName, Quantity: item.Quantity, Price: item.Price, Total: item.Total}
+	}
+	a := order.ShippingAddress
+	return &OrderLookupView{
+		OrderNumber:    order.OrderNumber,
+		Status:         normalizeOrderStatus(order.Status),
+		CreatedAt:      order.CreatedAt,
+		Items:          items,
+		Subtotal:       order.Subtotal,
+		DiscountAmount: order.DiscountAmount,
+		ShippingAmount: order.ShippingAmount,
+		TaxAmount:      order.TaxAmount,
+		TotalAmount:    order.TotalAmount,
+		PaymentMethod:  order.Payment.Method,
+		PaymentStatus:  order.Payment.Status,
+		ShippingMethod: order.ShippingMethod,
+		ShippingAddress: OrderLookupAddress{
+			FullName: maskName(a.FullName),
+			Phone:    maskPhone(a.Phone),
+			Email:    maskEmail(a.Email),
+			City:     a.City,
+			State:    a.State,
+			Country:  a.Country,
+		},
+		Tracking: ls.orders.TrackingInfo(order),
+	}
+}
+
+// orderContactMatches so contact với email (không phân biệt hoa thường) hoặc số điện thoại giao hàng của đơn
+func orderContactMatches(order *models.Order, contact string) bool {
+	contact = strings.TrimSpace(contact)
+	if strings.Contains(contact, "@") {
+		email := strings.ToLower(strings.TrimSpace(order.ShippingAddress.Email))
+		return email != "" && subtle.ConstantTimeCompare([]byte(email), []byte(strings.ToLower(contact))) == 1
+	}
+	phone := normalizePhone(order.ShippingAddress.Phone)
+	return phone != "" && subtle.ConstantTimeCompare([]byte(phone), []byte(normalizePhone(contact))) == 1
+}
+
+// normalizePhone chỉ giữ chữ số và đổi đầu số quốc tế 84 thành 0 (+84 901... = 0901...)
+func normalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	if strings.HasPrefix(digits, "84") && len(digits) >= 11 {
+		digits = "0" + digits[2:]
+	}
+	return digits
+}
+
+// maskName giữ chữ cái đầu của mỗi từ: "Nguyễn Văn An" -> "N***** V** A*"
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		runes := []rune(word)
+		words[i] = string(runes[0]) + strings.Repeat("*", len(runes)-1)
+	}
+	return strings.Join(words, " ")
+}
+
+// maskPhone chỉ giữ 3 chữ số cuối: "0901234567" -> "*******567"
+func maskPhone(phone string) string {
+	digits := []rune(normalizePhone(phone))
+	if len(digits) <= 3 {
+		return strings.Repeat("*", len(digits))
+	}
+	return strings.Repeat("*", len(digits)-3) + string(digits[len(digits)-3:])
+}
+
+// maskEmail giữ tối đa 2 ký tự đầu của phần trước @: "nguyenvana@gmail.com" -> "ng********@gmail.com"
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return strings.Repeat("*", len([]rune(email)))
+	}
+	local := []rune(email[:at])
+	keep := 2
+	if len(local) <= 2 {
+		keep = 1
+	}
+	return string(local[:keep]) + strings.Repeat("*", len(local)-keep) + email[at:]
+}
+
+// orderLookupURLFromEnv đọc ORDER_LOOKUP_URL (trang frontend nhận ?token=...)
+func orderLookupURLFromEnv() string {
+	value := strings.TrimSpace(os.Getenv("ORDER_LOOKUP_URL"))
+	if value != "" {
+		if _, err := url.Parse(value); err != nil {
+			log.Printf("Invalid ORDER_LOOKUP_URL %q, using default: %v", value, err)
+			return ""
+		}
+	}
+	return value
+}
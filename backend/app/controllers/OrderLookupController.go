@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
+)
+
+// Giới hạn theo mã đơn và IP, áp dụng thêm ngoài giới hạn theo IP ở route. Chỉ các lần tra cứu sai
+// được tính, và bộ đếm gắn với IP nên người khác không thể khóa tra cứu của chủ đơn.
+const (
+	orderLookupAttemptsPerOrder = 5
+	orderLookupAttemptWindow    = 15 * time.Minute
+	orderLookupLinksPerOrder    = 3
+	orderLookupLinkWindow       = time.Hour
+)
+
+// OrderLookupController handles order lookup HTTP requests for customers who are not logged in
+type OrderLookupController struct {
+	lookupService *OrderLookupService
+	attempts      *middleware.RateLimiter
+	links         *middleware.RateLimiter
+}
+
+// NewOrderLookupController creates a new order lookup controller instance
+func NewOrderLookupController(st store.Store) *OrderLookupController {
+	return &OrderLookupController{
+		lookupService: NewOrderLookupService(st, mailerFromEnv(), orderLookupURLFromEnv()),
+		attempts:      middleware.NewRateLimiter(orderLookupAttemptsPerOrder, orderLookupAttemptWindow),
+		links:         middleware.NewRateLimiter(orderLookupLinksPerOrder, orderLookupLinkWindow),
+	}
+}
+
+// OrderLookupRequest là mã đơn kèm email hoặc số điện thoại trên đơn
+type OrderLookupRequest struct {
+	OrderNumber string `json:"order_number" binding:"required"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+}
+
+// OrderLookupLinkRequest yêu cầu gửi liên kết tra cứu tới email trên đơn
+type OrderLookupLinkRequest struct {
+	OrderNumber string `json:"order_number" binding:"required"`
+}
+
+// OrderLookupRedeemRequest là token trong liên kết tra cứu
+type OrderLookupRedeemRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// LookupOrder tra cứu một đơn bằng mã đơn và email hoặc số điện thoại trên đơn
+func (lc *OrderLookupController) LookupOrder(c *gin.Context) {
+	var req OrderLookupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "order_number là bắt buộc",
+		})
+		return
+	}
+	contact := strings.TrimSpace(req.Email)
+	if contact == "" {
+		contact = strings.TrimSpace(req.Phone)
+	}
+	if contact == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Email hoặc số điện thoại trên đơn hàng là bắt buộc",
+		})
+		return
+	}
+
+	key := orderLookupLimitKey(c, req.OrderNumber)
+	if allowed, retryAfter := lc.attempts.Check(key); !allowed {
+		middleware.RejectRateLimited(c, retryAfter)
+		return
+	}
+
+	view, err := lc.lookupService.Lookup(req.OrderNumber, contact)
+	if err != nil {
+		if err == ErrOrderLookupFailed {
+			lc.attempts.Hit(key)
+		}
+		respondOrderLookupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    view,
+	})
+}
+
+// RequestLookupLink gửi liên kết tra cứu dùng một lần tới email trên đơn.
+// Luôn trả về cùng một response để không lộ mã đơn nào tồn tại.
+func (lc *OrderLookupController) RequestLookupLink(c *gin.Context) {
+	var req OrderLookupLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "order_number là bắt buộc",
+		})
+		return
+	}
+
+	if allowed, retryAfter := lc.links.Allow(orderLookupLimitKey(c, req.OrderNumber)); !allowed {
+		middleware.RejectRateLimited(c, retryAfter)
+		return
+	}
+
+	if err := lc.lookupService.SendLookupLink(req.OrderNumber); err != nil {
+		log.Printf("Send order lookup link for %q failed: %v", req.OrderNumber, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Nếu mã đơn hàng đúng, liên kết tra cứu đã được gửi tới email trên đơn hàng",
+	})
+}
+
+// RedeemLookupLink trả về đơn hàng của liên kết tra cứu; liên kết chỉ dùng được một lần
+func (lc *OrderLookupController) RedeemLookupLink(c *gin.Context) {
+	var req OrderLookupRedeemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "token là bắt buộc",
+		})
+		return
+	}
+
+	view, err := lc.lookupService.RedeemLookupLink(req.Token)
+	if err != nil {
+		respondOrderLookupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    view,
+	})
+}
+
+// orderLookupLimitKey là key của giới hạn theo mã đơn: mã đơn kèm IP client
+func orderLookupLimitKey(c *gin.Context, orderNumber string) string {
+	return strings.ToUpper(strings.TrimSpace(orderNumber)) + "|" + c.ClientIP()
+}
+
+func respondOrderLookupError(c *gin.Context, err error) {
+	switch err {
+	case ErrOrderLookupFailed:
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case ErrOrderLookupLinkInvalid:
+		c.JSON(http.StatusGone, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	default:
+		log.Printf("Order lookup failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+	}
+}
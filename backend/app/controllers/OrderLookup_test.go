@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/models"
+)
+
+// captureMailer giữ lại email đã gửi thay vì gửi thật
+type captureMailer struct {
+	sent []MailMessage
+}
+
+func (m *captureMailer) Send(ctx context.Context, msg MailMessage) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestOrderLookupService_Lookup(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, func(o *models.Order) {
+		o.ShippingAddress.FullName = "Nguyễn Văn An"
+		o.ShippingAddress.Phone = "090 123 4567"
+		o.ShippingAddress.Email = "NguyenVanA@Example.com"
+	})
+	ls := NewOrderLookupService(st, &captureMailer{}, "")
+
+	for _, contact := range []string{"nguyenvana@example.com", "0901234567", "+84 901 234 567"} {
+		view, err := ls.Lookup(" "+order.OrderNumber+" ", contact)
+		mustNoError(t, err)
+		if view.OrderNumber != order.OrderNumber || len(view.Items) != 1 || view.TotalAmount.Cmp(order.TotalAmount) != 0 {
+			t.Fatalf("unexpected view for %q: %+v", contact, view)
+		}
+		a := view.ShippingAddress
+		if a.FullName != "N***** V** A*" || a.Phone != "*******567" || a.Email != "Ng********@Example.com" || a.City != order.ShippingAddress.City {
+			t.Fatalf("expected masked contact, got %+v", a)
+		}
+	}
+
+	// Sai thông tin liên hệ và mã đơn không tồn tại trả về cùng một lỗi
+	for _, tc := range [][2]string{
+		{order.OrderNumber, "khac@example.com"},
+		{order.OrderNumber, "0907654321"},
+		{order.OrderNumber, "567"},
+		{"GP000000000000", "nguyenvana@example.com"},
+	} {
+		if _, err := ls.Lookup(tc[0], tc[1]); err != ErrOrderLookupFailed {
+			t.Fatalf("lookup %v: expected ErrOrderLookupFailed, got %v", tc, err)
+		}
+	}
+}
+
+func TestOrderLookupService_MagicLink(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID)
+	mailer := &captureMailer{}
+	ls := NewOrderLookupService(st, mailer, "https://shop.example/order-tracking")
+
+	mustNoError(t, ls.SendLookupLink("GP000000000000")) // Không lộ mã đơn không tồn tại
+	mustNoError(t, ls.SendLookupLink(order.OrderNumber))
+	if len(mailer.sent) != 1 || mailer.sent[0].To != order.ShippingAddress.Email {
+		t.Fatalf("expected one email to the order address, got %+v", mailer.sent)
+	}
+	match := regexp.MustCompile(`https://shop\.example/order-tracking\?token=(\S+)`).FindStringSubmatch(mailer.sent[0].Body)
+	if match == nil {
+		t.Fatalf("expected lookup link in body, got %q", mailer.sent[0].Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	mustNoError(t, err)
+
+	view, err := ls.RedeemLookupLink(token)
+	mustNoError(t, err)
+	if view.OrderNumber != order.OrderNumber || strings.Contains(view.ShippingAddress.Email, "test@") {
+		t.Fatalf("unexpected view %+v", view)
+	}
+
+	// Liên kết chỉ dùng được một lần
+	if _, err := ls.RedeemLookupLink(token); err != ErrOrderLookupLinkInvalid {
+		t.Fatalf("expected reused link to be rejected, got %v", err)
+	}
+
+	// Liên kết hết hạn hoặc bị sửa không dùng được
+	mustNoError(t, ls.SendLookupLink(order.OrderNumber))
+	expired := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(mailer.sent[1].Body)[1]
+	ls.now = func() time.Time { return time.Now().Add(OrderLookupLinkTTL + time.Minute) }
+	if _, err := ls.RedeemLookupLink(expired); err != ErrOrderLookupLinkInvalid {
+		t.Fatalf("expected expired link to be rejected, got %v", err)
+	}
+	ls.now = time.Now
+	if _, err := ls.RedeemLookupLink(expired + "x"); err != ErrOrderLookupLinkInvalid {
+		t.Fatalf("expected tampered link to be rejected, got %v", err)
+	}
+}
+
+func TestMailerFromEnv(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("MAIL_LOG_ENABLED", "")
+	// Chưa cấu hình SMTP: không âm thầm ghi email (có token tra cứu) ra log
+	if err := mailerFromEnv().Send(context.Background(), MailMessage{To: "a@example.com"}); err != ErrMailerNotConfigured {
+		t.Fatalf("expected ErrMailerNotConfigured, got %v", err)
+	}
+
+	t.Setenv("MAIL_LOG_ENABLED", "true")
+	if _, ok := mailerFromEnv().(LogMailer); !ok {
+		t.Fatalf("expected LogMailer in dev mode")
+	}
+
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	if _, ok := mailerFromEnv().(*SMTPMailer); !ok {
+		t.Fatalf("expected SMTPMailer when SMTP_HOST is set")
+	}
+}
+
+func TestOrderLookupController_FailedAttemptsPerIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID)
+	lc := NewOrderLookupController(st)
+
+	router := gin.New()
+	router.POST("/orders/lookup", lc.LookupOrder)
+	lookup := func(ip, email string) int {
+		body := `{"order_number":"` + order.OrderNumber + `","email":"` + email + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/orders/lookup", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Tra cứu đúng không bị tính lượt
+	for i := 0; i < orderLookupAttemptsPerOrder+1; i++ {
+		if code := lookup("10.0.0.1", "test@example.com"); code != http.StatusOK {
+			t.Fatalf("lookup %d: expected 200, got %d", i+1, code)
+		}
+	}
+
+	// Người khác đoán sai nhiều lần chỉ tự khóa IP của mình
+	for i := 0; i < orderLookupAttemptsPerOrder; i++ {
+		if code := lookup("10.0.0.2", "sai@example.com"); code != http.StatusNotFound {
+			t.Fatalf("attempt %d: expected 404, got %d", i+1, code)
+		}
+	}
+	if code := lookup("10.0.0.2", "test@example.com"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected attacker IP to be limited, got %d", code)
+	}
+	if code := lookup("10.0.0.1", "test@example.com"); code != http.StatusOK {
+		t.Fatalf("expected owner to still look up the order, got %d", code)
+	}
+}
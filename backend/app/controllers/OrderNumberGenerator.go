@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/store"
+)
+
+// defaultOrderNumberPrefix là tiền tố mã đơn hàng quen thuộc với nhân viên hỗ trợ
+const defaultOrderNumberPrefix = "GP"
+
+// OrderNumberGenerator sinh mã đơn hàng dạng <prefix><YYYYMMDD><sequence><check digit>,
+// ví dụ GP2025010100017. Sequence lấy từ bộ đếm theo ngày nên không bao giờ trùng,
+// chữ số cuối là chữ số kiểm tra Luhn để phát hiện đọc nhầm qua điện thoại.
+type OrderNumberGenerator struct {
+	counters store.CounterStore
+	prefix   string
+	now      func() time.Time
+}
+
+// NewOrderNumberGenerator tạo generator với prefix cho trước (rỗng = "GP")
+func NewOrderNumberGenerator(counters store.CounterStore, prefix string) *OrderNumberGenerator {
+	if prefix == "" {
+		prefix = defaultOrderNumberPrefix
+	}
+	return &OrderNumberGenerator{
+		counters: counters,
+		prefix:   prefix,
+		now:      time.Now,
+	}
+}
+
+// orderNumberPrefix đọc prefix từ ORDER_NUMBER_PREFIX
+func orderNumberPrefix() string {
+	return os.Getenv("ORDER_NUMBER_PREFIX")
+}
+
+// Next sinh mã đơn hàng tiếp theo của ngày hiện tại
+func (g *OrderNumberGenerator) Next(ctx context.Context) (string, error) {
+	date := g.now().Format("20060102")
+
+	seq, err := g.counters.Next(ctx, "order_number:"+date)
+	if err != nil {
+		return "", err
+	}
+
+	// Sequence tối thiểu 4 chữ số, tự mở rộng khi vượt quá 9999 đơn/ngày
+	body := fmt.Sprintf("%s%04d", date, seq)
+	return fmt.Sprintf("%s%s%d", g.prefix, body, luhnCheckDigit(body)), nil
+}
+
+// Valid kiểm tra prefix và chữ số kiểm tra của mã đơn hàng
+func (g *OrderNumberGenerator) Valid(orderNumber string) bool {
+	body := strings.TrimPrefix(orderNumber, g.prefix)
+	if body == orderNumber || len(body) < 13 {
+		return false
+	}
+	for _, r := range body {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	last := len(body) - 1
+	return int(body[last]-'0') == luhnCheckDigit(body[:last])
+}
+
+// Find tìm mã đơn hàng hợp lệ đầu tiên trong text (vd nội dung chuyển khoản "CK GP2025010100017 nguyen van a").
+// Không phân biệt hoa thường vì ngân hàng thường viết hoa hoặc thường hóa nội dung.
+func (g *OrderNumberGenerator) Find(text string) (string, bool) {
+	upper := strings.ToUpper(text)
+	prefix := strings.ToUpper(g.prefix)
+
+	for start := 0; ; {
+		i := strings.Index(upper[start:], prefix)
+		if i < 0 {
+			return "", false
+		}
+		digits := start + i + len(prefix)
+		end := digits
+		for end < len(upper) && upper[end] >= '0' && upper[end] <= '9' {
+			end++
+		}
+		if candidate := g.prefix + upper[digits:end]; g.Valid(candidate) {
+			return candidate, true
+		}
+		start = digits
+	}
+}
+
+// luhnCheckDigit tính chữ số kiểm tra Luhn (mod 10) cho chuỗi chữ số
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrderNumberGenerator_Next(t *testing.T) {
+	ctx := context.Background()
+	g := NewOrderNumberGenerator(newTestStore().Counters(), "")
+	day := time.Date(2025, 1, 1, 10, 0, 0, 0, time.Local)
+	g.now = func() time.Time { return day }
+
+	first, err := g.Next(ctx)
+	mustNoError(t, err)
+	second, err := g.Next(ctx)
+	mustNoError(t, err)
+	if first != "GP2025010100019" || second != "GP2025010100027" {
+		t.Fatalf("unexpected order numbers: %s, %s", first, second)
+	}
+
+	// Sang ngày mới thì sequence bắt đầu lại từ 1
+	g.now = func() time.Time { return day.AddDate(0, 0, 1) }
+	next, err := g.Next(ctx)
+	mustNoError(t, err)
+	if next[:14] != "GP202501020001" {
+		t.Fatalf("expected sequence to reset, got %s", next)
+	}
+}
+
+func TestOrderNumberGenerator_Prefix(t *testing.T) {
+	g := NewOrderNumberGenerator(newTestStore().Counters(), "SC")
+
+	number, err := g.Next(context.Background())
+	mustNoError(t, err)
+	if number[:2] != "SC" || !g.Valid(number) {
+		t.Fatalf("unexpected order number %s", number)
+	}
+}
+
+func TestOrderNumberGenerator_Valid(t *testing.T) {
+	g := NewOrderNumberGenerator(newTestStore().Counters(), "")
+
+	cases := map[string]bool{
+		"GP2025010100019": true,
+		"GP2025010100018": false, // sai chữ số kiểm tra
+		"GP2025010100091": false, // đảo hai chữ số
+		"GP202501010001":  false, // thiếu chữ số kiểm tra
+		"XX2025010100018": false,
+		"GP20250101000A9": false,
+	}
+	for number, want := range cases {
+		if got := g.Valid(number); got != want {
+			t.Errorf("Valid(%s) = %v, want %v", number, got, want)
+		}
+	}
+}
+
+func TestOrderNumberGenerator_Concurrent(t *testing.T) {
+	g := NewOrderNumberGenerator(newTestStore().Counters(), "")
+
+	const total = 200
+	numbers := make(chan string, total)
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			number, err := g.Next(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			numbers <- number
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+
+	seen := map[string]bool{}
+	for number := range numbers {
+		if seen[number] {
+			t.Fatalf("duplicate order number %s", number)
+		}
+		seen[number] = true
+	}
+	if len(seen) != total {
+		t.Fatalf("expected %d numbers, got %d", total, len(seen))
+	}
+}
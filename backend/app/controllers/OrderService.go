@@ -4,22 +4,45 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // OrderService handles business logic for order operations
-type OrderService struct{}
+type OrderService struct {
+	store        store.Store
+	orderNumbers *OrderNumberGenerator
+	statuses     *OrderStateMachine
+	tax          *TaxService
+	shipping     *ShippingService
+	coupons      *CouponService
+	promotions   *PromotionService
+	pricing      *PricingService
+	carriers     map[string]TrackingCarrier
+
+	onlinePayments bool // Có cổng thanh toán cho credit_card, e_wallet
+}
 
 // NewOrderService creates a new instance of OrderService
-func NewOrderService() *OrderService {
-	return &OrderService{}
+func NewOrderService(st store.Store) *OrderService {
+	return &OrderService{
+		store:        st,
+		orderNumbers: NewOrderNumberGenerator(st.Counters(), orderNumberPrefix()),
+		statuses:     NewDefaultOrderStateMachine(),
+		tax:          NewTaxService(taxConfigFromEnv()),
+		shipping:     NewShippingService(shippingConfigFromEnv()),
+		coupons:      NewCouponService(st),
+		promotions:   NewPromotionService(promotionsFromEnv()),
+		pricing:      NewPricingService(st),
+		carriers:     trackingCarriersFromEnv(),
+
+		onlinePayments: len(paymentProvidersFromEnv()) > 0,
+	}
 }
 
 // OrderResult represents the result structure for order operations
@@ -33,7 +56,31 @@ type OrderStatistics struct {
 	Statistics      []map[string]interface{} `json:"statistics"`
 	StatusBreakdown map[string]interface{}   `json:"status_breakdown"`
 	TotalOrders     int64                    `json:"total_orders"`
-	TotalRevenue    float64                  `json:"total_revenue"`
+	TotalRevenue    models.Money             `json:"total_revenue"` // Đã trừ số tiền hoàn
+	TotalTax        models.Money             `json:"total_tax"`
+	TotalRefunded   models.Money             `json:"total_refunded"`
+}
+
+// StockIssue mô tả một sản phẩm không đủ hàng khi checkout
+type StockIssue struct {
+	ProductID      string `json:"product_id"`
+	ProductName    string `json:"product_name"`
+	Requested      int    `json:"requested"`
+	AvailableStock int    `json:"available_stock"`
+	Message        string `json:"message"`
+}
+
+// StockError được trả về khi checkout bị từ chối vì một hoặc nhiều sản phẩm không đủ hàng
+type StockError struct {
+	Items []StockIssue `json:"items"`
+}
+
+func (e *StockError) Error() string {
+	messages := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		messages = append(messages, item.Message)
+	}
+	return strings.Join(messages, "; ")
 }
 
 // CreateOrderData represents data for creating an order
@@ -43,70 +90,35 @@ type CreateOrderData struct {
 	CustomerPhone   string `json:"customer_phone"`
 	CustomerName    string `json:"customer_name"`
 	CustomerEmail   string `json:"customer_email"`
+	City            string `json:"city"`
+	ShippingMethod  string `json:"shipping_method"`
 	PaymentMethod   string `json:"payment_method"`
 	Notes           string `json:"notes"`
+	Guest           bool   `json:"-"` // Khách đặt bằng guest token, userID là guest id
 }
 
+// guestEmailPattern kiểm tra email khách nhập khi đặt hàng không đăng nhập
+var guestEmailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+
 // GetAllOrders lấy tất cả đơn hàng với pagination (cho admin)
 func (os *OrderService) GetAllOrders(page, limit int, filters map[string]interface{}) (*OrderResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	skip := (page - 1) * limit
-	query := bson.M{}
-
-	// Áp dụng filters
-	if status, ok := filters["status"]; ok {
-		query["status"] = status
-	}
-
-	if userID, ok := filters["user_id"]; ok {
-		// Convert string userID to ObjectID for proper MongoDB query
-		if userOID, err := primitive.ObjectIDFromHex(userID.(string)); err == nil {
-			query["user_id"] = userOID
-		} else {
-			query["user_id"] = userID
-			fmt.Printf("DEBUG: Failed to convert userID to ObjectID: %v\n", err)
-		}
-	}
-
-	if dateFrom, ok := filters["date_from"]; ok {
-		if query["createdAt"] == nil {
-			query["createdAt"] = bson.M{}
-		}
-		query["createdAt"].(bson.M)["$gte"] = dateFrom
-	}
+	filter := buildOrderFilter(filters)
 
-	if dateTo, ok := filters["date_to"]; ok {
-		if query["createdAt"] == nil {
-			query["createdAt"] = bson.M{}
-		}
-		query["createdAt"].(bson.M)["$lte"] = dateTo
-	}
-
-	db := config.GetDB()
-	collection := db.Collection("orders")
-
-	// Build sort options
-	sortOptions := options.Find()
-	sortOptions.SetSort(bson.D{{Key: "createdAt", Value: -1}})
-	sortOptions.SetSkip(int64(skip)).SetLimit(int64(limit))
-
-	cursor, err := collection.Find(ctx, query, sortOptions)
+	orders, err := os.store.Orders().List(ctx, filter, store.ListOptions{
+		Skip:  int64(skip),
+		Limit: int64(limit),
+	})
 	if err != nil {
-		fmt.Printf("DEBUG: MongoDB Find error: %v\n", err)
+		fmt.Printf("DEBUG: Order list error: %v\n", err)
 		return nil, errors.New("lỗi khi lấy danh sách đơn hàng")
 	}
-	defer cursor.Close(ctx)
-
-	var orders []models.Order
-	if err = cursor.All(ctx, &orders); err != nil {
-		fmt.Printf("DEBUG: Cursor decode error: %v\n", err)
-		return nil, errors.New("lỗi khi decode đơn hàng")
-	}
 
 	// Count total
-	total, err := collection.CountDocuments(ctx, query)
+	total, err := os.store.Orders().Count(ctx, filter)
 	if err != nil {
 		return nil, errors.New("lỗi khi đếm đơn hàng")
 	}
@@ -124,6 +136,76 @@ func (os *OrderService) GetAllOrders(page, limit int, filters map[string]interfa
 	}, nil
 }
 
+// buildOrderFilter chuyển filters dạng map (status, user_id, guest_id, date_from, date_to) sang store.OrderFilter
+func buildOrderFilter(filters map[string]interface{}) store.OrderFilter {
+	var filter store.OrderFilter
+
+	if status, ok := filters["status"].(string); ok {
+		filter.Status = status
+	}
+	if returnStatus, ok := filters["return_status"].(string); ok {
+		filter.ReturnStatus = returnStatus
+	}
+
+	if userID, ok := filters["user_id"]; ok {
+		switch v := userID.(type) {
+		case primitive.ObjectID:
+			filter.UserID = &v
+		case string:
+			userOID, err := primitive.ObjectIDFromHex(v)
+			if err != nil {
+				fmt.Printf("DEBUG: Failed to convert userID to ObjectID: %v\n", err)
+			}
+			// ID không hợp lệ sẽ là ObjectID rỗng nên không khớp đơn hàng nào
+			filter.UserID = &userOID
+		}
+	}
+
+	if guestID, ok := filters["guest_id"].(string); ok {
+		// ID không hợp lệ sẽ là ObjectID rỗng nên không khớp đơn hàng nào
+		guestOID, _ := primitive.ObjectIDFromHex(guestID)
+		filter.GuestID = &guestOID
+	}
+
+	if dateFrom, ok := parseFilterDate(filters["date_from"]); ok {
+		filter.DateFrom = &dateFrom
+	}
+	if dateTo, ok := parseFilterDate(filters["date_to"]); ok {
+		filter.DateTo = &dateTo
+	}
+
+	return filter
+}
+
+// parseFilterDate nhận time.Time hoặc chuỗi RFC3339 / YYYY-MM-DD
+func parseFilterDate(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, true
+		}
+		if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseFilterDateTo giống parseFilterDate nhưng ngày dạng YYYY-MM-DD được tính tới hết ngày đó
+func parseFilterDateTo(value string) (time.Time, bool) {
+	t, ok := parseFilterDate(value)
+	if ok && len(value) == len("2006-01-02") {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, ok
+}
+
 // GetUserOrders lấy đơn hàng của user
 func (os *OrderService) GetUserOrders(userID string, page, limit int) (*OrderResult, error) {
 	fmt.Printf("DEBUG: GetUserOrders called with userID: %s\n", userID)
@@ -143,43 +225,70 @@ func (os *OrderService) GetUserOrders(userID string, page, limit int) (*OrderRes
 	return result, nil
 }
 
+// GetGuestOrders lấy danh sách đơn hàng khách đã đặt bằng guest token
+func (os *OrderService) GetGuestOrders(guestID string, page, limit int) (*OrderResult, error) {
+	return os.GetAllOrders(page, limit, map[string]interface{}{"guest_id": guestID})
+}
+
 // GetOrderByID lấy chi tiết đơn hàng
 func (os *OrderService) GetOrderByID(orderID, userID string) (*models.Order, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("orders")
-
-	query := bson.M{}
+	var order *models.Order
+	var err error
 
 	// Try to convert orderID to ObjectID
-	if objectID, err := primitive.ObjectIDFromHex(orderID); err == nil {
-		query["_id"] = objectID
+	if objectID, parseErr := primitive.ObjectIDFromHex(orderID); parseErr == nil {
+		order, err = os.store.Orders().FindByID(ctx, objectID)
 	} else {
 		// If not valid ObjectID, try to find by order number
-		query["orderNumber"] = orderID
+		order, err = os.store.Orders().FindByNumber(ctx, orderID)
+	}
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, errors.New("đơn hàng không tồn tại")
+		}
+		return nil, errors.New("lỗi khi tìm đơn hàng")
 	}
 
 	// Nếu không phải admin, chỉ được xem đơn hàng của mình
-	if userID != "" {
-		if userOID, err := primitive.ObjectIDFromHex(userID); err == nil {
-			query["user_id"] = userOID
-		} else {
-			query["user_id"] = userID
-		}
+	if userID != "" && !orderBelongsTo(order, userID) {
+		return nil, errors.New("đơn hàng không tồn tại")
 	}
 
-	var order models.Order
-	err := collection.FindOne(ctx, query).Decode(&order)
+	return order, nil
+}
+
+// orderBelongsTo kiểm tra đơn hàng có thuộc về user (hoặc khách có guest id) không
+func orderBelongsTo(order *models.Order, userID string) bool {
+	userOID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("đơn hàng không tồn tại")
-		}
-		return nil, errors.New("lỗi khi tìm đơn hàng")
+		return false
 	}
+	return (order.UserID != nil && *order.UserID == userOID) || (order.GuestID != nil && *order.GuestID == userOID)
+}
 
-	return &order, nil
+// orderCustomerID là id dùng để tính lượt dùng mã giảm giá và flash sale theo khách:
+// user id, hoặc số điện thoại trên đơn với đơn đặt không đăng nhập (ai cũng tạo được guest id mới)
+func orderCustomerID(order *models.Order) string {
+	switch {
+	case order.UserID != nil:
+		return order.UserID.Hex()
+	case order.GuestID != nil:
+		return guestCustomerID(order.ShippingAddress.Phone)
+	}
+	return ""
+}
+
+// guestCustomerID là id tính lượt dùng của khách không đăng nhập theo số điện thoại đã chuẩn hóa;
+// rỗng nếu số điện thoại không có chữ số nào
+func guestCustomerID(phone string) string {
+	phone = normalizePhone(phone)
+	if phone == "" {
+		return ""
+	}
+	return "guest_phone_" + phone
 }
 
 // CreateOrderFromCart tạo đơn hàng mới từ giỏ hàng
@@ -194,6 +303,9 @@ func (os *OrderService) CreateOrderFromCart(userID string, orderData CreateOrder
 	if orderData.ShippingAddress == "" || phoneNumber == "" {
 		return nil, errors.New("địa chỉ giao hàng và số điện thoại là bắt buộc")
 	}
+	if onlinePaymentMethods[orderData.PaymentMethod] && !os.onlinePayments {
+		return nil, ErrOnlinePaymentUnavailable
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -205,46 +317,45 @@ func (os *OrderService) CreateOrderFromCart(userID string, orderData CreateOrder
 	}
 
 	// Lấy giỏ hàng
-	db := config.GetDB()
-	cartCollection := db.Collection("Carts")
-
-	var cart models.Cart
-	err = cartCollection.FindOne(ctx, bson.M{"user_id": userOID}).Decode(&cart)
+	cart, err := os.store.Carts().FindByUser(ctx, userOID, "cart")
 	if err != nil || len(cart.Items) == 0 {
 		return nil, errors.New("giỏ hàng trống")
 	}
 
-	// Kiểm tra stock trước khi tạo đơn hàng
-	if err := os.validateOrderStock(cart.Items); err != nil {
-		return nil, err
+	// Prepare shipping address
+	shippingAddress := models.ShippingAddress{
+		FullName: orderData.CustomerName,
+		Phone:    phoneNumber,
+		Email:    orderData.CustomerEmail,
+		Street:   orderData.ShippingAddress,
+		City:     os.shipping.Province(strings.TrimSpace(orderData.City)),
+		Country:  "Vietnam",
 	}
 
-	// Convert cart items to order items
-	var orderItems []models.OrderItem
-	for _, item := range cart.Items {
-		// Convert ProductID string to ObjectID
-		productOID, err := primitive.ObjectIDFromHex(item.ProductID)
-		if err != nil {
-			// If conversion fails, create a new ObjectID
-			productOID = primitive.NewObjectID()
+	// Giới hạn mã giảm giá và flash sale theo khách: khách không đăng nhập được tính theo số điện thoại
+	customerID := userID
+	if orderData.Guest {
+		if customerID = guestCustomerID(phoneNumber); customerID == "" {
+			return nil, errors.New("số điện thoại không hợp lệ")
 		}
-
-		orderItems = append(orderItems, models.OrderItem{
-			ProductID:   productOID,
-			ProductName: item.ProductName,
-			ProductSKU:  item.ProductID, // Use original string ID as SKU
-			Quantity:    item.Quantity,
-			Price:       item.Price,
-			Total:       item.Price * float64(item.Quantity),
-		})
 	}
 
-	// Calculate totals
-	subtotal := cart.TotalAmount
-	totalAmount := subtotal
+	// Tính giá, khuyến mãi, mã giảm giá, thuế và phí giao hàng
+	pricing, err := os.priceCart(ctx, cart, shippingAddress.City, customerID)
+	if err != nil {
+		return nil, err
+	}
+	shippingOption, err := os.shipping.Option(shippingAddress.City, orderData.ShippingMethod, pricing.Shippable, pricing.merchandiseTotal())
+	if err != nil {
+		return nil, err
+	}
+	totalAmount := pricing.total(shippingOption.Fee)
 
 	// Generate order number
-	orderNumber := os.generateOrderNumber()
+	orderNumber, err := os.orderNumbers.Next(ctx)
+	if err != nil {
+		return nil, errors.New("lỗi khi tạo mã đơn hàng")
+	}
 
 	// Map payment method to valid enum value
 	paymentMethod := orderData.PaymentMethod
@@ -252,25 +363,23 @@ func (os *OrderService) CreateOrderFromCart(userID string, orderData CreateOrder
 		paymentMethod = "cash_on_delivery"
 	}
 
-	// Prepare shipping address
-	shippingAddress := models.ShippingAddress{
-		FullName: orderData.CustomerName,
-		Phone:    phoneNumber,
-		Email:    orderData.CustomerEmail,
-		Street:   orderData.ShippingAddress,
-		City:     "Ho Chi Minh City",
-		Country:  "Vietnam",
-	}
-
 	// Tạo đơn hàng
+	now := time.Now()
 	order := models.Order{
 		OrderNumber:     orderNumber,
-		UserID:          &userOID,
-		Items:           orderItems,
-		Subtotal:        subtotal,
+		Items:           pricing.Items,
+		Subtotal:        pricing.Subtotal,
+		TaxAmount:       pricing.Tax.TaxAmount,
+		TaxInclusive:    pricing.Tax.Inclusive,
+		ShippingAmount:  shippingOption.Fee,
+		DiscountAmount:  pricing.discountAmount(),
 		TotalAmount:     totalAmount,
-		Status:          "pending",
+		Currency:        totalAmount.CurrencyCode(),
+		Status:          OrderStatusPending,
 		ShippingAddress: shippingAddress,
+		ShippingMethod:  shippingOption.Method,
+		CouponCode:      pricing.couponCode(),
+		Promotions:      pricing.Promotions,
 		Payment: models.Payment{
 			Method: paymentMethod,
 			Status: "pending",
@@ -278,227 +387,361 @@ func (os *OrderService) CreateOrderFromCart(userID string, orderData CreateOrder
 		Notes: &models.Notes{
 			Customer: orderData.Notes,
 		},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		StatusHistory: []models.OrderStatusEntry{{
+			Status:    OrderStatusPending,
+			ActorID:   userID,
+			ActorRole: ActorCustomer,
+			ChangedAt: now,
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	// Đơn của khách không gắn user, khách xem lại đơn bằng guest token đã dùng khi đặt
+	if orderData.Guest {
+		order.GuestID = &userOID
+	} else {
+		order.UserID = &userOID
 	}
 
-	// Save order
-	orderCollection := db.Collection("orders")
-	result, err := orderCollection.InsertOne(ctx, order)
-	if err != nil {
-		return nil, errors.New("lỗi khi tạo đơn hàng")
+	// Trừ stock, lưu đơn hàng và làm trống giỏ hàng như một đơn vị
+	if err := runAtomic(ctx, os.store, func(ctx context.Context, rb *rollback) error {
+		return os.placeOrder(ctx, rb, &order, cart)
+	}); err != nil {
+		return nil, err
 	}
 
-	order.ID = result.InsertedID.(primitive.ObjectID)
+	return &order, nil
+}
+
+// cartPricing là kết quả tính giá giỏ hàng trước khi chọn phương thức giao hàng
+type cartPricing struct {
+	Items             []models.OrderItem
+	Shippable         []ShippableItem
+	Subtotal          models.Money
+	Promotions        []models.AppliedPromotion
+	PromotionDiscount models.Money
+	Coupon            *CouponDiscount // nil nếu giỏ hàng không có mã giảm giá
+	Tax               TaxResult
+}
 
-	// Update stock
-	if err := os.updateProductStock(cart.Items, -1); err != nil {
-		return nil, err
+// discountAmount là tổng tiền giảm từ khuyến mãi tự động và mã giảm giá
+func (p *cartPricing) discountAmount() models.Money {
+	discount := models.Money{Currency: p.Subtotal.Currency}.Add(p.PromotionDiscount)
+	if p.Coupon == nil {
+		return discount
 	}
+	return discount.Add(p.Coupon.Amount)
+}
 
-	// Clear cart after successful order creation
-	_, err = cartCollection.UpdateOne(
-		ctx,
-		bson.M{"user_id": userOID},
-		bson.M{
-			"$set": bson.M{
-				"items":       []models.CartItem{},
-				"totalAmount": 0,
-				"totalItems":  0,
-				"updatedAt":   time.Now(),
-			},
-		},
-	)
-	if err != nil {
-		// Log error but don't fail the order creation
-		fmt.Printf("Warning: Failed to clear cart after order creation: %v\n", err)
+func (p *cartPricing) couponCode() string {
+	if p.Coupon == nil {
+		return ""
 	}
+	return p.Coupon.Code
+}
 
-	return &order, nil
+// merchandiseTotal là tiền hàng sau giảm giá (dùng cho ngưỡng miễn phí giao hàng)
+func (p *cartPricing) merchandiseTotal() models.Money {
+	return p.Subtotal.Sub(p.discountAmount())
 }
 
-// UpdateOrderStatus cập nhật trạng thái đơn hàng (admin only)
-func (os *OrderService) UpdateOrderStatus(orderID, newStatus, userID string) (*models.Order, error) {
-	validStatuses := []string{"pending", "confirmed", "processing", "shipping", "delivered", "cancelled"}
+// total = subtotal - giảm giá + VAT (nếu giá chưa gồm VAT) + phí giao hàng
+func (p *cartPricing) total(shippingFee models.Money) models.Money {
+	total := p.merchandiseTotal()
+	if !p.Tax.Inclusive {
+		total = total.Add(p.Tax.TaxAmount)
+	}
+	return total.Add(shippingFee)
+}
+
+// priceCart chuyển cart items sang order items, lấy lại giá hiệu lực (sale_price, flash sale)
+// tại thời điểm checkout và tính lại tổng (không phụ thuộc số liệu lưu trong cart), áp dụng khuyến mãi tự động rồi mã giảm giá
+// của giỏ hàng và tính VAT trên giá sau giảm theo danh mục, tỉnh giao hàng
+func (os *OrderService) priceCart(ctx context.Context, cart *models.Cart, province, userID string) (*cartPricing, error) {
+	pricing := &cartPricing{}
+	now := time.Now()
+	for _, item := range cart.Items {
+		// Convert ProductID string to ObjectID
+		productOID, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			// If conversion fails, create a new ObjectID
+			productOID = primitive.NewObjectID()
+		}
 
-	// Validate status
-	isValid := false
-	for _, status := range validStatuses {
-		if status == newStatus {
-			isValid = true
-			break
+		// Danh mục và khối lượng lấy từ sản phẩm; sản phẩm không còn tồn tại sẽ bị reserveStock từ chối
+		orderItem := models.OrderItem{
+			ProductID:   productOID,
+			ProductName: item.ProductName,
+			ProductSKU:  item.ProductID, // Use original string ID as SKU
+			Quantity:    item.Quantity,
+			Price:       item.Price,
+			ListPrice:   item.ListPrice,
 		}
+		weight := 0
+		if product, err := os.store.Products().FindByProductID(ctx, item.ProductID); err == nil {
+			price, sale := os.pricing.Resolve(ctx, product, userID, item.Quantity, now)
+			orderItem.Price, orderItem.ListPrice = price, product.Price
+			if sale != nil {
+				orderItem.FlashSaleID = sale.FlashSaleID
+			}
+			orderItem.Category, weight = product.Category, product.Weight
+		}
+		if err := checkPriceCurrency(orderItem.ProductName, orderItem.Price); err != nil {
+			return nil, err
+		}
+		orderItem.Total = orderItem.Price.Mul(item.Quantity)
+
+		pricing.Items = append(pricing.Items, orderItem)
+		pricing.Shippable = append(pricing.Shippable, ShippableItem{Weight: weight, Quantity: item.Quantity})
+		pricing.Subtotal = pricing.Subtotal.Add(orderItem.Total)
 	}
-	if !isValid {
-		return nil, errors.New("trạng thái không hợp lệ")
+
+	promotionLines := make([]PromotionLine, len(pricing.Items))
+	for i, item := range pricing.Items {
+		promotionLines[i] = PromotionLine{ProductID: item.ProductSKU, Category: item.Category, Price: item.Price, Quantity: item.Quantity}
+	}
+	promotions := os.promotions.Apply(promotionLines, now)
+	pricing.Promotions = promotions.Applied
+	pricing.PromotionDiscount = promotions.Discount
+	for i := range pricing.Items {
+		pricing.Items[i].Discount = promotions.LineDiscounts[i]
+		pricing.Items[i].Promotions = promotions.LinePromotions[i]
 	}
 
+	// Mã giảm giá tính trên tiền hàng còn lại sau khuyến mãi tự động
+	if cart.CouponCode != "" {
+		lines := make([]CouponLine, len(pricing.Items))
+		for i, item := range pricing.Items {
+			lines[i] = CouponLine{ProductID: item.ProductSKU, Category: item.Category, Total: item.Total.Sub(item.Discount)}
+		}
+		discount, err := os.coupons.Evaluate(ctx, cart.CouponCode, userID, lines, now)
+		if err != nil {
+			return nil, err
+		}
+		pricing.Coupon = discount
+		for i := range pricing.Items {
+			pricing.Items[i].Discount = pricing.Items[i].Discount.Add(discount.Lines[i])
+		}
+	}
+
+	taxableItems := make([]TaxableItem, len(pricing.Items))
+	for i, item := range pricing.Items {
+		taxableItems[i] = TaxableItem{Category: item.Category, Total: item.Total.Sub(item.Discount)}
+	}
+	pricing.Tax = os.tax.Calculate(taxableItems, province)
+	for i := range pricing.Items {
+		pricing.Items[i].Tax = &pricing.Tax.Items[i]
+	}
+	return pricing, nil
+}
+
+// OrderQuote là báo giá cho giỏ hàng hiện tại trước khi checkout
+type OrderQuote struct {
+	City           string                    `json:"city"`
+	Subtotal       models.Money              `json:"subtotal"`
+	DiscountAmount models.Money              `json:"discount_amount"`
+	Promotions     []models.AppliedPromotion `json:"promotions,omitempty"`
+	Coupon         *CouponDiscount           `json:"coupon,omitempty"`
+	TaxAmount      models.Money              `json:"tax_amount"`
+	TaxInclusive   bool                      `json:"tax_inclusive"`
+	Options        []QuoteOption             `json:"options"`
+}
+
+// QuoteOption là một phương thức giao hàng kèm tổng tiền nếu chọn phương thức đó
+type QuoteOption struct {
+	ShippingOption
+	TotalAmount models.Money `json:"total_amount"`
+}
+
+// QuoteCart tính các phương thức giao hàng, phí và tổng tiền cho giỏ hàng của user
+func (os *OrderService) QuoteCart(userID, city string) (*OrderQuote, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("orders")
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("user ID không hợp lệ")
+	}
 
-	// Build query
-	query := bson.M{}
-	if objectID, err := primitive.ObjectIDFromHex(orderID); err == nil {
-		query["_id"] = objectID
-	} else {
-		return nil, errors.New("order ID không hợp lệ")
+	cart, err := os.store.Carts().FindByUser(ctx, userOID, "cart")
+	if err != nil || len(cart.Items) == 0 {
+		return nil, errors.New("giỏ hàng trống")
 	}
 
-	if userID != "" {
-		if userOID, err := primitive.ObjectIDFromHex(userID); err == nil {
-			query["user_id"] = userOID
-		}
+	province := os.shipping.Province(strings.TrimSpace(city))
+	pricing, err := os.priceCart(ctx, cart, province, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &OrderQuote{
+		City:           province,
+		Subtotal:       pricing.Subtotal,
+		DiscountAmount: pricing.discountAmount(),
+		Promotions:     pricing.Promotions,
+		Coupon:         pricing.Coupon,
+		TaxAmount:      pricing.Tax.TaxAmount,
+		TaxInclusive:   pricing.Tax.Inclusive,
+		Options:        []QuoteOption{},
+	}
+	for _, option := range os.shipping.Quote(province, pricing.Shippable, pricing.merchandiseTotal()) {
+		quote.Options = append(quote.Options, QuoteOption{
+			ShippingOption: option,
+			TotalAmount:    pricing.total(option.Fee),
+		})
+	}
+	return quote, nil
+}
+
+// UpdateOrderStatus chuyển trạng thái đơn hàng theo bảng chuyển trạng thái và ghi vào status_history.
+// Khách hàng (actor.Role == "customer") chỉ được thao tác trên đơn của mình.
+func (os *OrderService) UpdateOrderStatus(orderID, newStatus string, actor OrderActor, reason string) (*models.Order, error) {
+	if !os.statuses.IsValidStatus(newStatus) {
+		return nil, ErrInvalidOrderStatus
+	}
+	newStatus = normalizeOrderStatus(newStatus)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, errors.New("order ID không hợp lệ")
 	}
 
 	// Get current order to check status
-	var currentOrder models.Order
-	err := collection.FindOne(ctx, query).Decode(&currentOrder)
+	currentOrder, err := os.store.Orders().FindByID(ctx, objectID)
 	if err != nil {
 		return nil, errors.New("đơn hàng không tồn tại")
 	}
 
-	// Kiểm tra logic chuyển trạng thái
-	if currentOrder.Status == "delivered" || currentOrder.Status == "cancelled" {
-		return nil, errors.New("không thể thay đổi trạng thái đơn hàng đã hoàn thành hoặc đã hủy")
+	if actor.Role == ActorCustomer && !orderBelongsTo(currentOrder, actor.ID) {
+		return nil, errors.New("đơn hàng không tồn tại")
 	}
 
-	// Nếu hủy đơn hàng, hoàn lại stock
-	if newStatus == "cancelled" && currentOrder.Status != "cancelled" {
-		if err := os.restoreOrderStock(currentOrder.Items); err != nil {
-			return nil, err
-		}
+	// Kiểm tra bảng chuyển trạng thái và các guard
+	if err := os.statuses.Check(ctx, StatusChange{
+		Order:  currentOrder,
+		From:   currentOrder.Status,
+		To:     newStatus,
+		Actor:  actor,
+		Reason: reason,
+	}); err != nil {
+		return nil, err
 	}
 
-	// Update order status
-	update := bson.M{
-		"$set": bson.M{
-			"status":    newStatus,
-			"updatedAt": time.Now(),
-		},
+	now := time.Now()
+	entry := models.OrderStatusEntry{
+		From:      normalizeOrderStatus(currentOrder.Status),
+		Status:    newStatus,
+		ActorID:   actor.ID,
+		ActorRole: actor.Role,
+		Reason:    reason,
+		ChangedAt: now,
 	}
 
-	_, err = collection.UpdateOne(ctx, query, update)
+	// Nếu hủy đơn hàng, hoàn lại stock cùng lúc với cập nhật trạng thái
+	err = runAtomic(ctx, os.store, func(ctx context.Context, rb *rollback) error {
+		if newStatus == OrderStatusCancelled {
+			if err := os.restoreOrderStock(ctx, rb, currentOrder.Items); err != nil {
+				return err
+			}
+			if err := os.releaseCoupon(ctx, rb, currentOrder); err != nil {
+				return err
+			}
+			if err := os.releaseFlashSales(ctx, rb, currentOrder); err != nil {
+				return err
+			}
+		}
+
+		fields := statusTimestampFields(currentOrder, newStatus, now)
+		if err := os.store.Orders().UpdateStatus(ctx, objectID, currentOrder.Status, entry, fields); err != nil {
+			if err == store.ErrStatusConflict {
+				return errors.New("trạng thái đơn hàng vừa được cập nhật, vui lòng thử lại")
+			}
+			return errors.New("lỗi khi cập nhật đơn hàng")
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, errors.New("lỗi khi cập nhật đơn hàng")
+		return nil, err
 	}
 
 	// Get updated order
-	err = collection.FindOne(ctx, query).Decode(&currentOrder)
+	updatedOrder, err := os.store.Orders().FindByID(ctx, objectID)
 	if err != nil {
 		return nil, errors.New("lỗi khi lấy đơn hàng đã cập nhật")
 	}
 
-	currentOrder.Status = newStatus
-	currentOrder.UpdatedAt = time.Now()
-
-	return &currentOrder, nil
+	return updatedOrder, nil
 }
 
 // CancelOrder hủy đơn hàng (user only)
-func (os *OrderService) CancelOrder(orderID, userID string) (*models.Order, error) {
+func (os *OrderService) CancelOrder(orderID, userID, reason string) (*models.Order, error) {
 	// First check if order exists and belongs to user
 	order, err := os.GetOrderByID(orderID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if order.Status != "pending" {
+	if order.Status != OrderStatusPending {
 		return nil, errors.New("chỉ có thể hủy đơn hàng đang chờ xử lý")
 	}
 
-	return os.UpdateOrderStatus(orderID, "cancelled", userID)
+	return os.UpdateOrderStatus(order.ID.Hex(), OrderStatusCancelled, OrderActor{ID: userID, Role: ActorCustomer}, reason)
 }
 
-// GetOrderStatistics thống kê đơn hàng
+// NextOrderStatuses trả về các trạng thái mà actor có thể chuyển đơn hàng tới
+func (os *OrderService) NextOrderStatuses(order *models.Order, role string) []string {
+	return os.statuses.NextStatuses(order.Status, role)
+}
+
+// GetOrderStatistics thống kê đơn hàng. Doanh thu không tính đơn đã hủy và đã trừ số tiền hoàn;
+// tiền hoàn được tính theo ngày tạo đơn như các số liệu khác.
 func (os *OrderService) GetOrderStatistics(dateFrom, dateTo *time.Time) (*OrderStatistics, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("orders")
-
-	// Build match condition
-	matchCondition := bson.M{}
-	if dateFrom != nil || dateTo != nil {
-		matchCondition["createdAt"] = bson.M{}
-		if dateFrom != nil {
-			matchCondition["createdAt"].(bson.M)["$gte"] = *dateFrom
-		}
-		if dateTo != nil {
-			matchCondition["createdAt"].(bson.M)["$lte"] = *dateTo
-		}
+	filter := store.OrderFilter{
+		DateFrom: dateFrom,
+		DateTo:   dateTo,
 	}
 
 	// Aggregate statistics
-	pipeline := []bson.M{
-		{"$match": matchCondition},
-		{
-			"$group": bson.M{
-				"_id":          "$status",
-				"count":        bson.M{"$sum": 1},
-				"total_amount": bson.M{"$sum": "$totalAmount"},
-			},
-		},
-	}
-
-	cursor, err := collection.Aggregate(ctx, pipeline)
+	totals, err := os.store.Orders().StatusTotals(ctx, filter)
 	if err != nil {
 		return nil, errors.New("lỗi khi thống kê đơn hàng")
 	}
-	defer cursor.Close(ctx)
-
-	var statistics []map[string]interface{}
-	if err = cursor.All(ctx, &statistics); err != nil {
-		return nil, errors.New("lỗi khi decode thống kê")
-	}
-
-	// Get total orders
-	totalOrders, err := collection.CountDocuments(ctx, matchCondition)
-	if err != nil {
-		return nil, errors.New("lỗi khi đếm tổng đơn hàng")
-	}
-
-	// Get total revenue (excluding cancelled orders)
-	revenueMatchCondition := matchCondition
-	revenueMatchCondition["status"] = bson.M{"$ne": "cancelled"}
-
-	revenuePipeline := []bson.M{
-		{"$match": revenueMatchCondition},
-		{
-			"$group": bson.M{
-				"_id":   nil,
-				"total": bson.M{"$sum": "$totalAmount"},
-			},
-		},
-	}
 
-	revenueCursor, err := collection.Aggregate(ctx, revenuePipeline)
-	if err != nil {
-		return nil, errors.New("lỗi khi tính tổng doanh thu")
-	}
-	defer revenueCursor.Close(ctx)
-
-	var revenueResult []map[string]interface{}
-	revenueCursor.All(ctx, &revenueResult)
-
-	totalRevenue := float64(0)
-	if len(revenueResult) > 0 {
-		if total, ok := revenueResult[0]["total"].(float64); ok {
-			totalRevenue = total
+	statistics := make([]map[string]interface{}, 0, len(totals))
+	statusBreakdown := make(map[string]interface{})
+	totalOrders := int64(0)
+	var totalRevenue models.Money
+	var totalTax models.Money
+	var totalRefunded models.Money
+
+	for _, stat := range totals {
+		statistics = append(statistics, map[string]interface{}{
+			"_id":             stat.Status,
+			"count":           stat.Count,
+			"total_amount":    stat.TotalAmount,
+			"tax_amount":      stat.TaxAmount,
+			"refunded_amount": stat.Refunded,
+		})
+		statusBreakdown[stat.Status] = map[string]interface{}{
+			"count":           stat.Count,
+			"total_amount":    stat.TotalAmount,
+			"tax_amount":      stat.TaxAmount,
+			"refunded_amount": stat.Refunded,
 		}
-	}
 
-	// Create status breakdown
-	statusBreakdown := make(map[string]interface{})
-	for _, stat := range statistics {
-		if status, ok := stat["_id"].(string); ok {
-			statusBreakdown[status] = map[string]interface{}{
-				"count":        stat["count"],
-				"total_amount": stat["total_amount"],
-			}
+		totalOrders += stat.Count
+
+		// Total revenue excluding cancelled orders, net of refunds
+		if stat.Status != "cancelled" {
+			totalRevenue = totalRevenue.Add(stat.TotalAmount).Sub(stat.Refunded)
+			totalTax = totalTax.Add(stat.TaxAmount)
+			totalRefunded = totalRefunded.Add(stat.Refunded)
 		}
 	}
 
@@ -507,6 +750,8 @@ func (os *OrderService) GetOrderStatistics(dateFrom, dateTo *time.Time) (*OrderS
 		StatusBreakdown: statusBreakdown,
 		TotalOrders:     totalOrders,
 		TotalRevenue:    totalRevenue,
+		TotalTax:        totalTax,
+		TotalRefunded:   totalRefunded,
 	}, nil
 }
 
@@ -515,23 +760,10 @@ func (os *OrderService) GetRecentOrders(limit int) ([]models.Order, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("orders")
-
-	opts := options.Find()
-	opts.SetSort(bson.D{{Key: "createdAt", Value: -1}})
-	opts.SetLimit(int64(limit))
-
-	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	orders, err := os.store.Orders().List(ctx, store.OrderFilter{}, store.ListOptions{Limit: int64(limit)})
 	if err != nil {
 		return nil, errors.New("lỗi khi lấy đơn hàng gần đây")
 	}
-	defer cursor.Close(ctx)
-
-	var orders []models.Order
-	if err = cursor.All(ctx, &orders); err != nil {
-		return nil, errors.New("lỗi khi decode đơn hàng")
-	}
 
 	return orders, nil
 }
@@ -553,7 +785,12 @@ func (os *OrderService) ValidateOrderData(orderData CreateOrderData) error {
 		errors = append(errors, "Số điện thoại không hợp lệ")
 	}
 
-	validPaymentMethods := []string{"COD", "cash_on_delivery", "bank_transfer", "credit_card"}
+	// Khách không đăng nhập cần email để nhận thông tin và tra cứu đơn hàng
+	if orderData.Guest && !guestEmailPattern.MatchString(strings.TrimSpace(orderData.CustomerEmail)) {
+		errors = append(errors, "Email không hợp lệ (bắt buộc khi đặt hàng không đăng nhập)")
+	}
+
+	validPaymentMethods := []string{"COD", "cash_on_delivery", "bank_transfer", "credit_card", "e_wallet"}
 	if orderData.PaymentMethod != "" {
 		isValid := false
 		for _, method := range validPaymentMethods {
@@ -568,7 +805,7 @@ func (os *OrderService) ValidateOrderData(orderData CreateOrderData) error {
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf(errors[0])
+		return fmt.Errorf("%s", errors[0])
 	}
 
 	return nil
@@ -576,136 +813,193 @@ func (os *OrderService) ValidateOrderData(orderData CreateOrderData) error {
 
 // Private helper methods
 
-// validateOrderStock validate stock trước khi tạo đơn hàng
-func (os *OrderService) validateOrderStock(cartItems []models.CartItem) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
-	var stockErrors []string
+// placeOrder dùng mã giảm giá, giữ hàng, lưu đơn hàng rồi làm trống giỏ hàng, đăng ký bước hoàn tác cho từng thao tác
+func (os *OrderService) placeOrder(ctx context.Context, rb *rollback, order *models.Order, cart *models.Cart) error {
+	if err := os.redeemCoupon(ctx, rb, order); err != nil {
+		return err
+	}
 
-	for _, item := range cartItems {
-		var product models.Product
+	if err := os.reserveFlashSales(ctx, rb, order); err != nil {
+		return err
+	}
 
-		// Try to find by ProductID
-		filter := bson.M{"id": item.ProductID}
-		err := collection.FindOne(ctx, filter).Decode(&product)
+	if err := os.reserveStock(ctx, rb, cart.Items); err != nil {
+		return err
+	}
 
-		if err != nil {
-			stockErrors = append(stockErrors, fmt.Sprintf("Sản phẩm %s không còn tồn tại", item.ProductName))
-		} else if product.Amount < item.Quantity {
-			stockErrors = append(stockErrors, fmt.Sprintf("Sản phẩm %s chỉ còn %d trong kho", item.ProductName, product.Amount))
-		}
+	if err := os.store.Orders().Insert(ctx, order); err != nil {
+		return errors.New("lỗi khi tạo đơn hàng")
 	}
+	rb.onRollback(func(ctx context.Context) error {
+		return os.store.Orders().Delete(ctx, order.ID)
+	})
 
-	if len(stockErrors) > 0 {
-		return errors.New(stockErrors[0])
+	// Giỏ hàng phải được làm trống cùng lúc với việc tạo đơn, nếu không người dùng có thể đặt lại lần nữa
+	snapshot := *cart
+	if err := os.store.Carts().Clear(ctx, cart.UserID, cart.CartType); err != nil {
+		return errors.New("lỗi khi làm trống giỏ hàng")
 	}
+	rb.onRollback(func(ctx context.Context) error {
+		return os.store.Carts().Save(ctx, &snapshot)
+	})
 
 	return nil
 }
 
-// updateProductStock cập nhật stock sản phẩm
-func (os *OrderService) updateProductStock(cartItems []models.CartItem, multiplier int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	db := config.GetDB()
-	collection := db.Collection("Products")
+// redeemCoupon ghi nhận một lượt dùng mã giảm giá của đơn hàng. Giới hạn lượt dùng được kiểm tra
+// lại trong store nên hai đơn đặt đồng thời không thể cùng dùng lượt cuối cùng.
+func (os *OrderService) redeemCoupon(ctx context.Context, rb *rollback, order *models.Order) error {
+	userID := orderCustomerID(order)
+	if order.CouponCode == "" || userID == "" {
+		return nil
+	}
 
-	for _, item := range cartItems {
-		filter := bson.M{"id": item.ProductID}
-		update := bson.M{
-			"$inc": bson.M{"amount": multiplier * item.Quantity},
-		}
+	switch err := os.store.Coupons().Redeem(ctx, order.CouponCode, userID); err {
+	case nil:
+		rb.onRollback(func(ctx context.Context) error {
+			return os.store.Coupons().Release(ctx, order.CouponCode, userID)
+		})
+		return nil
+	case store.ErrCouponLimitReached:
+		return &CouponError{Reason: "mã giảm giá đã hết lượt sử dụng"}
+	case store.ErrNotFound:
+		return &CouponError{Reason: "mã giảm giá không tồn tại"}
+	default:
+		return errors.New("lỗi khi sử dụng mã giảm giá")
+	}
+}
 
-		_, err := collection.UpdateOne(ctx, filter, update)
-		if err != nil {
-			return fmt.Errorf("lỗi khi cập nhật stock cho sản phẩm %s", item.ProductName)
-		}
+// releaseCoupon trả lại lượt dùng mã giảm giá khi hủy đơn hàng
+func (os *OrderService) releaseCoupon(ctx context.Context, rb *rollback, order *models.Order) error {
+	userID := orderCustomerID(order)
+	if order.CouponCode == "" || userID == "" {
+		return nil
 	}
 
-	return nil
+	switch err := os.store.Coupons().Release(ctx, order.CouponCode, userID); err {
+	case nil:
+		rb.onRollback(func(ctx context.Context) error {
+			return os.store.Coupons().Redeem(ctx, order.CouponCode, userID)
+		})
+		return nil
+	case store.ErrNotFound:
+		// Mã đã bị xóa, không còn gì để trả lại
+		return nil
+	default:
+		return errors.New("lỗi khi hoàn lại lượt dùng mã giảm giá")
+	}
 }
 
-// restoreOrderStock hoàn lại stock khi hủy đơn hàng
-func (os *OrderService) restoreOrderStock(orderItems []models.OrderItem) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	db := config.GetDB()
-	collection := db.Collection("Products")
+// reserveFlashSales ghi nhận số lượng đã bán giá flash sale của từng dòng hàng. Giới hạn tổng và
+// theo khách được kiểm tra lại trong store nên hai đơn đồng thời không thể cùng vượt số lượng.
+func (os *OrderService) reserveFlashSales(ctx context.Context, rb *rollback, order *models.Order) error {
+	userID := orderCustomerID(order)
+	if userID == "" {
+		return nil
+	}
 
-	for _, item := range orderItems {
-		filter := bson.M{"id": item.ProductID}
-		update := bson.M{
-			"$inc": bson.M{"amount": int(item.Total / item.Price)}, // Calculate quantity from total/price
+	for _, item := range order.Items {
+		saleID, err := primitive.ObjectIDFromHex(item.FlashSaleID)
+		if err != nil {
+			continue
 		}
 
-		_, err := collection.UpdateOne(ctx, filter, update)
-		if err != nil {
-			return fmt.Errorf("lỗi khi hoàn lại stock cho sản phẩm %s", item.ProductName)
+		quantity := item.Quantity
+		switch err := os.store.FlashSales().Reserve(ctx, saleID, userID, quantity); err {
+		case nil:
+			rb.onRollback(func(ctx context.Context) error {
+				return os.store.FlashSales().Release(ctx, saleID, userID, quantity)
+			})
+		case store.ErrFlashSaleLimitReached, store.ErrNotFound:
+			return ErrFlashSaleSoldOut
+		default:
+			return errors.New("lỗi khi ghi nhận flash sale")
 		}
 	}
-
 	return nil
 }
 
-// generateOrderNumber тạо order number
-func (os *OrderService) generateOrderNumber() string {
-	date := time.Now()
-	year := date.Year()
-	month := int(date.Month())
-	day := date.Day()
-	sequence := date.Unix() % 10000 // Use unix timestamp for uniqueness
-
-	return fmt.Sprintf("GP%d%02d%02d%04d", year, month, day, sequence)
-}
-
-// GetOrderByNumber lấy đơn hàng theo order number
-func (os *OrderService) GetOrderByNumber(orderNumber string) (*models.Order, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	db := config.GetDB()
-	collection := db.Collection("orders")
+// releaseFlashSales trả lại số lượng flash sale khi hủy đơn hàng
+func (os *OrderService) releaseFlashSales(ctx context.Context, rb *rollback, order *models.Order) error {
+	userID := orderCustomerID(order)
+	if userID == "" {
+		return nil
+	}
 
-	query := bson.M{"order_number": orderNumber}
+	for _, item := range order.Items {
+		saleID, err := primitive.ObjectIDFromHex(item.FlashSaleID)
+		if err != nil {
+			continue
+		}
 
-	var order models.Order
-	err := collection.FindOne(ctx, query).Decode(&order)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("đơn hàng không tồn tại")
+		quantity := item.Quantity
+		switch err := os.store.FlashSales().Release(ctx, saleID, userID, quantity); err {
+		case nil:
+			rb.onRollback(func(ctx context.Context) error {
+				return os.store.FlashSales().Reserve(ctx, saleID, userID, quantity)
+			})
+		case store.ErrNotFound:
+			// Flash sale đã bị xóa, không còn gì để trả lại
+		default:
+			return errors.New("lỗi khi hoàn lại số lượng flash sale")
 		}
-		return nil, errors.New("lỗi khi tìm đơn hàng")
 	}
-
-	return &order, nil
+	return nil
 }
 
-// GetOrdersByEmail lấy đơn hàng theo email
-func (os *OrderService) GetOrdersByEmail(email string) ([]models.Order, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	db := config.GetDB()
-	collection := db.Collection("orders")
+// reserveStock trừ stock bằng update có điều kiện (amount >= quantity) cho từng sản phẩm.
+// Nếu có sản phẩm không đủ hàng thì trả về StockError liệt kê tất cả sản phẩm lỗi.
+func (os *OrderService) reserveStock(ctx context.Context, rb *rollback, cartItems []models.CartItem) error {
+	stockErr := &StockError{}
 
-	query := bson.M{"shipping_address.email": email}
+	for _, item := range cartItems {
+		err := os.store.Products().DecrementStock(ctx, item.ProductID, item.Quantity)
+		switch err {
+		case nil:
+			rb.onRollback(func(ctx context.Context) error {
+				return os.store.Products().AdjustStock(ctx, item.ProductID, item.Quantity)
+			})
+		case store.ErrNotFound:
+			stockErr.Items = append(stockErr.Items, StockIssue{
+				ProductID:   item.ProductID,
+				ProductName: item.ProductName,
+				Requested:   item.Quantity,
+				Message:     fmt.Sprintf("Sản phẩm %s không còn tồn tại", item.ProductName),
+			})
+		case store.ErrInsufficientStock:
+			available := 0
+			if product, err := os.store.Products().FindByProductID(ctx, item.ProductID); err == nil {
+				available = product.Amount
+			}
+			stockErr.Items = append(stockErr.Items, StockIssue{
+				ProductID:      item.ProductID,
+				ProductName:    item.ProductName,
+				Requested:      item.Quantity,
+				AvailableStock: available,
+				Message:        fmt.Sprintf("Sản phẩm %s chỉ còn %d trong kho", item.ProductName, available),
+			})
+		default:
+			return fmt.Errorf("lỗi khi cập nhật stock cho sản phẩm %s", item.ProductName)
+		}
+	}
 
-	cursor, err := collection.Find(ctx, query)
-	if err != nil {
-		return nil, errors.New("lỗi khi tìm đơn hàng theo email")
+	if len(stockErr.Items) > 0 {
+		return stockErr
 	}
-	defer cursor.Close(ctx)
+	return nil
+}
 
-	var orders []models.Order
-	if err = cursor.All(ctx, &orders); err != nil {
-		return nil, errors.New("lỗi khi decode đơn hàng")
+// restoreOrderStock hoàn lại stock khi hủy đơn hàng
+func (os *OrderService) restoreOrderStock(ctx context.Context, rb *rollback, orderItems []models.OrderItem) error {
+	for _, item := range orderItems {
+		// ProductSKU lưu id gốc của sản phẩm (xem CreateOrderFromCart)
+		if err := os.store.Products().AdjustStock(ctx, item.ProductSKU, item.Quantity); err != nil {
+			return fmt.Errorf("lỗi khi hoàn lại stock cho sản phẩm %s", item.ProductName)
+		}
+		rb.onRollback(func(ctx context.Context) error {
+			return os.store.Products().AdjustStock(ctx, item.ProductSKU, -item.Quantity)
+		})
 	}
 
-	return orders, nil
+	return nil
 }
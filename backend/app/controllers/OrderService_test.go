@@ -0,0 +1,931 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func validOrderData() CreateOrderData {
+	return CreateOrderData{
+		ShippingAddress: "123 Test Street, District 1",
+		Phone:           "0123456789",
+		CustomerName:    "Test Customer",
+		CustomerEmail:   "test@example.com",
+		PaymentMethod:   "cod",
+		Notes:           "Giao giờ hành chính",
+	}
+}
+
+func TestOrderService_GetAllOrders(t *testing.T) {
+	t.Run("trả về danh sách rỗng", func(t *testing.T) {
+		os := NewOrderService(newTestStore())
+
+		result, err := os.GetAllOrders(1, 10, map[string]interface{}{})
+		mustNoError(t, err)
+		if len(result.Orders) != 0 || result.Pagination["total_items"] != int64(0) {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("phân trang", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		for i := 0; i < 3; i++ {
+			createTestOrder(t, st, user.ID)
+		}
+
+		result, err := os.GetAllOrders(1, 2, map[string]interface{}{})
+		mustNoError(t, err)
+		if len(result.Orders) != 2 {
+			t.Fatalf("expected 2 orders, got %d", len(result.Orders))
+		}
+		if result.Pagination["total_items"] != int64(3) || result.Pagination["total_pages"] != 2 {
+			t.Fatalf("unexpected pagination: %+v", result.Pagination)
+		}
+
+		result, err = os.GetAllOrders(2, 2, map[string]interface{}{})
+		mustNoError(t, err)
+		if len(result.Orders) != 1 {
+			t.Fatalf("expected 1 order on page 2, got %d", len(result.Orders))
+		}
+	})
+
+	t.Run("lọc theo status", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		createTestOrder(t, st, user.ID)
+		createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = "confirmed" })
+
+		result, err := os.GetAllOrders(1, 10, map[string]interface{}{"status": "confirmed"})
+		mustNoError(t, err)
+		if len(result.Orders) != 1 || result.Orders[0].Status != "confirmed" {
+			t.Fatalf("unexpected orders: %+v", result.Orders)
+		}
+	})
+
+	t.Run("lọc theo user_id", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user1 := createTestUser(t, st)
+		user2 := createTestUser(t, st)
+		createTestOrder(t, st, user1.ID)
+		createTestOrder(t, st, user2.ID)
+
+		result, err := os.GetAllOrders(1, 10, map[string]interface{}{"user_id": user1.ID.Hex()})
+		mustNoError(t, err)
+		if len(result.Orders) != 1 || *result.Orders[0].UserID != user1.ID {
+			t.Fatalf("unexpected orders: %+v", result.Orders)
+		}
+	})
+}
+
+func TestOrderService_GetUserOrders(t *testing.T) {
+	st := newTestStore()
+	os := NewOrderService(st)
+	user := createTestUser(t, st)
+	other := createTestUser(t, st)
+	createTestOrder(t, st, user.ID, func(o *models.Order) { o.CreatedAt = time.Now().Add(-time.Hour) })
+	newest := createTestOrder(t, st, user.ID)
+	createTestOrder(t, st, other.ID)
+
+	result, err := os.GetUserOrders(user.ID.Hex(), 1, 10)
+	mustNoError(t, err)
+	if len(result.Orders) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(result.Orders))
+	}
+	if result.Orders[0].ID != newest.ID {
+		t.Fatalf("expected newest order first")
+	}
+}
+
+func TestOrderService_GetOrderByID(t *testing.T) {
+	st := newTestStore()
+	os := NewOrderService(st)
+	user := createTestUser(t, st)
+	other := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID)
+
+	t.Run("lấy đơn hàng theo ID", func(t *testing.T) {
+		result, err := os.GetOrderByID(order.ID.Hex(), user.ID.Hex())
+		mustNoError(t, err)
+		if result.OrderNumber != order.OrderNumber {
+			t.Fatalf("expected %s, got %s", order.OrderNumber, result.OrderNumber)
+		}
+	})
+
+	t.Run("lấy đơn hàng theo mã đơn", func(t *testing.T) {
+		result, err := os.GetOrderByID(order.OrderNumber, user.ID.Hex())
+		mustNoError(t, err)
+		if result.ID != order.ID {
+			t.Fatalf("expected order %s, got %s", order.ID.Hex(), result.ID.Hex())
+		}
+	})
+
+	t.Run("lỗi khi đơn hàng không tồn tại", func(t *testing.T) {
+		_, err := os.GetOrderByID(primitive.NewObjectID().Hex(), user.ID.Hex())
+		expectError(t, err, "đơn hàng không tồn tại")
+	})
+
+	t.Run("lỗi khi đơn hàng của user khác", func(t *testing.T) {
+		_, err := os.GetOrderByID(order.ID.Hex(), other.ID.Hex())
+		expectError(t, err, "đơn hàng không tồn tại")
+	})
+}
+
+func TestOrderService_CreateOrderFromCart(t *testing.T) {
+	t.Run("tạo đơn hàng thành công", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st, func(p *models.Product) { p.Price = models.VND(50000) })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(p1, 2), cartItemFor(p2, 1)})
+
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+		if order.Status != "pending" || len(order.Items) != 2 {
+			t.Fatalf("unexpected order: %+v", order)
+		}
+		// 3 sản phẩm x 500g nội thành: phí tiêu chuẩn 22000
+		if order.ShippingAmount.Amount != 22000 || order.ShippingMethod != "standard" || order.ShippingAddress.City != "Ho Chi Minh City" {
+			t.Fatalf("unexpected shipping: %v %s %s", order.ShippingAmount, order.ShippingMethod, order.ShippingAddress.City)
+		}
+		if order.Subtotal.Amount != 250000 || order.TotalAmount.Amount != 272000 {
+			t.Fatalf("expected subtotal 250000 and total 272000, got %v/%v", order.Subtotal, order.TotalAmount)
+		}
+		if !os.orderNumbers.Valid(order.OrderNumber) {
+			t.Fatalf("invalid order number %s", order.OrderNumber)
+		}
+		if order.Payment.Method != "cash_on_delivery" {
+			t.Fatalf("expected cash_on_delivery, got %s", order.Payment.Method)
+		}
+		if order.UserID == nil || *order.UserID != user.ID {
+			t.Fatalf("order not linked to user")
+		}
+
+		ctx := context.Background()
+		if _, err := st.Orders().FindByID(ctx, order.ID); err != nil {
+			t.Fatalf("order was not saved: %v", err)
+		}
+
+		// Tồn kho bị trừ
+		stored1, _ := st.Products().FindByProductID(ctx, p1.ProductID)
+		stored2, _ := st.Products().FindByProductID(ctx, p2.ProductID)
+		if stored1.Amount != 8 || stored2.Amount != 9 {
+			t.Fatalf("expected stock 8/9, got %d/%d", stored1.Amount, stored2.Amount)
+		}
+
+		// Giỏ hàng được làm trống
+		cart, err := st.Carts().FindByUser(ctx, user.ID, "cart")
+		mustNoError(t, err)
+		if len(cart.Items) != 0 || cart.TotalItems != 0 {
+			t.Fatalf("expected cart to be cleared, got %+v", cart)
+		}
+	})
+
+	t.Run("tính VAT đã gồm trong giá", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Price = models.VND(110000) })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+		if order.TaxAmount.Amount != 20000 || order.TotalAmount.Amount != 220000+15000 || !order.TaxInclusive {
+			t.Fatalf("expected tax 20000 within subtotal 220000, got %v/%v", order.TaxAmount, order.TotalAmount)
+		}
+		tax := order.Items[0].Tax
+		if tax == nil || tax.Rate != 10 || tax.Amount.Amount != 20000 || tax.Taxable.Amount != 200000 {
+			t.Fatalf("unexpected item tax: %+v", tax)
+		}
+		if order.Items[0].Category != product.Category {
+			t.Fatalf("expected item category %s, got %s", product.Category, order.Items[0].Category)
+		}
+	})
+
+	t.Run("cộng VAT khi giá chưa gồm thuế", func(t *testing.T) {
+		t.Setenv("TAX_PRICES_INCLUDE_VAT", "false")
+		t.Setenv("TAX_RULES", `[{"category":"books","rate":5}]`)
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		book := createTestProduct(t, st, func(p *models.Product) { p.Category = "books" })
+		other := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(book, 1), cartItemFor(other, 1)})
+
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+		// 100000 * 5% + 100000 * 10%, phí giao 15000
+		if order.Subtotal.Amount != 200000 || order.TaxAmount.Amount != 15000 || order.TotalAmount.Amount != 230000 {
+			t.Fatalf("expected 200000 + 15000 + 15000 = 230000, got %v + %v = %v", order.Subtotal, order.TaxAmount, order.TotalAmount)
+		}
+
+		stats, err := os.GetOrderStatistics(nil, nil)
+		mustNoError(t, err)
+		if stats.TotalTax.Amount != 15000 || stats.TotalRevenue.Amount != 230000 {
+			t.Fatalf("expected statistics to include tax, got %v/%v", stats.TotalTax, stats.TotalRevenue)
+		}
+	})
+
+	t.Run("giao nhanh ngoại tỉnh", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Weight = 2500 })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+		data := validOrderData()
+		data.City = "Đà Nẵng"
+		data.ShippingMethod = "express"
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), data)
+		mustNoError(t, err)
+		if order.ShippingAddress.City != "Đà Nẵng" || order.ShippingMethod != "express" || order.ShippingAmount.Amount != 70000 {
+			t.Fatalf("unexpected shipping: %s %s %v", order.ShippingAddress.City, order.ShippingMethod, order.ShippingAmount)
+		}
+		if order.TotalAmount.Amount != 170000 {
+			t.Fatalf("expected total 170000, got %v", order.TotalAmount)
+		}
+	})
+
+	t.Run("lỗi khi phương thức giao hàng không hợp lệ", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+		data := validOrderData()
+		data.ShippingMethod = "drone"
+		_, err := os.CreateOrderFromCart(user.ID.Hex(), data)
+		expectError(t, err, "phương thức giao hàng không hợp lệ")
+
+		// Không trừ stock khi bị từ chối
+		stored, _ := st.Products().FindByProductID(context.Background(), product.ProductID)
+		if stored.Amount != 10 {
+			t.Fatalf("expected stock to stay 10, got %d", stored.Amount)
+		}
+	})
+
+	t.Run("lỗi khi giỏ hàng trống", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+
+		_, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		expectError(t, err, "giỏ hàng trống")
+	})
+
+	t.Run("lỗi khi thiếu địa chỉ hoặc số điện thoại", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+
+		data := validOrderData()
+		data.Phone = ""
+		_, err := os.CreateOrderFromCart(user.ID.Hex(), data)
+		expectError(t, err, "địa chỉ giao hàng và số điện thoại là bắt buộc")
+	})
+
+	t.Run("lỗi khi không đủ hàng", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Amount = 1 })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 5)})
+
+		_, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		expectError(t, err, "chỉ còn 1 trong kho")
+
+		count, _ := st.Orders().Count(context.Background(), store.OrderFilter{})
+		if count != 0 {
+			t.Fatalf("expected no order to be created, got %d", count)
+		}
+	})
+}
+
+func TestOrderService_QuoteCart(t *testing.T) {
+	t.Run("báo giá các phương thức giao hàng", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Price = models.VND(300000) })
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+
+		quote, err := os.QuoteCart(user.ID.Hex(), "")
+		mustNoError(t, err)
+		if quote.City != "Ho Chi Minh City" || quote.Subtotal.Amount != 600000 || len(quote.Options) != 2 {
+			t.Fatalf("unexpected quote: %+v", quote)
+		}
+		// Vượt ngưỡng 500000: tiêu chuẩn miễn phí, giao nhanh vẫn tính phí
+		standard, express := quote.Options[0], quote.Options[1]
+		if standard.Method != "standard" || !standard.Free || !standard.Fee.IsZero() || standard.TotalAmount.Amount != 600000 {
+			t.Fatalf("unexpected standard option: %+v", standard)
+		}
+		if express.Method != "express" || express.Free || express.Fee.Amount != 30000 || express.TotalAmount.Amount != 630000 {
+			t.Fatalf("unexpected express option: %+v", express)
+		}
+	})
+
+	t.Run("lỗi khi giỏ hàng trống", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+
+		_, err := os.QuoteCart(user.ID.Hex(), "Hà Nội")
+		expectError(t, err, "giỏ hàng trống")
+	})
+}
+
+var testAdminActor = OrderActor{ID: "admin-id", Role: ActorAdmin}
+
+func TestOrderService_UpdateOrderStatus(t *testing.T) {
+	t.Run("cập nhật trạng thái", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		order := createTestOrder(t, st, user.ID)
+
+		result, err := os.UpdateOrderStatus(order.ID.Hex(), "confirmed", testAdminActor, "")
+		mustNoError(t, err)
+		if result.Status != "confirmed" {
+			t.Fatalf("expected confirmed, got %s", result.Status)
+		}
+	})
+
+	t.Run("lỗi khi trạng thái không hợp lệ", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		order := createTestOrder(t, st, user.ID)
+
+		_, err := os.UpdateOrderStatus(order.ID.Hex(), "unknown", testAdminActor, "")
+		expectError(t, err, "trạng thái không hợp lệ")
+	})
+
+	t.Run("lỗi khi đơn hàng không tồn tại", func(t *testing.T) {
+		os := NewOrderService(newTestStore())
+
+		_, err := os.UpdateOrderStatus(primitive.NewObjectID().Hex(), "confirmed", testAdminActor, "")
+		expectError(t, err, "đơn hàng không tồn tại")
+	})
+
+	for _, status := range []string{"cancelled", "delivered"} {
+		t.Run("không thể đổi trạng thái đơn "+status, func(t *testing.T) {
+			st := newTestStore()
+			os := NewOrderService(st)
+			user := createTestUser(t, st)
+			order := createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = status })
+
+			_, err := os.UpdateOrderStatus(order.ID.Hex(), "processing", testAdminActor, "")
+			expectError(t, err, "không thể chuyển đơn hàng từ trạng thái "+status+" sang processing")
+		})
+	}
+
+	t.Run("đi hết vòng đời và ghi lịch sử", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+
+		for _, status := range []string{"confirmed", "processing", "shipped", "delivered"} {
+			order, err = os.UpdateOrderStatus(order.ID.Hex(), status, testAdminActor, "bước "+status)
+			mustNoError(t, err)
+		}
+
+		if len(order.StatusHistory) != 5 {
+			t.Fatalf("expected 5 history entries, got %+v", order.StatusHistory)
+		}
+		first, last := order.StatusHistory[0], order.StatusHistory[4]
+		if first.Status != "pending" || first.ActorRole != ActorCustomer || first.ActorID != user.ID.Hex() {
+			t.Fatalf("unexpected first entry: %+v", first)
+		}
+		if last.From != "shipped" || last.Status != "delivered" || last.ActorID != testAdminActor.ID || last.Reason != "bước delivered" || last.ChangedAt.IsZero() {
+			t.Fatalf("unexpected last entry: %+v", last)
+		}
+		if order.ShippedAt == nil || order.DeliveredAt == nil {
+			t.Fatalf("expected shipped_at/delivered_at to be stamped")
+		}
+		if order.Payment.Status != "paid" || order.Payment.PaidAt == nil {
+			t.Fatalf("expected COD order to be paid on delivery, got %+v", order.Payment)
+		}
+	})
+
+	t.Run("không được nhảy cóc trạng thái", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		order := createTestOrder(t, st, user.ID)
+
+		_, err := os.UpdateOrderStatus(order.ID.Hex(), "delivered", testAdminActor, "")
+		expectError(t, err, "không thể chuyển đơn hàng từ trạng thái pending sang delivered")
+	})
+
+	t.Run("khách hàng không được xác nhận đơn", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		order := createTestOrder(t, st, user.ID)
+
+		_, err := os.UpdateOrderStatus(order.ID.Hex(), "confirmed", OrderActor{ID: user.ID.Hex(), Role: ActorCustomer}, "")
+		expectError(t, err, "không có quyền thực hiện")
+	})
+
+	t.Run("trạng thái cũ shipping được hiểu là shipped", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		order := createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = "shipping" })
+
+		result, err := os.UpdateOrderStatus(order.ID.Hex(), "delivered", testAdminActor, "")
+		mustNoError(t, err)
+		if result.Status != "delivered" || result.StatusHistory[0].From != "shipped" {
+			t.Fatalf("unexpected result: %s %+v", result.Status, result.StatusHistory)
+		}
+	})
+
+	t.Run("hoàn tiền đơn đã hủy cần đã thanh toán", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		unpaid := createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = "cancelled" })
+		paid := createTestOrder(t, st, user.ID, func(o *models.Order) {
+			o.Status = "cancelled"
+			o.Payment.Status = "paid"
+		})
+
+		_, err := os.UpdateOrderStatus(unpaid.ID.Hex(), "refunded", testAdminActor, "")
+		expectError(t, err, "đơn hàng chưa được thanh toán")
+
+		_, err = os.UpdateOrderStatus(paid.ID.Hex(), "refunded", testAdminActor, "khách yêu cầu")
+		mustNoError(t, err)
+	})
+}
+
+func TestOrderService_CancelOrder(t *testing.T) {
+	t.Run("hủy đơn hàng và hoàn lại tồn kho", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 3)})
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+
+		result, err := os.CancelOrder(order.ID.Hex(), user.ID.Hex(), "")
+		mustNoError(t, err)
+		if result.Status != "cancelled" {
+			t.Fatalf("expected cancelled, got %s", result.Status)
+		}
+
+		stored, _ := st.Products().FindByProductID(context.Background(), product.ProductID)
+		if stored.Amount != 10 {
+			t.Fatalf("expected stock restored to 10, got %d", stored.Amount)
+		}
+	})
+
+	t.Run("lỗi khi đơn hàng không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+
+		_, err := os.CancelOrder(primitive.NewObjectID().Hex(), user.ID.Hex(), "")
+		expectError(t, err, "đơn hàng không tồn tại")
+	})
+
+	t.Run("lỗi khi hủy đơn của user khác", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		other := createTestUser(t, st)
+		order := createTestOrder(t, st, user.ID)
+
+		_, err := os.CancelOrder(order.ID.Hex(), other.ID.Hex(), "")
+		expectError(t, err, "đơn hàng không tồn tại")
+	})
+
+	t.Run("lỗi khi đơn không còn pending", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		order := createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = "confirmed" })
+
+		_, err := os.CancelOrder(order.ID.Hex(), user.ID.Hex(), "")
+		expectError(t, err, "chỉ có thể hủy đơn hàng đang chờ xử lý")
+	})
+}
+
+func TestOrderService_GetOrderStatistics(t *testing.T) {
+	st := newTestStore()
+	os := NewOrderService(st)
+	user := createTestUser(t, st)
+	createTestOrder(t, st, user.ID)
+	createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = "delivered" })
+	createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = "cancelled" })
+
+	stats, err := os.GetOrderStatistics(nil, nil)
+	mustNoError(t, err)
+	if stats.TotalOrders != 3 {
+		t.Fatalf("expected 3 orders, got %d", stats.TotalOrders)
+	}
+	// Doanh thu không tính đơn đã hủy
+	if stats.TotalRevenue.Amount != 400000 {
+		t.Fatalf("expected revenue 400000, got %v", stats.TotalRevenue)
+	}
+	if _, ok := stats.StatusBreakdown["cancelled"]; !ok {
+		t.Fatalf("expected cancelled in status breakdown: %+v", stats.StatusBreakdown)
+	}
+}
+
+func TestOrderService_GetRecentOrders(t *testing.T) {
+	st := newTestStore()
+	os := NewOrderService(st)
+	user := createTestUser(t, st)
+	for i := 0; i < 5; i++ {
+		createTestOrder(t, st, user.ID, func(o *models.Order) {
+			o.CreatedAt = time.Now().Add(time.Duration(i) * time.Minute)
+		})
+	}
+
+	orders, err := os.GetRecentOrders(3)
+	mustNoError(t, err)
+	if len(orders) != 3 {
+		t.Fatalf("expected 3 orders, got %d", len(orders))
+	}
+	for i := 1; i < len(orders); i++ {
+		if orders[i].CreatedAt.After(orders[i-1].CreatedAt) {
+			t.Fatalf("orders are not sorted newest first")
+		}
+	}
+}
+
+func TestOrderService_CreateOrderFromCart_Promotions(t *testing.T) {
+	t.Setenv("PROMOTIONS", `[{"id":"b2g1","name":"Mua 2 tặng 1","type":"buy_x_get_y","buy_quantity":2,"get_quantity":1}]`)
+	st := newTestStore()
+	os := NewOrderService(st)
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	cart := createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 3)})
+	cart.CouponCode = "SALE10"
+	mustNoError(t, st.Carts().Save(context.Background(), cart))
+	createTestCoupon(t, st)
+
+	order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+	mustNoError(t, err)
+
+	// 300000 - 100000 (tặng 1) - 10% của 200000 = 180000, VAT đã gồm 16364, phí giao 1500g = 22000
+	if len(order.Promotions) != 1 || order.Promotions[0].ID != "b2g1" || order.Promotions[0].Discount.Amount != 100000 {
+		t.Fatalf("unexpected order promotions: %+v", order.Promotions)
+	}
+	item := order.Items[0]
+	if len(item.Promotions) != 1 || item.Discount.Amount != 120000 || order.DiscountAmount.Amount != 120000 {
+		t.Fatalf("unexpected discounts: item %v %+v, order %v", item.Discount, item.Promotions, order.DiscountAmount)
+	}
+	if order.TaxAmount.Amount != 16364 || order.TotalAmount.Amount != 180000+22000 {
+		t.Fatalf("unexpected totals: tax %v total %v", order.TaxAmount, order.TotalAmount)
+	}
+
+	stored, err := st.Orders().FindByID(context.Background(), order.ID)
+	mustNoError(t, err)
+	if len(stored.Promotions) != 1 || len(stored.Items[0].Promotions) != 1 {
+		t.Fatalf("expected promotions to be persisted, got %+v", stored.Promotions)
+	}
+}
+
+func TestOrderService_CreateOrderFromCart_SalePrices(t *testing.T) {
+	t.Run("giá hết hạn quay về giá gốc khi checkout", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		ended := time.Now().Add(-time.Minute)
+		salePrice := models.VND(80000)
+		product := createTestProduct(t, st, func(p *models.Product) { p.SalePrice, p.SaleEndsAt = &salePrice, &ended })
+		item := cartItemFor(product, 1)
+		item.Price = salePrice // giá lúc thêm vào giỏ
+		createTestCart(t, st, user.ID, []models.CartItem{item})
+
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+		if order.Items[0].Price.Amount != 100000 || order.Subtotal.Amount != 100000 {
+			t.Fatalf("expected regular price, got %v / %v", order.Items[0].Price, order.Subtotal)
+		}
+	})
+
+	t.Run("ghi nhận và hoàn lại số lượng flash sale", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		sale := createTestFlashSale(t, st, product)
+		createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+		if order.Items[0].Price.Amount != 60000 || order.Items[0].ListPrice.Amount != 100000 || order.Items[0].FlashSaleID != sale.ID.Hex() {
+			t.Fatalf("expected flash sale price, got %+v", order.Items[0])
+		}
+
+		ctx := context.Background()
+		stored, _ := st.FlashSales().FindByID(ctx, sale.ID)
+		if stored.SoldCount != 2 || stored.CustomerUsage[user.ID.Hex()] != 2 {
+			t.Fatalf("expected flash sale to be reserved, got %d %v", stored.SoldCount, stored.CustomerUsage)
+		}
+
+		_, err = os.CancelOrder(order.ID.Hex(), user.ID.Hex(), "")
+		mustNoError(t, err)
+		stored, _ = st.FlashSales().FindByID(ctx, sale.ID)
+		if stored.SoldCount != 0 || stored.CustomerUsage[user.ID.Hex()] != 0 {
+			t.Fatalf("expected flash sale to be released, got %d %v", stored.SoldCount, stored.CustomerUsage)
+		}
+	})
+
+	t.Run("hoàn lại flash sale khi đặt hàng thất bại", func(t *testing.T) {
+		mem := newTestStore()
+		fs := &faultyStore{Store: mem, failInsert: true}
+		os := NewOrderService(fs)
+		user := createTestUser(t, mem)
+		product := createTestProduct(t, mem)
+		sale := createTestFlashSale(t, mem, product)
+		createTestCart(t, mem, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+		_, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		expectError(t, err, "lỗi khi tạo đơn hàng")
+
+		stored, _ := mem.FlashSales().FindByID(context.Background(), sale.ID)
+		if stored.SoldCount != 0 {
+			t.Fatalf("expected flash sale reservation to be rolled back, got %d", stored.SoldCount)
+		}
+	})
+}
+
+func TestOrderService_CreateOrderFromCart_Coupon(t *testing.T) {
+	t.Run("giảm giá trước VAT và ghi nhận lượt dùng", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st, func(p *models.Product) { p.Price = models.VND(110000) })
+		cart := createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 2)})
+		cart.CouponCode = "SALE10"
+		mustNoError(t, st.Carts().Save(context.Background(), cart))
+		createTestCoupon(t, st, func(c *models.Coupon) { c.PerUserLimit = 1 })
+
+		order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		mustNoError(t, err)
+
+		// 220000 - 10% = 198000, VAT 10% đã gồm trong giá sau giảm = 18000, phí giao 15000
+		if order.CouponCode != "SALE10" || order.DiscountAmount.Amount != 22000 || order.Items[0].Discount.Amount != 22000 {
+			t.Fatalf("unexpected discount: %s %v %v", order.CouponCode, order.DiscountAmount, order.Items[0].Discount)
+		}
+		if order.TaxAmount.Amount != 18000 || order.TotalAmount.Amount != 198000+15000 {
+			t.Fatalf("unexpected totals: tax %v total %v", order.TaxAmount, order.TotalAmount)
+		}
+
+		ctx := context.Background()
+		coupon, _ := st.Coupons().FindByCode(ctx, "SALE10")
+		if coupon.UsedCount != 1 || coupon.UserUsage[user.ID.Hex()] != 1 {
+			t.Fatalf("expected coupon to be redeemed once, got %d %v", coupon.UsedCount, coupon.UserUsage)
+		}
+		stored, _ := st.Carts().FindByUser(ctx, user.ID, "cart")
+		if stored.CouponCode != "" {
+			t.Fatalf("expected coupon to be removed from cart, got %q", stored.CouponCode)
+		}
+
+		// Đã dùng hết lượt của user
+		stored.Items = []models.CartItem{cartItemFor(product, 1)}
+		stored.CouponCode = "SALE10"
+		mustNoError(t, st.Carts().Save(ctx, stored))
+		_, err = os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		expectError(t, err, "bạn đã dùng hết lượt cho mã giảm giá này")
+
+		// Hủy đơn trả lại lượt dùng
+		_, err = os.CancelOrder(order.ID.Hex(), user.ID.Hex(), "")
+		mustNoError(t, err)
+		coupon, _ = st.Coupons().FindByCode(ctx, "SALE10")
+		if coupon.UsedCount != 0 || coupon.UserUsage[user.ID.Hex()] != 0 {
+			t.Fatalf("expected coupon usage to be released, got %d %v", coupon.UsedCount, coupon.UserUsage)
+		}
+	})
+
+	t.Run("hoàn lại lượt dùng khi đặt hàng thất bại", func(t *testing.T) {
+		mem := newTestStore()
+		fs := &faultyStore{Store: mem, failInsert: true}
+		os := NewOrderService(fs)
+		user := createTestUser(t, mem)
+		product := createTestProduct(t, mem)
+		cart := createTestCart(t, mem, user.ID, []models.CartItem{cartItemFor(product, 1)})
+		cart.CouponCode = "SALE10"
+		mustNoError(t, mem.Carts().Save(context.Background(), cart))
+		createTestCoupon(t, mem, func(c *models.Coupon) { c.UsageLimit = 1 })
+
+		_, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		expectError(t, err, "lỗi khi tạo đơn hàng")
+
+		coupon, _ := mem.Coupons().FindByCode(context.Background(), "SALE10")
+		if coupon.UsedCount != 0 {
+			t.Fatalf("expected coupon usage to be rolled back, got %d", coupon.UsedCount)
+		}
+	})
+
+	t.Run("lỗi khi mã hết lượt sử dụng", func(t *testing.T) {
+		st := newTestStore()
+		os := NewOrderService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		cart := createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+		cart.CouponCode = "SALE10"
+		mustNoError(t, st.Carts().Save(context.Background(), cart))
+		createTestCoupon(t, st, func(c *models.Coupon) { c.UsageLimit, c.UsedCount = 1, 1 })
+
+		_, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+		var couponErr *CouponError
+		if !errors.As(err, &couponErr) || couponErr.Reason != "mã giảm giá đã hết lượt sử dụng" {
+			t.Fatalf("expected CouponError, got %v", err)
+		}
+	})
+}
+
+func TestOrderService_CreateOrderFromCart_Rollback(t *testing.T) {
+	cases := []struct {
+		name   string
+		inject func(fs *faultyStore, p1, p2 *models.Product)
+		want   string
+	}{
+		{
+			name:   "lỗi khi trừ stock sản phẩm thứ hai",
+			inject: func(fs *faultyStore, p1, p2 *models.Product) { fs.failStockFor = p2.ProductID },
+			want:   "lỗi khi cập nhật stock cho sản phẩm",
+		},
+		{
+			name:   "lỗi khi lưu đơn hàng",
+			inject: func(fs *faultyStore, p1, p2 *models.Product) { fs.failInsert = true },
+			want:   "lỗi khi tạo đơn hàng",
+		},
+		{
+			name:   "lỗi khi làm trống giỏ hàng",
+			inject: func(fs *faultyStore, p1, p2 *models.Product) { fs.failClear = true },
+			want:   "lỗi khi làm trống giỏ hàng",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := newTestStore()
+			fs := &faultyStore{Store: mem}
+			os := NewOrderService(fs)
+			user := createTestUser(t, mem)
+			p1 := createTestProduct(t, mem)
+			p2 := createTestProduct(t, mem)
+			createTestCart(t, mem, user.ID, []models.CartItem{cartItemFor(p1, 2), cartItemFor(p2, 3)})
+			tc.inject(fs, p1, p2)
+
+			_, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+			expectError(t, err, tc.want)
+
+			// Đơn hàng, stock và giỏ hàng phải giữ nguyên như trước khi checkout
+			ctx := context.Background()
+			count, _ := mem.Orders().Count(ctx, store.OrderFilter{})
+			if count != 0 {
+				t.Fatalf("expected no order, got %d", count)
+			}
+			for _, p := range []*models.Product{p1, p2} {
+				stored, _ := mem.Products().FindByProductID(ctx, p.ProductID)
+				if stored.Amount != p.Amount {
+					t.Fatalf("expected stock of %s to stay %d, got %d", p.ProductID, p.Amount, stored.Amount)
+				}
+			}
+			cart, err := mem.Carts().FindByUser(ctx, user.ID, "cart")
+			mustNoError(t, err)
+			if len(cart.Items) != 2 || cart.TotalItems != 5 {
+				t.Fatalf("expected cart to be kept, got %+v", cart)
+			}
+		})
+	}
+}
+
+func TestOrderService_CancelOrder_Rollback(t *testing.T) {
+	mem := newTestStore()
+	fs := &faultyStore{Store: mem}
+	os := NewOrderService(fs)
+	user := createTestUser(t, mem)
+	p1 := createTestProduct(t, mem)
+	p2 := createTestProduct(t, mem)
+	createTestCart(t, mem, user.ID, []models.CartItem{cartItemFor(p1, 2), cartItemFor(p2, 3)})
+	order, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+	mustNoError(t, err)
+
+	fs.failStockFor = p2.ProductID
+	_, err = os.CancelOrder(order.ID.Hex(), user.ID.Hex(), "")
+	expectError(t, err, "lỗi khi hoàn lại stock cho sản phẩm")
+
+	ctx := context.Background()
+	stored, _ := mem.Orders().FindByID(ctx, order.ID)
+	if stored.Status != "pending" {
+		t.Fatalf("expected order to stay pending, got %s", stored.Status)
+	}
+	product, _ := mem.Products().FindByProductID(ctx, p1.ProductID)
+	if product.Amount != 8 {
+		t.Fatalf("expected stock of first product to stay 8, got %d", product.Amount)
+	}
+}
+
+func TestOrderService_CreateOrderFromCart_StockErrors(t *testing.T) {
+	st := newTestStore()
+	os := NewOrderService(st)
+	user := createTestUser(t, st)
+	enough := createTestProduct(t, st)
+	low := createTestProduct(t, st, func(p *models.Product) { p.Amount = 1 })
+	gone := createTestProduct(t, st)
+	createTestCart(t, st, user.ID, []models.CartItem{
+		cartItemFor(enough, 2),
+		cartItemFor(low, 3),
+		cartItemFor(gone, 1),
+	})
+	mustNoError(t, st.Products().Delete(context.Background(), gone.ID))
+
+	_, err := os.CreateOrderFromCart(user.ID.Hex(), validOrderData())
+	var stockErr *StockError
+	if !errors.As(err, &stockErr) {
+		t.Fatalf("expected StockError, got %v", err)
+	}
+	if len(stockErr.Items) != 2 {
+		t.Fatalf("expected 2 stock issues, got %+v", stockErr.Items)
+	}
+	if stockErr.Items[0].ProductID != low.ProductID || stockErr.Items[0].Requested != 3 || stockErr.Items[0].AvailableStock != 1 {
+		t.Fatalf("unexpected issue: %+v", stockErr.Items[0])
+	}
+	if stockErr.Items[1].ProductID != gone.ProductID {
+		t.Fatalf("unexpected issue: %+v", stockErr.Items[1])
+	}
+
+	// Sản phẩm đủ hàng cũng không bị trừ stock
+	stored, _ := st.Products().FindByProductID(context.Background(), enough.ProductID)
+	if stored.Amount != 10 {
+		t.Fatalf("expected stock to stay 10, got %d", stored.Amount)
+	}
+}
+
+func TestOrderService_CreateOrderFromCart_Concurrent(t *testing.T) {
+	const (
+		stock     = 5
+		customers = 20
+	)
+
+	st := newTestStore()
+	os := NewOrderService(st)
+	product := createTestProduct(t, st, func(p *models.Product) { p.Amount = stock })
+
+	users := make([]*models.User, customers)
+	for i := range users {
+		users[i] = createTestUser(t, st)
+		createTestCart(t, st, users[i].ID, []models.CartItem{cartItemFor(product, 1)})
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, rejected := 0, 0
+	for _, user := range users {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			_, err := os.CreateOrderFromCart(userID, validOrderData())
+
+			mu.Lock()
+			defer mu.Unlock()
+			var stockErr *StockError
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.As(err, &stockErr):
+				rejected++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(user.ID.Hex())
+	}
+	wg.Wait()
+
+	ctx := context.Background()
+	stored, _ := st.Products().FindByProductID(ctx, product.ProductID)
+	if stored.Amount < 0 {
+		t.Fatalf("stock went negative: %d", stored.Amount)
+	}
+	if succeeded != stock || rejected != customers-stock || stored.Amount != 0 {
+		t.Fatalf("expected %d orders and stock 0, got %d orders, %d rejected, stock %d", stock, succeeded, rejected, stored.Amount)
+	}
+	count, _ := st.Orders().Count(ctx, store.OrderFilter{})
+	if count != stock {
+		t.Fatalf("expected %d stored orders, got %d", stock, count)
+	}
+}
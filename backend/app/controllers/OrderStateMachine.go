@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Trạng thái đơn hàng
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusConfirmed  = "confirmed"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+	OrderStatusRefunded   = "refunded"
+)
+
+// Vai trò của người thực hiện chuyển trạng thái
+const (
+	ActorAdmin    = "admin"
+	ActorCustomer = "customer"
+	ActorSystem   = "system"
+)
+
+// legacyOrderStatuses: giá trị cũ còn nằm trong DB, được hiểu như trạng thái mới tương ứng
+var legacyOrderStatuses = map[string]string{
+	"shipping": OrderStatusShipped,
+}
+
+// normalizeOrderStatus chuyển trạng thái cũ sang trạng thái hiện tại
+func normalizeOrderStatus(status string) string {
+	if mapped, ok := legacyOrderStatuses[status]; ok {
+		return mapped
+	}
+	return status
+}
+
+// OrderTransition là một dòng trong bảng chuyển trạng thái.
+// Roles là các vai trò được phép thực hiện; admin và system luôn được phép.
+type OrderTransition struct {
+	From  string
+	To    string
+	Roles []string
+}
+
+// DefaultOrderTransitions: pending→confirmed→processing→shipped→delivered,
+// hủy được trước khi giao cho vận chuyển, hoàn tiền sau khi giao hoặc sau khi hủy
+func DefaultOrderTransitions() []OrderTransition {
+	return []OrderTransition{
+		{From: OrderStatusPending, To: OrderStatusConfirmed},
+		{From: OrderStatusPending, To: OrderStatusCancelled, Roles: []string{ActorCustomer}},
+		{From: OrderStatusConfirmed, To: OrderStatusProcessing},
+		{From: OrderStatusConfirmed, To: OrderStatusCancelled},
+		{From: OrderStatusProcessing, To: OrderStatusShipped},
+		{From: OrderStatusProcessing, To: OrderStatusCancelled},
+		{From: OrderStatusShipped, To: OrderStatusDelivered},
+		{From: OrderStatusDelivered, To: OrderStatusRefunded},
+		{From: OrderStatusCancelled, To: OrderStatusRefunded},
+	}
+}
+
+// OrderActor là người (hoặc job) thực hiện thay đổi trạng thái
+type OrderActor struct {
+	ID   string
+	Role string
+}
+
+// StatusChange mô tả một lần chuyển trạng thái, được truyền cho các guard
+type StatusChange struct {
+	Order  *models.Order
+	From   string
+	To     string
+	Actor  OrderActor
+	Reason string
+}
+
+// TransitionGuard kiểm tra điều kiện nghiệp vụ trước khi chuyển trạng thái; trả lỗi để chặn
+type TransitionGuard func(ctx context.Context, change StatusChange) error
+
+// TransitionError được trả về khi bảng chuyển trạng thái hoặc guard không cho phép
+type TransitionError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("không thể chuyển đơn hàng từ trạng thái %s sang %s", e.From, e.To)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// ErrInvalidOrderStatus được trả về khi trạng thái đích không có trong bảng chuyển trạng thái
+var ErrInvalidOrderStatus = errors.New("trạng thái không hợp lệ")
+
+// OrderStateMachine kiểm tra chuyển trạng thái theo bảng khai báo và các guard đăng ký thêm
+type OrderStateMachine struct {
+	mu          sync.RWMutex
+	order       []OrderTransition
+	transitions map[string]map[string]OrderTransition
+	statuses    map[string]bool
+	guards      map[string][]TransitionGuard
+}
+
+// NewOrderStateMachine tạo state machine từ bảng chuyển trạng thái
+func NewOrderStateMachine(transitions []OrderTransition) *OrderStateMachine {
+	sm := &OrderStateMachine{
+		order:       transitions,
+		transitions: make(map[string]map[string]OrderTransition),
+		statuses:    make(map[string]bool),
+		guards:      make(map[string][]TransitionGuard),
+	}
+	for _, t := range transitions {
+		if sm.transitions[t.From] == nil {
+			sm.transitions[t.From] = make(map[string]OrderTransition)
+		}
+		sm.transitions[t.From][t.To] = t
+		sm.statuses[t.From] = true
+		sm.statuses[t.To] = true
+	}
+	return sm
+}
+
+// NewDefaultOrderStateMachine tạo state machine với bảng mặc định và các guard nghiệp vụ cơ bản
+func NewDefaultOrderStateMachine() *OrderStateMachine {
+	sm := NewOrderStateMachine(DefaultOrderTransitions())
+	sm.Guard(OrderStatusCancelled, OrderStatusRefunded, requirePaidOrder)
+	return sm
+}
+
+// Guard đăng ký guard cho chuyển trạng thái from→to; from rỗng áp dụng cho mọi trạng thái nguồn
+func (sm *OrderStateMachine) Guard(from, to string, guard TransitionGuard) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	key := guardKey(from, to)
+	sm.guards[key] = append(sm.guards[key], guard)
+}
+
+// IsValidStatus kiểm tra trạng thái có trong bảng không
+func (sm *OrderStateMachine) IsValidStatus(status string) bool {
+	return sm.statuses[normalizeOrderStatus(status)]
+}
+
+// NextStatuses trả về các trạng thái có thể chuyển tới từ from (theo thứ tự trong bảng)
+func (sm *OrderStateMachine) NextStatuses(from string, role string) []string {
+	from = normalizeOrderStatus(from)
+	next := []string{}
+	for _, t := range sm.order {
+		if t.From == from && roleAllowed(t, role) {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
+
+// Check kiểm tra change có hợp lệ theo bảng, vai trò và các guard không
+func (sm *OrderStateMachine) Check(ctx context.Context, change StatusChange) error {
+	change.From = normalizeOrderStatus(change.From)
+	change.To = normalizeOrderStatus(change.To)
+
+	if !sm.statuses[change.To] {
+		return ErrInvalidOrderStatus
+	}
+
+	transition, ok := sm.transitions[change.From][change.To]
+	if !ok {
+		return &TransitionError{From: change.From, To: change.To}
+	}
+	if !roleAllowed(transition, change.Actor.Role) {
+		return &TransitionError{From: change.From, To: change.To, Reason: "không có quyền thực hiện"}
+	}
+
+	sm.mu.RLock()
+	guards := append(append([]TransitionGuard{}, sm.guards[guardKey("", change.To)]...), sm.guards[guardKey(change.From, change.To)]...)
+	sm.mu.RUnlock()
+
+	for _, guard := range guards {
+		if err := guard(ctx, change); err != nil {
+			return &TransitionError{From: change.From, To: change.To, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+func guardKey(from, to string) string {
+	return from + ">" + to
+}
+
+func roleAllowed(t OrderTransition, role string) bool {
+	if role == ActorAdmin || role == ActorSystem {
+		return true
+	}
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// requirePaidOrder: chỉ hoàn tiền đơn đã hủy khi khách đã thanh toán
+func requirePaidOrder(ctx context.Context, change StatusChange) error {
+	if status := change.Order.Payment.Status; status != "paid" && status != "partially_refunded" {
+		return errors.New("đơn hàng chưa được thanh toán")
+	}
+	return nil
+}
+
+// OrderTimeline trả về lịch sử trạng thái của đơn hàng. Đơn tạo trước khi có status_history
+// được dựng lại từ createdAt/updatedAt.
+func OrderTimeline(order *models.Order) []models.OrderStatusEntry {
+	if len(order.StatusHistory) > 0 {
+		timeline := make([]models.OrderStatusEntry, len(order.StatusHistory))
+		copy(timeline, order.StatusHistory)
+		return timeline
+	}
+
+	timeline := []models.OrderStatusEntry{{
+		Status:    OrderStatusPending,
+		ActorRole: ActorCustomer,
+		ChangedAt: order.CreatedAt,
+	}}
+	if status := normalizeOrderStatus(order.Status); status != OrderStatusPending && status != "" {
+		timeline = append(timeline, models.OrderStatusEntry{
+			From:      OrderStatusPending,
+			Status:    status,
+			ActorRole: ActorSystem,
+			ChangedAt: order.UpdatedAt,
+		})
+	}
+	return timeline
+}
+
+// statusTimestampFields trả về các field thời gian cần set khi chuyển sang trạng thái to
+func statusTimestampFields(order *models.Order, to string, now time.Time) bson.M {
+	fields := bson.M{}
+	switch to {
+	case OrderStatusShipped:
+		fields["shipped_at"] = now
+	case OrderStatusDelivered:
+		fields["delivered_at"] = now
+		// COD: tiền được thu khi giao hàng
+		if order.Payment.Method == "cash_on_delivery" && order.Payment.Status == "pending" {
+			fields["payment.status"] = "paid"
+			fields["payment.paid_at"] = now
+		}
+	case OrderStatusCancelled:
+		fields["cancelled_at"] = now
+	}
+	return fields
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+func TestOrderStateMachine_Check(t *testing.T) {
+	sm := NewDefaultOrderStateMachine()
+	order := &models.Order{Status: "pending"}
+	ctx := context.Background()
+
+	t.Run("trạng thái không có trong bảng", func(t *testing.T) {
+		err := sm.Check(ctx, StatusChange{Order: order, From: "pending", To: "lost", Actor: testAdminActor})
+		if err != ErrInvalidOrderStatus {
+			t.Fatalf("expected ErrInvalidOrderStatus, got %v", err)
+		}
+	})
+
+	t.Run("khách hàng chỉ được hủy đơn pending", func(t *testing.T) {
+		customer := OrderActor{ID: "u1", Role: ActorCustomer}
+		mustNoError(t, sm.Check(ctx, StatusChange{Order: order, From: "pending", To: "cancelled", Actor: customer}))
+
+		err := sm.Check(ctx, StatusChange{Order: order, From: "confirmed", To: "cancelled", Actor: customer})
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) || transitionErr.Reason != "không có quyền thực hiện" {
+			t.Fatalf("expected permission error, got %v", err)
+		}
+	})
+
+	t.Run("guard chặn chuyển trạng thái", func(t *testing.T) {
+		custom := NewOrderStateMachine(DefaultOrderTransitions())
+		custom.Guard("", OrderStatusShipped, func(ctx context.Context, change StatusChange) error {
+			if change.Order.Tracking == nil {
+				return errors.New("chưa có mã vận đơn")
+			}
+			return nil
+		})
+
+		err := custom.Check(ctx, StatusChange{Order: order, From: "processing", To: "shipped", Actor: testAdminActor})
+		expectError(t, err, "không thể chuyển đơn hàng từ trạng thái processing sang shipped: chưa có mã vận đơn")
+
+		tracked := &models.Order{Status: "processing", Tracking: &models.Tracking{TrackingNumber: "VN123"}}
+		mustNoError(t, custom.Check(ctx, StatusChange{Order: tracked, From: "processing", To: "shipped", Actor: testAdminActor}))
+	})
+}
+
+func TestOrderStateMachine_NextStatuses(t *testing.T) {
+	sm := NewDefaultOrderStateMachine()
+
+	if got := sm.NextStatuses("pending", ActorAdmin); !reflect.DeepEqual(got, []string{"confirmed", "cancelled"}) {
+		t.Fatalf("unexpected admin statuses: %v", got)
+	}
+	if got := sm.NextStatuses("pending", ActorCustomer); !reflect.DeepEqual(got, []string{"cancelled"}) {
+		t.Fatalf("unexpected customer statuses: %v", got)
+	}
+	if got := sm.NextStatuses("shipping", ActorAdmin); !reflect.DeepEqual(got, []string{"delivered"}) {
+		t.Fatalf("expected legacy shipping to behave like shipped, got %v", got)
+	}
+	if got := sm.NextStatuses("refunded", ActorAdmin); len(got) != 0 {
+		t.Fatalf("expected refunded to be terminal, got %v", got)
+	}
+}
+
+func TestOrderTimeline(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	updatedAt := createdAt.Add(2 * time.Hour)
+
+	t.Run("đơn cũ không có status_history", func(t *testing.T) {
+		timeline := OrderTimeline(&models.Order{Status: "shipping", CreatedAt: createdAt, UpdatedAt: updatedAt})
+		if len(timeline) != 2 {
+			t.Fatalf("expected 2 entries, got %+v", timeline)
+		}
+		if timeline[0].Status != "pending" || !timeline[0].ChangedAt.Equal(createdAt) {
+			t.Fatalf("unexpected first entry: %+v", timeline[0])
+		}
+		if timeline[1].Status != "shipped" || !timeline[1].ChangedAt.Equal(updatedAt) {
+			t.Fatalf("unexpected second entry: %+v", timeline[1])
+		}
+	})
+
+	t.Run("đơn có status_history", func(t *testing.T) {
+		history := []models.OrderStatusEntry{{Status: "pending", ChangedAt: createdAt}}
+		timeline := OrderTimeline(&models.Order{Status: "pending", StatusHistory: history})
+		if !reflect.DeepEqual(timeline, history) {
+			t.Fatalf("expected history to be returned, got %+v", timeline)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// trackingNumberPlaceholder được thay bằng mã vận đơn trong URLTemplate
+const trackingNumberPlaceholder = "{tracking_number}"
+
+var trackingNumberPattern = regexp.MustCompile(`^[A-Za-z0-9-]{4,40}$`)
+
+// TrackingCarrier là một đơn vị vận chuyển có trang tra cứu vận đơn
+type TrackingCarrier struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	URLTemplate string `json:"url_template"` // vd https://carrier.vn/tracking?code={tracking_number}
+}
+
+// URL trả về link tra cứu của mã vận đơn, rỗng nếu carrier không có trang tra cứu
+func (c TrackingCarrier) URL(trackingNumber string) string {
+	if c.URLTemplate == "" {
+		return ""
+	}
+	return strings.ReplaceAll(c.URLTemplate, trackingNumberPlaceholder, url.QueryEscape(trackingNumber))
+}
+
+// DefaultTrackingCarriers là các đơn vị vận chuyển phổ biến tại Việt Nam
+func DefaultTrackingCarriers() []TrackingCarrier {
+	return []TrackingCarrier{
+		{Code: "ghn", Name: "Giao Hàng Nhanh", URLTemplate: "https://donhang.ghn.vn/?order_code={tracking_number}"},
+		{Code: "ghtk", Name: "Giao Hàng Tiết Kiệm", URLTemplate: "https://i.ghtk.vn/{tracking_number}"},
+		{Code: "viettelpost", Name: "Viettel Post", URLTemplate: "https://viettelpost.com.vn/tra-cuu-hanh-trinh-don/?code={tracking_number}"},
+		{Code: "vnpost", Name: "VNPost", URLTemplate: "https://www.vnpost.vn/tra-cuu-hanh-trinh/buu-pham?key={tracking_number}"},
+		{Code: "jt", Name: "J&T Express", URLTemplate: "https://jtexpress.vn/vi/tracking?type=track&billcode={tracking_number}"},
+	}
+}
+
+// trackingCarriersFromEnv đọc TRACKING_CARRIERS (JSON, vd [{"code":"ahamove","name":"Ahamove",
+// "url_template":"https://ahamove.com/tracking/{tracking_number}"}]); carrier cùng code ghi đè mặc định
+func trackingCarriersFromEnv() map[string]TrackingCarrier {
+	carriers := make(map[string]TrackingCarrier)
+	for _, carrier := range DefaultTrackingCarriers() {
+		carriers[carrier.Code] = carrier
+	}
+
+	value := os.Getenv("TRACKING_CARRIERS")
+	if value == "" {
+		return carriers
+	}
+	var custom []TrackingCarrier
+	if err := json.Unmarshal([]byte(value), &custom); err != nil {
+		log.Printf("Invalid TRACKING_CARRIERS, using default carriers: %v", err)
+		return carriers
+	}
+	for _, carrier := range custom {
+		code := strings.ToLower(strings.TrimSpace(carrier.Code))
+		if code == "" {
+			continue
+		}
+		carrier.Code = code
+		carriers[code] = carrier
+	}
+	return carriers
+}
+
+// TrackingUpdate là thông tin vận đơn admin gắn cho đơn hàng
+type TrackingUpdate struct {
+	Carrier        string
+	TrackingNumber string
+	TrackingURL    string // Chỉ dùng khi carrier không có URL template
+	MarkShipped    bool   // Đồng thời chuyển đơn sang "shipped"
+}
+
+// OrderTrackingInfo là phần theo dõi vận chuyển hiển thị cho khách
+type OrderTrackingInfo struct {
+	Status         string     `json:"status"`
+	Carrier        string     `json:"carrier,omitempty"`
+	CarrierName    string     `json:"carrier_name,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	TrackingURL    string     `json:"tracking_url,omitempty"`
+	ShippedAt      *time.Time `json:"shipped_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Carriers trả về danh sách đơn vị vận chuyển, sắp xếp theo code
+func (os *OrderService) Carriers() []TrackingCarrier {
+	carriers := make([]TrackingCarrier, 0, len(os.carriers))
+	for _, carrier := range os.carriers {
+		carriers = append(carriers, carrier)
+	}
+	sort.Slice(carriers, func(i, j int) bool { return carriers[i].Code < carriers[j].Code })
+	return carriers
+}
+
+// UpdateTracking gắn (hoặc sửa) vận đơn cho đơn hàng đã xác nhận; MarkShipped chuyển đơn sang
+// "shipped" trong cùng một lần cập nhật để shipped_at và vận đơn luôn đi cùng nhau
+func (os *OrderService) UpdateTracking(orderID string, update TrackingUpdate, actor OrderActor) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+	order, err := os.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+
+	tracking, err := os.buildTracking(update)
+	if err != nil {
+		return nil, err
+	}
+
+	status := normalizeOrderStatus(order.Status)
+	if update.MarkShipped && status != OrderStatusShipped {
+		change := StatusChange{Order: order, From: order.Status, To: OrderStatusShipped, Actor: actor}
+		if err := os.statuses.Check(ctx, change); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		entry := models.OrderStatusEntry{
+			From:      status,
+			Status:    OrderStatusShipped,
+			ActorID:   actor.ID,
+			ActorRole: actor.Role,
+			Reason:    fmt.Sprintf("%s %s", tracking.Carrier, tracking.TrackingNumber),
+			ChangedAt: now,
+		}
+		fields := statusTimestampFields(order, OrderStatusShipped, now)
+		fields["tracking"] = tracking
+		err = os.store.Orders().UpdateStatus(ctx, objectID, order.Status, entry, fields)
+	} else {
+		switch status {
+		case OrderStatusConfirmed, OrderStatusProcessing, OrderStatusShipped, OrderStatusDelivered:
+		default:
+			return nil, errors.New("chỉ có thể cập nhật vận đơn cho đơn hàng đã xác nhận")
+		}
+		err = os.store.Orders().UpdateTracking(ctx, objectID, order.Status, tracking)
+	}
+	if err != nil {
+		if err == store.ErrStatusConflict {
+			return nil, errors.New("trạng thái đơn hàng vừa được cập nhật, vui lòng thử lại")
+		}
+		return nil, errors.New("lỗi khi cập nhật vận đơn")
+	}
+
+	updated, err := os.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, errors.New("lỗi khi lấy đơn hàng đã cập nhật")
+	}
+	return updated, nil
+}
+
+// buildTracking kiểm tra carrier và mã vận đơn, tạo link tra cứu từ URL template của carrier
+func (os *OrderService) buildTracking(update TrackingUpdate) (models.Tracking, error) {
+	code := strings.ToLower(strings.TrimSpace(update.Carrier))
+	number := strings.TrimSpace(update.TrackingNumber)
+	if code == "" {
+		return models.Tracking{}, errors.New("đơn vị vận chuyển là bắt buộc")
+	}
+	if !trackingNumberPattern.MatchString(number) {
+		return models.Tracking{}, errors.New("mã vận đơn không hợp lệ")
+	}
+
+	tracking := models.Tracking{Carrier: code, TrackingNumber: number}
+	if carrier, ok := os.carriers[code]; ok && carrier.URLTemplate != "" {
+		tracking.TrackingURL = carrier.URL(number)
+		return tracking, nil
+	}
+
+	// Carrier chưa cấu hình: dùng link admin nhập (nếu có)
+	if link := strings.TrimSpace(update.TrackingURL); link != "" {
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return models.Tracking{}, errors.New("link tra cứu vận đơn không hợp lệ")
+		}
+		tracking.TrackingURL = link
+	}
+	return tracking, nil
+}
+
+// TrackingInfo trả về phần theo dõi vận chuyển của đơn, nil nếu đơn chưa có vận đơn và chưa giao cho vận chuyển
+func (os *OrderService) TrackingInfo(order *models.Order) *OrderTrackingInfo {
+	if order.Tracking == nil && order.ShippedAt == nil {
+		return nil
+	}
+
+	info := &OrderTrackingInfo{
+		Status:      normalizeOrderStatus(order.Status),
+		ShippedAt:   order.ShippedAt,
+		DeliveredAt: order.DeliveredAt,
+	}
+	if order.Tracking != nil {
+		info.Carrier = order.Tracking.Carrier
+		info.CarrierName = order.Tracking.Carrier
+		info.TrackingNumber = order.Tracking.TrackingNumber
+		info.TrackingURL = order.Tracking.TrackingURL
+		if carrier, ok := os.carriers[order.Tracking.Carrier]; ok {
+			info.CarrierName = carrier.Name
+			if info.TrackingURL == "" {
+				info.TrackingURL = carrier.URL(order.Tracking.TrackingNumber)
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+var trackingAdmin = OrderActor{ID: "admin-1", Role: ActorAdmin}
+
+func TestOrderService_UpdateTracking(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = OrderStatusProcessing })
+	os := NewOrderService(st)
+
+	updated, err := os.UpdateTracking(order.ID.Hex(), TrackingUpdate{Carrier: "GHN", TrackingNumber: " LBK7X9A2 "}, trackingAdmin)
+	mustNoError(t, err)
+	if updated.Tracking == nil || updated.Tracking.Carrier != "ghn" || updated.Tracking.TrackingURL != "https://donhang.ghn.vn/?order_code=LBK7X9A2" {
+		t.Fatalf("unexpected tracking %+v", updated.Tracking)
+	}
+	if updated.Status != OrderStatusProcessing || updated.ShippedAt != nil {
+		t.Fatalf("expected order still processing, got %s", updated.Status)
+	}
+
+	// Gắn lại vận đơn và giao cho vận chuyển
+	shipped, err := os.UpdateTracking(order.ID.Hex(), TrackingUpdate{Carrier: "ghtk", TrackingNumber: "S1234567", MarkShipped: true}, trackingAdmin)
+	mustNoError(t, err)
+	if shipped.Status != OrderStatusShipped || shipped.ShippedAt == nil || shipped.Tracking.TrackingNumber != "S1234567" {
+		t.Fatalf("expected shipped order with tracking, got %s %+v", shipped.Status, shipped.Tracking)
+	}
+
+	info := os.TrackingInfo(shipped)
+	if info == nil || info.CarrierName != "Giao Hàng Tiết Kiệm" || info.TrackingURL != "https://i.ghtk.vn/S1234567" || info.ShippedAt == nil {
+		t.Fatalf("unexpected tracking info %+v", info)
+	}
+
+	delivered, err := os.UpdateOrderStatus(order.ID.Hex(), OrderStatusDelivered, trackingAdmin, "")
+	mustNoError(t, err)
+	if info := os.TrackingInfo(delivered); info.Status != OrderStatusDelivered || info.DeliveredAt == nil {
+		t.Fatalf("expected delivered tracking info, got %+v", info)
+	}
+}
+
+func TestOrderService_UpdateTrackingValidation(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	os := NewOrderService(st)
+
+	pending := createTestOrder(t, st, user.ID)
+	_, err := os.UpdateTracking(pending.ID.Hex(), TrackingUpdate{Carrier: "ghn", TrackingNumber: "LBK7X9A2"}, trackingAdmin)
+	expectError(t, err, "đơn hàng đã xác nhận")
+	if os.TrackingInfo(pending) != nil {
+		t.Fatalf("expected no tracking section for pending order")
+	}
+
+	confirmed := createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = OrderStatusConfirmed })
+	_, err = os.UpdateTracking(confirmed.ID.Hex(), TrackingUpdate{Carrier: "ghn", TrackingNumber: "LBK7X9A2", MarkShipped: true}, trackingAdmin)
+	if _, ok := err.(*TransitionError); !ok {
+		t.Fatalf("expected TransitionError when skipping processing, got %v", err)
+	}
+	_, err = os.UpdateTracking(confirmed.ID.Hex(), TrackingUpdate{Carrier: "ghn", TrackingNumber: "a b"}, trackingAdmin)
+	expectError(t, err, "mã vận đơn không hợp lệ")
+	_, err = os.UpdateTracking(confirmed.ID.Hex(), TrackingUpdate{TrackingNumber: "LBK7X9A2"}, trackingAdmin)
+	expectError(t, err, "đơn vị vận chuyển là bắt buộc")
+	_, err = os.UpdateTracking(confirmed.ID.Hex(), TrackingUpdate{Carrier: "shop", TrackingNumber: "LBK7X9A2", TrackingURL: "ftp://x"}, trackingAdmin)
+	expectError(t, err, "link tra cứu vận đơn không hợp lệ")
+
+	// Carrier chưa cấu hình dùng link admin nhập
+	custom, err := os.UpdateTracking(confirmed.ID.Hex(), TrackingUpdate{Carrier: "shop", TrackingNumber: "LBK7X9A2", TrackingURL: "https://shop.vn/track/LBK7X9A2"}, trackingAdmin)
+	mustNoError(t, err)
+	if info := os.TrackingInfo(custom); info.CarrierName != "shop" || info.TrackingURL != "https://shop.vn/track/LBK7X9A2" {
+		t.Fatalf("unexpected custom tracking info %+v", info)
+	}
+}
+
+func TestTrackingCarriersFromEnv(t *testing.T) {
+	t.Setenv("TRACKING_CARRIERS", `[{"code":"Ahamove","name":"Ahamove","url_template":"https://ahamove.com/t/{tracking_number}"},{"code":"ghn","name":"GHN"}]`)
+
+	carriers := trackingCarriersFromEnv()
+	if carriers["ahamove"].URL("AB12") != "https://ahamove.com/t/AB12" {
+		t.Fatalf("expected custom carrier, got %+v", carriers["ahamove"])
+	}
+	if carriers["ghn"].URLTemplate != "" || carriers["ghtk"].URLTemplate == "" {
+		t.Fatalf("expected ghn overridden and defaults kept, got %+v", carriers)
+	}
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Khổ giấy (point, 1/72 inch)
+const (
+	PDFA4Width     = 595.0
+	PDFA4Height    = 842.0
+	PDFLabelWidth  = 298.0 // A6, khổ tem vận đơn phổ biến
+	PDFLabelHeight = 420.0
+)
+
+// PDFDocument là trình tạo PDF tối giản (chữ Helvetica, đường kẻ, khung) đủ cho tem vận đơn và hóa đơn,
+// không cần thư viện ngoài. Tọa độ tính từ góc dưới bên trái như chuẩn PDF.
+type PDFDocument struct {
+	width, height float64
+	pages         []*bytes.Buffer
+}
+
+// NewPDFDocument tạo tài liệu với một trang trống
+func NewPDFDocument(width, height float64) *PDFDocument {
+	d := &PDFDocument{width: width, height: height}
+	d.AddPage()
+	return d
+}
+
+// AddPage thêm trang mới, các lệnh vẽ sau đó ghi vào trang này
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *PDFDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Text viết một dòng chữ tại (x, y); tiếng Việt được bỏ dấu vì font chuẩn của PDF không có các ký tự này
+func (d *PDFDocument) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(pdfText(text)))
+}
+
+// TextRight viết chữ căn phải tại xRight (độ rộng ước lượng theo Helvetica)
+func (d *PDFDocument) TextRight(xRight, y, size float64, bold bool, text string) {
+	d.Text(xRight-pdfTextWidth(pdfText(text), size), y, size, bold, text)
+}
+
+// Line kẻ đoạn thẳng
+func (d *PDFDocument) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Rect vẽ khung chữ nhật có góc dưới trái tại (x, y)
+func (d *PDFDocument) Rect(x, y, w, h float64) {
+	fmt.Fprintf(d.page(), "%.2f %.2f %.2f %.2f re S\n", x, y, w, h)
+}
+
+// Bytes trả về nội dung file PDF
+func (d *PDFDocument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// 1: catalog, 2: pages, 3-4: font, sau đó mỗi trang gồm page + content stream
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			d.width, d.height, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// vietnameseLetters liệt kê các chữ có dấu theo chữ cái gốc
+var vietnameseLetters = map[rune]string{
+	'a': "àáảãạăằắẳẵặâầấẩẫậ", 'A': "ÀÁẢÃẠĂẰẮẲẴẶÂẦẤẨẪẬ",
+	'e': "èéẻẽẹêềếểễệ", 'E': "ÈÉẺẼẸÊỀẾỂỄỆ",
+	'i': "ìíỉĩị", 'I': "ÌÍỈĨỊ",
+	'o': "òóỏõọôồốổỗộơờớởỡợ", 'O': "ÒÓỎÕỌÔỒỐỔỖỘƠỜỚỞỠỢ",
+	'u': "ùúủũụưừứửữự", 'U': "ÙÚỦŨỤƯỪỨỬỮỰ",
+	'y': "ỳýỷỹỵ", 'Y': "ỲÝỶỸỴ",
+	'd': "đ", 'D': "Đ",
+}
+
+var vietnameseToASCII = func() map[rune]rune {
+	m := make(map[rune]rune)
+	for base, letters := range vietnameseLetters {
+		for _, r := range letters {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// pdfText bỏ dấu tiếng Việt và thay ký tự ngoài ASCII bằng "?"
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if base, ok := vietnameseToASCII[r]; ok {
+			r = base
+		}
+		if r >= 0x0300 && r <= 0x036F {
+			continue // dấu rời (Unicode dạng NFD)
+		}
+		if r < 32 || r > 126 {
+			r = '?'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
+
+// pdfTextWidth ước lượng độ rộng chuỗi Helvetica: chữ số và chữ thường ~0.55em, chữ hoa ~0.67em
+func pdfTextWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case r == ' ' || r == '.' || r == ',':
+			width += 0.28
+		case r >= 'A' && r <= 'Z':
+			width += 0.67
+		default:
+			width += 0.55
+		}
+	}
+	return width * size
+}
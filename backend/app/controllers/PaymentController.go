@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// PaymentController handles online payment HTTP requests
+type PaymentController struct {
+	paymentService *PaymentService
+	refundService  *RefundService
+}
+
+// RefundOrderRequest là body của yêu cầu hoàn tiền
+type RefundOrderRequest struct {
+	Amount  *models.Money            `json:"amount"` // Bỏ trống = hoàn toàn bộ số tiền còn lại
+	Reason  string                   `json:"reason" binding:"required"`
+	Restock bool                     `json:"restock"`
+	Items   []models.RefundStockItem `json:"items"`
+}
+
+// NewPaymentController creates a new payment controller instance
+func NewPaymentController(st store.Store) *PaymentController {
+	providers := paymentProvidersFromEnv()
+	return &PaymentController{
+		paymentService: NewPaymentService(st, providers...),
+		refundService:  NewRefundService(st, providers...),
+	}
+}
+
+// CreatePayment tạo phiên thanh toán online cho đơn hàng của user
+func (pc *PaymentController) CreatePayment(c *gin.Context) {
+	id := c.Param("id")
+
+	// Get user ID from token
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Không thể xác thực người dùng",
+		})
+		return
+	}
+
+	intent, err := pc.paymentService.CreatePayment(id, userID.(string))
+	if err != nil {
+		switch err {
+		case ErrPaymentNotRequired, ErrOrderNotPayable:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case ErrOrderAlreadyPaid, ErrPaymentConflict:
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case ErrBankTransferNotConfigured:
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		default:
+			if err.Error() == "đơn hàng không tồn tại" {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Internal server error",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Tạo phiên thanh toán thành công",
+		"data":    intent,
+	})
+}
+
+// Webhook nhận callback từ cổng thanh toán (không cần đăng nhập, xác thực bằng chữ ký)
+func (pc *PaymentController) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Không thể đọc dữ liệu request",
+		})
+		return
+	}
+
+	order, err := pc.paymentService.HandleWebhook(c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		switch err {
+		case ErrInvalidPaymentSignature:
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case ErrPaymentProviderNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case ErrPaymentConflict:
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		default:
+			if err.Error() == "lỗi khi cập nhật thanh toán" || err.Error() == "lỗi khi cập nhật đơn hàng" || err.Error() == "lỗi khi lấy đơn hàng đã cập nhật" {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Internal server error",
+				})
+				return
+			}
+			// Callback hợp lệ nhưng không khớp đơn hàng (đơn không tồn tại, sai intent, sai số tiền)
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"order_id":     order.ID.Hex(),
+			"order_number": order.OrderNumber,
+			"status":       order.Status,
+			"payment":      order.Payment,
+		},
+	})
+}
+
+// ReconcileBankStatement nhận file sao kê ngân hàng (CSV, field "file") và đối soát các khoản ghi có
+// với đơn hàng chuyển khoản (Admin only)
+func (pc *PaymentController) ReconcileBankStatement(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Vui lòng tải lên file sao kê (field \"file\")",
+		})
+		return
+	}
+	if fileHeader.Size > MaxBankStatementSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"success": false,
+			"message": "File sao kê quá lớn",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Không thể đọc file sao kê",
+		})
+		return
+	}
+	defer file.Close()
+
+	result, err := pc.paymentService.ReconcileBankStatement(file, orderActorFromContext(c).ID)
+	if err != nil {
+		var statementErr *BankStatementError
+		if errors.As(err, &statementErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Đối soát sao kê thành công",
+		"data":    result,
+	})
+}
+
+// RefundOrder hoàn tiền toàn bộ hoặc một phần cho đơn hàng đã giao/đã hủy (Admin only)
+func (pc *PaymentController) RefundOrder(c *gin.Context) {
+	var req RefundOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	order, refund, err := pc.refundService.RefundOrder(c.Param("id"), RefundRequest{
+		Amount:  req.Amount,
+		Reason:  req.Reason,
+		Restock: req.Restock,
+		Items:   req.Items,
+	}, orderActorFromContext(c))
+	if err != nil {
+		var refundErr *RefundError
+		switch {
+		case errors.As(err, &refundErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case err.Error() == "đơn hàng không tồn tại":
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case err == ErrRefundConflict || err == ErrRefundInProgress:
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+		case errors.Is(err, ErrRefundProviderFailed):
+			c.JSON(http.StatusBadGateway, gin.H{
+				"success": false,
+				"message": ErrRefundProviderFailed.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Internal server error",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Hoàn tiền thành công",
+		"data": gin.H{
+			"order":  order,
+			"refund": refund,
+		},
+	})
+}
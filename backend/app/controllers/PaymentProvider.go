@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+// Loại sự kiện webhook thanh toán
+const (
+	PaymentEventSucceeded = "payment.succeeded"
+	PaymentEventFailed    = "payment.failed"
+)
+
+// PaymentProvider là một cổng thanh toán online. Mỗi provider nhận webhook tại
+// /api/payments/webhook/:provider với :provider = Name().
+type PaymentProvider interface {
+	// Name là tên provider, được lưu vào payment.provider của đơn hàng
+	Name() string
+	// CreateIntent tạo phiên thanh toán cho toàn bộ giá trị đơn hàng
+	CreateIntent(ctx context.Context, order *models.Order) (*PaymentIntent, error)
+	// VerifyWebhook kiểm tra chữ ký của callback và trả về sự kiện đã xác thực.
+	// Trả về ErrInvalidPaymentSignature nếu chữ ký sai hoặc đã hết hạn.
+	VerifyWebhook(header http.Header, body []byte) (*PaymentEvent, error)
+	// Refund hoàn amount cho giao dịch đã thanh toán của đơn hàng
+	Refund(ctx context.Context, payment models.Payment, amount models.Money, reason string) (*PaymentRefund, error)
+}
+
+// PaymentIntent là phiên thanh toán do provider tạo
+type PaymentIntent struct {
+	Provider    string       `json:"provider"`
+	ID          string       `json:"id"`
+	OrderID     string       `json:"order_id"`
+	Amount      models.Money `json:"amount"`
+	CheckoutURL string       `json:"checkout_url,omitempty"` // Trang thanh toán của provider (nếu có)
+	CreatedAt   time.Time    `json:"created_at"`
+
+	// BankTransfer là thông tin chuyển khoản (chỉ có với phương thức bank_transfer)
+	BankTransfer *BankTransferInstructions `json:"bank_transfer,omitempty"`
+}
+
+// PaymentEvent là nội dung callback đã được provider xác thực
+type PaymentEvent struct {
+	ID            string       `json:"id"`
+	Type          string       `json:"type"` // "payment.succeeded", "payment.failed"
+	IntentID      string       `json:"intent_id"`
+	OrderID       string       `json:"order_id"`
+	TransactionID string       `json:"transaction_id,omitempty"`
+	Amount        models.Money `json:"amount"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// PaymentRefund là kết quả hoàn tiền từ provider
+type PaymentRefund struct {
+	ID        string       `json:"id"`
+	Amount    models.Money `json:"amount"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// ErrInvalidPaymentSignature được trả về khi callback không có chữ ký hợp lệ
+var ErrInvalidPaymentSignature = errors.New("chữ ký callback thanh toán không hợp lệ")
+
+// LocalPaymentProviderName là tên của provider giả lập
+const LocalPaymentProviderName = "local"
+
+// LocalSignatureHeader chứa chữ ký của webhook provider local, dạng "t=<unix>,v1=<hex HMAC-SHA256>"
+const LocalSignatureHeader = "X-Local-Signature"
+
+// localSignatureTolerance là độ lệch thời gian tối đa giữa lúc ký và lúc nhận webhook
+const localSignatureTolerance = 5 * time.Minute
+
+// LocalPaymentProvider là cổng thanh toán giả lập chạy hoàn toàn offline: tạo intent ngay lập tức
+// và ký webhook bằng HMAC như một provider thật, để test luồng thanh toán không cần mạng.
+type LocalPaymentProvider struct {
+	secret []byte
+}
+
+// NewLocalPaymentProvider tạo provider local với khóa ký webhook secret
+func NewLocalPaymentProvider(secret string) *LocalPaymentProvider {
+	return &LocalPaymentProvider{secret: []byte(secret)}
+}
+
+// Name trả về "local"
+func (p *LocalPaymentProvider) Name() string {
+	return LocalPaymentProviderName
+}
+
+// CreateIntent tạo intent cho đơn hàng; không có trang thanh toán, kết quả được gửi qua SimulateWebhook
+func (p *LocalPaymentProvider) CreateIntent(ctx context.Context, order *models.Order) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		Provider:  p.Name(),
+		ID:        "pi_local_" + randomHex(12),
+		OrderID:   order.ID.Hex(),
+		Amount:    order.TotalAmount,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// VerifyWebhook kiểm tra header X-Local-Signature rồi đọc sự kiện từ body
+func (p *LocalPaymentProvider) VerifyWebhook(header http.Header, body []byte) (*PaymentEvent, error) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get(LocalSignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return nil, ErrInvalidPaymentSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > localSignatureTolerance || age < -localSignatureTolerance {
+		return nil, ErrInvalidPaymentSignature
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, p.sign(timestamp, body)) {
+		return nil, ErrInvalidPaymentSignature
+	}
+
+	var event PaymentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, errors.New("dữ liệu callback thanh toán không hợp lệ")
+	}
+	return &event, nil
+}
+
+// Refund luôn thành công với giao dịch đã có transaction ID
+func (p *LocalPaymentProvider) Refund(ctx context.Context, payment models.Payment, amount models.Money, reason string) (*PaymentRefund, error) {
+	if payment.TransactionID == "" {
+		return nil, errors.New("giao dịch chưa được thanh toán")
+	}
+	if amount.Amount <= 0 {
+		return nil, errors.New("số tiền hoàn phải lớn hơn 0")
+	}
+	return &PaymentRefund{
+		ID:        "re_local_" + randomHex(12),
+		Amount:    amount,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// SimulateWebhook tạo body và header đã ký cho sự kiện, giống callback provider gửi tới webhook.
+// Sự kiện thiếu ID/TransactionID/CreatedAt được tự sinh.
+func (p *LocalPaymentProvider) SimulateWebhook(event PaymentEvent) ([]byte, http.Header, error) {
+	if event.ID == "" {
+		event.ID = "evt_local_" + randomHex(12)
+	}
+	if event.Type == PaymentEventSucceeded && event.TransactionID == "" {
+		event.TransactionID = "txn_local_" + randomHex(12)
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(LocalSignatureHeader, fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(p.sign(timestamp, body))))
+	return body, header, nil
+}
+
+// sign tính HMAC-SHA256 của "<timestamp>.<body>"
+func (p *LocalPaymentProvider) sign(timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// randomHex sinh chuỗi hex ngẫu nhiên n byte
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// localPaymentSecret đọc PAYMENT_LOCAL_SECRET; không đặt thì dùng khóa ngẫu nhiên cho mỗi lần chạy
+// (webhook local chỉ được ký từ trong process, vd. trong test)
+var localPaymentSecret = sync.OnceValue(func() string {
+	log.Printf("WARNING: local payment provider is enabled (PAYMENT_LOCAL_ENABLED), online payments are simulated and must not be used in production")
+	if secret := os.Getenv("PAYMENT_LOCAL_SECRET"); secret != "" {
+		return secret
+	}
+	log.Printf("PAYMENT_LOCAL_SECRET is not set, using a random secret for the local payment provider")
+	return randomHex(32)
+})
+
+// paymentProvidersFromEnv trả về các cổng thanh toán được bật; provider đầu tiên được dùng cho
+// các phương thức thanh toán online. Hiện chỉ có provider giả lập "local", chỉ bật khi đặt
+// PAYMENT_LOCAL_ENABLED=true (môi trường dev/test). Không có provider nào thì không nhận
+// thanh toán online.
+func paymentProvidersFromEnv() []PaymentProvider {
+	var providers []PaymentProvider
+	if enabled, _ := strconv.ParseBool(os.Getenv("PAYMENT_LOCAL_ENABLED")); enabled {
+		providers = append(providers, NewLocalPaymentProvider(localPaymentSecret()))
+	}
+	return providers
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Trạng thái thanh toán của đơn hàng (payment.status)
+const (
+	PaymentStatusPending  = "pending"
+	PaymentStatusPaid     = "paid"
+	PaymentStatusFailed   = "failed"
+	PaymentStatusRefunded = "refunded"
+
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+)
+
+// onlinePaymentMethods là các phương thức thanh toán qua cổng thanh toán.
+// Chuyển khoản ngân hàng (bank_transfer) được đối soát qua sao kê, xem BankTransfer.go.
+var onlinePaymentMethods = map[string]bool{
+	"credit_card": true,
+	"e_wallet":    true,
+}
+
+// Lỗi của PaymentService
+var (
+	ErrPaymentNotRequired       = errors.New("đơn hàng thanh toán khi nhận hàng, không cần thanh toán online")
+	ErrOrderNotPayable          = errors.New("đơn hàng không ở trạng thái chờ thanh toán")
+	ErrOrderAlreadyPaid         = errors.New("đơn hàng đã được thanh toán")
+	ErrPaymentProviderNotFound  = errors.New("cổng thanh toán không tồn tại")
+	ErrOnlinePaymentUnavailable = errors.New("cửa hàng chưa hỗ trợ thanh toán online, vui lòng chọn phương thức thanh toán khác")
+	ErrPaymentIntentMismatch    = errors.New("phiên thanh toán không khớp với đơn hàng")
+	ErrPaymentAmountMismatch    = errors.New("số tiền thanh toán không khớp với đơn hàng")
+	ErrPaymentConflict          = errors.New("thanh toán của đơn hàng vừa được cập nhật, vui lòng thử lại")
+)
+
+// PaymentService tạo phiên thanh toán qua PaymentProvider và cập nhật đơn hàng theo webhook
+// hoặc sao kê ngân hàng: thanh toán thành công thì payment.status = "paid" và đơn đang chờ
+// được chuyển sang "confirmed".
+type PaymentService struct {
+	store        store.Store
+	providers    map[string]PaymentProvider
+	online       PaymentProvider
+	statuses     *OrderStateMachine
+	bankAccount  BankTransferAccount
+	orderNumbers *OrderNumberGenerator
+}
+
+// NewPaymentService creates a new payment service; providers[0] xử lý các phương thức thanh toán online
+func NewPaymentService(st store.Store, providers ...PaymentProvider) *PaymentService {
+	ps := &PaymentService{
+		store:        st,
+		providers:    make(map[string]PaymentProvider),
+		statuses:     NewDefaultOrderStateMachine(),
+		bankAccount:  bankTransferAccountFromEnv(),
+		orderNumbers: NewOrderNumberGenerator(st.Counters(), orderNumberPrefix()),
+	}
+	for _, provider := range providers {
+		ps.providers[provider.Name()] = provider
+	}
+	if len(providers) > 0 {
+		ps.online = providers[0]
+	}
+	return ps
+}
+
+// CreatePayment tạo phiên thanh toán cho đơn hàng của userID. Có thể gọi lại khi lần thanh toán
+// trước thất bại; intent mới thay thế intent cũ. Đơn chuyển khoản nhận lại thông tin chuyển khoản
+// và mã VietQR cho số tiền còn thiếu.
+func (ps *PaymentService) CreatePayment(orderID, userID string) (*PaymentIntent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+
+	order, err := ps.store.Orders().FindByID(ctx, objectID)
+	if err != nil || !orderBelongsTo(order, userID) {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+
+	if order.Payment.Method == BankTransferMethod {
+		return ps.bankTransferIntent(order)
+	}
+	if !onlinePaymentMethods[order.Payment.Method] {
+		return nil, ErrPaymentNotRequired
+	}
+	if order.Payment.Status == PaymentStatusPaid {
+		return nil, ErrOrderAlreadyPaid
+	}
+	if normalizeOrderStatus(order.Status) != OrderStatusPending || order.Payment.Status == PaymentStatusRefunded {
+		return nil, ErrOrderNotPayable
+	}
+	if ps.online == nil {
+		return nil, ErrPaymentProviderNotFound
+	}
+
+	intent, err := ps.online.CreateIntent(ctx, order)
+	if err != nil {
+		log.Printf("Error creating payment intent for order %s: %v", order.OrderNumber, err)
+		return nil, errors.New("lỗi khi tạo phiên thanh toán")
+	}
+
+	err = ps.store.Orders().UpdatePayment(ctx, order.ID, order.Payment.Status, bson.M{
+		"payment.status":         PaymentStatusPending,
+		"payment.provider":       intent.Provider,
+		"payment.intent_id":      intent.ID,
+		"payment.failure_reason": "",
+	})
+	if err != nil {
+		if err == store.ErrStatusConflict {
+			return nil, ErrPaymentConflict
+		}
+		return nil, errors.New("lỗi khi cập nhật thanh toán")
+	}
+	return intent, nil
+}
+
+// bankTransferIntent trả về thông tin chuyển khoản của đơn hàng, không thay đổi đơn
+func (ps *PaymentService) bankTransferIntent(order *models.Order) (*PaymentIntent, error) {
+	if order.Payment.Status == PaymentStatusPaid {
+		return nil, ErrOrderAlreadyPaid
+	}
+	if normalizeOrderStatus(order.Status) != OrderStatusPending || order.Payment.Status == PaymentStatusRefunded {
+		return nil, ErrOrderNotPayable
+	}
+
+	instructions, err := ps.bankAccount.Instructions(order)
+	if err != nil {
+		log.Printf("Error creating bank transfer instructions for order %s: %v", order.OrderNumber, err)
+		return nil, err
+	}
+	return &PaymentIntent{
+		Provider:     BankTransferMethod,
+		ID:           order.OrderNumber,
+		OrderID:      order.ID.Hex(),
+		Amount:       instructions.Amount,
+		BankTransfer: instructions,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// HandleWebhook xác thực callback của provider và cập nhật thanh toán của đơn hàng.
+// Callback gửi lại (cùng transaction) không thay đổi gì và trả về đơn hàng hiện tại.
+func (ps *PaymentService) HandleWebhook(providerName string, header http.Header, body []byte) (*models.Order, error) {
+	provider, ok := ps.providers[providerName]
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+
+	event, err := provider.VerifyWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(event.OrderID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+	order, err := ps.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+	if order.Payment.Provider != provider.Name() || order.Payment.IntentID != event.IntentID {
+		return nil, ErrPaymentIntentMismatch
+	}
+
+	switch event.Type {
+	case PaymentEventSucceeded:
+		if status := order.Payment.Status; status == PaymentStatusPaid || status == PaymentStatusPartiallyRefunded || status == PaymentStatusRefunded {
+			if order.Payment.TransactionID != event.TransactionID {
+				log.Printf("Order %s already paid by transaction %s, ignoring transaction %s", order.OrderNumber, order.Payment.TransactionID, event.TransactionID)
+			}
+			return order, nil
+		}
+		if event.Amount.CurrencyCode() != order.TotalAmount.CurrencyCode() || event.Amount.Cmp(order.TotalAmount) != 0 {
+			return nil, ErrPaymentAmountMismatch
+		}
+		if err := ps.markPaid(ctx, order, provider.Name(), event); err != nil {
+			return nil, err
+		}
+
+	case PaymentEventFailed:
+		if order.Payment.Status != PaymentStatusPending {
+			return order, nil
+		}
+		err := ps.store.Orders().UpdatePayment(ctx, order.ID, PaymentStatusPending, bson.M{
+			"payment.status":         PaymentStatusFailed,
+			"payment.failure_reason": event.FailureReason,
+		})
+		if err != nil && err != store.ErrStatusConflict {
+			return nil, errors.New("lỗi khi cập nhật thanh toán")
+		}
+
+	default:
+		// Sự kiện khác (vd. đang xử lý) không làm thay đổi đơn hàng
+		return order, nil
+	}
+
+	updated, err := ps.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, errors.New("lỗi khi lấy đơn hàng đã cập nhật")
+	}
+	return updated, nil
+}
+
+// markPaid ghi nhận thanh toán và xác nhận đơn hàng đang chờ. Đơn đã bị hủy hoặc đã được admin
+// xử lý vẫn được ghi nhận thanh toán (để hoàn tiền) nhưng giữ nguyên trạng thái.
+func (ps *PaymentService) markPaid(ctx context.Context, order *models.Order, providerName string, event *PaymentEvent) error {
+	now := time.Now()
+	return runAtomic(ctx, ps.store, func(ctx context.Context, rb *rollback) error {
+		previous := order.Payment
+		err := ps.store.Orders().UpdatePayment(ctx, order.ID, previous.Status, bson.M{
+			"payment.status":         PaymentStatusPaid,
+			"payment.transaction_id": event.TransactionID,
+			"payment.failure_reason": "",
+			"payment.paid_at":        now,
+		})
+		if err != nil {
+			if err == store.ErrStatusConflict {
+				return ErrPaymentConflict
+			}
+			return errors.New("lỗi khi cập nhật thanh toán")
+		}
+		rb.onRollback(func(ctx context.Context) error {
+			return ps.store.Orders().UpdatePayment(ctx, order.ID, PaymentStatusPaid, bson.M{
+				"payment.status":         previous.Status,
+				"payment.transaction_id": previous.TransactionID,
+				"payment.failure_reason": previous.FailureReason,
+				"payment.paid_at":        previous.PaidAt,
+			})
+		})
+
+		actor := OrderActor{ID: providerName, Role: ActorSystem}
+		change := StatusChange{Order: order, From: order.Status, To: OrderStatusConfirmed, Actor: actor}
+		if ps.statuses.Check(ctx, change) != nil {
+			return nil
+		}
+
+		entry := models.OrderStatusEntry{
+			From:      normalizeOrderStatus(order.Status),
+			Status:    OrderStatusConfirmed,
+			ActorID:   actor.ID,
+			ActorRole: actor.Role,
+			Reason:    "thanh toán thành công",
+			ChangedAt: now,
+		}
+		err = ps.store.Orders().UpdateStatus(ctx, order.ID, order.Status, entry, statusTimestampFields(order, OrderStatusConfirmed, now))
+		if err != nil && err != store.ErrStatusConflict {
+			return errors.New("lỗi khi cập nhật đơn hàng")
+		}
+		// Trạng thái vừa bị đổi (vd. admin hủy đơn): vẫn giữ thanh toán đã nhận
+		return nil
+	})
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+func newTestPaymentService(st store.Store) (*PaymentService, *LocalPaymentProvider) {
+	provider := NewLocalPaymentProvider("test-secret")
+	return NewPaymentService(st, provider), provider
+}
+
+func onlinePayment(o *models.Order) {
+	o.Payment.Method = "credit_card"
+}
+
+// sendPaymentWebhook ký sự kiện bằng provider local rồi gửi tới service như một callback
+func sendPaymentWebhook(t *testing.T, ps *PaymentService, provider *LocalPaymentProvider, event PaymentEvent) (*models.Order, error) {
+	t.Helper()
+	body, header, err := provider.SimulateWebhook(event)
+	mustNoError(t, err)
+	return ps.HandleWebhook(provider.Name(), header, body)
+}
+
+func TestPaymentService_PayConfirmsOrder(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, onlinePayment)
+	ps, provider := newTestPaymentService(st)
+
+	intent, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	mustNoError(t, err)
+	if intent.Provider != LocalPaymentProviderName || intent.Amount != order.TotalAmount {
+		t.Fatalf("unexpected intent %+v", intent)
+	}
+
+	paid, err := sendPaymentWebhook(t, ps, provider, PaymentEvent{
+		Type:          PaymentEventSucceeded,
+		IntentID:      intent.ID,
+		OrderID:       intent.OrderID,
+		TransactionID: "txn-1",
+		Amount:        intent.Amount,
+	})
+	mustNoError(t, err)
+
+	if paid.Payment.Status != PaymentStatusPaid || paid.Payment.TransactionID != "txn-1" || paid.Payment.PaidAt == nil {
+		t.Fatalf("expected paid payment with transaction, got %+v", paid.Payment)
+	}
+	if paid.Status != OrderStatusConfirmed {
+		t.Fatalf("expected order confirmed, got %s", paid.Status)
+	}
+	last := paid.StatusHistory[len(paid.StatusHistory)-1]
+	if last.ActorRole != ActorSystem || last.ActorID != LocalPaymentProviderName {
+		t.Fatalf("expected confirmation by payment provider, got %+v", last)
+	}
+
+	// Callback gửi lại không thay đổi đơn hàng
+	again, err := sendPaymentWebhook(t, ps, provider, PaymentEvent{
+		Type:          PaymentEventSucceeded,
+		IntentID:      intent.ID,
+		OrderID:       intent.OrderID,
+		TransactionID: "txn-1",
+		Amount:        intent.Amount,
+	})
+	mustNoError(t, err)
+	if len(again.StatusHistory) != len(paid.StatusHistory) || !again.Payment.PaidAt.Equal(*paid.Payment.PaidAt) {
+		t.Fatalf("expected duplicate webhook to be a no-op")
+	}
+
+	_, err = ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	if err != ErrOrderAlreadyPaid {
+		t.Fatalf("expected ErrOrderAlreadyPaid, got %v", err)
+	}
+}
+
+func TestPaymentService_FailedPaymentCanBeRetried(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, onlinePayment)
+	ps, provider := newTestPaymentService(st)
+
+	first, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	mustNoError(t, err)
+
+	failed, err := sendPaymentWebhook(t, ps, provider, PaymentEvent{
+		Type:          PaymentEventFailed,
+		IntentID:      first.ID,
+		OrderID:       first.OrderID,
+		Amount:        first.Amount,
+		FailureReason: "card declined",
+	})
+	mustNoError(t, err)
+	if failed.Payment.Status != PaymentStatusFailed || failed.Payment.FailureReason != "card declined" || failed.Status != OrderStatusPending {
+		t.Fatalf("expected failed payment on pending order, got %s %+v", failed.Status, failed.Payment)
+	}
+
+	second, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	mustNoError(t, err)
+
+	// Intent cũ không còn được chấp nhận
+	_, err = sendPaymentWebhook(t, ps, provider, PaymentEvent{
+		Type:     PaymentEventSucceeded,
+		IntentID: first.ID,
+		OrderID:  first.OrderID,
+		Amount:   first.Amount,
+	})
+	if err != ErrPaymentIntentMismatch {
+		t.Fatalf("expected ErrPaymentIntentMismatch, got %v", err)
+	}
+
+	paid, err := sendPaymentWebhook(t, ps, provider, PaymentEvent{
+		Type:     PaymentEventSucceeded,
+		IntentID: second.ID,
+		OrderID:  second.OrderID,
+		Amount:   second.Amount,
+	})
+	mustNoError(t, err)
+	if paid.Payment.Status != PaymentStatusPaid || paid.Payment.FailureReason != "" || paid.Status != OrderStatusConfirmed {
+		t.Fatalf("expected paid and confirmed order, got %s %+v", paid.Status, paid.Payment)
+	}
+}
+
+func TestPaymentService_RejectsInvalidCallbacks(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, onlinePayment)
+	ps, provider := newTestPaymentService(st)
+
+	intent, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	mustNoError(t, err)
+	event := PaymentEvent{Type: PaymentEventSucceeded, IntentID: intent.ID, OrderID: intent.OrderID, Amount: intent.Amount}
+
+	// Ký bằng khóa khác
+	body, header, err := NewLocalPaymentProvider("other-secret").SimulateWebhook(event)
+	mustNoError(t, err)
+	if _, err := ps.HandleWebhook(LocalPaymentProviderName, header, body); err != ErrInvalidPaymentSignature {
+		t.Fatalf("expected ErrInvalidPaymentSignature, got %v", err)
+	}
+
+	// Body bị sửa sau khi ký
+	body, header, err = provider.SimulateWebhook(event)
+	mustNoError(t, err)
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] = ' '
+	if _, err := ps.HandleWebhook(LocalPaymentProviderName, header, tampered); err != ErrInvalidPaymentSignature {
+		t.Fatalf("expected ErrInvalidPaymentSignature for tampered body, got %v", err)
+	}
+	if _, err := ps.HandleWebhook(LocalPaymentProviderName, http.Header{}, body); err != ErrInvalidPaymentSignature {
+		t.Fatalf("expected ErrInvalidPaymentSignature without header, got %v", err)
+	}
+	if _, err := ps.HandleWebhook("stripe", header, body); err != ErrPaymentProviderNotFound {
+		t.Fatalf("expected ErrPaymentProviderNotFound, got %v", err)
+	}
+
+	short := event
+	short.Amount = models.VND(1000)
+	if _, err := sendPaymentWebhook(t, ps, provider, short); err != ErrPaymentAmountMismatch {
+		t.Fatalf("expected ErrPaymentAmountMismatch, got %v", err)
+	}
+
+	unchanged, err := st.Orders().FindByID(context.Background(), order.ID)
+	mustNoError(t, err)
+	if unchanged.Payment.Status != PaymentStatusPending || unchanged.Status != OrderStatusPending {
+		t.Fatalf("expected order untouched, got %s %+v", unchanged.Status, unchanged.Payment)
+	}
+}
+
+func TestPaymentService_CreatePaymentValidation(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	other := createTestUser(t, st)
+	ps, _ := newTestPaymentService(st)
+
+	cod := createTestOrder(t, st, user.ID)
+	if _, err := ps.CreatePayment(cod.ID.Hex(), user.ID.Hex()); err != ErrPaymentNotRequired {
+		t.Fatalf("expected ErrPaymentNotRequired, got %v", err)
+	}
+
+	online := createTestOrder(t, st, user.ID, onlinePayment)
+	_, err := ps.CreatePayment(online.ID.Hex(), other.ID.Hex())
+	expectError(t, err, "đơn hàng không tồn tại")
+
+	cancelled := createTestOrder(t, st, user.ID, onlinePayment, func(o *models.Order) {
+		o.Status = OrderStatusCancelled
+	})
+	if _, err := ps.CreatePayment(cancelled.ID.Hex(), user.ID.Hex()); err != ErrOrderNotPayable {
+		t.Fatalf("expected ErrOrderNotPayable, got %v", err)
+	}
+}
+
+func TestPaymentService_PaymentAfterCancelKeepsStatus(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, onlinePayment)
+	ps, provider := newTestPaymentService(st)
+
+	intent, err := ps.CreatePayment(order.ID.Hex(), user.ID.Hex())
+	mustNoError(t, err)
+
+	// Khách hủy đơn trong lúc đang thanh toán
+	mustNoError(t, st.Orders().UpdateStatus(context.Background(), order.ID, OrderStatusPending, models.OrderStatusEntry{
+		Status:    OrderStatusCancelled,
+		ActorRole: ActorCustomer,
+	}, nil))
+
+	paid, err := sendPaymentWebhook(t, ps, provider, PaymentEvent{
+		Type:     PaymentEventSucceeded,
+		IntentID: intent.ID,
+		OrderID:  intent.OrderID,
+		Amount:   intent.Amount,
+	})
+	mustNoError(t, err)
+	if paid.Status != OrderStatusCancelled || paid.Payment.Status != PaymentStatusPaid {
+		t.Fatalf("expected cancelled order with recorded payment, got %s %+v", paid.Status, paid.Payment)
+	}
+
+	// Đơn đã hủy và đã thanh toán thì admin có thể chuyển sang hoàn tiền
+	_, err = NewOrderService(st).UpdateOrderStatus(order.ID.Hex(), OrderStatusRefunded, OrderActor{Role: ActorAdmin}, "")
+	mustNoError(t, err)
+}
+
+func TestOrderService_OnlinePaymentRequiresProvider(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	createTestCart(t, st, user.ID, []models.CartItem{cartItemFor(product, 1)})
+
+	// Không bật provider nào: không nhận credit_card, e_wallet
+	data := validOrderData()
+	data.PaymentMethod = "credit_card"
+	_, err := NewOrderService(st).CreateOrderFromCart(user.ID.Hex(), data)
+	if err != ErrOnlinePaymentUnavailable {
+		t.Fatalf("expected ErrOnlinePaymentUnavailable, got %v", err)
+	}
+
+	t.Setenv("PAYMENT_LOCAL_ENABLED", "true")
+	if providers := paymentProvidersFromEnv(); len(providers) != 1 || providers[0].Name() != LocalPaymentProviderName {
+		t.Fatalf("expected local provider when enabled, got %v", providers)
+	}
+	order, err := NewOrderService(st).CreateOrderFromCart(user.ID.Hex(), data)
+	mustNoError(t, err)
+	if order.Payment.Method != "credit_card" {
+		t.Fatalf("expected credit_card order, got %s", order.Payment.Method)
+	}
+}
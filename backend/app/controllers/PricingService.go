@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// PricingService xác định giá hiệu lực của sản phẩm tại một thời điểm:
+// giá niêm yết, sale_price theo lịch hoặc flash sale đang diễn ra, lấy giá thấp nhất.
+type PricingService struct {
+	store store.Store
+}
+
+// NewPricingService creates a new pricing service instance
+func NewPricingService(st store.Store) *PricingService {
+	return &PricingService{store: st}
+}
+
+// Resolve trả về giá hiệu lực khi userID mua quantity sản phẩm. Flash sale chỉ được áp dụng khi còn đủ
+// số lượng (tổng và theo khách) cho cả dòng hàng, ngược lại dòng hàng dùng giá thường.
+func (ps *PricingService) Resolve(ctx context.Context, product *models.Product, userID string, quantity int, now time.Time) (models.Money, *models.ActiveSale) {
+	return effectivePrice(product, ps.runningFlashSales(ctx, []string{product.ProductID}, now), userID, quantity, now)
+}
+
+// ApplyToProducts gán EffectivePrice/ActiveSale cho danh sách sản phẩm (listing, tìm kiếm)
+func (ps *PricingService) ApplyToProducts(ctx context.Context, products []models.Product, now time.Time) {
+	if len(products) == 0 {
+		return
+	}
+
+	productIDs := make([]string, len(products))
+	for i := range products {
+		productIDs[i] = products[i].ProductID
+	}
+	flashSales := ps.runningFlashSales(ctx, productIDs, now)
+
+	for i := range products {
+		products[i].EffectivePrice, products[i].ActiveSale = effectivePrice(&products[i], flashSales, "", 1, now)
+	}
+}
+
+// runningFlashSales lấy các flash sale đang diễn ra; lỗi đọc DB chỉ làm mất giá flash sale, không chặn request
+func (ps *PricingService) runningFlashSales(ctx context.Context, productIDs []string, now time.Time) []models.FlashSale {
+	flashSales, err := ps.store.FlashSales().FindRunning(ctx, productIDs, now)
+	if err != nil {
+		log.Printf("Error loading flash sales: %v", err)
+		return nil
+	}
+	return flashSales
+}
+
+// effectivePrice chọn giá thấp nhất trong giá niêm yết, sale_price đang hiệu lực và
+// các flash sale còn đủ số lượng cho quantity sản phẩm
+func effectivePrice(product *models.Product, flashSales []models.FlashSale, userID string, quantity int, now time.Time) (models.Money, *models.ActiveSale) {
+	price := product.Price
+	var active *models.ActiveSale
+
+	if salePrice, ok := product.SalePriceAt(now); ok && salePrice.Cmp(price) < 0 {
+		price = salePrice
+		active = &models.ActiveSale{
+			Source: models.PriceSourceSale,
+			Price:  salePrice,
+			EndsAt: product.SaleEndsAt,
+		}
+	}
+
+	for i := range flashSales {
+		sale := &flashSales[i]
+		if sale.ProductID != product.ProductID || !sale.RunningAt(now) {
+			continue
+		}
+		remaining := sale.Remaining(userID)
+		if remaining < quantity || sale.Price.Cmp(price) >= 0 {
+			continue
+		}
+
+		endsAt := sale.EndsAt
+		price = sale.Price
+		active = &models.ActiveSale{
+			Source:      models.PriceSourceFlashSale,
+			Price:       sale.Price,
+			EndsAt:      &endsAt,
+			FlashSaleID: sale.ID.Hex(),
+			Remaining:   remaining,
+		}
+	}
+
+	return price, active
+}
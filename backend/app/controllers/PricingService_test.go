@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEffectivePrice(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	salePrice := models.VND(80000)
+
+	t.Run("sale_price theo lịch", func(t *testing.T) {
+		product := &models.Product{ProductID: "P1", Price: models.VND(100000), SalePrice: &salePrice, SaleEndsAt: &future}
+		price, sale := effectivePrice(product, nil, "", 1, now)
+		if price.Amount != 80000 || sale == nil || sale.Source != models.PriceSourceSale {
+			t.Fatalf("expected sale price, got %v %+v", price, sale)
+		}
+
+		// Hết hạn thì tự quay về giá gốc
+		product.SaleEndsAt = &past
+		price, sale = effectivePrice(product, nil, "", 1, now)
+		if price.Amount != 100000 || sale != nil {
+			t.Fatalf("expected regular price after sale ended, got %v %+v", price, sale)
+		}
+
+		product.SaleStartsAt, product.SaleEndsAt = &future, nil
+		if price, _ := effectivePrice(product, nil, "", 1, now); price.Amount != 100000 {
+			t.Fatalf("expected regular price before sale starts, got %v", price)
+		}
+	})
+
+	t.Run("flash sale giới hạn số lượng", func(t *testing.T) {
+		product := &models.Product{ProductID: "P1", Price: models.VND(100000), SalePrice: &salePrice}
+		flashSales := []models.FlashSale{{
+			ProductID: "P1", Price: models.VND(60000), Quantity: 5, SoldCount: 2, PerCustomerLimit: 2,
+			CustomerUsage: map[string]int{"u1": 1}, StartsAt: past, EndsAt: future, IsActive: true,
+		}}
+
+		price, sale := effectivePrice(product, flashSales, "u2", 2, now)
+		if price.Amount != 60000 || sale.Source != models.PriceSourceFlashSale || sale.Remaining != 2 {
+			t.Fatalf("expected flash sale price, got %v %+v", price, sale)
+		}
+
+		// u1 chỉ còn 1 suất: cả dòng hàng 2 sản phẩm dùng sale_price
+		price, sale = effectivePrice(product, flashSales, "u1", 2, now)
+		if price.Amount != 80000 || sale.Source != models.PriceSourceSale {
+			t.Fatalf("expected sale price when flash sale limit exceeded, got %v %+v", price, sale)
+		}
+
+		flashSales[0].EndsAt = past
+		if price, _ := effectivePrice(product, flashSales, "u2", 1, now); price.Amount != 80000 {
+			t.Fatalf("expected sale price after flash sale ended, got %v", price)
+		}
+	})
+}
+
+func TestProductService_SalePrice(t *testing.T) {
+	st := newTestStore()
+	ps := NewProductService(st)
+	product := createTestProduct(t, st)
+	createTestFlashSale(t, st, createTestProduct(t, st, func(p *models.Product) { p.Name = "Flash product" }))
+
+	_, err := ps.UpdateProduct(product.ProductID, bson.M{"sale_price": 150000.0})
+	expectError(t, err, "invalid sale price")
+	_, err = ps.UpdateProduct(product.ProductID, bson.M{"sale_price": 90000.0, "sale_starts_at": "2030-01-02T00:00:00Z", "sale_ends_at": "2030-01-01T00:00:00Z"})
+	expectError(t, err, "sale_ends_at must be after sale_starts_at")
+	_, err = ps.UpdateProduct(product.ProductID, bson.M{"price": map[string]interface{}{"amount": 1250, "currency": "USD"}})
+	expectError(t, err, "invalid price: currency must be VND")
+
+	end := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	updated, err := ps.UpdateProduct(product.ProductID, bson.M{"sale_price": 90000.0, "sale_ends_at": end})
+	mustNoError(t, err)
+	if updated.SalePrice == nil || updated.SalePrice.Amount != 90000 || updated.EffectivePrice.Amount != 90000 {
+		t.Fatalf("unexpected updated product: %+v", updated)
+	}
+
+	result, err := ps.GetAllProducts(1, 10, "", "name", "")
+	mustNoError(t, err)
+	prices := map[string]int64{}
+	for _, p := range result.Data {
+		prices[p.Name] = p.EffectivePrice.Amount
+	}
+	if prices[product.Name] != 90000 || prices["Flash product"] != 60000 {
+		t.Fatalf("unexpected effective prices: %v", prices)
+	}
+
+	// Bỏ giá khuyến mãi
+	updated, err = ps.UpdateProduct(product.ProductID, bson.M{"sale_price": nil, "sale_ends_at": nil})
+	mustNoError(t, err)
+	if updated.SalePrice != nil || updated.SaleEndsAt != nil || updated.EffectivePrice.Amount != 100000 {
+		t.Fatalf("expected sale price to be removed, got %+v", updated)
+	}
+}
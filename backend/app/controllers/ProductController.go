@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -14,9 +20,9 @@ type ProductController struct {
 }
 
 // NewProductController creates a new product controller instance
-func NewProductController() *ProductController {
+func NewProductController(st store.Store) *ProductController {
 	return &ProductController{
-		productService: NewProductService(),
+		productService: NewProductService(st),
 	}
 }
 
@@ -125,7 +131,7 @@ func (pc *ProductController) CreateProduct(c *gin.Context) {
 	// Call service method
 	createdProduct, err := pc.productService.CreateProduct(product)
 	if err != nil {
-		if err.Error() == "product with this ID or slug already exists" {
+		if err.Error() == "product with this ID or slug already exists" || strings.HasPrefix(err.Error(), "invalid sale price") {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -167,6 +173,11 @@ func (pc *ProductController) UpdateProduct(c *gin.Context) {
 				"success": false,
 				"message": "Không tìm thấy sản phẩm",
 			})
+		} else if strings.HasPrefix(err.Error(), "invalid sale price") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
@@ -336,3 +347,77 @@ func (pc *ProductController) GetRelatedProducts(c *gin.Context) {
 		"category": category,
 	})
 }
+
+// ImportProducts nhập sản phẩm từ file CSV (field "file"), upsert theo id (Admin only).
+// dry_run=true chỉ kiểm tra từng dòng, không lưu.
+func (pc *ProductController) ImportProducts(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Vui lòng tải lên file sản phẩm (field \"file\")",
+		})
+		return
+	}
+	if fileHeader.Size > MaxProductImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"success": false,
+			"message": "File sản phẩm quá lớn",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Không thể đọc file sản phẩm",
+		})
+		return
+	}
+	defer file.Close()
+
+	result, err := pc.productService.ImportProductsCSV(file, dryRun)
+	if err != nil {
+		var importErr *ProductImportError
+		if errors.As(err, &importErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		log.Printf("Error importing products: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	message := "Nhập sản phẩm hoàn tất"
+	if dryRun {
+		message = "Kiểm tra file sản phẩm hoàn tất, chưa có thay đổi nào được lưu"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// ExportProducts xuất toàn bộ sản phẩm ra CSV cùng định dạng với ImportProducts (Admin only)
+func (pc *ProductController) ExportProducts(c *gin.Context) {
+	filename := fmt.Sprintf("products_%s.csv", time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	if _, err := pc.productService.ExportProductsCSV(c.Request.Context(), c.Writer); err != nil {
+		// Header đã được gửi, chỉ có thể log lỗi
+		log.Printf("Error exporting products to %s: %v", filename, err)
+	}
+}
@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MaxProductImportSize là dung lượng tối đa của file sản phẩm được tải lên
+const MaxProductImportSize = 5 << 20
+
+// MaxProductImportRows là số dòng sản phẩm tối đa trong một lần nhập
+const MaxProductImportRows = 5000
+
+// Kết quả xử lý từng dòng khi nhập sản phẩm
+const (
+	ProductImportCreated = "created"
+	ProductImportUpdated = "updated"
+	ProductImportFailed  = "failed"
+)
+
+// productCSVColumns là các cột của file sản phẩm. File xuất ra dùng đúng các cột này nên nhập lại
+// không mất dữ liệu; file nhập có thể chỉ gồm một phần các cột (bắt buộc có id).
+var productCSVColumns = []string{
+	"id", "name", "slug", "category", "price", "currency", "sale_price", "sale_starts_at", "sale_ends_at",
+	"amount", "stock", "weight", "is_featured", "image", "description",
+}
+
+// ProductImportError được trả về khi cả file sản phẩm không đọc được
+type ProductImportError struct {
+	Reason string
+}
+
+func (e *ProductImportError) Error() string {
+	return "file sản phẩm không hợp lệ: " + e.Reason
+}
+
+// ProductImportRow là kết quả của một dòng trong file (Row tính cả dòng tiêu đề, giống số dòng trong Excel)
+type ProductImportRow struct {
+	Row       int      `json:"row"`
+	ProductID string   `json:"id,omitempty"`
+	Action    string   `json:"action"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ProductImportResult tổng hợp kết quả nhập file sản phẩm
+type ProductImportResult struct {
+	DryRun  bool               `json:"dry_run"`
+	Total   int                `json:"total"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Failed  int                `json:"failed"`
+	Rows    []ProductImportRow `json:"rows"`
+}
+
+// ExportProductsCSV ghi toàn bộ sản phẩm (theo id) ra CSV với các cột productCSVColumns
+func (ps *ProductService) ExportProductsCSV(ctx context.Context, w io.Writer) (int, error) {
+	products, err := ps.store.Products().List(ctx, store.ProductFilter{}, store.ListOptions{Sort: "id"})
+	if err != nil {
+		return 0, fmt.Errorf("error finding products: %v", err)
+	}
+
+	writer, err := NewOrderRowWriter(ExportFormatCSV, w)
+	if err != nil {
+		return 0, err
+	}
+	header := make([]interface{}, len(productCSVColumns))
+	for i, column := range productCSVColumns {
+		header[i] = column
+	}
+	if err := writer.WriteRow(header); err != nil {
+		return 0, err
+	}
+	for i := range products {
+		if err := writer.WriteRow(productExportRow(&products[i])); err != nil {
+			return i, err
+		}
+	}
+	return len(products), writer.Close()
+}
+
+func productExportRow(p *models.Product) []interface{} {
+	var salePrice interface{} = ""
+	if p.SalePrice != nil {
+		salePrice = p.SalePrice.Major()
+	}
+	return []interface{}{
+		p.ProductID, p.Name, p.Slug, p.Category, p.Price.Major(), p.Price.CurrencyCode(), salePrice,
+		productCSVTime(p.SaleStartsAt), productCSVTime(p.SaleEndsAt),
+		p.Amount, p.Stock, p.Weight, p.IsFeatured, p.Image, p.Description,
+	}
+}
+
+func productCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// ImportProductsCSV nhập sản phẩm từ CSV, upsert theo id. Dòng lỗi được bỏ qua và báo lại,
+// các dòng hợp lệ vẫn được lưu. dryRun chỉ kiểm tra, không ghi gì vào DB.
+// Với sản phẩm đã có, cột không có trong file giữ nguyên giá trị cũ.
+func (ps *ProductService) ImportProductsCSV(r io.Reader, dryRun bool) (*ProductImportResult, error) {
+	// Mỗi dòng cần vài truy vấn nên cho nhiều thời gian hơn các thao tác thường
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxProductImportSize+1))
+	if err != nil {
+		return nil, &ProductImportError{Reason: "không đọc được file"}
+	}
+	if len(data) > MaxProductImportSize {
+		return nil, &ProductImportError{Reason: "file quá lớn"}
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &ProductImportError{Reason: err.Error()}
+	}
+	if len(records) < 2 {
+		return nil, &ProductImportError{Reason: "file không có dòng sản phẩm nào"}
+	}
+	if len(records)-1 > MaxProductImportRows {
+		return nil, &ProductImportError{Reason: fmt.Sprintf("tối đa %d dòng mỗi lần nhập", MaxProductImportRows)}
+	}
+
+	columns, err := productImportColumns(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	importer := &productImporter{
+		ps:         ps,
+		ctx:        ctx,
+		columns:    columns,
+		ids:        map[string]int{},
+		slugs:      map[string]int{},
+		categories: map[string]bool{},
+	}
+	result := &ProductImportResult{DryRun: dryRun, Rows: []ProductImportRow{}}
+	for i, record := range records[1:] {
+		row := ProductImportRow{Row: i + 2}
+		product, existing, errs, err := importer.parse(row.Row, record)
+		if err != nil {
+			return nil, err
+		}
+		if product != nil {
+			row.ProductID = product.ProductID
+		}
+		if len(errs) == 0 && !dryRun {
+			if err := ps.saveImportedProduct(ctx, product, existing); err == store.ErrStockChanged {
+				errs = append(errs, "tồn kho đã thay đổi trong lúc nhập (có đơn hàng mới?), hãy xuất lại file rồi nhập lại")
+			} else if err != nil {
+				errs = append(errs, fmt.Sprintf("lỗi lưu sản phẩm: %v", err))
+			}
+		}
+
+		result.Total++
+		switch {
+		case len(errs) > 0:
+			row.Action, row.Errors = ProductImportFailed, errs
+			result.Failed++
+		case existing != nil:
+			row.Action = ProductImportUpdated
+			result.Updated++
+		default:
+			row.Action = ProductImportCreated
+			result.Created++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// productImportColumns đọc dòng tiêu đề, trả về vị trí của từng cột
+func productImportColumns(header []string) (map[string]int, error) {
+	known := map[string]bool{}
+	for _, column := range productCSVColumns {
+		known[column] = true
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, &ProductImportError{Reason: fmt.Sprintf("cột %q không được hỗ trợ", name)}
+		}
+		if _, dup := columns[name]; dup {
+			return nil, &ProductImportError{Reason: fmt.Sprintf("cột %q bị lặp", name)}
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, &ProductImportError{Reason: "thiếu cột id"}
+	}
+	return columns, nil
+}
+
+// productImporter giữ trạng thái giữa các dòng để phát hiện id/slug trùng trong cùng file
+type productImporter struct {
+	ps         *ProductService
+	ctx        context.Context
+	columns    map[string]int
+	ids        map[string]int  // id → dòng đầu tiên dùng id đó
+	slugs      map[string]int  // slug → dòng đầu tiên dùng slug đó
+	categories map[string]bool // kết quả categoryExists đã kiểm tra
+}
+
+// parse dựng sản phẩm từ một dòng. existing khác nil nghĩa là cập nhật sản phẩm đã có.
+// errs là lỗi dữ liệu của dòng, err là lỗi truy vấn DB (dừng cả lần nhập).
+func (pi *productImporter) parse(rowNumber int, record []string) (product, existing *models.Product, errs []string, err error) {
+	if len(record) != len(pi.columns) {
+		return nil, nil, []string{fmt.Sprintf("cần %d cột, dòng có %d cột", len(pi.columns), len(record))}, nil
+	}
+	value := func(column string) (string, bool) {
+		i, ok := pi.columns[column]
+		if !ok {
+			return "", false
+		}
+		text := csvUnescapeText(record[i])
+		if column != "name" && column != "description" {
+			text = strings.TrimSpace(text)
+		}
+		return text, true
+	}
+
+	id, _ := value("id")
+	if id == "" {
+		return nil, nil, []string{"id là bắt buộc"}, nil
+	}
+	product = &models.Product{ProductID: id}
+	if first, dup := pi.ids[id]; dup {
+		return product, nil, []string{fmt.Sprintf("id bị lặp (trùng dòng %d)", first)}, nil
+	}
+	pi.ids[id] = rowNumber
+
+	found, err := pi.ps.store.Products().FindByProductID(pi.ctx, id)
+	switch {
+	case err == nil:
+		copied := *found
+		existing, product = found, &copied
+	case err != store.ErrNotFound:
+		return nil, nil, nil, fmt.Errorf("error finding product %s: %v", id, err)
+	}
+
+	if name, ok := value("name"); ok {
+		product.Name = name
+	}
+	if product.Name == "" {
+		errs = append(errs, "tên sản phẩm là bắt buộc")
+	}
+
+	currency := product.Price.CurrencyCode()
+	if code, ok := value("currency"); ok && code != "" {
+		currency = strings.ToUpper(code)
+	}
+	priceOK := true
+	if text, ok := value("price"); ok && text != "" {
+		amount, parseErr := strconv.ParseFloat(text, 64)
+		price, moneyErr := models.MoneyFromMajorChecked(amount, currency)
+		if parseErr != nil || moneyErr != nil || amount < 0 {
+			errs, priceOK = append(errs, "giá phải là số không âm"), false
+		} else {
+			product.Price = price
+		}
+	} else if existing == nil || ok {
+		errs, priceOK = append(errs, "giá là bắt buộc"), false
+	}
+	if text, ok := value("sale_price"); ok {
+		product.SalePrice = nil
+		if text != "" {
+			amount, parseErr := strconv.ParseFloat(text, 64)
+			salePrice, moneyErr := models.MoneyFromMajorChecked(amount, currency)
+			if parseErr != nil || moneyErr != nil {
+				errs, priceOK = append(errs, "giá khuyến mãi không hợp lệ: phải là số"), false
+			} else {
+				product.SalePrice = &salePrice
+			}
+		}
+	}
+	saleTimes := []struct {
+		column string
+		target **time.Time
+	}{{"sale_starts_at", &product.SaleStartsAt}, {"sale_ends_at", &product.SaleEndsAt}}
+	for _, field := range saleTimes {
+		text, ok := value(field.column)
+		if !ok {
+			continue
+		}
+		*field.target = nil
+		if text != "" {
+			parsed, parseErr := time.Parse(time.RFC3339, text)
+			if parseErr != nil {
+				errs, priceOK = append(errs, fmt.Sprintf("giá khuyến mãi không hợp lệ: %s phải là thời gian RFC3339", field.column)), false
+				continue
+			}
+			*field.target = &parsed
+		}
+	}
+	if priceOK {
+		if saleErr := validateSalePrice(product.Price, product.SalePrice, product.SaleStartsAt, product.SaleEndsAt); saleErr != nil {
+			errs = append(errs, importSalePriceError(saleErr))
+		}
+	}
+
+	quantities := []struct {
+		column string
+		target *int
+	}{{"amount", &product.Amount}, {"stock", &product.Stock}, {"weight", &product.Weight}}
+	for _, field := range quantities {
+		text, ok := value(field.column)
+		if !ok {
+			continue
+		}
+		number := 0
+		if text != "" {
+			parsed, parseErr := strconv.Atoi(text)
+			if parseErr != nil || parsed < 0 {
+				errs = append(errs, fmt.Sprintf("%s phải là số nguyên không âm", field.column))
+				continue
+			}
+			number = parsed
+		}
+		*field.target = number
+	}
+
+	if text, ok := value("is_featured"); ok {
+		featured, parseErr := false, error(nil)
+		if text != "" {
+			if featured, parseErr = strconv.ParseBool(text); parseErr != nil {
+				errs = append(errs, "is_featured phải là true hoặc false")
+			}
+		}
+		product.IsFeatured = featured
+	}
+
+	if category, ok := value("category"); ok && category != "" {
+		exists, checked := pi.categories[category]
+		if !checked {
+			if exists, err = pi.ps.categoryExists(category); err != nil {
+				return nil, nil, nil, err
+			}
+			pi.categories[category] = exists
+		}
+		if !exists {
+			errs = append(errs, "danh mục không tồn tại")
+		}
+		product.Category = category
+	}
+	if product.Category == "" {
+		product.Category = "food" // default category, như CreateProduct
+	}
+
+	if image, ok := value("image"); ok {
+		product.Image = image
+	}
+	if description, ok := value("description"); ok {
+		product.Description = description
+	}
+
+	if slug, ok := value("slug"); ok && slug != "" {
+		product.Slug = slug
+	}
+	if product.Slug == "" && product.Name != "" {
+		product.Slug = pi.ps.generateSlug(product.Name)
+	}
+	if product.Slug != "" {
+		if first, dup := pi.slugs[product.Slug]; dup {
+			errs = append(errs, fmt.Sprintf("slug bị lặp (trùng dòng %d)", first))
+		} else {
+			pi.slugs[product.Slug] = rowNumber
+			other, findErr := pi.ps.store.Products().FindBySlug(pi.ctx, product.Slug)
+			switch {
+			case findErr == nil && other.ProductID != id:
+				errs = append(errs, fmt.Sprintf("slug đã được dùng cho sản phẩm %s", other.ProductID))
+			case findErr != nil && findErr != store.ErrNotFound:
+				return nil, nil, nil, fmt.Errorf("error checking slug %s: %v", product.Slug, findErr)
+			}
+		}
+	}
+
+	return product, existing, errs, nil
+}
+
+// importSalePriceError dịch lỗi của validateSalePrice (dùng chung với API sản phẩm) sang lỗi của dòng nhập
+func importSalePriceError(err error) string {
+	switch {
+	case errors.Is(err, ErrPriceCurrency):
+		return fmt.Sprintf("giá phải tính bằng %s", models.DefaultCurrency)
+	case errors.Is(err, ErrSaleWindow):
+		return "giá khuyến mãi không hợp lệ: sale_ends_at phải sau sale_starts_at"
+	case errors.Is(err, ErrSaleCurrency):
+		return "giá khuyến mãi không hợp lệ: phải cùng loại tiền với giá gốc"
+	case errors.Is(err, ErrSalePriceRange):
+		return "giá khuyến mãi không hợp lệ: phải lớn hơn 0 và thấp hơn giá gốc"
+	}
+	return err.Error()
+}
+
+// saveImportedProduct thêm mới hoặc ghi đè các field của sản phẩm đã có.
+// amount và stock là giá trị tuyệt đối đọc từ file, nên chỉ ghi khi tồn kho vẫn bằng giá trị đã đọc lúc
+// parse; nếu trong lúc nhập có đơn hàng trừ kho thì trả về store.ErrStockChanged thay vì ghi đè.
+func (ps *ProductService) saveImportedProduct(ctx context.Context, product, existing *models.Product) error {
+	now := time.Now()
+	if existing == nil {
+		product.CreatedAt = now
+		product.UpdatedAt = now
+		return ps.store.Products().Insert(ctx, product)
+	}
+
+	fields := bson.M{
+		"name":           product.Name,
+		"slug":           product.Slug,
+		"category":       product.Category,
+		"price":          product.Price,
+		"sale_price":     nil,
+		"sale_starts_at": nil,
+		"sale_ends_at":   nil,
+		"amount":         product.Amount,
+		"stock":          product.Stock,
+		"weight":         product.Weight,
+		"is_featured":    product.IsFeatured,
+		"image":          product.Image,
+		"description":    product.Description,
+		"updated_at":     now,
+	}
+	if product.SalePrice != nil {
+		fields["sale_price"] = *product.SalePrice
+	}
+	if product.SaleStartsAt != nil {
+		fields["sale_starts_at"] = *product.SaleStartsAt
+	}
+	if product.SaleEndsAt != nil {
+		fields["sale_ends_at"] = *product.SaleEndsAt
+	}
+	return ps.store.Products().UpdateIfStock(ctx, existing.ID, existing.Amount, existing.Stock, fields)
+}
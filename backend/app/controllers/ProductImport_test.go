@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+func TestProductService_ImportExportRoundTrip(t *testing.T) {
+	st := newTestStore()
+	ps := NewProductService(st)
+	startsAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	endsAt := startsAt.AddDate(0, 0, 7)
+
+	createTestProduct(t, st, func(p *models.Product) {
+		p.Name = "Bánh tráng, trộn \"đặc biệt\""
+		p.Description = "=SUM(A1)\nDòng 2"
+		sale := models.VND(80000)
+		p.SalePrice, p.SaleStartsAt, p.SaleEndsAt = &sale, &startsAt, &endsAt
+		p.Stock, p.Weight, p.IsFeatured = 3, 250, true
+	})
+	createTestProduct(t, st, func(p *models.Product) {
+		p.Price = models.VND(1250)
+		p.Description = "'quoted"
+	})
+
+	var exported bytes.Buffer
+	count, err := ps.ExportProductsCSV(context.Background(), &exported)
+	mustNoError(t, err)
+	if count != 2 {
+		t.Fatalf("expected 2 products exported, got %d", count)
+	}
+
+	// Nhập vào store khác có cùng danh mục rồi xuất lại phải ra đúng file ban đầu
+	target := newTestStore()
+	categories, _ := st.Categories().List(context.Background())
+	for i := range categories {
+		mustNoError(t, target.Categories().Insert(context.Background(), &categories[i]))
+	}
+	result, err := NewProductService(target).ImportProductsCSV(bytes.NewReader(exported.Bytes()), false)
+	mustNoError(t, err)
+	if result.Created != 2 || result.Failed != 0 {
+		t.Fatalf("unexpected import result %+v", result)
+	}
+
+	var again bytes.Buffer
+	_, err = NewProductService(target).ExportProductsCSV(context.Background(), &again)
+	mustNoError(t, err)
+	if again.String() != exported.String() {
+		t.Fatalf("round trip changed data:\n%s\n---\n%s", exported.String(), again.String())
+	}
+
+	// Nhập lại vào store gốc: cập nhật theo id, không tạo thêm sản phẩm
+	result, err = ps.ImportProductsCSV(bytes.NewReader(exported.Bytes()), false)
+	mustNoError(t, err)
+	if result.Updated != 2 || result.Created != 0 {
+		t.Fatalf("expected products to be updated, got %+v", result)
+	}
+}
+
+func TestProductService_ImportValidationAndDryRun(t *testing.T) {
+	st := newTestStore()
+	ps := NewProductService(st)
+	category := createTestCategory(t, st)
+	existing := createTestProduct(t, st, func(p *models.Product) { p.Category = category.CategoryID })
+	other := createTestProduct(t, st, func(p *models.Product) { p.Category = category.CategoryID })
+
+	file := strings.Join([]string{
+		"id,name,slug,category,price,stock,amount",
+		"NEW-1,Sản phẩm mới,san-pham-moi," + category.CategoryID + ",50000,5,5",
+		existing.ProductID + ",,,,,,25",
+		"NEW-2,Sai danh mục,sai-danh-muc,khong-co,50000,-1,0",
+		"NEW-1,Trùng id,trung-id," + category.CategoryID + ",1000,0,0",
+		"NEW-3,Trùng slug,san-pham-moi," + category.CategoryID + ",1000,0,0",
+		"NEW-4,Slug đã dùng," + other.Slug + "," + category.CategoryID + ",1000,0,0",
+		"NEW-5,Thiếu giá,thieu-gia," + category.CategoryID + ",,0,0",
+	}, "\n")
+
+	result, err := ps.ImportProductsCSV(strings.NewReader(file), true)
+	mustNoError(t, err)
+	if !result.DryRun || result.Created != 1 || result.Updated != 0 || result.Failed != 6 {
+		t.Fatalf("unexpected dry run result %+v", result)
+	}
+	wantErrors := map[int]string{
+		3: "giá là bắt buộc",
+		4: "danh mục không tồn tại",
+		5: "id bị lặp (trùng dòng 2)",
+		6: "slug bị lặp (trùng dòng 2)",
+		7: "slug đã được dùng cho sản phẩm " + other.ProductID,
+		8: "giá là bắt buộc",
+	}
+	for _, row := range result.Rows {
+		want, failed := wantErrors[row.Row]
+		if failed != (row.Action == ProductImportFailed) || (failed && !strings.Contains(strings.Join(row.Errors, "; "), want)) {
+			t.Fatalf("row %d: expected error %q, got %+v", row.Row, want, row)
+		}
+	}
+	if !strings.Contains(strings.Join(result.Rows[2].Errors, "; "), "stock phải là số nguyên không âm") {
+		t.Fatalf("expected negative stock to be rejected, got %v", result.Rows[2].Errors)
+	}
+	if _, err := st.Products().FindByProductID(context.Background(), "NEW-1"); err == nil {
+		t.Fatalf("dry run must not create products")
+	}
+
+	// Cột không có trong file giữ nguyên giá trị cũ khi cập nhật
+	result, err = ps.ImportProductsCSV(strings.NewReader("id,amount\n"+existing.ProductID+",25\n"), false)
+	mustNoError(t, err)
+	updated, _ := st.Products().FindByProductID(context.Background(), existing.ProductID)
+	if result.Updated != 1 || updated.Amount != 25 || updated.Name != existing.Name || updated.Price.Cmp(existing.Price) != 0 {
+		t.Fatalf("unexpected partial update %+v", updated)
+	}
+
+	// Không ghi đè tồn kho đã bị đơn hàng trừ sau khi đọc
+	stale := *updated
+	stale.Amount = 30
+	mustNoError(t, st.Products().DecrementStock(context.Background(), existing.ProductID, 1))
+	if err := ps.saveImportedProduct(context.Background(), &stale, updated); err != store.ErrStockChanged {
+		t.Fatalf("expected ErrStockChanged, got %v", err)
+	}
+	if current, _ := st.Products().FindByProductID(context.Background(), existing.ProductID); current.Amount != 24 {
+		t.Fatalf("expected stock to be kept, got %d", current.Amount)
+	}
+
+	// Lỗi giá khuyến mãi dùng chung với API sản phẩm được dịch theo loại lỗi
+	result, err = ps.ImportProductsCSV(strings.NewReader("id,name,price,sale_price\nSALE-1,Khuyến mãi,1000,2000\n"), true)
+	mustNoError(t, err)
+	if got := strings.Join(result.Rows[0].Errors, "; "); got != "giá khuyến mãi không hợp lệ: phải lớn hơn 0 và thấp hơn giá gốc" {
+		t.Fatalf("unexpected sale price error %q", got)
+	}
+
+	_, err = ps.ImportProductsCSV(strings.NewReader("id,gia\nA,1\n"), false)
+	expectError(t, err, "cột \"gia\" không được hỗ trợ")
+}
+
+func TestProductService_ImportCategoryLookupError(t *testing.T) {
+	st := newTestStore()
+	category := createTestCategory(t, st)
+	ps := NewProductService(&faultyStore{Store: st, failCategory: true})
+
+	// Lỗi DB khi kiểm tra danh mục dừng cả lần nhập, không bị báo thành "danh mục không tồn tại"
+	_, err := ps.ImportProductsCSV(strings.NewReader("id,name,category,price\nNEW-1,Sản phẩm,"+category.CategoryID+",1000\n"), true)
+	if err == nil || !strings.Contains(err.Error(), errInjected.Error()) {
+		t.Fatalf("expected category lookup error, got %v", err)
+	}
+	if _, ok := err.(*ProductImportError); ok {
+		t.Fatalf("store error must not be reported as an invalid file")
+	}
+}
@@ -2,21 +2,31 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type ProductService struct{}
+// Lỗi của validateSalePrice, dùng chung cho API sản phẩm và nhập file
+var (
+	ErrPriceCurrency  = fmt.Errorf("invalid price: currency must be %s", models.DefaultCurrency)
+	ErrSaleWindow     = errors.New("invalid sale price: sale_ends_at must be after sale_starts_at")
+	ErrSaleCurrency   = fmt.Errorf("invalid sale price: currency must be %s", models.DefaultCurrency)
+	ErrSalePriceRange = errors.New("invalid sale price: must be greater than 0 and lower than price")
+)
+
+type ProductService struct {
+	store   store.Store
+	pricing *PricingService
+}
 
 // PaginatedProducts represents paginated product response
 type PaginatedProducts struct {
@@ -26,24 +36,31 @@ type PaginatedProducts struct {
 
 // ProductWithCategory represents product with populated category info
 type ProductWithCategory struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	ProductID   string             `bson:"id" json:"id"`
-	Name        string             `bson:"name" json:"name"`
-	Price       float64            `bson:"price" json:"price"`
-	Image       string             `bson:"image" json:"image"`
-	Slug        string             `bson:"slug" json:"slug"`
-	Amount      int                `bson:"amount" json:"amount"`
-	Category    interface{}        `bson:"category" json:"category"` // Can be string or Category object
-	IsFeatured  bool               `bson:"is_featured" json:"is_featured"`
-	Description string             `bson:"description,omitempty" json:"description,omitempty"`
-	Stock       int                `bson:"stock" json:"stock"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	ProductID    string             `bson:"id" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Price        models.Money       `bson:"price" json:"price"`
+	SalePrice    *models.Money      `bson:"sale_price,omitempty" json:"sale_price,omitempty"`
+	SaleStartsAt *time.Time         `bson:"sale_starts_at,omitempty" json:"sale_starts_at,omitempty"`
+	SaleEndsAt   *time.Time         `bson:"sale_ends_at,omitempty" json:"sale_ends_at,omitempty"`
+	Image        string             `bson:"image" json:"image"`
+	Slug         string             `bson:"slug" json:"slug"`
+	Amount       int                `bson:"amount" json:"amount"`
+	Weight       int                `bson:"weight,omitempty" json:"weight,omitempty"`
+	Category     interface{}        `bson:"category" json:"category"` // Can be string or Category object
+	IsFeatured   bool               `bson:"is_featured" json:"is_featured"`
+	Description  string             `bson:"description,omitempty" json:"description,omitempty"`
+	Stock        int                `bson:"stock" json:"stock"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+
+	EffectivePrice models.Money       `bson:"-" json:"effective_price"`
+	ActiveSale     *models.ActiveSale `bson:"-" json:"active_sale,omitempty"`
 }
 
 // NewProductService creates a new product service instance
-func NewProductService() *ProductService {
-	return &ProductService{}
+func NewProductService(st store.Store) *ProductService {
+	return &ProductService{store: st, pricing: NewPricingService(st)}
 }
 
 // GetAllProducts retrieves all products with pagination and filters
@@ -51,9 +68,6 @@ func (ps *ProductService) GetAllProducts(page, limit int, search, sortBy, catego
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
 	// Default values
 	if page < 1 {
 		page = 1
@@ -62,52 +76,35 @@ func (ps *ProductService) GetAllProducts(page, limit int, search, sortBy, catego
 		limit = 12
 	}
 
-	// Build query filter
-	filter := bson.M{}
-
-	// Search by name
-	if search != "" {
-		filter["name"] = bson.M{"$regex": search, "$options": "i"}
-	}
-
-	// Filter by category
-	if category != "" {
-		filter["category"] = category
+	// Build query filter (search by name, filter by category)
+	filter := store.ProductFilter{
+		Search:   search,
+		Category: category,
 	}
 
 	// Build sort options
-	sortOptions := options.Find()
+	sort := "name"
 	switch sortBy {
-	case "price":
-		sortOptions.SetSort(bson.D{{Key: "price", Value: 1}})
-	case "-price":
-		sortOptions.SetSort(bson.D{{Key: "price", Value: -1}})
-	case "name":
-		sortOptions.SetSort(bson.D{{Key: "name", Value: 1}})
-	case "-name":
-		sortOptions.SetSort(bson.D{{Key: "name", Value: -1}})
-	default:
-		sortOptions.SetSort(bson.D{{Key: "name", Value: 1}})
+	case "price", "-price", "name", "-name":
+		sort = sortBy
 	}
 
 	// Apply pagination
 	skip := (page - 1) * limit
-	sortOptions.SetSkip(int64(skip)).SetLimit(int64(limit))
 
 	// Execute query
-	cursor, err := collection.Find(ctx, filter, sortOptions)
+	products, err := ps.store.Products().List(ctx, filter, store.ListOptions{
+		Skip:  int64(skip),
+		Limit: int64(limit),
+		Sort:  sort,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error finding products: %v", err)
 	}
-	defer cursor.Close(ctx)
-
-	var products []models.Product
-	if err = cursor.All(ctx, &products); err != nil {
-		return nil, fmt.Errorf("error decoding products: %v", err)
-	}
+	ps.pricing.ApplyToProducts(ctx, products, time.Now())
 
 	// Count total documents
-	total, err := collection.CountDocuments(ctx, filter)
+	total, err := ps.store.Products().Count(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("error counting products: %v", err)
 	}
@@ -130,26 +127,23 @@ func (ps *ProductService) GetProductByID(id string) (*ProductWithCategory, error
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
-	var product models.Product
+	var product *models.Product
 	var err error
 
 	// Try to find by ObjectID first, then by ProductID, then by slug
 	if objectID, parseErr := primitive.ObjectIDFromHex(id); parseErr == nil {
-		err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&product)
+		product, err = ps.store.Products().FindByObjectID(ctx, objectID)
 	} else {
 		// Try finding by ProductID first
-		err = collection.FindOne(ctx, bson.M{"id": id}).Decode(&product)
+		product, err = ps.store.Products().FindByProductID(ctx, id)
 		if err != nil {
 			// If not found by ProductID, try finding by slug
-			err = collection.FindOne(ctx, bson.M{"slug": id}).Decode(&product)
+			product, err = ps.store.Products().FindBySlug(ctx, id)
 		}
 	}
 
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, fmt.Errorf("product not found")
 		}
 		return nil, fmt.Errorf("error finding product: %v", err)
@@ -157,27 +151,30 @@ func (ps *ProductService) GetProductByID(id string) (*ProductWithCategory, error
 
 	// Populate category information
 	productWithCategory := &ProductWithCategory{
-		ID:          product.ID,
-		ProductID:   product.ProductID,
-		Name:        product.Name,
-		Price:       product.Price,
-		Image:       product.Image,
-		Slug:        product.Slug,
-		Amount:      product.Amount,
-		IsFeatured:  product.IsFeatured,
-		Description: product.Description,
-		Stock:       product.Stock,
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
-	}
+		ID:           product.ID,
+		ProductID:    product.ProductID,
+		Name:         product.Name,
+		Price:        product.Price,
+		SalePrice:    product.SalePrice,
+		SaleStartsAt: product.SaleStartsAt,
+		SaleEndsAt:   product.SaleEndsAt,
+		Image:        product.Image,
+		Slug:         product.Slug,
+		Amount:       product.Amount,
+		Weight:       product.Weight,
+		IsFeatured:   product.IsFeatured,
+		Description:  product.Description,
+		Stock:        product.Stock,
+		CreatedAt:    product.CreatedAt,
+		UpdatedAt:    product.UpdatedAt,
+	}
+	productWithCategory.EffectivePrice, productWithCategory.ActiveSale = ps.pricing.Resolve(ctx, product, "", 1, time.Now())
 
 	// If category is set, try to populate it
 	if product.Category != "" {
-		categoryCollection := db.Collection("categories")
-		var category models.Category
-		err = categoryCollection.FindOne(ctx, bson.M{"id": product.Category}).Decode(&category)
+		category, err := ps.store.Categories().FindByCategoryID(ctx, product.Category)
 		if err == nil {
-			productWithCategory.Category = category
+			productWithCategory.Category = *category
 		} else {
 			// If category not found, keep the string value
 			productWithCategory.Category = product.Category
@@ -194,9 +191,6 @@ func (ps *ProductService) CreateProduct(productData models.Product) (*models.Pro
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
 	// Validate required fields
 	if productData.ProductID == "" {
 		return nil, fmt.Errorf("product ID is required")
@@ -210,9 +204,17 @@ func (ps *ProductService) CreateProduct(productData models.Product) (*models.Pro
 		productData.Slug = ps.generateSlug(productData.Name)
 	}
 
+	if err := validateSalePrice(productData.Price, productData.SalePrice, productData.SaleStartsAt, productData.SaleEndsAt); err != nil {
+		return nil, err
+	}
+
 	// Validate category exists if provided
 	if productData.Category != "" {
-		if !ps.categoryExists(productData.Category) {
+		exists, err := ps.categoryExists(productData.Category)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
 			return nil, fmt.Errorf("category does not exist")
 		}
 	} else {
@@ -220,19 +222,12 @@ func (ps *ProductService) CreateProduct(productData models.Product) (*models.Pro
 	}
 
 	// Check if product with same ID or slug exists
-	existingFilter := bson.M{
-		"$or": []bson.M{
-			{"id": productData.ProductID},
-			{"slug": productData.Slug},
-		},
+	exists, err := ps.store.Products().ExistsByProductIDOrSlug(ctx, productData.ProductID, productData.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing product: %v", err)
 	}
-
-	var existing models.Product
-	err := collection.FindOne(ctx, existingFilter).Decode(&existing)
-	if err == nil {
+	if exists {
 		return nil, fmt.Errorf("product with this ID or slug already exists")
-	} else if err != mongo.ErrNoDocuments {
-		return nil, fmt.Errorf("error checking existing product: %v", err)
 	}
 
 	// Set timestamps
@@ -241,12 +236,11 @@ func (ps *ProductService) CreateProduct(productData models.Product) (*models.Pro
 	productData.UpdatedAt = now
 
 	// Insert product
-	result, err := collection.InsertOne(ctx, productData)
-	if err != nil {
+	if err := ps.store.Products().Insert(ctx, &productData); err != nil {
 		return nil, fmt.Errorf("error creating product: %v", err)
 	}
+	productData.EffectivePrice, productData.ActiveSale = ps.pricing.Resolve(ctx, &productData, "", 1, now)
 
-	productData.ID = result.InsertedID.(primitive.ObjectID)
 	return &productData, nil
 }
 
@@ -255,26 +249,45 @@ func (ps *ProductService) UpdateProduct(id string, updateData bson.M) (*models.P
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
-	// Build filter
-	var filter bson.M
-	if objectID, parseErr := primitive.ObjectIDFromHex(id); parseErr == nil {
-		filter = bson.M{"_id": objectID}
-	} else {
-		filter = bson.M{"id": id}
+	// Find existing product
+	existing, err := ps.findProduct(ctx, id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf("product not found")
+		}
+		return nil, fmt.Errorf("error updating product: %v", err)
 	}
 
 	// Validate category if being updated
 	if category, exists := updateData["category"]; exists {
 		if categoryStr, ok := category.(string); ok && categoryStr != "" {
-			if !ps.categoryExists(categoryStr) {
+			exists, err := ps.categoryExists(categoryStr)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
 				return nil, fmt.Errorf("category does not exist")
 			}
 		}
 	}
 
+	// Body JSON cho price dạng float64/string, chuẩn hoá về Money trước khi lưu
+	if price, exists := updateData["price"]; exists {
+		money, err := parseMoney(price)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price: %v", err)
+		}
+		updateData["price"] = money
+	}
+
+	// sale_price và thời gian áp dụng, gửi null để bỏ giá khuyến mãi.
+	// effective_price/active_sale chỉ được tính khi đọc, không lưu lại.
+	if err := normalizeSaleFields(existing, updateData); err != nil {
+		return nil, err
+	}
+	delete(updateData, "effective_price")
+	delete(updateData, "active_sale")
+
 	// Generate new slug if name is being updated
 	if name, exists := updateData["name"]; exists {
 		if nameStr, ok := name.(string); ok && nameStr != "" {
@@ -285,25 +298,17 @@ func (ps *ProductService) UpdateProduct(id string, updateData bson.M) (*models.P
 	// Set updated timestamp
 	updateData["updated_at"] = time.Now()
 
-	// Update product
-	update := bson.M{"$set": updateData}
-	result, err := collection.UpdateOne(ctx, filter, update)
+	// Update product and return updated document
+	updatedProduct, err := ps.store.Products().Update(ctx, existing.ID, updateData)
 	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf("product not found")
+		}
 		return nil, fmt.Errorf("error updating product: %v", err)
 	}
+	updatedProduct.EffectivePrice, updatedProduct.ActiveSale = ps.pricing.Resolve(ctx, updatedProduct, "", 1, time.Now())
 
-	if result.MatchedCount == 0 {
-		return nil, fmt.Errorf("product not found")
-	}
-
-	// Fetch and return updated product
-	var updatedProduct models.Product
-	err = collection.FindOne(ctx, filter).Decode(&updatedProduct)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching updated product: %v", err)
-	}
-
-	return &updatedProduct, nil
+	return updatedProduct, nil
 }
 
 // DeleteProduct deletes a product
@@ -311,25 +316,21 @@ func (ps *ProductService) DeleteProduct(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
-	// Build filter
-	var filter bson.M
-	if objectID, parseErr := primitive.ObjectIDFromHex(id); parseErr == nil {
-		filter = bson.M{"_id": objectID}
-	} else {
-		filter = bson.M{"id": id}
-	}
-
-	// Delete product
-	result, err := collection.DeleteOne(ctx, filter)
+	// Find product
+	product, err := ps.findProduct(ctx, id)
 	if err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Errorf("product not found")
+		}
 		return fmt.Errorf("error deleting product: %v", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return fmt.Errorf("product not found")
+	// Delete product
+	if err := ps.store.Products().Delete(ctx, product.ID); err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Errorf("product not found")
+		}
+		return fmt.Errorf("error deleting product: %v", err)
 	}
 
 	return nil
@@ -340,9 +341,6 @@ func (ps *ProductService) GetProductsByCategory(categoryID string, page, limit i
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
 	// Default values
 	if page < 1 {
 		page = 1
@@ -351,30 +349,24 @@ func (ps *ProductService) GetProductsByCategory(categoryID string, page, limit i
 		limit = 12
 	}
 
-	filter := bson.M{"category": categoryID}
-
-	// Build sort options
-	sortOptions := options.Find()
-	sortOptions.SetSort(bson.D{{Key: "name", Value: 1}})
+	filter := store.ProductFilter{Category: categoryID}
 
 	// Apply pagination
 	skip := (page - 1) * limit
-	sortOptions.SetSkip(int64(skip)).SetLimit(int64(limit))
 
 	// Execute query
-	cursor, err := collection.Find(ctx, filter, sortOptions)
+	products, err := ps.store.Products().List(ctx, filter, store.ListOptions{
+		Skip:  int64(skip),
+		Limit: int64(limit),
+		Sort:  "name",
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error finding products by category: %v", err)
 	}
-	defer cursor.Close(ctx)
-
-	var products []models.Product
-	if err = cursor.All(ctx, &products); err != nil {
-		return nil, fmt.Errorf("error decoding products: %v", err)
-	}
+	ps.pricing.ApplyToProducts(ctx, products, time.Now())
 
 	// Count total documents
-	total, err := collection.CountDocuments(ctx, filter)
+	total, err := ps.store.Products().Count(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("error counting products: %v", err)
 	}
@@ -397,9 +389,6 @@ func (ps *ProductService) SearchProducts(keyword string, page, limit int) (*Pagi
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
 	// Default values
 	if page < 1 {
 		page = 1
@@ -421,32 +410,24 @@ func (ps *ProductService) SearchProducts(keyword string, page, limit int) (*Pagi
 		}, nil
 	}
 
-	filter := bson.M{
-		"name": bson.M{"$regex": keyword, "$options": "i"},
-	}
-
-	// Build sort options
-	sortOptions := options.Find()
-	sortOptions.SetSort(bson.D{{Key: "name", Value: 1}})
+	filter := store.ProductFilter{Search: keyword}
 
 	// Apply pagination
 	skip := (page - 1) * limit
-	sortOptions.SetSkip(int64(skip)).SetLimit(int64(limit))
 
 	// Execute query
-	cursor, err := collection.Find(ctx, filter, sortOptions)
+	products, err := ps.store.Products().List(ctx, filter, store.ListOptions{
+		Skip:  int64(skip),
+		Limit: int64(limit),
+		Sort:  "name",
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error searching products: %v", err)
 	}
-	defer cursor.Close(ctx)
-
-	var products []models.Product
-	if err = cursor.All(ctx, &products); err != nil {
-		return nil, fmt.Errorf("error decoding products: %v", err)
-	}
+	ps.pricing.ApplyToProducts(ctx, products, time.Now())
 
 	// Count total documents
-	total, err := collection.CountDocuments(ctx, filter)
+	total, err := ps.store.Products().Count(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("error counting search results: %v", err)
 	}
@@ -469,30 +450,21 @@ func (ps *ProductService) GetFeaturedProducts(limit int) ([]models.Product, erro
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
 	if limit < 1 {
 		limit = 8
 	}
 
-	filter := bson.M{"is_featured": true}
+	featured := true
+	filter := store.ProductFilter{IsFeatured: &featured}
 
-	// Build options
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSort(bson.D{{Key: "name", Value: 1}})
-
-	cursor, err := collection.Find(ctx, filter, findOptions)
+	products, err := ps.store.Products().List(ctx, filter, store.ListOptions{
+		Limit: int64(limit),
+		Sort:  "name",
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error finding featured products: %v", err)
 	}
-	defer cursor.Close(ctx)
-
-	var products []models.Product
-	if err = cursor.All(ctx, &products); err != nil {
-		return nil, fmt.Errorf("error decoding featured products: %v", err)
-	}
+	ps.pricing.ApplyToProducts(ctx, products, time.Now())
 
 	return products, nil
 }
@@ -502,26 +474,23 @@ func (ps *ProductService) GetRelatedProducts(identifier string, limit int) ([]mo
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
 	if limit < 1 {
 		limit = 4
 	}
 
 	// First, get the current product - try by slug first, then by ID, then by ObjectID
-	var currentProduct models.Product
+	var currentProduct *models.Product
 	var err error
 
 	// Try to find by slug first
-	err = collection.FindOne(ctx, bson.M{"slug": identifier}).Decode(&currentProduct)
+	currentProduct, err = ps.store.Products().FindBySlug(ctx, identifier)
 	if err != nil {
 		// If not found by slug, try by ProductID
-		err = collection.FindOne(ctx, bson.M{"id": identifier}).Decode(&currentProduct)
+		currentProduct, err = ps.store.Products().FindByProductID(ctx, identifier)
 		if err != nil {
 			// If not found by ProductID, try by ObjectID
 			if objectID, parseErr := primitive.ObjectIDFromHex(identifier); parseErr == nil {
-				err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&currentProduct)
+				currentProduct, err = ps.store.Products().FindByObjectID(ctx, objectID)
 			}
 		}
 	}
@@ -530,44 +499,22 @@ func (ps *ProductService) GetRelatedProducts(identifier string, limit int) ([]mo
 		return nil, "", fmt.Errorf("product not found: %v", err)
 	}
 
-	// Build query for related products
-	var filter bson.M
-	if currentProduct.Category != "" {
-		// Get products from same category, excluding current product
-		filter = bson.M{
-			"category": currentProduct.Category,
-			"$and": []bson.M{
-				{"slug": bson.M{"$ne": currentProduct.Slug}},
-				{"id": bson.M{"$ne": currentProduct.ProductID}},
-				{"_id": bson.M{"$ne": currentProduct.ID}},
-			},
-		}
-	} else {
-		// Fallback: get random products excluding current product
-		filter = bson.M{
-			"$and": []bson.M{
-				{"slug": bson.M{"$ne": currentProduct.Slug}},
-				{"id": bson.M{"$ne": currentProduct.ProductID}},
-				{"_id": bson.M{"$ne": currentProduct.ID}},
-			},
-		}
+	// Build query for related products: same category (if any), excluding current product
+	filter := store.ProductFilter{
+		Category:         currentProduct.Category,
+		ExcludeID:        currentProduct.ID,
+		ExcludeProductID: currentProduct.ProductID,
+		ExcludeSlug:      currentProduct.Slug,
 	}
 
-	// Build options
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSort(bson.D{{Key: "name", Value: 1}})
-
-	cursor, err := collection.Find(ctx, filter, findOptions)
+	products, err := ps.store.Products().List(ctx, filter, store.ListOptions{
+		Limit: int64(limit),
+		Sort:  "name",
+	})
 	if err != nil {
 		return nil, "", fmt.Errorf("error finding related products: %v", err)
 	}
-	defer cursor.Close(ctx)
-
-	var products []models.Product
-	if err = cursor.All(ctx, &products); err != nil {
-		return nil, "", fmt.Errorf("error decoding related products: %v", err)
-	}
+	ps.pricing.ApplyToProducts(ctx, products, time.Now())
 
 	category := currentProduct.Category
 	if category == "" {
@@ -596,19 +543,105 @@ func (ps *ProductService) generateSlug(name string) string {
 	return fmt.Sprintf("%s-%d", slug, timestamp)
 }
 
-// categoryExists checks if a category exists
-func (ps *ProductService) categoryExists(categoryID string) bool {
+// parseMoney converts a decoded JSON value (number, string or {amount, currency}) to Money
+func parseMoney(value interface{}) (models.Money, error) {
+	var money models.Money
+	data, err := json.Marshal(value)
+	if err != nil {
+		return money, err
+	}
+	err = json.Unmarshal(data, &money)
+	return money, err
+}
+
+// normalizeSaleFields chuẩn hoá sale_price/sale_starts_at/sale_ends_at trong updateData
+// và kiểm tra lại giá khuyến mãi sau khi ghép với dữ liệu hiện có
+func normalizeSaleFields(existing *models.Product, updateData bson.M) error {
+	price := existing.Price
+	if value, ok := updateData["price"].(models.Money); ok {
+		price = value
+	}
+
+	salePrice := existing.SalePrice
+	if value, exists := updateData["sale_price"]; exists {
+		salePrice = nil
+		if value != nil {
+			money, err := parseMoney(value)
+			if err != nil {
+				return fmt.Errorf("invalid sale price: %v", err)
+			}
+			salePrice = &money
+			updateData["sale_price"] = money
+		}
+	}
+
+	times := map[string]**time.Time{
+		"sale_starts_at": &existing.SaleStartsAt,
+		"sale_ends_at":   &existing.SaleEndsAt,
+	}
+	resolved := map[string]*time.Time{}
+	for key, current := range times {
+		resolved[key] = *current
+		value, exists := updateData[key]
+		if !exists {
+			continue
+		}
+		resolved[key] = nil
+		if text, ok := value.(string); ok && text != "" {
+			parsed, err := time.Parse(time.RFC3339, text)
+			if err != nil {
+				return fmt.Errorf("invalid sale price: %s must be RFC3339 time", key)
+			}
+			resolved[key] = &parsed
+			updateData[key] = parsed
+		} else {
+			updateData[key] = nil
+		}
+	}
+
+	return validateSalePrice(price, salePrice, resolved["sale_starts_at"], resolved["sale_ends_at"])
+}
+
+// validateSalePrice: giá phải tính bằng DefaultCurrency (giỏ hàng, phí giao hàng và mã giảm giá chỉ dùng một
+// loại tiền); giá khuyến mãi phải dương, thấp hơn giá gốc và kết thúc sau khi bắt đầu
+func validateSalePrice(price models.Money, salePrice *models.Money, startsAt, endsAt *time.Time) error {
+	if price.CurrencyCode() != models.DefaultCurrency {
+		return ErrPriceCurrency
+	}
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return ErrSaleWindow
+	}
+	if salePrice == nil {
+		return nil
+	}
+	if salePrice.CurrencyCode() != price.CurrencyCode() {
+		return ErrSaleCurrency
+	}
+	if salePrice.Amount <= 0 || salePrice.Cmp(price) >= 0 {
+		return ErrSalePriceRange
+	}
+	return nil
+}
+
+// findProduct finds a product by ObjectID or ProductID
+func (ps *ProductService) findProduct(ctx context.Context, id string) (*models.Product, error) {
+	if objectID, parseErr := primitive.ObjectIDFromHex(id); parseErr == nil {
+		return ps.store.Products().FindByObjectID(ctx, objectID)
+	}
+	return ps.store.Products().FindByProductID(ctx, id)
+}
+
+// categoryExists checks if a category exists; err chỉ khác nil khi truy vấn DB lỗi
+func (ps *ProductService) categoryExists(categoryID string) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("categories")
-
-	var category models.Category
-	err := collection.FindOne(ctx, bson.M{"id": categoryID}).Decode(&category)
+	_, err := ps.store.Categories().FindByCategoryID(ctx, categoryID)
+	if err == store.ErrNotFound {
+		return false, nil
+	}
 	if err != nil {
-		log.Printf("Category validation error: %v", err)
-		return false
+		return false, fmt.Errorf("error checking category %s: %v", categoryID, err)
 	}
-	return true
+	return true, nil
 }
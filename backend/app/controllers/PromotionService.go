@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+// Loại khuyến mãi tự động
+const (
+	PromotionBuyXGetY = "buy_x_get_y"       // mua X tặng Y trên cùng một sản phẩm
+	PromotionTiered   = "tiered_percentage" // giảm % theo bậc giá trị giỏ hàng
+	PromotionBundle   = "bundle"            // giảm khi mua đủ bộ sản phẩm
+)
+
+// PromotionTier là một bậc giảm giá: áp dụng khi tiền hàng thuộc phạm vi >= MinSubtotal
+type PromotionTier struct {
+	MinSubtotal models.Money `json:"min_subtotal"`
+	Percent     float64      `json:"percent"`
+}
+
+// Promotion là một khuyến mãi tự động (không cần mã). Products/Categories giới hạn phạm vi
+// cho buy_x_get_y và tiered_percentage; rỗng là áp dụng cho tất cả sản phẩm.
+type Promotion struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Products   []string   `json:"products,omitempty"`
+	Categories []string   `json:"categories,omitempty"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+
+	// buy_x_get_y: cứ mua BuyQuantity thì được tặng GetQuantity
+	BuyQuantity int `json:"buy_quantity,omitempty"`
+	GetQuantity int `json:"get_quantity,omitempty"`
+
+	// tiered_percentage
+	Tiers []PromotionTier `json:"tiers,omitempty"`
+
+	// bundle: mỗi bộ gồm 1 sản phẩm của mỗi id trong BundleProducts, giảm Percent (%) giá bộ hoặc Amount mỗi bộ
+	BundleProducts []string     `json:"bundle_products,omitempty"`
+	Percent        float64      `json:"percent,omitempty"`
+	Amount         models.Money `json:"amount"`
+}
+
+// PromotionLine là một dòng hàng đưa vào bộ tính khuyến mãi
+type PromotionLine struct {
+	ProductID string
+	Category  string
+	Price     models.Money
+	Quantity  int
+}
+
+// PromotionResult là kết quả áp dụng khuyến mãi, các slice theo cùng thứ tự với lines
+type PromotionResult struct {
+	LineDiscounts  []models.Money
+	LinePromotions [][]models.AppliedPromotion
+	Applied        []models.AppliedPromotion // tổng giảm theo từng khuyến mãi
+	Discount       models.Money
+}
+
+// PromotionService tính khuyến mãi tự động cho giỏ hàng
+type PromotionService struct {
+	promotions []Promotion
+}
+
+// NewPromotionService tạo PromotionService với danh sách khuyến mãi cho trước
+func NewPromotionService(promotions []Promotion) *PromotionService {
+	return &PromotionService{promotions: promotions}
+}
+
+// promotionsFromEnv đọc PROMOTIONS (JSON, vd
+// [{"id":"b2g1","name":"Mua 2 tặng 1","type":"buy_x_get_y","buy_quantity":2,"get_quantity":1}])
+func promotionsFromEnv() []Promotion {
+	value := os.Getenv("PROMOTIONS")
+	if value == "" {
+		return nil
+	}
+
+	var promotions []Promotion
+	if err := json.Unmarshal([]byte(value), &promotions); err != nil {
+		log.Printf("Invalid PROMOTIONS: %v", err)
+		return nil
+	}
+	return promotions
+}
+
+// NeedsCategories cho biết có khuyến mãi nào giới hạn theo danh mục không
+// (để service chỉ tra category của sản phẩm khi cần)
+func (ps *PromotionService) NeedsCategories() bool {
+	for _, promotion := range ps.promotions {
+		if len(promotion.Categories) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply áp dụng các khuyến mãi đang hiệu lực tại thời điểm now theo thứ tự cấu hình.
+// Khuyến mãi theo dòng (buy_x_get_y, bundle) được tính trước; tiered_percentage tính trên
+// tiền hàng còn lại sau các khuyến mãi đó.
+func (ps *PromotionService) Apply(lines []PromotionLine, now time.Time) *PromotionResult {
+	result := &PromotionResult{
+		LineDiscounts:  make([]models.Money, len(lines)),
+		LinePromotions: make([][]models.AppliedPromotion, len(lines)),
+	}
+	for i, line := range lines {
+		result.LineDiscounts[i] = models.Money{Currency: line.Price.Currency}
+	}
+
+	var tiered []Promotion
+	for _, promotion := range ps.promotions {
+		if !promotion.activeAt(now) {
+			continue
+		}
+		switch promotion.Type {
+		case PromotionBuyXGetY:
+			result.add(promotion, promotion.buyXGetY(lines))
+		case PromotionBundle:
+			result.add(promotion, promotion.bundle(lines))
+		case PromotionTiered:
+			tiered = append(tiered, promotion)
+		}
+	}
+	for _, promotion := range tiered {
+		result.add(promotion, promotion.tieredPercentage(lines, result.LineDiscounts))
+	}
+
+	return result
+}
+
+// add ghi nhận tiền giảm theo dòng của một khuyến mãi
+func (r *PromotionResult) add(promotion Promotion, discounts []int64) {
+	var total models.Money
+	for i, amount := range discounts {
+		if amount <= 0 {
+			continue
+		}
+		discount := models.Money{Amount: amount, Currency: r.LineDiscounts[i].Currency}
+		r.LineDiscounts[i] = r.LineDiscounts[i].Add(discount)
+		r.LinePromotions[i] = append(r.LinePromotions[i], promotion.applied(discount))
+		total = total.Add(discount)
+	}
+	if total.Amount > 0 {
+		r.Applied = append(r.Applied, promotion.applied(total))
+		r.Discount = r.Discount.Add(total)
+	}
+}
+
+func (p Promotion) applied(discount models.Money) models.AppliedPromotion {
+	return models.AppliedPromotion{ID: p.ID, Name: p.Name, Type: p.Type, Discount: discount}
+}
+
+func (p Promotion) activeAt(now time.Time) bool {
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// covers kiểm tra dòng hàng có thuộc phạm vi khuyến mãi không
+func (p Promotion) covers(line PromotionLine) bool {
+	if len(p.Products) == 0 && len(p.Categories) == 0 {
+		return true
+	}
+	for _, productID := range p.Products {
+		if productID == line.ProductID {
+			return true
+		}
+	}
+	for _, category := range p.Categories {
+		if sameTaxKey(category, line.Category) {
+			return true
+		}
+	}
+	return false
+}
+
+// buyXGetY: mỗi nhóm BuyQuantity+GetQuantity sản phẩm cùng loại được tặng GetQuantity sản phẩm
+func (p Promotion) buyXGetY(lines []PromotionLine) []int64 {
+	discounts := make([]int64, len(lines))
+	group := p.BuyQuantity + p.GetQuantity
+	if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+		return discounts
+	}
+	for i, line := range lines {
+		if !p.covers(line) {
+			continue
+		}
+		free := line.Quantity / group * p.GetQuantity
+		discounts[i] = line.Price.Amount * int64(free)
+	}
+	return discounts
+}
+
+// bundle: số bộ là số lượng nhỏ nhất trong các sản phẩm của bộ; tiền giảm chia cho các dòng theo giá
+func (p Promotion) bundle(lines []PromotionLine) []int64 {
+	discounts := make([]int64, len(lines))
+	if len(p.BundleProducts) == 0 {
+		return discounts
+	}
+
+	indexes := make([]int, 0, len(p.BundleProducts))
+	sets := -1
+	for _, productID := range p.BundleProducts {
+		found := -1
+		for i, line := range lines {
+			if line.ProductID == productID {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return discounts
+		}
+		indexes = append(indexes, found)
+		if sets < 0 || lines[found].Quantity < sets {
+			sets = lines[found].Quantity
+		}
+	}
+	if sets <= 0 {
+		return discounts
+	}
+
+	var bundlePrice int64
+	for _, i := range indexes {
+		bundlePrice += lines[i].Price.Amount
+	}
+
+	perSet := p.Amount.Amount
+	if p.Percent > 0 {
+		perSet = divRound(bundlePrice*int64(math.Round(p.Percent*100)), 10000)
+	}
+	if perSet > bundlePrice {
+		perSet = bundlePrice
+	}
+	if perSet <= 0 {
+		return discounts
+	}
+
+	// Chia theo giá từng sản phẩm trong bộ, phần dư dồn vào sản phẩm cuối
+	remaining := perSet
+	for n, i := range indexes {
+		share := perSet * lines[i].Price.Amount / bundlePrice
+		if n == len(indexes)-1 {
+			share = remaining
+		}
+		remaining -= share
+		discounts[i] += share * int64(sets)
+	}
+	return discounts
+}
+
+// tieredPercentage chọn bậc cao nhất mà tiền hàng thuộc phạm vi (sau các giảm giá trước đó) đạt được
+func (p Promotion) tieredPercentage(lines []PromotionLine, previous []models.Money) []int64 {
+	discounts := make([]int64, len(lines))
+
+	couponLines := make([]CouponLine, len(lines))
+	inScope := make([]bool, len(lines))
+	var eligible models.Money
+	for i, line := range lines {
+		couponLines[i] = CouponLine{Total: line.Price.Mul(line.Quantity).Sub(previous[i])}
+		if p.covers(line) {
+			inScope[i] = true
+			eligible = eligible.Add(couponLines[i].Total)
+		}
+	}
+
+	percent := 0.0
+	for _, tier := range p.Tiers {
+		if eligible.Cmp(tier.MinSubtotal) >= 0 && tier.Percent > percent {
+			percent = tier.Percent
+		}
+	}
+	if percent <= 0 || eligible.Amount <= 0 {
+		return discounts
+	}
+
+	amount := divRound(eligible.Amount*int64(math.Round(percent*100)), 10000)
+	for i, share := range allocateDiscount(amount, couponLines, inScope) {
+		discounts[i] = share.Amount
+	}
+	return discounts
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+func TestPromotionService_Apply(t *testing.T) {
+	now := time.Now()
+
+	t.Run("mua 2 tặng 1", func(t *testing.T) {
+		ps := NewPromotionService([]Promotion{
+			{ID: "b2g1", Name: "Mua 2 tặng 1", Type: PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Products: []string{"P1"}},
+		})
+		result := ps.Apply([]PromotionLine{
+			{ProductID: "P1", Price: models.VND(100000), Quantity: 7},
+			{ProductID: "P2", Price: models.VND(50000), Quantity: 3},
+		}, now)
+
+		// 7 sản phẩm = 2 nhóm 3 -> tặng 2, P2 ngoài phạm vi
+		if result.LineDiscounts[0].Amount != 200000 || !result.LineDiscounts[1].IsZero() {
+			t.Fatalf("unexpected line discounts: %v", result.LineDiscounts)
+		}
+		if len(result.LinePromotions[0]) != 1 || result.LinePromotions[0][0].ID != "b2g1" || len(result.LinePromotions[1]) != 0 {
+			t.Fatalf("unexpected line promotions: %+v", result.LinePromotions)
+		}
+		if result.Discount.Amount != 200000 || len(result.Applied) != 1 || result.Applied[0].Discount.Amount != 200000 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("giảm theo bậc sau khuyến mãi theo dòng", func(t *testing.T) {
+		ps := NewPromotionService([]Promotion{
+			{ID: "tier", Name: "Giảm 10% đơn từ 2 triệu", Type: PromotionTiered, Tiers: []PromotionTier{
+				{MinSubtotal: models.VND(1000000), Percent: 5},
+				{MinSubtotal: models.VND(2000000), Percent: 10},
+			}},
+			{ID: "b2g1", Name: "Mua 2 tặng 1", Type: PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Products: []string{"P1"}},
+		})
+		lines := []PromotionLine{
+			{ProductID: "P1", Price: models.VND(1000000), Quantity: 3},
+			{ProductID: "P2", Price: models.VND(500000), Quantity: 1},
+		}
+		result := ps.Apply(lines, now)
+
+		// Còn 2.500.000 sau mua 2 tặng 1 -> bậc 10% = 250.000 chia theo tỷ lệ 4:1
+		if result.LineDiscounts[0].Amount != 1000000+200000 || result.LineDiscounts[1].Amount != 50000 {
+			t.Fatalf("unexpected line discounts: %v", result.LineDiscounts)
+		}
+		if len(result.Applied) != 2 || result.Applied[0].ID != "b2g1" || result.Applied[1].Discount.Amount != 250000 {
+			t.Fatalf("unexpected applied promotions: %+v", result.Applied)
+		}
+
+		// Dưới 2 triệu chỉ đạt bậc 5%
+		lines[0].Quantity = 1
+		result = ps.Apply(lines, now)
+		if result.Discount.Amount != 75000 {
+			t.Fatalf("expected 5%% tier discount 75000, got %v", result.Discount)
+		}
+	})
+
+	t.Run("giảm giá theo bộ", func(t *testing.T) {
+		ps := NewPromotionService([]Promotion{
+			{ID: "combo", Name: "Combo", Type: PromotionBundle, BundleProducts: []string{"K1", "K2"}, Percent: 10},
+		})
+		lines := []PromotionLine{
+			{ProductID: "K1", Price: models.VND(200000), Quantity: 3},
+			{ProductID: "K2", Price: models.VND(100000), Quantity: 2},
+		}
+		result := ps.Apply(lines, now)
+
+		// 2 bộ, mỗi bộ giảm 30.000 chia 20.000/10.000
+		if result.LineDiscounts[0].Amount != 40000 || result.LineDiscounts[1].Amount != 20000 {
+			t.Fatalf("unexpected line discounts: %v", result.LineDiscounts)
+		}
+
+		// Thiếu một sản phẩm của bộ thì không áp dụng
+		result = ps.Apply(lines[:1], now)
+		if !result.Discount.IsZero() || len(result.Applied) != 0 {
+			t.Fatalf("expected no bundle discount, got %+v", result)
+		}
+	})
+
+	t.Run("ngoài thời gian áp dụng", func(t *testing.T) {
+		past, future := now.Add(-time.Hour), now.Add(time.Hour)
+		ps := NewPromotionService([]Promotion{
+			{ID: "ended", Type: PromotionBuyXGetY, BuyQuantity: 1, GetQuantity: 1, EndsAt: &past},
+			{ID: "upcoming", Type: PromotionBuyXGetY, BuyQuantity: 1, GetQuantity: 1, StartsAt: &future},
+		})
+		result := ps.Apply([]PromotionLine{{ProductID: "P1", Price: models.VND(100000), Quantity: 4}}, now)
+		if !result.Discount.IsZero() {
+			t.Fatalf("expected no discount, got %v", result.Discount)
+		}
+	})
+}
+
+func TestPromotionsFromEnv(t *testing.T) {
+	t.Setenv("PROMOTIONS", `[{"id":"big","name":"Giảm 10%","type":"tiered_percentage","categories":["books"],"tiers":[{"min_subtotal":2000000,"percent":10}]}]`)
+	promotions := promotionsFromEnv()
+	if len(promotions) != 1 || promotions[0].Tiers[0].MinSubtotal.Amount != 2000000 || promotions[0].Tiers[0].Percent != 10 {
+		t.Fatalf("unexpected promotions: %+v", promotions)
+	}
+	if !NewPromotionService(promotions).NeedsCategories() {
+		t.Fatal("expected category-scoped promotion")
+	}
+
+	t.Setenv("PROMOTIONS", `not json`)
+	if promotions := promotionsFromEnv(); promotions != nil {
+		t.Fatalf("expected no promotions for invalid config, got %+v", promotions)
+	}
+}
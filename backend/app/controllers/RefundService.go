@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cách hoàn tiền (OrderRefund.Method)
+const (
+	RefundMethodProvider = "provider" // Hoàn qua cổng thanh toán đã thu tiền
+	RefundMethodManual   = "manual"   // Shop tự chuyển khoản lại (COD, chuyển khoản ngân hàng)
+)
+
+// Trạng thái của một lần hoàn tiền (OrderRefund.Status)
+const (
+	RefundStatusPending   = "pending"   // Đã giữ chỗ số tiền hoàn, đang chờ provider
+	RefundStatusSucceeded = "succeeded" // Đã hoàn xong
+	RefundStatusFailed    = "failed"    // Provider từ chối, số tiền giữ chỗ đã được trả lại
+)
+
+// Lỗi của RefundService
+var (
+	ErrRefundConflict       = errors.New("đơn hàng vừa được hoàn tiền bởi yêu cầu khác, vui lòng thử lại")
+	ErrRefundInProgress     = errors.New("đơn hàng đang có lần hoàn tiền chưa hoàn tất, vui lòng thử lại sau")
+	ErrRefundProviderFailed = errors.New("cổng thanh toán từ chối hoàn tiền")
+)
+
+// RefundError được trả về khi yêu cầu hoàn tiền không hợp lệ
+type RefundError struct {
+	Reason string
+}
+
+func (e *RefundError) Error() string {
+	return e.Reason
+}
+
+// RefundRequest là yêu cầu hoàn tiền của admin
+type RefundRequest struct {
+	Amount  *models.Money            // nil = hoàn toàn bộ số tiền còn lại
+	Reason  string                   // Bắt buộc
+	Restock bool                     // Nhập lại kho các sản phẩm trong Items
+	Items   []models.RefundStockItem // Rỗng = nhập lại toàn bộ hàng chưa nhập kho
+
+	ReturnID string // Yêu cầu trả hàng được hoàn tiền (nếu có)
+}
+
+// RefundService hoàn tiền đơn hàng đã giao hoặc đã hủy: gọi provider đã thu tiền (nếu có), ghi lại
+// lần hoàn vào refunds, cập nhật payment.status và chuyển đơn sang "refunded" khi đã hoàn hết.
+type RefundService struct {
+	store     store.Store
+	providers map[string]PaymentProvider
+	statuses  *OrderStateMachine
+}
+
+// NewRefundService creates a new refund service
+func NewRefundService(st store.Store, providers ...PaymentProvider) *RefundService {
+	rs := &RefundService{
+		store:     st,
+		providers: make(map[string]PaymentProvider),
+		statuses:  NewDefaultOrderStateMachine(),
+	}
+	for _, provider := range providers {
+		rs.providers[provider.Name()] = provider
+	}
+	return rs
+}
+
+// PaidAmount là số tiền shop đã thu của đơn hàng (kể cả phần chuyển khoản thừa)
+func PaidAmount(order *models.Order) models.Money {
+	received := order.Payment.ReceivedAmount
+	switch order.Payment.Status {
+	case PaymentStatusPaid, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		if received.Cmp(order.TotalAmount) > 0 {
+			return received
+		}
+		return order.TotalAmount
+	}
+	// Chuyển khoản thiếu: chỉ hoàn được phần đã nhận
+	return models.Money{Currency: order.TotalAmount.Currency}.Add(received)
+}
+
+// RefundableAmount là số tiền còn có thể hoàn
+func RefundableAmount(order *models.Order) models.Money {
+	return PaidAmount(order).Sub(order.RefundedAmount)
+}
+
+// RefundOrder hoàn tiền cho đơn hàng. Với provider, số tiền hoàn được giữ chỗ trước bằng một lần hoàn
+// "pending" (chặn các yêu cầu hoàn tiền đồng thời), sau đó mới gọi provider và ghi nhận hoặc trả lại
+// phần giữ chỗ theo kết quả. Nếu ghi nhận thất bại sau khi provider đã hoàn, lần hoàn tiền vẫn ở
+// "pending" và mã hoàn tiền được log để đối soát thủ công.
+func (rs *RefundService) RefundOrder(orderID string, req RefundRequest, actor OrderActor) (*models.Order, *models.OrderRefund, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, nil, errors.New("đơn hàng không tồn tại")
+	}
+	order, err := rs.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, nil, errors.New("đơn hàng không tồn tại")
+	}
+
+	status := normalizeOrderStatus(order.Status)
+	if status != OrderStatusDelivered && status != OrderStatusCancelled && status != OrderStatusRefunded {
+		return nil, nil, &RefundError{Reason: "chỉ có thể hoàn tiền đơn hàng đã giao hoặc đã hủy"}
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, nil, &RefundError{Reason: "lý do hoàn tiền là bắt buộc"}
+	}
+
+	if refundPending(order) {
+		return nil, nil, ErrRefundInProgress
+	}
+
+	remaining := RefundableAmount(order)
+	if remaining.Amount <= 0 {
+		return nil, nil, &RefundError{Reason: "đơn hàng không còn khoản thanh toán nào để hoàn"}
+	}
+	amount := remaining
+	if req.Amount != nil {
+		amount = *req.Amount
+		if amount.CurrencyCode() != remaining.CurrencyCode() {
+			return nil, nil, &RefundError{Reason: "loại tiền hoàn không khớp với đơn hàng"}
+		}
+		if amount.Amount <= 0 {
+			return nil, nil, &RefundError{Reason: "số tiền hoàn phải lớn hơn 0"}
+		}
+		if amount.Cmp(remaining) > 0 {
+			return nil, nil, &RefundError{Reason: fmt.Sprintf("số tiền hoàn vượt quá số tiền còn có thể hoàn (%s)", remaining)}
+		}
+	}
+
+	var restock []models.RefundStockItem
+	if req.Restock {
+		if status != OrderStatusDelivered && order.DeliveredAt == nil {
+			return nil, nil, &RefundError{Reason: "đơn hàng chưa được giao, hàng đã được nhập lại kho khi hủy đơn"}
+		}
+		if restock, err = restockItems(order, req.Items); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	refund := models.OrderRefund{
+		Amount:    amount,
+		Reason:    reason,
+		Method:    RefundMethodManual,
+		Status:    RefundStatusSucceeded,
+		Items:     restock,
+		ReturnID:  req.ReturnID,
+		ActorID:   actor.ID,
+		CreatedAt: time.Now(),
+	}
+	refunded := order.RefundedAmount.Add(amount)
+	paymentStatus := PaymentStatusPartiallyRefunded
+	if refunded.Cmp(PaidAmount(order)) >= 0 {
+		paymentStatus = PaymentStatusRefunded
+	}
+
+	provider, online := rs.providers[order.Payment.Provider]
+	online = online && order.Payment.TransactionID != ""
+	if !online {
+		refund.ID = "re_manual_" + randomHex(8)
+		err = rs.recordRefund(ctx, order, refund, restock, paymentStatus, func(ctx context.Context, fields bson.M) error {
+			fields["refunded_amount"] = refunded
+			return rs.store.Orders().AddRefund(ctx, order.ID, len(order.Refunds), refund, fields)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		// Giữ chỗ: cộng số tiền vào refunded_amount cùng lần hoàn "pending" trước khi gọi provider
+		claim := refund
+		claim.ID = "re_pending_" + randomHex(8)
+		claim.Status = RefundStatusPending
+		claim.Provider = provider.Name()
+		claim.Items = nil
+		if err := rs.store.Orders().AddRefund(ctx, order.ID, len(order.Refunds), claim, bson.M{"refunded_amount": refunded}); err != nil {
+			if err == store.ErrStatusConflict {
+				return nil, nil, ErrRefundConflict
+			}
+			return nil, nil, errors.New("lỗi khi ghi nhận hoàn tiền")
+		}
+
+		result, err := provider.Refund(ctx, order.Payment, amount, reason)
+		if err != nil {
+			log.Printf("Provider %s refused refund for order %s: %v", provider.Name(), order.OrderNumber, err)
+			failed := claim
+			failed.Status = RefundStatusFailed
+			if err := rs.store.Orders().UpdateRefund(ctx, order.ID, claim.ID, RefundStatusPending, failed, bson.M{"refunded_amount": order.RefundedAmount}); err != nil {
+				log.Printf("Refund claim %s for order %s could not be released: %v", claim.ID, order.OrderNumber, err)
+			}
+			return nil, nil, fmt.Errorf("%w: %v", ErrRefundProviderFailed, err)
+		}
+
+		refund.ID, refund.Method, refund.Provider = result.ID, RefundMethodProvider, provider.Name()
+		err = rs.recordRefund(ctx, order, refund, restock, paymentStatus, func(ctx context.Context, fields bson.M) error {
+			return rs.store.Orders().UpdateRefund(ctx, order.ID, claim.ID, RefundStatusPending, refund, fields)
+		})
+		if err != nil {
+			log.Printf("Refund %s for order %s was issued by %s but not recorded, claim %s stays pending: %v", refund.ID, order.OrderNumber, refund.Provider, claim.ID, err)
+			return nil, nil, err
+		}
+	}
+
+	if paymentStatus == PaymentStatusRefunded {
+		rs.markOrderRefunded(ctx, order, actor, reason)
+	}
+
+	updated, err := rs.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, nil, errors.New("lỗi khi lấy đơn hàng đã cập nhật")
+	}
+	return updated, &refund, nil
+}
+
+// recordRefund nhập lại kho (nếu có) và ghi nhận lần hoàn tiền bằng write trong cùng một thao tác nguyên tử
+func (rs *RefundService) recordRefund(ctx context.Context, order *models.Order, refund models.OrderRefund, restock []models.RefundStockItem, paymentStatus string, write func(ctx context.Context, fields bson.M) error) error {
+	return runAtomic(ctx, rs.store, func(ctx context.Context, rb *rollback) error {
+		fields := bson.M{"payment.status": paymentStatus}
+		if len(restock) > 0 {
+			items, err := rs.restock(ctx, rb, order, restock)
+			if err != nil {
+				return err
+			}
+			fields["items"] = items
+		}
+
+		if err := write(ctx, fields); err != nil {
+			if err == store.ErrStatusConflict {
+				return ErrRefundConflict
+			}
+			return errors.New("lỗi khi ghi nhận hoàn tiền")
+		}
+		return nil
+	})
+}
+
+// refundPending cho biết đơn có lần hoàn tiền qua provider chưa hoàn tất
+func refundPending(order *models.Order) bool {
+	for _, refund := range order.Refunds {
+		if refund.Status == RefundStatusPending {
+			return true
+		}
+	}
+	return false
+}
+
+// restockItems xác định số lượng nhập lại kho cho từng dòng hàng, không vượt quá số lượng chưa nhập kho
+func restockItems(order *models.Order, requested []models.RefundStockItem) ([]models.RefundStockItem, error) {
+	available := make(map[string]int)
+	names := make(map[string]string)
+	var skus []string
+	for _, item := range order.Items {
+		if _, seen := available[item.ProductSKU]; !seen {
+			skus = append(skus, item.ProductSKU)
+		}
+		available[item.ProductSKU] += item.Quantity - item.RestockedQuantity
+		names[item.ProductSKU] = item.ProductName
+	}
+
+	quantities := make(map[string]int)
+	if len(requested) == 0 {
+		for _, sku := range skus {
+			quantities[sku] = available[sku]
+		}
+	}
+	for _, item := range requested {
+		if _, ok := available[item.ProductSKU]; !ok {
+			return nil, &RefundError{Reason: fmt.Sprintf("sản phẩm %s không có trong đơn hàng", item.ProductSKU)}
+		}
+		if item.Quantity <= 0 {
+			return nil, &RefundError{Reason: "số lượng nhập lại kho phải lớn hơn 0"}
+		}
+		quantities[item.ProductSKU] += item.Quantity
+	}
+
+	var items []models.RefundStockItem
+	for _, sku := range skus {
+		quantity := quantities[sku]
+		if quantity > available[sku] {
+			return nil, &RefundError{Reason: fmt.Sprintf("sản phẩm %s chỉ còn %d chưa nhập lại kho", names[sku], available[sku])}
+		}
+		if quantity > 0 {
+			items = append(items, models.RefundStockItem{ProductSKU: sku, ProductName: names[sku], Quantity: quantity})
+		}
+	}
+	if len(items) == 0 {
+		return nil, &RefundError{Reason: "hàng của đơn đã được nhập lại kho"}
+	}
+	return items, nil
+}
+
+// restock cộng lại stock và trả về danh sách dòng hàng với restocked_quantity mới
+func (rs *RefundService) restock(ctx context.Context, rb *rollback, order *models.Order, restock []models.RefundStockItem) ([]models.OrderItem, error) {
+	items := make([]models.OrderItem, len(order.Items))
+	copy(items, order.Items)
+
+	for _, entry := range restock {
+		if err := rs.store.Products().AdjustStock(ctx, entry.ProductSKU, entry.Quantity); err != nil {
+			return nil, fmt.Errorf("lỗi khi nhập lại kho sản phẩm %s", entry.ProductName)
+		}
+		rb.onRollback(func(ctx context.Context) error {
+			return rs.store.Products().AdjustStock(ctx, entry.ProductSKU, -entry.Quantity)
+		})
+
+		// Phân bổ vào các dòng hàng cùng sản phẩm theo thứ tự
+		left := entry.Quantity
+		for i := range items {
+			if items[i].ProductSKU != entry.ProductSKU || left == 0 {
+				continue
+			}
+			n := min(left, items[i].Quantity-items[i].RestockedQuantity)
+			items[i].RestockedQuantity += n
+			left -= n
+		}
+	}
+	return items, nil
+}
+
+// markOrderRefunded chuyển đơn đã hoàn hết tiền sang "refunded" nếu bảng trạng thái cho phép.
+// Tiền đã được hoàn nên lỗi ở bước này chỉ được log lại.
+func (rs *RefundService) markOrderRefunded(ctx context.Context, order *models.Order, actor OrderActor, reason string) {
+	if normalizeOrderStatus(order.Status) == OrderStatusRefunded {
+		return
+	}
+	change := StatusChange{Order: order, From: order.Status, To: OrderStatusRefunded, Actor: actor, Reason: reason}
+	if err := rs.statuses.Check(ctx, change); err != nil {
+		log.Printf("Order %s fully refunded but cannot move to refunded: %v", order.OrderNumber, err)
+		return
+	}
+
+	now := time.Now()
+	entry := models.OrderStatusEntry{
+		From:      normalizeOrderStatus(order.Status),
+		Status:    OrderStatusRefunded,
+		ActorID:   actor.ID,
+		ActorRole: actor.Role,
+		Reason:    reason,
+		ChangedAt: now,
+	}
+	fields := statusTimestampFields(order, OrderStatusRefunded, now)
+	if err := rs.store.Orders().UpdateStatus(ctx, order.ID, order.Status, entry, fields); err != nil {
+		log.Printf("Order %s fully refunded but status update failed: %v", order.OrderNumber, err)
+	}
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+var refundAdmin = OrderActor{ID: "admin-1", Role: ActorAdmin}
+
+// deliveredOrder: đơn đã giao và đã thanh toán online qua provider local
+func deliveredOrder(o *models.Order) {
+	now := time.Now()
+	o.Status = OrderStatusDelivered
+	o.DeliveredAt = &now
+	o.Payment.Method = "credit_card"
+	o.Payment.Provider = LocalPaymentProviderName
+	o.Payment.Status = PaymentStatusPaid
+	o.Payment.TransactionID = "txn_local_test"
+}
+
+func TestRefundService_PartialThenFullRefund(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, deliveredOrder)
+	rs := NewRefundService(st, NewLocalPaymentProvider("test-secret"))
+
+	amount := models.VND(50000)
+	partial, refund, err := rs.RefundOrder(order.ID.Hex(), RefundRequest{Amount: &amount, Reason: "giao thiếu quà tặng"}, refundAdmin)
+	mustNoError(t, err)
+	if refund.Method != RefundMethodProvider || refund.Provider != LocalPaymentProviderName || refund.ActorID != "admin-1" {
+		t.Fatalf("expected provider refund, got %+v", refund)
+	}
+	if partial.Payment.Status != PaymentStatusPartiallyRefunded || partial.RefundedAmount != amount || partial.Status != OrderStatusDelivered {
+		t.Fatalf("expected partially refunded delivered order, got %s %v %+v", partial.Status, partial.RefundedAmount, partial.Payment)
+	}
+
+	// Không truyền số tiền = hoàn phần còn lại
+	full, refund, err := rs.RefundOrder(order.ID.Hex(), RefundRequest{Reason: "khách trả hàng"}, refundAdmin)
+	mustNoError(t, err)
+	if refund.Amount != models.VND(150000) {
+		t.Fatalf("expected remaining 150000 refunded, got %v", refund.Amount)
+	}
+	if full.Payment.Status != PaymentStatusRefunded || full.Status != OrderStatusRefunded || len(full.Refunds) != 2 {
+		t.Fatalf("expected fully refunded order, got %s %+v", full.Status, full.Payment)
+	}
+	if last := full.StatusHistory[len(full.StatusHistory)-1]; last.Status != OrderStatusRefunded || last.ActorID != "admin-1" {
+		t.Fatalf("expected refunded status entry, got %+v", last)
+	}
+
+	_, _, err = rs.RefundOrder(order.ID.Hex(), RefundRequest{Reason: "lần nữa"}, refundAdmin)
+	expectError(t, err, "không còn khoản thanh toán nào để hoàn")
+}
+
+func TestRefundService_ManualRefundWithRestock(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	order := createTestOrder(t, st, user.ID, deliveredOrder, func(o *models.Order) {
+		o.Payment = models.Payment{Method: "cash_on_delivery", Status: PaymentStatusPaid}
+		o.Items[0].ProductSKU = product.ProductID
+	})
+	rs := NewRefundService(st)
+
+	amount := models.VND(100000)
+	updated, refund, err := rs.RefundOrder(order.ID.Hex(), RefundRequest{
+		Amount:  &amount,
+		Reason:  "trả lại 1 hộp",
+		Restock: true,
+		Items:   []models.RefundStockItem{{ProductSKU: product.ProductID, Quantity: 1}},
+	}, refundAdmin)
+	mustNoError(t, err)
+	if refund.Method != RefundMethodManual || len(refund.Items) != 1 || refund.Items[0].Quantity != 1 {
+		t.Fatalf("expected manual refund restocking 1 item, got %+v", refund)
+	}
+	if updated.Items[0].RestockedQuantity != 1 {
+		t.Fatalf("expected restocked quantity 1, got %d", updated.Items[0].RestockedQuantity)
+	}
+	stocked, err := st.Products().FindByProductID(context.Background(), product.ProductID)
+	mustNoError(t, err)
+	if stocked.Amount != product.Amount+1 {
+		t.Fatalf("expected stock %d, got %d", product.Amount+1, stocked.Amount)
+	}
+
+	// Chỉ còn 1 hộp chưa nhập kho
+	_, _, err = rs.RefundOrder(order.ID.Hex(), RefundRequest{
+		Reason:  "trả nốt",
+		Restock: true,
+		Items:   []models.RefundStockItem{{ProductSKU: product.ProductID, Quantity: 2}},
+	}, refundAdmin)
+	expectError(t, err, "chưa nhập lại kho")
+}
+
+func TestRefundService_Validation(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	rs := NewRefundService(st, NewLocalPaymentProvider("test-secret"))
+
+	pending := createTestOrder(t, st, user.ID)
+	_, _, err := rs.RefundOrder(pending.ID.Hex(), RefundRequest{Reason: "x"}, refundAdmin)
+	expectError(t, err, "đã giao hoặc đã hủy")
+
+	unpaid := createTestOrder(t, st, user.ID, func(o *models.Order) { o.Status = OrderStatusCancelled })
+	_, _, err = rs.RefundOrder(unpaid.ID.Hex(), RefundRequest{Reason: "x"}, refundAdmin)
+	expectError(t, err, "không còn khoản thanh toán nào để hoàn")
+
+	order := createTestOrder(t, st, user.ID, deliveredOrder)
+	_, _, err = rs.RefundOrder(order.ID.Hex(), RefundRequest{}, refundAdmin)
+	expectError(t, err, "lý do hoàn tiền là bắt buộc")
+
+	tooMuch := models.VND(200001)
+	_, _, err = rs.RefundOrder(order.ID.Hex(), RefundRequest{Amount: &tooMuch, Reason: "x"}, refundAdmin)
+	var refundErr *RefundError
+	if !errors.As(err, &refundErr) {
+		t.Fatalf("expected RefundError for over-refund, got %v", err)
+	}
+
+	_, _, err = rs.RefundOrder(order.ID.Hex(), RefundRequest{Reason: "x", Restock: true, Items: []models.RefundStockItem{{ProductSKU: "unknown", Quantity: 1}}}, refundAdmin)
+	expectError(t, err, "không có trong đơn hàng")
+
+	cancelled := createTestOrder(t, st, user.ID, deliveredOrder, func(o *models.Order) {
+		o.Status = OrderStatusCancelled
+		o.DeliveredAt = nil
+	})
+	_, _, err = rs.RefundOrder(cancelled.ID.Hex(), RefundRequest{Reason: "x", Restock: true}, refundAdmin)
+	expectError(t, err, "chưa được giao")
+}
+
+func TestRefundService_StatisticsNetOfRefunds(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, deliveredOrder)
+	createTestOrder(t, st, user.ID, deliveredOrder)
+	rs := NewRefundService(st, NewLocalPaymentProvider("test-secret"))
+
+	amount := models.VND(30000)
+	_, _, err := rs.RefundOrder(order.ID.Hex(), RefundRequest{Amount: &amount, Reason: "bồi thường"}, refundAdmin)
+	mustNoError(t, err)
+
+	stats, err := NewOrderService(st).GetOrderStatistics(nil, nil)
+	mustNoError(t, err)
+	if stats.TotalRevenue != models.VND(370000) || stats.TotalRefunded != amount {
+		t.Fatalf("expected revenue 370000 and refunded 30000, got %v %v", stats.TotalRevenue, stats.TotalRefunded)
+	}
+}
+
+// hookedRefundProvider là provider local gọi onRefund thay cho Refund thật
+type hookedRefundProvider struct {
+	*LocalPaymentProvider
+	onRefund func() error
+}
+
+func (p *hookedRefundProvider) Refund(ctx context.Context, payment models.Payment, amount models.Money, reason string) (*PaymentRefund, error) {
+	if err := p.onRefund(); err != nil {
+		return nil, err
+	}
+	return p.LocalPaymentProvider.Refund(ctx, payment, amount, reason)
+}
+
+func TestRefundService_ClaimsBeforeProvider(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, deliveredOrder)
+	provider := &hookedRefundProvider{LocalPaymentProvider: NewLocalPaymentProvider("test-secret")}
+	rs := NewRefundService(st, provider)
+
+	// Yêu cầu thứ hai tới trong lúc provider đang hoàn tiền thì bị từ chối, provider chỉ được gọi một lần
+	calls := 0
+	provider.onRefund = func() error {
+		calls++
+		if calls == 1 {
+			_, _, err := rs.RefundOrder(order.ID.Hex(), RefundRequest{Reason: "bấm hai lần"}, refundAdmin)
+			if err != ErrRefundInProgress {
+				t.Errorf("expected concurrent refund to be rejected, got %v", err)
+			}
+		}
+		return nil
+	}
+	refunded, refund, err := rs.RefundOrder(order.ID.Hex(), RefundRequest{Reason: "khách trả hàng"}, refundAdmin)
+	mustNoError(t, err)
+	if calls != 1 || len(refunded.Refunds) != 1 || refunded.Refunds[0].ID != refund.ID || refunded.Refunds[0].Status != RefundStatusSucceeded {
+		t.Fatalf("expected one recorded refund after %d provider calls, got %+v", calls, refunded.Refunds)
+	}
+	if refunded.RefundedAmount != models.VND(200000) || refunded.Payment.Status != PaymentStatusRefunded {
+		t.Fatalf("expected fully refunded order, got %v %+v", refunded.RefundedAmount, refunded.Payment)
+	}
+
+	// Provider từ chối: phần giữ chỗ được trả lại và có thể hoàn tiền lại
+	failing := createTestOrder(t, st, user.ID, deliveredOrder)
+	provider.onRefund = func() error { return errors.New("gateway timeout") }
+	_, _, err = rs.RefundOrder(failing.ID.Hex(), RefundRequest{Reason: "khách trả hàng"}, refundAdmin)
+	if !errors.Is(err, ErrRefundProviderFailed) {
+		t.Fatalf("expected provider failure, got %v", err)
+	}
+	after, err := st.Orders().FindByID(context.Background(), failing.ID)
+	mustNoError(t, err)
+	if after.RefundedAmount.Amount != 0 || after.Payment.Status != PaymentStatusPaid || len(after.Refunds) != 1 || after.Refunds[0].Status != RefundStatusFailed {
+		t.Fatalf("expected released claim, got %v %+v %+v", after.RefundedAmount, after.Payment, after.Refunds)
+	}
+
+	provider.onRefund = func() error { return nil }
+	retried, _, err := rs.RefundOrder(failing.ID.Hex(), RefundRequest{Reason: "khách trả hàng"}, refundAdmin)
+	mustNoError(t, err)
+	if retried.RefundedAmount != models.VND(200000) || len(retried.Refunds) != 2 {
+		t.Fatalf("expected retry to refund, got %v %+v", retried.RefundedAmount, retried.Refunds)
+	}
+}
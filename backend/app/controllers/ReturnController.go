@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// ReturnController handles return (RMA) HTTP requests
+type ReturnController struct {
+	returnService *ReturnService
+}
+
+// NewReturnController creates a new return controller instance
+func NewReturnController(st store.Store) *ReturnController {
+	return &ReturnController{
+		returnService: NewReturnService(st, returnWindowFromEnv(), NewRefundService(st, paymentProvidersFromEnv()...)),
+	}
+}
+
+// CreateReturnRequest là body khách gửi khi yêu cầu trả hàng
+type CreateReturnRequest struct {
+	Items  []models.ReturnItem `json:"items" binding:"required"`
+	Reason string              `json:"reason" binding:"required"`
+	Photos []string            `json:"photos"`
+}
+
+// ReturnDecisionRequest là body admin gửi khi duyệt/từ chối yêu cầu trả hàng
+type ReturnDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+// RefundReturnRequest là body admin gửi khi hoàn tiền cho hàng trả (có thể rỗng)
+type RefundReturnRequest struct {
+	Amount *models.Money `json:"amount"` // Bỏ trống = giá trị hàng trả
+}
+
+// RequestReturn tạo yêu cầu trả hàng cho đơn đã giao
+func (rc *ReturnController) RequestReturn(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Không thể xác thực người dùng",
+		})
+		return
+	}
+
+	var req CreateReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dữ liệu không hợp lệ",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	order, ret, err := rc.returnService.RequestReturn(c.Param("id"), userID.(string), ReturnRequest{
+		Items:  req.Items,
+		Reason: req.Reason,
+		Photos: req.Photos,
+	})
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Đã gửi yêu cầu trả hàng",
+		"data": gin.H{
+			"order":  order,
+			"return": ret,
+		},
+	})
+}
+
+// ApproveReturn duyệt yêu cầu trả hàng (Admin only)
+func (rc *ReturnController) ApproveReturn(c *gin.Context) {
+	var req ReturnDecisionRequest
+	_ = c.ShouldBindJSON(&req)
+
+	order, err := rc.returnService.ApproveReturn(c.Param("id"), c.Param("returnId"), orderActorFromContext(c), req.Note)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Đã duyệt yêu cầu trả hàng",
+		"data":    order,
+	})
+}
+
+// RejectReturn từ chối yêu cầu trả hàng (Admin only)
+func (rc *ReturnController) RejectReturn(c *gin.Context) {
+	var req ReturnDecisionRequest
+	_ = c.ShouldBindJSON(&req)
+
+	order, err := rc.returnService.RejectReturn(c.Param("id"), c.Param("returnId"), orderActorFromContext(c), req.Note)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Đã từ chối yêu cầu trả hàng",
+		"data":    order,
+	})
+}
+
+// ReceiveReturn ghi nhận đã nhận hàng trả về và nhập lại kho (Admin only)
+func (rc *ReturnController) ReceiveReturn(c *gin.Context) {
+	order, err := rc.returnService.ReceiveReturn(c.Param("id"), c.Param("returnId"), orderActorFromContext(c))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Đã nhận hàng trả về",
+		"data":    order,
+	})
+}
+
+// RefundReturn hoàn tiền cho hàng trả đã nhận (Admin only)
+func (rc *ReturnController) RefundReturn(c *gin.Context) {
+	var req RefundReturnRequest
+	_ = c.ShouldBindJSON(&req)
+
+	order, refund, err := rc.returnService.RefundReturn(c.Param("id"), c.Param("returnId"), orderActorFromContext(c), req.Amount)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Hoàn tiền trả hàng thành công",
+		"data": gin.H{
+			"order":  order,
+			"refund": refund,
+		},
+	})
+}
+
+// respondReturnError map lỗi của ReturnService (và RefundService khi hoàn tiền) sang HTTP status
+func respondReturnError(c *gin.Context, err error) {
+	var returnErr *ReturnError
+	var refundErr *RefundError
+	switch {
+	case errors.As(err, &returnErr), errors.As(err, &refundErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case err == ErrReturnNotFound || err.Error() == "đơn hàng không tồn tại":
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case err == ErrReturnConflict || err == ErrRefundConflict || err == ErrRefundInProgress:
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrRefundProviderFailed):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": ErrRefundProviderFailed.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Trạng thái yêu cầu trả hàng (OrderReturn.Status)
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	ReturnStatusReceived  = "received"
+	ReturnStatusRefunded  = "refunded"
+)
+
+const (
+	defaultReturnWindow = 7 * 24 * time.Hour
+	MaxReturnPhotos     = 5
+)
+
+// Lỗi của ReturnService
+var (
+	ErrReturnNotFound = errors.New("yêu cầu trả hàng không tồn tại")
+	ErrReturnConflict = errors.New("yêu cầu trả hàng vừa được cập nhật bởi request khác, vui lòng thử lại")
+)
+
+// ReturnError được trả về khi yêu cầu trả hàng hoặc thao tác của admin không hợp lệ
+type ReturnError struct {
+	Reason string
+}
+
+func (e *ReturnError) Error() string {
+	return e.Reason
+}
+
+// ReturnRequest là yêu cầu trả hàng của khách
+type ReturnRequest struct {
+	Items  []models.ReturnItem
+	Reason string
+	Photos []string
+}
+
+// ReturnService xử lý yêu cầu trả hàng (RMA): khách tạo yêu cầu trong thời hạn sau khi nhận hàng,
+// admin duyệt/từ chối, nhận hàng về kho rồi hoàn tiền qua RefundService.
+type ReturnService struct {
+	store   store.Store
+	refunds *RefundService
+	window  time.Duration
+}
+
+// NewReturnService creates a new return service
+func NewReturnService(st store.Store, window time.Duration, refunds *RefundService) *ReturnService {
+	return &ReturnService{
+		store:   st,
+		refunds: refunds,
+		window:  window,
+	}
+}
+
+// returnWindowFromEnv đọc RETURN_WINDOW_DAYS (số ngày được trả hàng kể từ khi giao, mặc định 7)
+func returnWindowFromEnv() time.Duration {
+	value := os.Getenv("RETURN_WINDOW_DAYS")
+	if value == "" {
+		return defaultReturnWindow
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		log.Printf("Invalid RETURN_WINDOW_DAYS %q, using %v", value, defaultReturnWindow)
+		return defaultReturnWindow
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ReturnDeadline trả về hạn cuối khách được yêu cầu trả hàng, nil nếu đơn chưa giao
+func ReturnDeadline(order *models.Order, window time.Duration) *time.Time {
+	if normalizeOrderStatus(order.Status) != OrderStatusDelivered || order.DeliveredAt == nil {
+		return nil
+	}
+	deadline := order.DeliveredAt.Add(window)
+	return &deadline
+}
+
+// RequestReturn tạo yêu cầu trả hàng cho đơn đã giao của user
+func (rs *ReturnService) RequestReturn(orderID, userID string, req ReturnRequest) (*models.Order, *models.OrderReturn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, err := rs.findOrder(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !orderBelongsTo(order, userID) {
+		return nil, nil, errors.New("đơn hàng không tồn tại")
+	}
+
+	deadline := ReturnDeadline(order, rs.window)
+	if deadline == nil {
+		return nil, nil, &ReturnError{Reason: "chỉ có thể trả hàng cho đơn hàng đã giao"}
+	}
+	if time.Now().After(*deadline) {
+		return nil, nil, &ReturnError{Reason: fmt.Sprintf("đã quá thời hạn trả hàng (%s)", deadline.Format("02/01/2006"))}
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, nil, &ReturnError{Reason: "lý do trả hàng là bắt buộc"}
+	}
+	photos, err := validateReturnPhotos(req.Photos)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, amount, err := returnItems(order, req.Items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ret := models.OrderReturn{
+		ID:         fmt.Sprintf("%s-R%d", order.OrderNumber, len(order.Returns)+1),
+		Status:     ReturnStatusRequested,
+		Items:      items,
+		Reason:     reason,
+		Photos:     photos,
+		CustomerID: userID,
+		Amount:     amount,
+		CreatedAt:  time.Now(),
+	}
+	if err := rs.store.Orders().AddReturn(ctx, order.ID, len(order.Returns), ret); err != nil {
+		if err == store.ErrStatusConflict {
+			return nil, nil, ErrReturnConflict
+		}
+		return nil, nil, errors.New("lỗi khi tạo yêu cầu trả hàng")
+	}
+
+	updated, err := rs.store.Orders().FindByID(ctx, order.ID)
+	if err != nil {
+		return nil, nil, errors.New("lỗi khi lấy đơn hàng đã cập nhật")
+	}
+	return updated, &ret, nil
+}
+
+// ApproveReturn duyệt yêu cầu trả hàng, khách có thể gửi hàng về
+func (rs *ReturnService) ApproveReturn(orderID, returnID string, actor OrderActor, note string) (*models.Order, error) {
+	return rs.decide(orderID, returnID, ReturnStatusApproved, actor, strings.TrimSpace(note))
+}
+
+// RejectReturn từ chối yêu cầu trả hàng, bắt buộc có lý do để báo lại cho khách
+func (rs *ReturnService) RejectReturn(orderID, returnID string, actor OrderActor, note string) (*models.Order, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, &ReturnError{Reason: "lý do từ chối là bắt buộc"}
+	}
+	return rs.decide(orderID, returnID, ReturnStatusRejected, actor, note)
+}
+
+func (rs *ReturnService) decide(orderID, returnID, to string, actor OrderActor, note string) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, ret, err := rs.findReturn(ctx, orderID, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != ReturnStatusRequested {
+		return nil, &ReturnError{Reason: fmt.Sprintf("yêu cầu trả hàng đang ở trạng thái %s, không thể duyệt hoặc từ chối", ret.Status)}
+	}
+
+	now := time.Now()
+	ret.Status, ret.AdminNote, ret.DecidedAt, ret.DecidedBy = to, note, &now, actor.ID
+	if err := rs.updateReturn(ctx, order, ReturnStatusRequested, *ret, nil); err != nil {
+		return nil, err
+	}
+	return rs.reload(ctx, order.ID)
+}
+
+// ReceiveReturn ghi nhận đã nhận hàng trả về và nhập lại kho
+func (rs *ReturnService) ReceiveReturn(orderID, returnID string, actor OrderActor) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, ret, err := rs.findReturn(ctx, orderID, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != ReturnStatusApproved {
+		return nil, &ReturnError{Reason: "chỉ có thể nhận hàng của yêu cầu trả hàng đã được duyệt"}
+	}
+
+	requested := make([]models.RefundStockItem, len(ret.Items))
+	for i, item := range ret.Items {
+		requested[i] = models.RefundStockItem{ProductSKU: item.ProductSKU, ProductName: item.ProductName, Quantity: item.Quantity}
+	}
+	restock, err := restockItems(order, requested)
+	if err != nil {
+		return nil, &ReturnError{Reason: err.Error()}
+	}
+
+	now := time.Now()
+	ret.Status, ret.ReceivedAt = ReturnStatusReceived, &now
+	err = runAtomic(ctx, rs.store, func(ctx context.Context, rb *rollback) error {
+		items, err := rs.refunds.restock(ctx, rb, order, restock)
+		if err != nil {
+			return err
+		}
+		return rs.updateReturn(ctx, order, ReturnStatusApproved, *ret, bson.M{"items": items})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rs.reload(ctx, order.ID)
+}
+
+// RefundReturn hoàn tiền cho hàng đã nhận về. amount nil = giá trị hàng trả, tối đa bằng số tiền còn có thể hoàn.
+func (rs *ReturnService) RefundReturn(orderID, returnID string, actor OrderActor, amount *models.Money) (*models.Order, *models.OrderRefund, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, ret, err := rs.findReturn(ctx, orderID, returnID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ret.Status != ReturnStatusReceived {
+		return nil, nil, &ReturnError{Reason: "chỉ có thể hoàn tiền cho yêu cầu trả hàng đã nhận hàng"}
+	}
+
+	// Lần trước đã hoàn tiền nhưng chưa cập nhật được trạng thái yêu cầu: không hoàn lần hai
+	refund := findReturnRefund(order, ret.ID)
+	if refund == nil {
+		if amount == nil {
+			value := ret.Amount
+			if remaining := RefundableAmount(order); value.Cmp(remaining) > 0 {
+				value = remaining
+			}
+			amount = &value
+		}
+		_, refund, err = rs.refunds.RefundOrder(order.ID.Hex(), RefundRequest{
+			Amount:   amount,
+			Reason:   fmt.Sprintf("Trả hàng %s: %s", ret.ID, ret.Reason),
+			ReturnID: ret.ID,
+		}, actor)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	ret.Status, ret.RefundID, ret.RefundedAt = ReturnStatusRefunded, refund.ID, &now
+	if err := rs.updateReturn(ctx, order, ReturnStatusReceived, *ret, nil); err != nil {
+		log.Printf("Refund %s issued for return %s but return status update failed: %v", refund.ID, ret.ID, err)
+		return nil, nil, err
+	}
+
+	updated, err := rs.reload(ctx, order.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, refund, nil
+}
+
+func (rs *ReturnService) findOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+	order, err := rs.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+	return order, nil
+}
+
+func (rs *ReturnService) findReturn(ctx context.Context, orderID, returnID string) (*models.Order, *models.OrderReturn, error) {
+	order, err := rs.findOrder(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range order.Returns {
+		if order.Returns[i].ID == returnID {
+			ret := order.Returns[i]
+			return order, &ret, nil
+		}
+	}
+	return nil, nil, ErrReturnNotFound
+}
+
+func (rs *ReturnService) updateReturn(ctx context.Context, order *models.Order, from string, ret models.OrderReturn, fields bson.M) error {
+	if err := rs.store.Orders().UpdateReturn(ctx, order.ID, from, ret, fields); err != nil {
+		if err == store.ErrStatusConflict {
+			return ErrReturnConflict
+		}
+		return errors.New("lỗi khi cập nhật yêu cầu trả hàng")
+	}
+	return nil
+}
+
+func (rs *ReturnService) reload(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	order, err := rs.store.Orders().FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("lỗi khi lấy đơn hàng đã cập nhật")
+	}
+	return order, nil
+}
+
+func findReturnRefund(order *models.Order, returnID string) *models.OrderRefund {
+	for i := range order.Refunds {
+		status := order.Refunds[i].Status
+		if order.Refunds[i].ReturnID == returnID && status != RefundStatusPending && status != RefundStatusFailed {
+			return &order.Refunds[i]
+		}
+	}
+	return nil
+}
+
+// validateReturnPhotos chỉ nhận URL http(s) của ảnh đã tải lên
+func validateReturnPhotos(photos []string) ([]string, error) {
+	if len(photos) > MaxReturnPhotos {
+		return nil, &ReturnError{Reason: fmt.Sprintf("chỉ được gửi tối đa %d ảnh", MaxReturnPhotos)}
+	}
+	var valid []string
+	for _, photo := range photos {
+		photo = strings.TrimSpace(photo)
+		u, err := url.Parse(photo)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, &ReturnError{Reason: "đường dẫn ảnh không hợp lệ"}
+		}
+		valid = append(valid, photo)
+	}
+	return valid, nil
+}
+
+// returnItems kiểm tra số lượng trả của từng dòng hàng (không vượt quá số đã mua trừ các yêu cầu
+// chưa bị từ chối) và tính giá trị hàng trả theo giá thực trả của dòng hàng
+func returnItems(order *models.Order, requested []models.ReturnItem) ([]models.ReturnItem, models.Money, error) {
+	amount := models.Money{Currency: order.TotalAmount.Currency}
+	if len(requested) == 0 {
+		return nil, amount, &ReturnError{Reason: "vui lòng chọn sản phẩm cần trả"}
+	}
+
+	returned := make(map[string]int)
+	for _, ret := range order.Returns {
+		if ret.Status == ReturnStatusRejected {
+			continue
+		}
+		for _, item := range ret.Items {
+			returned[item.ProductSKU] += item.Quantity
+		}
+	}
+
+	quantities := make(map[string]int)
+	for _, item := range requested {
+		if item.Quantity <= 0 {
+			return nil, amount, &ReturnError{Reason: "số lượng trả phải lớn hơn 0"}
+		}
+		quantities[item.ProductSKU] += item.Quantity
+	}
+
+	var items []models.ReturnItem
+	for _, line := range order.Items {
+		quantity := quantities[line.ProductSKU]
+		if quantity == 0 {
+			continue
+		}
+		delete(quantities, line.ProductSKU)
+
+		// Sản phẩm có thể nằm trên nhiều dòng hàng (vd một phần mua theo flash sale)
+		bought := 0
+		for _, other := range order.Items {
+			if other.ProductSKU == line.ProductSKU {
+				bought += other.Quantity
+			}
+		}
+		if available := bought - returned[line.ProductSKU]; quantity > available {
+			return nil, amount, &ReturnError{Reason: fmt.Sprintf("sản phẩm %s chỉ còn %d có thể trả", line.ProductName, available)}
+		}
+
+		left := quantity
+		for _, other := range order.Items {
+			if other.ProductSKU != line.ProductSKU || left == 0 {
+				continue
+			}
+			// Bỏ qua phần đã được trả ở các yêu cầu trước
+			skip := min(returned[line.ProductSKU], other.Quantity)
+			returned[line.ProductSKU] -= skip
+			n := min(left, other.Quantity-skip)
+			amount = amount.Add(returnLineValue(other, n))
+			left -= n
+		}
+		items = append(items, models.ReturnItem{ProductSKU: line.ProductSKU, ProductName: line.ProductName, Quantity: quantity})
+	}
+	for sku := range quantities {
+		return nil, amount, &ReturnError{Reason: fmt.Sprintf("sản phẩm %s không có trong đơn hàng", sku)}
+	}
+	return items, amount, nil
+}
+
+// returnLineValue là số tiền khách đã trả cho quantity sản phẩm của dòng hàng (sau giảm giá, kèm VAT cộng thêm)
+func returnLineValue(item models.OrderItem, quantity int) models.Money {
+	paid := item.Total.Sub(item.Discount)
+	if item.Tax != nil && !item.Tax.Inclusive {
+		paid = paid.Add(item.Tax.Amount)
+	}
+	if item.Quantity == 0 {
+		return models.Money{Currency: paid.Currency}
+	}
+	return models.Money{Amount: paid.Amount * int64(quantity) / int64(item.Quantity), Currency: paid.Currency}
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+func newTestReturnService(st store.Store) *ReturnService {
+	return NewReturnService(st, 7*24*time.Hour, NewRefundService(st, NewLocalPaymentProvider("test-secret")))
+}
+
+func TestReturnService_FullFlow(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	order := createTestOrder(t, st, user.ID, deliveredOrder, func(o *models.Order) {
+		o.Items[0].ProductSKU = product.ProductID
+		o.Items[0].Discount = models.VND(20000)
+		o.TotalAmount = models.VND(180000)
+	})
+	rs := newTestReturnService(st)
+
+	_, ret, err := rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{
+		Items:  []models.ReturnItem{{ProductSKU: product.ProductID, Quantity: 1}},
+		Reason: "hộp bị móp",
+		Photos: []string{"https://cdn.example.com/returns/1.jpg"},
+	})
+	mustNoError(t, err)
+	if ret.Status != ReturnStatusRequested || ret.Amount != models.VND(90000) || ret.ID != order.OrderNumber+"-R1" {
+		t.Fatalf("unexpected return %+v", ret)
+	}
+
+	_, err = rs.ReceiveReturn(order.ID.Hex(), ret.ID, refundAdmin)
+	expectError(t, err, "đã được duyệt")
+
+	approved, err := rs.ApproveReturn(order.ID.Hex(), ret.ID, refundAdmin, "gửi hàng về kho Q7")
+	mustNoError(t, err)
+	if approved.Returns[0].Status != ReturnStatusApproved || approved.Returns[0].DecidedBy != "admin-1" {
+		t.Fatalf("expected approved return, got %+v", approved.Returns[0])
+	}
+
+	received, err := rs.ReceiveReturn(order.ID.Hex(), ret.ID, refundAdmin)
+	mustNoError(t, err)
+	if received.Returns[0].Status != ReturnStatusReceived || received.Items[0].RestockedQuantity != 1 {
+		t.Fatalf("expected received return and restocked item, got %+v %+v", received.Returns[0], received.Items[0])
+	}
+	stocked, err := st.Products().FindByProductID(context.Background(), product.ProductID)
+	mustNoError(t, err)
+	if stocked.Amount != product.Amount+1 {
+		t.Fatalf("expected stock %d, got %d", product.Amount+1, stocked.Amount)
+	}
+
+	refunded, refund, err := rs.RefundReturn(order.ID.Hex(), ret.ID, refundAdmin, nil)
+	mustNoError(t, err)
+	if refund.Amount != models.VND(90000) || refund.ReturnID != ret.ID {
+		t.Fatalf("unexpected refund %+v", refund)
+	}
+	if refunded.Returns[0].Status != ReturnStatusRefunded || refunded.Returns[0].RefundID != refund.ID {
+		t.Fatalf("expected refunded return, got %+v", refunded.Returns[0])
+	}
+	if refunded.Payment.Status != PaymentStatusPartiallyRefunded || refunded.Status != OrderStatusDelivered {
+		t.Fatalf("expected partially refunded delivered order, got %s %+v", refunded.Status, refunded.Payment)
+	}
+
+	_, _, err = rs.RefundReturn(order.ID.Hex(), ret.ID, refundAdmin, nil)
+	expectError(t, err, "đã nhận hàng")
+}
+
+func TestReturnService_RefundRecoversRecordedRefund(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, deliveredOrder)
+	rs := newTestReturnService(st)
+
+	_, ret, err := rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{
+		Items:  []models.ReturnItem{{ProductSKU: "TEST-001", Quantity: 2}},
+		Reason: "sai hàng",
+	})
+	mustNoError(t, err)
+
+	// Hàng đã nhận và tiền đã hoàn nhưng trạng thái yêu cầu chưa được cập nhật
+	now := time.Now()
+	ret.Status, ret.ReceivedAt = ReturnStatusReceived, &now
+	mustNoError(t, st.Orders().UpdateReturn(context.Background(), order.ID, ReturnStatusRequested, *ret, nil))
+	_, first, err := rs.refunds.RefundOrder(order.ID.Hex(), RefundRequest{Reason: "sai hàng", ReturnID: ret.ID}, refundAdmin)
+	mustNoError(t, err)
+
+	updated, refund, err := rs.RefundReturn(order.ID.Hex(), ret.ID, refundAdmin, nil)
+	mustNoError(t, err)
+	if refund.ID != first.ID || len(updated.Refunds) != 1 || updated.Returns[0].Status != ReturnStatusRefunded {
+		t.Fatalf("expected existing refund reused, got %+v %+v", refund, updated.Refunds)
+	}
+}
+
+func TestReturnService_RequestValidation(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	other := createTestUser(t, st)
+	rs := newTestReturnService(st)
+	one := []models.ReturnItem{{ProductSKU: "TEST-001", Quantity: 1}}
+
+	pending := createTestOrder(t, st, user.ID)
+	_, _, err := rs.RequestReturn(pending.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: one, Reason: "x"})
+	expectError(t, err, "đơn hàng đã giao")
+
+	expired := createTestOrder(t, st, user.ID, deliveredOrder, func(o *models.Order) {
+		deliveredAt := time.Now().Add(-8 * 24 * time.Hour)
+		o.DeliveredAt = &deliveredAt
+	})
+	_, _, err = rs.RequestReturn(expired.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: one, Reason: "x"})
+	expectError(t, err, "quá thời hạn trả hàng")
+
+	order := createTestOrder(t, st, user.ID, deliveredOrder)
+	_, _, err = rs.RequestReturn(order.ID.Hex(), other.ID.Hex(), ReturnRequest{Items: one, Reason: "x"})
+	expectError(t, err, "đơn hàng không tồn tại")
+	_, _, err = rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: one})
+	expectError(t, err, "lý do trả hàng là bắt buộc")
+	_, _, err = rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: one, Reason: "x", Photos: []string{"javascript:alert(1)"}})
+	expectError(t, err, "đường dẫn ảnh không hợp lệ")
+	_, _, err = rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: []models.ReturnItem{{ProductSKU: "OTHER", Quantity: 1}}, Reason: "x"})
+	expectError(t, err, "không có trong đơn hàng")
+	_, _, err = rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: []models.ReturnItem{{ProductSKU: "TEST-001", Quantity: 3}}, Reason: "x"})
+	expectError(t, err, "chỉ còn 2 có thể trả")
+
+	// Số lượng đã yêu cầu trả không được yêu cầu lại, trừ khi yêu cầu bị từ chối
+	_, first, err := rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: []models.ReturnItem{{ProductSKU: "TEST-001", Quantity: 2}}, Reason: "x"})
+	mustNoError(t, err)
+	_, _, err = rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: one, Reason: "x"})
+	expectError(t, err, "chỉ còn 0 có thể trả")
+
+	_, err = rs.RejectReturn(order.ID.Hex(), first.ID, refundAdmin, "")
+	expectError(t, err, "lý do từ chối là bắt buộc")
+	rejected, err := rs.RejectReturn(order.ID.Hex(), first.ID, refundAdmin, "hàng đã qua sử dụng")
+	mustNoError(t, err)
+	if rejected.Returns[0].Status != ReturnStatusRejected || rejected.Returns[0].AdminNote != "hàng đã qua sử dụng" {
+		t.Fatalf("expected rejected return, got %+v", rejected.Returns[0])
+	}
+	_, second, err := rs.RequestReturn(order.ID.Hex(), user.ID.Hex(), ReturnRequest{Items: one, Reason: "x"})
+	mustNoError(t, err)
+	if second.ID != order.OrderNumber+"-R2" {
+		t.Fatalf("expected second return id, got %s", second.ID)
+	}
+
+	// Admin lọc đơn có yêu cầu trả hàng đang chờ xử lý
+	orders, err := NewOrderService(st).GetAllOrders(1, 10, map[string]interface{}{"return_status": ReturnStatusRequested})
+	mustNoError(t, err)
+	if len(orders.Orders) != 1 || orders.Orders[0].ID != order.ID {
+		t.Fatalf("expected only order with pending return, got %d orders", len(orders.Orders))
+	}
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/store"
+)
+
+// ShipmentController handles carrier shipment HTTP requests (admin)
+type ShipmentController struct {
+	shipmentService *ShipmentService
+}
+
+// NewShipmentController creates a new shipment controller instance
+func NewShipmentController(st store.Store) *ShipmentController {
+	return &ShipmentController{
+		shipmentService: NewShipmentService(st, carriersFromEnv()...),
+	}
+}
+
+// CreateShipmentRequest là body admin gửi khi tạo vận đơn (có thể rỗng nếu chỉ kết nối một carrier)
+type CreateShipmentRequest struct {
+	Carrier string `json:"carrier"`
+}
+
+// QuoteShipment báo phí vận chuyển của các carrier cho đơn hàng
+func (sc *ShipmentController) QuoteShipment(c *gin.Context) {
+	rates, err := sc.shipmentService.QuoteRates(c.Param("id"))
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Lấy báo giá vận chuyển thành công",
+		"data":    rates,
+	})
+}
+
+// CreateShipment tạo vận đơn cho đơn hàng, trả về mã vận đơn và tem PDF (base64)
+func (sc *ShipmentController) CreateShipment(c *gin.Context) {
+	var req CreateShipmentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Dữ liệu không hợp lệ",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	result, err := sc.shipmentService.CreateShipment(c.Param("id"), req.Carrier, orderActorFromContext(c))
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	message := "Tạo vận đơn thành công"
+	if len(result.Label) == 0 {
+		message = "Tạo vận đơn thành công, chưa lấy được tem vận đơn, vui lòng tải lại sau"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": message,
+		"data": gin.H{
+			"order":           result.Order,
+			"tracking_number": result.TrackingNumber,
+			"label_pdf":       result.Label, // base64
+		},
+	})
+}
+
+// GetShipmentLabel tải tem vận đơn PDF của đơn hàng
+func (sc *ShipmentController) GetShipmentLabel(c *gin.Context) {
+	order, label, err := sc.shipmentService.Label(c.Param("id"))
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s-%s.pdf"`, order.OrderNumber, order.Shipment.TrackingNumber))
+	c.Data(http.StatusOK, "application/pdf", label)
+}
+
+func respondShipmentError(c *gin.Context, err error) {
+	var shipmentErr *ShipmentError
+	switch {
+	case errors.As(err, &shipmentErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case err.Error() == "đơn hàng không tồn tại":
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case err == ErrShipmentConflict:
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	case errors.Is(err, ErrCarrierRequest):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+		})
+	}
+}
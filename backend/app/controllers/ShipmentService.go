@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultShipmentPollInterval = 10 * time.Minute
+
+// ErrShipmentConflict được trả về khi đơn hàng thay đổi trong lúc đang tạo vận đơn
+var ErrShipmentConflict = errors.New("đơn hàng vừa được cập nhật bởi request khác, vui lòng thử lại")
+
+// ShipmentError được trả về khi đơn hàng không thể tạo vận đơn
+type ShipmentError struct {
+	Reason string
+}
+
+func (e *ShipmentError) Error() string {
+	return e.Reason
+}
+
+// ShipmentResult là kết quả tạo vận đơn; Label rỗng nếu carrier chưa trả được tem (tải lại sau)
+type ShipmentResult struct {
+	Order          *models.Order
+	TrackingNumber string
+	Label          []byte
+}
+
+// shipmentActor là actor của các thay đổi trạng thái do carrier báo về
+var shipmentActor = OrderActor{ID: "carrier", Role: ActorSystem}
+
+// ShipmentService tạo vận đơn qua API của carrier và đồng bộ trạng thái đơn hàng theo hành trình vận đơn
+type ShipmentService struct {
+	store    store.Store
+	orders   *OrderService
+	carriers map[string]Carrier
+}
+
+// NewShipmentService creates a new shipment service with the given carriers
+func NewShipmentService(st store.Store, carriers ...Carrier) *ShipmentService {
+	byCode := make(map[string]Carrier, len(carriers))
+	for _, carrier := range carriers {
+		byCode[carrier.Code()] = carrier
+	}
+	return &ShipmentService{
+		store:    st,
+		orders:   NewOrderService(st),
+		carriers: byCode,
+	}
+}
+
+// shipmentPollIntervalFromEnv đọc SHIPMENT_POLL_INTERVAL (duration của Go, vd "5m"; "0" để tắt job)
+func shipmentPollIntervalFromEnv() time.Duration {
+	value := os.Getenv("SHIPMENT_POLL_INTERVAL")
+	if value == "" {
+		return defaultShipmentPollInterval
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		log.Printf("Invalid SHIPMENT_POLL_INTERVAL %q, using %s", value, defaultShipmentPollInterval)
+		return defaultShipmentPollInterval
+	}
+	return interval
+}
+
+// StartShipmentPolling chạy job đồng bộ vận đơn với các carrier cấu hình trong CARRIERS
+func StartShipmentPolling(ctx context.Context, st store.Store) {
+	NewShipmentService(st, carriersFromEnv()...).StartPolling(ctx, shipmentPollIntervalFromEnv())
+}
+
+// carrier trả về carrier theo code; code rỗng chỉ hợp lệ khi cấu hình đúng một carrier
+func (ss *ShipmentService) carrier(code string) (Carrier, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" && len(ss.carriers) == 1 {
+		for _, carrier := range ss.carriers {
+			return carrier, nil
+		}
+	}
+	carrier, ok := ss.carriers[code]
+	if !ok {
+		return nil, &ShipmentError{Reason: "đơn vị vận chuyển chưa được kết nối"}
+	}
+	return carrier, nil
+}
+
+func (ss *ShipmentService) findOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+	order, err := ss.store.Orders().FindByID(ctx, objectID)
+	if err != nil {
+		return nil, errors.New("đơn hàng không tồn tại")
+	}
+	return order, nil
+}
+
+// shipmentRequest tạo kiện hàng từ đơn: khối lượng lấy theo sản phẩm hiện tại, đơn COD chưa thanh toán thì carrier thu hộ
+func (ss *ShipmentService) shipmentRequest(ctx context.Context, order *models.Order) ShipmentRequest {
+	req := ShipmentRequest{
+		OrderNumber:   order.OrderNumber,
+		Recipient:     order.ShippingAddress,
+		DeclaredValue: order.TotalAmount,
+		CODAmount:     models.VND(0),
+	}
+	for _, item := range order.Items {
+		weight := 0
+		if product, err := ss.store.Products().FindByProductID(ctx, item.ProductSKU); err == nil {
+			weight = product.Weight
+		}
+		if weight <= 0 {
+			weight = defaultItemWeight
+		}
+		req.WeightGrams += weight * item.Quantity
+		req.Items = append(req.Items, ShipmentItem{Name: item.ProductName, Quantity: item.Quantity})
+	}
+	if order.Payment.Method == "cash_on_delivery" && order.Payment.Status == PaymentStatusPending {
+		req.CODAmount = order.TotalAmount
+	}
+	return req
+}
+
+// QuoteRates báo phí của tất cả carrier cho đơn hàng, rẻ nhất trước. Carrier lỗi được bỏ qua.
+func (ss *ShipmentService) QuoteRates(orderID string) ([]CarrierRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, err := ss.findOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ss.carriers) == 0 {
+		return nil, &ShipmentError{Reason: "chưa kết nối đơn vị vận chuyển nào"}
+	}
+
+	req := ss.shipmentRequest(ctx, order)
+	var rates []CarrierRate
+	var lastErr error
+	for _, carrier := range ss.carriers {
+		rate, err := carrier.QuoteRate(ctx, req)
+		if err != nil {
+			log.Printf("Quote rate from %s for order %s failed: %v", carrier.Code(), order.OrderNumber, err)
+			lastErr = err
+			continue
+		}
+		rates = append(rates, *rate)
+	}
+	if len(rates) == 0 {
+		return nil, lastErr
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Fee.Cmp(rates[j].Fee) < 0 })
+	return rates, nil
+}
+
+// CreateShipment tạo vận đơn cho đơn đã xác nhận, gắn vào đơn (kèm tracking cho khách) và lấy tem PDF.
+// Đơn "confirmed" được chuyển sang "processing" vì hàng đã chờ carrier tới lấy.
+func (ss *ShipmentService) CreateShipment(orderID, carrierCode string, actor OrderActor) (*ShipmentResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	order, err := ss.findOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	status := normalizeOrderStatus(order.Status)
+	if status != OrderStatusConfirmed && status != OrderStatusProcessing {
+		return nil, &ShipmentError{Reason: "chỉ có thể tạo vận đơn cho đơn hàng đã xác nhận và chưa giao cho vận chuyển"}
+	}
+	if order.Shipment != nil {
+		return nil, &ShipmentError{Reason: "đơn hàng đã có vận đơn " + order.Shipment.TrackingNumber}
+	}
+	carrier, err := ss.carrier(carrierCode)
+	if err != nil {
+		return nil, err
+	}
+
+	req := ss.shipmentRequest(ctx, order)
+	created, err := carrier.CreateShipment(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	shipment := models.Shipment{
+		Carrier:        carrier.Code(),
+		TrackingNumber: created.TrackingNumber,
+		Service:        created.Service,
+		Fee:            created.Fee,
+		CODAmount:      req.CODAmount,
+		Status:         ShipmentStatusCreated,
+		CreatedAt:      now,
+	}
+	tracking := models.Tracking{Carrier: carrier.Code(), TrackingNumber: created.TrackingNumber}
+	if tc, ok := ss.orders.carriers[carrier.Code()]; ok {
+		tracking.TrackingURL = tc.URL(created.TrackingNumber)
+	}
+	if err := ss.store.Orders().AttachShipment(ctx, order.ID, order.Status, shipment, tracking); err != nil {
+		// Vận đơn đã tạo bên carrier nhưng không gắn được vào đơn: cần hủy thủ công
+		log.Printf("Shipment %s (%s) created but not attached to order %s: %v", created.TrackingNumber, carrier.Code(), order.OrderNumber, err)
+		if err == store.ErrStatusConflict {
+			return nil, ErrShipmentConflict
+		}
+		return nil, errors.New("lỗi khi lưu vận đơn")
+	}
+
+	if status == OrderStatusConfirmed {
+		reason := fmt.Sprintf("Tạo vận đơn %s %s", carrier.Code(), created.TrackingNumber)
+		if _, err := ss.orders.UpdateOrderStatus(orderID, OrderStatusProcessing, actor, reason); err != nil {
+			log.Printf("Move order %s to processing after creating shipment failed: %v", order.OrderNumber, err)
+		}
+	}
+
+	result := &ShipmentResult{TrackingNumber: created.TrackingNumber}
+	if result.Label, err = carrier.PrintLabel(ctx, created.TrackingNumber); err != nil {
+		log.Printf("Print label %s for order %s failed: %v", created.TrackingNumber, order.OrderNumber, err)
+	}
+	if result.Order, err = ss.store.Orders().FindByID(ctx, order.ID); err != nil {
+		return nil, errors.New("lỗi khi lấy đơn hàng đã cập nhật")
+	}
+	return result, nil
+}
+
+// Label tải tem PDF của vận đơn đã gắn với đơn hàng
+func (ss *ShipmentService) Label(orderID string) (*models.Order, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	order, err := ss.findOrder(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Shipment == nil {
+		return nil, nil, &ShipmentError{Reason: "đơn hàng chưa có vận đơn"}
+	}
+	carrier, err := ss.carrier(order.Shipment.Carrier)
+	if err != nil {
+		return nil, nil, err
+	}
+	label, err := carrier.PrintLabel(ctx, order.Shipment.TrackingNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, label, nil
+}
+
+// StartPolling đồng bộ vận đơn định kỳ cho tới khi ctx bị hủy. Không làm gì nếu chưa kết nối carrier.
+func (ss *ShipmentService) StartPolling(ctx context.Context, interval time.Duration) {
+	if len(ss.carriers) == 0 || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if updated, err := ss.PollOnce(ctx); err != nil {
+					log.Printf("Shipment polling failed: %v", err)
+				} else if updated > 0 {
+					log.Printf("Shipment polling updated %d orders", updated)
+				}
+			}
+		}
+	}()
+}
+
+// PollOnce lấy trạng thái vận đơn của các đơn đang xử lý/đang giao và chuyển trạng thái đơn theo carrier.
+// Trả về số đơn có vận đơn thay đổi; lỗi của từng vận đơn chỉ được log để không chặn các đơn khác.
+func (ss *ShipmentService) PollOnce(ctx context.Context) (int, error) {
+	updated := 0
+	for _, status := range []string{OrderStatusProcessing, OrderStatusShipped} {
+		orders, err := ss.store.Orders().List(ctx, store.OrderFilter{Status: status, HasShipment: true}, store.ListOptions{})
+		if err != nil {
+			return updated, err
+		}
+		for i := range orders {
+			changed, err := ss.syncShipment(ctx, &orders[i])
+			if err != nil {
+				log.Printf("Sync shipment of order %s failed: %v", orders[i].OrderNumber, err)
+				continue
+			}
+			if changed {
+				updated++
+			}
+		}
+	}
+	return updated, nil
+}
+
+// syncShipment lưu hành trình mới nhất của vận đơn và chuyển đơn sang shipped/delivered khi carrier đã lấy/giao hàng.
+// Giao thất bại, hoàn hàng hay hủy vận đơn chỉ được ghi nhận để admin xử lý.
+func (ss *ShipmentService) syncShipment(ctx context.Context, order *models.Order) (bool, error) {
+	current := order.Shipment
+	carrier, ok := ss.carriers[current.Carrier]
+	if !ok {
+		return false, nil
+	}
+	status, err := carrier.FetchStatus(ctx, current.TrackingNumber)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	shipment := *current
+	shipment.Status = status.Status
+	shipment.Events = status.Events
+	shipment.CheckedAt = &now
+	if err := ss.store.Orders().UpdateShipment(ctx, order.ID, current.TrackingNumber, shipment); err != nil {
+		return false, err
+	}
+	changed := status.Status != current.Status || len(status.Events) != len(current.Events)
+
+	var targets []string
+	orderStatus := normalizeOrderStatus(order.Status)
+	switch status.Status {
+	case ShipmentStatusPickedUp, ShipmentStatusInTransit:
+		if orderStatus == OrderStatusProcessing {
+			targets = []string{OrderStatusShipped}
+		}
+	case ShipmentStatusDelivered:
+		if orderStatus == OrderStatusProcessing {
+			targets = []string{OrderStatusShipped, OrderStatusDelivered}
+		} else if orderStatus == OrderStatusShipped {
+			targets = []string{OrderStatusDelivered}
+		}
+	case ShipmentStatusFailed, ShipmentStatusReturned, ShipmentStatusCancelled:
+		if changed {
+			log.Printf("Shipment %s of order %s is %s, needs attention", current.TrackingNumber, order.OrderNumber, status.Status)
+		}
+	}
+
+	reason := fmt.Sprintf("%s: %s", carrier.Code(), status.Status)
+	if n := len(status.Events); n > 0 && status.Events[n-1].Description != "" {
+		reason = fmt.Sprintf("%s: %s", carrier.Code(), status.Events[n-1].Description)
+	}
+	for _, target := range targets {
+		if _, err := ss.orders.UpdateOrderStatus(order.ID.Hex(), target, shipmentActor, reason); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
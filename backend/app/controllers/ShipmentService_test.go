@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+func newTestCarrier(t *testing.T, code string) (*MockCarrierServer, Carrier) {
+	t.Helper()
+	mock := NewMockCarrierServer("carrier-token")
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return mock, NewHTTPCarrier(CarrierConfig{Code: code, BaseURL: server.URL, Token: "carrier-token"})
+}
+
+func confirmedOrder(o *models.Order) {
+	o.Status = OrderStatusConfirmed
+	o.ShippingAddress = models.ShippingAddress{FullName: "Nguyễn Văn A", Phone: "0901234567", Street: "12 Lý Thường Kiệt", City: "Hà Nội"}
+}
+
+func TestShipmentService_CreateShipment(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	order := createTestOrder(t, st, user.ID, confirmedOrder)
+	mock, carrier := newTestCarrier(t, "ghn")
+	ss := NewShipmentService(st, carrier)
+
+	rates, err := ss.QuoteRates(order.ID.Hex())
+	mustNoError(t, err)
+	// 2 sản phẩm chưa khai báo khối lượng = 1000g
+	if len(rates) != 1 || rates[0].Carrier != "ghn" || rates[0].Fee.Amount != 27000 {
+		t.Fatalf("unexpected rates %+v", rates)
+	}
+
+	result, err := ss.CreateShipment(order.ID.Hex(), "", trackingAdmin)
+	mustNoError(t, err)
+	if !bytes.HasPrefix(result.Label, []byte("%PDF-")) {
+		t.Fatalf("expected PDF label, got %q", result.Label)
+	}
+	created := result.Order
+	if created.Status != OrderStatusProcessing || created.Shipment == nil || created.Shipment.TrackingNumber != result.TrackingNumber {
+		t.Fatalf("expected processing order with shipment, got %s %+v", created.Status, created.Shipment)
+	}
+	if created.Shipment.CODAmount.Amount != order.TotalAmount.Amount || created.Tracking == nil ||
+		created.Tracking.TrackingURL != "https://donhang.ghn.vn/?order_code="+result.TrackingNumber {
+		t.Fatalf("unexpected shipment %+v tracking %+v", created.Shipment, created.Tracking)
+	}
+
+	_, err = ss.CreateShipment(order.ID.Hex(), "ghn", trackingAdmin)
+	expectError(t, err, "đơn hàng đã có vận đơn")
+
+	// Carrier lấy hàng rồi giao thành công: đơn COD được đánh dấu đã thanh toán
+	mustNoError(t, mock.Advance(result.TrackingNumber, ShipmentStatusPickedUp, "Đã lấy hàng"))
+	updated, err := ss.PollOnce(context.Background())
+	mustNoError(t, err)
+	shipped, _ := st.Orders().FindByID(context.Background(), order.ID)
+	if updated != 1 || shipped.Status != OrderStatusShipped || shipped.ShippedAt == nil || len(shipped.Shipment.Events) != 2 {
+		t.Fatalf("expected shipped order, got %d %s %+v", updated, shipped.Status, shipped.Shipment)
+	}
+
+	mustNoError(t, mock.Advance(result.TrackingNumber, ShipmentStatusDelivered, "Giao hàng thành công"))
+	_, err = ss.PollOnce(context.Background())
+	mustNoError(t, err)
+	delivered, _ := st.Orders().FindByID(context.Background(), order.ID)
+	if delivered.Status != OrderStatusDelivered || delivered.Payment.Status != PaymentStatusPaid || delivered.Shipment.Status != ShipmentStatusDelivered {
+		t.Fatalf("expected delivered and paid order, got %s %s", delivered.Status, delivered.Payment.Status)
+	}
+	last := delivered.StatusHistory[len(delivered.StatusHistory)-1]
+	if last.ActorRole != ActorSystem || last.Reason != "ghn: Giao hàng thành công" {
+		t.Fatalf("unexpected status entry %+v", last)
+	}
+
+	// Không còn đơn cần đồng bộ
+	updated, err = ss.PollOnce(context.Background())
+	mustNoError(t, err)
+	if updated != 0 {
+		t.Fatalf("expected nothing to poll, got %d", updated)
+	}
+}
+
+func TestShipmentService_CreateShipmentValidation(t *testing.T) {
+	st := newTestStore()
+	user := createTestUser(t, st)
+	mock, carrier := newTestCarrier(t, "ghn")
+	ss := NewShipmentService(st, carrier)
+
+	pending := createTestOrder(t, st, user.ID)
+	_, err := ss.CreateShipment(pending.ID.Hex(), "ghn", trackingAdmin)
+	expectError(t, err, "chỉ có thể tạo vận đơn")
+
+	order := createTestOrder(t, st, user.ID, confirmedOrder)
+	_, err = ss.CreateShipment(order.ID.Hex(), "ghtk", trackingAdmin)
+	expectError(t, err, "chưa được kết nối")
+
+	noAddress := createTestOrder(t, st, user.ID, func(o *models.Order) {
+		o.Status = OrderStatusConfirmed
+		o.ShippingAddress.Street = ""
+	})
+	_, err = ss.CreateShipment(noAddress.ID.Hex(), "ghn", trackingAdmin)
+	if !errors.Is(err, ErrCarrierRequest) || !strings.Contains(err.Error(), "thiếu thông tin người nhận") {
+		t.Fatalf("expected carrier rejection, got %v", err)
+	}
+
+	// Sai token
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	wrongToken := NewShipmentService(st, NewHTTPCarrier(CarrierConfig{Code: "ghn", BaseURL: server.URL, Token: "wrong"}))
+	_, err = wrongToken.CreateShipment(order.ID.Hex(), "ghn", trackingAdmin)
+	if !errors.Is(err, ErrCarrierRequest) {
+		t.Fatalf("expected ErrCarrierRequest, got %v", err)
+	}
+	if current, _ := st.Orders().FindByID(context.Background(), order.ID); current.Shipment != nil || current.Status != OrderStatusConfirmed {
+		t.Fatalf("expected order untouched, got %s %+v", current.Status, current.Shipment)
+	}
+}
+
+func TestPDFDocument_Bytes(t *testing.T) {
+	doc := NewPDFDocument(PDFA4Width, PDFA4Height)
+	doc.Text(40, 800, 12, true, "Hóa đơn (Đơn hàng)")
+	doc.AddPage()
+	doc.Line(40, 40, 200, 40)
+	pdf := doc.Bytes()
+
+	if !bytes.Contains(pdf, []byte(`(Hoa don \(Don hang\)) Tj`)) || !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Fatalf("unexpected PDF content:\n%s", pdf)
+	}
+
+	// Bảng xref phải trỏ đúng vị trí của từng object
+	start := bytes.LastIndex(pdf, []byte("startxref\n"))
+	xref, err := strconv.Atoi(strings.Fields(string(pdf[start+len("startxref\n"):]))[0])
+	mustNoError(t, err)
+	lines := strings.Split(string(pdf[xref:]), "\n")
+	for i := 1; i <= 8; i++ {
+		offset, err := strconv.Atoi(lines[2+i][:10])
+		mustNoError(t, err)
+		if !bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj", i))) {
+			t.Fatalf("xref entry %d points to %q", i, pdf[offset:offset+10])
+		}
+	}
+}
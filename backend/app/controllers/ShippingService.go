@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+// Phương thức giao hàng
+const (
+	ShippingStandard = "standard"
+	ShippingExpress  = "express"
+)
+
+// Cách tính bậc phí
+const (
+	ShippingBasisWeight = "weight" // theo tổng khối lượng (gram)
+	ShippingBasisItems  = "items"  // theo tổng số món
+)
+
+// defaultItemWeight là khối lượng (gram) dùng cho sản phẩm chưa khai báo weight
+const defaultItemWeight = 500
+
+// defaultShippingProvince dùng khi client không gửi tỉnh/thành (form checkout cũ)
+const defaultShippingProvince = "Ho Chi Minh City"
+
+// ShippingTier là một bậc phí: áp dụng khi tổng khối lượng/số món <= Max (Max = 0 là không giới hạn)
+type ShippingTier struct {
+	Max int          `json:"max"`
+	Fee models.Money `json:"fee"`
+}
+
+// ShippingMethodRate là biểu phí của một phương thức giao hàng trong một vùng
+type ShippingMethodRate struct {
+	Method        string         `json:"method"`
+	Name          string         `json:"name"`
+	Basis         string         `json:"basis"` // "weight" (mặc định) hoặc "items"
+	Tiers         []ShippingTier `json:"tiers"` // sắp xếp tăng dần theo Max
+	FreeThreshold models.Money   `json:"free_threshold"`
+	EstimatedDays string         `json:"estimated_days,omitempty"`
+}
+
+// ShippingZone gom các tỉnh có cùng biểu phí. Zone không có Provinces là zone mặc định.
+type ShippingZone struct {
+	Name      string               `json:"name"`
+	Provinces []string             `json:"provinces"`
+	Methods   []ShippingMethodRate `json:"methods"`
+}
+
+// ShippingConfig cấu hình ShippingService
+type ShippingConfig struct {
+	DefaultProvince string         `json:"default_province"`
+	Zones           []ShippingZone `json:"zones"`
+}
+
+// ShippableItem là một dòng hàng cần giao
+type ShippableItem struct {
+	Weight   int // gram / sản phẩm, 0 = dùng defaultItemWeight
+	Quantity int
+}
+
+// ShippingOption là một lựa chọn giao hàng kèm phí cho giỏ hàng hiện tại
+type ShippingOption struct {
+	Method        string       `json:"method"`
+	Name          string       `json:"name"`
+	Zone          string       `json:"zone"`
+	Fee           models.Money `json:"fee"`
+	Free          bool         `json:"free"`
+	EstimatedDays string       `json:"estimated_days,omitempty"`
+}
+
+// ErrInvalidShippingMethod được trả về khi phương thức giao hàng không có cho tỉnh/giỏ hàng
+var ErrInvalidShippingMethod = errors.New("phương thức giao hàng không hợp lệ")
+
+// ShippingService tính phí giao hàng theo vùng, bậc khối lượng/số món và ngưỡng miễn phí
+type ShippingService struct {
+	config ShippingConfig
+}
+
+// NewShippingService tạo ShippingService với cấu hình cho trước
+func NewShippingService(config ShippingConfig) *ShippingService {
+	if config.DefaultProvince == "" {
+		config.DefaultProvince = defaultShippingProvince
+	}
+	return &ShippingService{config: config}
+}
+
+// DefaultShippingConfig: nội thành TP.HCM, Hà Nội và liên tỉnh
+func DefaultShippingConfig() ShippingConfig {
+	vnd := models.VND
+	return ShippingConfig{
+		DefaultProvince: defaultShippingProvince,
+		Zones: []ShippingZone{
+			{
+				Name:      "hcm",
+				Provinces: []string{"Ho Chi Minh City", "Ho Chi Minh", "Hồ Chí Minh", "TP. Hồ Chí Minh", "TP.HCM", "TP HCM", "HCM", "Sài Gòn"},
+				Methods: []ShippingMethodRate{
+					{Method: ShippingStandard, Name: "Giao hàng tiêu chuẩn", Basis: ShippingBasisWeight, EstimatedDays: "1-2",
+						Tiers: []ShippingTier{{Max: 1000, Fee: vnd(15000)}, {Max: 3000, Fee: vnd(22000)}, {Fee: vnd(30000)}}, FreeThreshold: vnd(500000)},
+					{Method: ShippingExpress, Name: "Giao hàng nhanh", Basis: ShippingBasisWeight, EstimatedDays: "0-1",
+						Tiers: []ShippingTier{{Max: 1000, Fee: vnd(30000)}, {Max: 3000, Fee: vnd(40000)}, {Fee: vnd(55000)}}},
+				},
+			},
+			{
+				Name:      "hanoi",
+				Provinces: []string{"Ha Noi", "Hà Nội", "Hanoi"},
+				Methods: []ShippingMethodRate{
+					{Method: ShippingStandard, Name: "Giao hàng tiêu chuẩn", Basis: ShippingBasisWeight, EstimatedDays: "2-3",
+						Tiers: []ShippingTier{{Max: 1000, Fee: vnd(25000)}, {Max: 3000, Fee: vnd(35000)}, {Fee: vnd(50000)}}, FreeThreshold: vnd(800000)},
+					{Method: ShippingExpress, Name: "Giao hàng nhanh", Basis: ShippingBasisWeight, EstimatedDays: "1-2",
+						Tiers: []ShippingTier{{Max: 1000, Fee: vnd(45000)}, {Max: 3000, Fee: vnd(60000)}, {Fee: vnd(80000)}}},
+				},
+			},
+			{
+				Name: "national",
+				Methods: []ShippingMethodRate{
+					{Method: ShippingStandard, Name: "Giao hàng tiêu chuẩn", Basis: ShippingBasisWeight, EstimatedDays: "3-5",
+						Tiers: []ShippingTier{{Max: 1000, Fee: vnd(30000)}, {Max: 3000, Fee: vnd(45000)}, {Fee: vnd(65000)}}, FreeThreshold: vnd(1000000)},
+					{Method: ShippingExpress, Name: "Giao hàng nhanh", Basis: ShippingBasisWeight, EstimatedDays: "2-3",
+						Tiers: []ShippingTier{{Max: 1000, Fee: vnd(50000)}, {Max: 3000, Fee: vnd(70000)}, {Fee: vnd(95000)}}},
+				},
+			},
+		},
+	}
+}
+
+// shippingConfigFromEnv đọc SHIPPING_RATES (JSON dạng ShippingConfig) và SHIPPING_DEFAULT_PROVINCE,
+// không có thì dùng DefaultShippingConfig
+func shippingConfigFromEnv() ShippingConfig {
+	config := DefaultShippingConfig()
+
+	if value := os.Getenv("SHIPPING_RATES"); value != "" {
+		var custom ShippingConfig
+		if err := json.Unmarshal([]byte(value), &custom); err != nil || len(custom.Zones) == 0 {
+			log.Printf("Invalid SHIPPING_RATES, using default rates: %v", err)
+		} else {
+			if custom.DefaultProvince == "" {
+				custom.DefaultProvince = config.DefaultProvince
+			}
+			config = custom
+		}
+	}
+
+	if value := os.Getenv("SHIPPING_DEFAULT_PROVINCE"); value != "" {
+		config.DefaultProvince = value
+	}
+	return config
+}
+
+// Province trả về tỉnh giao hàng, dùng tỉnh mặc định nếu rỗng
+func (ss *ShippingService) Province(province string) string {
+	if province == "" {
+		return ss.config.DefaultProvince
+	}
+	return province
+}
+
+// ZoneFor tìm vùng giao hàng của tỉnh; nil nếu không có zone nào (kể cả zone mặc định)
+func (ss *ShippingService) ZoneFor(province string) *ShippingZone {
+	province = ss.Province(province)
+	var fallback *ShippingZone
+	for i := range ss.config.Zones {
+		zone := &ss.config.Zones[i]
+		if len(zone.Provinces) == 0 {
+			if fallback == nil {
+				fallback = zone
+			}
+			continue
+		}
+		for _, p := range zone.Provinces {
+			if sameTaxKey(p, province) {
+				return zone
+			}
+		}
+	}
+	return fallback
+}
+
+// Quote trả về các phương thức giao hàng khả dụng cùng phí cho giỏ hàng
+func (ss *ShippingService) Quote(province string, items []ShippableItem, subtotal models.Money) []ShippingOption {
+	options := []ShippingOption{}
+	zone := ss.ZoneFor(province)
+	if zone == nil {
+		return options
+	}
+
+	weight, count := 0, 0
+	for _, item := range items {
+		w := item.Weight
+		if w <= 0 {
+			w = defaultItemWeight
+		}
+		weight += w * item.Quantity
+		count += item.Quantity
+	}
+
+	for _, rate := range zone.Methods {
+		measure := weight
+		if rate.Basis == ShippingBasisItems {
+			measure = count
+		}
+		fee, ok := rate.feeFor(measure)
+		if !ok {
+			continue
+		}
+
+		option := ShippingOption{
+			Method:        rate.Method,
+			Name:          rate.Name,
+			Zone:          zone.Name,
+			Fee:           fee,
+			EstimatedDays: rate.EstimatedDays,
+		}
+		if !rate.FreeThreshold.IsZero() && subtotal.Cmp(rate.FreeThreshold) >= 0 {
+			option.Fee = models.Money{Amount: 0, Currency: fee.Currency}
+			option.Free = true
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// Option trả về phí của một phương thức cụ thể (rỗng = standard)
+func (ss *ShippingService) Option(province, method string, items []ShippableItem, subtotal models.Money) (*ShippingOption, error) {
+	if method == "" {
+		method = ShippingStandard
+	}
+	for _, option := range ss.Quote(province, items, subtotal) {
+		if option.Method == method {
+			return &option, nil
+		}
+	}
+	return nil, ErrInvalidShippingMethod
+}
+
+// feeFor tìm bậc phí đầu tiên chứa measure
+func (r ShippingMethodRate) feeFor(measure int) (models.Money, bool) {
+	for _, tier := range r.Tiers {
+		if tier.Max == 0 || measure <= tier.Max {
+			return tier.Fee, true
+		}
+	}
+	return models.Money{}, false
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+func TestShippingService_ZoneFor(t *testing.T) {
+	ss := NewShippingService(DefaultShippingConfig())
+
+	cases := []struct {
+		province, zone string
+	}{
+		{"", "hcm"},
+		{"tp.hcm", "hcm"},
+		{"hà  nội", "hanoi"},
+		{"Đà Nẵng", "national"},
+	}
+	for _, tc := range cases {
+		zone := ss.ZoneFor(tc.province)
+		if zone == nil || zone.Name != tc.zone {
+			t.Fatalf("%q: expected zone %s, got %+v", tc.province, tc.zone, zone)
+		}
+	}
+
+	t.Run("không có zone mặc định", func(t *testing.T) {
+		ss := NewShippingService(ShippingConfig{Zones: []ShippingZone{{Name: "hcm", Provinces: []string{"HCM"}}}})
+		if zone := ss.ZoneFor("Hà Nội"); zone != nil {
+			t.Fatalf("expected no zone, got %+v", zone)
+		}
+		if options := ss.Quote("Hà Nội", nil, models.VND(0)); len(options) != 0 {
+			t.Fatalf("expected no options, got %+v", options)
+		}
+	})
+}
+
+func TestShippingService_Quote(t *testing.T) {
+	ss := NewShippingService(DefaultShippingConfig())
+
+	t.Run("bậc phí theo khối lượng", func(t *testing.T) {
+		cases := []struct {
+			items []ShippableItem
+			fee   int64
+		}{
+			{[]ShippableItem{{Weight: 1000, Quantity: 1}}, 15000},
+			{[]ShippableItem{{Weight: 1000, Quantity: 1}, {Quantity: 1}}, 22000}, // 500g mặc định
+			{[]ShippableItem{{Weight: 2000, Quantity: 2}}, 30000},
+		}
+		for _, tc := range cases {
+			option, err := ss.Option("HCM", "", tc.items, models.VND(100000))
+			mustNoError(t, err)
+			if option.Method != ShippingStandard || option.Fee.Amount != tc.fee || option.Free {
+				t.Fatalf("%+v: expected fee %d, got %+v", tc.items, tc.fee, option)
+			}
+		}
+	})
+
+	t.Run("miễn phí khi đạt ngưỡng", func(t *testing.T) {
+		items := []ShippableItem{{Weight: 800, Quantity: 1}}
+		options := ss.Quote("Hà Nội", items, models.VND(800000))
+		if len(options) != 2 {
+			t.Fatalf("expected 2 options, got %+v", options)
+		}
+		if !options[0].Free || !options[0].Fee.IsZero() || options[0].Zone != "hanoi" {
+			t.Fatalf("expected free standard shipping, got %+v", options[0])
+		}
+		if options[1].Free || options[1].Fee.Amount != 45000 {
+			t.Fatalf("expected paid express shipping, got %+v", options[1])
+		}
+	})
+
+	t.Run("bậc phí theo số món", func(t *testing.T) {
+		ss := NewShippingService(ShippingConfig{Zones: []ShippingZone{{
+			Name: "all",
+			Methods: []ShippingMethodRate{{
+				Method: ShippingStandard,
+				Basis:  ShippingBasisItems,
+				Tiers:  []ShippingTier{{Max: 2, Fee: models.VND(10000)}, {Max: 5, Fee: models.VND(20000)}},
+			}},
+		}}})
+
+		option, err := ss.Option("", ShippingStandard, []ShippableItem{{Weight: 5000, Quantity: 3}}, models.VND(0))
+		mustNoError(t, err)
+		if option.Fee.Amount != 20000 {
+			t.Fatalf("expected 20000, got %v", option.Fee)
+		}
+
+		// Vượt bậc cuối có Max thì phương thức không khả dụng
+		_, err = ss.Option("", ShippingStandard, []ShippableItem{{Quantity: 6}}, models.VND(0))
+		if err != ErrInvalidShippingMethod {
+			t.Fatalf("expected ErrInvalidShippingMethod, got %v", err)
+		}
+	})
+
+	t.Run("phương thức không tồn tại", func(t *testing.T) {
+		_, err := ss.Option("HCM", "drone", []ShippableItem{{Quantity: 1}}, models.VND(0))
+		if err != ErrInvalidShippingMethod {
+			t.Fatalf("expected ErrInvalidShippingMethod, got %v", err)
+		}
+	})
+}
+
+func TestShippingConfigFromEnv(t *testing.T) {
+	t.Run("mặc định", func(t *testing.T) {
+		config := shippingConfigFromEnv()
+		if config.DefaultProvince != defaultShippingProvince || len(config.Zones) != 3 {
+			t.Fatalf("unexpected default config: %+v", config)
+		}
+	})
+
+	t.Run("đọc từ biến môi trường", func(t *testing.T) {
+		t.Setenv("SHIPPING_RATES", `{"zones":[{"name":"all","methods":[{"method":"standard","tiers":[{"max":0,"fee":20000}]}]}]}`)
+		t.Setenv("SHIPPING_DEFAULT_PROVINCE", "Hà Nội")
+
+		config := shippingConfigFromEnv()
+		if config.DefaultProvince != "Hà Nội" || len(config.Zones) != 1 || config.Zones[0].Methods[0].Tiers[0].Fee != models.VND(20000) {
+			t.Fatalf("unexpected config: %+v", config)
+		}
+	})
+
+	t.Run("bỏ qua cấu hình sai", func(t *testing.T) {
+		t.Setenv("SHIPPING_RATES", `not json`)
+
+		config := shippingConfigFromEnv()
+		if len(config.Zones) != 3 {
+			t.Fatalf("expected default zones, got %+v", config)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+// defaultTaxRate là thuế suất VAT phổ thông (%) khi không có rule nào khớp
+const defaultTaxRate = 10
+
+// TaxRule là một dòng cấu hình thuế suất. Category/Province rỗng nghĩa là áp dụng cho tất cả;
+// rule càng cụ thể (có cả category và province) càng được ưu tiên.
+type TaxRule struct {
+	Name     string  `json:"name,omitempty"`
+	Category string  `json:"category,omitempty"`
+	Province string  `json:"province,omitempty"`
+	Rate     float64 `json:"rate"` // phần trăm, vd 10 = 10%
+}
+
+// TaxConfig cấu hình TaxService
+type TaxConfig struct {
+	DefaultRate      float64   // phần trăm
+	PricesIncludeTax bool      // giá bán đã gồm VAT (mặc định của thị trường VN)
+	Rules            []TaxRule // rule theo danh mục / tỉnh
+}
+
+// TaxableItem là một dòng hàng cần tính thuế
+type TaxableItem struct {
+	Category string
+	Total    models.Money // thành tiền của dòng theo giá bán
+}
+
+// TaxResult là kết quả tính thuế cho cả đơn hàng
+type TaxResult struct {
+	Items     []models.OrderItemTax // cùng thứ tự với items đầu vào
+	TaxAmount models.Money
+	Inclusive bool
+}
+
+// TaxService tính VAT theo danh mục sản phẩm và tỉnh giao hàng
+type TaxService struct {
+	config TaxConfig
+}
+
+// NewTaxService tạo TaxService với cấu hình cho trước
+func NewTaxService(config TaxConfig) *TaxService {
+	return &TaxService{config: config}
+}
+
+// taxConfigFromEnv đọc cấu hình thuế từ biến môi trường:
+// TAX_DEFAULT_RATE (%, mặc định 10), TAX_PRICES_INCLUDE_VAT (mặc định true),
+// TAX_RULES (JSON, vd [{"category":"food","rate":5},{"province":"Hà Nội","category":"electronics","rate":8}])
+func taxConfigFromEnv() TaxConfig {
+	config := TaxConfig{DefaultRate: defaultTaxRate, PricesIncludeTax: true}
+
+	if value := os.Getenv("TAX_DEFAULT_RATE"); value != "" {
+		if rate, err := strconv.ParseFloat(value, 64); err == nil && rate >= 0 {
+			config.DefaultRate = rate
+		} else {
+			log.Printf("Invalid TAX_DEFAULT_RATE %q, using %v", value, config.DefaultRate)
+		}
+	}
+
+	if value := os.Getenv("TAX_PRICES_INCLUDE_VAT"); value != "" {
+		if inclusive, err := strconv.ParseBool(value); err == nil {
+			config.PricesIncludeTax = inclusive
+		} else {
+			log.Printf("Invalid TAX_PRICES_INCLUDE_VAT %q, using %v", value, config.PricesIncludeTax)
+		}
+	}
+
+	if value := os.Getenv("TAX_RULES"); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.Rules); err != nil {
+			log.Printf("Invalid TAX_RULES: %v", err)
+			config.Rules = nil
+		}
+	}
+
+	return config
+}
+
+// PricesIncludeTax cho biết giá bán đã gồm VAT hay chưa
+func (ts *TaxService) PricesIncludeTax() bool {
+	return ts.config.PricesIncludeTax
+}
+
+// RateFor tìm thuế suất (%) và tên rule áp dụng cho category tại province
+func (ts *TaxService) RateFor(category, province string) (float64, string) {
+	best, bestScore := -1, -1
+	for i, rule := range ts.config.Rules {
+		score := 0
+		if rule.Category != "" {
+			if !sameTaxKey(rule.Category, category) {
+				continue
+			}
+			score += 2
+		}
+		if rule.Province != "" {
+			if !sameTaxKey(rule.Province, province) {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 {
+		return ts.config.DefaultRate, "default"
+	}
+	rule := ts.config.Rules[best]
+	name := rule.Name
+	if name == "" {
+		name = strings.Trim(rule.Category+"/"+rule.Province, "/")
+	}
+	if name == "" {
+		name = "default"
+	}
+	return rule.Rate, name
+}
+
+// Calculate tính thuế từng dòng hàng (làm tròn theo từng dòng) và tổng thuế
+func (ts *TaxService) Calculate(items []TaxableItem, province string) TaxResult {
+	result := TaxResult{
+		Items:     make([]models.OrderItemTax, 0, len(items)),
+		Inclusive: ts.config.PricesIncludeTax,
+	}
+
+	for _, item := range items {
+		rate, rule := ts.RateFor(item.Category, province)
+		bp := int64(math.Round(rate * 100)) // basis points, 10% = 1000
+
+		var tax int64
+		if ts.config.PricesIncludeTax {
+			tax = divRound(item.Total.Amount*bp, 10000+bp)
+		} else {
+			tax = divRound(item.Total.Amount*bp, 10000)
+		}
+
+		taxAmount := models.Money{Amount: tax, Currency: item.Total.Currency}
+		taxable := item.Total
+		if ts.config.PricesIncludeTax {
+			taxable = item.Total.Sub(taxAmount)
+		}
+
+		result.Items = append(result.Items, models.OrderItemTax{
+			Rule:      rule,
+			Rate:      rate,
+			Inclusive: ts.config.PricesIncludeTax,
+			Taxable:   taxable,
+			Amount:    taxAmount,
+		})
+		result.TaxAmount = result.TaxAmount.Add(taxAmount)
+	}
+
+	return result
+}
+
+// sameTaxKey so sánh category/province không phân biệt hoa thường và khoảng trắng thừa
+func sameTaxKey(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// divRound chia làm tròn half-up (a >= 0, b > 0)
+func divRound(a, b int64) int64 {
+	if a < 0 {
+		return -divRound(-a, b)
+	}
+	return (a + b/2) / b
+}
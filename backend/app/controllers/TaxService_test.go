@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+func testTaxRules() []TaxRule {
+	return []TaxRule{
+		{Category: "food", Rate: 5},
+		{Province: "Hà Nội", Rate: 8},
+		{Name: "hn-food", Category: "food", Province: "Hà Nội", Rate: 0},
+	}
+}
+
+func TestTaxService_RateFor(t *testing.T) {
+	ts := NewTaxService(TaxConfig{DefaultRate: 10, Rules: testTaxRules()})
+
+	cases := []struct {
+		category, province string
+		rate               float64
+		rule               string
+	}{
+		{"electronics", "Ho Chi Minh City", 10, "default"},
+		{"food", "Ho Chi Minh City", 5, "food"},
+		{"electronics", "hà  nội", 8, "Hà Nội"},
+		{"FOOD", "Hà Nội", 0, "hn-food"},
+	}
+	for _, tc := range cases {
+		rate, rule := ts.RateFor(tc.category, tc.province)
+		if rate != tc.rate || rule != tc.rule {
+			t.Fatalf("%s/%s: expected %v (%s), got %v (%s)", tc.category, tc.province, tc.rate, tc.rule, rate, rule)
+		}
+	}
+}
+
+func TestTaxService_Calculate(t *testing.T) {
+	items := []TaxableItem{
+		{Category: "electronics", Total: models.VND(110000)},
+		{Category: "food", Total: models.VND(99999)},
+	}
+
+	t.Run("giá đã gồm VAT", func(t *testing.T) {
+		ts := NewTaxService(TaxConfig{DefaultRate: 10, PricesIncludeTax: true, Rules: testTaxRules()})
+		result := ts.Calculate(items, "Ho Chi Minh City")
+
+		// 110000 gồm 10% => 10000 thuế; 99999 gồm 5% => 4761.857 ≈ 4762
+		if result.Items[0].Amount != models.VND(10000) || result.Items[0].Taxable != models.VND(100000) {
+			t.Fatalf("unexpected first item: %+v", result.Items[0])
+		}
+		if result.Items[1].Amount != models.VND(4762) || result.Items[1].Rate != 5 || !result.Items[1].Inclusive {
+			t.Fatalf("unexpected second item: %+v", result.Items[1])
+		}
+		if result.TaxAmount != models.VND(14762) || !result.Inclusive {
+			t.Fatalf("expected 14762 VND inclusive, got %v", result.TaxAmount)
+		}
+	})
+
+	t.Run("giá chưa gồm VAT", func(t *testing.T) {
+		ts := NewTaxService(TaxConfig{DefaultRate: 10, Rules: testTaxRules()})
+		result := ts.Calculate(items, "Hà Nội")
+
+		// Hà Nội: electronics 8% => 8800; food 0%
+		if result.Items[0].Amount != models.VND(8800) || result.Items[0].Taxable != models.VND(110000) {
+			t.Fatalf("unexpected first item: %+v", result.Items[0])
+		}
+		if !result.Items[1].Amount.IsZero() || result.Items[1].Rule != "hn-food" {
+			t.Fatalf("unexpected second item: %+v", result.Items[1])
+		}
+		if result.TaxAmount != models.VND(8800) || result.Inclusive {
+			t.Fatalf("expected 8800 VND exclusive, got %v", result.TaxAmount)
+		}
+	})
+}
+
+func TestTaxConfigFromEnv(t *testing.T) {
+	t.Run("mặc định", func(t *testing.T) {
+		config := taxConfigFromEnv()
+		if config.DefaultRate != 10 || !config.PricesIncludeTax || len(config.Rules) != 0 {
+			t.Fatalf("unexpected default config: %+v", config)
+		}
+	})
+
+	t.Run("đọc từ biến môi trường", func(t *testing.T) {
+		t.Setenv("TAX_DEFAULT_RATE", "8")
+		t.Setenv("TAX_PRICES_INCLUDE_VAT", "false")
+		t.Setenv("TAX_RULES", `[{"category":"food","rate":5}]`)
+
+		config := taxConfigFromEnv()
+		if config.DefaultRate != 8 || config.PricesIncludeTax || len(config.Rules) != 1 || config.Rules[0].Rate != 5 {
+			t.Fatalf("unexpected config: %+v", config)
+		}
+	})
+
+	t.Run("bỏ qua cấu hình sai", func(t *testing.T) {
+		t.Setenv("TAX_DEFAULT_RATE", "abc")
+		t.Setenv("TAX_RULES", `not json`)
+
+		config := taxConfigFromEnv()
+		if config.DefaultRate != 10 || len(config.Rules) != 0 {
+			t.Fatalf("unexpected config: %+v", config)
+		}
+	})
+}
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword là mật khẩu gốc của user tạo bởi createTestUser
+const testPassword = "password123"
+
+var testSeq int64
+
+// nextSeq sinh hậu tố duy nhất cho username, email, id, slug...
+func nextSeq() int64 {
+	return atomic.AddInt64(&testSeq, 1)
+}
+
+// newTestStore tạo store trong bộ nhớ cho mỗi test, thay cho Mongo + cleanUp() bên JS
+func newTestStore() *store.MemoryStore {
+	return store.NewMemoryStore()
+}
+
+// createTestUser tạo user với mật khẩu đã hash (tương ứng createTestUserForServices)
+func createTestUser(t *testing.T, st store.Store, modify ...func(u *models.User)) *models.User {
+	t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	n := nextSeq()
+	user := &models.User{
+		Username:  fmt.Sprintf("testuser_%d", n),
+		Email:     fmt.Sprintf("test_%d@example.com", n),
+		Password:  string(hashed),
+		FullName:  "Test User",
+		Phone:     "0123456789",
+		Role:      "user",
+		Status:    "active",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	for _, fn := range modify {
+		fn(user)
+	}
+
+	if err := st.Users().Insert(context.Background(), user); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	return user
+}
+
+// createTestAdmin tạo user có role admin
+func createTestAdmin(t *testing.T, st store.Store, modify ...func(u *models.User)) *models.User {
+	t.Helper()
+
+	n := nextSeq()
+	return createTestUser(t, st, append([]func(u *models.User){func(u *models.User) {
+		u.Username = fmt.Sprintf("testadmin_%d", n)
+		u.Email = fmt.Sprintf("admin_%d@example.com", n)
+		u.FullName = "Test Admin"
+		u.Role = "admin"
+	}}, modify...)...)
+}
+
+// createTestCategory tạo category với id duy nhất
+func createTestCategory(t *testing.T, st store.Store) *models.Category {
+	t.Helper()
+
+	n := nextSeq()
+	category := &models.Category{
+		CategoryID: fmt.Sprintf("cat_%d", n),
+		Name:       fmt.Sprintf("Test Category %d", n),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := st.Categories().Insert(context.Background(), category); err != nil {
+		t.Fatalf("insert category: %v", err)
+	}
+	return category
+}
+
+// createTestProduct tạo sản phẩm mặc định giá 100000, tồn kho 10
+func createTestProduct(t *testing.T, st store.Store, modify ...func(p *models.Product)) *models.Product {
+	t.Helper()
+
+	n := nextSeq()
+	product := &models.Product{
+		ProductID:   fmt.Sprintf("prod_%d", n),
+		Name:        fmt.Sprintf("Test Product %d", n),
+		Slug:        fmt.Sprintf("test-product-%d", n),
+		Description: "Test product description",
+		Price:       models.VND(100000),
+		Amount:      10,
+		Image:       "test-image.jpg",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	for _, fn := range modify {
+		fn(product)
+	}
+	if product.Category == "" {
+		product.Category = createTestCategory(t, st).CategoryID
+	}
+
+	if err := st.Products().Insert(context.Background(), product); err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+	return product
+}
+
+// createTestCart lưu trực tiếp giỏ hàng (bỏ qua kiểm tra tồn kho của CartService)
+func createTestCart(t *testing.T, st store.Store, userID primitive.ObjectID, items []models.CartItem) *models.Cart {
+	t.Helper()
+
+	cart := &models.Cart{
+		UserID:    userID,
+		CartType:  "cart",
+		Items:     items,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	for i := range cart.Items {
+		cart.Items[i].Total = cart.Items[i].Price.Mul(cart.Items[i].Quantity)
+		cart.TotalAmount = cart.TotalAmount.Add(cart.Items[i].Total)
+		cart.TotalItems += cart.Items[i].Quantity
+	}
+
+	if err := st.Carts().Save(context.Background(), cart); err != nil {
+		t.Fatalf("save cart: %v", err)
+	}
+	return cart
+}
+
+// cartItemFor tạo CartItem từ sản phẩm
+func cartItemFor(product *models.Product, quantity int) models.CartItem {
+	return models.CartItem{
+		ProductID:    product.ProductID,
+		ProductName:  product.Name,
+		ProductImage: product.Image,
+		ProductSlug:  product.Slug,
+		Price:        product.Price,
+		Quantity:     quantity,
+	}
+}
+
+// createTestOrder tạo đơn hàng pending với 1 item 2 x 100000
+func createTestOrder(t *testing.T, st store.Store, userID primitive.ObjectID, modify ...func(o *models.Order)) *models.Order {
+	t.Helper()
+
+	n := nextSeq()
+	items := []models.OrderItem{{
+		ProductID:   primitive.NewObjectID(),
+		ProductName: "Test Product",
+		ProductSKU:  "TEST-001",
+		Quantity:    2,
+		Price:       models.VND(100000),
+		Total:       models.VND(200000),
+	}}
+	order := &models.Order{
+		OrderNumber: fmt.Sprintf("GP%s%04d", time.Now().Format("20060102"), n),
+		UserID:      &userID,
+		Status:      "pending",
+		Items:       items,
+		Subtotal:    models.VND(200000),
+		TotalAmount: models.VND(200000),
+		ShippingAddress: models.ShippingAddress{
+			FullName:   "Test Customer",
+			Phone:      "0123456789",
+			Email:      "test@example.com",
+			Street:     "123 Test Street",
+			City:       "Ho Chi Minh City",
+			State:      "Ho Chi Minh",
+			PostalCode: "700000",
+			Country:    "Vietnam",
+		},
+		Payment: models.Payment{
+			Method: "cash_on_delivery",
+			Status: "pending",
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	for _, fn := range modify {
+		fn(order)
+	}
+
+	if err := st.Orders().Insert(context.Background(), order); err != nil {
+		t.Fatalf("insert order: %v", err)
+	}
+	return order
+}
+
+// expectError kiểm tra err khác nil và chứa chuỗi want
+func expectError(t *testing.T, err error, want string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected error %q, got nil", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected error containing %q, got %q", want, err.Error())
+	}
+}
+
+// mustNoError dừng test nếu err khác nil
+func mustNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// faultyStore bọc store.Store để giả lập lỗi ghi giữa chừng
+type faultyStore struct {
+	store.Store
+	failStockFor string // AdjustStock/DecrementStock lỗi với product id này
+	failInsert   bool   // Orders().Insert lỗi
+	failClear    bool   // Carts().Clear lỗi
+	failCategory bool   // Categories().FindByCategoryID lỗi
+}
+
+var errInjected = errors.New("injected failure")
+
+type faultyProducts struct {
+	store.ProductStore
+	fs *faultyStore
+}
+
+func (p *faultyProducts) AdjustStock(ctx context.Context, productID string, delta int) error {
+	if productID == p.fs.failStockFor {
+		return errInjected
+	}
+	return p.ProductStore.AdjustStock(ctx, productID, delta)
+}
+
+func (p *faultyProducts) DecrementStock(ctx context.Context, productID string, qty int) error {
+	if productID == p.fs.failStockFor {
+		return errInjected
+	}
+	return p.ProductStore.DecrementStock(ctx, productID, qty)
+}
+
+type faultyOrders struct {
+	store.OrderStore
+	fs *faultyStore
+}
+
+func (o *faultyOrders) Insert(ctx context.Context, order *models.Order) error {
+	if o.fs.failInsert {
+		return errInjected
+	}
+	return o.OrderStore.Insert(ctx, order)
+}
+
+type faultyCarts struct {
+	store.CartStore
+	fs *faultyStore
+}
+
+func (c *faultyCarts) Clear(ctx context.Context, userID primitive.ObjectID, cartType string) error {
+	if c.fs.failClear {
+		return errInjected
+	}
+	return c.CartStore.Clear(ctx, userID, cartType)
+}
+
+type faultyCategories struct {
+	store.CategoryStore
+	fs *faultyStore
+}
+
+func (c *faultyCategories) FindByCategoryID(ctx context.Context, categoryID string) (*models.Category, error) {
+	if c.fs.failCategory {
+		return nil, errInjected
+	}
+	return c.CategoryStore.FindByCategoryID(ctx, categoryID)
+}
+
+func (s *faultyStore) Products() store.ProductStore { return &faultyProducts{s.Store.Products(), s} }
+func (s *faultyStore) Categories() store.CategoryStore {
+	return &faultyCategories{s.Store.Categories(), s}
+}
+func (s *faultyStore) Orders() store.OrderStore { return &faultyOrders{s.Store.Orders(), s} }
+func (s *faultyStore) Carts() store.CartStore   { return &faultyCarts{s.Store.Carts(), s} }
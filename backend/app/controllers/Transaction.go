@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"log"
+
+	"github.com/mingfulsnack/app/store"
+)
+
+// rollback ghi lại các bước hoàn tác cho trường hợp store không hỗ trợ transaction
+type rollback struct {
+	steps []func(ctx context.Context) error
+}
+
+// onRollback đăng ký bước hoàn tác cho thao tác vừa ghi thành công
+func (r *rollback) onRollback(step func(ctx context.Context) error) {
+	r.steps = append(r.steps, step)
+}
+
+// run chạy các bước hoàn tác theo thứ tự ngược lại
+func (r *rollback) run(ctx context.Context) {
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		if err := r.steps[i](ctx); err != nil {
+			log.Printf("Warning: rollback step failed: %v", err)
+		}
+	}
+}
+
+// runAtomic chạy fn như một đơn vị: dùng transaction của store nếu có,
+// ngược lại chạy trực tiếp và hoàn tác các bước fn đã đăng ký khi fn lỗi.
+// Lỗi trả về luôn là lỗi của fn nếu fn thất bại.
+func runAtomic(ctx context.Context, st store.Store, fn func(ctx context.Context, rb *rollback) error) error {
+	var fnErr error
+	err := st.WithTransaction(ctx, func(txCtx context.Context) error {
+		// Transaction tự hủy khi lỗi nên không cần các bước hoàn tác
+		fnErr = fn(txCtx, &rollback{})
+		return fnErr
+	})
+	if err != store.ErrTransactionsUnsupported {
+		if fnErr != nil {
+			return fnErr
+		}
+		return err
+	}
+
+	rb := &rollback{}
+	if err := fn(ctx, rb); err != nil {
+		rb.run(ctx)
+		return err
+	}
+	return nil
+}
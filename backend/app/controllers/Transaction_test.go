@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mingfulsnack/app/store"
+)
+
+// txStore giả lập store có hỗ trợ transaction
+type txStore struct {
+	store.Store
+	calls int
+}
+
+func (s *txStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	s.calls++
+	return fn(ctx)
+}
+
+func TestRunAtomic(t *testing.T) {
+	t.Run("dùng transaction khi store hỗ trợ, không chạy bước hoàn tác", func(t *testing.T) {
+		st := &txStore{Store: newTestStore()}
+		undone := false
+
+		err := runAtomic(context.Background(), st, func(ctx context.Context, rb *rollback) error {
+			rb.onRollback(func(ctx context.Context) error { undone = true; return nil })
+			return errInjected
+		})
+		if err != errInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+		if st.calls != 1 || undone {
+			t.Fatalf("expected one transaction and no rollback steps, got calls=%d undone=%v", st.calls, undone)
+		}
+	})
+
+	t.Run("hoàn tác ngược thứ tự khi không có transaction", func(t *testing.T) {
+		var order []int
+
+		err := runAtomic(context.Background(), newTestStore(), func(ctx context.Context, rb *rollback) error {
+			rb.onRollback(func(ctx context.Context) error { order = append(order, 1); return nil })
+			rb.onRollback(func(ctx context.Context) error { order = append(order, 2); return nil })
+			return errInjected
+		})
+		if err != errInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+		if len(order) != 2 || order[0] != 2 || order[1] != 1 {
+			t.Fatalf("expected rollback order [2 1], got %v", order)
+		}
+	})
+
+	t.Run("không hoàn tác khi thành công", func(t *testing.T) {
+		undone := false
+
+		err := runAtomic(context.Background(), newTestStore(), func(ctx context.Context, rb *rollback) error {
+			rb.onRollback(func(ctx context.Context) error { undone = true; return nil })
+			return nil
+		})
+		mustNoError(t, err)
+		if undone {
+			t.Fatalf("rollback step should not run on success")
+		}
+	})
+}
@@ -8,16 +8,16 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type UserService struct{}
+type UserService struct {
+	store store.Store
+}
 
 // PaginatedUsers represents paginated user response
 type PaginatedUsers struct {
@@ -46,8 +46,8 @@ type UserFilters struct {
 }
 
 // NewUserService creates a new user service instance
-func NewUserService() *UserService {
-	return &UserService{}
+func NewUserService(st store.Store) *UserService {
+	return &UserService{store: st}
 }
 
 // GetAllUsers retrieves all users with pagination and filters (admin only)
@@ -55,9 +55,6 @@ func (us *UserService) GetAllUsers(page, limit int, filters UserFilters) (*Pagin
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	// Default values
 	if page < 1 {
 		page = 1
@@ -66,42 +63,24 @@ func (us *UserService) GetAllUsers(page, limit int, filters UserFilters) (*Pagin
 		limit = 10
 	}
 
-	// Build query filter
-	filter := bson.M{}
-
-	// Apply role filter
-	if filters.Role != "" {
-		filter["role"] = filters.Role
+	// Build query filter (role, search by username/email/full_name)
+	filter := store.UserFilter{
+		Role:   filters.Role,
+		Search: filters.Search,
 	}
 
-	// Apply search filter
-	if filters.Search != "" {
-		filter["$or"] = []bson.M{
-			{"username": bson.M{"$regex": filters.Search, "$options": "i"}},
-			{"email": bson.M{"$regex": filters.Search, "$options": "i"}},
-			{"full_name": bson.M{"$regex": filters.Search, "$options": "i"}},
-		}
-	}
-
-	// Build sort options
-	sortOptions := options.Find()
-	sortOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
-
 	// Apply pagination
 	skip := (page - 1) * limit
-	sortOptions.SetSkip(int64(skip)).SetLimit(int64(limit))
 
 	// Execute query
-	cursor, err := collection.Find(ctx, filter, sortOptions)
+	users, err := us.store.Users().List(ctx, filter, store.ListOptions{
+		Skip:  int64(skip),
+		Limit: int64(limit),
+		Sort:  "-created_at",
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error finding users: %v", err)
 	}
-	defer cursor.Close(ctx)
-
-	var users []models.User
-	if err = cursor.All(ctx, &users); err != nil {
-		return nil, fmt.Errorf("error decoding users: %v", err)
-	}
 
 	// Remove password from response
 	for i := range users {
@@ -109,7 +88,7 @@ func (us *UserService) GetAllUsers(page, limit int, filters UserFilters) (*Pagin
 	}
 
 	// Count total documents
-	total, err := collection.CountDocuments(ctx, filter)
+	total, err := us.store.Users().Count(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("error counting users: %v", err)
 	}
@@ -132,18 +111,14 @@ func (us *UserService) GetUserByID(userID string) (*models.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID format")
 	}
 
-	var user models.User
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	user, err := us.store.Users().FindByID(ctx, objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, fmt.Errorf("user does not exist")
 		}
 		return nil, fmt.Errorf("error finding user: %v", err)
@@ -151,7 +126,7 @@ func (us *UserService) GetUserByID(userID string) (*models.User, error) {
 
 	// Remove password from result
 	user.Password = ""
-	return &user, nil
+	return user, nil
 }
 
 // RegisterUser creates a new user account
@@ -159,29 +134,25 @@ func (us *UserService) RegisterUser(userData models.User) (*models.User, error)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	// Validate user data
 	if err := us.validateUserData(userData, false); err != nil {
 		return nil, err
 	}
 
 	// Check if username exists
-	var existingUser models.User
-	err := collection.FindOne(ctx, bson.M{"username": userData.Username}).Decode(&existingUser)
-	if err == nil {
-		return nil, fmt.Errorf("username already exists")
-	} else if err != mongo.ErrNoDocuments {
+	exists, err := us.store.Users().ExistsByUsername(ctx, userData.Username, primitive.NilObjectID)
+	if err != nil {
 		return nil, fmt.Errorf("error checking username: %v", err)
+	} else if exists {
+		return nil, fmt.Errorf("username already exists")
 	}
 
 	// Check if email exists
-	err = collection.FindOne(ctx, bson.M{"email": userData.Email}).Decode(&existingUser)
-	if err == nil {
-		return nil, fmt.Errorf("email already exists")
-	} else if err != mongo.ErrNoDocuments {
+	exists, err = us.store.Users().ExistsByEmail(ctx, userData.Email, primitive.NilObjectID)
+	if err != nil {
 		return nil, fmt.Errorf("error checking email: %v", err)
+	} else if exists {
+		return nil, fmt.Errorf("email already exists")
 	}
 
 	// Hash password
@@ -200,12 +171,10 @@ func (us *UserService) RegisterUser(userData models.User) (*models.User, error)
 	userData.UpdatedAt = now
 
 	// Insert user
-	result, err := collection.InsertOne(ctx, userData)
-	if err != nil {
+	if err := us.store.Users().Insert(ctx, &userData); err != nil {
 		return nil, fmt.Errorf("error creating user: %v", err)
 	}
 
-	userData.ID = result.InsertedID.(primitive.ObjectID)
 	userData.Password = "" // Remove password from response
 
 	return &userData, nil
@@ -216,18 +185,14 @@ func (us *UserService) LoginUser(username, password string) (*UserLoginResult, e
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	if username == "" || password == "" {
 		return nil, fmt.Errorf("username and password are required")
 	}
 
 	// Find user by username
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	user, err := us.store.Users().FindByUsername(ctx, username)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, fmt.Errorf("username or password is incorrect")
 		}
 		return nil, fmt.Errorf("error finding user: %v", err)
@@ -256,12 +221,10 @@ func (us *UserService) LoginUser(username, password string) (*UserLoginResult, e
 	// Update last login
 	now := time.Now()
 	user.LastLogin = &now
-	collection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
-		"$set": bson.M{"last_login": now},
-	})
+	us.store.Users().Update(ctx, user.ID, bson.M{"last_login": now})
 
 	// Generate JWT token
-	token, err := us.generateToken(user)
+	token, err := us.generateToken(*user)
 	if err != nil {
 		return nil, fmt.Errorf("error generating token: %v", err)
 	}
@@ -270,7 +233,7 @@ func (us *UserService) LoginUser(username, password string) (*UserLoginResult, e
 	user.Password = ""
 
 	return &UserLoginResult{
-		User:  user,
+		User:  *user,
 		Token: token,
 	}, nil
 }
@@ -294,9 +257,6 @@ func (us *UserService) UpdateUser(userID string, updateData bson.M, currentUserI
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	// Check permissions: user can only update themselves, admin can update anyone
 	if currentUserRole != "admin" && userID != currentUserID {
 		return nil, fmt.Errorf("you do not have permission to update this user")
@@ -308,10 +268,9 @@ func (us *UserService) UpdateUser(userID string, updateData bson.M, currentUserI
 	}
 
 	// Get current user
-	var currentUser models.User
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&currentUser)
+	_, err = us.store.Users().FindByID(ctx, objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, fmt.Errorf("user does not exist")
 		}
 		return nil, fmt.Errorf("error finding user: %v", err)
@@ -324,9 +283,7 @@ func (us *UserService) UpdateUser(userID string, updateData bson.M, currentUserI
 				return nil, err
 			}
 			// Check if email already exists for another user
-			var existingUser models.User
-			err = collection.FindOne(ctx, bson.M{"email": emailStr, "_id": bson.M{"$ne": objectID}}).Decode(&existingUser)
-			if err == nil {
+			if exists, _ := us.store.Users().ExistsByEmail(ctx, emailStr, objectID); exists {
 				return nil, fmt.Errorf("email already exists")
 			}
 		}
@@ -338,9 +295,7 @@ func (us *UserService) UpdateUser(userID string, updateData bson.M, currentUserI
 				return nil, err
 			}
 			// Check if username already exists for another user
-			var existingUser models.User
-			err = collection.FindOne(ctx, bson.M{"username": usernameStr, "_id": bson.M{"$ne": objectID}}).Decode(&existingUser)
-			if err == nil {
+			if exists, _ := us.store.Users().ExistsByUsername(ctx, usernameStr, objectID); exists {
 				return nil, fmt.Errorf("username already exists")
 			}
 		}
@@ -369,25 +324,22 @@ func (us *UserService) UpdateUser(userID string, updateData bson.M, currentUserI
 	updateData["updated_at"] = time.Now()
 
 	// Update user
-	update := bson.M{"$set": updateData}
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	err = us.store.Users().Update(ctx, objectID, updateData)
 	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf("user not found")
+		}
 		return nil, fmt.Errorf("error updating user: %v", err)
 	}
 
-	if result.MatchedCount == 0 {
-		return nil, fmt.Errorf("user not found")
-	}
-
 	// Fetch and return updated user
-	var updatedUser models.User
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&updatedUser)
+	updatedUser, err := us.store.Users().FindByID(ctx, objectID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching updated user: %v", err)
 	}
 
 	updatedUser.Password = "" // Remove password from response
-	return &updatedUser, nil
+	return updatedUser, nil
 }
 
 // DeleteUser removes a user (admin only)
@@ -399,19 +351,15 @@ func (us *UserService) DeleteUser(userID, currentUserRole string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return fmt.Errorf("invalid user ID format")
 	}
 
 	// Get user to check if it's admin
-	var user models.User
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	user, err := us.store.Users().FindByID(ctx, objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return fmt.Errorf("user does not exist")
 		}
 		return fmt.Errorf("error finding user: %v", err)
@@ -423,15 +371,14 @@ func (us *UserService) DeleteUser(userID, currentUserRole string) error {
 	}
 
 	// Delete user
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	err = us.store.Users().Delete(ctx, objectID)
 	if err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Errorf("user not found")
+		}
 		return fmt.Errorf("error deleting user: %v", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return fmt.Errorf("user not found")
-	}
-
 	return nil
 }
 
@@ -440,9 +387,6 @@ func (us *UserService) ChangePassword(userID, currentPassword, newPassword strin
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	if currentPassword == "" || newPassword == "" {
 		return fmt.Errorf("current password and new password are required")
 	}
@@ -453,10 +397,9 @@ func (us *UserService) ChangePassword(userID, currentPassword, newPassword strin
 	}
 
 	// Get user
-	var user models.User
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	user, err := us.store.Users().FindByID(ctx, objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return fmt.Errorf("user does not exist")
 		}
 		return fmt.Errorf("error finding user: %v", err)
@@ -489,14 +432,10 @@ func (us *UserService) ChangePassword(userID, currentPassword, newPassword strin
 	}
 
 	// Update password
-	update := bson.M{
-		"$set": bson.M{
-			"password":   string(hashedPassword),
-			"updated_at": time.Now(),
-		},
-	}
-
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	err = us.store.Users().Update(ctx, objectID, bson.M{
+		"password":   string(hashedPassword),
+		"updated_at": time.Now(),
+	})
 	if err != nil {
 		return fmt.Errorf("error updating password: %v", err)
 	}
@@ -509,32 +448,27 @@ func (us *UserService) GetUserStatistics() (*UserStatistics, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Users")
-
 	// Count total users
-	totalUsers, err := collection.CountDocuments(ctx, bson.M{})
+	totalUsers, err := us.store.Users().Count(ctx, store.UserFilter{})
 	if err != nil {
 		return nil, fmt.Errorf("error counting total users: %v", err)
 	}
 
 	// Count admins
-	totalAdmins, err := collection.CountDocuments(ctx, bson.M{"role": "admin"})
+	totalAdmins, err := us.store.Users().Count(ctx, store.UserFilter{Role: "admin"})
 	if err != nil {
 		return nil, fmt.Errorf("error counting admins: %v", err)
 	}
 
 	// Count customers/regular users
-	totalCustomers, err := collection.CountDocuments(ctx, bson.M{"role": "user"})
+	totalCustomers, err := us.store.Users().Count(ctx, store.UserFilter{Role: "user"})
 	if err != nil {
 		return nil, fmt.Errorf("error counting customers: %v", err)
 	}
 
 	// Users registered in the last 30 days
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
-	newUsersThisMonth, err := collection.CountDocuments(ctx, bson.M{
-		"created_at": bson.M{"$gte": thirtyDaysAgo},
-	})
+	newUsersThisMonth, err := us.store.Users().Count(ctx, store.UserFilter{CreatedSince: &thirtyDaysAgo})
 	if err != nil {
 		return nil, fmt.Errorf("error counting new users: %v", err)
 	}
@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_GetAllUsers(t *testing.T) {
+	t.Run("phân trang và ẩn mật khẩu", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		for i := 0; i < 3; i++ {
+			createTestUser(t, st)
+		}
+
+		result, err := us.GetAllUsers(1, 2, UserFilters{})
+		mustNoError(t, err)
+		if len(result.Users) != 2 || result.Pagination["total_items"] != int64(3) || result.Pagination["total_pages"] != 2 {
+			t.Fatalf("unexpected result: %d users, %+v", len(result.Users), result.Pagination)
+		}
+		for _, user := range result.Users {
+			if user.Password != "" {
+				t.Fatalf("password should not be returned")
+			}
+		}
+	})
+
+	t.Run("lọc theo role", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		createTestUser(t, st)
+		createTestAdmin(t, st)
+
+		result, err := us.GetAllUsers(1, 10, UserFilters{Role: "admin"})
+		mustNoError(t, err)
+		if len(result.Users) != 1 || result.Users[0].Role != "admin" {
+			t.Fatalf("unexpected users: %+v", result.Users)
+		}
+	})
+
+	t.Run("tìm kiếm theo username", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		createTestUser(t, st, func(u *models.User) { u.Username = "john_doe" })
+		createTestUser(t, st)
+
+		result, err := us.GetAllUsers(1, 10, UserFilters{Search: "JOHN"})
+		mustNoError(t, err)
+		if len(result.Users) != 1 || result.Users[0].Username != "john_doe" {
+			t.Fatalf("unexpected users: %+v", result.Users)
+		}
+	})
+}
+
+func TestUserService_GetUserByID(t *testing.T) {
+	st := newTestStore()
+	us := NewUserService(st)
+	user := createTestUser(t, st)
+
+	result, err := us.GetUserByID(user.ID.Hex())
+	mustNoError(t, err)
+	if result.Username != user.Username || result.Password != "" {
+		t.Fatalf("unexpected user: %+v", result)
+	}
+
+	_, err = us.GetUserByID(primitive.NewObjectID().Hex())
+	expectError(t, err, "user does not exist")
+
+	_, err = us.GetUserByID("invalid")
+	expectError(t, err, "invalid user ID format")
+}
+
+func TestUserService_RegisterUser(t *testing.T) {
+	t.Run("đăng ký thành công và hash mật khẩu", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		data := registerData()
+
+		user, err := us.RegisterUser(data)
+		mustNoError(t, err)
+		if user.Password != "" || user.Role != "user" || user.Status != "active" {
+			t.Fatalf("unexpected user: %+v", user)
+		}
+
+		stored, err := st.Users().FindByUsername(context.Background(), data.Username)
+		mustNoError(t, err)
+		if stored.Password == testPassword {
+			t.Fatalf("password should be hashed")
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte(testPassword)); err != nil {
+			t.Fatalf("stored hash does not match password: %v", err)
+		}
+	})
+
+	t.Run("lỗi khi username đã tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		existing := createTestUser(t, st)
+
+		data := registerData()
+		data.Username = existing.Username
+		_, err := us.RegisterUser(data)
+		expectError(t, err, "username already exists")
+	})
+
+	t.Run("lỗi khi email đã tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		existing := createTestUser(t, st)
+
+		data := registerData()
+		data.Email = existing.Email
+		_, err := us.RegisterUser(data)
+		expectError(t, err, "email already exists")
+	})
+
+	t.Run("lỗi validate dữ liệu", func(t *testing.T) {
+		us := NewUserService(newTestStore())
+
+		data := registerData()
+		data.Username = "ab"
+		_, err := us.RegisterUser(data)
+		expectError(t, err, "username must be at least 3 characters")
+
+		data = registerData()
+		data.Email = "invalid-email"
+		_, err = us.RegisterUser(data)
+		expectError(t, err, "invalid email format")
+
+		data = registerData()
+		data.Password = "123"
+		_, err = us.RegisterUser(data)
+		expectError(t, err, "password must be at least 6 characters")
+
+		data = registerData()
+		data.Phone = "123"
+		_, err = us.RegisterUser(data)
+		expectError(t, err, "invalid phone number format")
+	})
+}
+
+func TestUserService_LoginUser(t *testing.T) {
+	st := newTestStore()
+	us := NewUserService(st)
+	user := createTestUser(t, st)
+
+	t.Run("đăng nhập thành công", func(t *testing.T) {
+		result, err := us.LoginUser(user.Username, testPassword)
+		mustNoError(t, err)
+		if result.Token == "" || result.User.Username != user.Username || result.User.Password != "" {
+			t.Fatalf("unexpected login result: %+v", result)
+		}
+
+		stored, _ := st.Users().FindByID(context.Background(), user.ID)
+		if stored.LastLogin == nil {
+			t.Fatalf("expected last_login to be updated")
+		}
+	})
+
+	t.Run("lỗi khi username không tồn tại", func(t *testing.T) {
+		_, err := us.LoginUser("nonexistent", testPassword)
+		expectError(t, err, "username or password is incorrect")
+	})
+
+	t.Run("lỗi khi sai mật khẩu", func(t *testing.T) {
+		_, err := us.LoginUser(user.Username, "wrongpassword")
+		expectError(t, err, "username or password is incorrect")
+	})
+
+	t.Run("lỗi khi thiếu thông tin", func(t *testing.T) {
+		_, err := us.LoginUser("", "")
+		expectError(t, err, "username and password are required")
+	})
+
+	t.Run("lỗi khi tài khoản bị khóa", func(t *testing.T) {
+		inactive := createTestUser(t, st, func(u *models.User) { u.Status = "inactive" })
+
+		_, err := us.LoginUser(inactive.Username, testPassword)
+		expectError(t, err, "account is disabled")
+	})
+}
+
+func TestUserService_LoginAdmin(t *testing.T) {
+	st := newTestStore()
+	us := NewUserService(st)
+	admin := createTestAdmin(t, st)
+	user := createTestUser(t, st)
+
+	result, err := us.LoginAdmin(admin.Username, testPassword)
+	mustNoError(t, err)
+	if result.User.Role != "admin" {
+		t.Fatalf("expected admin role, got %s", result.User.Role)
+	}
+
+	_, err = us.LoginAdmin(user.Username, testPassword)
+	expectError(t, err, "you do not have permission to access admin area")
+}
+
+func TestUserService_UpdateUser(t *testing.T) {
+	t.Run("user tự cập nhật thông tin", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+
+		updated, err := us.UpdateUser(user.ID.Hex(), bson.M{"full_name": "Updated Name"}, user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if updated.FullName != "Updated Name" || updated.Password != "" {
+			t.Fatalf("unexpected user: %+v", updated)
+		}
+	})
+
+	t.Run("admin cập nhật user khác", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		admin := createTestAdmin(t, st)
+		user := createTestUser(t, st)
+
+		updated, err := us.UpdateUser(user.ID.Hex(), bson.M{"status": "inactive", "role": "admin"}, admin.ID.Hex(), "admin")
+		mustNoError(t, err)
+		if updated.Status != "inactive" || updated.Role != "admin" {
+			t.Fatalf("unexpected user: %+v", updated)
+		}
+	})
+
+	t.Run("lỗi khi user cập nhật user khác", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+		other := createTestUser(t, st)
+
+		_, err := us.UpdateUser(other.ID.Hex(), bson.M{"full_name": "Hacked"}, user.ID.Hex(), "user")
+		expectError(t, err, "you do not have permission to update this user")
+	})
+
+	t.Run("lỗi khi username đã tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+		other := createTestUser(t, st)
+
+		_, err := us.UpdateUser(user.ID.Hex(), bson.M{"username": other.Username}, user.ID.Hex(), "user")
+		expectError(t, err, "username already exists")
+	})
+
+	t.Run("user không thể tự đổi role", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+
+		updated, err := us.UpdateUser(user.ID.Hex(), bson.M{"role": "admin", "full_name": "Still User"}, user.ID.Hex(), "user")
+		mustNoError(t, err)
+		if updated.Role != "user" || updated.FullName != "Still User" {
+			t.Fatalf("unexpected user: %+v", updated)
+		}
+	})
+
+	t.Run("lỗi khi user không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		admin := createTestAdmin(t, st)
+
+		_, err := us.UpdateUser(primitive.NewObjectID().Hex(), bson.M{"full_name": "Nobody"}, admin.ID.Hex(), "admin")
+		expectError(t, err, "user does not exist")
+	})
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	t.Run("admin xóa user", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+
+		mustNoError(t, us.DeleteUser(user.ID.Hex(), "admin"))
+		_, err := us.GetUserByID(user.ID.Hex())
+		expectError(t, err, "user does not exist")
+	})
+
+	t.Run("lỗi khi không phải admin", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+
+		expectError(t, us.DeleteUser(user.ID.Hex(), "user"), "you do not have permission to delete users")
+	})
+
+	t.Run("không thể xóa tài khoản admin", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		admin := createTestAdmin(t, st)
+
+		expectError(t, us.DeleteUser(admin.ID.Hex(), "admin"), "cannot delete admin account")
+	})
+
+	t.Run("lỗi khi user không tồn tại", func(t *testing.T) {
+		us := NewUserService(newTestStore())
+
+		expectError(t, us.DeleteUser(primitive.NewObjectID().Hex(), "admin"), "user does not exist")
+	})
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	t.Run("đổi mật khẩu thành công", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+
+		mustNoError(t, us.ChangePassword(user.ID.Hex(), testPassword, "newpassword123"))
+
+		_, err := us.LoginUser(user.Username, "newpassword123")
+		mustNoError(t, err)
+		_, err = us.LoginUser(user.Username, testPassword)
+		expectError(t, err, "username or password is incorrect")
+	})
+
+	t.Run("lỗi khi sai mật khẩu hiện tại", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+
+		expectError(t, us.ChangePassword(user.ID.Hex(), "wrongpassword", "newpassword123"), "current password is incorrect")
+	})
+
+	t.Run("lỗi khi mật khẩu mới quá ngắn", func(t *testing.T) {
+		st := newTestStore()
+		us := NewUserService(st)
+		user := createTestUser(t, st)
+
+		expectError(t, us.ChangePassword(user.ID.Hex(), testPassword, "123"), "password must be at least 6 characters")
+	})
+}
+
+func TestUserService_GetUserStatistics(t *testing.T) {
+	st := newTestStore()
+	us := NewUserService(st)
+	createTestUser(t, st)
+	createTestUser(t, st)
+	createTestAdmin(t, st)
+
+	stats, err := us.GetUserStatistics()
+	mustNoError(t, err)
+	if stats.TotalUsers != 3 || stats.TotalAdmins != 1 || stats.TotalCustomers != 2 || stats.NewUsersThisMonth != 3 {
+		t.Fatalf("unexpected statistics: %+v", stats)
+	}
+}
+
+// registerData tạo dữ liệu đăng ký hợp lệ với username/email duy nhất
+func registerData() models.User {
+	suffix := strings.ToLower(primitive.NewObjectID().Hex()[16:])
+	return models.User{
+		Username: "newuser_" + suffix,
+		Email:    "new_" + suffix + "@example.com",
+		Password: testPassword,
+		FullName: "New User",
+		Phone:    "0987654321",
+		Role:     "admin", // RegisterUser luôn đặt role = user
+	}
+}
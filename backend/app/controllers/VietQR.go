@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Các giá trị cố định của VietQR (chuẩn EMVCo QR do NAPAS quy định)
+const (
+	vietQRGUID            = "A000000727" // Định danh NAPAS
+	vietQRServiceTransfer = "QRIBFTTA"   // Chuyển khoản nhanh 24/7 tới số tài khoản
+	vietQRCurrencyVND     = "704"        // ISO 4217
+	vietQRCountry         = "VN"
+)
+
+// VietQR là nội dung một mã VietQR chuyển khoản tới số tài khoản
+type VietQR struct {
+	BankBIN       string // Mã BIN 6 số của ngân hàng nhận (vd 970436 - Vietcombank)
+	AccountNumber string
+	Amount        int64  // Số tiền VND, 0 = để khách tự nhập (QR tĩnh)
+	Memo          string // Nội dung chuyển khoản
+}
+
+// Payload trả về chuỗi EMVCo để render thành mã QR; mọi app ngân hàng tại Việt Nam đều đọc được
+func (q VietQR) Payload() (string, error) {
+	if !isDigits(q.BankBIN) || len(q.BankBIN) != 6 {
+		return "", errors.New("mã BIN ngân hàng không hợp lệ")
+	}
+	if q.AccountNumber == "" || len(q.AccountNumber) > 19 {
+		return "", errors.New("số tài khoản không hợp lệ")
+	}
+	if q.Amount < 0 {
+		return "", errors.New("số tiền không hợp lệ")
+	}
+	if len(q.Memo) > 25 {
+		return "", errors.New("nội dung chuyển khoản tối đa 25 ký tự")
+	}
+
+	beneficiary := emvField("00", q.BankBIN) + emvField("01", q.AccountNumber)
+	merchant := emvField("00", vietQRGUID) + emvField("01", beneficiary) + emvField("02", vietQRServiceTransfer)
+
+	initiation := "11" // QR tĩnh
+	if q.Amount > 0 {
+		initiation = "12" // QR động: có số tiền
+	}
+
+	var b strings.Builder
+	b.WriteString(emvField("00", "01"))
+	b.WriteString(emvField("01", initiation))
+	b.WriteString(emvField("38", merchant))
+	b.WriteString(emvField("53", vietQRCurrencyVND))
+	if q.Amount > 0 {
+		b.WriteString(emvField("54", fmt.Sprintf("%d", q.Amount)))
+	}
+	b.WriteString(emvField("58", vietQRCountry))
+	if q.Memo != "" {
+		b.WriteString(emvField("62", emvField("08", q.Memo)))
+	}
+
+	// CRC tính trên toàn bộ payload kể cả ID và độ dài của trường 63
+	b.WriteString("6304")
+	b.WriteString(fmt.Sprintf("%04X", crc16CCITT([]byte(b.String()))))
+	return b.String(), nil
+}
+
+// emvField mã hóa một trường EMVCo dạng ID (2 số) + độ dài (2 số) + giá trị
+func emvField(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// crc16CCITT tính CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF) theo yêu cầu của EMVCo
+func crc16CCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
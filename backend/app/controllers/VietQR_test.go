@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// parseEMV tách payload EMVCo thành map ID → giá trị (chỉ một cấp)
+func parseEMV(t *testing.T, payload string) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+	for len(payload) > 0 {
+		if len(payload) < 4 {
+			t.Fatalf("truncated EMV field %q", payload)
+		}
+		n, err := strconv.Atoi(payload[2:4])
+		if err != nil || len(payload) < 4+n {
+			t.Fatalf("invalid EMV length in %q", payload)
+		}
+		fields[payload[:2]] = payload[4 : 4+n]
+		payload = payload[4+n:]
+	}
+	return fields
+}
+
+func TestCRC16CCITT(t *testing.T) {
+	// Giá trị kiểm tra chuẩn của CRC-16/CCITT-FALSE
+	if crc := crc16CCITT([]byte("123456789")); crc != 0x29B1 {
+		t.Fatalf("expected 0x29B1, got %#04x", crc)
+	}
+}
+
+func TestVietQR_Payload(t *testing.T) {
+	payload, err := VietQR{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 250000, Memo: "GP2025010100019"}.Payload()
+	mustNoError(t, err)
+
+	fields := parseEMV(t, payload)
+	if fields["00"] != "01" || fields["01"] != "12" || fields["53"] != "704" || fields["54"] != "250000" || fields["58"] != "VN" {
+		t.Fatalf("unexpected header fields %v", fields)
+	}
+
+	merchant := parseEMV(t, fields["38"])
+	beneficiary := parseEMV(t, merchant["01"])
+	if merchant["00"] != "A000000727" || merchant["02"] != "QRIBFTTA" || beneficiary["00"] != "970436" || beneficiary["01"] != "0011001234567" {
+		t.Fatalf("unexpected merchant account info %v %v", merchant, beneficiary)
+	}
+	if memo := parseEMV(t, fields["62"])["08"]; memo != "GP2025010100019" {
+		t.Fatalf("expected memo in additional data, got %q", memo)
+	}
+
+	body := payload[:len(payload)-4]
+	if want := fmt.Sprintf("%04X", crc16CCITT([]byte(body))); fields["63"] != want {
+		t.Fatalf("expected CRC %s, got %s", want, fields["63"])
+	}
+}
+
+func TestVietQR_StaticWithoutAmount(t *testing.T) {
+	payload, err := VietQR{BankBIN: "970436", AccountNumber: "0011001234567"}.Payload()
+	mustNoError(t, err)
+
+	fields := parseEMV(t, payload)
+	if fields["01"] != "11" {
+		t.Fatalf("expected static QR, got %q", fields["01"])
+	}
+	if _, ok := fields["54"]; ok {
+		t.Fatalf("expected no amount field")
+	}
+}
+
+func TestVietQR_Validation(t *testing.T) {
+	cases := map[string]VietQR{
+		"short bin":    {BankBIN: "9704", AccountNumber: "123"},
+		"no account":   {BankBIN: "970436"},
+		"long memo":    {BankBIN: "970436", AccountNumber: "123", Memo: "this memo is definitely too long"},
+		"negative amt": {BankBIN: "970436", AccountNumber: "123", Amount: -1},
+	}
+	for name, qr := range cases {
+		if _, err := qr.Payload(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -5,13 +5,18 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/store"
 )
 
-type WishlistController struct{}
+type WishlistController struct {
+	wishlistService *WishlistService
+}
 
 // NewWishlistController tạo instance mới của WishlistController
-func NewWishlistController() *WishlistController {
-	return &WishlistController{}
+func NewWishlistController(st store.Store) *WishlistController {
+	return &WishlistController{
+		wishlistService: NewWishlistService(st),
+	}
 }
 
 // AddToWishlistRequest struct for adding products to wishlist
@@ -42,7 +47,7 @@ func (wc *WishlistController) GetWishlist(c *gin.Context) {
 		userRole = "user" // default role
 	}
 
-	wishlistService := NewWishlistService()
+	wishlistService := wc.wishlistService
 
 	// Validate user role
 	if err := wishlistService.ValidateUserRole(userRole.(string)); err != nil {
@@ -109,7 +114,7 @@ func (wc *WishlistController) AddToWishlist(c *gin.Context) {
 
 	fmt.Printf("AddToWishlist - UserRole: %s\n", userRole.(string))
 
-	wishlistService := NewWishlistService()
+	wishlistService := wc.wishlistService
 
 	// Validate user role
 	if err := wishlistService.ValidateUserRole(userRole.(string)); err != nil {
@@ -195,7 +200,7 @@ func (wc *WishlistController) RemoveFromWishlist(c *gin.Context) {
 		userRole = "user" // default role
 	}
 
-	wishlistService := NewWishlistService()
+	wishlistService := wc.wishlistService
 
 	// Validate user role
 	if err := wishlistService.ValidateUserRole(userRole.(string)); err != nil {
@@ -251,7 +256,7 @@ func (wc *WishlistController) ClearWishlist(c *gin.Context) {
 		userRole = "user" // default role
 	}
 
-	wishlistService := NewWishlistService()
+	wishlistService := wc.wishlistService
 
 	// Validate user role
 	if err := wishlistService.ValidateUserRole(userRole.(string)); err != nil {
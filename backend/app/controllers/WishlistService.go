@@ -6,19 +6,19 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // WishlistService handles business logic for wishlist operations
-type WishlistService struct{}
+type WishlistService struct {
+	store store.Store
+}
 
 // NewWishlistService creates a new instance of WishlistService
-func NewWishlistService() *WishlistService {
-	return &WishlistService{}
+func NewWishlistService(st store.Store) *WishlistService {
+	return &WishlistService{store: st}
 }
 
 // WishlistResult represents the result structure for wishlist operations
@@ -38,11 +38,7 @@ func (ws *WishlistService) GetUserWishlist(userID string) (*WishlistResult, erro
 		return nil, errors.New("user ID không hợp lệ")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("Wishlists")
-
-	var wishlist models.Wishlist
-	err = collection.FindOne(ctx, bson.M{"user_id": userOID}).Decode(&wishlist)
+	wishlist, err := ws.store.Wishlists().FindByUser(ctx, userOID)
 	if err != nil {
 		// Return empty wishlist if not found
 		return &WishlistResult{
@@ -78,20 +74,16 @@ func (ws *WishlistService) FindProductByID(productID string) (*models.Product, e
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Products")
-
-	var product models.Product
 	// Search by the 'id' field (not '_id') which contains product IDs like "P001", "P002", etc.
-	err := collection.FindOne(ctx, bson.M{"id": productID}).Decode(&product)
+	product, err := ws.store.Products().FindByProductID(ctx, productID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == store.ErrNotFound {
 			return nil, errors.New("sản phẩm không tồn tại")
 		}
 		return nil, fmt.Errorf("lỗi tìm kiếm sản phẩm: %v", err)
 	}
 
-	return &product, nil
+	return product, nil
 }
 
 // FindOrCreateWishlist tìm hoặc tạo wishlist mới
@@ -105,14 +97,10 @@ func (ws *WishlistService) FindOrCreateWishlist(userID string) (*models.Wishlist
 		return nil, errors.New("user ID không hợp lệ")
 	}
 
-	db := config.GetDB()
-	collection := db.Collection("Wishlists")
-
-	var wishlist models.Wishlist
-	err = collection.FindOne(ctx, bson.M{"user_id": userOID}).Decode(&wishlist)
+	wishlist, err := ws.store.Wishlists().FindByUser(ctx, userOID)
 	if err != nil {
 		// Create new wishlist if not found
-		wishlist = models.Wishlist{
+		wishlist = &models.Wishlist{
 			UserID:    userOID,
 			Items:     []models.WishlistItem{},
 			CreatedAt: time.Now(),
@@ -120,7 +108,7 @@ func (ws *WishlistService) FindOrCreateWishlist(userID string) (*models.Wishlist
 		}
 	}
 
-	return &wishlist, nil
+	return wishlist, nil
 }
 
 // CheckProductInWishlist kiểm tra sản phẩm có trong wishlist không
@@ -170,31 +158,16 @@ func (ws *WishlistService) AddProductToWishlist(userID, productID string) (*Wish
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Wishlists")
+	isNew := wishlist.ID.IsZero()
+	wishlist.Items = append(wishlist.Items, newItem)
+	wishlist.TotalItems = len(wishlist.Items)
+	wishlist.UpdatedAt = time.Now()
 
-	if wishlist.ID.IsZero() {
-		// Insert new wishlist
-		wishlist.Items = append(wishlist.Items, newItem)
-		result, err := collection.InsertOne(ctx, wishlist)
-		if err != nil {
+	if err := ws.store.Wishlists().Save(ctx, wishlist); err != nil {
+		if isNew {
 			return nil, errors.New("lỗi khi tạo wishlist")
 		}
-		wishlist.ID = result.InsertedID.(primitive.ObjectID)
-	} else {
-		// Update existing wishlist
-		update := bson.M{
-			"$push": bson.M{"items": newItem},
-			"$set":  bson.M{"updatedAt": time.Now()},
-		}
-
-		_, err = collection.UpdateOne(ctx, bson.M{"_id": wishlist.ID}, update)
-		if err != nil {
-			return nil, errors.New("lỗi khi cập nhật wishlist")
-		}
-
-		wishlist.Items = append(wishlist.Items, newItem)
-		wishlist.UpdatedAt = time.Now()
+		return nil, errors.New("lỗi khi cập nhật wishlist")
 	}
 
 	return &WishlistResult{
@@ -230,28 +203,23 @@ func (ws *WishlistService) RemoveProductFromWishlist(userID, productID string) (
 		return nil, errors.New("sản phẩm không có trong wishlist")
 	}
 
-	// Convert userID to ObjectID
-	userOID, err := primitive.ObjectIDFromHex(userID)
+	// Update database
+	current, err := ws.FindOrCreateWishlist(userID)
 	if err != nil {
-		return nil, errors.New("user ID không hợp lệ")
+		return nil, err
 	}
 
-	// Update database
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Wishlists")
-
-	update := bson.M{
-		"$set": bson.M{
-			"items":     newItems,
-			"updatedAt": time.Now(),
-		},
+	if newItems == nil {
+		newItems = []models.WishlistItem{}
 	}
+	current.Items = newItems
+	current.TotalItems = len(newItems)
+	current.UpdatedAt = time.Now()
 
-	_, err = collection.UpdateOne(ctx, bson.M{"user_id": userOID}, update)
-	if err != nil {
+	if err := ws.store.Wishlists().Save(ctx, current); err != nil {
 		return nil, errors.New("lỗi khi cập nhật wishlist")
 	}
 
@@ -272,23 +240,7 @@ func (ws *WishlistService) ClearUserWishlist(userID string) (*WishlistResult, er
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db := config.GetDB()
-	collection := db.Collection("Wishlists")
-
-	update := bson.M{
-		"$set": bson.M{
-			"items":     []models.WishlistItem{},
-			"updatedAt": time.Now(),
-		},
-	}
-
-	// Use upsert to create if doesn't exist
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"user_id": userOID},
-		update,
-		// options.Update().SetUpsert(true) - can add this if needed
-	)
+	err = ws.store.Wishlists().Clear(ctx, userOID)
 	if err != nil {
 		return nil, errors.New("lỗi khi xóa wishlist")
 	}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"testing"
+)
+
+func TestWishlistService_GetUserWishlist(t *testing.T) {
+	t.Run("trả về wishlist rỗng khi chưa có", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+
+		result, err := ws.GetUserWishlist(user.ID.Hex())
+		mustNoError(t, err)
+		if len(result.Items) != 0 || result.Count != 0 {
+			t.Fatalf("expected empty wishlist, got %+v", result)
+		}
+	})
+
+	t.Run("lỗi khi user ID không hợp lệ", func(t *testing.T) {
+		ws := NewWishlistService(newTestStore())
+
+		_, err := ws.GetUserWishlist("invalid")
+		expectError(t, err, "user ID không hợp lệ")
+	})
+}
+
+func TestWishlistService_AddProductToWishlist(t *testing.T) {
+	t.Run("thêm sản phẩm vào wishlist", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		result, err := ws.AddProductToWishlist(user.ID.Hex(), product.ProductID)
+		mustNoError(t, err)
+		if result.Count != 1 || result.Items[0].ProductID != product.ProductID || result.Items[0].ProductName != product.Name {
+			t.Fatalf("unexpected wishlist: %+v", result)
+		}
+
+		stored, err := ws.GetUserWishlist(user.ID.Hex())
+		mustNoError(t, err)
+		if stored.Count != 1 {
+			t.Fatalf("expected stored wishlist to have 1 item, got %d", stored.Count)
+		}
+	})
+
+	t.Run("thêm nhiều sản phẩm", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st)
+
+		_, err := ws.AddProductToWishlist(user.ID.Hex(), p1.ProductID)
+		mustNoError(t, err)
+		result, err := ws.AddProductToWishlist(user.ID.Hex(), p2.ProductID)
+		mustNoError(t, err)
+		if result.Count != 2 {
+			t.Fatalf("expected 2 items, got %d", result.Count)
+		}
+	})
+
+	t.Run("lỗi khi sản phẩm đã có trong wishlist", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+
+		_, err := ws.AddProductToWishlist(user.ID.Hex(), product.ProductID)
+		mustNoError(t, err)
+		_, err = ws.AddProductToWishlist(user.ID.Hex(), product.ProductID)
+		expectError(t, err, "sản phẩm đã có trong wishlist")
+	})
+
+	t.Run("lỗi khi sản phẩm không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+
+		_, err := ws.AddProductToWishlist(user.ID.Hex(), "prod_missing")
+		expectError(t, err, "sản phẩm không tồn tại")
+	})
+
+	t.Run("lỗi khi thiếu product_id", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+
+		_, err := ws.AddProductToWishlist(user.ID.Hex(), "")
+		expectError(t, err, "product_id là bắt buộc")
+	})
+}
+
+func TestWishlistService_RemoveProductFromWishlist(t *testing.T) {
+	t.Run("xóa sản phẩm khỏi wishlist", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+		p1 := createTestProduct(t, st)
+		p2 := createTestProduct(t, st)
+		_, err := ws.AddProductToWishlist(user.ID.Hex(), p1.ProductID)
+		mustNoError(t, err)
+		_, err = ws.AddProductToWishlist(user.ID.Hex(), p2.ProductID)
+		mustNoError(t, err)
+
+		result, err := ws.RemoveProductFromWishlist(user.ID.Hex(), p1.ProductID)
+		mustNoError(t, err)
+		if result.Count != 1 || result.Items[0].ProductID != p2.ProductID {
+			t.Fatalf("unexpected wishlist: %+v", result)
+		}
+
+		count, err := ws.GetWishlistCount(user.ID.Hex())
+		mustNoError(t, err)
+		if count != 1 {
+			t.Fatalf("expected stored count 1, got %d", count)
+		}
+	})
+
+	t.Run("lỗi khi wishlist không tồn tại", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+
+		_, err := ws.RemoveProductFromWishlist(user.ID.Hex(), "prod_missing")
+		expectError(t, err, "wishlist không tồn tại")
+	})
+
+	t.Run("lỗi khi sản phẩm không có trong wishlist", func(t *testing.T) {
+		st := newTestStore()
+		ws := NewWishlistService(st)
+		user := createTestUser(t, st)
+		product := createTestProduct(t, st)
+		_, err := ws.AddProductToWishlist(user.ID.Hex(), product.ProductID)
+		mustNoError(t, err)
+
+		_, err = ws.RemoveProductFromWishlist(user.ID.Hex(), "prod_missing")
+		expectError(t, err, "sản phẩm không có trong wishlist")
+	})
+}
+
+func TestWishlistService_ClearUserWishlist(t *testing.T) {
+	st := newTestStore()
+	ws := NewWishlistService(st)
+	user := createTestUser(t, st)
+	product := createTestProduct(t, st)
+	_, err := ws.AddProductToWishlist(user.ID.Hex(), product.ProductID)
+	mustNoError(t, err)
+
+	result, err := ws.ClearUserWishlist(user.ID.Hex())
+	mustNoError(t, err)
+	if result.Count != 0 {
+		t.Fatalf("expected empty result, got %+v", result)
+	}
+
+	count, err := ws.GetWishlistCount(user.ID.Hex())
+	mustNoError(t, err)
+	if count != 0 {
+		t.Fatalf("expected stored wishlist to be empty, got %d", count)
+	}
+}
+
+func TestWishlistService_IsProductInWishlist(t *testing.T) {
+	st := newTestStore()
+	ws := NewWishlistService(st)
+	user := createTestUser(t, st)
+	p1 := createTestProduct(t, st)
+	p2 := createTestProduct(t, st)
+	_, err := ws.AddProductToWishlist(user.ID.Hex(), p1.ProductID)
+	mustNoError(t, err)
+
+	inWishlist, err := ws.IsProductInWishlist(user.ID.Hex(), p1.ProductID)
+	mustNoError(t, err)
+	if !inWishlist {
+		t.Fatalf("expected product to be in wishlist")
+	}
+
+	inWishlist, err = ws.IsProductInWishlist(user.ID.Hex(), p2.ProductID)
+	mustNoError(t, err)
+	if inWishlist {
+		t.Fatalf("expected product not to be in wishlist")
+	}
+}
+
+func TestWishlistService_ValidateUserRole(t *testing.T) {
+	ws := NewWishlistService(newTestStore())
+
+	expectError(t, ws.ValidateUserRole("admin"), "admin không có quyền thao tác với wishlist")
+	mustNoError(t, ws.ValidateUserRole("user"))
+}
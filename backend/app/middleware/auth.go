@@ -11,9 +11,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/mingfulsnack/app/config"
 	"github.com/mingfulsnack/app/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -97,20 +96,21 @@ func AuthMiddleware() gin.HandlerFunc {
 	})
 }
 
-// OptionalAuthMiddleware - sets user info if token exists, but continues if no token (for guest checkout)
+// OptionalAuthMiddleware - sets user info if token exists, but continues if no token (for guest checkout).
+// Không có token đăng nhập hợp lệ thì dùng guest token trong header X-Guest-Token (nếu có).
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			// No token provided - continue without setting user info (guest)
-			c.Set("user", nil)
+			setGuestFromHeader(c)
 			c.Next()
 			return
 		}
 
 		// Check if the header starts with "Bearer "
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.Set("user", nil)
+			setGuestFromHeader(c)
 			c.Next()
 			return
 		}
@@ -138,7 +138,7 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 		if err != nil || !token.Valid {
 			log.Printf("JWT verify error (optional): %v", err)
 			// Invalid token - continue as guest
-			c.Set("user", nil)
+			setGuestFromHeader(c)
 			c.Next()
 			return
 		}
@@ -317,7 +317,7 @@ func StaffOrAdminMiddleware() gin.HandlerFunc {
 }
 
 // ValidateOrderAccess middleware để kiểm tra quyền truy cập đơn hàng
-func ValidateOrderAccess() gin.HandlerFunc {
+func ValidateOrderAccess(orders store.OrderStore) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		orderID := c.Param("id")
 		if orderID == "" {
@@ -348,11 +348,7 @@ func ValidateOrderAccess() gin.HandlerFunc {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		db := config.GetDB()
-		collection := db.Collection("Orders")
-
-		var order models.Order
-		err = collection.FindOne(ctx, bson.M{"_id": orderOID}).Decode(&order)
+		found, err := orders.FindByID(ctx, orderOID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{
 				"success": false,
@@ -362,6 +358,8 @@ func ValidateOrderAccess() gin.HandlerFunc {
 			return
 		}
 
+		order := *found
+
 		// Check access permissions
 		userRole, _ := c.Get("role")
 		userID, _ := c.Get("userID")
@@ -400,6 +398,24 @@ func ValidateOrderAccess() gin.HandlerFunc {
 			return
 		}
 
+		// Khách chưa đăng nhập chỉ truy cập được đơn đặt bằng guest token của mình
+		if userRole == RoleGuest {
+			guestID, _ := c.Get("guestID")
+			guestOID, err := primitive.ObjectIDFromHex(fmt.Sprint(guestID))
+			if err != nil || order.GuestID == nil || *order.GuestID != guestOID {
+				c.JSON(http.StatusForbidden, gin.H{
+					"success": false,
+					"message": "Không có quyền truy cập đơn hàng này",
+				})
+				c.Abort()
+				return
+			}
+
+			c.Set("order", order)
+			c.Next()
+			return
+		}
+
 		c.JSON(http.StatusForbidden, gin.H{
 			"success": false,
 			"message": "Không có quyền truy cập",
@@ -409,7 +425,7 @@ func ValidateOrderAccess() gin.HandlerFunc {
 }
 
 // ValidateProduct middleware để kiểm tra sản phẩm tồn tại và active
-func ValidateProduct() gin.HandlerFunc {
+func ValidateProduct(products store.ProductStore) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		productID := c.Param("id")
 		if productID == "" {
@@ -440,11 +456,7 @@ func ValidateProduct() gin.HandlerFunc {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		db := config.GetDB()
-		collection := db.Collection("Products")
-
-		var product models.Product
-		err = collection.FindOne(ctx, bson.M{"_id": productOID}).Decode(&product)
+		found, err := products.FindByObjectID(ctx, productOID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{
 				"success": false,
@@ -461,7 +473,7 @@ func ValidateProduct() gin.HandlerFunc {
 		// For now, all products are considered active unless we add a Status field to Product model
 		_ = userRole // Use the variable to avoid unused variable error
 
-		c.Set("product", product)
+		c.Set("product", *found)
 		c.Next()
 	})
 }
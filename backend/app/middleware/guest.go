@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GuestTokenHeader là header chứa guest token của khách chưa đăng nhập
+const GuestTokenHeader = "X-Guest-Token"
+
+// GuestTokenTTL là thời hạn của guest token (giỏ hàng và đơn hàng của khách gắn với token này)
+const GuestTokenTTL = 30 * 24 * time.Hour
+
+// RoleGuest là role được đặt vào context khi request dùng guest token
+const RoleGuest = "guest"
+
+// guestTokenSecret tách khóa ký guest token khỏi token đăng nhập để guest token
+// không thể dùng qua AuthMiddleware như một tài khoản
+func guestTokenSecret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "fallback_jwt_secret_g47_project_2024"
+	}
+	return []byte(secret + ":guest")
+}
+
+// NewGuestToken tạo guest id mới và token đã ký cho id đó
+func NewGuestToken() (string, primitive.ObjectID, time.Time, error) {
+	guestID := primitive.NewObjectID()
+	expiresAt := time.Now().Add(GuestTokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"guestID": guestID.Hex(),
+		"role":    RoleGuest,
+		"exp":     expiresAt.Unix(),
+	})
+	signed, err := token.SignedString(guestTokenSecret())
+	return signed, guestID, expiresAt, err
+}
+
+// ParseGuestToken kiểm tra chữ ký, hạn dùng và trả về guest id của token
+func ParseGuestToken(tokenString string) (primitive.ObjectID, error) {
+	tokenString = strings.Trim(strings.TrimSpace(tokenString), "\"")
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("invalid signing method")
+		}
+		return guestTokenSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return primitive.NilObjectID, fmt.Errorf("guest token không hợp lệ hoặc đã hết hạn")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["role"] != RoleGuest {
+		return primitive.NilObjectID, fmt.Errorf("guest token không hợp lệ hoặc đã hết hạn")
+	}
+	guestID, _ := claims["guestID"].(string)
+	id, err := primitive.ObjectIDFromHex(guestID)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("guest token không hợp lệ hoặc đã hết hạn")
+	}
+	return id, nil
+}
+
+// setGuestFromHeader đặt guest id vào context (userID, guestID, role=guest) nếu request có guest token hợp lệ.
+// userID được đặt bằng guest id để giỏ hàng và đơn hàng của khách dùng chung luồng với user.
+func setGuestFromHeader(c *gin.Context) {
+	c.Set("user", nil)
+	tokenString := c.GetHeader(GuestTokenHeader)
+	if tokenString == "" {
+		return
+	}
+	guestID, err := ParseGuestToken(tokenString)
+	if err != nil {
+		return
+	}
+	c.Set("userID", guestID.Hex())
+	c.Set("guestID", guestID.Hex())
+	c.Set("role", RoleGuest)
+}
+
+// RequireUserOrGuest dùng sau OptionalAuthMiddleware: chặn request không có token đăng nhập và cũng không có guest token
+func RequireUserOrGuest() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if _, exists := c.Get("userID"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Vui lòng đăng nhập hoặc gửi guest token (" + GuestTokenHeader + ")",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+}
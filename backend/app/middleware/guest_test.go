@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGuestToken_OrderAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := store.NewMemoryStore()
+	token, guestID, _, err := NewGuestToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	order := &models.Order{OrderNumber: "ORD-1", GuestID: &guestID, CreatedAt: time.Now()}
+	if err := st.Orders().Insert(context.Background(), order); err != nil {
+		t.Fatalf("insert order: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/orders/:id", OptionalAuthMiddleware(), RequireUserOrGuest(), ValidateOrderAccess(st.Orders()), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"role": c.GetString("role"), "user": c.GetString("userID")})
+	})
+	router.GET("/profile", AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	send := func(path string, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("/orders/"+order.ID.Hex(), GuestTokenHeader, token); w.Code != http.StatusOK {
+		t.Fatalf("expected guest to view own order, got %d %s", w.Code, w.Body)
+	}
+	other, _, _, _ := NewGuestToken()
+	if w := send("/orders/"+order.ID.Hex(), GuestTokenHeader, other); w.Code != http.StatusForbidden {
+		t.Fatalf("expected other guest to be forbidden, got %d", w.Code)
+	}
+	if w := send("/orders/"+order.ID.Hex(), "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected request without token to be rejected, got %d", w.Code)
+	}
+
+	// Guest token không dùng được như token đăng nhập và ngược lại
+	if w := send("/profile", "Authorization", "Bearer "+token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected guest token to be rejected by AuthMiddleware, got %d", w.Code)
+	}
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"guestID": guestID.Hex(), "role": RoleGuest, "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("fallback_jwt_secret_g47_project_2024"))
+	if _, err := ParseGuestToken(forged); err == nil {
+		t.Fatalf("expected token signed with login secret to be rejected")
+	}
+	if _, err := ParseGuestToken(primitive.NewObjectID().Hex()); err == nil {
+		t.Fatalf("expected malformed guest token to be rejected")
+	}
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// IdempotencyHeader là header client gửi kèm để có thể retry request an toàn
+const IdempotencyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL là thời gian lưu response để phát lại
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyProcessingLease là thời gian giữ key đang xử lý. Nếu process chết giữa request, key tự hết hạn
+// sau khoảng này để client thử lại được; Complete gia hạn key thành ttl khi đã có response.
+const IdempotencyProcessingLease = time.Minute
+
+// responseRecorder ghi lại body response để lưu vào IdempotencyRecord
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency phát lại response đã lưu khi client gửi lại cùng Idempotency-Key trong khoảng ttl
+// thay vì chạy lại handler. Key được gắn với method, route và user (nếu đã xác thực) nên middleware
+// dùng được cho mọi endpoint thay đổi dữ liệu như tạo đơn hàng hay callback thanh toán.
+// Request không có header được xử lý bình thường.
+func Idempotency(records store.IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		clientKey := strings.TrimSpace(c.GetHeader(IdempotencyHeader))
+		if clientKey == "" {
+			c.Next()
+			return
+		}
+
+		if len(clientKey) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Idempotency-Key không được dài quá 255 ký tự",
+			})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Không thể đọc dữ liệu request",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := idempotencyScope(c) + "|" + clientKey
+		fingerprint := sha256.Sum256(body)
+		now := time.Now()
+
+		lease := IdempotencyProcessingLease
+		if ttl < lease {
+			lease = ttl
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		existing, err := records.Claim(ctx, &models.IdempotencyRecord{
+			Key:         key,
+			Fingerprint: hex.EncodeToString(fingerprint[:]),
+			Status:      models.IdempotencyProcessing,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(lease),
+		})
+		cancel()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Lỗi khi kiểm tra Idempotency-Key",
+			})
+			c.Abort()
+			return
+		}
+
+		if existing != nil {
+			replayIdempotent(c, existing, hex.EncodeToString(fingerprint[:]))
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Handler panic thì nhả key trước khi panic tiếp lên Recovery, để client thử lại được
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			releaseIdempotencyKey(records, key)
+			if r := recover(); r != nil {
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		// Lỗi server không được lưu để client có thể thử lại với cùng key
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		completed = true
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := records.Complete(ctx, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes(), time.Now().Add(ttl)); err != nil {
+			log.Printf("Failed to store idempotent response for %s: %v", key, err)
+		}
+	})
+}
+
+// releaseIdempotencyKey xóa key đang xử lý để request sau với cùng key được chạy lại
+func releaseIdempotencyKey(records store.IdempotencyStore, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := records.Release(ctx, key); err != nil {
+		log.Printf("Failed to release idempotency key %s: %v", key, err)
+	}
+}
+
+// idempotencyScope gắn key với method, route và user để key của người khác không bị trùng
+func idempotencyScope(c *gin.Context) string {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	scope := c.Request.Method + " " + route
+	if userID, exists := c.Get("userID"); exists {
+		scope += " " + fmt.Sprint(userID)
+	}
+	return scope
+}
+
+// replayIdempotent trả về response đã lưu, hoặc báo lỗi nếu request trước chưa xong hay body khác
+func replayIdempotent(c *gin.Context, existing *models.IdempotencyRecord, fingerprint string) {
+	if existing.Fingerprint != fingerprint {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"message": "Idempotency-Key đã được dùng cho một request khác",
+		})
+		c.Abort()
+		return
+	}
+
+	if existing.Status != models.IdempotencyCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Request với Idempotency-Key này đang được xử lý",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+	c.Abort()
+}
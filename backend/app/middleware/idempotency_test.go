@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/models"
+	"github.com/mingfulsnack/app/store"
+)
+
+// newIdempotentRouter tạo router với handler đếm số lần được gọi
+func newIdempotentRouter(st store.Store, ttl time.Duration, status int, calls *int32) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/orders", func(c *gin.Context) {
+		if userID := c.GetHeader("X-User"); userID != "" {
+			c.Set("userID", userID)
+		}
+		c.Next()
+	}, Idempotency(st.Idempotency(), ttl), func(c *gin.Context) {
+		n := atomic.AddInt32(calls, 1)
+		c.JSON(status, gin.H{"success": status < 400, "call": n})
+	})
+	return router
+}
+
+func sendIdempotent(router *gin.Engine, key, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	var calls int32
+	router := newIdempotentRouter(store.NewMemoryStore(), time.Hour, http.StatusCreated, &calls)
+
+	first := sendIdempotent(router, "key-1", "u1", `{"a":1}`)
+	second := sendIdempotent(router, "key-1", "u1", `{"a":1}`)
+
+	if calls != 1 {
+		t.Fatalf("expected handler to run once, ran %d times", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected replay of %d %s, got %d %s", first.Code, first.Body, second.Code, second.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected Idempotent-Replayed header")
+	}
+}
+
+func TestIdempotency_Scope(t *testing.T) {
+	var calls int32
+	router := newIdempotentRouter(store.NewMemoryStore(), time.Hour, http.StatusCreated, &calls)
+
+	// Không có header thì luôn chạy handler
+	sendIdempotent(router, "", "u1", `{}`)
+	sendIdempotent(router, "", "u1", `{}`)
+	// Cùng key nhưng khác user là hai request khác nhau
+	sendIdempotent(router, "key-1", "u1", `{}`)
+	sendIdempotent(router, "key-1", "u2", `{}`)
+
+	if calls != 4 {
+		t.Fatalf("expected handler to run 4 times, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_RejectsDifferentBody(t *testing.T) {
+	var calls int32
+	router := newIdempotentRouter(store.NewMemoryStore(), time.Hour, http.StatusCreated, &calls)
+
+	sendIdempotent(router, "key-1", "u1", `{"a":1}`)
+	w := sendIdempotent(router, "key-1", "u1", `{"a":2}`)
+
+	if w.Code != http.StatusUnprocessableEntity || calls != 1 {
+		t.Fatalf("expected 422 without running handler, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	var calls int32
+	router := newIdempotentRouter(store.NewMemoryStore(), time.Hour, http.StatusInternalServerError, &calls)
+
+	sendIdempotent(router, "key-1", "u1", `{}`)
+	sendIdempotent(router, "key-1", "u1", `{}`)
+
+	if calls != 2 {
+		t.Fatalf("expected retry after server error, handler ran %d times", calls)
+	}
+}
+
+func TestIdempotency_ExpiredKey(t *testing.T) {
+	var calls int32
+	router := newIdempotentRouter(store.NewMemoryStore(), time.Nanosecond, http.StatusCreated, &calls)
+
+	sendIdempotent(router, "key-1", "u1", `{}`)
+	time.Sleep(time.Millisecond)
+	sendIdempotent(router, "key-1", "u1", `{}`)
+
+	if calls != 2 {
+		t.Fatalf("expected expired key to run handler again, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	st := store.NewMemoryStore()
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/orders", Idempotency(st.Idempotency(), time.Hour), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"success": true})
+	})
+
+	done := make(chan struct{})
+	go func() {
+		sendIdempotent(router, "key-1", "", `{}`)
+		close(done)
+	}()
+	<-started
+
+	w := sendIdempotent(router, "key-1", "", `{}`)
+	close(release)
+	<-done
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 while first request is processing, got %d", w.Code)
+	}
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	var calls int32
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, err any) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	router.POST("/orders", Idempotency(store.NewMemoryStore().Idempotency(), time.Hour), func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"success": true})
+	})
+
+	if w := sendIdempotent(router, "key-1", "", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 from panic, got %d", w.Code)
+	}
+	if w := sendIdempotent(router, "key-1", "", `{}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Fatalf("expected retry after panic to run handler, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotency_ProcessingClaimHasLease(t *testing.T) {
+	records := store.NewMemoryStore().Idempotency()
+	peek := func() *models.IdempotencyRecord {
+		existing, err := records.Claim(context.Background(), &models.IdempotencyRecord{Key: "POST /orders|key-1"})
+		if err != nil || existing == nil {
+			t.Fatalf("expected existing record, got %v %v", existing, err)
+		}
+		return existing
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var processing *models.IdempotencyRecord
+	router.POST("/orders", Idempotency(records, time.Hour), func(c *gin.Context) {
+		processing = peek()
+		c.JSON(http.StatusCreated, gin.H{"success": true})
+	})
+	sendIdempotent(router, "key-1", "", `{}`)
+
+	// Process chết giữa request thì key đang xử lý chỉ bị giữ trong thời gian lease
+	if processing.Status != models.IdempotencyProcessing || time.Until(processing.ExpiresAt) > IdempotencyProcessingLease {
+		t.Fatalf("expected processing claim to expire within lease, got %+v", processing)
+	}
+	// Đã có response thì giữ tới hết ttl
+	if completed := peek(); completed.Status != models.IdempotencyCompleted || time.Until(completed.ExpiresAt) < 59*time.Minute {
+		t.Fatalf("expected completed record to be kept for ttl, got %+v", completed)
+	}
+}
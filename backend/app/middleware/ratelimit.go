@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter giới hạn số lần gọi theo key trong một cửa sổ thời gian cố định.
+// Bộ đếm nằm trong bộ nhớ nên giới hạn áp dụng riêng cho từng instance server.
+type RateLimiter struct {
+	limit     int
+	window    time.Duration
+	mu        sync.Mutex
+	counters  map[string]*rateCounter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type rateCounter struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewRateLimiter tạo limiter cho phép tối đa limit lần gọi mỗi window cho mỗi key
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:    limit,
+		window:   window,
+		counters: make(map[string]*rateCounter),
+		now:      time.Now,
+	}
+}
+
+// Allow tính một lần gọi cho key. Khi vượt giới hạn trả về false và thời gian phải chờ đến cửa sổ tiếp theo.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counter, now := l.counter(key)
+	if counter.count >= l.limit {
+		return false, counter.resetAt.Sub(now)
+	}
+	counter.count++
+	return true, 0
+}
+
+// Check giống Allow nhưng không tính lượt; dùng cùng Hit khi chỉ tính các lần gọi thất bại
+func (l *RateLimiter) Check(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counter, now := l.counter(key)
+	if counter.count >= l.limit {
+		return false, counter.resetAt.Sub(now)
+	}
+	return true, 0
+}
+
+// Hit tính một lượt cho key
+func (l *RateLimiter) Hit(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counter, _ := l.counter(key)
+	counter.count++
+}
+
+// counter trả về bộ đếm của key trong cửa sổ hiện tại; gọi khi đang giữ l.mu
+func (l *RateLimiter) counter(key string) (*rateCounter, time.Time) {
+	now := l.now()
+	// Dọn các key đã hết cửa sổ để map không lớn dần theo số IP
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, counter := range l.counters {
+			if !now.Before(counter.resetAt) {
+				delete(l.counters, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	counter, ok := l.counters[key]
+	if !ok || !now.Before(counter.resetAt) {
+		counter = &rateCounter{resetAt: now.Add(l.window)}
+		l.counters[key] = counter
+	}
+	return counter, now
+}
+
+// RateLimit chặn request vượt giới hạn của limiter theo IP client với mã 429 và header Retry-After.
+// scope tách bộ đếm khi dùng chung limiter cho nhiều route. IP client chỉ lấy từ X-Forwarded-For khi
+// request đi qua proxy tin cậy (xem TRUSTED_PROXIES ở cmd/server).
+func RateLimit(limiter *RateLimiter, scope string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		allowed, retryAfter := limiter.Allow(scope + "|" + c.ClientIP())
+		if !allowed {
+			RejectRateLimited(c, retryAfter)
+			return
+		}
+		c.Next()
+	})
+}
+
+// RejectRateLimited trả về 429 kèm Retry-After (giây, làm tròn lên)
+func RejectRateLimited(c *gin.Context, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"success": false,
+		"message": "Bạn đã thử quá nhiều lần, vui lòng thử lại sau " + strconv.Itoa(seconds) + " giây",
+	})
+	c.Abort()
+}
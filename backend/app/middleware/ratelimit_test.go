@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(2, time.Minute)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	router := gin.New()
+	router.POST("/lookup", RateLimit(limiter, "lookup"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	send := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/lookup", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send("10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+	w := send("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := send("10.0.0.2"); w.Code != http.StatusOK {
+		t.Fatalf("expected other IP to be allowed, got %d", w.Code)
+	}
+
+	// Cửa sổ mới thì được gọi lại
+	now = now.Add(time.Minute)
+	if w := send("10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("expected limit to reset after window, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_IgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(1, time.Minute)
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	router.POST("/lookup", RateLimit(limiter, "lookup"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	send := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/lookup", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("1.1.1.1"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	// Đổi X-Forwarded-For không tạo được bộ đếm mới khi không qua proxy tin cậy
+	if code := send("2.2.2.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected spoofed header to be ignored, got %d", code)
+	}
+}
+
+func TestRateLimiter_CheckAndHit(t *testing.T) {
+	limiter := NewRateLimiter(2, time.Minute)
+
+	// Check không tính lượt
+	for i := 0; i < 5; i++ {
+		if allowed, _ := limiter.Check("order|ip"); !allowed {
+			t.Fatalf("check %d: expected allowed", i+1)
+		}
+	}
+	limiter.Hit("order|ip")
+	limiter.Hit("order|ip")
+	if allowed, retryAfter := limiter.Check("order|ip"); allowed || retryAfter <= 0 {
+		t.Fatalf("expected blocked after 2 hits, got %v %v", allowed, retryAfter)
+	}
+	if allowed, _ := limiter.Check("order|other-ip"); !allowed {
+		t.Fatalf("expected other key to be allowed")
+	}
+}
@@ -17,21 +17,28 @@ type CartItem struct {
 	ProductName  string             `bson:"product_name" json:"product_name"`
 	ProductImage string             `bson:"product_image" json:"product_image"`
 	ProductSlug  string             `bson:"product_slug" json:"product_slug"`
-	Price        float64            `bson:"price" json:"price"` // Changed to float64 for monetary values
+	Price        Money              `bson:"price" json:"price"`           // Giá hiệu lực (đã gồm sale_price/flash sale)
+	ListPrice    Money              `bson:"list_price" json:"list_price"` // Giá niêm yết của sản phẩm
+	FlashSaleID  string             `bson:"flash_sale_id,omitempty" json:"flash_sale_id,omitempty"`
 	Quantity     int                `bson:"quantity" json:"quantity"`
-	Total        float64            `bson:"total" json:"total"` // Changed to float64 for monetary values
+	Total        Money              `bson:"total" json:"total"`       // Integer minor units (see Money)
+	Discount     Money              `bson:"discount" json:"discount"` // Tổng giảm từ khuyến mãi tự động
+	Promotions   []AppliedPromotion `bson:"promotions,omitempty" json:"promotions,omitempty"`
 }
 
 // Cart struct tương đương với cartSchema trong JS
 type Cart struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
-	CartType    string             `bson:"cart_type" json:"cart_type"` // "cart", "wishlist", "compare"
-	Items       []CartItem         `bson:"items" json:"items"`
-	TotalItems  int                `bson:"total_items" json:"total_items"`
-	TotalAmount float64            `bson:"total_amount" json:"total_amount"` // Changed to float64 for monetary values
-	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CartType       string             `bson:"cart_type" json:"cart_type"` // "cart", "wishlist", "compare"
+	Items          []CartItem         `bson:"items" json:"items"`
+	TotalItems     int                `bson:"total_items" json:"total_items"`
+	TotalAmount    Money              `bson:"total_amount" json:"total_amount"`       // Integer minor units (see Money), đã trừ khuyến mãi
+	DiscountAmount Money              `bson:"discount_amount" json:"discount_amount"` // Tổng giảm từ khuyến mãi tự động
+	Promotions     []AppliedPromotion `bson:"promotions,omitempty" json:"promotions,omitempty"`
+	CouponCode     string             `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"` // Mã giảm giá đang áp dụng
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
 
 func EnsureCartCollection(ctx context.Context, db *mongo.Database) (*mongo.Collection, error) {
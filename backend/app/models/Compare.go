@@ -18,7 +18,7 @@ type CompareItem struct {
 	ProductName  string             `bson:"product_name" json:"product_name"`
 	ProductImage string             `bson:"product_image" json:"product_image"`
 	ProductSlug  string             `bson:"product_slug" json:"product_slug"`
-	Price        float64            `bson:"price" json:"price"` // Changed to float64 for monetary values
+	Price        Money              `bson:"price" json:"price"` // Integer minor units (see Money)
 	AddedAt      time.Time          `bson:"added_at" json:"added_at"`
 }
 
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Loại mã giảm giá
+const (
+	CouponPercentage = "percentage"
+	CouponFixed      = "fixed"
+)
+
+// Coupon là mã giảm giá do admin tạo
+type Coupon struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code           string             `bson:"code" json:"code"` // Luôn viết hoa, unique
+	Description    string             `bson:"description,omitempty" json:"description,omitempty"`
+	Type           string             `bson:"type" json:"type"`                 // "percentage", "fixed"
+	Percent        float64            `bson:"percent,omitempty" json:"percent"` // Dùng cho percentage, vd 10 = 10%
+	Amount         Money              `bson:"amount" json:"amount"`             // Dùng cho fixed
+	MaxDiscount    Money              `bson:"max_discount" json:"max_discount"` // Giảm tối đa cho percentage, 0 = không giới hạn
+	MinOrderAmount Money              `bson:"min_order_amount" json:"min_order_amount"`
+	UsageLimit     int                `bson:"usage_limit" json:"usage_limit"`       // Tổng số lượt, 0 = không giới hạn
+	PerUserLimit   int                `bson:"per_user_limit" json:"per_user_limit"` // Số lượt mỗi user, 0 = không giới hạn
+	UsedCount      int                `bson:"used_count" json:"used_count"`
+	UserUsage      map[string]int     `bson:"user_usage,omitempty" json:"-"` // user id -> số lượt đã dùng
+	StartsAt       *time.Time         `bson:"starts_at,omitempty" json:"starts_at"`
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty" json:"expires_at"`
+	Categories     []string           `bson:"categories,omitempty" json:"categories"` // Category.id được áp dụng, rỗng = tất cả
+	Products       []string           `bson:"products,omitempty" json:"products"`     // Product.id được áp dụng, rỗng = tất cả
+	IsActive       bool               `bson:"is_active" json:"is_active"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// EnsureCouponCollection khởi tạo collection và index unique trên code
+func EnsureCouponCollection(ctx context.Context, db *mongo.Database) (*mongo.Collection, error) {
+	coll := db.Collection("Coupons")
+
+	idxModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	if _, err := coll.Indexes().CreateOne(ctx, idxModel); err != nil {
+		// Ignore index conflicts, collections might already exist
+		return coll, nil
+	}
+	return coll, nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FlashSale là một đợt bán giá sốc cho một sản phẩm, giới hạn tổng số lượng và số lượng mỗi khách
+type FlashSale struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name             string             `bson:"name" json:"name"`
+	ProductID        string             `bson:"product_id" json:"product_id"` // Product.id
+	Price            Money              `bson:"price" json:"price"`
+	Quantity         int                `bson:"quantity" json:"quantity"`                     // Tổng số lượng bán giá flash sale
+	PerCustomerLimit int                `bson:"per_customer_limit" json:"per_customer_limit"` // 0 = không giới hạn
+	SoldCount        int                `bson:"sold_count" json:"sold_count"`
+	CustomerUsage    map[string]int     `bson:"customer_usage,omitempty" json:"-"` // user id -> số lượng đã mua
+	StartsAt         time.Time          `bson:"starts_at" json:"starts_at"`
+	EndsAt           time.Time          `bson:"ends_at" json:"ends_at"`
+	IsActive         bool               `bson:"is_active" json:"is_active"`
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Remaining là số lượng còn bán được với giá flash sale cho userID ("" = không tính giới hạn mỗi khách)
+func (f *FlashSale) Remaining(userID string) int {
+	remaining := f.Quantity - f.SoldCount
+	if userID != "" && f.PerCustomerLimit > 0 {
+		if left := f.PerCustomerLimit - f.CustomerUsage[userID]; left < remaining {
+			remaining = left
+		}
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// RunningAt kiểm tra flash sale đang diễn ra tại thời điểm now
+func (f *FlashSale) RunningAt(now time.Time) bool {
+	return f.IsActive && !now.Before(f.StartsAt) && now.Before(f.EndsAt)
+}
+
+// EnsureFlashSaleCollection khởi tạo collection và index tra cứu theo sản phẩm
+func EnsureFlashSaleCollection(ctx context.Context, db *mongo.Database) (*mongo.Collection, error) {
+	coll := db.Collection("FlashSales")
+
+	idxModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "product_id", Value: 1}, {Key: "ends_at", Value: 1}},
+		Options: options.Index(),
+	}
+
+	if _, err := coll.Indexes().CreateOne(ctx, idxModel); err != nil {
+		// Ignore index conflicts, collections might already exist
+		return coll, nil
+	}
+	return coll, nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trạng thái của IdempotencyRecord
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyRecord lưu response của request có header Idempotency-Key để phát lại khi client gửi lại
+type IdempotencyRecord struct {
+	Key         string    `bson:"_id" json:"key"`                 // scope (method, route, user) + key của client
+	Fingerprint string    `bson:"fingerprint" json:"fingerprint"` // sha256 của body request
+	Status      string    `bson:"status" json:"status"`           // processing, completed
+	StatusCode  int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	ContentType string    `bson:"content_type,omitempty" json:"content_type,omitempty"`
+	Body        []byte    `bson:"body,omitempty" json:"-"`
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+// EnsureIdempotencyCollection tạo TTL index để Mongo tự xóa record hết hạn
+func EnsureIdempotencyCollection(ctx context.Context, db *mongo.Database) (*mongo.Collection, error) {
+	coll := db.Collection("IdempotencyKeys")
+
+	idxModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	if _, err := coll.Indexes().CreateOne(ctx, idxModel); err != nil {
+		// Ignore index conflicts, collections might already exist
+		return coll, nil
+	}
+	return coll, nil
+}
@@ -0,0 +1,269 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// DefaultCurrency là đơn vị tiền của shop, dùng khi Money không ghi currency
+const DefaultCurrency = "VND"
+
+// currencyExponents: số chữ số thập phân của đơn vị nhỏ nhất (ISO 4217)
+var currencyExponents = map[string]int{
+	"VND": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"USD": 2,
+	"EUR": 2,
+}
+
+// Money lưu số tiền dưới dạng số nguyên theo đơn vị nhỏ nhất của currency
+// (đồng với VND, cent với USD) để cộng/nhân không bị sai số float.
+//
+// BSON: currency mặc định được lưu là int64 (đơn vị nhỏ nhất) để sort và $sum
+// vẫn chạy chung với dữ liệu cũ; currency khác lưu dạng {amount, currency}.
+// Dữ liệu cũ kiểu double/int32 được hiểu là số tiền theo đơn vị chính.
+//
+// JSON: trả ra số theo đơn vị chính (giữ nguyên format cũ cho frontend).
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney tạo Money từ số tiền theo đơn vị nhỏ nhất
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: Money{Currency: currency}.CurrencyCode()}
+}
+
+// VND tạo Money theo đồng
+func VND(amount int64) Money {
+	return Money{Amount: amount, Currency: "VND"}
+}
+
+// MoneyFromMajor tạo Money từ số tiền theo đơn vị chính (vd 12.34 USD), làm tròn về đơn vị nhỏ nhất
+func MoneyFromMajor(amount float64, currency string) Money {
+	return NewMoney(int64(math.Round(amount*scaleOf(currency))), currency)
+}
+
+// MoneyFromMajorChecked như MoneyFromMajor nhưng trả về lỗi nếu amount không hữu hạn (NaN, Inf)
+// hoặc vượt quá int64 sau khi đổi về đơn vị nhỏ nhất, thay vì tạo ra số tiền sai
+func MoneyFromMajorChecked(amount float64, currency string) (Money, error) {
+	minor := math.Round(amount * scaleOf(currency))
+	if math.IsNaN(minor) || math.IsInf(minor, 0) || minor >= math.MaxInt64 || minor < math.MinInt64 {
+		return Money{}, fmt.Errorf("money: số tiền không hợp lệ %v", amount)
+	}
+	return NewMoney(int64(minor), currency), nil
+}
+
+// CurrencyCode trả về currency, rỗng nghĩa là DefaultCurrency
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(m.Currency)
+}
+
+// Add cộng hai số tiền cùng currency
+func (m Money) Add(other Money) Money {
+	currency := m.sameCurrency(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: currency}
+}
+
+// Sub trừ hai số tiền cùng currency
+func (m Money) Sub(other Money) Money {
+	currency := m.sameCurrency(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: currency}
+}
+
+// Mul nhân đơn giá với số lượng
+func (m Money) Mul(qty int) Money {
+	return Money{Amount: m.Amount * int64(qty), Currency: m.Currency}
+}
+
+// IsZero kiểm tra số tiền bằng 0
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Cmp so sánh hai số tiền cùng currency: -1, 0 hoặc 1
+func (m Money) Cmp(other Money) int {
+	m.sameCurrency(other)
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Major trả về số tiền theo đơn vị chính (chỉ dùng để hiển thị)
+func (m Money) Major() float64 {
+	return float64(m.Amount) / scaleOf(m.Currency)
+}
+
+// String format số tiền theo đơn vị chính kèm currency, vd "150000 VND", "12.50 USD"
+func (m Money) String() string {
+	return m.majorString() + " " + m.CurrencyCode()
+}
+
+// sameCurrency trả về currency chung. Money{} (zero value) nhận currency của bên kia
+// để có thể dùng làm giá trị khởi đầu khi cộng dồn.
+func (m Money) sameCurrency(other Money) string {
+	switch {
+	case m == Money{}:
+		return other.Currency
+	case other == Money{}:
+		return m.Currency
+	}
+	if m.CurrencyCode() != other.CurrencyCode() {
+		panic(fmt.Sprintf("money: currency mismatch %s vs %s", m.CurrencyCode(), other.CurrencyCode()))
+	}
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return other.Currency
+}
+
+// majorString format số tiền theo đơn vị chính mà không qua float
+func (m Money) majorString() string {
+	exp := exponentOf(m.Currency)
+	if exp == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := fmt.Sprintf("%0*d", exp+1, amount)
+	cut := len(digits) - exp
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+func exponentOf(currency string) int {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+func scaleOf(currency string) float64 {
+	return math.Pow10(exponentOf(currency))
+}
+
+// isDefaultCurrency: currency được lưu dạng số trong BSON
+func (m Money) isDefaultCurrency() bool {
+	return m.CurrencyCode() == DefaultCurrency
+}
+
+// moneyDoc là dạng BSON của Money có currency khác DefaultCurrency
+type moneyDoc struct {
+	Amount   int64  `bson:"amount"`
+	Currency string `bson:"currency"`
+}
+
+// MarshalBSONValue implements bson.ValueMarshaler
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if m.isDefaultCurrency() {
+		return bsontype.Int64, bsoncore.AppendInt64(nil, m.Amount), nil
+	}
+	data, err := bson.Marshal(moneyDoc{Amount: m.Amount, Currency: m.CurrencyCode()})
+	return bsontype.EmbeddedDocument, data, err
+}
+
+// UnmarshalBSONValue implements bson.ValueUnmarshaler, đọc được cả dữ liệu float cũ
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bsoncore.Value{Type: t, Data: data}
+
+	switch t {
+	case bsontype.Int64:
+		*m = NewMoney(value.Int64(), DefaultCurrency)
+	case bsontype.Int32:
+		*m = MoneyFromMajor(float64(value.Int32()), "")
+	case bsontype.Double:
+		*m = MoneyFromMajor(value.Double(), "")
+	case bsontype.Decimal128:
+		major, err := strconv.ParseFloat(value.Decimal128().String(), 64)
+		if err != nil {
+			return fmt.Errorf("money: invalid decimal %s", value.Decimal128().String())
+		}
+		money, err := MoneyFromMajorChecked(major, "")
+		if err != nil {
+			return err
+		}
+		*m = money
+	case bsontype.EmbeddedDocument:
+		var doc moneyDoc
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		*m = NewMoney(doc.Amount, doc.Currency)
+	case bsontype.Null, bsontype.Undefined:
+		*m = Money{}
+	default:
+		return fmt.Errorf("money: cannot decode BSON %s", t)
+	}
+	return nil
+}
+
+// MarshalJSON trả về số tiền theo đơn vị chính, vd 150000 hoặc 12.50
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.majorString()), nil
+}
+
+// UnmarshalJSON nhận số (đơn vị chính), chuỗi số hoặc object {amount, currency}
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+
+	switch data[0] {
+	case '{':
+		var doc struct {
+			Amount   *int64 `json:"amount"`
+			Currency string `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if doc.Amount == nil {
+			return errors.New("money: thiếu amount")
+		}
+		*m = NewMoney(*doc.Amount, doc.Currency)
+		return nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(strings.TrimSpace(s))
+	}
+
+	major, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("money: số tiền không hợp lệ %s", data)
+	}
+	money, err := MoneyFromMajorChecked(major, "")
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
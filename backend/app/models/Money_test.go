@@ -0,0 +1,174 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type moneyHolder struct {
+	Price Money `bson:"price" json:"price"`
+}
+
+func TestMoney_DecodeLegacyBSON(t *testing.T) {
+	decimal, _ := primitive.ParseDecimal128("45000.5")
+
+	cases := []struct {
+		name  string
+		value interface{}
+		want  Money
+	}{
+		{"double từ dữ liệu cũ", 150000.0, VND(150000)},
+		{"double lẻ được làm tròn", 99999.6, VND(100000)},
+		{"int32 từ mongoose", int32(99000), VND(99000)},
+		{"int64 đơn vị nhỏ nhất", int64(120000), VND(120000)},
+		{"decimal128", decimal, VND(45001)},
+		{"document có currency", bson.M{"amount": int64(1250), "currency": "USD"}, NewMoney(1250, "USD")},
+		{"null", nil, Money{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := bson.Marshal(bson.M{"price": tc.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got moneyHolder
+			if err := bson.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Price != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got.Price)
+			}
+		})
+	}
+}
+
+func TestMoney_BSONRoundTrip(t *testing.T) {
+	t.Run("currency mặc định lưu dạng int64", func(t *testing.T) {
+		data, err := bson.Marshal(moneyHolder{Price: VND(150000)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw bson.M
+		if err := bson.Unmarshal(data, &raw); err != nil {
+			t.Fatal(err)
+		}
+		if raw["price"] != int64(150000) {
+			t.Fatalf("expected int64 150000, got %T %v", raw["price"], raw["price"])
+		}
+
+		var got moneyHolder
+		if err := bson.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Price != VND(150000) {
+			t.Fatalf("expected 150000 VND, got %v", got.Price)
+		}
+	})
+
+	t.Run("currency khác lưu kèm currency", func(t *testing.T) {
+		data, err := bson.Marshal(moneyHolder{Price: NewMoney(1999, "usd")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got moneyHolder
+		if err := bson.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Price != NewMoney(1999, "USD") {
+			t.Fatalf("expected 19.99 USD, got %v", got.Price)
+		}
+	})
+}
+
+func TestMoney_JSON(t *testing.T) {
+	t.Run("marshal theo đơn vị chính", func(t *testing.T) {
+		cases := map[string]Money{
+			`{"price":150000}`: VND(150000),
+			`{"price":12.50}`:  NewMoney(1250, "USD"),
+			`{"price":-0.05}`:  NewMoney(-5, "USD"),
+			`{"price":0}`:      {},
+		}
+		for want, money := range cases {
+			data, err := json.Marshal(moneyHolder{Price: money})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != want {
+				t.Fatalf("expected %s, got %s", want, data)
+			}
+		}
+	})
+
+	t.Run("unmarshal số, chuỗi và object", func(t *testing.T) {
+		cases := map[string]Money{
+			`{"price":150000}`:                           VND(150000),
+			`{"price":"99000"}`:                          VND(99000),
+			`{"price":{"amount":1250,"currency":"USD"}}`: NewMoney(1250, "USD"),
+			`{"price":null}`:                             {},
+		}
+		for input, want := range cases {
+			var got moneyHolder
+			if err := json.Unmarshal([]byte(input), &got); err != nil {
+				t.Fatalf("%s: unexpected error: %v", input, err)
+			}
+			if got.Price != want {
+				t.Fatalf("%s: expected %v, got %v", input, want, got.Price)
+			}
+		}
+	})
+
+	t.Run("từ chối giá trị không hợp lệ", func(t *testing.T) {
+		for _, input := range []string{`{"price":"abc"}`, `{"price":{"currency":"USD"}}`, `{"price":true}`,
+			`{"price":"NaN"}`, `{"price":"Inf"}`, `{"price":"-Infinity"}`, `{"price":1e19}`, `{"price":"-9.3e18"}`,
+		} {
+			var got moneyHolder
+			if err := json.Unmarshal([]byte(input), &got); err == nil {
+				t.Fatalf("%s: expected error", input)
+			}
+		}
+	})
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	t.Run("cộng không sai số", func(t *testing.T) {
+		sum := MoneyFromMajor(0.1, "USD").Add(MoneyFromMajor(0.2, "USD"))
+		if sum != MoneyFromMajor(0.3, "USD") {
+			t.Fatalf("expected 0.30 USD, got %v", sum)
+		}
+	})
+
+	t.Run("nhân số lượng và tổng", func(t *testing.T) {
+		var total Money
+		for i := 0; i < 1000; i++ {
+			total = total.Add(VND(33333).Mul(3))
+		}
+		if total != VND(99999000) {
+			t.Fatalf("expected 99999000 VND, got %v", total)
+		}
+		if total.Sub(VND(999000)).Cmp(VND(99000000)) != 0 {
+			t.Fatalf("unexpected difference")
+		}
+	})
+
+	t.Run("không cộng khác currency", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		VND(1000).Add(NewMoney(100, "USD"))
+	})
+
+	t.Run("format", func(t *testing.T) {
+		if s := NewMoney(1250, "USD").String(); s != "12.50 USD" {
+			t.Fatalf("unexpected format %s", s)
+		}
+		if s := VND(150000).String(); s != "150000 VND" {
+			t.Fatalf("unexpected format %s", s)
+		}
+	})
+}
@@ -17,8 +17,25 @@ type OrderItem struct {
 	ProductName string             `bson:"product_name" json:"product_name"`
 	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
 	Quantity    int                `bson:"quantity" json:"quantity"`
-	Price       float64            `bson:"price" json:"price"` // Changed to float64 for monetary values
-	Total       float64            `bson:"total" json:"total"` // Changed to float64 for monetary values
+	Price       Money              `bson:"price" json:"price"` // Integer minor units (see Money)
+	ListPrice   Money              `bson:"list_price" json:"list_price"`
+	FlashSaleID string             `bson:"flash_sale_id,omitempty" json:"flash_sale_id,omitempty"`
+	Total       Money              `bson:"total" json:"total"` // Integer minor units (see Money)
+	Discount    Money              `bson:"discount" json:"discount"`
+	Promotions  []AppliedPromotion `bson:"promotions,omitempty" json:"promotions,omitempty"`
+	Category    string             `bson:"category,omitempty" json:"category,omitempty"`
+	Tax         *OrderItemTax      `bson:"tax,omitempty" json:"tax,omitempty"` // Chi tiết VAT của dòng hàng
+
+	RestockedQuantity int `bson:"restocked_quantity,omitempty" json:"restocked_quantity,omitempty"` // Số lượng đã nhập lại kho khi hoàn tiền
+}
+
+// OrderItemTax là chi tiết thuế của một dòng hàng
+type OrderItemTax struct {
+	Rule      string  `bson:"rule" json:"rule"`           // Tên rule thuế được áp dụng
+	Rate      float64 `bson:"rate" json:"rate"`           // Thuế suất (%)
+	Inclusive bool    `bson:"inclusive" json:"inclusive"` // Giá bán đã gồm VAT
+	Taxable   Money   `bson:"taxable" json:"taxable"`     // Giá trị tính thuế (trước VAT)
+	Amount    Money   `bson:"amount" json:"amount"`       // Tiền thuế
 }
 
 // ShippingAddress struct tương đương với shipping_address trong JS
@@ -49,8 +66,73 @@ type BillingAddress struct {
 type Payment struct {
 	Method        string     `bson:"method" json:"method"` // "cash_on_delivery", "bank_transfer", "credit_card", "e_wallet"
 	Status        string     `bson:"status" json:"status"` // "pending", "paid", "failed", "refunded"
+	Provider      string     `bson:"provider,omitempty" json:"provider,omitempty"`
+	IntentID      string     `bson:"intent_id,omitempty" json:"intent_id,omitempty"`
 	TransactionID string     `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+	FailureReason string     `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
 	PaidAt        *time.Time `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+
+	// Chuyển khoản ngân hàng: các khoản ghi có đã đối soát từ sao kê
+	Credits        []PaymentCredit `bson:"credits,omitempty" json:"credits,omitempty"`
+	ReceivedAmount Money           `bson:"received_amount,omitempty" json:"received_amount,omitempty"`
+	Flag           string          `bson:"flag,omitempty" json:"flag,omitempty"` // "partial", "overpaid"
+}
+
+// PaymentCredit là một khoản tiền khách chuyển vào tài khoản shop, đọc từ sao kê ngân hàng
+type PaymentCredit struct {
+	Reference   string    `bson:"reference" json:"reference"` // Mã giao dịch của ngân hàng
+	Amount      Money     `bson:"amount" json:"amount"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	PostedAt    time.Time `bson:"posted_at,omitempty" json:"posted_at,omitempty"`
+	RecordedBy  string    `bson:"recorded_by,omitempty" json:"recorded_by,omitempty"`
+	RecordedAt  time.Time `bson:"recorded_at" json:"recorded_at"`
+}
+
+// OrderRefund là một lần hoàn tiền của đơn hàng
+type OrderRefund struct {
+	ID        string            `bson:"id" json:"id"` // Mã hoàn tiền của provider, hoặc mã tự sinh khi hoàn thủ công
+	Amount    Money             `bson:"amount" json:"amount"`
+	Reason    string            `bson:"reason" json:"reason"`
+	Method    string            `bson:"method" json:"method"`                     // "provider" hoặc "manual"
+	Status    string            `bson:"status,omitempty" json:"status,omitempty"` // "pending", "succeeded" hoặc "failed"; rỗng = succeeded (dữ liệu cũ)
+	Provider  string            `bson:"provider,omitempty" json:"provider,omitempty"`
+	Items     []RefundStockItem `bson:"items,omitempty" json:"items,omitempty"`         // Hàng được nhập lại kho
+	ReturnID  string            `bson:"return_id,omitempty" json:"return_id,omitempty"` // Yêu cầu trả hàng được hoàn tiền
+	ActorID   string            `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+}
+
+// RefundStockItem là số lượng của một dòng hàng được nhập lại kho khi hoàn tiền
+type RefundStockItem struct {
+	ProductSKU  string `bson:"product_sku" json:"product_sku"`
+	ProductName string `bson:"product_name" json:"product_name"`
+	Quantity    int    `bson:"quantity" json:"quantity"`
+}
+
+// OrderReturn là một yêu cầu trả hàng (RMA) của khách sau khi nhận hàng.
+// Trạng thái: requested → approved/rejected, approved → received → refunded.
+type OrderReturn struct {
+	ID         string       `bson:"id" json:"id"`
+	Status     string       `bson:"status" json:"status"`
+	Items      []ReturnItem `bson:"items" json:"items"`
+	Reason     string       `bson:"reason" json:"reason"`
+	Photos     []string     `bson:"photos,omitempty" json:"photos,omitempty"` // URL ảnh khách gửi kèm
+	CustomerID string       `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
+	Amount     Money        `bson:"amount" json:"amount"` // Giá trị hàng trả (đã trừ giảm giá), mặc định là số tiền hoàn
+	AdminNote  string       `bson:"admin_note,omitempty" json:"admin_note,omitempty"`
+	RefundID   string       `bson:"refund_id,omitempty" json:"refund_id,omitempty"`
+	CreatedAt  time.Time    `bson:"created_at" json:"created_at"`
+	DecidedAt  *time.Time   `bson:"decided_at,omitempty" json:"decided_at,omitempty"`
+	DecidedBy  string       `bson:"decided_by,omitempty" json:"decided_by,omitempty"`
+	ReceivedAt *time.Time   `bson:"received_at,omitempty" json:"received_at,omitempty"`
+	RefundedAt *time.Time   `bson:"refunded_at,omitempty" json:"refunded_at,omitempty"`
+}
+
+// ReturnItem là số lượng khách trả của một dòng hàng
+type ReturnItem struct {
+	ProductSKU  string `bson:"product_sku" json:"product_sku"`
+	ProductName string `bson:"product_name" json:"product_name"`
+	Quantity    int    `bson:"quantity" json:"quantity"`
 }
 
 // Notes struct tương đương với notes trong JS
@@ -66,27 +148,75 @@ type Tracking struct {
 	TrackingURL    string `bson:"tracking_url,omitempty" json:"tracking_url,omitempty"`
 }
 
+// Shipment là vận đơn được tạo qua API của đơn vị vận chuyển
+type Shipment struct {
+	Carrier        string          `bson:"carrier" json:"carrier"`
+	TrackingNumber string          `bson:"tracking_number" json:"tracking_number"`
+	Service        string          `bson:"service,omitempty" json:"service,omitempty"`
+	Fee            Money           `bson:"fee" json:"fee"`
+	CODAmount      Money           `bson:"cod_amount" json:"cod_amount"` // Tiền carrier thu hộ khi giao
+	Status         string          `bson:"status" json:"status"`
+	Events         []ShipmentEvent `bson:"events,omitempty" json:"events,omitempty"`
+	CreatedAt      time.Time       `bson:"created_at" json:"created_at"`
+	CheckedAt      *time.Time      `bson:"checked_at,omitempty" json:"checked_at,omitempty"` // Lần cuối lấy trạng thái từ carrier
+}
+
+// ShipmentEvent là một mốc hành trình do carrier trả về
+type ShipmentEvent struct {
+	Status      string    `bson:"status" json:"status"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	Location    string    `bson:"location,omitempty" json:"location,omitempty"`
+	Time        time.Time `bson:"time" json:"time"`
+}
+
+// Invoice là hóa đơn đã xuất cho đơn hàng; số hóa đơn được cấp một lần và không đổi khi in lại
+type Invoice struct {
+	Number   string    `bson:"number" json:"number"`
+	IssuedAt time.Time `bson:"issued_at" json:"issued_at"`
+}
+
+// OrderStatusEntry là một bước trong lịch sử trạng thái đơn hàng (status_history)
+type OrderStatusEntry struct {
+	From      string    `bson:"from,omitempty" json:"from,omitempty"`
+	Status    string    `bson:"status" json:"status"`
+	ActorID   string    `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorRole string    `bson:"actor_role" json:"actor_role"` // "admin", "customer", "system"
+	Reason    string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChangedAt time.Time `bson:"changed_at" json:"changed_at"`
+}
+
 // Order struct tương đương với orderSchema trong JS
 type Order struct {
 	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
 	OrderNumber     string              `bson:"order_number" json:"order_number"` // Unique order number
 	UserID          *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id"` // nullable cho guest checkout
+	GuestID         *primitive.ObjectID `bson:"guest_id,omitempty" json:"-"`      // Guest id trong guest token khi khách đặt hàng không đăng nhập
 	Status          string              `bson:"status" json:"status"`             // "pending", "confirmed", "processing", "shipped", "delivered", "cancelled", "refunded"
 	Items           []OrderItem         `bson:"items" json:"items"`
-	Subtotal        float64             `bson:"subtotal" json:"subtotal"`               // Changed to float64 for monetary values
-	TaxAmount       float64             `bson:"tax_amount" json:"tax_amount"`           // Changed to float64 for monetary values
-	ShippingAmount  float64             `bson:"shipping_amount" json:"shipping_amount"` // Changed to float64 for monetary values
-	DiscountAmount  float64             `bson:"discount_amount" json:"discount_amount"` // Changed to float64 for monetary values
-	TotalAmount     float64             `bson:"total_amount" json:"total_amount"`       // Changed to float64 for monetary values
+	Subtotal        Money               `bson:"subtotal" json:"subtotal"`               // Integer minor units (see Money)
+	TaxAmount       Money               `bson:"tax_amount" json:"tax_amount"`           // Integer minor units (see Money)
+	ShippingAmount  Money               `bson:"shipping_amount" json:"shipping_amount"` // Integer minor units (see Money)
+	DiscountAmount  Money               `bson:"discount_amount" json:"discount_amount"` // Integer minor units (see Money)
+	TotalAmount     Money               `bson:"total_amount" json:"total_amount"`       // Integer minor units (see Money)
 	Currency        string              `bson:"currency" json:"currency"`               // Default "VND"
+	TaxInclusive    bool                `bson:"tax_inclusive" json:"tax_inclusive"`     // Giá bán đã gồm VAT
 	ShippingAddress ShippingAddress     `bson:"shipping_address" json:"shipping_address"`
+	ShippingMethod  string              `bson:"shipping_method,omitempty" json:"shipping_method,omitempty"`
+	CouponCode      string              `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	Promotions      []AppliedPromotion  `bson:"promotions,omitempty" json:"promotions,omitempty"`
 	BillingAddress  *BillingAddress     `bson:"billing_address,omitempty" json:"billing_address"` // Optional
 	Payment         Payment             `bson:"payment" json:"payment"`
-	Notes           *Notes               `bson:"notes" json:"notes"`
+	Notes           *Notes              `bson:"notes" json:"notes"`
 	Tracking        *Tracking           `bson:"tracking,omitempty" json:"tracking"` // Optional tracking info
+	Shipment        *Shipment           `bson:"shipment,omitempty" json:"shipment,omitempty"`
+	Invoice         *Invoice            `bson:"invoice,omitempty" json:"invoice,omitempty"`
 	ShippedAt       *time.Time          `bson:"shipped_at,omitempty" json:"shipped_at"`
 	DeliveredAt     *time.Time          `bson:"delivered_at,omitempty" json:"delivered_at"`
 	CancelledAt     *time.Time          `bson:"cancelled_at,omitempty" json:"cancelled_at"`
+	StatusHistory   []OrderStatusEntry  `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	Refunds         []OrderRefund       `bson:"refunds,omitempty" json:"refunds,omitempty"`
+	Returns         []OrderReturn       `bson:"returns,omitempty" json:"returns,omitempty"`
+	RefundedAmount  Money               `bson:"refunded_amount,omitempty" json:"refunded_amount"` // Tổng đã hoàn
 	CreatedAt       time.Time           `bson:"createdAt" json:"createdAt"`
 	UpdatedAt       time.Time           `bson:"updatedAt" json:"updatedAt"`
 }
@@ -105,6 +235,10 @@ func EnsureOrderCollection(ctx context.Context, db *mongo.Database) (*mongo.Coll
 			Keys:    bson.D{{Key: "user_id", Value: 1}},
 			Options: options.Index(),
 		},
+		{
+			Keys:    bson.D{{Key: "guest_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 		{
 			Keys:    bson.D{{Key: "status", Value: 1}},
 			Options: options.Index(),
@@ -113,6 +247,14 @@ func EnsureOrderCollection(ctx context.Context, db *mongo.Database) (*mongo.Coll
 			Keys:    bson.D{{Key: "createdAt", Value: -1}},
 			Options: options.Index(),
 		},
+		{
+			Keys:    bson.D{{Key: "returns.status", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "invoice.number", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
 	}
 
 	if _, err := coll.Indexes().CreateMany(ctx, idxModels); err != nil {
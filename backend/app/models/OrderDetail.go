@@ -17,8 +17,8 @@ type OrderProduct struct {
 	ProductImage string             `bson:"product_image" json:"product_image"`
 	ProductSlug  string             `bson:"product_slug" json:"product_slug"`
 	Quantity     int                `bson:"quantity" json:"quantity"`
-	UnitPrice    float64            `bson:"unit_price" json:"unit_price"`   // Changed to float64 for monetary values
-	TotalPrice   float64            `bson:"total_price" json:"total_price"` // Changed to float64 for monetary values
+	UnitPrice    Money              `bson:"unit_price" json:"unit_price"`   // Integer minor units (see Money)
+	TotalPrice   Money              `bson:"total_price" json:"total_price"` // Integer minor units (see Money)
 	Category     string             `bson:"category,omitempty" json:"category,omitempty"`
 }
 
@@ -27,7 +27,7 @@ type UserOrder struct {
 	OrderNumber     string             `bson:"order_number" json:"order_number"`
 	OrderDate       time.Time          `bson:"order_date" json:"order_date"`
 	Status          string             `bson:"status" json:"status"`
-	TotalAmount     float64            `bson:"total_amount" json:"total_amount"`
+	TotalAmount     Money              `bson:"total_amount" json:"total_amount"`
 	ShippingAddress struct {
 		FullName string `bson:"full_name,omitempty" json:"full_name,omitempty"`
 		Phone    string `bson:"phone,omitempty" json:"phone,omitempty"`
@@ -12,21 +12,59 @@ import (
 
 // Product struct tương đương với productSchema trong JS
 type Product struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	ProductID   string             `bson:"id" json:"id"` // tương ứng với field `id` trong mongoose (ví dụ: "P001")
-	Name        string             `bson:"name" json:"name"`
-	Price       float64            `bson:"price" json:"price"`
-	Image       string             `bson:"image" json:"image"`
-	Slug        string             `bson:"slug" json:"slug"`
-	Amount      int                `bson:"amount" json:"amount"`
-	Category    string             `bson:"category,omitempty" json:"category,omitempty"` // tương ứng với Category.id
-	IsFeatured  bool               `bson:"is_featured" json:"is_featured"`
-	Description string             `bson:"description,omitempty" json:"description,omitempty"`
-	Stock       int                `bson:"stock" json:"stock"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	ProductID    string             `bson:"id" json:"id"` // tương ứng với field `id` trong mongoose (ví dụ: "P001")
+	Name         string             `bson:"name" json:"name"`
+	Price        Money              `bson:"price" json:"price"`
+	SalePrice    *Money             `bson:"sale_price,omitempty" json:"sale_price,omitempty"`         // Giá khuyến mãi theo lịch, nil = không có
+	SaleStartsAt *time.Time         `bson:"sale_starts_at,omitempty" json:"sale_starts_at,omitempty"` // nil = áp dụng ngay
+	SaleEndsAt   *time.Time         `bson:"sale_ends_at,omitempty" json:"sale_ends_at,omitempty"`     // nil = không hết hạn
+	Image        string             `bson:"image" json:"image"`
+	Slug         string             `bson:"slug" json:"slug"`
+	Amount       int                `bson:"amount" json:"amount"`
+	Weight       int                `bson:"weight,omitempty" json:"weight,omitempty"`     // gram, dùng tính phí giao hàng
+	Category     string             `bson:"category,omitempty" json:"category,omitempty"` // tương ứng với Category.id
+	IsFeatured   bool               `bson:"is_featured" json:"is_featured"`
+	Description  string             `bson:"description,omitempty" json:"description,omitempty"`
+	Stock        int                `bson:"stock" json:"stock"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Giá hiệu lực tại thời điểm trả về, không lưu DB (xem PricingService)
+	EffectivePrice Money       `bson:"-" json:"effective_price"`
+	ActiveSale     *ActiveSale `bson:"-" json:"active_sale,omitempty"`
+}
+
+// Nguồn giá của sản phẩm
+const (
+	PriceSourceRegular   = "regular"
+	PriceSourceSale      = "sale"
+	PriceSourceFlashSale = "flash_sale"
+)
+
+// ActiveSale mô tả giá khuyến mãi đang áp dụng cho sản phẩm
+type ActiveSale struct {
+	Source      string     `json:"source"` // "sale", "flash_sale"
+	Price       Money      `json:"price"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	FlashSaleID string     `json:"flash_sale_id,omitempty"`
+	Remaining   int        `json:"remaining,omitempty"` // Số lượng flash sale còn lại
 }
 
+// SalePriceAt trả về giá khuyến mãi theo lịch nếu đang trong thời gian áp dụng.
+// Hết thời gian thì giá tự quay về Price mà không cần admin sửa lại.
+func (p *Product) SalePriceAt(now time.Time) (Money, bool) {
+	if p.SalePrice == nil {
+		return Money{}, false
+	}
+	if p.SaleStartsAt != nil && now.Before(*p.SaleStartsAt) {
+		return Money{}, false
+	}
+	if p.SaleEndsAt != nil && !now.Before(*p.SaleEndsAt) {
+		return Money{}, false
+	}
+	return *p.SalePrice, true
+}
 
 // EnsureProductCollection khởi tạo collection và index
 func EnsureProductCollection(ctx context.Context, db *mongo.Database) (*mongo.Collection, error) {
@@ -0,0 +1,9 @@
+package models
+
+// AppliedPromotion là một khuyến mãi tự động đã được áp dụng cho dòng hàng hoặc đơn hàng
+type AppliedPromotion struct {
+	ID       string `bson:"id" json:"id"`
+	Name     string `bson:"name" json:"name"`
+	Type     string `bson:"type" json:"type"` // "buy_x_get_y", "tiered_percentage", "bundle"
+	Discount Money  `bson:"discount" json:"discount"`
+}
@@ -16,7 +16,7 @@ type WishlistItem struct {
 	ProductName  string    `bson:"product_name" json:"product_name"`
 	ProductImage string    `bson:"product_image" json:"product_image"`
 	ProductSlug  string    `bson:"product_slug" json:"product_slug"`
-	Price        float64   `bson:"price" json:"price"`
+	Price        Money     `bson:"price" json:"price"`
 	AddedAt      time.Time `bson:"added_at,omitempty" json:"added_at,omitempty"`
 }
 
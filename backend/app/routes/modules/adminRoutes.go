@@ -4,11 +4,20 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupAdminRoutes thiết lập routes cho admin
-func SetupAdminRoutes(rg *gin.RouterGroup) {
-	adminController := controllers.NewAdminController()
+func SetupAdminRoutes(rg *gin.RouterGroup, st store.Store) {
+	adminController := controllers.NewAdminController(st)
+	couponController := controllers.NewCouponController(st)
+	flashSaleController := controllers.NewFlashSaleController(st)
+	paymentController := controllers.NewPaymentController(st)
+	returnController := controllers.NewReturnController(st)
+	orderController := controllers.NewOrderController(st)
+	shipmentController := controllers.NewShipmentController(st)
+	invoiceController := controllers.NewInvoiceController(st)
+	productController := controllers.NewProductController(st)
 
 	admin := rg.Group("/admin")
 	admin.Use(middleware.AuthMiddleware()) // Tất cả admin routes đều cần auth
@@ -39,4 +48,59 @@ func SetupAdminRoutes(rg *gin.RouterGroup) {
 		admin.PUT("/categories/:id", adminController.UpdateCategory)
 		admin.DELETE("/categories/:id", adminController.DeleteCategory)
 	}
+
+	// Coupon Management (chỉ admin)
+	coupons := rg.Group("/admin/coupons")
+	coupons.Use(middleware.AdminMiddleware())
+	{
+		coupons.GET("", couponController.GetCoupons)
+		coupons.GET("/:id", couponController.GetCouponByID)
+		coupons.POST("", couponController.CreateCoupon)
+		coupons.PUT("/:id", couponController.UpdateCoupon)
+		coupons.DELETE("/:id", couponController.DeleteCoupon)
+	}
+
+	// Flash Sale Management (chỉ admin)
+	flashSales := rg.Group("/admin/flash-sales")
+	flashSales.Use(middleware.AdminMiddleware())
+	{
+		flashSales.GET("", flashSaleController.GetFlashSales)
+		flashSales.GET("/:id", flashSaleController.GetFlashSaleByID)
+		flashSales.POST("", flashSaleController.CreateFlashSale)
+		flashSales.PUT("/:id", flashSaleController.UpdateFlashSale)
+		flashSales.DELETE("/:id", flashSaleController.DeleteFlashSale)
+	}
+
+	// Đối soát thanh toán chuyển khoản (chỉ admin)
+	payments := rg.Group("/admin/payments")
+	payments.Use(middleware.AdminMiddleware())
+	{
+		payments.POST("/bank-statements", paymentController.ReconcileBankStatement)
+	}
+
+	// Vận đơn, hoàn tiền và xử lý trả hàng (chỉ admin); danh sách yêu cầu trả hàng: GET /admin/orders?return_status=requested
+	orders := rg.Group("/admin/orders")
+	orders.Use(middleware.AdminMiddleware())
+	{
+		orders.GET("/export", orderController.ExportOrders) // CSV/XLSX, cùng filter với GET /admin/orders
+		orders.PUT("/:id/tracking", orderController.UpdateTracking)
+		orders.POST("/:id/shipments/quote", shipmentController.QuoteShipment)
+		orders.POST("/:id/shipments", middleware.Idempotency(st.Idempotency(), middleware.DefaultIdempotencyTTL), shipmentController.CreateShipment)
+		orders.GET("/:id/shipments/label", shipmentController.GetShipmentLabel)
+		orders.POST("/:id/refunds", middleware.Idempotency(st.Idempotency(), middleware.DefaultIdempotencyTTL), paymentController.RefundOrder)
+		orders.POST("/:id/returns/:returnId/approve", returnController.ApproveReturn)
+		orders.POST("/:id/returns/:returnId/reject", returnController.RejectReturn)
+		orders.POST("/:id/returns/:returnId/receive", returnController.ReceiveReturn)
+		orders.POST("/:id/returns/:returnId/refund", middleware.Idempotency(st.Idempotency(), middleware.DefaultIdempotencyTTL), returnController.RefundReturn)
+	}
+	// Nhập/xuất sản phẩm hàng loạt bằng CSV (chỉ admin)
+	products := rg.Group("/admin/products")
+	products.Use(middleware.AdminMiddleware())
+	{
+		products.GET("/export", productController.ExportProducts)
+		products.POST("/import", productController.ImportProducts) // ?dry_run=true để chỉ kiểm tra
+	}
+
+	rg.GET("/admin/carriers", middleware.AdminMiddleware(), orderController.GetCarriers)
+	rg.GET("/admin/invoices", middleware.AdminMiddleware(), invoiceController.DownloadInvoices) // ZIP hóa đơn theo khoảng ngày
 }
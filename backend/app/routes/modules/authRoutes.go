@@ -4,11 +4,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupAuthRoutes thiết lập routes cho authentication
-func SetupAuthRoutes(rg *gin.RouterGroup) {
-	authController := controllers.NewAuthController()
+func SetupAuthRoutes(rg *gin.RouterGroup, st store.Store) {
+	authController := controllers.NewAuthController(st)
 
 	auth := rg.Group("/auth")
 	{
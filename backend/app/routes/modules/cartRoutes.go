@@ -1,22 +1,33 @@
 package modules
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupCartRoutes thiết lập routes cho cart
-func SetupCartRoutes(rg *gin.RouterGroup) {
-	cartController := &controllers.CartController{}
+func SetupCartRoutes(rg *gin.RouterGroup, st store.Store) {
+	cartController := controllers.NewCartController(st)
+
+	// Giới hạn số guest token mỗi IP được cấp
+	guestLimiter := middleware.NewRateLimiter(10, time.Hour)
 
 	cart := rg.Group("/cart")
-	cart.Use(middleware.AuthMiddleware()) // Tất cả cart routes đều cần auth
+	cart.POST("/guest", middleware.RateLimit(guestLimiter, "guest"), cartController.CreateGuestToken) // Cấp guest token cho khách chưa đăng nhập
+
+	// Cần token đăng nhập hoặc guest token (X-Guest-Token)
+	cart.Use(middleware.OptionalAuthMiddleware(), middleware.RequireUserOrGuest())
 	{
 		cart.GET("", cartController.GetCart)
 		cart.POST("/add", cartController.AddToCart)
 		cart.PUT("/update/:productId", cartController.UpdateCartItem)
 		cart.DELETE("/remove/:productId", cartController.RemoveFromCart)
 		cart.DELETE("/clear", cartController.ClearCart)
+		cart.POST("/coupon", cartController.ApplyCoupon)
+		cart.DELETE("/coupon", cartController.RemoveCoupon)
 	}
 }
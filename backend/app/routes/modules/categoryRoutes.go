@@ -4,11 +4,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupCategoryRoutes thiết lập routes cho categories
-func SetupCategoryRoutes(rg *gin.RouterGroup) {
-	categoryController := &controllers.CategoryController{}
+func SetupCategoryRoutes(rg *gin.RouterGroup, st store.Store) {
+	categoryController := controllers.NewCategoryController(st)
 
 	categories := rg.Group("/categories")
 	{
@@ -4,14 +4,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupCompareRoutes thiết lập routes cho compare
-func SetupCompareRoutes(rg *gin.RouterGroup) {
-	compareController := controllers.NewCompareController()
+func SetupCompareRoutes(rg *gin.RouterGroup, st store.Store) {
+	compareController := controllers.NewCompareController(st)
 
 	compare := rg.Group("/compare")
-	compare.Use(middleware.AuthMiddleware()) // Tất cả compare routes đều cần auth
+	compare.Use(middleware.OptionalAuthMiddleware(), middleware.RequireUserOrGuest()) // Cần token đăng nhập hoặc guest token
 	{
 		compare.GET("", compareController.GetCompare)
 		compare.POST("/add", compareController.AddToCompare)
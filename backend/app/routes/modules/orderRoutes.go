@@ -1,29 +1,53 @@
 package modules
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupOrderRoutes thiết lập routes cho orders
-func SetupOrderRoutes(rg *gin.RouterGroup) {
-	orderController := &controllers.OrderController{}
+func SetupOrderRoutes(rg *gin.RouterGroup, st store.Store) {
+	orderController := controllers.NewOrderController(st)
+	paymentController := controllers.NewPaymentController(st)
+	returnController := controllers.NewReturnController(st)
+	invoiceController := controllers.NewInvoiceController(st)
+	orderLookupController := controllers.NewOrderLookupController(st)
 
 	orders := rg.Group("/orders")
 
-	// Public routes (no auth required)
-	orders.GET("/number/:orderNumber", orderController.GetOrderByNumber) // Get order by number
-	orders.GET("/by-email", orderController.GetOrdersByEmail)            // Get orders by email
+	// Tra cứu đơn không cần đăng nhập: mã đơn + email/số điện thoại trên đơn, hoặc liên kết dùng một lần
+	// gửi tới email trên đơn. Chỉ trả về đúng đơn đó với thông tin liên hệ đã che bớt.
+	lookupLimiter := middleware.NewRateLimiter(20, 15*time.Minute)
+	linkLimiter := middleware.NewRateLimiter(5, time.Hour)
+	lookup := orders.Group("/lookup")
+	{
+		lookup.POST("", middleware.RateLimit(lookupLimiter, "lookup"), orderLookupController.LookupOrder)
+		lookup.POST("/link", middleware.RateLimit(linkLimiter, "link"), orderLookupController.RequestLookupLink)
+		lookup.POST("/redeem", middleware.RateLimit(lookupLimiter, "redeem"), orderLookupController.RedeemLookupLink)
+	}
+
+	// Đặt hàng và xem đơn: token đăng nhập hoặc guest token (X-Guest-Token, xem POST /cart/guest).
+	// Khách chỉ thấy các đơn đã đặt bằng guest token của mình.
+	userOrGuest := orders.Group("", middleware.OptionalAuthMiddleware(), middleware.RequireUserOrGuest())
+	{
+		userOrGuest.POST("", middleware.Idempotency(st.Idempotency(), middleware.DefaultIdempotencyTTL), orderController.CreateOrder)
+		userOrGuest.POST("/quote", orderController.QuoteOrder) // Báo giá phí giao hàng cho giỏ hàng hiện tại
+		userOrGuest.GET("", orderController.GetOrders)
+		userOrGuest.GET("/my-orders", orderController.GetOrders) // Explicit route for my orders
+		userOrGuest.GET("/:id", orderController.GetOrderByID)
+		userOrGuest.GET("/:id/invoice.pdf", middleware.ValidateOrderAccess(st.Orders()), invoiceController.GetInvoicePDF) // Hóa đơn GTGT
+		userOrGuest.PUT("/:id/cancel", orderController.CancelOrder)
+		userOrGuest.POST("/:id/pay", middleware.Idempotency(st.Idempotency(), middleware.DefaultIdempotencyTTL), paymentController.CreatePayment) // Tạo phiên thanh toán online
+	}
 
 	// Protected routes (auth required)
 	orders.Use(middleware.AuthMiddleware())
 	{
-		orders.POST("", orderController.CreateOrder)
-		orders.GET("", orderController.GetOrders)
-		orders.GET("/my-orders", orderController.GetOrders) // Explicit route for my orders
-		orders.GET("/:id", orderController.GetOrderByID)
-		orders.PUT("/:id/cancel", orderController.CancelOrder)
+		orders.POST("/:id/returns", middleware.Idempotency(st.Idempotency(), middleware.DefaultIdempotencyTTL), returnController.RequestReturn) // Yêu cầu trả hàng
 
 		// Admin routes
 		orders.GET("/admin/all", orderController.GetAllOrders)
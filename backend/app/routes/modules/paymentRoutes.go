@@ -0,0 +1,18 @@
+package modules
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mingfulsnack/app/controllers"
+	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
+)
+
+// SetupPaymentRoutes thiết lập routes cho callback của cổng thanh toán
+func SetupPaymentRoutes(rg *gin.RouterGroup, st store.Store) {
+	paymentController := controllers.NewPaymentController(st)
+
+	payments := rg.Group("/payments")
+
+	// Public: provider xác thực bằng chữ ký; gửi kèm Idempotency-Key (vd. event id) để retry an toàn
+	payments.POST("/webhook/:provider", middleware.Idempotency(st.Idempotency(), middleware.DefaultIdempotencyTTL), paymentController.Webhook)
+}
@@ -4,11 +4,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupProductRoutes thiết lập routes cho products
-func SetupProductRoutes(rg *gin.RouterGroup) {
-	productController := controllers.NewProductController()
+func SetupProductRoutes(rg *gin.RouterGroup, st store.Store) {
+	productController := controllers.NewProductController(st)
 
 	products := rg.Group("/products")
 	{
@@ -4,14 +4,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/middleware"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupWishlistRoutes thiết lập routes cho wishlist
-func SetupWishlistRoutes(rg *gin.RouterGroup) {
-	wishlistController := controllers.NewWishlistController()
+func SetupWishlistRoutes(rg *gin.RouterGroup, st store.Store) {
+	wishlistController := controllers.NewWishlistController(st)
 
 	wishlist := rg.Group("/wishlist")
-	wishlist.Use(middleware.AuthMiddleware()) // Tất cả wishlist routes đều cần auth
+	wishlist.Use(middleware.OptionalAuthMiddleware(), middleware.RequireUserOrGuest()) // Cần token đăng nhập hoặc guest token
 	{
 		wishlist.GET("", wishlistController.GetWishlist)
 		wishlist.POST("/add", wishlistController.AddToWishlist)
@@ -3,10 +3,11 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/mingfulsnack/app/routes/modules"
+	"github.com/mingfulsnack/app/store"
 )
 
 // SetupRoutes thiết lập tất cả routes cho API
-func SetupRoutes(router *gin.Engine) {
+func SetupRoutes(router *gin.Engine, st store.Store) {
 	// API prefix
 	api := router.Group("/api")
 
@@ -19,12 +20,13 @@ func SetupRoutes(router *gin.Engine) {
 	})
 
 	// Setup all module routes
-	modules.SetupAuthRoutes(api)
-	modules.SetupCategoryRoutes(api)
-	modules.SetupProductRoutes(api)
-	modules.SetupCartRoutes(api)
-	modules.SetupOrderRoutes(api)
-	modules.SetupWishlistRoutes(api)
-	modules.SetupCompareRoutes(api)
-	modules.SetupAdminRoutes(api)
+	modules.SetupAuthRoutes(api, st)
+	modules.SetupCategoryRoutes(api, st)
+	modules.SetupProductRoutes(api, st)
+	modules.SetupCartRoutes(api, st)
+	modules.SetupOrderRoutes(api, st)
+	modules.SetupPaymentRoutes(api, st)
+	modules.SetupWishlistRoutes(api, st)
+	modules.SetupCompareRoutes(api, st)
+	modules.SetupAdminRoutes(api, st)
 }
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore là implementation của Store lưu dữ liệu trong bộ nhớ.
+// Dùng cho test và chạy offline; mọi document trả ra đều là bản copy
+// nên service không thể sửa dữ liệu trong store mà không gọi Save/Update.
+type MemoryStore struct {
+	mu          sync.Mutex
+	products    []*models.Product
+	categories  []*models.Category
+	users       []*models.User
+	carts       []*models.Cart
+	wishlists   []*models.Wishlist
+	compares    []*models.Compare
+	orders      []*models.Order
+	counters    map[string]int64
+	idempotency map[string]*models.IdempotencyRecord
+	coupons     []*models.Coupon
+	flashSales  []*models.FlashSale
+}
+
+// NewMemoryStore tạo Store rỗng trong bộ nhớ
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Products() ProductStore        { return &memoryProductStore{s} }
+func (s *MemoryStore) Categories() CategoryStore     { return &memoryCategoryStore{s} }
+func (s *MemoryStore) Users() UserStore              { return &memoryUserStore{s} }
+func (s *MemoryStore) Carts() CartStore              { return &memoryCartStore{s} }
+func (s *MemoryStore) Wishlists() WishlistStore      { return &memoryWishlistStore{s} }
+func (s *MemoryStore) Compares() CompareStore        { return &memoryCompareStore{s} }
+func (s *MemoryStore) Orders() OrderStore            { return &memoryOrderStore{s} }
+func (s *MemoryStore) Counters() CounterStore        { return &memoryCounterStore{s} }
+func (s *MemoryStore) Idempotency() IdempotencyStore { return &memoryIdempotencyStore{s} }
+func (s *MemoryStore) Coupons() CouponStore          { return &memoryCouponStore{s} }
+func (s *MemoryStore) FlashSales() FlashSaleStore    { return &memoryFlashSaleStore{s} }
+
+// WithTransaction: MemoryStore không hỗ trợ transaction, service sẽ dùng rollback bù trừ
+func (s *MemoryStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return ErrTransactionsUnsupported
+}
+
+// clone copy document qua BSON để giữ đúng hành vi encode/decode như khi lưu vào Mongo
+func clone[T any](doc *T) *T {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		panic("store: clone marshal: " + err.Error())
+	}
+	var out T
+	if err := bson.Unmarshal(data, &out); err != nil {
+		panic("store: clone unmarshal: " + err.Error())
+	}
+	return &out
+}
+
+// cloneAll copy danh sách document, luôn trả về slice khác nil
+func cloneAll[T any](docs []*T) []T {
+	out := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		out = append(out, *clone(doc))
+	}
+	return out
+}
+
+// applySet áp dụng các field của $set lên document (hỗ trợ key dạng "a.b")
+func applySet[T any](doc *T, fields bson.M) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	for key, value := range fields {
+		setPath(m, strings.Split(key, "."), value)
+	}
+
+	if data, err = bson.Marshal(m); err != nil {
+		return err
+	}
+	var out T
+	if err := bson.Unmarshal(data, &out); err != nil {
+		return err
+	}
+	*doc = out
+	return nil
+}
+
+func setPath(m bson.M, path []string, value interface{}) {
+	if len(path) == 1 {
+		m[path[0]] = value
+		return
+	}
+	child, ok := m[path[0]].(bson.M)
+	if !ok {
+		child = bson.M{}
+		m[path[0]] = child
+	}
+	setPath(child, path[1:], value)
+}
+
+// toM chuyển document sang bson.M để đọc field theo tên (dùng khi sort)
+func toM(doc interface{}) bson.M {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return bson.M{}
+	}
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return bson.M{}
+	}
+	return m
+}
+
+// paginate sort theo opts.Sort rồi áp dụng skip/limit
+func paginate[T any](docs []*T, opts ListOptions) []*T {
+	if opts.Sort != "" {
+		key, dir := sortKey(opts.Sort)
+		values := make(map[*T]interface{}, len(docs))
+		for _, doc := range docs {
+			values[doc] = toM(doc)[key]
+		}
+		sort.SliceStable(docs, func(i, j int) bool {
+			return compareValues(values[docs[i]], values[docs[j]])*dir < 0
+		})
+	}
+
+	if opts.Skip > 0 {
+		if opts.Skip >= int64(len(docs)) {
+			return nil
+		}
+		docs = docs[opts.Skip:]
+	}
+	if opts.Limit > 0 && opts.Limit < int64(len(docs)) {
+		docs = docs[:opts.Limit]
+	}
+	return docs
+}
+
+// compareValues so sánh hai giá trị BSON cùng kiểu (string, số, thời gian, bool)
+func compareValues(a, b interface{}) int {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	switch av := a.(type) {
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv)
+		}
+	case primitive.DateTime:
+		if bv, ok := b.(primitive.DateTime); ok {
+			return compareValues(int64(av), int64(bv))
+		}
+	case bool:
+		if bv, ok := b.(bool); ok && av != bv {
+			if !av {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	}
+
+	// Giá trị thiếu (nil) đứng trước, giống Mongo
+	switch {
+	case a == nil && b != nil:
+		return -1
+	case a != nil && b == nil:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// matchRegex mô phỏng {$regex: pattern, $options: "i"}
+func matchRegex(pattern, value string) bool {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return strings.Contains(strings.ToLower(value), strings.ToLower(pattern))
+	}
+	return re.MatchString(value)
+}
+
+// inRange kiểm tra t nằm trong [from, to] (bỏ qua cận nil)
+func inRange(t time.Time, from, to *time.Time) bool {
+	if from != nil && t.Before(*from) {
+		return false
+	}
+	if to != nil && t.After(*to) {
+		return false
+	}
+	return true
+}
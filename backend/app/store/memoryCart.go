@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryCartStore struct {
+	s *MemoryStore
+}
+
+func (m *memoryCartStore) find(userID primitive.ObjectID, cartType string) *models.Cart {
+	for _, cart := range m.s.carts {
+		if cart.UserID == userID && cart.CartType == cartType {
+			return cart
+		}
+	}
+	return nil
+}
+
+func (m *memoryCartStore) FindByUser(ctx context.Context, userID primitive.ObjectID, cartType string) (*models.Cart, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if cart := m.find(userID, cartType); cart != nil {
+		return clone(cart), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryCartStore) Save(ctx context.Context, cart *models.Cart) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if cart.ID.IsZero() {
+		cart.ID = primitive.NewObjectID()
+		m.s.carts = append(m.s.carts, clone(cart))
+		return nil
+	}
+	for i, existing := range m.s.carts {
+		if existing.ID == cart.ID {
+			m.s.carts[i] = clone(cart)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryCartStore) Clear(ctx context.Context, userID primitive.ObjectID, cartType string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if cart := m.find(userID, cartType); cart != nil {
+		cart.Items = []models.CartItem{}
+		cart.TotalAmount = models.Money{}
+		cart.DiscountAmount = models.Money{}
+		cart.Promotions = nil
+		cart.TotalItems = 0
+		cart.CouponCode = ""
+		cart.UpdatedAt = time.Now()
+	}
+	return nil
+}
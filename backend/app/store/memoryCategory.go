@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryCategoryStore struct {
+	s *MemoryStore
+}
+
+func (m *memoryCategoryStore) find(match func(c *models.Category) bool) *models.Category {
+	for _, c := range m.s.categories {
+		if match(c) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (m *memoryCategoryStore) findOne(match func(c *models.Category) bool) (*models.Category, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if c := m.find(match); c != nil {
+		return clone(c), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryCategoryStore) List(ctx context.Context) ([]models.Category, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return cloneAll(m.s.categories), nil
+}
+
+func (m *memoryCategoryStore) Count(ctx context.Context) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return int64(len(m.s.categories)), nil
+}
+
+func (m *memoryCategoryStore) FindByObjectID(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	return m.findOne(func(c *models.Category) bool { return c.ID == id })
+}
+
+func (m *memoryCategoryStore) FindByCategoryID(ctx context.Context, categoryID string) (*models.Category, error) {
+	return m.findOne(func(c *models.Category) bool { return c.CategoryID == categoryID })
+}
+
+func (m *memoryCategoryStore) ExistsByName(ctx context.Context, name string, excludeID primitive.ObjectID) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	c := m.find(func(c *models.Category) bool {
+		return strings.EqualFold(c.Name, name) && (excludeID.IsZero() || c.ID != excludeID)
+	})
+	return c != nil, nil
+}
+
+func (m *memoryCategoryStore) Insert(ctx context.Context, category *models.Category) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
+	m.s.categories = append(m.s.categories, clone(category))
+	return nil
+}
+
+func (m *memoryCategoryStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	c := m.find(func(c *models.Category) bool { return c.ID == id })
+	if c == nil {
+		return ErrNotFound
+	}
+	return applySet(c, fields)
+}
+
+func (m *memoryCategoryStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for i, c := range m.s.categories {
+		if c.ID == id {
+			m.s.categories = append(m.s.categories[:i], m.s.categories[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
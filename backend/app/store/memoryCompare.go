@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryCompareStore struct {
+	s *MemoryStore
+}
+
+func (m *memoryCompareStore) find(userID primitive.ObjectID) *models.Compare {
+	for _, compare := range m.s.compares {
+		if compare.UserID == userID {
+			return compare
+		}
+	}
+	return nil
+}
+
+func (m *memoryCompareStore) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.Compare, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if compare := m.find(userID); compare != nil {
+		return clone(compare), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryCompareStore) Save(ctx context.Context, compare *models.Compare) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if compare.ID.IsZero() {
+		compare.ID = primitive.NewObjectID()
+		m.s.compares = append(m.s.compares, clone(compare))
+		return nil
+	}
+	for i, existing := range m.s.compares {
+		if existing.ID == compare.ID {
+			m.s.compares[i] = clone(compare)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryCompareStore) Clear(ctx context.Context, userID primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if compare := m.find(userID); compare != nil {
+		compare.Items = []models.CompareItem{}
+		compare.TotalItems = 0
+		compare.UpdatedAt = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"context"
+)
+
+type memoryCounterStore struct {
+	s *MemoryStore
+}
+
+func (m *memoryCounterStore) Next(ctx context.Context, key string) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if m.s.counters == nil {
+		m.s.counters = map[string]int64{}
+	}
+	m.s.counters[key]++
+	return m.s.counters[key], nil
+}
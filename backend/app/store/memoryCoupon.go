@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryCouponStore struct {
+	s *MemoryStore
+}
+
+// matchCoupon tương đương couponQuery
+func matchCoupon(c *models.Coupon, filter CouponFilter) bool {
+	if filter.Search != "" && !matchRegex(filter.Search, c.Code) && !matchRegex(filter.Search, c.Description) {
+		return false
+	}
+	if filter.IsActive != nil && c.IsActive != *filter.IsActive {
+		return false
+	}
+	return true
+}
+
+func (m *memoryCouponStore) filter(filter CouponFilter) []*models.Coupon {
+	var matched []*models.Coupon
+	for _, c := range m.s.coupons {
+		if matchCoupon(c, filter) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+func (m *memoryCouponStore) find(match func(c *models.Coupon) bool) *models.Coupon {
+	for _, c := range m.s.coupons {
+		if match(c) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (m *memoryCouponStore) findOne(match func(c *models.Coupon) bool) (*models.Coupon, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if c := m.find(match); c != nil {
+		return clone(c), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryCouponStore) List(ctx context.Context, filter CouponFilter, opts ListOptions) ([]models.Coupon, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return cloneAll(paginate(m.filter(filter), opts)), nil
+}
+
+func (m *memoryCouponStore) Count(ctx context.Context, filter CouponFilter) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return int64(len(m.filter(filter))), nil
+}
+
+func (m *memoryCouponStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Coupon, error) {
+	return m.findOne(func(c *models.Coupon) bool { return c.ID == id })
+}
+
+func (m *memoryCouponStore) FindByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	code = strings.ToUpper(code)
+	return m.findOne(func(c *models.Coupon) bool { return c.Code == code })
+}
+
+func (m *memoryCouponStore) ExistsByCode(ctx context.Context, code string, excludeID primitive.ObjectID) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	code = strings.ToUpper(code)
+	c := m.find(func(c *models.Coupon) bool {
+		return c.Code == code && (excludeID.IsZero() || c.ID != excludeID)
+	})
+	return c != nil, nil
+}
+
+func (m *memoryCouponStore) Insert(ctx context.Context, coupon *models.Coupon) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if coupon.ID.IsZero() {
+		coupon.ID = primitive.NewObjectID()
+	}
+	m.s.coupons = append(m.s.coupons, clone(coupon))
+	return nil
+}
+
+func (m *memoryCouponStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.Coupon, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	c := m.find(func(c *models.Coupon) bool { return c.ID == id })
+	if c == nil {
+		return nil, ErrNotFound
+	}
+	if err := applySet(c, fields); err != nil {
+		return nil, err
+	}
+	return clone(c), nil
+}
+
+func (m *memoryCouponStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for i, c := range m.s.coupons {
+		if c.ID == id {
+			m.s.coupons = append(m.s.coupons[:i], m.s.coupons[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *memoryCouponStore) Redeem(ctx context.Context, code, userID string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	code = strings.ToUpper(code)
+	c := m.find(func(c *models.Coupon) bool { return c.Code == code })
+	if c == nil {
+		return ErrNotFound
+	}
+	if c.UsageLimit > 0 && c.UsedCount >= c.UsageLimit {
+		return ErrCouponLimitReached
+	}
+	if c.PerUserLimit > 0 && c.UserUsage[userID] >= c.PerUserLimit {
+		return ErrCouponLimitReached
+	}
+	if c.UserUsage == nil {
+		c.UserUsage = map[string]int{}
+	}
+	c.UsedCount++
+	c.UserUsage[userID]++
+	return nil
+}
+
+func (m *memoryCouponStore) Release(ctx context.Context, code, userID string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	code = strings.ToUpper(code)
+	c := m.find(func(c *models.Coupon) bool { return c.Code == code })
+	if c == nil {
+		return ErrNotFound
+	}
+	if c.UserUsage[userID] > 0 {
+		c.UserUsage[userID]--
+		c.UsedCount--
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryFlashSaleStore struct {
+	s *MemoryStore
+}
+
+// matchFlashSale tương đương flashSaleQuery
+func matchFlashSale(f *models.FlashSale, filter FlashSaleFilter) bool {
+	if filter.ProductID != "" && f.ProductID != filter.ProductID {
+		return false
+	}
+	if filter.IsActive != nil && f.IsActive != *filter.IsActive {
+		return false
+	}
+	return true
+}
+
+func (m *memoryFlashSaleStore) filter(filter FlashSaleFilter) []*models.FlashSale {
+	var matched []*models.FlashSale
+	for _, f := range m.s.flashSales {
+		if matchFlashSale(f, filter) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+func (m *memoryFlashSaleStore) find(id primitive.ObjectID) *models.FlashSale {
+	for _, f := range m.s.flashSales {
+		if f.ID == id {
+			return f
+		}
+	}
+	return nil
+}
+
+func (m *memoryFlashSaleStore) List(ctx context.Context, filter FlashSaleFilter, opts ListOptions) ([]models.FlashSale, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if opts.Sort == "" {
+		opts.Sort = "starts_at"
+	}
+	return cloneAll(paginate(m.filter(filter), opts)), nil
+}
+
+func (m *memoryFlashSaleStore) Count(ctx context.Context, filter FlashSaleFilter) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return int64(len(m.filter(filter))), nil
+}
+
+func (m *memoryFlashSaleStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.FlashSale, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if f := m.find(id); f != nil {
+		return clone(f), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryFlashSaleStore) FindRunning(ctx context.Context, productIDs []string, at time.Time) ([]models.FlashSale, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	wanted := make(map[string]bool, len(productIDs))
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
+
+	var matched []*models.FlashSale
+	for _, f := range m.s.flashSales {
+		if wanted[f.ProductID] && f.RunningAt(at) {
+			matched = append(matched, f)
+		}
+	}
+	return cloneAll(matched), nil
+}
+
+func (m *memoryFlashSaleStore) Insert(ctx context.Context, sale *models.FlashSale) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if sale.ID.IsZero() {
+		sale.ID = primitive.NewObjectID()
+	}
+	m.s.flashSales = append(m.s.flashSales, clone(sale))
+	return nil
+}
+
+func (m *memoryFlashSaleStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.FlashSale, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	f := m.find(id)
+	if f == nil {
+		return nil, ErrNotFound
+	}
+	if err := applySet(f, fields); err != nil {
+		return nil, err
+	}
+	return clone(f), nil
+}
+
+func (m *memoryFlashSaleStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for i, f := range m.s.flashSales {
+		if f.ID == id {
+			m.s.flashSales = append(m.s.flashSales[:i], m.s.flashSales[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *memoryFlashSaleStore) Reserve(ctx context.Context, id primitive.ObjectID, userID string, qty int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	f := m.find(id)
+	if f == nil {
+		return ErrNotFound
+	}
+	if f.SoldCount+qty > f.Quantity {
+		return ErrFlashSaleLimitReached
+	}
+	if f.PerCustomerLimit > 0 && f.CustomerUsage[userID]+qty > f.PerCustomerLimit {
+		return ErrFlashSaleLimitReached
+	}
+	if f.CustomerUsage == nil {
+		f.CustomerUsage = map[string]int{}
+	}
+	f.SoldCount += qty
+	f.CustomerUsage[userID] += qty
+	return nil
+}
+
+func (m *memoryFlashSaleStore) Release(ctx context.Context, id primitive.ObjectID, userID string, qty int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	f := m.find(id)
+	if f == nil {
+		return ErrNotFound
+	}
+	if f.CustomerUsage[userID] >= qty {
+		f.CustomerUsage[userID] -= qty
+		f.SoldCount -= qty
+	}
+	return nil
+}
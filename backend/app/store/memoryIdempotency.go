@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+)
+
+type memoryIdempotencyStore struct {
+	s *MemoryStore
+}
+
+func (m *memoryIdempotencyStore) Claim(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if m.s.idempotency == nil {
+		m.s.idempotency = map[string]*models.IdempotencyRecord{}
+	}
+	if existing, ok := m.s.idempotency[record.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return clone(existing), nil
+	}
+	m.s.idempotency[record.Key] = clone(record)
+	return nil, nil
+}
+
+func (m *memoryIdempotencyStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	record, ok := m.s.idempotency[key]
+	if !ok {
+		return ErrNotFound
+	}
+	record.Status = models.IdempotencyCompleted
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = append([]byte(nil), body...)
+	record.ExpiresAt = expiresAt
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	delete(m.s.idempotency, key)
+	return nil
+}
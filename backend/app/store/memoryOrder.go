@@ -0,0 +1,363 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryOrderStore struct {
+	s *MemoryStore
+}
+
+// matchOrder tương đương orderQuery
+func matchOrder(o *models.Order, filter OrderFilter) bool {
+	if filter.Status != "" && o.Status != filter.Status {
+		return false
+	}
+	if filter.UserID != nil && (o.UserID == nil || *o.UserID != *filter.UserID) {
+		return false
+	}
+	if filter.GuestID != nil && (o.GuestID == nil || *o.GuestID != *filter.GuestID) {
+		return false
+	}
+	if filter.Email != "" && o.ShippingAddress.Email != filter.Email {
+		return false
+	}
+	if filter.ReturnStatus != "" && !hasReturnStatus(o, filter.ReturnStatus) {
+		return false
+	}
+	if filter.HasShipment && o.Shipment == nil {
+		return false
+	}
+	return inRange(o.CreatedAt, filter.DateFrom, filter.DateTo)
+}
+
+func hasReturnStatus(o *models.Order, status string) bool {
+	for _, ret := range o.Returns {
+		if ret.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryOrderStore) filter(filter OrderFilter) []*models.Order {
+	var matched []*models.Order
+	for _, o := range m.s.orders {
+		if matchOrder(o, filter) {
+			matched = append(matched, o)
+		}
+	}
+	return matched
+}
+
+func (m *memoryOrderStore) find(match func(o *models.Order) bool) *models.Order {
+	for _, o := range m.s.orders {
+		if match(o) {
+			return o
+		}
+	}
+	return nil
+}
+
+func (m *memoryOrderStore) findOne(match func(o *models.Order) bool) (*models.Order, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if o := m.find(match); o != nil {
+		return clone(o), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryOrderStore) List(ctx context.Context, filter OrderFilter, opts ListOptions) ([]models.Order, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	orders := m.filter(filter)
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	opts.Sort = ""
+	return cloneAll(paginate(orders, opts)), nil
+}
+
+func (m *memoryOrderStore) Stream(ctx context.Context, filter OrderFilter, fn func(order *models.Order) error) error {
+	m.s.mu.Lock()
+	matched := m.filter(filter)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+	})
+	orders := cloneAll(matched)
+	m.s.mu.Unlock()
+
+	for i := range orders {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryOrderStore) Count(ctx context.Context, filter OrderFilter) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return int64(len(m.filter(filter))), nil
+}
+
+func (m *memoryOrderStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	return m.findOne(func(o *models.Order) bool { return o.ID == id })
+}
+
+func (m *memoryOrderStore) FindByNumber(ctx context.Context, orderNumber string) (*models.Order, error) {
+	return m.findOne(func(o *models.Order) bool { return o.OrderNumber == orderNumber })
+}
+
+func (m *memoryOrderStore) Insert(ctx context.Context, order *models.Order) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	m.s.orders = append(m.s.orders, clone(order))
+	return nil
+}
+
+func (m *memoryOrderStore) UpdateStatus(ctx context.Context, id primitive.ObjectID, from string, entry models.OrderStatusEntry, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if o.Status != from {
+		return ErrStatusConflict
+	}
+	if err := applySet(o, fields); err != nil {
+		return err
+	}
+	o.Status = entry.Status
+	o.StatusHistory = append(o.StatusHistory, entry)
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) UpdatePayment(ctx context.Context, id primitive.ObjectID, from string, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if o.Payment.Status != from {
+		return ErrStatusConflict
+	}
+	if err := applySet(o, fields); err != nil {
+		return err
+	}
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) UpdateTracking(ctx context.Context, id primitive.ObjectID, from string, tracking models.Tracking) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if o.Status != from {
+		return ErrStatusConflict
+	}
+	o.Tracking = &tracking
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) AttachShipment(ctx context.Context, id primitive.ObjectID, from string, shipment models.Shipment, tracking models.Tracking) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if o.Status != from || o.Shipment != nil {
+		return ErrStatusConflict
+	}
+	o.Shipment = &shipment
+	o.Tracking = &tracking
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) UpdateShipment(ctx context.Context, id primitive.ObjectID, trackingNumber string, shipment models.Shipment) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if o.Shipment == nil || o.Shipment.TrackingNumber != trackingNumber {
+		return ErrStatusConflict
+	}
+	o.Shipment = &shipment
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) AssignInvoice(ctx context.Context, id primitive.ObjectID, invoice models.Invoice) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if o.Invoice != nil {
+		return ErrStatusConflict
+	}
+	o.Invoice = &invoice
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) AddPaymentCredit(ctx context.Context, id primitive.ObjectID, from string, credit models.PaymentCredit, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	for _, existing := range o.Payment.Credits {
+		if existing.Reference == credit.Reference {
+			return ErrDuplicateCredit
+		}
+	}
+	if o.Payment.Status != from {
+		return ErrStatusConflict
+	}
+	if err := applySet(o, fields); err != nil {
+		return err
+	}
+	o.Payment.Credits = append(o.Payment.Credits, credit)
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) AddRefund(ctx context.Context, id primitive.ObjectID, count int, refund models.OrderRefund, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if len(o.Refunds) != count {
+		return ErrStatusConflict
+	}
+	if err := applySet(o, fields); err != nil {
+		return err
+	}
+	o.Refunds = append(o.Refunds, refund)
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) UpdateRefund(ctx context.Context, id primitive.ObjectID, refundID, from string, refund models.OrderRefund, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	for i := range o.Refunds {
+		if o.Refunds[i].ID != refundID {
+			continue
+		}
+		if o.Refunds[i].Status != from {
+			return ErrStatusConflict
+		}
+		if err := applySet(o, fields); err != nil {
+			return err
+		}
+		o.Refunds[i] = refund
+		o.UpdatedAt = time.Now()
+		return nil
+	}
+	return ErrStatusConflict
+}
+
+func (m *memoryOrderStore) AddReturn(ctx context.Context, id primitive.ObjectID, count int, ret models.OrderReturn) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	if len(o.Returns) != count {
+		return ErrStatusConflict
+	}
+	o.Returns = append(o.Returns, ret)
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *memoryOrderStore) UpdateReturn(ctx context.Context, id primitive.ObjectID, from string, ret models.OrderReturn, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	o := m.find(func(o *models.Order) bool { return o.ID == id })
+	if o == nil {
+		return ErrNotFound
+	}
+	for i := range o.Returns {
+		if o.Returns[i].ID != ret.ID {
+			continue
+		}
+		if o.Returns[i].Status != from {
+			return ErrStatusConflict
+		}
+		if err := applySet(o, fields); err != nil {
+			return err
+		}
+		o.Returns[i] = ret
+		o.UpdatedAt = time.Now()
+		return nil
+	}
+	return ErrStatusConflict
+}
+
+func (m *memoryOrderStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for i, o := range m.s.orders {
+		if o.ID == id {
+			m.s.orders = append(m.s.orders[:i], m.s.orders[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *memoryOrderStore) StatusTotals(ctx context.Context, filter OrderFilter) ([]OrderStatusTotal, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	totals := []OrderStatusTotal{}
+	index := map[string]int{}
+	for _, o := range m.filter(filter) {
+		i, ok := index[o.Status]
+		if !ok {
+			i = len(totals)
+			index[o.Status] = i
+			totals = append(totals, OrderStatusTotal{Status: o.Status})
+		}
+		totals[i].Count++
+		totals[i].TotalAmount = totals[i].TotalAmount.Add(o.TotalAmount)
+		totals[i].TaxAmount = totals[i].TaxAmount.Add(o.TaxAmount)
+		totals[i].Refunded = totals[i].Refunded.Add(o.RefundedAmount)
+	}
+	return totals, nil
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryProductStore struct {
+	s *MemoryStore
+}
+
+// matchProduct tương đương productQuery
+func matchProduct(p *models.Product, filter ProductFilter) bool {
+	if filter.Search != "" && !matchRegex(filter.Search, p.Name) {
+		return false
+	}
+	if filter.Category != "" && p.Category != filter.Category {
+		return false
+	}
+	if filter.IsFeatured != nil && p.IsFeatured != *filter.IsFeatured {
+		return false
+	}
+	if filter.ExcludeSlug != "" && p.Slug == filter.ExcludeSlug {
+		return false
+	}
+	if filter.ExcludeProductID != "" && p.ProductID == filter.ExcludeProductID {
+		return false
+	}
+	if !filter.ExcludeID.IsZero() && p.ID == filter.ExcludeID {
+		return false
+	}
+	return true
+}
+
+func (m *memoryProductStore) filter(filter ProductFilter) []*models.Product {
+	var matched []*models.Product
+	for _, p := range m.s.products {
+		if matchProduct(p, filter) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+func (m *memoryProductStore) find(match func(p *models.Product) bool) *models.Product {
+	for _, p := range m.s.products {
+		if match(p) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (m *memoryProductStore) List(ctx context.Context, filter ProductFilter, opts ListOptions) ([]models.Product, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return cloneAll(paginate(m.filter(filter), opts)), nil
+}
+
+func (m *memoryProductStore) Count(ctx context.Context, filter ProductFilter) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return int64(len(m.filter(filter))), nil
+}
+
+func (m *memoryProductStore) findOne(match func(p *models.Product) bool) (*models.Product, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if p := m.find(match); p != nil {
+		return clone(p), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryProductStore) FindByObjectID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	return m.findOne(func(p *models.Product) bool { return p.ID == id })
+}
+
+func (m *memoryProductStore) FindByProductID(ctx context.Context, productID string) (*models.Product, error) {
+	return m.findOne(func(p *models.Product) bool { return p.ProductID == productID })
+}
+
+func (m *memoryProductStore) FindBySlug(ctx context.Context, slug string) (*models.Product, error) {
+	return m.findOne(func(p *models.Product) bool { return p.Slug == slug })
+}
+
+func (m *memoryProductStore) ExistsByProductIDOrSlug(ctx context.Context, productID, slug string) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	p := m.find(func(p *models.Product) bool { return p.ProductID == productID || p.Slug == slug })
+	return p != nil, nil
+}
+
+func (m *memoryProductStore) Insert(ctx context.Context, product *models.Product) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	m.s.products = append(m.s.products, clone(product))
+	return nil
+}
+
+func (m *memoryProductStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.Product, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	p := m.find(func(p *models.Product) bool { return p.ID == id })
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	if err := applySet(p, fields); err != nil {
+		return nil, err
+	}
+	return clone(p), nil
+}
+
+func (m *memoryProductStore) UpdateIfStock(ctx context.Context, id primitive.ObjectID, amount, stock int, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	p := m.find(func(p *models.Product) bool { return p.ID == id })
+	if p == nil {
+		return ErrNotFound
+	}
+	if p.Amount != amount || p.Stock != stock {
+		return ErrStockChanged
+	}
+	return applySet(p, fields)
+}
+
+func (m *memoryProductStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for i, p := range m.s.products {
+		if p.ID == id {
+			m.s.products = append(m.s.products[:i], m.s.products[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *memoryProductStore) AdjustStock(ctx context.Context, productID string, delta int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	p := m.find(func(p *models.Product) bool { return p.ProductID == productID })
+	if p == nil {
+		return ErrNotFound
+	}
+	p.Amount += delta
+	return nil
+}
+
+func (m *memoryProductStore) DecrementStock(ctx context.Context, productID string, qty int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	p := m.find(func(p *models.Product) bool { return p.ProductID == productID })
+	if p == nil {
+		return ErrNotFound
+	}
+	if p.Amount < qty {
+		return ErrInsufficientStock
+	}
+	p.Amount -= qty
+	return nil
+}
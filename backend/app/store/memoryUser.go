@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUserStore struct {
+	s *MemoryStore
+}
+
+// matchUser tương đương userQuery
+func matchUser(u *models.User, filter UserFilter) bool {
+	if filter.Role != "" && u.Role != filter.Role {
+		return false
+	}
+	if filter.Search != "" &&
+		!matchRegex(filter.Search, u.Username) &&
+		!matchRegex(filter.Search, u.Email) &&
+		!matchRegex(filter.Search, u.FullName) {
+		return false
+	}
+	if filter.CreatedSince != nil && u.CreatedAt.Before(*filter.CreatedSince) {
+		return false
+	}
+	return true
+}
+
+func (m *memoryUserStore) filter(filter UserFilter) []*models.User {
+	var matched []*models.User
+	for _, u := range m.s.users {
+		if matchUser(u, filter) {
+			matched = append(matched, u)
+		}
+	}
+	return matched
+}
+
+func (m *memoryUserStore) find(match func(u *models.User) bool) *models.User {
+	for _, u := range m.s.users {
+		if match(u) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (m *memoryUserStore) findOne(match func(u *models.User) bool) (*models.User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if u := m.find(match); u != nil {
+		return clone(u), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryUserStore) List(ctx context.Context, filter UserFilter, opts ListOptions) ([]models.User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return cloneAll(paginate(m.filter(filter), opts)), nil
+}
+
+func (m *memoryUserStore) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return int64(len(m.filter(filter))), nil
+}
+
+func (m *memoryUserStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return m.findOne(func(u *models.User) bool { return u.ID == id })
+}
+
+func (m *memoryUserStore) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return m.findOne(func(u *models.User) bool { return u.Username == username })
+}
+
+func (m *memoryUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return m.findOne(func(u *models.User) bool { return u.Email == email })
+}
+
+func (m *memoryUserStore) exists(match func(u *models.User) bool, excludeID primitive.ObjectID) bool {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	u := m.find(func(u *models.User) bool {
+		return match(u) && (excludeID.IsZero() || u.ID != excludeID)
+	})
+	return u != nil
+}
+
+func (m *memoryUserStore) ExistsByUsername(ctx context.Context, username string, excludeID primitive.ObjectID) (bool, error) {
+	return m.exists(func(u *models.User) bool { return u.Username == username }, excludeID), nil
+}
+
+func (m *memoryUserStore) ExistsByEmail(ctx context.Context, email string, excludeID primitive.ObjectID) (bool, error) {
+	return m.exists(func(u *models.User) bool { return u.Email == email }, excludeID), nil
+}
+
+func (m *memoryUserStore) Insert(ctx context.Context, user *models.User) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	m.s.users = append(m.s.users, clone(user))
+	return nil
+}
+
+func (m *memoryUserStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	u := m.find(func(u *models.User) bool { return u.ID == id })
+	if u == nil {
+		return ErrNotFound
+	}
+	return applySet(u, fields)
+}
+
+func (m *memoryUserStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for i, u := range m.s.users {
+		if u.ID == id {
+			m.s.users = append(m.s.users[:i], m.s.users[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
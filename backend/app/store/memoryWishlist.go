@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryWishlistStore struct {
+	s *MemoryStore
+}
+
+func (m *memoryWishlistStore) find(userID primitive.ObjectID) *models.Wishlist {
+	for _, wishlist := range m.s.wishlists {
+		if wishlist.UserID == userID {
+			return wishlist
+		}
+	}
+	return nil
+}
+
+func (m *memoryWishlistStore) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.Wishlist, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if wishlist := m.find(userID); wishlist != nil {
+		return clone(wishlist), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryWishlistStore) Save(ctx context.Context, wishlist *models.Wishlist) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if wishlist.ID.IsZero() {
+		wishlist.ID = primitive.NewObjectID()
+		m.s.wishlists = append(m.s.wishlists, clone(wishlist))
+		return nil
+	}
+	for i, existing := range m.s.wishlists {
+		if existing.ID == wishlist.ID {
+			m.s.wishlists[i] = clone(wishlist)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryWishlistStore) Clear(ctx context.Context, userID primitive.ObjectID) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if wishlist := m.find(userID); wishlist != nil {
+		wishlist.Items = []models.WishlistItem{}
+		wishlist.TotalItems = 0
+		wishlist.UpdatedAt = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Tên collection theo JS pattern (xem MIGRATION_SUMMARY.md)
+const (
+	UsersCollection       = "Users"
+	ProductsCollection    = "Products"
+	CategoryCollection    = "categories"
+	CartsCollection       = "Carts"
+	WishlistsCollection   = "Wishlists"
+	ComparesCollection    = "Compares"
+	OrdersCollection      = "orders"
+	CountersCollection    = "Counters"
+	IdempotencyCollection = "IdempotencyKeys"
+	CouponsCollection     = "Coupons"
+	FlashSalesCollection  = "FlashSales"
+)
+
+// MongoStore là implementation của Store trên MongoDB
+type MongoStore struct {
+	db          *mongo.Database
+	products    *mongoProductStore
+	categories  *mongoCategoryStore
+	users       *mongoUserStore
+	carts       *mongoCartStore
+	wishlists   *mongoWishlistStore
+	compares    *mongoCompareStore
+	orders      *mongoOrderStore
+	counters    *mongoCounterStore
+	idempotency *mongoIdempotencyStore
+	coupons     *mongoCouponStore
+	flashSales  *mongoFlashSaleStore
+
+	txMu        sync.Mutex
+	txChecked   bool
+	txSupported bool
+}
+
+// NewMongoStore tạo Store dùng database đã kết nối
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{
+		db:          db,
+		products:    &mongoProductStore{coll: db.Collection(ProductsCollection)},
+		categories:  &mongoCategoryStore{coll: db.Collection(CategoryCollection)},
+		users:       &mongoUserStore{coll: db.Collection(UsersCollection)},
+		carts:       &mongoCartStore{coll: db.Collection(CartsCollection)},
+		wishlists:   &mongoWishlistStore{coll: db.Collection(WishlistsCollection)},
+		compares:    &mongoCompareStore{coll: db.Collection(ComparesCollection)},
+		orders:      &mongoOrderStore{coll: db.Collection(OrdersCollection)},
+		counters:    &mongoCounterStore{coll: db.Collection(CountersCollection)},
+		idempotency: &mongoIdempotencyStore{coll: db.Collection(IdempotencyCollection)},
+		coupons:     &mongoCouponStore{coll: db.Collection(CouponsCollection)},
+		flashSales:  &mongoFlashSaleStore{coll: db.Collection(FlashSalesCollection)},
+	}
+}
+
+func (s *MongoStore) Products() ProductStore        { return s.products }
+func (s *MongoStore) Categories() CategoryStore     { return s.categories }
+func (s *MongoStore) Users() UserStore              { return s.users }
+func (s *MongoStore) Carts() CartStore              { return s.carts }
+func (s *MongoStore) Wishlists() WishlistStore      { return s.wishlists }
+func (s *MongoStore) Compares() CompareStore        { return s.compares }
+func (s *MongoStore) Orders() OrderStore            { return s.orders }
+func (s *MongoStore) Counters() CounterStore        { return s.counters }
+func (s *MongoStore) Idempotency() IdempotencyStore { return s.idempotency }
+func (s *MongoStore) Coupons() CouponStore          { return s.coupons }
+func (s *MongoStore) FlashSales() FlashSaleStore    { return s.flashSales }
+
+// WithTransaction chạy fn trong session transaction nếu server là replica set hoặc mongos
+func (s *MongoStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !s.supportsTransactions(ctx) {
+		return ErrTransactionsUnsupported
+	}
+
+	session, err := s.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// supportsTransactions kiểm tra topology bằng lệnh hello, kết quả được cache sau lần hỏi thành công
+func (s *MongoStore) supportsTransactions(ctx context.Context) bool {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	if !s.txChecked {
+		var hello struct {
+			SetName string `bson:"setName"`
+			Msg     string `bson:"msg"`
+		}
+		if err := s.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+			return false
+		}
+		s.txChecked = true
+		s.txSupported = hello.SetName != "" || hello.Msg == "isdbgrid"
+	}
+	return s.txSupported
+}
+
+// findOptions chuyển ListOptions sang options của driver
+func findOptions(opts ListOptions) *options.FindOptions {
+	findOpts := options.Find()
+	if opts.Sort != "" {
+		key, dir := sortKey(opts.Sort)
+		findOpts.SetSort(bson.D{{Key: key, Value: dir}})
+	}
+	if opts.Skip > 0 {
+		findOpts.SetSkip(opts.Skip)
+	}
+	if opts.Limit > 0 {
+		findOpts.SetLimit(opts.Limit)
+	}
+	return findOpts
+}
+
+// sortKey tách "-field" thành (field, -1)
+func sortKey(sort string) (string, int) {
+	if strings.HasPrefix(sort, "-") {
+		return strings.TrimPrefix(sort, "-"), -1
+	}
+	return sort, 1
+}
+
+// translateErr chuyển lỗi của driver sang lỗi của store
+func translateErr(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoCartStore struct {
+	coll *mongo.Collection
+}
+
+func (s *mongoCartStore) FindByUser(ctx context.Context, userID primitive.ObjectID, cartType string) (*models.Cart, error) {
+	var cart models.Cart
+	err := s.coll.FindOne(ctx, bson.M{
+		"user_id":   userID,
+		"cart_type": cartType,
+	}).Decode(&cart)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &cart, nil
+}
+
+func (s *mongoCartStore) Save(ctx context.Context, cart *models.Cart) error {
+	if cart.ID.IsZero() {
+		result, err := s.coll.InsertOne(ctx, cart)
+		if err != nil {
+			return err
+		}
+		cart.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	}
+
+	_, err := s.coll.ReplaceOne(ctx, bson.M{"_id": cart.ID}, cart)
+	return err
+}
+
+func (s *mongoCartStore) Clear(ctx context.Context, userID primitive.ObjectID, cartType string) error {
+	_, err := s.coll.UpdateOne(
+		ctx,
+		bson.M{
+			"user_id":   userID,
+			"cart_type": cartType,
+		},
+		bson.M{
+			"$set": bson.M{
+				"items":           []models.CartItem{},
+				"total_amount":    models.Money{},
+				"discount_amount": models.Money{},
+				"total_items":     0,
+				"updatedAt":       time.Now(),
+			},
+			"$unset": bson.M{"coupon_code": "", "promotions": ""},
+		},
+	)
+	return err
+}
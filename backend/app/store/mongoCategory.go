@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCategoryStore struct {
+	coll *mongo.Collection
+}
+
+func (s *mongoCategoryStore) List(ctx context.Context) ([]models.Category, error) {
+	cursor, err := s.coll.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categories := []models.Category{}
+	if err = cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (s *mongoCategoryStore) Count(ctx context.Context) (int64, error) {
+	return s.coll.CountDocuments(ctx, bson.M{})
+}
+
+func (s *mongoCategoryStore) findOne(ctx context.Context, query bson.M) (*models.Category, error) {
+	var category models.Category
+	if err := s.coll.FindOne(ctx, query).Decode(&category); err != nil {
+		return nil, translateErr(err)
+	}
+	return &category, nil
+}
+
+func (s *mongoCategoryStore) FindByObjectID(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+func (s *mongoCategoryStore) FindByCategoryID(ctx context.Context, categoryID string) (*models.Category, error) {
+	return s.findOne(ctx, bson.M{"id": categoryID})
+}
+
+func (s *mongoCategoryStore) ExistsByName(ctx context.Context, name string, excludeID primitive.ObjectID) (bool, error) {
+	query := bson.M{
+		"name": bson.M{"$regex": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"}},
+	}
+	if !excludeID.IsZero() {
+		query["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	count, err := s.coll.CountDocuments(ctx, query, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *mongoCategoryStore) Insert(ctx context.Context, category *models.Category) error {
+	result, err := s.coll.InsertOne(ctx, category)
+	if err != nil {
+		return err
+	}
+	category.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoCategoryStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	result, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoCategoryStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoCompareStore struct {
+	coll *mongo.Collection
+}
+
+func (s *mongoCompareStore) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.Compare, error) {
+	var compare models.Compare
+	if err := s.coll.FindOne(ctx, bson.M{"user_id": userID}).Decode(&compare); err != nil {
+		return nil, translateErr(err)
+	}
+	return &compare, nil
+}
+
+func (s *mongoCompareStore) Save(ctx context.Context, compare *models.Compare) error {
+	if compare.ID.IsZero() {
+		result, err := s.coll.InsertOne(ctx, compare)
+		if err != nil {
+			return err
+		}
+		compare.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	}
+
+	_, err := s.coll.ReplaceOne(ctx, bson.M{"_id": compare.ID}, compare)
+	return err
+}
+
+func (s *mongoCompareStore) Clear(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{
+			"items":       []models.CompareItem{},
+			"total_items": 0,
+			"updatedAt":   time.Now(),
+		},
+	})
+	return err
+}
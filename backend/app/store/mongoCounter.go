@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCounterStore struct {
+	coll *mongo.Collection
+}
+
+func (s *mongoCounterStore) Next(ctx context.Context, key string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	next := func() error {
+		return s.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&counter)
+	}
+
+	err := next()
+	if mongo.IsDuplicateKeyError(err) {
+		// Hai upsert đồng thời cho cùng key: document đã được tạo, thử lại sẽ chỉ $inc
+		err = next()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
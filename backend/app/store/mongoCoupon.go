@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCouponStore struct {
+	coll *mongo.Collection
+}
+
+// couponQuery build filter Mongo từ CouponFilter
+func couponQuery(filter CouponFilter) bson.M {
+	query := bson.M{}
+
+	if filter.Search != "" {
+		query["$or"] = []bson.M{
+			{"code": bson.M{"$regex": filter.Search, "$options": "i"}},
+			{"description": bson.M{"$regex": filter.Search, "$options": "i"}},
+		}
+	}
+	if filter.IsActive != nil {
+		query["is_active"] = *filter.IsActive
+	}
+
+	return query
+}
+
+func (s *mongoCouponStore) List(ctx context.Context, filter CouponFilter, opts ListOptions) ([]models.Coupon, error) {
+	cursor, err := s.coll.Find(ctx, couponQuery(filter), findOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	coupons := []models.Coupon{}
+	if err = cursor.All(ctx, &coupons); err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+func (s *mongoCouponStore) Count(ctx context.Context, filter CouponFilter) (int64, error) {
+	return s.coll.CountDocuments(ctx, couponQuery(filter))
+}
+
+func (s *mongoCouponStore) findOne(ctx context.Context, query bson.M) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := s.coll.FindOne(ctx, query).Decode(&coupon); err != nil {
+		return nil, translateErr(err)
+	}
+	return &coupon, nil
+}
+
+func (s *mongoCouponStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Coupon, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+func (s *mongoCouponStore) FindByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	return s.findOne(ctx, bson.M{"code": strings.ToUpper(code)})
+}
+
+func (s *mongoCouponStore) ExistsByCode(ctx context.Context, code string, excludeID primitive.ObjectID) (bool, error) {
+	query := bson.M{"code": strings.ToUpper(code)}
+	if !excludeID.IsZero() {
+		query["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	count, err := s.coll.CountDocuments(ctx, query, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *mongoCouponStore) Insert(ctx context.Context, coupon *models.Coupon) error {
+	result, err := s.coll.InsertOne(ctx, coupon)
+	if err != nil {
+		return err
+	}
+	coupon.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoCouponStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.Coupon, error) {
+	var updated models.Coupon
+	err := s.coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &updated, nil
+}
+
+func (s *mongoCouponStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoCouponStore) Redeem(ctx context.Context, code, userID string) error {
+	code = strings.ToUpper(code)
+	usageKey := "user_usage." + userID
+
+	// Giới hạn được so sánh ngay trong filter nên hai request đồng thời không thể cùng vượt giới hạn
+	result, err := s.coll.UpdateOne(ctx, bson.M{
+		"code": code,
+		"$expr": bson.M{"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"$lte": bson.A{bson.M{"$ifNull": bson.A{"$usage_limit", 0}}, 0}},
+				bson.M{"$lt": bson.A{"$used_count", "$usage_limit"}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"$lte": bson.A{bson.M{"$ifNull": bson.A{"$per_user_limit", 0}}, 0}},
+				bson.M{"$lt": bson.A{bson.M{"$ifNull": bson.A{"$" + usageKey, 0}}, "$per_user_limit"}},
+			}},
+		}},
+	}, bson.M{
+		"$inc": bson.M{"used_count": 1, usageKey: 1},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByCode(ctx, code); err != nil {
+			return err
+		}
+		return ErrCouponLimitReached
+	}
+	return nil
+}
+
+func (s *mongoCouponStore) Release(ctx context.Context, code, userID string) error {
+	usageKey := "user_usage." + userID
+	_, err := s.coll.UpdateOne(ctx, bson.M{
+		"code":   strings.ToUpper(code),
+		usageKey: bson.M{"$gt": 0},
+	}, bson.M{
+		"$inc": bson.M{"used_count": -1, usageKey: -1},
+	})
+	return err
+}
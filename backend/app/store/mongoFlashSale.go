@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoFlashSaleStore struct {
+	coll *mongo.Collection
+}
+
+// flashSaleQuery build filter Mongo từ FlashSaleFilter
+func flashSaleQuery(filter FlashSaleFilter) bson.M {
+	query := bson.M{}
+
+	if filter.ProductID != "" {
+		query["product_id"] = filter.ProductID
+	}
+	if filter.IsActive != nil {
+		query["is_active"] = *filter.IsActive
+	}
+
+	return query
+}
+
+func (s *mongoFlashSaleStore) find(ctx context.Context, query bson.M, opts *options.FindOptions) ([]models.FlashSale, error) {
+	cursor, err := s.coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sales := []models.FlashSale{}
+	if err = cursor.All(ctx, &sales); err != nil {
+		return nil, err
+	}
+	return sales, nil
+}
+
+func (s *mongoFlashSaleStore) List(ctx context.Context, filter FlashSaleFilter, opts ListOptions) ([]models.FlashSale, error) {
+	if opts.Sort == "" {
+		opts.Sort = "starts_at"
+	}
+	return s.find(ctx, flashSaleQuery(filter), findOptions(opts))
+}
+
+func (s *mongoFlashSaleStore) Count(ctx context.Context, filter FlashSaleFilter) (int64, error) {
+	return s.coll.CountDocuments(ctx, flashSaleQuery(filter))
+}
+
+func (s *mongoFlashSaleStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.FlashSale, error) {
+	var sale models.FlashSale
+	if err := s.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&sale); err != nil {
+		return nil, translateErr(err)
+	}
+	return &sale, nil
+}
+
+func (s *mongoFlashSaleStore) FindRunning(ctx context.Context, productIDs []string, at time.Time) ([]models.FlashSale, error) {
+	return s.find(ctx, bson.M{
+		"product_id": bson.M{"$in": productIDs},
+		"is_active":  true,
+		"starts_at":  bson.M{"$lte": at},
+		"ends_at":    bson.M{"$gt": at},
+	}, options.Find())
+}
+
+func (s *mongoFlashSaleStore) Insert(ctx context.Context, sale *models.FlashSale) error {
+	result, err := s.coll.InsertOne(ctx, sale)
+	if err != nil {
+		return err
+	}
+	sale.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoFlashSaleStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.FlashSale, error) {
+	var updated models.FlashSale
+	err := s.coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &updated, nil
+}
+
+func (s *mongoFlashSaleStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoFlashSaleStore) Reserve(ctx context.Context, id primitive.ObjectID, userID string, qty int) error {
+	usageKey := "customer_usage." + userID
+
+	// Giới hạn được so sánh ngay trong filter nên hai đơn đồng thời không thể cùng vượt số lượng
+	result, err := s.coll.UpdateOne(ctx, bson.M{
+		"_id": id,
+		"$expr": bson.M{"$and": bson.A{
+			bson.M{"$lte": bson.A{bson.M{"$add": bson.A{"$sold_count", qty}}, "$quantity"}},
+			bson.M{"$or": bson.A{
+				bson.M{"$lte": bson.A{bson.M{"$ifNull": bson.A{"$per_customer_limit", 0}}, 0}},
+				bson.M{"$lte": bson.A{bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + usageKey, 0}}, qty}}, "$per_customer_limit"}},
+			}},
+		}},
+	}, bson.M{
+		"$inc": bson.M{"sold_count": qty, usageKey: qty},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrFlashSaleLimitReached
+	}
+	return nil
+}
+
+func (s *mongoFlashSaleStore) Release(ctx context.Context, id primitive.ObjectID, userID string, qty int) error {
+	usageKey := "customer_usage." + userID
+	_, err := s.coll.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		usageKey: bson.M{"$gte": qty},
+	}, bson.M{
+		"$inc": bson.M{"sold_count": -qty, usageKey: -qty},
+	})
+	return err
+}
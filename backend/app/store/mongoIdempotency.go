@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoIdempotencyStore struct {
+	coll *mongo.Collection
+}
+
+func (s *mongoIdempotencyStore) Claim(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	// TTL index chỉ dọn mỗi 60 giây nên tự xóa record đã hết hạn trước khi insert
+	if _, err := s.coll.DeleteOne(ctx, bson.M{
+		"_id":        record.Key,
+		"expires_at": bson.M{"$lte": time.Now()},
+	}); err != nil {
+		return nil, err
+	}
+
+	_, err := s.coll.InsertOne(ctx, record)
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	var existing models.IdempotencyRecord
+	if err := s.coll.FindOne(ctx, bson.M{"_id": record.Key}).Decode(&existing); err != nil {
+		return nil, translateErr(err)
+	}
+	return &existing, nil
+}
+
+func (s *mongoIdempotencyStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	result, err := s.coll.UpdateOne(ctx, bson.M{"_id": key}, bson.M{
+		"$set": bson.M{
+			"status":       models.IdempotencyCompleted,
+			"status_code":  statusCode,
+			"content_type": contentType,
+			"body":         body,
+			"expires_at":   expiresAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoIdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,398 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoOrderStore struct {
+	coll *mongo.Collection
+}
+
+// orderQuery build filter Mongo từ OrderFilter
+func orderQuery(filter OrderFilter) bson.M {
+	query := bson.M{}
+
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.UserID != nil {
+		query["user_id"] = *filter.UserID
+	}
+	if filter.GuestID != nil {
+		query["guest_id"] = *filter.GuestID
+	}
+	if filter.Email != "" {
+		query["shipping_address.email"] = filter.Email
+	}
+	if filter.ReturnStatus != "" {
+		query["returns.status"] = filter.ReturnStatus
+	}
+	if filter.HasShipment {
+		query["shipment"] = bson.M{"$exists": true}
+	}
+	if filter.DateFrom != nil || filter.DateTo != nil {
+		createdAt := bson.M{}
+		if filter.DateFrom != nil {
+			createdAt["$gte"] = *filter.DateFrom
+		}
+		if filter.DateTo != nil {
+			createdAt["$lte"] = *filter.DateTo
+		}
+		query["createdAt"] = createdAt
+	}
+
+	return query
+}
+
+func (s *mongoOrderStore) List(ctx context.Context, filter OrderFilter, opts ListOptions) ([]models.Order, error) {
+	opts.Sort = "-createdAt"
+	cursor, err := s.coll.Find(ctx, orderQuery(filter), findOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []models.Order{}
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (s *mongoOrderStore) Stream(ctx context.Context, filter OrderFilter, fn func(order *models.Order) error) error {
+	findOpts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetBatchSize(500)
+	cursor, err := s.coll.Find(ctx, orderQuery(filter), findOpts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var order models.Order
+		if err := cursor.Decode(&order); err != nil {
+			return err
+		}
+		if err := fn(&order); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (s *mongoOrderStore) Count(ctx context.Context, filter OrderFilter) (int64, error) {
+	return s.coll.CountDocuments(ctx, orderQuery(filter))
+}
+
+func (s *mongoOrderStore) findOne(ctx context.Context, query bson.M) (*models.Order, error) {
+	var order models.Order
+	if err := s.coll.FindOne(ctx, query).Decode(&order); err != nil {
+		return nil, translateErr(err)
+	}
+	return &order, nil
+}
+
+func (s *mongoOrderStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+func (s *mongoOrderStore) FindByNumber(ctx context.Context, orderNumber string) (*models.Order, error) {
+	return s.findOne(ctx, bson.M{"order_number": orderNumber})
+}
+
+func (s *mongoOrderStore) Insert(ctx context.Context, order *models.Order) error {
+	result, err := s.coll.InsertOne(ctx, order)
+	if err != nil {
+		return err
+	}
+	order.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoOrderStore) UpdateStatus(ctx context.Context, id primitive.ObjectID, from string, entry models.OrderStatusEntry, fields bson.M) error {
+	set := bson.M{
+		"status":    entry.Status,
+		"updatedAt": time.Now(),
+	}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	result, err := s.coll.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{
+		"$set":  set,
+		"$push": bson.M{"status_history": entry},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) UpdatePayment(ctx context.Context, id primitive.ObjectID, from string, fields bson.M) error {
+	set := bson.M{"updatedAt": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	result, err := s.coll.UpdateOne(ctx, bson.M{"_id": id, "payment.status": from}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) UpdateTracking(ctx context.Context, id primitive.ObjectID, from string, tracking models.Tracking) error {
+	result, err := s.coll.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{
+		"$set": bson.M{"tracking": tracking, "updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) AttachShipment(ctx context.Context, id primitive.ObjectID, from string, shipment models.Shipment, tracking models.Tracking) error {
+	query := bson.M{"_id": id, "status": from, "shipment": bson.M{"$exists": false}}
+	result, err := s.coll.UpdateOne(ctx, query, bson.M{
+		"$set": bson.M{"shipment": shipment, "tracking": tracking, "updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) UpdateShipment(ctx context.Context, id primitive.ObjectID, trackingNumber string, shipment models.Shipment) error {
+	query := bson.M{"_id": id, "shipment.tracking_number": trackingNumber}
+	result, err := s.coll.UpdateOne(ctx, query, bson.M{
+		"$set": bson.M{"shipment": shipment, "updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) AssignInvoice(ctx context.Context, id primitive.ObjectID, invoice models.Invoice) error {
+	query := bson.M{"_id": id, "invoice": bson.M{"$exists": false}}
+	result, err := s.coll.UpdateOne(ctx, query, bson.M{
+		"$set": bson.M{"invoice": invoice, "updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) AddPaymentCredit(ctx context.Context, id primitive.ObjectID, from string, credit models.PaymentCredit, fields bson.M) error {
+	set := bson.M{"updatedAt": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	result, err := s.coll.UpdateOne(ctx, bson.M{
+		"_id":                       id,
+		"payment.status":            from,
+		"payment.credits.reference": bson.M{"$ne": credit.Reference},
+	}, bson.M{
+		"$set":  set,
+		"$push": bson.M{"payment.credits": credit},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		order, err := s.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, existing := range order.Payment.Credits {
+			if existing.Reference == credit.Reference {
+				return ErrDuplicateCredit
+			}
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) AddRefund(ctx context.Context, id primitive.ObjectID, count int, refund models.OrderRefund, fields bson.M) error {
+	set := bson.M{"updatedAt": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	// Đơn có đúng count phần tử trong refunds: phần tử thứ count chưa tồn tại (và phần tử count-1 đã có)
+	query := bson.M{"_id": id, fmt.Sprintf("refunds.%d", count): bson.M{"$exists": false}}
+	if count > 0 {
+		query[fmt.Sprintf("refunds.%d", count-1)] = bson.M{"$exists": true}
+	}
+
+	result, err := s.coll.UpdateOne(ctx, query, bson.M{
+		"$set":  set,
+		"$push": bson.M{"refunds": refund},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) UpdateRefund(ctx context.Context, id primitive.ObjectID, refundID, from string, refund models.OrderRefund, fields bson.M) error {
+	set := bson.M{"updatedAt": time.Now(), "refunds.$": refund}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	query := bson.M{"_id": id, "refunds": bson.M{"$elemMatch": bson.M{"id": refundID, "status": from}}}
+	result, err := s.coll.UpdateOne(ctx, query, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) AddReturn(ctx context.Context, id primitive.ObjectID, count int, ret models.OrderReturn) error {
+	query := bson.M{"_id": id, fmt.Sprintf("returns.%d", count): bson.M{"$exists": false}}
+	if count > 0 {
+		query[fmt.Sprintf("returns.%d", count-1)] = bson.M{"$exists": true}
+	}
+
+	result, err := s.coll.UpdateOne(ctx, query, bson.M{
+		"$set":  bson.M{"updatedAt": time.Now()},
+		"$push": bson.M{"returns": ret},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) UpdateReturn(ctx context.Context, id primitive.ObjectID, from string, ret models.OrderReturn, fields bson.M) error {
+	set := bson.M{"updatedAt": time.Now(), "returns.$": ret}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	query := bson.M{"_id": id, "returns": bson.M{"$elemMatch": bson.M{"id": ret.ID, "status": from}}}
+	result, err := s.coll.UpdateOne(ctx, query, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoOrderStore) StatusTotals(ctx context.Context, filter OrderFilter) ([]OrderStatusTotal, error) {
+	// total_amount là số (currency mặc định, kể cả dữ liệu float cũ) hoặc {amount, currency};
+	// với VND đơn vị nhỏ nhất trùng đơn vị chính nên cộng lẫn hai kiểu vẫn đúng
+	amount := func(field string) bson.M {
+		return bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$" + field}, "object"}},
+			"$" + field + ".amount",
+			"$" + field,
+		}}
+	}
+	pipeline := []bson.M{
+		{"$match": orderQuery(filter)},
+		{
+			"$group": bson.M{
+				"_id":          "$status",
+				"count":        bson.M{"$sum": 1},
+				"total_amount": bson.M{"$sum": amount("total_amount")},
+				"tax_amount":   bson.M{"$sum": amount("tax_amount")},
+				// Đơn chưa hoàn tiền không có refunded_amount, $sum bỏ qua giá trị thiếu
+				"refunded_amount": bson.M{"$sum": amount("refunded_amount")},
+			},
+		},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	totals := []OrderStatusTotal{}
+	if err = cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoProductStore struct {
+	coll *mongo.Collection
+}
+
+// productQuery build filter Mongo từ ProductFilter
+func productQuery(filter ProductFilter) bson.M {
+	query := bson.M{}
+
+	if filter.Search != "" {
+		query["name"] = bson.M{"$regex": filter.Search, "$options": "i"}
+	}
+	if filter.Category != "" {
+		query["category"] = filter.Category
+	}
+	if filter.IsFeatured != nil {
+		query["is_featured"] = *filter.IsFeatured
+	}
+
+	var exclusions []bson.M
+	if filter.ExcludeSlug != "" {
+		exclusions = append(exclusions, bson.M{"slug": bson.M{"$ne": filter.ExcludeSlug}})
+	}
+	if filter.ExcludeProductID != "" {
+		exclusions = append(exclusions, bson.M{"id": bson.M{"$ne": filter.ExcludeProductID}})
+	}
+	if !filter.ExcludeID.IsZero() {
+		exclusions = append(exclusions, bson.M{"_id": bson.M{"$ne": filter.ExcludeID}})
+	}
+	if len(exclusions) > 0 {
+		query["$and"] = exclusions
+	}
+
+	return query
+}
+
+func (s *mongoProductStore) List(ctx context.Context, filter ProductFilter, opts ListOptions) ([]models.Product, error) {
+	cursor, err := s.coll.Find(ctx, productQuery(filter), findOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	products := []models.Product{}
+	if err = cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (s *mongoProductStore) Count(ctx context.Context, filter ProductFilter) (int64, error) {
+	return s.coll.CountDocuments(ctx, productQuery(filter))
+}
+
+func (s *mongoProductStore) findOne(ctx context.Context, query bson.M) (*models.Product, error) {
+	var product models.Product
+	if err := s.coll.FindOne(ctx, query).Decode(&product); err != nil {
+		return nil, translateErr(err)
+	}
+	return &product, nil
+}
+
+func (s *mongoProductStore) FindByObjectID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+func (s *mongoProductStore) FindByProductID(ctx context.Context, productID string) (*models.Product, error) {
+	return s.findOne(ctx, bson.M{"id": productID})
+}
+
+func (s *mongoProductStore) FindBySlug(ctx context.Context, slug string) (*models.Product, error) {
+	return s.findOne(ctx, bson.M{"slug": slug})
+}
+
+func (s *mongoProductStore) ExistsByProductIDOrSlug(ctx context.Context, productID, slug string) (bool, error) {
+	count, err := s.coll.CountDocuments(ctx, bson.M{
+		"$or": []bson.M{
+			{"id": productID},
+			{"slug": slug},
+		},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *mongoProductStore) Insert(ctx context.Context, product *models.Product) error {
+	result, err := s.coll.InsertOne(ctx, product)
+	if err != nil {
+		return err
+	}
+	product.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoProductStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.Product, error) {
+	var updated models.Product
+	err := s.coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &updated, nil
+}
+
+func (s *mongoProductStore) UpdateIfStock(ctx context.Context, id primitive.ObjectID, amount, stock int, fields bson.M) error {
+	result, err := s.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "amount": amount, "stock": stock},
+		bson.M{"$set": fields},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := s.FindByObjectID(ctx, id); err != nil {
+			return err
+		}
+		return ErrStockChanged
+	}
+	return nil
+}
+
+func (s *mongoProductStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoProductStore) AdjustStock(ctx context.Context, productID string, delta int) error {
+	result, err := s.coll.UpdateOne(ctx, bson.M{"id": productID}, bson.M{
+		"$inc": bson.M{"amount": delta},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoProductStore) DecrementStock(ctx context.Context, productID string, qty int) error {
+	result, err := s.coll.UpdateOne(ctx,
+		bson.M{"id": productID, "amount": bson.M{"$gte": qty}},
+		bson.M{"$inc": bson.M{"amount": -qty}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		// Phân biệt sản phẩm không tồn tại với không đủ hàng
+		if _, err := s.FindByProductID(ctx, productID); err != nil {
+			return err
+		}
+		return ErrInsufficientStock
+	}
+	return nil
+}
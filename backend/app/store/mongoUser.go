@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUserStore struct {
+	coll *mongo.Collection
+}
+
+// userQuery build filter Mongo từ UserFilter
+func userQuery(filter UserFilter) bson.M {
+	query := bson.M{}
+
+	if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+	if filter.Search != "" {
+		query["$or"] = []bson.M{
+			{"username": bson.M{"$regex": filter.Search, "$options": "i"}},
+			{"email": bson.M{"$regex": filter.Search, "$options": "i"}},
+			{"full_name": bson.M{"$regex": filter.Search, "$options": "i"}},
+		}
+	}
+	if filter.CreatedSince != nil {
+		query["created_at"] = bson.M{"$gte": *filter.CreatedSince}
+	}
+
+	return query
+}
+
+func (s *mongoUserStore) List(ctx context.Context, filter UserFilter, opts ListOptions) ([]models.User, error) {
+	cursor, err := s.coll.Find(ctx, userQuery(filter), findOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *mongoUserStore) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	return s.coll.CountDocuments(ctx, userQuery(filter))
+}
+
+func (s *mongoUserStore) findOne(ctx context.Context, query bson.M) (*models.User, error) {
+	var user models.User
+	if err := s.coll.FindOne(ctx, query).Decode(&user); err != nil {
+		return nil, translateErr(err)
+	}
+	return &user, nil
+}
+
+func (s *mongoUserStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+func (s *mongoUserStore) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.findOne(ctx, bson.M{"username": username})
+}
+
+func (s *mongoUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.findOne(ctx, bson.M{"email": email})
+}
+
+func (s *mongoUserStore) exists(ctx context.Context, query bson.M, excludeID primitive.ObjectID) (bool, error) {
+	if !excludeID.IsZero() {
+		query["_id"] = bson.M{"$ne": excludeID}
+	}
+	count, err := s.coll.CountDocuments(ctx, query, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *mongoUserStore) ExistsByUsername(ctx context.Context, username string, excludeID primitive.ObjectID) (bool, error) {
+	return s.exists(ctx, bson.M{"username": username}, excludeID)
+}
+
+func (s *mongoUserStore) ExistsByEmail(ctx context.Context, email string, excludeID primitive.ObjectID) (bool, error) {
+	return s.exists(ctx, bson.M{"email": email}, excludeID)
+}
+
+func (s *mongoUserStore) Insert(ctx context.Context, user *models.User) error {
+	result, err := s.coll.InsertOne(ctx, user)
+	if err != nil {
+		return err
+	}
+	user.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoUserStore) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	result, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoUserStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoWishlistStore struct {
+	coll *mongo.Collection
+}
+
+func (s *mongoWishlistStore) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	if err := s.coll.FindOne(ctx, bson.M{"user_id": userID}).Decode(&wishlist); err != nil {
+		return nil, translateErr(err)
+	}
+	return &wishlist, nil
+}
+
+func (s *mongoWishlistStore) Save(ctx context.Context, wishlist *models.Wishlist) error {
+	if wishlist.ID.IsZero() {
+		result, err := s.coll.InsertOne(ctx, wishlist)
+		if err != nil {
+			return err
+		}
+		wishlist.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	}
+
+	_, err := s.coll.ReplaceOne(ctx, bson.M{"_id": wishlist.ID}, wishlist)
+	return err
+}
+
+func (s *mongoWishlistStore) Clear(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{
+		"$set": bson.M{
+			"items":       []models.WishlistItem{},
+			"total_items": 0,
+			"updated_at":  time.Now(),
+		},
+	})
+	return err
+}
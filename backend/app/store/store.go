@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mingfulsnack/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound được trả về khi không tìm thấy document phù hợp
+var ErrNotFound = errors.New("store: not found")
+
+// ErrTransactionsUnsupported được trả về bởi WithTransaction khi backend không hỗ trợ transaction
+var ErrTransactionsUnsupported = errors.New("store: transactions not supported")
+
+// ErrInsufficientStock được trả về bởi DecrementStock khi amount nhỏ hơn số lượng cần trừ
+var ErrInsufficientStock = errors.New("store: insufficient stock")
+
+// ErrStatusConflict được trả về bởi UpdateStatus khi trạng thái đơn hàng đã bị thay đổi bởi request khác
+var ErrStatusConflict = errors.New("store: order status changed")
+
+// ErrStockChanged được trả về bởi UpdateIfStock khi amount/stock của sản phẩm đã bị thay đổi bởi request khác
+var ErrStockChanged = errors.New("store: product stock changed")
+
+// ErrDuplicateCredit được trả về bởi AddPaymentCredit khi giao dịch ngân hàng đã được ghi nhận cho đơn hàng
+var ErrDuplicateCredit = errors.New("store: payment credit already recorded")
+
+// ErrCouponLimitReached được trả về bởi Redeem khi mã giảm giá đã hết lượt (tổng hoặc theo user)
+var ErrCouponLimitReached = errors.New("store: coupon usage limit reached")
+
+// ErrFlashSaleLimitReached được trả về bởi FlashSaleStore.Reserve khi flash sale không còn đủ số lượng
+var ErrFlashSaleLimitReached = errors.New("store: flash sale quantity limit reached")
+
+// Store gom tất cả các repository mà tầng service cần dùng.
+// Service chỉ phụ thuộc vào interface này nên có thể thay Mongo bằng implementation khác.
+type Store interface {
+	Products() ProductStore
+	Categories() CategoryStore
+	Users() UserStore
+	Carts() CartStore
+	Wishlists() WishlistStore
+	Compares() CompareStore
+	Orders() OrderStore
+	Counters() CounterStore
+	Idempotency() IdempotencyStore
+	Coupons() CouponStore
+	FlashSales() FlashSaleStore
+
+	// WithTransaction chạy fn trong một transaction, các thao tác phải dùng ctx được truyền vào fn.
+	// Trả về ErrTransactionsUnsupported (không gọi fn) nếu backend không hỗ trợ, ví dụ Mongo standalone.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ListOptions represents pagination and sorting for list queries
+type ListOptions struct {
+	Skip  int64
+	Limit int64  // 0 = không giới hạn
+	Sort  string // tên field, tiền tố "-" để sắp xếp giảm dần (ví dụ "-price")
+}
+
+// ProductFilter represents the supported product query conditions
+type ProductFilter struct {
+	Search     string // tìm theo name, không phân biệt hoa thường
+	Category   string // Category.id
+	IsFeatured *bool
+
+	// Loại trừ một sản phẩm (dùng cho sản phẩm liên quan)
+	ExcludeID        primitive.ObjectID
+	ExcludeProductID string
+	ExcludeSlug      string
+}
+
+// ProductStore quản lý collection Products
+type ProductStore interface {
+	List(ctx context.Context, filter ProductFilter, opts ListOptions) ([]models.Product, error)
+	Count(ctx context.Context, filter ProductFilter) (int64, error)
+	FindByObjectID(ctx context.Context, id primitive.ObjectID) (*models.Product, error)
+	FindByProductID(ctx context.Context, productID string) (*models.Product, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Product, error)
+	ExistsByProductIDOrSlug(ctx context.Context, productID, slug string) (bool, error)
+	Insert(ctx context.Context, product *models.Product) error
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.Product, error)
+	// UpdateIfStock set các field trong fields chỉ khi amount và stock hiện tại bằng giá trị đã đọc trước đó.
+	// Trả về ErrStockChanged nếu tồn kho đã thay đổi.
+	UpdateIfStock(ctx context.Context, id primitive.ObjectID, amount, stock int, fields bson.M) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// AdjustStock cộng delta vào amount của sản phẩm có id (ProductID) tương ứng
+	AdjustStock(ctx context.Context, productID string, delta int) error
+	// DecrementStock trừ qty khỏi amount chỉ khi amount >= qty, ngược lại trả về ErrInsufficientStock
+	DecrementStock(ctx context.Context, productID string, qty int) error
+}
+
+// CategoryStore quản lý collection categories
+type CategoryStore interface {
+	List(ctx context.Context) ([]models.Category, error)
+	Count(ctx context.Context) (int64, error)
+	FindByObjectID(ctx context.Context, id primitive.ObjectID) (*models.Category, error)
+	FindByCategoryID(ctx context.Context, categoryID string) (*models.Category, error)
+	// ExistsByName kiểm tra tên (không phân biệt hoa thường), bỏ qua excludeID nếu khác zero
+	ExistsByName(ctx context.Context, name string, excludeID primitive.ObjectID) (bool, error)
+	Insert(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// UserFilter represents the supported user query conditions
+type UserFilter struct {
+	Role         string
+	Search       string // tìm theo username, email, full_name
+	CreatedSince *time.Time
+}
+
+// UserStore quản lý collection Users
+type UserStore interface {
+	List(ctx context.Context, filter UserFilter, opts ListOptions) ([]models.User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// ExistsByUsername/ExistsByEmail bỏ qua excludeID nếu khác zero
+	ExistsByUsername(ctx context.Context, username string, excludeID primitive.ObjectID) (bool, error)
+	ExistsByEmail(ctx context.Context, email string, excludeID primitive.ObjectID) (bool, error)
+	Insert(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// CartStore quản lý collection Carts
+type CartStore interface {
+	FindByUser(ctx context.Context, userID primitive.ObjectID, cartType string) (*models.Cart, error)
+	// Save insert nếu cart chưa có ID, ngược lại replace toàn bộ document
+	Save(ctx context.Context, cart *models.Cart) error
+	// Clear xóa toàn bộ items và mã giảm giá, không lỗi nếu cart chưa tồn tại
+	Clear(ctx context.Context, userID primitive.ObjectID, cartType string) error
+}
+
+// WishlistStore quản lý collection Wishlists
+type WishlistStore interface {
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.Wishlist, error)
+	Save(ctx context.Context, wishlist *models.Wishlist) error
+	Clear(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// CompareStore quản lý collection Compares
+type CompareStore interface {
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.Compare, error)
+	Save(ctx context.Context, compare *models.Compare) error
+	Clear(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// OrderFilter represents the supported order query conditions
+type OrderFilter struct {
+	Status       string
+	UserID       *primitive.ObjectID
+	GuestID      *primitive.ObjectID // Đơn của khách đặt bằng guest token
+	Email        string              // shipping_address.email
+	ReturnStatus string              // Đơn có ít nhất một yêu cầu trả hàng ở trạng thái này
+	HasShipment  bool                // Chỉ đơn đã tạo vận đơn qua carrier
+	DateFrom     *time.Time
+	DateTo       *time.Time
+}
+
+// OrderStatusTotal là kết quả thống kê đơn hàng theo từng trạng thái
+type OrderStatusTotal struct {
+	Status      string       `bson:"_id" json:"_id"`
+	Count       int64        `bson:"count" json:"count"`
+	TotalAmount models.Money `bson:"total_amount" json:"total_amount"`
+	TaxAmount   models.Money `bson:"tax_amount" json:"tax_amount"`
+	Refunded    models.Money `bson:"refunded_amount" json:"refunded_amount"`
+}
+
+// OrderStore quản lý collection orders. List luôn trả về đơn mới nhất trước.
+type OrderStore interface {
+	List(ctx context.Context, filter OrderFilter, opts ListOptions) ([]models.Order, error)
+	// Stream gọi fn cho từng đơn khớp filter theo thứ tự tạo (cũ trước), đọc dần qua cursor thay vì
+	// tải cả danh sách vào bộ nhớ. Dừng lại và trả về lỗi của fn nếu fn trả lỗi.
+	Stream(ctx context.Context, filter OrderFilter, fn func(order *models.Order) error) error
+	Count(ctx context.Context, filter OrderFilter) (int64, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error)
+	FindByNumber(ctx context.Context, orderNumber string) (*models.Order, error)
+	Insert(ctx context.Context, order *models.Order) error
+	// UpdateStatus chuyển đơn từ trạng thái from sang entry.Status, thêm entry vào status_history
+	// và set thêm các field trong fields. Trả về ErrStatusConflict nếu trạng thái hiện tại khác from.
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, from string, entry models.OrderStatusEntry, fields bson.M) error
+	// UpdatePayment set các field thanh toán (payment.*) khi payment.status hiện tại bằng from.
+	// Trả về ErrStatusConflict nếu trạng thái thanh toán đã thay đổi.
+	UpdatePayment(ctx context.Context, id primitive.ObjectID, from string, fields bson.M) error
+	// UpdateTracking set thông tin vận chuyển khi trạng thái đơn hiện tại bằng from.
+	// Trả về ErrStatusConflict nếu trạng thái đã thay đổi.
+	UpdateTracking(ctx context.Context, id primitive.ObjectID, from string, tracking models.Tracking) error
+	// AttachShipment gắn vận đơn (và tracking tương ứng) khi trạng thái đơn bằng from và đơn chưa có vận đơn.
+	// Trả về ErrStatusConflict nếu trạng thái đã thay đổi hoặc đơn đã có vận đơn.
+	AttachShipment(ctx context.Context, id primitive.ObjectID, from string, shipment models.Shipment, tracking models.Tracking) error
+	// UpdateShipment thay vận đơn của đơn khi vận đơn hiện tại có mã trackingNumber.
+	// Trả về ErrStatusConflict nếu đơn đã đổi vận đơn khác.
+	UpdateShipment(ctx context.Context, id primitive.ObjectID, trackingNumber string, shipment models.Shipment) error
+	// AssignInvoice gắn hóa đơn cho đơn chưa có hóa đơn.
+	// Trả về ErrStatusConflict nếu đơn đã được cấp hóa đơn.
+	AssignInvoice(ctx context.Context, id primitive.ObjectID, invoice models.Invoice) error
+	// AddPaymentCredit thêm credit vào payment.credits và set fields khi payment.status bằng from.
+	// Trả về ErrDuplicateCredit nếu đơn đã có credit cùng reference, ErrStatusConflict nếu trạng thái đã đổi.
+	AddPaymentCredit(ctx context.Context, id primitive.ObjectID, from string, credit models.PaymentCredit, fields bson.M) error
+	// AddRefund thêm refund vào refunds và set fields khi đơn đang có đúng count lần hoàn tiền.
+	// Trả về ErrStatusConflict nếu đơn vừa được hoàn tiền bởi request khác.
+	AddRefund(ctx context.Context, id primitive.ObjectID, count int, refund models.OrderRefund, fields bson.M) error
+	// UpdateRefund thay lần hoàn tiền có mã refundID bằng refund và set fields khi lần hoàn tiền đang ở trạng thái from.
+	// Trả về ErrStatusConflict nếu trạng thái lần hoàn tiền đã thay đổi.
+	UpdateRefund(ctx context.Context, id primitive.ObjectID, refundID, from string, refund models.OrderRefund, fields bson.M) error
+	// AddReturn thêm yêu cầu trả hàng vào returns khi đơn đang có đúng count yêu cầu.
+	// Trả về ErrStatusConflict nếu đơn vừa có yêu cầu khác.
+	AddReturn(ctx context.Context, id primitive.ObjectID, count int, ret models.OrderReturn) error
+	// UpdateReturn thay yêu cầu trả hàng có cùng ret.ID và set fields khi yêu cầu đang ở trạng thái from.
+	// Trả về ErrStatusConflict nếu trạng thái yêu cầu đã thay đổi.
+	UpdateReturn(ctx context.Context, id primitive.ObjectID, from string, ret models.OrderReturn, fields bson.M) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	StatusTotals(ctx context.Context, filter OrderFilter) ([]OrderStatusTotal, error)
+}
+
+// CounterStore quản lý các bộ đếm tăng dần (collection Counters)
+type CounterStore interface {
+	// Next tăng bộ đếm key lên 1 một cách nguyên tử và trả về giá trị mới (bắt đầu từ 1)
+	Next(ctx context.Context, key string) (int64, error)
+}
+
+// IdempotencyStore quản lý collection IdempotencyKeys
+type IdempotencyStore interface {
+	// Claim lưu record (trạng thái processing) nếu key chưa được dùng hoặc đã hết hạn và trả về nil.
+	// Nếu key đang còn hiệu lực thì không ghi gì và trả về record hiện có.
+	Claim(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// Complete lưu response để phát lại cho các request sau và gia hạn record tới expiresAt
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error
+	// Release xóa record để client có thể thử lại (dùng khi xử lý lỗi)
+	Release(ctx context.Context, key string) error
+}
+
+// CouponFilter represents the supported coupon query conditions
+type CouponFilter struct {
+	Search   string // tìm theo code, description
+	IsActive *bool
+}
+
+// CouponStore quản lý collection Coupons. Code luôn được lưu dạng viết hoa.
+type CouponStore interface {
+	List(ctx context.Context, filter CouponFilter, opts ListOptions) ([]models.Coupon, error)
+	Count(ctx context.Context, filter CouponFilter) (int64, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Coupon, error)
+	FindByCode(ctx context.Context, code string) (*models.Coupon, error)
+	// ExistsByCode bỏ qua excludeID nếu khác zero
+	ExistsByCode(ctx context.Context, code string, excludeID primitive.ObjectID) (bool, error)
+	Insert(ctx context.Context, coupon *models.Coupon) error
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.Coupon, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Redeem tăng used_count và user_usage[userID] một cách nguyên tử, chỉ khi chưa vượt
+	// usage_limit và per_user_limit đang lưu trong document; ngược lại trả về ErrCouponLimitReached
+	Redeem(ctx context.Context, code, userID string) error
+	// Release trả lại một lượt đã Redeem (hủy đơn hoặc rollback)
+	Release(ctx context.Context, code, userID string) error
+}
+
+// FlashSaleFilter represents the supported flash sale query conditions
+type FlashSaleFilter struct {
+	ProductID string
+	IsActive  *bool
+}
+
+// FlashSaleStore quản lý collection FlashSales. List trả về flash sale bắt đầu sớm nhất trước.
+type FlashSaleStore interface {
+	List(ctx context.Context, filter FlashSaleFilter, opts ListOptions) ([]models.FlashSale, error)
+	Count(ctx context.Context, filter FlashSaleFilter) (int64, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.FlashSale, error)
+	// FindRunning trả về các flash sale đang bật và đang diễn ra tại at của các sản phẩm (Product.id)
+	FindRunning(ctx context.Context, productIDs []string, at time.Time) ([]models.FlashSale, error)
+	Insert(ctx context.Context, sale *models.FlashSale) error
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*models.FlashSale, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Reserve tăng sold_count và customer_usage[userID] thêm qty một cách nguyên tử, chỉ khi không vượt
+	// quantity và per_customer_limit; ngược lại trả về ErrFlashSaleLimitReached
+	Reserve(ctx context.Context, id primitive.ObjectID, userID string, qty int) error
+	// Release trả lại số lượng đã Reserve (hủy đơn hoặc rollback)
+	Release(ctx context.Context, id primitive.ObjectID, userID string, qty int) error
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/mingfulsnack/app/controllers"
+)
+
+// mockcarrier chạy carrier giả để thử tạo vận đơn khi phát triển local, vd:
+//
+//	MOCK_CARRIER_TOKEN=dev go run ./cmd/mockcarrier
+//	CARRIERS='[{"code":"ghn","base_url":"http://localhost:5055","token":"dev"}]' go run ./cmd/server
+func main() {
+	addr := os.Getenv("MOCK_CARRIER_ADDR")
+	if addr == "" {
+		addr = ":5055"
+	}
+	token := os.Getenv("MOCK_CARRIER_TOKEN")
+	if token == "" {
+		token = "dev"
+	}
+
+	log.Printf("Mock carrier listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, controllers.NewMockCarrierServer(token)))
+}
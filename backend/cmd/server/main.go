@@ -3,14 +3,17 @@ package main
 import (
 	"context"
 	"log"
-	//"os"
+	"os"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/mingfulsnack/app/config"
+	"github.com/mingfulsnack/app/controllers"
 	"github.com/mingfulsnack/app/models"
 	"github.com/mingfulsnack/app/routes"
+	"github.com/mingfulsnack/app/store"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -34,16 +37,27 @@ func main() {
 	// Setup Gin router
 	router := gin.Default()
 
+	// Chỉ đọc IP client từ X-Forwarded-For khi request đến từ proxy tin cậy (TRUSTED_PROXIES,
+	// danh sách IP/CIDR cách nhau bởi dấu phẩy); mặc định dùng địa chỉ kết nối trực tiếp.
+	// Giới hạn số lần gọi theo IP (tra cứu đơn, guest token) dựa vào IP này.
+	if err := router.SetTrustedProxies(trustedProxiesFromEnv()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Setup CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"}, // Frontend URLs
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Idempotency-Key", "X-Guest-Token"},
 		AllowCredentials: true,
 	}))
 
 	// Setup routes
-	routes.SetupRoutes(router)
+	st := store.NewMongoStore(config.GetDB())
+	routes.SetupRoutes(router, st)
+
+	// Đồng bộ trạng thái vận đơn với carrier (CARRIERS, SHIPMENT_POLL_INTERVAL)
+	controllers.StartShipmentPolling(context.Background(), st)
 
 	// Get port from environment variable or use default
 	port := "5000" //os.Getenv("PORT")
@@ -55,6 +69,17 @@ func main() {
 	log.Fatal(router.Run(":" + port))
 }
 
+// trustedProxiesFromEnv đọc TRUSTED_PROXIES; rỗng = không tin proxy nào
+func trustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 // initializeCollections tạo collections và indexes
 func initializeCollections() {
 	ctx := context.Background()
@@ -69,6 +94,9 @@ func initializeCollections() {
 		models.EnsureOrderCollection,
 		models.EnsureWishlistCollection,
 		models.EnsureCompareCollection,
+		models.EnsureIdempotencyCollection,
+		models.EnsureCouponCollection,
+		models.EnsureFlashSaleCollection,
 	}
 
 	for _, ensureFunc := range collections {
@@ -22,7 +22,6 @@ import OrderDetail from './pages/OrderDetail'
 import DebugAuth from './pages/DebugAuth'
 
 import LoadingSpinner from './components/LoadingSpinner'
-import ProtectedRoute from './components/ProtectedRoute'
 import ProtectedAdminRoute from './components/ProtectedAdminRoute'
 
 // Admin Pages
@@ -62,39 +61,11 @@ function AppContent() {
           <Route path="/order-tracking" element={<OrderDetail />} />
           <Route path="/order-tracking/:orderNumber" element={<OrderDetail />} />
           
-          {/* Protected Routes - Require Authentication */}
-          <Route 
-            path="/cart" 
-            element={
-              <ProtectedRoute>
-                <Cart />
-              </ProtectedRoute>
-            } 
-          />
-          <Route 
-            path="/wishlist" 
-            element={
-              <ProtectedRoute>
-                <Wishlist />
-              </ProtectedRoute>
-            } 
-          />
-          <Route 
-            path="/compare" 
-            element={
-              <ProtectedRoute>
-                <Compare />
-              </ProtectedRoute>
-            } 
-          />
-          <Route 
-            path="/checkout" 
-            element={
-              <ProtectedRoute>
-                <Checkout />
-              </ProtectedRoute>
-            } 
-          />
+          {/* Giỏ hàng, wishlist, so sánh và đặt hàng: đăng nhập hoặc guest token */}
+          <Route path="/cart" element={<Cart />} />
+          <Route path="/wishlist" element={<Wishlist />} />
+          <Route path="/compare" element={<Compare />} />
+          <Route path="/checkout" element={<Checkout />} />
           
           {/* Admin Routes */}
           <Route 
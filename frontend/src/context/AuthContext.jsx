@@ -86,7 +86,11 @@ export function AuthProvider({ children }) {
     // Lưu vào localStorage
     localStorage.setItem('token', token) // Changed from 'accessToken' to 'token'
     localStorage.setItem('user', JSON.stringify(user))
-    
+    // Giỏ hàng của khách đã được gộp vào tài khoản nên không cần guest token nữa
+    if (data.guest_merge && !data.guest_merge.error) {
+      localStorage.removeItem('guestToken')
+    }
+
     dispatch({
       type: 'LOGIN_SUCCESS',
       payload: { user, token }
@@ -1,5 +1,5 @@
 import React, { createContext, useContext, useReducer, useEffect } from 'react'
-import { cartAPI, wishlistAPI, compareAPI } from '../services/api'
+import { cartAPI, wishlistAPI, compareAPI, ensureGuestToken } from '../services/api'
 import { useAuth } from './AuthContext'
 import { toast } from 'react-toastify'
 
@@ -61,9 +61,9 @@ export function CartProvider({ children }) {
   const [state, dispatch] = useReducer(cartReducer, initialState)
   const { isLoggedIn, isAdmin } = useAuth()
 
-  // Load cart data when user logs in
+  // Load cart data when user logs in, or for a guest who already has a guest token
   useEffect(() => {
-    if (isLoggedIn && !isAdmin()) {
+    if (!isAdmin() && (isLoggedIn || localStorage.getItem('guestToken'))) {
       loadAllCartData()
     } else {
       // Clear cart data when logged out or admin
@@ -71,6 +71,19 @@ export function CartProvider({ children }) {
     }
   }, [isLoggedIn])
 
+  // Khách chưa đăng nhập cần guest token trước khi thao tác với giỏ hàng
+  const ensureCartAccess = async () => {
+    if (isLoggedIn) return true
+    try {
+      await ensureGuestToken()
+      return true
+    } catch (error) {
+      console.error('Guest token error:', error)
+      toast.error('Không thể tạo giỏ hàng cho khách, vui lòng thử lại')
+      return false
+    }
+  }
+
   const loadAllCartData = async () => {
     try {
       await Promise.all([
@@ -135,13 +148,12 @@ export function CartProvider({ children }) {
   }
 
   const addToCart = async (productId, cartType = 'cart', quantity = 1) => {
-    if (!isLoggedIn) {
-      toast.error('Vui lòng đăng nhập để thêm sản phẩm vào giỏ hàng')
+    if (isAdmin()) {
+      toast.error('Admin không thể thêm sản phẩm vào giỏ hàng')
       return
     }
 
-    if (isAdmin()) {
-      toast.error('Admin không thể thêm sản phẩm vào giỏ hàng')
+    if (!(await ensureCartAccess())) {
       return
     }
 
@@ -187,13 +199,12 @@ export function CartProvider({ children }) {
   }
 
   const removeFromCart = async (productId, cartType = 'cart') => {
-    if (!isLoggedIn) {
-      toast.error('Vui lòng đăng nhập')
+    if (isAdmin()) {
+      toast.error('Admin không có giỏ hàng')
       return
     }
 
-    if (isAdmin()) {
-      toast.error('Admin không có giỏ hàng')
+    if (!(await ensureCartAccess())) {
       return
     }
 
@@ -230,13 +241,12 @@ export function CartProvider({ children }) {
   }
 
   const clearCart = async (cartType = 'cart') => {
-    if (!isLoggedIn) {
-      toast.error('Vui lòng đăng nhập')
+    if (isAdmin()) {
+      toast.error('Admin không có giỏ hàng')
       return
     }
 
-    if (isAdmin()) {
-      toast.error('Admin không có giỏ hàng')
+    if (!(await ensureCartAccess())) {
       return
     }
 
@@ -275,13 +285,12 @@ export function CartProvider({ children }) {
   }
 
   const updateQuantity = async (productId, quantity) => {
-    if (!isLoggedIn) {
-      toast.error('Vui lòng đăng nhập')
+    if (isAdmin()) {
+      toast.error('Admin không có giỏ hàng')
       return
     }
 
-    if (isAdmin()) {
-      toast.error('Admin không có giỏ hàng')
+    if (!(await ensureCartAccess())) {
       return
     }
 
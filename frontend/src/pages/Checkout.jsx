@@ -1,7 +1,7 @@
-import React, { useState } from 'react'
+import React, { useState, useEffect } from 'react'
 import { Link, useNavigate } from 'react-router-dom'
 import { useCart } from '../context/CartContext'
-import { orderAPI } from '../services/api'
+import { orderAPI, ensureGuestToken } from '../services/api'
 
 function Checkout() {
   const { cart = [], cartCount = 0, clearCart } = useCart()
@@ -16,10 +16,57 @@ function Checkout() {
     country: 'VN',
     address1: '',
     address2: '',
+    city: '',
     comment: '',
     paymentMethod: 'COD' // Add payment method field with valid default
   })
 
+  const [quote, setQuote] = useState(null)
+  const [quoteLoading, setQuoteLoading] = useState(false)
+  const [quoteError, setQuoteError] = useState('')
+  const [shippingMethod, setShippingMethod] = useState('')
+
+  // Báo giá lại khi địa chỉ hoặc số lượng trong giỏ thay đổi
+  const cartKey = cart.map(item => `${item.product_id}:${item.quantity}`).join(',')
+
+  // Lấy phương thức giao hàng, phí và tổng tiền theo tỉnh/thành giao hàng
+  useEffect(() => {
+    if (cartCount === 0) {
+      setQuote(null)
+      return
+    }
+
+    let cancelled = false
+    const timer = setTimeout(async () => {
+      try {
+        setQuoteLoading(true)
+        setQuoteError('')
+        await ensureGuestToken()
+        const response = await orderAPI.quoteOrder({ city: formData.city })
+        if (cancelled) return
+        const data = response.data.data
+        setQuote(data)
+        setShippingMethod(prev =>
+          data.options.some(option => option.method === prev) ? prev : (data.options[0]?.method || '')
+        )
+      } catch (error) {
+        if (cancelled) return
+        console.error('Quote error:', error)
+        setQuote(null)
+        setQuoteError(error.response?.data?.message || 'Không thể tính phí giao hàng')
+      } finally {
+        if (!cancelled) setQuoteLoading(false)
+      }
+    }, 400)
+
+    return () => {
+      cancelled = true
+      clearTimeout(timer)
+    }
+  }, [formData.city, cartCount, cartKey])
+
+  const selectedOption = quote?.options.find(option => option.method === shippingMethod)
+
   const handleInputChange = (e) => {
     const { name, value } = e.target
     setFormData(prev => ({
@@ -34,6 +81,7 @@ function Checkout() {
   }
 
   const calculateTotal = () => {
+    if (selectedOption) return selectedOption.total_amount
     return calculateSubtotal()
   }
 
@@ -51,6 +99,11 @@ function Checkout() {
       return
     }
 
+    if (!selectedOption) {
+      alert('Please choose a shipping method')
+      return
+    }
+
     try {
       setIsSubmitting(true)
       
@@ -61,16 +114,20 @@ function Checkout() {
       
       const orderData = {
         shipping_address: fullAddress,
+        city: formData.city,
         phone: formData.phone,
         customer_phone: formData.phone,
         customer_name: `${formData.firstName} ${formData.lastName}`,
         customer_email: formData.email,
         payment_method: formData.paymentMethod,
+        shipping_method: shippingMethod,
         notes: formData.comment || ""
       }
       
       console.log('Creating order with data:', orderData)
       
+      // Khách chưa đăng nhập đặt hàng bằng guest token của giỏ hàng
+      await ensureGuestToken()
       const response = await orderAPI.createOrder(orderData)
       
       if (response.data.success) {
@@ -155,6 +212,32 @@ function Checkout() {
                       ))}
                     </tbody>
                     <tfoot>
+                      {quote && (
+                        <>
+                          <tr>
+                            <td colSpan="3">Subtotal:</td>
+                            <td>${quote.subtotal.toFixed(2)}</td>
+                          </tr>
+                          {quote.discount_amount > 0 && (
+                            <tr>
+                              <td colSpan="3">Discount{quote.coupon?.code ? ` (${quote.coupon.code})` : ''}:</td>
+                              <td>-${quote.discount_amount.toFixed(2)}</td>
+                            </tr>
+                          )}
+                          <tr>
+                            <td colSpan="3">VAT{quote.tax_inclusive ? ' (included)' : ''}:</td>
+                            <td>${quote.tax_amount.toFixed(2)}</td>
+                          </tr>
+                          <tr>
+                            <td colSpan="3">Shipping{selectedOption ? ` (${selectedOption.name})` : ''}:</td>
+                            <td>
+                              {selectedOption
+                                ? (selectedOption.free ? 'Free' : `$${selectedOption.fee.toFixed(2)}`)
+                                : '-'}
+                            </td>
+                          </tr>
+                        </>
+                      )}
                       <tr>
                         <td colSpan="3"><strong>Total:</strong></td>
                         <td><strong>${calculateTotal().toFixed(2)}</strong></td>
@@ -255,6 +338,44 @@ function Checkout() {
                     />
                   </div>
 
+                  <div className="mb-3">
+                    <label className="form-label">City / Province</label>
+                    <input 
+                      type="text" 
+                      className="form-control" 
+                      name="city"
+                      value={formData.city}
+                      onChange={handleInputChange}
+                      placeholder="Ho Chi Minh City"
+                    />
+                  </div>
+
+                  <div className="mb-3">
+                    <label className="form-label">Shipping Method</label>
+                    {quoteLoading && <div className="text-muted small">Calculating shipping...</div>}
+                    {quoteError && <div className="text-danger small">{quoteError}</div>}
+                    {quote && quote.options.length === 0 && (
+                      <div className="text-danger small">No shipping method available for this address</div>
+                    )}
+                    {quote && quote.options.map(option => (
+                      <div className="form-check" key={option.method}>
+                        <input 
+                          type="radio" 
+                          className="form-check-input" 
+                          id={`shipping-${option.method}`}
+                          name="shippingMethod"
+                          value={option.method}
+                          checked={shippingMethod === option.method}
+                          onChange={(e) => setShippingMethod(e.target.value)}
+                        />
+                        <label className="form-check-label" htmlFor={`shipping-${option.method}`}>
+                          {option.name} - {option.free ? 'Free' : `$${option.fee.toFixed(2)}`}
+                          {option.estimated_days && <span className="text-muted"> ({option.estimated_days} days)</span>}
+                        </label>
+                      </div>
+                    ))}
+                  </div>
+
                   <div className="mb-3">
                     <label className="form-label">Payment Method</label>
                     <select 
@@ -283,7 +404,7 @@ function Checkout() {
                     />
                   </div>
 
-                  <button type="submit" className="btn btn-primary btn-lg w-100" disabled={isSubmitting}>
+                  <button type="submit" className="btn btn-primary btn-lg w-100" disabled={isSubmitting || !selectedOption}>
                     {isSubmitting ? 'Placing Order...' : 'Complete Order'}
                   </button>
                 </form>
@@ -51,8 +51,15 @@ function Login() {
         // Login with redirect options
         login({
           token: response.data.token,
-          user: response.data.user
+          user: response.data.user,
+          guest_merge: response.data.guest_merge
         }, { from })
+
+        // Báo các sản phẩm trong giỏ của khách không gộp được vào tài khoản
+        const skipped = response.data.guest_merge?.skipped || []
+        if (skipped.length > 0) {
+          toast.warning(`${skipped.length} sản phẩm không thể chuyển vào tài khoản (hết hàng hoặc không còn tồn tại)`)
+        }
         
         // Show role-specific message
         if (user.role === 'admin') {
@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react'
+import React, { useState, useEffect, useRef } from 'react'
 import { Link, useParams, useSearchParams } from 'react-router-dom'
 import { orderAPI } from '../services/api'
 import { useAuth } from '../context/AuthContext'
@@ -6,7 +6,7 @@ import { useAuth } from '../context/AuthContext'
 function OrderDetail() {
   const { orderNumber } = useParams()
   const [searchParams] = useSearchParams()
-  const emailParam = searchParams.get('email')
+  const tokenParam = searchParams.get('token')
   const { isLoggedIn, user } = useAuth()
   
   const [order, setOrder] = useState(null)
@@ -14,13 +14,15 @@ function OrderDetail() {
   const [loading, setLoading] = useState(false)
   const [searchData, setSearchData] = useState({
     orderNumber: orderNumber || '',
-    email: emailParam || ''
+    contact: ''
   })
-  const [searchType, setSearchType] = useState(orderNumber ? 'number' : 'email')
+  const [searchType, setSearchType] = useState('number') // number: mã đơn + email/SĐT, link: gửi liên kết qua email
+  const [linkSent, setLinkSent] = useState(false)
+  const redeemedToken = useRef(null) // Liên kết tra cứu chỉ dùng được một lần nên không gọi lại khi effect chạy lại
   const [viewType, setViewType] = useState(isLoggedIn ? 'my-orders' : 'search') // my-orders hoặc search
 
   useEffect(() => {
-    console.log('OrderDetail useEffect triggered', { orderNumber, emailParam, isLoggedIn, viewType })
+    console.log('OrderDetail useEffect triggered', { orderNumber, isLoggedIn, viewType })
     
     // Prevent infinite loops by adding a flag
     let isMounted = true
@@ -29,13 +31,12 @@ function OrderDetail() {
       if (!isMounted) return
       
       try {
-        // Auto search if orderNumber is provided in URL
-        if (orderNumber) {
-          console.log('Auto searching by order number:', orderNumber)
-          await handleSearchByNumber(orderNumber)
-        } else if (emailParam) {
-          console.log('Auto searching by email:', emailParam)
-          await handleSearchByEmail(emailParam)
+        // Mở liên kết tra cứu gửi qua email
+        if (tokenParam) {
+          if (redeemedToken.current !== tokenParam) {
+            redeemedToken.current = tokenParam
+            await handleRedeemLink(tokenParam)
+          }
         } else if (isLoggedIn && viewType === 'my-orders') {
           // Auto load my orders for logged in users
           console.log('Auto loading my orders for logged in user')
@@ -52,7 +53,7 @@ function OrderDetail() {
     return () => {
       isMounted = false
     }
-  }, [orderNumber, emailParam, isLoggedIn, viewType])
+  }, [tokenParam, isLoggedIn, viewType])
 
   const handleInputChange = (e) => {
     const { name, value } = e.target
@@ -98,23 +99,27 @@ Your role: ${errorData.your_role || 'unknown'}`)
     }
   }
 
-  const handleSearchByNumber = async (number = null) => {
-    const searchNumber = number || searchData.orderNumber
-    if (!searchNumber) {
-      alert('Please enter order number')
+  const handleSearchByNumber = async () => {
+    const searchNumber = searchData.orderNumber.trim()
+    const contact = searchData.contact.trim()
+    if (!searchNumber || !contact) {
+      alert('Please enter order number and the email or phone number used for the order')
       return
     }
 
     try {
       setLoading(true)
-      const response = await orderAPI.getOrderByNumber(searchNumber)
-      
+      const response = await orderAPI.lookupOrder({
+        order_number: searchNumber,
+        ...(contact.includes('@') ? { email: contact } : { phone: contact })
+      })
+
       if (response.data.success) {
         setOrder(response.data.data)
         setOrders([])
       }
     } catch (error) {
-      console.error('Search by number error:', error)
+      console.error('Order lookup error:', error)
       alert(error.response?.data?.message || 'Order not found')
       setOrder(null)
     } finally {
@@ -122,25 +127,38 @@ Your role: ${errorData.your_role || 'unknown'}`)
     }
   }
 
-  const handleSearchByEmail = async (email = null) => {
-    const searchEmail = email || searchData.email
-    if (!searchEmail) {
-      alert('Please enter email address')
+  const handleRequestLink = async () => {
+    const searchNumber = searchData.orderNumber.trim()
+    if (!searchNumber) {
+      alert('Please enter order number')
       return
     }
 
     try {
       setLoading(true)
-      const response = await orderAPI.getOrdersByEmail(searchEmail)
-      
+      await orderAPI.requestLookupLink(searchNumber)
+      setLinkSent(true)
+    } catch (error) {
+      console.error('Request lookup link error:', error)
+      alert(error.response?.data?.message || 'Could not send lookup link')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const handleRedeemLink = async (token) => {
+    try {
+      setLoading(true)
+      const response = await orderAPI.redeemLookupLink(token)
+
       if (response.data.success) {
-        setOrders(response.data.data)
-        setOrder(null)
+        setOrder(response.data.data)
+        setOrders([])
       }
     } catch (error) {
-      console.error('Search by email error:', error)
-      alert(error.response?.data?.message || 'No orders found for this email')
-      setOrders([])
+      console.error('Redeem lookup link error:', error)
+      alert(error.response?.data?.message || 'This lookup link is invalid or has expired')
+      setOrder(null)
     } finally {
       setLoading(false)
     }
@@ -151,7 +169,19 @@ Your role: ${errorData.your_role || 'unknown'}`)
     if (searchType === 'number') {
       handleSearchByNumber()
     } else {
-      handleSearchByEmail()
+      handleRequestLink()
+    }
+  }
+
+  const handleDownloadInvoice = async (orderId) => {
+    try {
+      const response = await orderAPI.getInvoice(orderId)
+      const url = window.URL.createObjectURL(new Blob([response.data], { type: 'application/pdf' }))
+      window.open(url, '_blank')
+      setTimeout(() => window.URL.revokeObjectURL(url), 60000)
+    } catch (error) {
+      console.error('Download invoice error:', error)
+      alert('Could not download invoice. Please try again later.')
     }
   }
 
@@ -275,8 +305,8 @@ Your role: ${errorData.your_role || 'unknown'}`)
                         </div>
                       </td>
                       <td>{String(item.quantity || 0)}</td>
-                      <td>{formatPrice(item.unit_price || 0)}</td>
-                      <td>{formatPrice(item.total_price || 0)}</td>
+                      <td>{formatPrice(item.unit_price || item.price || 0)}</td>
+                      <td>{formatPrice(item.total_price || item.total || 0)}</td>
                     </tr>
                   ))
                 ) : (
@@ -288,6 +318,38 @@ Your role: ${errorData.your_role || 'unknown'}`)
             </table>
           </div>
           
+          {(safeOrderData.tracking?.tracking_number || safeOrderData.shipped_at) && (
+            <div className="mt-3">
+              <h6>Shipment Tracking</h6>
+              {safeOrderData.tracking?.tracking_number && (
+                <p>
+                  <strong>{String(safeOrderData.tracking.carrier_name || safeOrderData.tracking.carrier || 'Carrier').toUpperCase()}:</strong>{' '}
+                  {safeOrderData.tracking.tracking_url ? (
+                    <a href={safeOrderData.tracking.tracking_url} target="_blank" rel="noopener noreferrer">
+                      {String(safeOrderData.tracking.tracking_number)}
+                    </a>
+                  ) : (
+                    String(safeOrderData.tracking.tracking_number)
+                  )}
+                </p>
+              )}
+              {safeOrderData.shipped_at && (
+                <p><strong>Shipped:</strong> {formatDate(safeOrderData.shipped_at)}</p>
+              )}
+              {safeOrderData.delivered_at && (
+                <p><strong>Delivered:</strong> {formatDate(safeOrderData.delivered_at)}</p>
+              )}
+            </div>
+          )}
+
+          {isLoggedIn && safeOrderData.id && !['pending', 'cancelled'].includes(safeOrderData.status) && (
+            <div className="mt-3">
+              <button type="button" className="btn btn-outline-secondary btn-sm" onClick={() => handleDownloadInvoice(safeOrderData.id)}>
+                Download Invoice (PDF)
+              </button>
+            </div>
+          )}
+
           {safeOrderData.notes && (
             <div className="mt-3">
               <h6>Customer Notes</h6>
@@ -353,46 +415,55 @@ Your role: ${errorData.your_role || 'unknown'}`)
                       <select 
                         className="form-select" 
                         value={searchType} 
-                        onChange={(e) => setSearchType(e.target.value)}
+                        onChange={(e) => {
+                          setSearchType(e.target.value)
+                          setLinkSent(false)
+                        }}
                       >
-                        <option value="number">Order Number</option>
-                        <option value="email">Email Address</option>
+                        <option value="number">Order Number + Email/Phone</option>
+                        <option value="link">Email Me a Link</option>
                       </select>
                     </div>
-                    <div className="col-md-6">
-                      {searchType === 'number' ? (
+                    <div className={searchType === 'number' ? 'col-md-3' : 'col-md-6'}>
+                      <input
+                        type="text"
+                        className="form-control"
+                        name="orderNumber"
+                        placeholder="Order number (e.g., GP20241201001)"
+                        value={searchData.orderNumber}
+                        onChange={handleInputChange}
+                        required
+                      />
+                    </div>
+                    {searchType === 'number' && (
+                      <div className="col-md-3">
                         <input
                           type="text"
                           className="form-control"
-                          name="orderNumber"
-                          placeholder="Enter your order number (e.g., GP20241201001)"
-                          value={searchData.orderNumber}
+                          name="contact"
+                          placeholder="Email or phone on the order"
+                          value={searchData.contact}
                           onChange={handleInputChange}
                           required
                         />
-                      ) : (
-                        <input
-                          type="email"
-                          className="form-control"
-                          name="email"
-                          placeholder="Enter your email address"
-                          value={searchData.email}
-                          onChange={handleInputChange}
-                          required
-                        />
-                      )}
-                    </div>
+                      </div>
+                    )}
                     <div className="col-md-3">
                       <button 
                         type="submit" 
                         className="btn btn-primary w-100"
                         disabled={loading}
                       >
-                        {loading ? 'Searching...' : 'Search'}
+                        {loading ? 'Searching...' : searchType === 'number' ? 'Search' : 'Send Link'}
                       </button>
                     </div>
                   </div>
                 </form>
+                {linkSent && searchType === 'link' && (
+                  <div className="alert alert-info mt-3 mb-0">
+                    If the order number is correct, a one-time lookup link has been sent to the email address on the order.
+                  </div>
+                )}
               </div>
             </div>
           )}
@@ -426,15 +497,12 @@ Your role: ${errorData.your_role || 'unknown'}`)
           )}
 
           {/* No Results */}
-          {!loading && !order && orders.length === 0 && (viewType === 'search' && (searchData.orderNumber || searchData.email)) && (
+          {!loading && !order && orders.length === 0 && viewType === 'search' && searchType === 'number' && searchData.orderNumber && searchData.contact && (
             <div className="text-center py-5">
               <i className="fas fa-search fa-3x text-muted mb-3"></i>
               <h4>No Orders Found</h4>
               <p className="text-muted">
-                {searchType === 'number' 
-                  ? 'No order found with this order number.' 
-                  : 'No orders found for this email address.'
-                }
+                No order found with this order number and contact details.
               </p>
               <Link to="/product" className="btn btn-primary">
                 Continue Shopping
@@ -443,14 +511,14 @@ Your role: ${errorData.your_role || 'unknown'}`)
           )}
 
           {/* Initial State */}
-          {!loading && !order && orders.length === 0 && viewType === 'search' && !searchData.orderNumber && !searchData.email && (
+          {!loading && !order && orders.length === 0 && viewType === 'search' && !searchData.orderNumber && (
             <div className="text-center py-5">
               <i className="fas fa-package fa-3x text-primary mb-3"></i>
               <h4>Track Your Order</h4>
               <p className="text-muted">
                 {isLoggedIn 
                   ? 'Choose "My Orders" to see your order history, or use the search form above.'
-                  : 'Enter your order number or email address above to track your order status and view details.'
+                  : 'Enter your order number with the email or phone used for the order, or have a one-time link emailed to you.'
                 }
               </p>
             </div>
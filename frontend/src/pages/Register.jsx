@@ -59,6 +59,10 @@ function Register() {
       })
       
       if (response.data.success) {
+        // Giỏ hàng của khách đã được gộp vào tài khoản mới
+        if (response.data.guest_merge && !response.data.guest_merge.error) {
+          localStorage.removeItem('guestToken')
+        }
         toast.success(response.data.message || 'Đăng ký thành công!')
         navigate('/login')
       }
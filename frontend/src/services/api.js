@@ -19,6 +19,11 @@ api.interceptors.request.use(
     if (token) {
       config.headers.Authorization = `Bearer ${token}`;
     }
+    // Khách chưa đăng nhập: giỏ hàng và đơn hàng gắn với guest token
+    const guestToken = localStorage.getItem("guestToken");
+    if (guestToken) {
+      config.headers["X-Guest-Token"] = guestToken;
+    }
     return config;
   },
   (error) => {
@@ -30,6 +35,11 @@ api.interceptors.request.use(
 api.interceptors.response.use(
   (response) => response,
   (error) => {
+    // Guest token hết hạn hoặc không hợp lệ: bỏ đi để lần sau cấp token mới, không chuyển sang trang đăng nhập
+    if (error.response?.status === 401 && !localStorage.getItem("token") && localStorage.getItem("guestToken")) {
+      localStorage.removeItem("guestToken");
+      return Promise.reject(error);
+    }
     if (error.response?.status === 401) {
       localStorage.removeItem("token");
       localStorage.removeItem("user");
@@ -95,14 +105,19 @@ export const productAPI = {
 // Order API
 export const orderAPI = {
   createOrder: (orderData) => api.post("/orders", orderData),
+  quoteOrder: (data) => api.post("/orders/quote", data), // Phương thức giao hàng, phí và tổng tiền: { city }
   getOrders: (params) => api.get("/orders", { params }),
   getOrder: (id) => api.get(`/orders/${id}`),
-  getOrderByNumber: (orderNumber) => api.get(`/orders/number/${orderNumber}`),
-  getOrdersByEmail: (email) => api.get("/orders/by-email", { params: { email } }),
+  lookupOrder: (data) => api.post("/orders/lookup", data), // Tra cứu đơn: { order_number, email | phone }
+  requestLookupLink: (orderNumber) => api.post("/orders/lookup/link", { order_number: orderNumber }), // Gửi liên kết tra cứu tới email trên đơn
+  redeemLookupLink: (token) => api.post("/orders/lookup/redeem", { token }), // Mở liên kết tra cứu (dùng một lần)
   getMyOrders: () => api.get("/orders/my-orders"), // API mới: user đăng nhập xem orders riêng
   testMyOrdersAccess: () => api.get("/orders/test-my-orders-access"), // Test quyền truy cập
   updateOrderStatus: (id, status) => api.put(`/orders/${id}/status`, { status }),
   cancelOrder: (id) => api.put(`/orders/${id}/cancel`),
+  payOrder: (id) => api.post(`/orders/${id}/pay`), // Tạo phiên thanh toán online
+  requestReturn: (id, data) => api.post(`/orders/${id}/returns`, data), // Yêu cầu trả hàng
+  getInvoice: (id) => api.get(`/orders/${id}/invoice.pdf`, { responseType: 'blob' }), // Hóa đơn GTGT (PDF)
 };
 
 // Admin API
@@ -124,6 +139,12 @@ export const adminAPI = {
   createProduct: (productData) => api.post('/admin/products', productData),
   updateProduct: (productId, productData) => api.put(`/admin/products/${productId}`, productData),
   deleteProduct: (productId) => api.delete(`/admin/products/${productId}`),
+  exportProducts: () => api.get('/admin/products/export', { responseType: 'blob' }),
+  importProducts: (file, dryRun = false) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    return api.post('/admin/products/import', formData, { params: { dry_run: dryRun } })
+  },
   
   // User Management
   getUsers: (params = {}) => {
@@ -160,6 +181,44 @@ export const adminAPI = {
   createCategory: (data) => api.post('/admin/categories', data),
   updateCategory: (id, data) => api.put(`/admin/categories/${id}`, data),
   deleteCategory: (id) => api.delete(`/admin/categories/${id}`),
+
+  // Coupons
+  getCoupons: (params) => api.get('/admin/coupons', { params }),
+  createCoupon: (data) => api.post('/admin/coupons', data),
+  updateCoupon: (id, data) => api.put(`/admin/coupons/${id}`, data),
+  deleteCoupon: (id) => api.delete(`/admin/coupons/${id}`),
+
+  // Flash sales
+  getFlashSales: (params) => api.get('/admin/flash-sales', { params }),
+  createFlashSale: (data) => api.post('/admin/flash-sales', data),
+  updateFlashSale: (id, data) => api.put(`/admin/flash-sales/${id}`, data),
+  deleteFlashSale: (id) => api.delete(`/admin/flash-sales/${id}`),
+
+  // Bank statement reconciliation (CSV)
+  reconcileBankStatement: (file) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    return api.post('/admin/payments/bank-statements', formData)
+  },
+
+  // Shipment tracking
+  getCarriers: () => api.get('/admin/carriers'),
+  updateTracking: (id, data) => api.put(`/admin/orders/${id}/tracking`, data),
+  exportOrders: (params = {}) => api.get('/admin/orders/export', { params, responseType: 'blob' }), // format: csv|xlsx, layout: orders|items
+  quoteShipment: (id) => api.post(`/admin/orders/${id}/shipments/quote`),
+  createShipment: (id, data = {}) => api.post(`/admin/orders/${id}/shipments`, data),
+  getShipmentLabel: (id) => api.get(`/admin/orders/${id}/shipments/label`, { responseType: 'blob' }),
+  downloadInvoices: (dateFrom, dateTo) => api.get('/admin/invoices', { params: { date_from: dateFrom, date_to: dateTo }, responseType: 'blob' }),
+
+  // Refunds
+  refundOrder: (id, data) => api.post(`/admin/orders/${id}/refunds`, data),
+
+  // Returns (RMA)
+  getReturnRequests: (params = {}) => api.get('/admin/orders', { params: { return_status: 'requested', ...params } }),
+  approveReturn: (orderId, returnId, note) => api.post(`/admin/orders/${orderId}/returns/${returnId}/approve`, { note }),
+  rejectReturn: (orderId, returnId, note) => api.post(`/admin/orders/${orderId}/returns/${returnId}/reject`, { note }),
+  receiveReturn: (orderId, returnId) => api.post(`/admin/orders/${orderId}/returns/${returnId}/receive`),
+  refundReturn: (orderId, returnId, data = {}) => api.post(`/admin/orders/${orderId}/returns/${returnId}/refund`, data),
   
   // Reports
   getRevenueReport: (period = 'month') => api.get(`/admin/reports/revenue?period=${period}`),
@@ -173,6 +232,31 @@ export const cartAPI = {
   updateCartItem: (productId, data) => api.put(`/cart/update/${productId}`, data), // productId in URL path
   removeFromCart: (productId) => api.delete(`/cart/remove/${productId}`), // productId in URL path
   clearCart: () => api.delete("/cart/clear"),
+  applyCoupon: (code) => api.post("/cart/coupon", { code }),
+  removeCoupon: () => api.delete("/cart/coupon"),
+  createGuestToken: () => api.post("/cart/guest"), // Cấp guest token cho khách chưa đăng nhập
+};
+
+// Khách chưa đăng nhập: lấy guest token (POST /cart/guest) một lần và lưu vào localStorage
+// trước khi gọi giỏ hàng, wishlist, so sánh hoặc đặt hàng. Đã đăng nhập thì không cần.
+let pendingGuestToken = null;
+export const ensureGuestToken = async () => {
+  if (localStorage.getItem("token")) return null;
+  const existing = localStorage.getItem("guestToken");
+  if (existing) return existing;
+  if (!pendingGuestToken) {
+    pendingGuestToken = cartAPI
+      .createGuestToken()
+      .then((response) => {
+        const guestToken = response.data.data.guest_token;
+        localStorage.setItem("guestToken", guestToken);
+        return guestToken;
+      })
+      .finally(() => {
+        pendingGuestToken = null;
+      });
+  }
+  return pendingGuestToken;
 };
 
 // Wishlist API